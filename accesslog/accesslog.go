@@ -0,0 +1,173 @@
+// Package accesslog writes one structured record per request to pluggable sinks,
+// independently of the application logger.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/log"
+)
+
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+
+	// bufferSize is the number of records which can wait to be written before new ones are dropped.
+	bufferSize = 4096
+)
+
+var logger = log.Get().WithField("prefix", "access-log")
+
+// Latency holds the request latency breakdown in milliseconds.
+type Latency struct {
+	Total    int64 `json:"total"`
+	Upstream int64 `json:"upstream"`
+	Gateway  int64 `json:"gateway"`
+}
+
+// Record is a single access log entry.
+type Record struct {
+	Timestamp       time.Time `json:"timestamp"`
+	APIID           string    `json:"api_id"`
+	APIName         string    `json:"api_name"`
+	OrgID           string    `json:"org_id"`
+	Method          string    `json:"method"`
+	Host            string    `json:"host"`
+	Path            string    `json:"path"`
+	Status          int       `json:"status"`
+	ClientIP        string    `json:"client_ip"`
+	Key             string    `json:"key,omitempty"`
+	KeyAlias        string    `json:"key_alias,omitempty"`
+	UpstreamAddress string    `json:"upstream_address,omitempty"`
+	BytesWritten    int64     `json:"bytes_written"`
+	Latency         Latency   `json:"latency"`
+
+	// upstreamLatency is filled by the proxy while the request is in flight.
+	upstreamLatency time.Duration
+}
+
+// SetUpstream records the upstream address and round trip time of the request.
+func (r *Record) SetUpstream(address string, latency time.Duration) {
+	r.UpstreamAddress = address
+	r.upstreamLatency = latency
+}
+
+// Finish computes the latency breakdown of a request which started at start.
+func (r *Record) Finish(start time.Time) {
+	total := time.Since(start)
+	r.Latency = Latency{
+		Total:    total.Milliseconds(),
+		Upstream: r.upstreamLatency.Milliseconds(),
+		Gateway:  (total - r.upstreamLatency).Milliseconds(),
+	}
+}
+
+// Logger writes records asynchronously to all configured sinks.
+type Logger struct {
+	sinks   []io.WriteCloser
+	records chan Record
+	done    chan struct{}
+	dropped uint64
+
+	// mu guards records against being written to after Close
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New creates a Logger writing to the sinks of conf, defaulting to stdout when none is configured.
+func New(conf config.AccessLogsConfig) (*Logger, error) {
+	sinkConfs := conf.Sinks
+	if len(sinkConfs) == 0 {
+		sinkConfs = []config.AccessLogSinkConfig{{Type: SinkStdout}}
+	}
+
+	l := &Logger{
+		records: make(chan Record, bufferSize),
+		done:    make(chan struct{}),
+	}
+
+	for _, sinkConf := range sinkConfs {
+		sink, err := newSink(sinkConf)
+		if err != nil {
+			l.closeSinks()
+			return nil, fmt.Errorf("access log sink %q: %v", sinkConf.Type, err)
+		}
+		l.sinks = append(l.sinks, sink)
+	}
+
+	go l.run()
+
+	return l, nil
+}
+
+// Log queues rec to be written, dropping it if the buffer is full or the logger is closed.
+func (l *Logger) Log(rec Record) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.records <- rec:
+	default:
+		if atomic.AddUint64(&l.dropped, 1)%1000 == 1 {
+			logger.Warning("Access log buffer is full, dropping records")
+		}
+	}
+}
+
+// Dropped returns the number of records dropped because the buffer was full.
+func (l *Logger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// Close flushes queued records and closes all sinks.
+func (l *Logger) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.records)
+	l.mu.Unlock()
+
+	<-l.done
+	l.closeSinks()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	for rec := range l.records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			logger.WithError(err).Error("Could not encode access log record")
+			continue
+		}
+		line = append(line, '\n')
+
+		for _, sink := range l.sinks {
+			if _, err := sink.Write(line); err != nil {
+				logger.WithError(err).Error("Could not write access log record")
+			}
+		}
+	}
+}
+
+func (l *Logger) closeSinks() {
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			logger.WithError(err).Error("Could not close access log sink")
+		}
+	}
+}
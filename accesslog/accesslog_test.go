@@ -0,0 +1,85 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	l, err := New(config.AccessLogsConfig{Sinks: []config.AccessLogSinkConfig{{Type: SinkFile, Path: path}}})
+	assert.NoError(t, err)
+
+	rec := Record{APIID: "api", Status: 200, KeyAlias: "alias"}
+	rec.SetUpstream("upstream:8080", 0)
+	rec.Finish(time.Now())
+	l.Log(rec)
+	l.Close()
+	// logging after close is a no-op
+	l.Log(rec)
+	l.Close()
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	assert.True(t, scanner.Scan())
+
+	var result Record
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+	assert.Equal(t, "api", result.APIID)
+	assert.Equal(t, "alias", result.KeyAlias)
+	assert.Equal(t, "upstream:8080", result.UpstreamAddress)
+	assert.False(t, scanner.Scan())
+
+	_, err = New(config.AccessLogsConfig{Sinks: []config.AccessLogSinkConfig{{Type: "unknown"}}})
+	assert.Error(t, err)
+
+	_, err = New(config.AccessLogsConfig{Sinks: []config.AccessLogSinkConfig{{Type: SinkFile}}})
+	assert.Error(t, err)
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	f, err := NewRotatingFile(path, 1, 2)
+	assert.NoError(t, err)
+	// rotate every two lines
+	f.maxSize = 10
+
+	for _, line := range []string{"1111\n", "2222\n", "3333\n", "4444\n", "5555\n", "6666\n", "7777\n"} {
+		_, err := f.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Close())
+
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		return strings.Replace(string(data), "\n", " ", -1)
+	}
+
+	assert.Equal(t, "7777 ", read(path))
+	assert.Equal(t, "5555 6666 ", read(path+".1"))
+	assert.Equal(t, "3333 4444 ", read(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "backups over the limit must be removed")
+}
@@ -38,6 +38,7 @@ type IdExtractorSource string
 type IdExtractorType string
 type AuthTypeEnum string
 type RoutingTriggerOnType string
+type BodyMaskAction string
 
 const (
 	NoAction EndpointMethodAction = "no_action"
@@ -45,6 +46,10 @@ const (
 
 	UseBlob TemplateMode = "blob"
 	UseFile TemplateMode = "file"
+	// UseJSONToXML and UseXMLToJSON convert the body without template. The template source
+	// is the optional name of the XML root element when converting JSON to XML.
+	UseJSONToXML TemplateMode = "json_to_xml"
+	UseXMLToJSON TemplateMode = "xml_to_json"
 
 	RequestXML  RequestInputType = "xml"
 	RequestJSON RequestInputType = "json"
@@ -54,6 +59,7 @@ const (
 	LuaDriver      MiddlewareDriver = "lua"
 	GrpcDriver     MiddlewareDriver = "grpc"
 	GoPluginDriver MiddlewareDriver = "goplugin"
+	WasmDriver     MiddlewareDriver = "wasm"
 
 	BodySource        IdExtractorSource = "body"
 	HeaderSource      IdExtractorSource = "header"
@@ -78,6 +84,11 @@ const (
 	Any    RoutingTriggerOnType = "any"
 	Ignore RoutingTriggerOnType = ""
 
+	// For body masking
+	BodyMaskRedact   BodyMaskAction = "redact"
+	BodyMaskHash     BodyMaskAction = "hash"
+	BodyMaskTokenize BodyMaskAction = "tokenize"
+
 	// TykInternalApiHeader - flags request as internal api looping request
 	TykInternalApiHeader = "x-tyk-internal"
 )
@@ -205,6 +216,8 @@ type HardTimeoutMeta struct {
 	Path    string `bson:"path" json:"path"`
 	Method  string `bson:"method" json:"method"`
 	TimeOut int    `bson:"timeout" json:"timeout"`
+	// ConnectTimeOut is the upstream connect timeout in seconds, TimeOut is used when not set.
+	ConnectTimeOut int `bson:"connect_timeout" json:"connect_timeout"`
 }
 
 type TrackEndpointMeta struct {
@@ -223,6 +236,53 @@ type RequestSizeMeta struct {
 	SizeLimit int64  `bson:"size_limit" json:"size_limit"`
 }
 
+type ResponseSizeMeta struct {
+	Path      string `bson:"path" json:"path"`
+	Method    string `bson:"method" json:"method"`
+	SizeLimit int64  `bson:"size_limit" json:"size_limit"`
+}
+
+// BodyMaskingMeta describes the fields of the JSON request body masked before a request to an
+// endpoint is proxied upstream.
+type BodyMaskingMeta struct {
+	Path   string         `bson:"path" json:"path"`
+	Method string         `bson:"method" json:"method"`
+	Rules  []BodyMaskRule `bson:"rules" json:"rules"`
+	// MaskResponse applies the rules to the JSON response body too, before it's returned to the client.
+	MaskResponse bool `bson:"mask_response" json:"mask_response"`
+}
+
+type BodyMaskRule struct {
+	// JSONPath selects the fields to mask, e.g. `$.card.number`, `$.items[*].cvv` or `$..email`.
+	JSONPath string `bson:"json_path" json:"json_path"`
+	// Action is `redact`, `hash`, which replaces fields by their HMAC-SHA256 keyed with the secret
+	// of the gateway, or `tokenize`.
+	Action BodyMaskAction `bson:"action" json:"action"`
+	// Replacement is the value of redacted fields, `****` when empty.
+	Replacement string `bson:"replacement" json:"replacement"`
+	// TokenTTL is the time in seconds during which the values of tokenized fields are kept, forever when 0.
+	TokenTTL int64 `bson:"token_ttl" json:"token_ttl"`
+}
+
+// QueryTransformMeta describes changes to the query parameters of requests to an endpoint.
+// Parameters are renamed first, then deleted, then added.
+type QueryTransformMeta struct {
+	Path   string `bson:"path" json:"path"`
+	Method string `bson:"method" json:"method"`
+	// RenameParams maps parameter names sent by clients to the names expected by the upstream.
+	RenameParams map[string]string `bson:"rename_params" json:"rename_params"`
+	DeleteParams []string          `bson:"delete_params" json:"delete_params"`
+	// AddParams sets default values of parameters missing from the request, values can
+	// reference context variables.
+	AddParams map[string]string `bson:"add_params" json:"add_params"`
+}
+
+type RequiredScopesMeta struct {
+	Path   string   `bson:"path" json:"path"`
+	Method string   `bson:"method" json:"method"`
+	Scopes []string `bson:"scopes" json:"scopes"`
+}
+
 type CircuitBreakerMeta struct {
 	Path                 string  `bson:"path" json:"path"`
 	Method               string  `bson:"method" json:"method"`
@@ -288,6 +348,88 @@ type ValidatePathMeta struct {
 	ErrorResponseCode int `bson:"error_response_code" json:"error_response_code"`
 }
 
+// ValidateXMLMeta validates the XML body of requests against an XML schema (XSD).
+type ValidateXMLMeta struct {
+	Path   string `bson:"path" json:"path"`
+	Method string `bson:"method" json:"method"`
+	// Schema is the XSD document. The payload of SOAP requests, the first child of the envelope
+	// body, is validated rather than the envelope.
+	Schema string `bson:"schema" json:"schema"`
+	// Allows override of default 422 Unprocessible Entity response code for validation errors.
+	ErrorResponseCode int `bson:"error_response_code" json:"error_response_code"`
+}
+
+// DeprecatedMeta marks an endpoint as deprecated. Its responses carry deprecation headers, and its
+// requests are blocked after the sunset date.
+type DeprecatedMeta struct {
+	Disabled bool   `bson:"disabled" json:"disabled"`
+	Path     string `bson:"path" json:"path"`
+	Method   string `bson:"method" json:"method"`
+	// Date is when the endpoint was deprecated, in RFC 3339 format.
+	Date string `bson:"date" json:"date"`
+	// Sunset is the date after which requests are blocked, in RFC 3339 format. Never if empty.
+	Sunset string `bson:"sunset" json:"sunset"`
+	// Link is the URL of the documentation of the deprecation, e.g. a migration guide.
+	Link string `bson:"link" json:"link"`
+}
+
+// ValidateMultipartMeta validates the parts of multipart uploads while they are streamed to the
+// upstream, without buffering their body. Uploads violating the limits are aborted mid-stream.
+type ValidateMultipartMeta struct {
+	Disabled bool   `bson:"disabled" json:"disabled"`
+	Path     string `bson:"path" json:"path"`
+	Method   string `bson:"method" json:"method"`
+	// MaxPartSize is the maximum size of a part in bytes, unlimited if 0.
+	MaxPartSize int64 `bson:"max_part_size" json:"max_part_size"`
+	// MaxParts is the maximum number of parts, unlimited if 0.
+	MaxParts int `bson:"max_parts" json:"max_parts"`
+	// AllowedContentTypes are the media types allowed for the files, e.g. image/png or image/*.
+	// Any if empty.
+	AllowedContentTypes []string `bson:"allowed_content_types" json:"allowed_content_types"`
+}
+
+// CompositeMeta declares an endpoint aggregating the JSON responses of internal APIs, called in
+// parallel, rather than proxying to the upstream.
+type CompositeMeta struct {
+	Disabled bool               `bson:"disabled" json:"disabled"`
+	Path     string             `bson:"path" json:"path"`
+	Method   string             `bson:"method" json:"method"`
+	Requests []CompositeRequest `bson:"requests" json:"requests"`
+	// Template renders the response body with the parsed responses of the requests by name. The
+	// responses are merged into an object by name if empty.
+	Template string `bson:"template" json:"template"`
+	// Timeout is the combined timeout of the requests in seconds, proxy_default_timeout if 0.
+	Timeout float64 `bson:"timeout" json:"timeout"`
+}
+
+// CompositeRequest is a request of a composite endpoint to an internal API.
+type CompositeRequest struct {
+	// Name is the key of the response in the data of the template.
+	Name string `bson:"name" json:"name"`
+	// URL is the loop URL of the request, e.g. tyk://users-api/users, where context and metadata
+	// variables are replaced.
+	URL     string            `bson:"url" json:"url"`
+	Method  string            `bson:"method" json:"method"`
+	Headers map[string]string `bson:"headers" json:"headers,omitempty"`
+	// Optional requests which fail are null in the data of the template rather than failing the
+	// composite response.
+	Optional bool `bson:"optional" json:"optional"`
+}
+
+// AsyncMeta declares an endpoint whose requests are queued and answered immediately with 202
+// Accepted and the ID of an operation, then delivered to the upstream in the background with
+// retries. Clients poll the operation for the upstream response.
+type AsyncMeta struct {
+	Disabled bool   `bson:"disabled" json:"disabled"`
+	Path     string `bson:"path" json:"path"`
+	Method   string `bson:"method" json:"method"`
+	// MaxAttempts is the number of delivery attempts, retried on network errors and 5xx responses.
+	// 3 if 0.
+	MaxAttempts int `bson:"max_attempts" json:"max_attempts"`
+	// RetryBackoff is the delay in seconds before the first retry, doubled on each attempt. 1 if 0.
+	RetryBackoff float64 `bson:"retry_backoff" json:"retry_backoff"`
+}
+
 type GoPluginMeta struct {
 	Path       string `bson:"path" json:"path"`
 	Method     string `bson:"method" json:"method"`
@@ -296,28 +438,37 @@ type GoPluginMeta struct {
 }
 
 type ExtendedPathsSet struct {
-	Ignored                 []EndPointMeta        `bson:"ignored" json:"ignored,omitempty"`
-	WhiteList               []EndPointMeta        `bson:"white_list" json:"white_list,omitempty"`
-	BlackList               []EndPointMeta        `bson:"black_list" json:"black_list,omitempty"`
-	Cached                  []string              `bson:"cache" json:"cache,omitempty"`
-	AdvanceCacheConfig      []CacheMeta           `bson:"advance_cache_config" json:"advance_cache_config,omitempty"`
-	Transform               []TemplateMeta        `bson:"transform" json:"transform,omitempty"`
-	TransformResponse       []TemplateMeta        `bson:"transform_response" json:"transform_response,omitempty"`
-	TransformJQ             []TransformJQMeta     `bson:"transform_jq" json:"transform_jq,omitempty"`
-	TransformJQResponse     []TransformJQMeta     `bson:"transform_jq_response" json:"transform_jq_response,omitempty"`
-	TransformHeader         []HeaderInjectionMeta `bson:"transform_headers" json:"transform_headers,omitempty"`
-	TransformResponseHeader []HeaderInjectionMeta `bson:"transform_response_headers" json:"transform_response_headers,omitempty"`
-	HardTimeouts            []HardTimeoutMeta     `bson:"hard_timeouts" json:"hard_timeouts,omitempty"`
-	CircuitBreaker          []CircuitBreakerMeta  `bson:"circuit_breakers" json:"circuit_breakers,omitempty"`
-	URLRewrite              []URLRewriteMeta      `bson:"url_rewrites" json:"url_rewrites,omitempty"`
-	Virtual                 []VirtualMeta         `bson:"virtual" json:"virtual,omitempty"`
-	SizeLimit               []RequestSizeMeta     `bson:"size_limits" json:"size_limits,omitempty"`
-	MethodTransforms        []MethodTransformMeta `bson:"method_transforms" json:"method_transforms,omitempty"`
-	TrackEndpoints          []TrackEndpointMeta   `bson:"track_endpoints" json:"track_endpoints,omitempty"`
-	DoNotTrackEndpoints     []TrackEndpointMeta   `bson:"do_not_track_endpoints" json:"do_not_track_endpoints,omitempty"`
-	ValidateJSON            []ValidatePathMeta    `bson:"validate_json" json:"validate_json,omitempty"`
-	Internal                []InternalMeta        `bson:"internal" json:"internal,omitempty"`
-	GoPlugin                []GoPluginMeta        `bson:"go_plugin" json:"go_plugin,omitempty"`
+	Ignored                 []EndPointMeta          `bson:"ignored" json:"ignored,omitempty"`
+	WhiteList               []EndPointMeta          `bson:"white_list" json:"white_list,omitempty"`
+	BlackList               []EndPointMeta          `bson:"black_list" json:"black_list,omitempty"`
+	Cached                  []string                `bson:"cache" json:"cache,omitempty"`
+	AdvanceCacheConfig      []CacheMeta             `bson:"advance_cache_config" json:"advance_cache_config,omitempty"`
+	Transform               []TemplateMeta          `bson:"transform" json:"transform,omitempty"`
+	TransformResponse       []TemplateMeta          `bson:"transform_response" json:"transform_response,omitempty"`
+	TransformJQ             []TransformJQMeta       `bson:"transform_jq" json:"transform_jq,omitempty"`
+	TransformJQResponse     []TransformJQMeta       `bson:"transform_jq_response" json:"transform_jq_response,omitempty"`
+	TransformHeader         []HeaderInjectionMeta   `bson:"transform_headers" json:"transform_headers,omitempty"`
+	TransformQuery          []QueryTransformMeta    `bson:"transform_query" json:"transform_query,omitempty"`
+	BodyMasking             []BodyMaskingMeta       `bson:"body_masking" json:"body_masking,omitempty"`
+	TransformResponseHeader []HeaderInjectionMeta   `bson:"transform_response_headers" json:"transform_response_headers,omitempty"`
+	HardTimeouts            []HardTimeoutMeta       `bson:"hard_timeouts" json:"hard_timeouts,omitempty"`
+	CircuitBreaker          []CircuitBreakerMeta    `bson:"circuit_breakers" json:"circuit_breakers,omitempty"`
+	URLRewrite              []URLRewriteMeta        `bson:"url_rewrites" json:"url_rewrites,omitempty"`
+	Virtual                 []VirtualMeta           `bson:"virtual" json:"virtual,omitempty"`
+	SizeLimit               []RequestSizeMeta       `bson:"size_limits" json:"size_limits,omitempty"`
+	ResponseSizeLimit       []ResponseSizeMeta      `bson:"response_size_limits" json:"response_size_limits,omitempty"`
+	RequiredScopes          []RequiredScopesMeta    `bson:"required_scopes" json:"required_scopes,omitempty"`
+	MethodTransforms        []MethodTransformMeta   `bson:"method_transforms" json:"method_transforms,omitempty"`
+	TrackEndpoints          []TrackEndpointMeta     `bson:"track_endpoints" json:"track_endpoints,omitempty"`
+	DoNotTrackEndpoints     []TrackEndpointMeta     `bson:"do_not_track_endpoints" json:"do_not_track_endpoints,omitempty"`
+	ValidateJSON            []ValidatePathMeta      `bson:"validate_json" json:"validate_json,omitempty"`
+	ValidateXML             []ValidateXMLMeta       `bson:"validate_xml" json:"validate_xml,omitempty"`
+	ValidateMultipart       []ValidateMultipartMeta `bson:"validate_multipart" json:"validate_multipart,omitempty"`
+	Internal                []InternalMeta          `bson:"internal" json:"internal,omitempty"`
+	Deprecated              []DeprecatedMeta        `bson:"deprecated" json:"deprecated,omitempty"`
+	Composite               []CompositeMeta         `bson:"composite" json:"composite,omitempty"`
+	Async                   []AsyncMeta             `bson:"async" json:"async,omitempty"`
+	GoPlugin                []GoPluginMeta          `bson:"go_plugin" json:"go_plugin,omitempty"`
 }
 
 type VersionInfo struct {
@@ -337,6 +488,7 @@ type VersionInfo struct {
 	GlobalResponseHeadersRemove []string          `bson:"global_response_headers_remove" json:"global_response_headers_remove"`
 	IgnoreEndpointCase          bool              `bson:"ignore_endpoint_case" json:"ignore_endpoint_case"`
 	GlobalSizeLimit             int64             `bson:"global_size_limit" json:"global_size_limit"`
+	GlobalResponseSizeLimit     int64             `bson:"global_response_size_limit" json:"global_response_size_limit"`
 	OverrideTarget              string            `bson:"override_target" json:"override_target"`
 }
 
@@ -366,6 +518,10 @@ type MiddlewareDefinition struct {
 	Path           string `bson:"path" json:"path"`
 	RequireSession bool   `bson:"require_session" json:"require_session"`
 	RawBodyOnly    bool   `bson:"raw_body_only" json:"raw_body_only"`
+	// MaxMemoryPages limits the memory of a wasm plugin, in pages of 64KiB.
+	MaxMemoryPages uint32 `bson:"max_memory_pages" json:"max_memory_pages"`
+	// Timeout limits the execution time of a wasm plugin, in milliseconds.
+	Timeout int64 `bson:"timeout" json:"timeout"`
 }
 
 type MiddlewareIdExtractor struct {
@@ -383,6 +539,8 @@ type MiddlewareSection struct {
 	Response    []MiddlewareDefinition `bson:"response" json:"response"`
 	Driver      MiddlewareDriver       `bson:"driver" json:"driver"`
 	IdExtractor MiddlewareIdExtractor  `bson:"id_extractor" json:"id_extractor"`
+	// JSVMTimeout overrides the jsvm_timeout of the gateway for the API, in seconds.
+	JSVMTimeout int64 `bson:"jsvm_timeout" json:"jsvm_timeout"`
 }
 
 type CacheOptions struct {
@@ -393,6 +551,7 @@ type CacheOptions struct {
 	EnableUpstreamCacheControl bool     `bson:"enable_upstream_cache_control" json:"enable_upstream_cache_control"`
 	CacheControlTTLHeader      string   `bson:"cache_control_ttl_header" json:"cache_control_ttl_header"`
 	CacheByHeaders             []string `bson:"cache_by_headers" json:"cache_by_headers"`
+	CollapseRequests           bool     `bson:"collapse_requests" json:"collapse_requests"`
 }
 
 type ResponseProcessor struct {
@@ -506,39 +665,63 @@ type APIDefinition struct {
 		DefaultVersion string                 `bson:"default_version" json:"default_version"`
 		Versions       map[string]VersionInfo `bson:"versions" json:"versions"`
 	} `bson:"version_data" json:"version_data"`
-	UptimeTests               UptimeTests            `bson:"uptime_tests" json:"uptime_tests"`
-	Proxy                     ProxyConfig            `bson:"proxy" json:"proxy"`
-	DisableRateLimit          bool                   `bson:"disable_rate_limit" json:"disable_rate_limit"`
-	DisableQuota              bool                   `bson:"disable_quota" json:"disable_quota"`
-	CustomMiddleware          MiddlewareSection      `bson:"custom_middleware" json:"custom_middleware"`
-	CustomMiddlewareBundle    string                 `bson:"custom_middleware_bundle" json:"custom_middleware_bundle"`
-	CacheOptions              CacheOptions           `bson:"cache_options" json:"cache_options"`
-	SessionLifetime           int64                  `bson:"session_lifetime" json:"session_lifetime"`
-	Active                    bool                   `bson:"active" json:"active"`
-	Internal                  bool                   `bson:"internal" json:"internal"`
-	AuthProvider              AuthProviderMeta       `bson:"auth_provider" json:"auth_provider"`
-	SessionProvider           SessionProviderMeta    `bson:"session_provider" json:"session_provider"`
-	EventHandlers             EventHandlerMetaConfig `bson:"event_handlers" json:"event_handlers"`
-	EnableBatchRequestSupport bool                   `bson:"enable_batch_request_support" json:"enable_batch_request_support"`
-	EnableIpWhiteListing      bool                   `mapstructure:"enable_ip_whitelisting" bson:"enable_ip_whitelisting" json:"enable_ip_whitelisting"`
-	AllowedIPs                []string               `mapstructure:"allowed_ips" bson:"allowed_ips" json:"allowed_ips"`
-	EnableIpBlacklisting      bool                   `mapstructure:"enable_ip_blacklisting" bson:"enable_ip_blacklisting" json:"enable_ip_blacklisting"`
-	BlacklistedIPs            []string               `mapstructure:"blacklisted_ips" bson:"blacklisted_ips" json:"blacklisted_ips"`
-	DontSetQuotasOnCreate     bool                   `mapstructure:"dont_set_quota_on_create" bson:"dont_set_quota_on_create" json:"dont_set_quota_on_create"`
-	ExpireAnalyticsAfter      int64                  `mapstructure:"expire_analytics_after" bson:"expire_analytics_after" json:"expire_analytics_after"` // must have an expireAt TTL index set (http://docs.mongodb.org/manual/tutorial/expire-data/)
-	ResponseProcessors        []ResponseProcessor    `bson:"response_processors" json:"response_processors"`
-	CORS                      CORSConfig             `bson:"CORS" json:"CORS"`
-	Domain                    string                 `bson:"domain" json:"domain"`
-	Certificates              []string               `bson:"certificates" json:"certificates"`
-	DoNotTrack                bool                   `bson:"do_not_track" json:"do_not_track"`
-	Tags                      []string               `bson:"tags" json:"tags"`
-	EnableContextVars         bool                   `bson:"enable_context_vars" json:"enable_context_vars"`
-	ConfigData                map[string]interface{} `bson:"config_data" json:"config_data"`
-	TagHeaders                []string               `bson:"tag_headers" json:"tag_headers"`
-	GlobalRateLimit           GlobalRateLimit        `bson:"global_rate_limit" json:"global_rate_limit"`
-	StripAuthData             bool                   `bson:"strip_auth_data" json:"strip_auth_data"`
-	EnableDetailedRecording   bool                   `bson:"enable_detailed_recording" json:"enable_detailed_recording"`
-	GraphQL                   GraphQLConfig          `bson:"graphql" json:"graphql"`
+	UptimeTests               UptimeTests               `bson:"uptime_tests" json:"uptime_tests"`
+	Proxy                     ProxyConfig               `bson:"proxy" json:"proxy"`
+	DisableRateLimit          bool                      `bson:"disable_rate_limit" json:"disable_rate_limit"`
+	DisableQuota              bool                      `bson:"disable_quota" json:"disable_quota"`
+	CustomMiddleware          MiddlewareSection         `bson:"custom_middleware" json:"custom_middleware"`
+	CustomMiddlewareBundle    string                    `bson:"custom_middleware_bundle" json:"custom_middleware_bundle"`
+	CacheOptions              CacheOptions              `bson:"cache_options" json:"cache_options"`
+	SessionLifetime           int64                     `bson:"session_lifetime" json:"session_lifetime"`
+	Active                    bool                      `bson:"active" json:"active"`
+	Internal                  bool                      `bson:"internal" json:"internal"`
+	AuthProvider              AuthProviderMeta          `bson:"auth_provider" json:"auth_provider"`
+	SessionProvider           SessionProviderMeta       `bson:"session_provider" json:"session_provider"`
+	EventHandlers             EventHandlerMetaConfig    `bson:"event_handlers" json:"event_handlers"`
+	EnableBatchRequestSupport bool                      `bson:"enable_batch_request_support" json:"enable_batch_request_support"`
+	EnableIpWhiteListing      bool                      `mapstructure:"enable_ip_whitelisting" bson:"enable_ip_whitelisting" json:"enable_ip_whitelisting"`
+	AllowedIPs                []string                  `mapstructure:"allowed_ips" bson:"allowed_ips" json:"allowed_ips"`
+	EnableIpBlacklisting      bool                      `mapstructure:"enable_ip_blacklisting" bson:"enable_ip_blacklisting" json:"enable_ip_blacklisting"`
+	BlacklistedIPs            []string                  `mapstructure:"blacklisted_ips" bson:"blacklisted_ips" json:"blacklisted_ips"`
+	DontSetQuotasOnCreate     bool                      `mapstructure:"dont_set_quota_on_create" bson:"dont_set_quota_on_create" json:"dont_set_quota_on_create"`
+	ExpireAnalyticsAfter      int64                     `mapstructure:"expire_analytics_after" bson:"expire_analytics_after" json:"expire_analytics_after"` // must have an expireAt TTL index set (http://docs.mongodb.org/manual/tutorial/expire-data/)
+	ResponseProcessors        []ResponseProcessor       `bson:"response_processors" json:"response_processors"`
+	CORS                      CORSConfig                `bson:"CORS" json:"CORS"`
+	Domain                    string                    `bson:"domain" json:"domain"`
+	Certificates              []string                  `bson:"certificates" json:"certificates"`
+	DoNotTrack                bool                      `bson:"do_not_track" json:"do_not_track"`
+	Tags                      []string                  `bson:"tags" json:"tags"`
+	EnableContextVars         bool                      `bson:"enable_context_vars" json:"enable_context_vars"`
+	ConfigData                map[string]interface{}    `bson:"config_data" json:"config_data"`
+	TagHeaders                []string                  `bson:"tag_headers" json:"tag_headers"`
+	GlobalRateLimit           GlobalRateLimit           `bson:"global_rate_limit" json:"global_rate_limit"`
+	StripAuthData             bool                      `bson:"strip_auth_data" json:"strip_auth_data"`
+	EnableDetailedRecording   bool                      `bson:"enable_detailed_recording" json:"enable_detailed_recording"`
+	GraphQL                   GraphQLConfig             `bson:"graphql" json:"graphql"`
+	WebhookSubscriptions      WebhookSubscriptions      `bson:"webhook_subscriptions" json:"webhook_subscriptions"`
+	AnalyticsSampling         AnalyticsSampling         `bson:"analytics_sampling" json:"analytics_sampling"`
+	Kafka                     KafkaConfig               `bson:"kafka" json:"kafka"`
+	OAuthConsent              OAuthConsentConfig        `bson:"oauth_consent" json:"oauth_consent"`
+	Broker                    BrokerProxyConfig         `bson:"broker" json:"broker"`
+	GeoIPAccess               GeoIPAccessConfig         `bson:"geo_ip_access" json:"geo_ip_access"`
+	BotDetection              BotDetectionConfig        `bson:"bot_detection" json:"bot_detection"`
+	SyntheticMonitoring       SyntheticMonitoringConfig `bson:"synthetic_monitoring" json:"synthetic_monitoring"`
+	HeaderLimits              HeaderLimitsConfig        `bson:"header_limits" json:"header_limits"`
+	Compression               CompressionConfig         `bson:"compression" json:"compression"`
+	GRPC                      GRPCConfig                `bson:"grpc" json:"grpc"`
+	SessionHeaders            SessionHeadersConfig      `bson:"session_headers" json:"session_headers"`
+	TrafficSamples            TrafficSamplesConfig      `bson:"traffic_samples" json:"traffic_samples"`
+	AsyncOperations           AsyncOperationsConfig     `bson:"async_operations" json:"async_operations"`
+	GatewayFederation         GatewayFederationConfig   `bson:"gateway_federation" json:"gateway_federation"`
+	Tracing                   TracingConfig             `bson:"tracing" json:"tracing"`
+	ConcurrencyLimit          ConcurrencyLimitConfig    `bson:"concurrency_limit" json:"concurrency_limit"`
+	Maintenance               MaintenanceConfig         `bson:"maintenance" json:"maintenance"`
+	ErrorTemplates            []ErrorTemplate           `bson:"error_templates" json:"error_templates"`
+	TokenExchange             TokenExchangeConfig       `bson:"token_exchange" json:"token_exchange"`
+	Logging                   LoggingConfig             `bson:"logging" json:"logging"`
+	// MaxRequestBodySize is the maximum size in bytes of the request bodies, overriding the global
+	// limit of the gateway. Unlimited if 0 and there is no global limit, -1 disables the global limit.
+	MaxRequestBodySize int64 `bson:"max_request_body_size" json:"max_request_body_size"`
 }
 
 type UptimeTests struct {
@@ -577,6 +760,234 @@ type GlobalRateLimit struct {
 	Per  float64 `bson:"per" json:"per"`
 }
 
+// WebhookSubscriptions lets API consumers register callback URLs which receive events published by the upstream.
+type WebhookSubscriptions struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Path is the path, relative to the listen path, where consumers manage their subscriptions.
+	Path string `bson:"path" json:"path"`
+	// MaxPerKey limits the number of subscriptions a single key can register, 0 means unlimited.
+	MaxPerKey int `bson:"max_per_key" json:"max_per_key"`
+	// AllowedHosts restricts callback URLs to the given hosts. When empty, callback URLs can't
+	// target loopback, private and link-local addresses.
+	AllowedHosts []string `bson:"allowed_hosts" json:"allowed_hosts"`
+	// AllowedEvents restricts the event types consumers can subscribe to, all events are allowed when empty.
+	AllowedEvents []string `bson:"allowed_events" json:"allowed_events"`
+	// MaxAttempts is the number of delivery attempts per event and subscriber.
+	MaxAttempts int `bson:"max_attempts" json:"max_attempts"`
+	// RetryBackoff is the delay in seconds before the first retry, doubled on each attempt.
+	RetryBackoff float64 `bson:"retry_backoff" json:"retry_backoff"`
+	// Timeout is the delivery request timeout in seconds.
+	Timeout float64 `bson:"timeout" json:"timeout"`
+}
+
+// AnalyticsSampling reduces the number of analytics records stored for high-throughput APIs.
+// Requests which failed are always recorded.
+type AnalyticsSampling struct {
+	// SampleRate records 1 in SampleRate successful requests, all of them are recorded when 0 or 1.
+	SampleRate int64 `bson:"sample_rate" json:"sample_rate"`
+	// SkipPaths lists path patterns, relative to the listen path, of requests like health checks
+	// which are not recorded when successful. Patterns match whole paths.
+	SkipPaths []string `bson:"skip_paths" json:"skip_paths"`
+}
+
+// KafkaConfig replaces the HTTP upstream by a Kafka topic. Requests passing the middleware chain are
+// published to Topic and, when ResponseTopic is set, the reply published there with the same
+// correlation ID is returned to the client.
+type KafkaConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Brokers are the addresses of the brokers used to discover the cluster.
+	Brokers  []string `bson:"brokers" json:"brokers"`
+	ClientID string   `bson:"client_id" json:"client_id"`
+	Topic    string   `bson:"topic" json:"topic"`
+	// KeyHeader is the request header whose value is used as the message key, messages have no
+	// key when empty.
+	KeyHeader string `bson:"key_header" json:"key_header"`
+	// ResponseTopic is the topic replies are consumed from. Requests are acknowledged with
+	// 202 Accepted once published when empty.
+	ResponseTopic string `bson:"response_topic" json:"response_topic"`
+	// ResponseTimeout is the time in seconds to wait for a reply, 30 seconds when 0.
+	ResponseTimeout float64 `bson:"response_timeout" json:"response_timeout"`
+	// UseSSL connects to the brokers over TLS.
+	UseSSL                bool `bson:"use_ssl" json:"use_ssl"`
+	SSLInsecureSkipVerify bool `bson:"ssl_insecure_skip_verify" json:"ssl_insecure_skip_verify"`
+	// SASLMechanism authenticates to the brokers, one of "plain", "scram-sha-256" and
+	// "scram-sha-512". No authentication when empty.
+	SASLMechanism string `bson:"sasl_mechanism" json:"sasl_mechanism"`
+	SASLUsername  string `bson:"sasl_username" json:"sasl_username"`
+	SASLPassword  string `bson:"sasl_password" json:"sasl_password"`
+}
+
+// OAuthConsentConfig lets the authorize endpoint of the embedded OAuth provider ask resource owners
+// to approve the scopes requested by clients. Approved scopes are recorded per user and client, so
+// consent is only asked again when a client requests new scopes.
+//
+// The resource owner is identified by a consent session, issued by the secret protected
+// /tyk/oauth/consent-session endpoint to the login system once it authenticated the user, and
+// passed to the authorize endpoint as the consent_session parameter. Requests without it are
+// redirected to the login redirect.
+type OAuthConsentConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// ServiceURL is called to decide on consent, a built-in consent page is rendered when empty.
+	ServiceURL string `bson:"service_url" json:"service_url"`
+	// ServiceTimeout is the timeout in seconds of consent service calls, 5 seconds when 0.
+	ServiceTimeout float64 `bson:"service_timeout" json:"service_timeout"`
+}
+
+// BrokerProxyConfig configures the MQTT and AMQP protocols, which proxy broker connections after
+// authenticating the session they open with the key (or certificate) of the client. The key is
+// the password of the session, or its username when there is no password.
+type BrokerProxyConfig struct {
+	// UpstreamUsername and UpstreamPassword replace the credentials of authenticated clients when
+	// opening the session with the upstream broker, the client credentials are kept when both are empty.
+	UpstreamUsername string `bson:"upstream_username" json:"upstream_username"`
+	UpstreamPassword string `bson:"upstream_password" json:"upstream_password"`
+}
+
+// GeoIPAccessConfig allows or denies requests to an API by the country of the client IP, resolved
+// with the MaxMind database of the gateway.
+type GeoIPAccessConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// AllowedCountries are ISO 3166-1 alpha-2 country codes. When set, requests from other
+	// countries, or from IPs without a known country, are denied.
+	AllowedCountries []string `bson:"allowed_countries" json:"allowed_countries"`
+	// BlockedCountries are ISO 3166-1 alpha-2 country codes denied access to the API.
+	BlockedCountries []string `bson:"blocked_countries" json:"blocked_countries"`
+}
+
+// BotDetectionConfig scores requests from 0 to 100 on how likely they are to come from a bot, using
+// header anomalies, known bad user agents and request bursts. Thresholds are disabled when 0.
+type BotDetectionConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// BadUserAgents are regular expressions matching the user agents of bad bots, in addition to
+	// common vulnerability scanners.
+	BadUserAgents []string `bson:"bad_user_agents" json:"bad_user_agents"`
+	// BurstRequests is the number of requests from an IP within BurstPeriod seconds above which
+	// requests are scored as a burst, bursts aren't detected when 0.
+	BurstRequests int64 `bson:"burst_requests" json:"burst_requests"`
+	BurstPeriod   int64 `bson:"burst_period" json:"burst_period"`
+	// TagThreshold is the score from which requests are tagged as `bot` in analytics and sent
+	// upstream with their score in the X-Tyk-Bot-Score header.
+	TagThreshold int `bson:"tag_threshold" json:"tag_threshold"`
+	// ThrottleThreshold is the score from which requests are delayed by ThrottleDelay milliseconds.
+	ThrottleThreshold int   `bson:"throttle_threshold" json:"throttle_threshold"`
+	ThrottleDelay     int64 `bson:"throttle_delay" json:"throttle_delay"`
+	// BlockThreshold is the score from which requests are rejected.
+	BlockThreshold int `bson:"block_threshold" json:"block_threshold"`
+}
+
+// HeaderLimitsConfig limits the request headers of the API, within the 1MB limit of the HTTP
+// server. Limits are disabled when 0.
+type HeaderLimitsConfig struct {
+	// MaxCount is the maximum number of request headers, a header sent several times counts
+	// once per value.
+	MaxCount int `bson:"max_count" json:"max_count"`
+	// MaxHeaderSize is the maximum size in bytes of the name and value of a header.
+	MaxHeaderSize int64 `bson:"max_header_size" json:"max_header_size"`
+	// MaxTotalSize is the maximum size in bytes of all the headers.
+	MaxTotalSize int64 `bson:"max_total_size" json:"max_total_size"`
+}
+
+// CompressionConfig compresses the upstream responses of the API with the encoding preferred by
+// the client among Algorithms, negotiated with its Accept-Encoding header. Responses already
+// encoded in a way the client doesn't accept are decompressed first.
+type CompressionConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Algorithms are the encodings responses are compressed with, in order of preference among
+	// br, zstd and gzip. Defaults to all of them in this order.
+	Algorithms []string `bson:"algorithms" json:"algorithms"`
+	// MinSize is the size in bytes below which responses aren't compressed, defaults to 1024.
+	MinSize int64 `bson:"min_size" json:"min_size"`
+	// ContentTypes are the media types of the responses to compress, `type/*` matching all its
+	// subtypes. Defaults to text, JSON, XML and JavaScript types.
+	ContentTypes []string `bson:"content_types" json:"content_types"`
+}
+
+// GRPCConfig controls the calls to the gRPC services proxied by the API. Once enabled, the server
+// reflection service of the upstream is blocked unless Reflection is enabled.
+type GRPCConfig struct {
+	Enabled    bool                 `bson:"enabled" json:"enabled"`
+	Reflection GRPCReflectionConfig `bson:"reflection" json:"reflection"`
+	// Descriptors is a base64 encoded FileDescriptorSet of the services of the upstream, as
+	// produced by `protoc --include_imports --descriptor_set_out`. When empty, the services are
+	// discovered with the reflection service of the upstream.
+	Descriptors string `bson:"descriptors" json:"descriptors"`
+	// KnownMethodsOnly rejects the calls to methods which aren't declared by the services.
+	KnownMethodsOnly bool `bson:"known_methods_only" json:"known_methods_only"`
+	// MethodACLs restrict methods to some keys. The first ACL matching a method applies, methods
+	// not matching any ACL are allowed.
+	MethodACLs []GRPCMethodACL `bson:"method_acls" json:"method_acls"`
+}
+
+// GRPCReflectionConfig passes the server reflection service through to the upstream.
+type GRPCReflectionConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// AllowedTags restricts reflection to the keys with one of these tags, e.g. `internal`.
+	// Reflection is allowed to all clients when empty.
+	AllowedTags []string `bson:"allowed_tags" json:"allowed_tags"`
+}
+
+// GRPCMethodACL allows a method to the keys with one of AllowedTags only.
+type GRPCMethodACL struct {
+	// Method is the full name of a method, `package.Service/Method`, or `package.Service/*` for
+	// all the methods of a service.
+	Method      string   `bson:"method" json:"method"`
+	AllowedTags []string `bson:"allowed_tags" json:"allowed_tags"`
+}
+
+// SessionHeadersConfig injects fields of the session of the key into the headers of the upstream
+// requests. Headers sent by the clients with the same names are replaced, or removed when their
+// template renders empty, so that upstreams can trust them.
+type SessionHeadersConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Headers maps header names to templates rendered with the session, e.g. `{{.Alias}}` or
+	// `{{.MetaData.tenant}}`. Available fields are Alias, OrgID, KeyHash, MetaData, Tags and
+	// Policies. Missing metadata fields render empty.
+	Headers map[string]string `bson:"headers" json:"headers"`
+}
+
+// TrafficSamplesConfig captures a sample of the request and response pairs of the API, masked
+// before they leave the gateway, and uploads them to the object storage of the gateway
+// configuration, e.g. to generate regression tests and load-test scenarios from real traffic.
+// The Authorization, Cookie and Set-Cookie headers and the auth header of the API are always masked.
+type TrafficSamplesConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// SampleRate captures 1 in SampleRate requests, all of them are captured when 0 or 1.
+	SampleRate int64 `bson:"sample_rate" json:"sample_rate"`
+	// MaskHeaders lists the request and response headers whose values are masked.
+	MaskHeaders []string `bson:"mask_headers" json:"mask_headers"`
+	// MaskQueryParams lists the query parameters whose values are masked.
+	MaskQueryParams []string `bson:"mask_query_params" json:"mask_query_params"`
+	// MaskRules mask the fields of the JSON request and response bodies, with the redact or hash
+	// actions. Bodies which aren't JSON are omitted when rules are set.
+	MaskRules []BodyMaskRule `bson:"mask_rules" json:"mask_rules"`
+	// MaxBodySize is the size in bytes above which bodies are omitted, defaults to 65536.
+	MaxBodySize int64 `bson:"max_body_size" json:"max_body_size"`
+}
+
+// SyntheticMonitoringConfig configures synthetic requests periodically sent through the public
+// interface of the gateway, so that they go through the full middleware chain of the API.
+type SyntheticMonitoringConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Interval is the number of seconds between two runs of the checks, defaults to 60.
+	Interval int64 `bson:"interval" json:"interval"`
+	// Key is the dedicated key sent in the auth header of the API with every check.
+	Key    string           `bson:"key" json:"key"`
+	Checks []SyntheticCheck `bson:"checks" json:"checks"`
+}
+
+// SyntheticCheck is a synthetic request, successful when answered with ExpectedStatus.
+type SyntheticCheck struct {
+	Name    string            `bson:"name" json:"name"`
+	Method  string            `bson:"method" json:"method"`
+	Path    string            `bson:"path" json:"path"`
+	Headers map[string]string `bson:"headers" json:"headers"`
+	Body    string            `bson:"body" json:"body"`
+	// ExpectedStatus defaults to 200.
+	ExpectedStatus int `bson:"expected_status" json:"expected_status"`
+	// Timeout is the number of seconds to wait for a response, defaults to 10.
+	Timeout int64 `bson:"timeout" json:"timeout"`
+}
+
 type BundleManifest struct {
 	FileList         []string          `bson:"file_list" json:"file_list"`
 	CustomMiddleware MiddlewareSection `bson:"custom_middleware" json:"custom_middleware"`
@@ -612,7 +1023,77 @@ type ProxyConfig struct {
 		SSLMaxVersion           uint16   `bson:"ssl_max_version" json:"ssl_max_version"`
 		SSLForceCommonNameCheck bool     `json:"ssl_force_common_name_check"`
 		ProxyURL                string   `bson:"proxy_url" json:"proxy_url"`
+		// EnableHTTP2 negotiates HTTP/2 with the TLS upstreams, as proxy_enable_http2 does for all APIs.
+		EnableHTTP2 bool `bson:"enable_http2" json:"enable_http2"`
+		// H2C sends the requests to the plaintext upstreams over HTTP/2, as the h2c:// target scheme does.
+		H2C bool `bson:"h2c" json:"h2c"`
+		// MaxIdleConnsPerHost overrides max_idle_conns_per_host of the gateway if set.
+		MaxIdleConnsPerHost int `bson:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+		// IdleConnTimeout is how long in seconds idle upstream connections are kept, forever if 0.
+		IdleConnTimeout float64 `bson:"idle_conn_timeout" json:"idle_conn_timeout"`
+		// TLSSessionCacheSize is the number of TLS sessions cached to resume them with the upstreams,
+		// sessions aren't resumed if 0.
+		TLSSessionCacheSize int `bson:"tls_session_cache_size" json:"tls_session_cache_size"`
 	} `bson:"transport" json:"transport"`
+	Canary            CanaryConfig            `bson:"canary" json:"canary"`
+	Retry             RetryConfig             `bson:"retry" json:"retry"`
+	ConsistentHashing ConsistentHashingConfig `bson:"consistent_hashing" json:"consistent_hashing"`
+}
+
+// CanaryConfig routes a share of the API traffic to an alternative upstream.
+// Requests carrying the configured header or cookie value are always sent to
+// the canary, the rest are split according to Weight.
+type CanaryConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// TargetURL is the upstream that receives canary traffic.
+	TargetURL string `bson:"target_url" json:"target_url"`
+	// Weight is the percentage (0-100) of requests routed to the canary.
+	Weight      int    `bson:"weight" json:"weight"`
+	HeaderName  string `bson:"header_name" json:"header_name"`
+	HeaderValue string `bson:"header_value" json:"header_value"`
+	CookieName  string `bson:"cookie_name" json:"cookie_name"`
+	CookieValue string `bson:"cookie_value" json:"cookie_value"`
+}
+
+// RetryConfig controls how failed upstream requests are retried.
+// The delay between attempts starts at InitialBackoff and is doubled on every
+// retry, capped at MaxBackoff.
+type RetryConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// MaxAttempts is the total number of upstream attempts, including the first one.
+	MaxAttempts int `bson:"max_attempts" json:"max_attempts"`
+	// InitialBackoff is the delay in seconds before the first retry.
+	InitialBackoff float64 `bson:"initial_backoff" json:"initial_backoff"`
+	// MaxBackoff is the maximum delay in seconds between two attempts.
+	MaxBackoff float64 `bson:"max_backoff" json:"max_backoff"`
+	// StatusCodes are the upstream response codes which trigger a retry.
+	StatusCodes []int `bson:"status_codes" json:"status_codes"`
+	// OnConnectionError retries requests which failed without an upstream response.
+	OnConnectionError bool `bson:"on_connection_error" json:"on_connection_error"`
+	// NonIdempotent allows retrying methods such as POST and PATCH.
+	NonIdempotent bool `bson:"non_idempotent" json:"non_idempotent"`
+}
+
+// Consistent hashing sources.
+const (
+	HashSourceKey    = "key"
+	HashSourceHeader = "header"
+	HashSourcePath   = "path"
+)
+
+// ConsistentHashingConfig makes the load balancer pick the upstream target by hashing a
+// request attribute, so requests of the same entity always reach the same target.
+// Requests without a value for the attribute fall back to round robin.
+type ConsistentHashingConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Source is the hashed attribute: `key` (key alias, or the key itself), `header` or `path`.
+	Source string `bson:"source" json:"source"`
+	// HeaderName is the request header hashed by the `header` source.
+	HeaderName string `bson:"header_name" json:"header_name"`
+	// PathPattern is a regular expression whose first capture group is hashed by the `path` source.
+	PathPattern string `bson:"path_pattern" json:"path_pattern"`
+	// Replicas is the number of points each target has on the hash ring, defaults to 100.
+	Replicas int `bson:"replicas" json:"replicas"`
 }
 
 type CORSConfig struct {
@@ -1093,7 +1574,8 @@ func DummyAPI() APIDefinition {
 	}
 }
 
-var Template = template.New("").Funcs(map[string]interface{}{
+// TemplateFuncs are the functions of Template, for the templates parsed with html/template.
+var TemplateFuncs = map[string]interface{}{
 	"jsonMarshal": func(v interface{}) (string, error) {
 		bs, err := json.Marshal(v)
 		return string(bs), err
@@ -1117,4 +1599,116 @@ var Template = template.New("").Funcs(map[string]interface{}{
 
 		return string(xmlValue), err
 	},
-})
+}
+
+var Template = template.New("").Funcs(TemplateFuncs)
+
+// AsyncOperationsConfig configures the operations of the async endpoints of the API.
+type AsyncOperationsConfig struct {
+	// Path is the path, relative to the listen path, where clients get an operation by ID,
+	// /operations if empty.
+	Path string `bson:"path" json:"path"`
+	// TTL is the number of seconds operations are kept for, a day if 0.
+	TTL int64 `bson:"ttl" json:"ttl"`
+}
+
+// GatewayFederationConfig configures how the API takes part in the federation of gateways.
+type GatewayFederationConfig struct {
+	// Forward marks the upstream as a federated gateway, the requests carry a signed hop header.
+	Forward bool `bson:"forward" json:"forward"`
+	// Require rejects the requests which don't come from a federated gateway.
+	Require bool `bson:"require" json:"require"`
+}
+
+// TracingConfig configures the tracing of the requests to the API.
+type TracingConfig struct {
+	// CaptureLogs adds the logs of the middleware processing a request to its span.
+	CaptureLogs bool `bson:"capture_logs" json:"capture_logs"`
+}
+
+// ConcurrencyLimitConfig caps the requests to the API processed at the same time, so an API with a
+// slow upstream can't use up the resources of the gateway shared with the other APIs.
+type ConcurrencyLimitConfig struct {
+	// MaxInFlight is the number of requests processed at the same time, unlimited if 0.
+	MaxInFlight int `bson:"max_in_flight" json:"max_in_flight"`
+	// QueueTimeout is the number of milliseconds a request over the limit waits for another one to
+	// finish before being rejected with 503, rejected right away if 0.
+	QueueTimeout int64 `bson:"queue_timeout" json:"queue_timeout"`
+}
+
+// ErrorTemplate is the template of the error responses of the gateway with a status code, in a
+// content type. Among the templates of the status code, the one of the content type accepted by
+// the client is used.
+type ErrorTemplate struct {
+	// StatusCode is the status of the error responses, any status if 0.
+	StatusCode int `bson:"status_code" json:"status_code"`
+	// ContentType is the content type of the rendered body, e.g. application/json, application/xml
+	// or text/html.
+	ContentType string `bson:"content_type" json:"content_type"`
+	// Body is the template of the body, rendered with the message and status code of the error, the
+	// API ID and name, the method and path of the request, the trace and request IDs and the context
+	// variables.
+	// The values of text/html templates are escaped automatically, the other templates escape them,
+	// e.g. with jsonMarshal or xmlMarshal.
+	Body string `bson:"body" json:"body"`
+}
+
+// TokenExchangeConfig exchanges the JWT of the requests, once validated, for a token issued for
+// the upstream by a security token service with an OAuth 2.0 token exchange (RFC 8693). The
+// exchanged tokens are cached per subject until they expire.
+type TokenExchangeConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// TokenEndpoint is the token endpoint of the security token service.
+	TokenEndpoint string `bson:"token_endpoint" json:"token_endpoint"`
+	// ClientID and ClientSecret authenticate the gateway with the security token service, with
+	// HTTP basic authentication. The gateway doesn't authenticate when ClientID is empty.
+	ClientID     string `bson:"client_id" json:"client_id"`
+	ClientSecret string `bson:"client_secret" json:"client_secret"`
+	// Audience is the logical name of the upstream the token is requested for.
+	Audience string `bson:"audience" json:"audience"`
+	// Resource is the URI of the upstream the token is requested for, when the service requires one.
+	Resource string   `bson:"resource" json:"resource"`
+	Scopes   []string `bson:"scopes" json:"scopes"`
+	// RequestedTokenType is the type of the token requested, an access token when empty.
+	RequestedTokenType string `bson:"requested_token_type" json:"requested_token_type"`
+	// Header is the upstream request header the token is sent with, as a bearer token in the
+	// Authorization header when empty.
+	Header string `bson:"header" json:"header"`
+	// Timeout is the timeout in seconds of the token exchange requests, 5 seconds when 0.
+	Timeout float64 `bson:"timeout" json:"timeout"`
+}
+
+// LoggingConfig sets the log level of the API, and the values redacted from its logs, its traces
+// and its debug captures.
+type LoggingConfig struct {
+	// Level is the log level of the API, one of error, warn, info or debug, the log level of the
+	// gateway when empty.
+	Level string `bson:"level" json:"level"`
+	// RedactHeaders lists the request and response headers whose values are redacted.
+	RedactHeaders []string `bson:"redact_headers" json:"redact_headers"`
+	// RedactQueryParams lists the query parameters whose values are redacted.
+	RedactQueryParams []string `bson:"redact_query_params" json:"redact_query_params"`
+	// RedactRules mask the fields of the JSON request and response bodies, with the redact or hash
+	// actions.
+	RedactRules []BodyMaskRule `bson:"redact_rules" json:"redact_rules"`
+}
+
+// MaintenanceConfig puts the API under maintenance, all its endpoints being responded with a
+// static response instead of being proxied.
+type MaintenanceConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// StatusCode is the status of the response, 503 if 0.
+	StatusCode int `bson:"status_code" json:"status_code"`
+	// Body is the template of the response body, rendered with the API ID and name, and the
+	// method and path of the request.
+	Body string `bson:"body" json:"body"`
+	// ContentType is the content type of the response, application/json if empty.
+	ContentType string `bson:"content_type" json:"content_type"`
+	// RetryAfter is the number of seconds sent in the Retry-After header, not sent if 0.
+	RetryAfter int64 `bson:"retry_after" json:"retry_after"`
+	// BypassHeader is the name of the request header letting requests through, to test the API
+	// during the maintenance.
+	BypassHeader string `bson:"bypass_header" json:"bypass_header"`
+	// BypassValue is the value of BypassHeader letting requests through, any value if empty.
+	BypassValue string `bson:"bypass_value" json:"bypass_value"`
+}
@@ -0,0 +1,351 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const PostmanSource APIImporterSource = "postman"
+
+const (
+	// PostmanSchema is the schema of the collections, exported collections are in this format.
+	PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+	// PostmanBaseURLVariable is the variable of the exported collections holding the gateway URL.
+	PostmanBaseURLVariable = "baseUrl"
+
+	postmanVersion = "1.0.0"
+)
+
+// Locations of the version names of the API definitions.
+const (
+	versionHeaderLocation   = "header"
+	versionURLParamLocation = "url-param"
+	versionURLLocation      = "url"
+)
+
+// PostmanCollection is a Postman collection in the v2.0 or v2.1 format.
+type PostmanCollection struct {
+	Info struct {
+		PostmanID   string `json:"_postman_id,omitempty"`
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Schema      string `json:"schema"`
+	} `json:"info"`
+	Item     []*PostmanItem    `json:"item"`
+	Variable []PostmanVariable `json:"variable,omitempty"`
+}
+
+// PostmanItem is a request of a collection, or a folder of requests.
+type PostmanItem struct {
+	Name     string            `json:"name"`
+	Item     []*PostmanItem    `json:"item,omitempty"`
+	Request  *PostmanRequest   `json:"request,omitempty"`
+	Response []PostmanResponse `json:"response,omitempty"`
+}
+
+type PostmanRequest struct {
+	Method string          `json:"method"`
+	Header []PostmanHeader `json:"header,omitempty"`
+	Body   *PostmanBody    `json:"body,omitempty"`
+	URL    PostmanURL      `json:"url"`
+}
+
+// UnmarshalJSON accepts the requests defined by their URL only, sent with GET.
+func (r *PostmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*r = PostmanRequest{Method: http.MethodGet, URL: PostmanURL{Raw: raw}}
+		return nil
+	}
+
+	type request PostmanRequest
+	return json.Unmarshal(data, (*request)(r))
+}
+
+type PostmanURL struct {
+	Raw   string            `json:"raw"`
+	Host  []string          `json:"host,omitempty"`
+	Path  []string          `json:"path,omitempty"`
+	Query []PostmanVariable `json:"query,omitempty"`
+}
+
+// UnmarshalJSON accepts the URLs defined as strings.
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = PostmanURL{Raw: raw}
+		return nil
+	}
+
+	type postmanURL PostmanURL
+	return json.Unmarshal(data, (*postmanURL)(u))
+}
+
+// path returns the path of the URL in the API definition format, with the Postman path
+// variables, e.g. :id, and the collection variables, e.g. {{id}}, turned into {id}.
+func (u PostmanURL) path() string {
+	segments := u.Path
+	if len(segments) == 0 {
+		raw := u.Raw
+		if i := strings.IndexAny(raw, "?#"); i >= 0 {
+			raw = raw[:i]
+		}
+		if i := strings.Index(raw, "://"); i >= 0 {
+			raw = raw[i+len("://"):]
+		}
+		// the first segment is the host, e.g. {{baseUrl}}
+		if i := strings.Index(raw, "/"); i >= 0 {
+			segments = strings.Split(raw[i+1:], "/")
+		}
+	}
+
+	converted := make([]string, len(segments))
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segment = "{" + segment[1:] + "}"
+		case strings.HasPrefix(segment, "{{") && strings.HasSuffix(segment, "}}"):
+			segment = "{" + strings.TrimSuffix(strings.TrimPrefix(segment, "{{"), "}}") + "}"
+		}
+		converted[i] = segment
+	}
+	return "/" + strings.Join(converted, "/")
+}
+
+type PostmanHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type PostmanBody struct {
+	Mode string `json:"mode"`
+	Raw  string `json:"raw,omitempty"`
+}
+
+type PostmanVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PostmanResponse is a sample response saved with a request, used for mocks.
+type PostmanResponse struct {
+	Name   string          `json:"name"`
+	Code   int             `json:"code"`
+	Header []PostmanHeader `json:"header,omitempty"`
+	Body   string          `json:"body"`
+}
+
+func (c *PostmanCollection) LoadFrom(r io.Reader) error {
+	return json.NewDecoder(r).Decode(&c)
+}
+
+// ConvertIntoApiVersion allows the requests of the collection, replying with their first sample
+// response when asMock is set.
+func (c *PostmanCollection) ConvertIntoApiVersion(asMock bool) (apidef.VersionInfo, error) {
+	versionInfo := apidef.VersionInfo{}
+	versionInfo.UseExtendedPaths = true
+	versionInfo.Name = postmanVersion
+
+	// endpoints are in the order of the collection, by path
+	endpoints := map[string]*apidef.EndPointMeta{}
+	var paths []string
+
+	var walk func(items []*PostmanItem)
+	walk = func(items []*PostmanItem) {
+		for _, item := range items {
+			walk(item.Item)
+			if item.Request == nil {
+				continue
+			}
+
+			path := item.Request.URL.path()
+			method := strings.ToUpper(item.Request.Method)
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			endpoint, ok := endpoints[path]
+			if !ok {
+				endpoint = &apidef.EndPointMeta{Path: path, MethodActions: map[string]apidef.EndpointMethodMeta{}}
+				endpoints[path] = endpoint
+				paths = append(paths, path)
+			}
+			if _, ok := endpoint.MethodActions[method]; ok {
+				log.Warningf("Duplicate request %s %s in the Postman collection, ignoring %q", method, path, item.Name)
+				continue
+			}
+
+			methodMeta := apidef.EndpointMethodMeta{Action: apidef.NoAction, Code: http.StatusOK}
+			if asMock {
+				methodMeta.Action = apidef.Reply
+				if len(item.Response) > 0 {
+					response := item.Response[0]
+					if response.Code > 0 {
+						methodMeta.Code = response.Code
+					}
+					methodMeta.Data = response.Body
+					if len(response.Header) > 0 {
+						methodMeta.Headers = make(map[string]string, len(response.Header))
+						for _, h := range response.Header {
+							methodMeta.Headers[h.Key] = h.Value
+						}
+					}
+				}
+			}
+			endpoint.MethodActions[method] = methodMeta
+		}
+	}
+	walk(c.Item)
+
+	if len(paths) == 0 {
+		return versionInfo, errors.New("no requests defined in the Postman collection")
+	}
+
+	versionInfo.ExtendedPaths.WhiteList = make([]apidef.EndPointMeta, 0, len(paths))
+	for _, path := range paths {
+		versionInfo.ExtendedPaths.WhiteList = append(versionInfo.ExtendedPaths.WhiteList, *endpoints[path])
+	}
+
+	return versionInfo, nil
+}
+
+func (c *PostmanCollection) InsertIntoAPIDefinitionAsVersion(version apidef.VersionInfo, def *apidef.APIDefinition, versionName string) error {
+	def.VersionData.NotVersioned = false
+	def.VersionData.Versions[versionName] = version
+	return nil
+}
+
+func (c *PostmanCollection) ToAPIDefinition(orgID, upstreamURL string, asMock bool) (*apidef.APIDefinition, error) {
+	ad := apidef.APIDefinition{
+		Name:             c.Info.Name,
+		Active:           true,
+		UseKeylessAccess: true,
+		APIID:            uuid.NewV4().String(),
+		OrgID:            orgID,
+	}
+	ad.VersionDefinition.Key = "version"
+	ad.VersionDefinition.Location = "header"
+	ad.VersionData.Versions = make(map[string]apidef.VersionInfo)
+	ad.Proxy.ListenPath = "/" + ad.APIID + "/"
+	ad.Proxy.StripListenPath = true
+	ad.Proxy.TargetURL = upstreamURL
+
+	versionData, err := c.ConvertIntoApiVersion(asMock)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.InsertIntoAPIDefinitionAsVersion(versionData, &ad, postmanVersion)
+	ad.VersionData.DefaultVersion = postmanVersion
+	return &ad, err
+}
+
+// NewPostmanCollection exports the allowed operations of the API definition to a collection, their
+// mock responses as sample responses. The requests are sent to the gateway URL held by the baseUrl
+// variable, baseURL. There is a folder per version when the API is versioned.
+func NewPostmanCollection(def *apidef.APIDefinition, baseURL string) *PostmanCollection {
+	c := &PostmanCollection{}
+	c.Info.PostmanID = def.APIID
+	c.Info.Name = def.Name
+	c.Info.Schema = PostmanSchema
+	c.Variable = []PostmanVariable{{Key: PostmanBaseURLVariable, Value: baseURL}}
+	c.Item = []*PostmanItem{}
+
+	versionNames := make([]string, 0, len(def.VersionData.Versions))
+	for name := range def.VersionData.Versions {
+		versionNames = append(versionNames, name)
+	}
+	sort.Strings(versionNames)
+
+	for _, name := range versionNames {
+		items := postmanItems(def, name, def.VersionData.Versions[name])
+		if def.VersionData.NotVersioned || len(versionNames) == 1 {
+			c.Item = append(c.Item, items...)
+			continue
+		}
+		c.Item = append(c.Item, &PostmanItem{Name: name, Item: items})
+	}
+
+	return c
+}
+
+func postmanItems(def *apidef.APIDefinition, versionName string, version apidef.VersionInfo) []*PostmanItem {
+	items := []*PostmanItem{}
+	for _, endpoint := range version.ExtendedPaths.WhiteList {
+		methods := make([]string, 0, len(endpoint.MethodActions))
+		for method := range endpoint.MethodActions {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			item := &PostmanItem{
+				Name:    method + " " + endpoint.Path,
+				Request: postmanRequest(def, versionName, method, endpoint.Path),
+			}
+
+			if action := endpoint.MethodActions[method]; action.Action == apidef.Reply {
+				response := PostmanResponse{Name: "Mock", Code: action.Code, Body: action.Data}
+				for key, value := range action.Headers {
+					response.Header = append(response.Header, PostmanHeader{Key: key, Value: value})
+				}
+				sort.Slice(response.Header, func(i, j int) bool {
+					return response.Header[i].Key < response.Header[j].Key
+				})
+				item.Response = []PostmanResponse{response}
+			}
+
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// postmanRequest returns the request to the endpoint path of the version, with the version name
+// where the API expects it.
+func postmanRequest(def *apidef.APIDefinition, versionName, method, path string) *PostmanRequest {
+	segments := []string{}
+	if !def.VersionData.NotVersioned && def.VersionDefinition.Location == versionURLLocation {
+		segments = append(segments, versionName)
+	}
+	for _, segment := range strings.Split(strings.Trim(def.Proxy.ListenPath, "/")+"/"+strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segment = ":" + strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		}
+		segments = append(segments, segment)
+	}
+
+	host := "{{" + PostmanBaseURLVariable + "}}"
+	request := &PostmanRequest{
+		Method: method,
+		URL: PostmanURL{
+			Raw:  host + "/" + strings.Join(segments, "/"),
+			Host: []string{host},
+			Path: segments,
+		},
+	}
+
+	if !def.VersionData.NotVersioned {
+		switch def.VersionDefinition.Location {
+		case versionHeaderLocation:
+			request.Header = []PostmanHeader{{Key: def.VersionDefinition.Key, Value: versionName}}
+		case versionURLParamLocation:
+			request.URL.Query = []PostmanVariable{{Key: def.VersionDefinition.Key, Value: versionName}}
+			request.URL.Raw += "?" + url.QueryEscape(def.VersionDefinition.Key) + "=" + url.QueryEscape(versionName)
+		}
+	}
+
+	return request
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const petsCollection = `{
+	"info": {
+		"name": "Pets",
+		"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+	},
+	"item": [
+		{
+			"name": "Pets",
+			"item": [
+				{
+					"name": "List pets",
+					"request": {
+						"method": "GET",
+						"url": {"raw": "{{baseUrl}}/pets?limit=10", "host": ["{{baseUrl}}"], "path": ["pets"]}
+					},
+					"response": [
+						{
+							"name": "OK",
+							"code": 200,
+							"header": [{"key": "Content-Type", "value": "application/json"}],
+							"body": "[{\"name\": \"Rex\"}]"
+						}
+					]
+				},
+				{
+					"name": "Create pet",
+					"request": {
+						"method": "POST",
+						"body": {"mode": "raw", "raw": "{\"name\": \"Rex\"}"},
+						"url": "https://pets.example.com/pets"
+					}
+				}
+			]
+		},
+		{
+			"name": "Get pet",
+			"request": {
+				"method": "GET",
+				"url": {"raw": "{{baseUrl}}/pets/:id/{{tag}}", "path": ["pets", ":id", "{{tag}}"]}
+			},
+			"response": [{"name": "Not found", "code": 404, "body": "{}"}]
+		},
+		{
+			"name": "Health",
+			"request": "{{baseUrl}}/health"
+		}
+	]
+}`
+
+func TestPostmanCollection_ToAPIDefinition(t *testing.T) {
+	imp, err := GetImporterForSource(PostmanSource)
+	require.NoError(t, err)
+	require.NoError(t, imp.LoadFrom(bytes.NewBufferString(petsCollection)))
+
+	def, err := imp.ToAPIDefinition("org", "http://upstream.com", true)
+	require.NoError(t, err)
+	assert.Equal(t, "Pets", def.Name)
+	assert.Equal(t, "http://upstream.com", def.Proxy.TargetURL)
+	assert.Equal(t, "1.0.0", def.VersionData.DefaultVersion)
+
+	allowed := def.VersionData.Versions["1.0.0"].ExtendedPaths.WhiteList
+	require.Len(t, allowed, 3)
+
+	assert.Equal(t, "/pets", allowed[0].Path)
+	assert.Equal(t, apidef.EndpointMethodMeta{
+		Action:  apidef.Reply,
+		Code:    200,
+		Data:    `[{"name": "Rex"}]`,
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}, allowed[0].MethodActions["GET"])
+	assert.Equal(t, apidef.EndpointMethodMeta{Action: apidef.Reply, Code: 200}, allowed[0].MethodActions["POST"])
+
+	assert.Equal(t, "/pets/{id}/{tag}", allowed[1].Path)
+	assert.Equal(t, 404, allowed[1].MethodActions["GET"].Code)
+
+	assert.Equal(t, "/health", allowed[2].Path)
+	assert.Contains(t, allowed[2].MethodActions, "GET")
+
+	t.Run("without mocks", func(t *testing.T) {
+		version, err := imp.ConvertIntoApiVersion(false)
+		require.NoError(t, err)
+		assert.Equal(t, apidef.EndpointMethodMeta{Action: apidef.NoAction, Code: 200},
+			version.ExtendedPaths.WhiteList[0].MethodActions["GET"])
+	})
+
+	t.Run("empty collection", func(t *testing.T) {
+		_, err := (&PostmanCollection{}).ConvertIntoApiVersion(false)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewPostmanCollection(t *testing.T) {
+	def := &apidef.APIDefinition{APIID: "pets", Name: "Pets"}
+	def.Proxy.ListenPath = "/pets-api/"
+	def.VersionDefinition.Location = "header"
+	def.VersionDefinition.Key = "x-api-version"
+	def.VersionData.Versions = map[string]apidef.VersionInfo{
+		"v1": {ExtendedPaths: apidef.ExtendedPathsSet{WhiteList: []apidef.EndPointMeta{{
+			Path: "/pets/{id}",
+			MethodActions: map[string]apidef.EndpointMethodMeta{
+				"GET":    {Action: apidef.Reply, Code: 200, Data: `{}`, Headers: map[string]string{"Content-Type": "application/json"}},
+				"DELETE": {Action: apidef.NoAction},
+			},
+		}}}},
+		"v2": {},
+	}
+
+	c := NewPostmanCollection(def, "http://gateway:8080")
+	assert.Equal(t, PostmanSchema, c.Info.Schema)
+	assert.Equal(t, []PostmanVariable{{Key: "baseUrl", Value: "http://gateway:8080"}}, c.Variable)
+	require.Len(t, c.Item, 2)
+	assert.Equal(t, "v1", c.Item[0].Name)
+	assert.Empty(t, c.Item[1].Item)
+
+	items := c.Item[0].Item
+	require.Len(t, items, 2)
+	assert.Equal(t, "DELETE", items[0].Request.Method)
+	assert.Empty(t, items[0].Response)
+
+	get := items[1]
+	assert.Equal(t, "{{baseUrl}}/pets-api/pets/:id", get.Request.URL.Raw)
+	assert.Equal(t, []PostmanHeader{{Key: "x-api-version", Value: "v1"}}, get.Request.Header)
+	assert.Equal(t, []PostmanResponse{{Name: "Mock", Code: 200, Body: `{}`,
+		Header: []PostmanHeader{{Key: "Content-Type", Value: "application/json"}}}}, get.Response)
+
+	t.Run("round trip", func(t *testing.T) {
+		def.VersionData.NotVersioned = true
+		delete(def.VersionData.Versions, "v2")
+
+		version, err := NewPostmanCollection(def, "").ConvertIntoApiVersion(true)
+		require.NoError(t, err)
+		require.Len(t, version.ExtendedPaths.WhiteList, 1)
+		assert.Equal(t, "/pets-api/pets/{id}", version.ExtendedPaths.WhiteList[0].Path)
+		assert.Equal(t, `{}`, version.ExtendedPaths.WhiteList[0].MethodActions["GET"].Data)
+	})
+}
@@ -1,10 +1,13 @@
 package importer
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/TykTechnologies/tyk/apidef"
@@ -22,12 +25,23 @@ func (*WSDLDef) SetServicePortMapping(input map[string]string) {
 	}
 }
 
+// SetAllowList restricts the API to the operations of the services.
+func (def *WSDLDef) SetAllowList(enabled bool) {
+	def.allowList = enabled
+}
+
+// SetXMLValidation validates the bodies of the SOAP operations against the schema of the WSDL types.
+func (def *WSDLDef) SetXMLValidation(enabled bool) {
+	def.validateXML = enabled
+}
+
 const (
 	NS_WSDL20 = "http://www.w3.org/ns/wsdl"
 	NS_WSDL   = "http://schemas.xmlsoap.org/wsdl/"
 	NS_SOAP   = "http://schemas.xmlsoap.org/wsdl/soap/"
 	NS_SOAP12 = "http://schemas.xmlsoap.org/wsdl/soap12/"
 	NS_HTTP   = "http://schemas.xmlsoap.org/wsdl/http/"
+	NS_XSD    = "http://www.w3.org/2001/XMLSchema"
 )
 
 const (
@@ -38,6 +52,11 @@ const (
 
 type WSDLDef struct {
 	Definition WSDL `xml:"http://schemas.xmlsoap.org/wsdl/ definitions"`
+
+	allowList   bool
+	validateXML bool
+	// schemas are the XML schemas of the types, standalone with the namespaces they inherit.
+	schemas []string
 }
 
 type WSDL struct {
@@ -269,7 +288,108 @@ func (op *WSDLOperation) UnmarshalXML(d *xml.Decoder, start xml.StartElement) er
 }
 
 func (s *WSDLDef) LoadFrom(r io.Reader) error {
-	return xml.NewDecoder(r).Decode(&s)
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return err
+	}
+
+	s.schemas, err = extractXMLSchemas(data)
+	return err
+}
+
+// extractXMLSchemas returns the XML schemas of the types of the WSDL document data. The namespace
+// declarations of their ancestors are copied onto them so that they can be used on their own.
+func extractXMLSchemas(data []byte) ([]string, error) {
+	var (
+		schemas []string
+		// scopes are the namespace declarations of the open elements.
+		scopes []map[string]string
+	)
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return schemas, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == NS_XSD && t.Name.Local == "schema" {
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+				schemas = append(schemas, inheritNamespaces(string(data[offset:d.InputOffset()]), t, scopes))
+				continue
+			}
+
+			scope := map[string]string{}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					scope[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					scope[""] = attr.Value
+				}
+			}
+			scopes = append(scopes, scope)
+		case xml.EndElement:
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+}
+
+// inheritNamespaces declares on the schema element start of raw the namespaces of scopes it doesn't
+// declare itself.
+func inheritNamespaces(raw string, start xml.StartElement, scopes []map[string]string) string {
+	declared := map[string]bool{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			declared[attr.Name.Local] = true
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			declared[""] = true
+		}
+	}
+
+	inherited := map[string]string{}
+	for _, scope := range scopes {
+		for prefix, uri := range scope {
+			if !declared[prefix] {
+				inherited[prefix] = uri
+			}
+		}
+	}
+	if len(inherited) == 0 {
+		return raw
+	}
+
+	prefixes := make([]string, 0, len(inherited))
+	for prefix := range inherited {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var decls strings.Builder
+	for _, prefix := range prefixes {
+		decls.WriteString(" xmlns")
+		if prefix != "" {
+			decls.WriteString(":" + prefix)
+		}
+		decls.WriteString(`="`)
+		xml.EscapeText(&decls, []byte(inherited[prefix]))
+		decls.WriteString(`"`)
+	}
+
+	// the declarations go right after the element name
+	nameEnd := strings.IndexAny(raw, " \t\r\n/>")
+	return raw[:nameEnd] + decls.String() + raw[nameEnd:]
 }
 
 func (def *WSDLDef) ToAPIDefinition(orgId, upstreamURL string, as_mock bool) (*apidef.APIDefinition, error) {
@@ -322,6 +442,12 @@ func (def *WSDLDef) ConvertIntoApiVersion(bool) (apidef.VersionInfo, error) {
 	var foundPort bool
 	var serviceCount int
 
+	if def.validateXML && len(def.schemas) == 0 {
+		log.Warning("No XML schema found in the WSDL types, XML validation skipped")
+	} else if def.validateXML && len(def.schemas) > 1 {
+		log.Warning("Several XML schemas found in the WSDL types, validating against the first one")
+	}
+
 	for _, service := range def.Definition.Services {
 		foundPort = false
 		if service.Name == "" {
@@ -399,6 +525,23 @@ func (def *WSDLDef) ConvertIntoApiVersion(bool) (apidef.VersionInfo, error) {
 					}
 
 					versionInfo.ExtendedPaths.URLRewrite = append(versionInfo.ExtendedPaths.URLRewrite, operationUrlRewrite)
+
+					if def.allowList {
+						versionInfo.ExtendedPaths.WhiteList = append(versionInfo.ExtendedPaths.WhiteList, apidef.EndPointMeta{
+							Path: path,
+							MethodActions: map[string]apidef.EndpointMethodMeta{
+								method: {Action: apidef.NoAction, Code: http.StatusOK},
+							},
+						})
+					}
+
+					if def.validateXML && binding.Protocol != PROT_HTTP && len(def.schemas) > 0 {
+						versionInfo.ExtendedPaths.ValidateXML = append(versionInfo.ExtendedPaths.ValidateXML, apidef.ValidateXMLMeta{
+							Path:   path,
+							Method: method,
+							Schema: def.schemas[0],
+						})
+					}
 				}
 
 				break
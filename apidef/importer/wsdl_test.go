@@ -2,7 +2,11 @@ package importer
 
 import (
 	"bytes"
+	"encoding/xml"
+	"strings"
 	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
 )
 
 type testWSDLInput struct {
@@ -294,6 +298,55 @@ func TestToAPIDefinition_WSDL(t *testing.T) {
 	}
 }
 
+func TestToAPIDefinition_WSDLAllowListAndValidation(t *testing.T) {
+	wsdl_imp := &WSDLDef{}
+	if err := wsdl_imp.LoadFrom(bytes.NewBufferString(holidayService)); err != nil {
+		t.Fatal(err)
+	}
+	wsdl_imp.SetServicePortMapping(map[string]string{"HolidayService2": "HolidayService2Soap"})
+	wsdl_imp.SetAllowList(true)
+	wsdl_imp.SetXMLValidation(true)
+
+	def, err := wsdl_imp.ToAPIDefinition("testOrg", "http://test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := def.VersionData.Versions["1.0.0"]
+	if len(v.ExtendedPaths.WhiteList) != 6 || len(v.ExtendedPaths.ValidateXML) != 6 {
+		t.Fatalf("Expected 6 allowed and validated operations, found %v and %v", len(v.ExtendedPaths.WhiteList), len(v.ExtendedPaths.ValidateXML))
+	}
+
+	allowed := v.ExtendedPaths.WhiteList[0]
+	if allowed.Path != "HolidayService2/GetCountriesAvailable" {
+		t.Fatalf("Unexpected allowed operation %s", allowed.Path)
+	}
+	if action, ok := allowed.MethodActions["POST"]; !ok || action.Action != apidef.NoAction {
+		t.Fatalf("Expected the operation to be allowed with POST, found %+v", allowed.MethodActions)
+	}
+
+	var schema struct {
+		XMLName         xml.Name
+		TargetNamespace string `xml:"targetNamespace,attr"`
+		Elements        []struct {
+			Name string `xml:"name,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"http://www.w3.org/2001/XMLSchema element"`
+	}
+	if err := xml.Unmarshal([]byte(v.ExtendedPaths.ValidateXML[0].Schema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.XMLName.Space != NS_XSD || schema.TargetNamespace != "http://www.holidaywebservice.com/HolidayService_v2/" {
+		t.Fatalf("Unexpected schema %s in %s", schema.XMLName.Space, schema.TargetNamespace)
+	}
+	if len(schema.Elements) == 0 {
+		t.Fatal("Expected the schema elements")
+	}
+	if !strings.Contains(v.ExtendedPaths.ValidateXML[0].Schema, `xmlns:tns="http://www.holidaywebservice.com/HolidayService_v2/"`) {
+		t.Fatal("Expected the schema to declare the inherited namespaces")
+	}
+}
+
 var holidayService string = `
 <?xml version="1.0" encoding="UTF-8"?>
 <wsdl:definitions xmlns:tm="http://microsoft.com/wsdl/mime/textMatching/" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" xmlns:mime="http://schemas.xmlsoap.org/wsdl/mime/" xmlns:tns="http://www.holidaywebservice.com/HolidayService_v2/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:s="http://www.w3.org/2001/XMLSchema" xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/" xmlns:http="http://schemas.xmlsoap.org/wsdl/http/" targetNamespace="http://www.holidaywebservice.com/HolidayService_v2/" xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/">
@@ -7,6 +7,9 @@ import (
 type Middleware struct {
 	// Global contains the configurations related to the global middleware.
 	Global *Global `bson:"global,omitempty" json:"global,omitempty"`
+	// Paths contains the configurations related to the endpoint middleware, keyed by path.
+	// Old API Definition: `version_data.versions["Default"].extended_paths`
+	Paths Paths `bson:"paths,omitempty" json:"paths,omitempty"`
 }
 
 func (m *Middleware) Fill(api apidef.APIDefinition) {
@@ -18,12 +21,32 @@ func (m *Middleware) Fill(api apidef.APIDefinition) {
 	if ShouldOmit(m.Global) {
 		m.Global = nil
 	}
+
+	if m.Paths == nil {
+		m.Paths = make(Paths)
+	}
+
+	m.Paths.Fill(api.VersionData.Versions["Default"].ExtendedPaths)
+	if ShouldOmit(m.Paths) {
+		m.Paths = nil
+	}
 }
 
 func (m *Middleware) ExtractTo(api *apidef.APIDefinition) {
 	if m.Global != nil {
 		m.Global.ExtractTo(api)
 	}
+
+	if m.Paths != nil {
+		if api.VersionData.Versions == nil {
+			api.VersionData.Versions = make(map[string]apidef.VersionInfo)
+		}
+
+		version := api.VersionData.Versions["Default"]
+		m.Paths.ExtractTo(&version.ExtendedPaths)
+		version.UseExtendedPaths = true
+		api.VersionData.Versions["Default"] = version
+	}
 }
 
 type Global struct {
@@ -31,6 +54,18 @@ type Global struct {
 	// Cache contains the configurations related to caching.
 	// Old API Definition: `cache_options`
 	Cache *Cache `bson:"cache,omitempty" json:"cache,omitempty"`
+	// TransformRequestHeaders contains the headers set on and removed from all the requests to the API.
+	// Old API Definition: `version_data.versions["Default"].global_headers` and `global_headers_remove`
+	TransformRequestHeaders *TransformRequestHeaders `bson:"transformRequestHeaders,omitempty" json:"transformRequestHeaders,omitempty"`
+	// TransformResponseHeaders contains the headers set on and removed from all the responses of the API.
+	// Old API Definition: `version_data.versions["Default"].global_response_headers` and `global_response_headers_remove`
+	TransformResponseHeaders *TransformResponseHeaders `bson:"transformResponseHeaders,omitempty" json:"transformResponseHeaders,omitempty"`
+	// DoNotTrack excludes all the requests to the API from analytics.
+	// Old API Definition: `do_not_track`
+	DoNotTrack bool `bson:"doNotTrack,omitempty" json:"doNotTrack,omitempty"`
+	// ErrorTemplates contains the templates of the error responses of the API, by status code and content type.
+	// Old API Definition: `error_templates`
+	ErrorTemplates ErrorTemplates `bson:"errorTemplates,omitempty" json:"errorTemplates,omitempty"`
 }
 
 func (g *Global) Fill(api apidef.APIDefinition) {
@@ -53,6 +88,38 @@ func (g *Global) Fill(api apidef.APIDefinition) {
 	if ShouldOmit(g.Cache) {
 		g.Cache = nil
 	}
+
+	g.DoNotTrack = api.DoNotTrack
+
+	g.ErrorTemplates.Fill(api.ErrorTemplates)
+
+	version := api.VersionData.Versions["Default"]
+
+	// Request headers
+	if g.TransformRequestHeaders == nil {
+		g.TransformRequestHeaders = &TransformRequestHeaders{}
+	}
+
+	g.TransformRequestHeaders.Fill(apidef.HeaderInjectionMeta{
+		AddHeaders:    version.GlobalHeaders,
+		DeleteHeaders: version.GlobalHeadersRemove,
+	})
+	if ShouldOmit(g.TransformRequestHeaders) {
+		g.TransformRequestHeaders = nil
+	}
+
+	// Response headers
+	if g.TransformResponseHeaders == nil {
+		g.TransformResponseHeaders = &TransformResponseHeaders{}
+	}
+
+	g.TransformResponseHeaders.Fill(apidef.HeaderInjectionMeta{
+		AddHeaders:    version.GlobalResponseHeaders,
+		DeleteHeaders: version.GlobalResponseHeadersRemove,
+	})
+	if ShouldOmit(g.TransformResponseHeaders) {
+		g.TransformResponseHeaders = nil
+	}
 }
 
 func (g *Global) ExtractTo(api *apidef.APIDefinition) {
@@ -63,6 +130,36 @@ func (g *Global) ExtractTo(api *apidef.APIDefinition) {
 	if g.Cache != nil {
 		g.Cache.ExtractTo(&api.CacheOptions)
 	}
+
+	api.DoNotTrack = g.DoNotTrack
+	g.ErrorTemplates.ExtractTo(&api.ErrorTemplates)
+
+	requestHeaders := g.TransformRequestHeaders != nil && g.TransformRequestHeaders.Enabled
+	responseHeaders := g.TransformResponseHeaders != nil && g.TransformResponseHeaders.Enabled
+	if !requestHeaders && !responseHeaders {
+		return
+	}
+
+	if api.VersionData.Versions == nil {
+		api.VersionData.Versions = make(map[string]apidef.VersionInfo)
+	}
+	version := api.VersionData.Versions["Default"]
+
+	if requestHeaders {
+		var meta apidef.HeaderInjectionMeta
+		g.TransformRequestHeaders.ExtractTo(&meta)
+		version.GlobalHeaders = meta.AddHeaders
+		version.GlobalHeadersRemove = meta.DeleteHeaders
+	}
+
+	if responseHeaders {
+		var meta apidef.HeaderInjectionMeta
+		g.TransformResponseHeaders.ExtractTo(&meta)
+		version.GlobalResponseHeaders = meta.AddHeaders
+		version.GlobalResponseHeadersRemove = meta.DeleteHeaders
+	}
+
+	api.VersionData.Versions["Default"] = version
 }
 
 type CORS struct {
@@ -125,6 +222,10 @@ type Cache struct {
 	// ControlTTLHeaderName is the response header which tells Tyk how long it is safe to cache the response for.
 	// Old API Definition: `cache_options.cache_control_ttl_header`
 	ControlTTLHeaderName string `bson:"controlTTLHeaderName,omitempty" json:"controlTTLHeaderName,omitempty"`
+	// CollapseRequests makes concurrent identical `GET` requests missing the cache share a single upstream request,
+	// all of them receiving its response.
+	// Old API Definition: `cache_options.collapse_requests`
+	CollapseRequests bool `bson:"collapseRequests,omitempty" json:"collapseRequests,omitempty"`
 }
 
 func (c *Cache) Fill(cache apidef.CacheOptions) {
@@ -135,6 +236,7 @@ func (c *Cache) Fill(cache apidef.CacheOptions) {
 	c.CacheByHeaders = cache.CacheByHeaders
 	c.EnableUpstreamCacheControl = cache.EnableUpstreamCacheControl
 	c.ControlTTLHeaderName = cache.CacheControlTTLHeader
+	c.CollapseRequests = cache.CollapseRequests
 }
 
 func (c *Cache) ExtractTo(cache *apidef.CacheOptions) {
@@ -145,4 +247,32 @@ func (c *Cache) ExtractTo(cache *apidef.CacheOptions) {
 	cache.CacheByHeaders = c.CacheByHeaders
 	cache.EnableUpstreamCacheControl = c.EnableUpstreamCacheControl
 	cache.CacheControlTTLHeader = c.ControlTTLHeaderName
+	cache.CollapseRequests = c.CollapseRequests
+}
+
+// ErrorTemplates are the templates of the error responses of the API.
+type ErrorTemplates []ErrorTemplate
+
+type ErrorTemplate struct {
+	// StatusCode is the status of the error responses the template is used for, any status if 0.
+	StatusCode int `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	// ContentType is the content type of the rendered body, negotiated with the `Accept` header of the request.
+	ContentType string `bson:"contentType" json:"contentType"` // required
+	// Body is the template of the body, rendered with the `Message`, `StatusCode`, `APIID`, `APIName`, `Method`, `Path`,
+	// `TraceID`, `SpanID`, `RequestID` and `ContextVars` of the error.
+	Body string `bson:"body" json:"body"` // required
+}
+
+func (e *ErrorTemplates) Fill(templates []apidef.ErrorTemplate) {
+	*e = nil
+	for _, t := range templates {
+		*e = append(*e, ErrorTemplate{StatusCode: t.StatusCode, ContentType: t.ContentType, Body: t.Body})
+	}
+}
+
+func (e ErrorTemplates) ExtractTo(templates *[]apidef.ErrorTemplate) {
+	*templates = nil
+	for _, t := range e {
+		*templates = append(*templates, apidef.ErrorTemplate{StatusCode: t.StatusCode, ContentType: t.ContentType, Body: t.Body})
+	}
 }
@@ -31,6 +31,71 @@ func TestGlobal(t *testing.T) {
 	assert.Equal(t, emptyGlobal, resultGlobal)
 }
 
+func TestGlobalTransformHeaders(t *testing.T) {
+	global := Global{
+		TransformRequestHeaders: &TransformRequestHeaders{
+			Enabled: true,
+			Remove:  []string{"Cookie"},
+			Add:     Headers{{Name: "X-Correlation-Id", Value: "$tyk_context.request_id"}},
+		},
+		TransformResponseHeaders: &TransformResponseHeaders{
+			Enabled: true,
+			Remove:  []string{"Server"},
+			Add:     Headers{{Name: "Strict-Transport-Security", Value: "max-age=31536000"}, {Name: "X-Frame-Options", Value: "DENY"}},
+		},
+	}
+
+	var convertedAPI apidef.APIDefinition
+	global.ExtractTo(&convertedAPI)
+
+	version := convertedAPI.VersionData.Versions["Default"]
+	assert.Equal(t, map[string]string{"X-Correlation-Id": "$tyk_context.request_id"}, version.GlobalHeaders)
+	assert.Equal(t, []string{"Cookie"}, version.GlobalHeadersRemove)
+	assert.Equal(t, map[string]string{"Strict-Transport-Security": "max-age=31536000", "X-Frame-Options": "DENY"}, version.GlobalResponseHeaders)
+	assert.Equal(t, []string{"Server"}, version.GlobalResponseHeadersRemove)
+
+	var resultGlobal Global
+	resultGlobal.Fill(convertedAPI)
+
+	assert.Equal(t, global, resultGlobal)
+}
+
+func TestGlobalDoNotTrack(t *testing.T) {
+	global := Global{DoNotTrack: true}
+
+	var convertedAPI apidef.APIDefinition
+	global.ExtractTo(&convertedAPI)
+
+	assert.True(t, convertedAPI.DoNotTrack)
+
+	var resultGlobal Global
+	resultGlobal.Fill(convertedAPI)
+
+	assert.Equal(t, global, resultGlobal)
+}
+
+func TestGlobalErrorTemplates(t *testing.T) {
+	global := Global{
+		ErrorTemplates: ErrorTemplates{
+			{StatusCode: 429, ContentType: "text/html", Body: "<p>{{ .Message | html }}</p>"},
+			{ContentType: "application/json", Body: `{"message": {{ jsonMarshal .Message }}}`},
+		},
+	}
+
+	var convertedAPI apidef.APIDefinition
+	global.ExtractTo(&convertedAPI)
+
+	assert.Equal(t, []apidef.ErrorTemplate{
+		{StatusCode: 429, ContentType: "text/html", Body: "<p>{{ .Message | html }}</p>"},
+		{ContentType: "application/json", Body: `{"message": {{ jsonMarshal .Message }}}`},
+	}, convertedAPI.ErrorTemplates)
+
+	var resultGlobal Global
+	resultGlobal.Fill(convertedAPI)
+
+	assert.Equal(t, global, resultGlobal)
+}
+
 func TestCORS(t *testing.T) {
 	var emptyCORS CORS
 
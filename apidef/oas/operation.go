@@ -0,0 +1,651 @@
+package oas
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// Paths is a mapping of API endpoints to Path plugin configurations.
+type Paths map[string]*Path
+
+func (ps Paths) Fill(ep apidef.ExtendedPathsSet) {
+	for _, timeout := range ep.HardTimeouts {
+		ps.operation(timeout.Path, timeout.Method).fillEnforceTimeout(timeout)
+	}
+
+	for _, validateXML := range ep.ValidateXML {
+		ps.operation(validateXML.Path, validateXML.Method).fillValidateXML(validateXML)
+	}
+
+	for _, deprecated := range ep.Deprecated {
+		ps.operation(deprecated.Path, deprecated.Method).fillDeprecation(deprecated)
+	}
+
+	for _, transformHeaders := range ep.TransformHeader {
+		ps.operation(transformHeaders.Path, transformHeaders.Method).fillTransformRequestHeaders(transformHeaders)
+	}
+
+	for _, urlRewrite := range ep.URLRewrite {
+		ps.operation(urlRewrite.Path, urlRewrite.Method).fillURLRewrite(urlRewrite)
+	}
+
+	for _, methodTransform := range ep.MethodTransforms {
+		ps.operation(methodTransform.Path, methodTransform.Method).fillTransformRequestMethod(methodTransform)
+	}
+
+	for _, internal := range ep.Internal {
+		ps.operation(internal.Path, internal.Method).Internal = &Internal{Enabled: true}
+	}
+
+	for _, doNotTrack := range ep.DoNotTrackEndpoints {
+		ps.operation(doNotTrack.Path, doNotTrack.Method).DoNotTrack = &DoNotTrack{Enabled: true}
+	}
+
+	for path, p := range ps {
+		if ShouldOmit(p) {
+			delete(ps, path)
+		}
+	}
+}
+
+func (ps Paths) ExtractTo(ep *apidef.ExtendedPathsSet) {
+	paths := make([]string, 0, len(ps))
+	for path := range ps {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		ps[path].extractTo(path, ep)
+	}
+}
+
+// operation returns the operation of the given path and method, creating it if needed.
+func (ps Paths) operation(path, method string) *Operation {
+	p, ok := ps[path]
+	if !ok {
+		p = &Path{}
+		ps[path] = p
+	}
+
+	op := p.getMethod(method)
+	if op == nil {
+		op = &Operation{}
+		p.setMethod(method, op)
+	}
+
+	return op
+}
+
+// Path holds plugin configurations for HTTP method verbs.
+type Path struct {
+	Delete  *Operation `bson:"DELETE,omitempty" json:"DELETE,omitempty"`
+	Get     *Operation `bson:"GET,omitempty" json:"GET,omitempty"`
+	Head    *Operation `bson:"HEAD,omitempty" json:"HEAD,omitempty"`
+	Options *Operation `bson:"OPTIONS,omitempty" json:"OPTIONS,omitempty"`
+	Patch   *Operation `bson:"PATCH,omitempty" json:"PATCH,omitempty"`
+	Post    *Operation `bson:"POST,omitempty" json:"POST,omitempty"`
+	Put     *Operation `bson:"PUT,omitempty" json:"PUT,omitempty"`
+	Trace   *Operation `bson:"TRACE,omitempty" json:"TRACE,omitempty"`
+	Connect *Operation `bson:"CONNECT,omitempty" json:"CONNECT,omitempty"`
+}
+
+var pathMethods = []string{
+	http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPatch,
+	http.MethodPost, http.MethodPut, http.MethodTrace, http.MethodConnect,
+}
+
+func (p *Path) getMethod(method string) *Operation {
+	switch method {
+	case http.MethodDelete:
+		return p.Delete
+	case http.MethodGet:
+		return p.Get
+	case http.MethodHead:
+		return p.Head
+	case http.MethodOptions:
+		return p.Options
+	case http.MethodPatch:
+		return p.Patch
+	case http.MethodPost:
+		return p.Post
+	case http.MethodPut:
+		return p.Put
+	case http.MethodTrace:
+		return p.Trace
+	case http.MethodConnect:
+		return p.Connect
+	}
+
+	return nil
+}
+
+func (p *Path) setMethod(method string, op *Operation) {
+	switch method {
+	case http.MethodDelete:
+		p.Delete = op
+	case http.MethodGet:
+		p.Get = op
+	case http.MethodHead:
+		p.Head = op
+	case http.MethodOptions:
+		p.Options = op
+	case http.MethodPatch:
+		p.Patch = op
+	case http.MethodPost:
+		p.Post = op
+	case http.MethodPut:
+		p.Put = op
+	case http.MethodTrace:
+		p.Trace = op
+	case http.MethodConnect:
+		p.Connect = op
+	}
+}
+
+func (p *Path) extractTo(path string, ep *apidef.ExtendedPathsSet) {
+	for _, method := range pathMethods {
+		if op := p.getMethod(method); op != nil {
+			op.extractTo(path, method, ep)
+		}
+	}
+}
+
+// Operation holds plugin configurations for a single endpoint.
+type Operation struct {
+	// EnforceTimeout contains the upstream timeouts applied to the endpoint.
+	// Old API Definition: `version_data.versions[].extended_paths.hard_timeouts`
+	EnforceTimeout *EnforceTimeout `bson:"enforceTimeout,omitempty" json:"enforceTimeout,omitempty"`
+	// ValidateXML contains the XML schema validating the request body of the endpoint.
+	// Old API Definition: `version_data.versions[].extended_paths.validate_xml`
+	ValidateXML *ValidateXML `bson:"validateXML,omitempty" json:"validateXML,omitempty"`
+	// Deprecation contains the deprecation and sunset dates of the endpoint. Operations marked
+	// `deprecated` in the OAS document are deprecated without dates.
+	// Old API Definition: `version_data.versions[].extended_paths.deprecated`
+	Deprecation *Deprecation `bson:"deprecation,omitempty" json:"deprecation,omitempty"`
+	// TransformRequestHeaders contains the headers set on and removed from the requests to the endpoint.
+	// Old API Definition: `version_data.versions[].extended_paths.transform_headers`
+	TransformRequestHeaders *TransformRequestHeaders `bson:"transformRequestHeaders,omitempty" json:"transformRequestHeaders,omitempty"`
+	// URLRewrite contains the rewrite of the URL of the requests to the endpoint.
+	// Old API Definition: `version_data.versions[].extended_paths.url_rewrites`
+	URLRewrite *URLRewrite `bson:"urlRewrite,omitempty" json:"urlRewrite,omitempty"`
+	// TransformRequestMethod contains the method the requests to the endpoint are sent upstream with.
+	// Old API Definition: `version_data.versions[].extended_paths.method_transforms`
+	TransformRequestMethod *TransformRequestMethod `bson:"transformRequestMethod,omitempty" json:"transformRequestMethod,omitempty"`
+	// Internal makes the endpoint reachable only by looping, from `tyk://` URLs.
+	// Old API Definition: `version_data.versions[].extended_paths.internal`
+	Internal *Internal `bson:"internal,omitempty" json:"internal,omitempty"`
+	// DoNotTrack excludes the requests to the endpoint from analytics, e.g. health checks or static assets.
+	// Old API Definition: `version_data.versions[].extended_paths.do_not_track_endpoints`
+	DoNotTrack *DoNotTrack `bson:"doNotTrack,omitempty" json:"doNotTrack,omitempty"`
+}
+
+func (o *Operation) fillEnforceTimeout(meta apidef.HardTimeoutMeta) {
+	if o.EnforceTimeout == nil {
+		o.EnforceTimeout = &EnforceTimeout{}
+	}
+
+	o.EnforceTimeout.Fill(meta)
+	if ShouldOmit(o.EnforceTimeout) {
+		o.EnforceTimeout = nil
+	}
+}
+
+func (o *Operation) fillValidateXML(meta apidef.ValidateXMLMeta) {
+	if o.ValidateXML == nil {
+		o.ValidateXML = &ValidateXML{}
+	}
+
+	o.ValidateXML.Fill(meta)
+	if ShouldOmit(o.ValidateXML) {
+		o.ValidateXML = nil
+	}
+}
+
+func (o *Operation) fillDeprecation(meta apidef.DeprecatedMeta) {
+	if o.Deprecation == nil {
+		o.Deprecation = &Deprecation{}
+	}
+
+	o.Deprecation.Fill(meta)
+	if ShouldOmit(o.Deprecation) {
+		o.Deprecation = nil
+	}
+}
+
+func (o *Operation) fillTransformRequestHeaders(meta apidef.HeaderInjectionMeta) {
+	if o.TransformRequestHeaders == nil {
+		o.TransformRequestHeaders = &TransformRequestHeaders{}
+	}
+
+	o.TransformRequestHeaders.Fill(meta)
+	if ShouldOmit(o.TransformRequestHeaders) {
+		o.TransformRequestHeaders = nil
+	}
+}
+
+func (o *Operation) fillURLRewrite(meta apidef.URLRewriteMeta) {
+	if o.URLRewrite == nil {
+		o.URLRewrite = &URLRewrite{}
+	}
+
+	o.URLRewrite.Fill(meta)
+	if ShouldOmit(o.URLRewrite) {
+		o.URLRewrite = nil
+	}
+}
+
+func (o *Operation) fillTransformRequestMethod(meta apidef.MethodTransformMeta) {
+	if o.TransformRequestMethod == nil {
+		o.TransformRequestMethod = &TransformRequestMethod{}
+	}
+
+	o.TransformRequestMethod.Fill(meta)
+	if ShouldOmit(o.TransformRequestMethod) {
+		o.TransformRequestMethod = nil
+	}
+}
+
+func (o *Operation) extractTo(path, method string, ep *apidef.ExtendedPathsSet) {
+	if o.EnforceTimeout != nil && o.EnforceTimeout.Enabled {
+		meta := apidef.HardTimeoutMeta{Path: path, Method: method}
+		o.EnforceTimeout.ExtractTo(&meta)
+		ep.HardTimeouts = append(ep.HardTimeouts, meta)
+	}
+
+	if o.ValidateXML != nil && o.ValidateXML.Enabled {
+		meta := apidef.ValidateXMLMeta{Path: path, Method: method}
+		o.ValidateXML.ExtractTo(&meta)
+		ep.ValidateXML = append(ep.ValidateXML, meta)
+	}
+
+	if o.Deprecation != nil && o.Deprecation.Enabled {
+		meta := apidef.DeprecatedMeta{Path: path, Method: method}
+		o.Deprecation.ExtractTo(&meta)
+		ep.Deprecated = append(ep.Deprecated, meta)
+	}
+
+	if o.TransformRequestHeaders != nil && o.TransformRequestHeaders.Enabled {
+		meta := apidef.HeaderInjectionMeta{Path: path, Method: method}
+		o.TransformRequestHeaders.ExtractTo(&meta)
+		ep.TransformHeader = append(ep.TransformHeader, meta)
+	}
+
+	if o.URLRewrite != nil && o.URLRewrite.Enabled {
+		meta := apidef.URLRewriteMeta{Path: path, Method: method}
+		o.URLRewrite.ExtractTo(&meta)
+		ep.URLRewrite = append(ep.URLRewrite, meta)
+	}
+
+	if o.TransformRequestMethod != nil && o.TransformRequestMethod.Enabled {
+		meta := apidef.MethodTransformMeta{Path: path, Method: method}
+		o.TransformRequestMethod.ExtractTo(&meta)
+		ep.MethodTransforms = append(ep.MethodTransforms, meta)
+	}
+
+	if o.Internal != nil && o.Internal.Enabled {
+		ep.Internal = append(ep.Internal, apidef.InternalMeta{Path: path, Method: method})
+	}
+
+	if o.DoNotTrack != nil && o.DoNotTrack.Enabled {
+		ep.DoNotTrackEndpoints = append(ep.DoNotTrackEndpoints, apidef.TrackEndpointMeta{Path: path, Method: method})
+	}
+}
+
+type EnforceTimeout struct {
+	// Enabled enables the upstream timeouts of the endpoint.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Value is the time in seconds to wait for the upstream response headers.
+	// Old API Definition: `hard_timeouts[].timeout`
+	Value int `bson:"value,omitempty" json:"value,omitempty"`
+	// ConnectValue is the time in seconds to wait for a connection to the upstream, defaults to Value.
+	// Old API Definition: `hard_timeouts[].connect_timeout`
+	ConnectValue int `bson:"connectValue,omitempty" json:"connectValue,omitempty"`
+}
+
+func (et *EnforceTimeout) Fill(meta apidef.HardTimeoutMeta) {
+	et.Enabled = meta.TimeOut > 0 || meta.ConnectTimeOut > 0
+	et.Value = meta.TimeOut
+	et.ConnectValue = meta.ConnectTimeOut
+}
+
+func (et *EnforceTimeout) ExtractTo(meta *apidef.HardTimeoutMeta) {
+	meta.TimeOut = et.Value
+	meta.ConnectTimeOut = et.ConnectValue
+}
+
+type ValidateXML struct {
+	// Enabled enables the XML schema validation of the endpoint.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Schema is the XSD document the request body is validated against.
+	// Old API Definition: `validate_xml[].schema`
+	Schema string `bson:"schema,omitempty" json:"schema,omitempty"`
+	// ErrorResponseCode is the response code of invalid requests, defaults to 422.
+	// Old API Definition: `validate_xml[].error_response_code`
+	ErrorResponseCode int `bson:"errorResponseCode,omitempty" json:"errorResponseCode,omitempty"`
+}
+
+func (v *ValidateXML) Fill(meta apidef.ValidateXMLMeta) {
+	v.Enabled = meta.Schema != ""
+	v.Schema = meta.Schema
+	v.ErrorResponseCode = meta.ErrorResponseCode
+}
+
+func (v *ValidateXML) ExtractTo(meta *apidef.ValidateXMLMeta) {
+	meta.Schema = v.Schema
+	meta.ErrorResponseCode = v.ErrorResponseCode
+}
+
+type Deprecation struct {
+	// Enabled enables the deprecation headers of the endpoint.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Date is when the endpoint was deprecated, in RFC 3339 format.
+	// Old API Definition: `deprecated[].date`
+	Date string `bson:"date,omitempty" json:"date,omitempty"`
+	// Sunset is the date after which requests to the endpoint are blocked, in RFC 3339 format.
+	// Old API Definition: `deprecated[].sunset`
+	Sunset string `bson:"sunset,omitempty" json:"sunset,omitempty"`
+	// Link is the URL of the documentation of the deprecation.
+	// Old API Definition: `deprecated[].link`
+	Link string `bson:"link,omitempty" json:"link,omitempty"`
+}
+
+func (d *Deprecation) Fill(meta apidef.DeprecatedMeta) {
+	d.Enabled = !meta.Disabled
+	d.Date = meta.Date
+	d.Sunset = meta.Sunset
+	d.Link = meta.Link
+}
+
+func (d *Deprecation) ExtractTo(meta *apidef.DeprecatedMeta) {
+	meta.Disabled = !d.Enabled
+	meta.Date = d.Date
+	meta.Sunset = d.Sunset
+	meta.Link = d.Link
+}
+
+type TransformRequestHeaders struct {
+	// Enabled enables the request header transformations.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Remove contains the names of the headers removed from the request.
+	// Old API Definition: `transform_headers[].delete_headers`
+	Remove []string `bson:"remove,omitempty" json:"remove,omitempty"`
+	// Add contains the headers set on the request, replacing their current values. Values may contain context
+	// variables, e.g. `$tyk_context.request_id`, when context variables are enabled.
+	// Old API Definition: `transform_headers[].add_headers`
+	Add Headers `bson:"add,omitempty" json:"add,omitempty"`
+}
+
+func (t *TransformRequestHeaders) Fill(meta apidef.HeaderInjectionMeta) {
+	t.Enabled = len(meta.DeleteHeaders) > 0 || len(meta.AddHeaders) > 0
+	t.Remove = meta.DeleteHeaders
+	t.Add = NewHeaders(meta.AddHeaders)
+}
+
+func (t *TransformRequestHeaders) ExtractTo(meta *apidef.HeaderInjectionMeta) {
+	meta.DeleteHeaders = t.Remove
+	meta.AddHeaders = t.Add.Map()
+}
+
+type TransformResponseHeaders struct {
+	// Enabled enables the response header transformations.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Remove contains the names of the headers removed from the response.
+	// Old API Definition: `transform_response_headers[].delete_headers`
+	Remove []string `bson:"remove,omitempty" json:"remove,omitempty"`
+	// Add contains the headers set on the response, replacing their current values. Values may
+	// contain context variables, e.g. `$tyk_context.request_id`, when context variables are enabled.
+	// Old API Definition: `transform_response_headers[].add_headers`
+	Add Headers `bson:"add,omitempty" json:"add,omitempty"`
+}
+
+func (t *TransformResponseHeaders) Fill(meta apidef.HeaderInjectionMeta) {
+	t.Enabled = len(meta.DeleteHeaders) > 0 || len(meta.AddHeaders) > 0
+	t.Remove = meta.DeleteHeaders
+	t.Add = NewHeaders(meta.AddHeaders)
+}
+
+func (t *TransformResponseHeaders) ExtractTo(meta *apidef.HeaderInjectionMeta) {
+	meta.DeleteHeaders = t.Remove
+	meta.AddHeaders = t.Add.Map()
+}
+
+type URLRewrite struct {
+	// Enabled enables the URL rewrite of the endpoint.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Pattern is the regular expression matching the path of the request, relative to the listen path.
+	// Old API Definition: `url_rewrites[].match_pattern`
+	Pattern string `bson:"pattern,omitempty" json:"pattern,omitempty"`
+	// RewriteTo is the URL requests matching Pattern are rewritten to, unless a trigger matches. It
+	// may reference the groups of Pattern as `$1`, `$2`... and context variables. `tyk://` URLs
+	// loop the request to another API, e.g. `tyk://self/path` or `tyk://<api-id>/path`.
+	// Old API Definition: `url_rewrites[].rewrite_to`
+	RewriteTo string `bson:"rewriteTo,omitempty" json:"rewriteTo,omitempty"`
+	// Triggers are checked in order, the first matching trigger rewrites the request to its own URL.
+	// Old API Definition: `url_rewrites[].triggers`
+	Triggers []*URLRewriteTrigger `bson:"triggers,omitempty" json:"triggers,omitempty"`
+}
+
+func (u *URLRewrite) Fill(meta apidef.URLRewriteMeta) {
+	u.Enabled = meta.MatchPattern != ""
+	u.Pattern = meta.MatchPattern
+	u.RewriteTo = meta.RewriteTo
+
+	u.Triggers = nil
+	for _, trigger := range meta.Triggers {
+		t := &URLRewriteTrigger{}
+		t.Fill(trigger)
+		u.Triggers = append(u.Triggers, t)
+	}
+}
+
+func (u *URLRewrite) ExtractTo(meta *apidef.URLRewriteMeta) {
+	meta.MatchPattern = u.Pattern
+	meta.RewriteTo = u.RewriteTo
+
+	meta.Triggers = nil
+	for _, t := range u.Triggers {
+		var trigger apidef.RoutingTrigger
+		t.ExtractTo(&trigger)
+		meta.Triggers = append(meta.Triggers, trigger)
+	}
+}
+
+// Sources of the values checked by URL rewrite rules.
+const (
+	URLRewriteInHeader          = "header"
+	URLRewriteInQuery           = "query"
+	URLRewriteInPath            = "path"
+	URLRewriteInSessionMetadata = "sessionMetadata"
+	URLRewriteInRequestContext  = "requestContext"
+	URLRewriteInRequestBody     = "requestBody"
+)
+
+type URLRewriteTrigger struct {
+	// Condition is `all` when all the rules must match, or `any` when one of them is enough.
+	// Old API Definition: `triggers[].on`
+	Condition string `bson:"condition,omitempty" json:"condition,omitempty"`
+	// Rules contains the rules checked by the trigger.
+	// Old API Definition: `triggers[].options`
+	Rules []*URLRewriteRule `bson:"rules,omitempty" json:"rules,omitempty"`
+	// RewriteTo is the URL the requests matching the trigger are rewritten to.
+	// Old API Definition: `triggers[].rewrite_to`
+	RewriteTo string `bson:"rewriteTo,omitempty" json:"rewriteTo,omitempty"`
+}
+
+func (t *URLRewriteTrigger) Fill(trigger apidef.RoutingTrigger) {
+	t.Condition = string(trigger.On)
+	t.RewriteTo = trigger.RewriteTo
+
+	t.Rules = nil
+	options := trigger.Options
+	for _, matches := range []struct {
+		in      string
+		matches map[string]apidef.StringRegexMap
+	}{
+		{URLRewriteInHeader, options.HeaderMatches},
+		{URLRewriteInQuery, options.QueryValMatches},
+		{URLRewriteInPath, options.PathPartMatches},
+		{URLRewriteInSessionMetadata, options.SessionMetaMatches},
+		{URLRewriteInRequestContext, options.RequestContextMatches},
+	} {
+		names := make([]string, 0, len(matches.matches))
+		for name := range matches.matches {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			match := matches.matches[name]
+			t.Rules = append(t.Rules, &URLRewriteRule{In: matches.in, Name: name, Pattern: match.MatchPattern, Negate: match.Reverse})
+		}
+	}
+
+	if options.PayloadMatches.MatchPattern != "" {
+		t.Rules = append(t.Rules, &URLRewriteRule{
+			In:      URLRewriteInRequestBody,
+			Pattern: options.PayloadMatches.MatchPattern,
+			Negate:  options.PayloadMatches.Reverse,
+		})
+	}
+}
+
+func (t *URLRewriteTrigger) ExtractTo(trigger *apidef.RoutingTrigger) {
+	trigger.On = apidef.RoutingTriggerOnType(t.Condition)
+	trigger.RewriteTo = t.RewriteTo
+
+	trigger.Options = apidef.RoutingTriggerOptions{}
+	options := &trigger.Options
+	for _, rule := range t.Rules {
+		match := apidef.StringRegexMap{MatchPattern: rule.Pattern, Reverse: rule.Negate}
+
+		var matches *map[string]apidef.StringRegexMap
+		switch rule.In {
+		case URLRewriteInHeader:
+			matches = &options.HeaderMatches
+		case URLRewriteInQuery:
+			matches = &options.QueryValMatches
+		case URLRewriteInPath:
+			matches = &options.PathPartMatches
+		case URLRewriteInSessionMetadata:
+			matches = &options.SessionMetaMatches
+		case URLRewriteInRequestContext:
+			matches = &options.RequestContextMatches
+		case URLRewriteInRequestBody:
+			options.PayloadMatches = match
+			continue
+		default:
+			continue
+		}
+
+		if *matches == nil {
+			*matches = make(map[string]apidef.StringRegexMap)
+		}
+		(*matches)[rule.Name] = match
+	}
+}
+
+type URLRewriteRule struct {
+	// In is where the checked value comes from: `header`, `query`, `path`, `sessionMetadata`,
+	// `requestContext` or `requestBody`.
+	In string `bson:"in" json:"in"`
+	// Name is the name of the header, query parameter, session metadata or context variable. Path
+	// rules match the parts of the path, their name only labels them. Unused for `requestBody`.
+	Name string `bson:"name,omitempty" json:"name,omitempty"`
+	// Pattern is the regular expression the value must match.
+	Pattern string `bson:"pattern" json:"pattern"`
+	// Negate makes the rule match when the value doesn't match Pattern.
+	Negate bool `bson:"negate,omitempty" json:"negate,omitempty"`
+}
+
+type TransformRequestMethod struct {
+	// Enabled enables the method transformation of the endpoint.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// ToMethod is the method the requests are sent upstream with, e.g. `POST`.
+	// Old API Definition: `method_transforms[].to_method`
+	ToMethod string `bson:"toMethod,omitempty" json:"toMethod,omitempty"`
+}
+
+func (t *TransformRequestMethod) Fill(meta apidef.MethodTransformMeta) {
+	t.Enabled = meta.ToMethod != ""
+	t.ToMethod = meta.ToMethod
+}
+
+func (t *TransformRequestMethod) ExtractTo(meta *apidef.MethodTransformMeta) {
+	meta.ToMethod = t.ToMethod
+}
+
+type Internal struct {
+	// Enabled makes the endpoint internal.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+}
+
+type DoNotTrack struct {
+	// Enabled excludes the endpoint from analytics.
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+}
+
+// Header is a header name and value.
+type Header struct {
+	Name  string `bson:"name" json:"name"`
+	Value string `bson:"value" json:"value"`
+}
+
+// Headers is a list of headers.
+type Headers []Header
+
+// NewHeaders returns the headers of a map, sorted by name.
+func NewHeaders(m map[string]string) Headers {
+	if len(m) == 0 {
+		return nil
+	}
+
+	headers := make(Headers, 0, len(m))
+	for name, value := range m {
+		headers = append(headers, Header{Name: name, Value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Name < headers[j].Name
+	})
+	return headers
+}
+
+// Map returns the headers as a map of header names to values.
+func (hs Headers) Map() map[string]string {
+	if len(hs) == 0 {
+		return nil
+	}
+
+	m := make(map[string]string, len(hs))
+	for _, h := range hs {
+		m[h.Name] = h.Value
+	}
+	return m
+}
+
+// DeprecatedOperations returns the operations marked `deprecated` in the paths of an OAS document.
+func DeprecatedOperations(paths openapi3.Paths) []apidef.DeprecatedMeta {
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		names = append(names, path)
+	}
+	sort.Strings(names)
+
+	var deprecated []apidef.DeprecatedMeta
+	for _, path := range names {
+		item := paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range pathMethods {
+			if op := item.GetOperation(method); op != nil && op.Deprecated {
+				deprecated = append(deprecated, apidef.DeprecatedMeta{Path: path, Method: method})
+			}
+		}
+	}
+	return deprecated
+}
@@ -0,0 +1,253 @@
+package oas
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestPaths(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var emptyPaths Paths
+
+		var convertedEP apidef.ExtendedPathsSet
+		emptyPaths.ExtractTo(&convertedEP)
+
+		resultPaths := make(Paths)
+		resultPaths.Fill(convertedEP)
+
+		assert.Empty(t, resultPaths)
+	})
+
+	t.Run("filled", func(t *testing.T) {
+		paths := Paths{
+			"/users": {
+				Get:  &Operation{EnforceTimeout: &EnforceTimeout{Enabled: true, Value: 5, ConnectValue: 2}},
+				Post: &Operation{EnforceTimeout: &EnforceTimeout{Enabled: true, Value: 10}},
+			},
+			"/orders": {
+				Delete: &Operation{EnforceTimeout: &EnforceTimeout{Enabled: true, Value: 1}},
+				Put:    &Operation{ValidateXML: &ValidateXML{Enabled: true, Schema: "<xs:schema/>", ErrorResponseCode: 400}},
+				Get:    &Operation{Deprecation: &Deprecation{Enabled: true, Sunset: "2030-01-01T00:00:00Z"}},
+			},
+			"/orders/{id}": {
+				Put: &Operation{URLRewrite: &URLRewrite{Enabled: true, Pattern: "/orders/(.*)", RewriteTo: "/v2/orders/$1"}},
+				Post: &Operation{
+					TransformRequestMethod: &TransformRequestMethod{Enabled: true, ToMethod: http.MethodPut},
+					Internal:               &Internal{Enabled: true},
+					DoNotTrack:             &DoNotTrack{Enabled: true},
+				},
+				Get: &Operation{TransformRequestHeaders: &TransformRequestHeaders{
+					Enabled: true,
+					Remove:  []string{"Cookie"},
+					Add:     Headers{{Name: "X-Request-Id", Value: "$tyk_context.request_id"}, {Name: "X-Source", Value: "tyk"}},
+				}},
+			},
+		}
+
+		var convertedEP apidef.ExtendedPathsSet
+		paths.ExtractTo(&convertedEP)
+
+		assert.Equal(t, []apidef.HardTimeoutMeta{
+			{Path: "/orders", Method: http.MethodDelete, TimeOut: 1},
+			{Path: "/users", Method: http.MethodGet, TimeOut: 5, ConnectTimeOut: 2},
+			{Path: "/users", Method: http.MethodPost, TimeOut: 10},
+		}, convertedEP.HardTimeouts)
+		assert.Equal(t, []apidef.ValidateXMLMeta{
+			{Path: "/orders", Method: http.MethodPut, Schema: "<xs:schema/>", ErrorResponseCode: 400},
+		}, convertedEP.ValidateXML)
+		assert.Equal(t, []apidef.DeprecatedMeta{
+			{Path: "/orders", Method: http.MethodGet, Sunset: "2030-01-01T00:00:00Z"},
+		}, convertedEP.Deprecated)
+		assert.Equal(t, []apidef.HeaderInjectionMeta{
+			{
+				Path:          "/orders/{id}",
+				Method:        http.MethodGet,
+				DeleteHeaders: []string{"Cookie"},
+				AddHeaders:    map[string]string{"X-Request-Id": "$tyk_context.request_id", "X-Source": "tyk"},
+			},
+		}, convertedEP.TransformHeader)
+		assert.Equal(t, []apidef.URLRewriteMeta{
+			{Path: "/orders/{id}", Method: http.MethodPut, MatchPattern: "/orders/(.*)", RewriteTo: "/v2/orders/$1"},
+		}, convertedEP.URLRewrite)
+		assert.Equal(t, []apidef.MethodTransformMeta{
+			{Path: "/orders/{id}", Method: http.MethodPost, ToMethod: http.MethodPut},
+		}, convertedEP.MethodTransforms)
+		assert.Equal(t, []apidef.InternalMeta{
+			{Path: "/orders/{id}", Method: http.MethodPost},
+		}, convertedEP.Internal)
+		assert.Equal(t, []apidef.TrackEndpointMeta{
+			{Path: "/orders/{id}", Method: http.MethodPost},
+		}, convertedEP.DoNotTrackEndpoints)
+
+		resultPaths := make(Paths)
+		resultPaths.Fill(convertedEP)
+
+		assert.Equal(t, paths, resultPaths)
+	})
+}
+
+func TestEnforceTimeout(t *testing.T) {
+	var emptyEnforceTimeout EnforceTimeout
+
+	var convertedMeta apidef.HardTimeoutMeta
+	emptyEnforceTimeout.ExtractTo(&convertedMeta)
+
+	var resultEnforceTimeout EnforceTimeout
+	resultEnforceTimeout.Fill(convertedMeta)
+
+	assert.Equal(t, emptyEnforceTimeout, resultEnforceTimeout)
+}
+
+func TestValidateXML(t *testing.T) {
+	var emptyValidateXML ValidateXML
+
+	var convertedMeta apidef.ValidateXMLMeta
+	emptyValidateXML.ExtractTo(&convertedMeta)
+
+	var resultValidateXML ValidateXML
+	resultValidateXML.Fill(convertedMeta)
+
+	assert.Equal(t, emptyValidateXML, resultValidateXML)
+}
+
+func TestDeprecation(t *testing.T) {
+	var emptyDeprecation Deprecation
+
+	var convertedMeta apidef.DeprecatedMeta
+	emptyDeprecation.ExtractTo(&convertedMeta)
+
+	var resultDeprecation Deprecation
+	resultDeprecation.Fill(convertedMeta)
+
+	assert.Equal(t, emptyDeprecation, resultDeprecation)
+}
+
+func TestTransformRequestHeaders(t *testing.T) {
+	var emptyTransformRequestHeaders TransformRequestHeaders
+
+	var convertedMeta apidef.HeaderInjectionMeta
+	emptyTransformRequestHeaders.ExtractTo(&convertedMeta)
+
+	var resultTransformRequestHeaders TransformRequestHeaders
+	resultTransformRequestHeaders.Fill(convertedMeta)
+
+	assert.Equal(t, emptyTransformRequestHeaders, resultTransformRequestHeaders)
+}
+
+func TestTransformResponseHeaders(t *testing.T) {
+	var emptyTransformResponseHeaders TransformResponseHeaders
+
+	var convertedMeta apidef.HeaderInjectionMeta
+	emptyTransformResponseHeaders.ExtractTo(&convertedMeta)
+
+	var resultTransformResponseHeaders TransformResponseHeaders
+	resultTransformResponseHeaders.Fill(convertedMeta)
+
+	assert.Equal(t, emptyTransformResponseHeaders, resultTransformResponseHeaders)
+}
+
+func TestTransformRequestMethod(t *testing.T) {
+	var emptyTransformRequestMethod TransformRequestMethod
+
+	var convertedMeta apidef.MethodTransformMeta
+	emptyTransformRequestMethod.ExtractTo(&convertedMeta)
+
+	var resultTransformRequestMethod TransformRequestMethod
+	resultTransformRequestMethod.Fill(convertedMeta)
+
+	assert.Equal(t, emptyTransformRequestMethod, resultTransformRequestMethod)
+}
+
+func TestURLRewrite(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var emptyURLRewrite URLRewrite
+
+		var convertedMeta apidef.URLRewriteMeta
+		emptyURLRewrite.ExtractTo(&convertedMeta)
+
+		var resultURLRewrite URLRewrite
+		resultURLRewrite.Fill(convertedMeta)
+
+		assert.Equal(t, emptyURLRewrite, resultURLRewrite)
+	})
+
+	t.Run("triggers", func(t *testing.T) {
+		urlRewrite := URLRewrite{
+			Enabled:   true,
+			Pattern:   "/orders/(.*)",
+			RewriteTo: "/v2/orders/$1",
+			Triggers: []*URLRewriteTrigger{
+				{
+					Condition: "all",
+					Rules: []*URLRewriteRule{
+						{In: URLRewriteInHeader, Name: "X-Beta", Pattern: "true"},
+						{In: URLRewriteInQuery, Name: "region", Pattern: "eu-.*", Negate: true},
+						{In: URLRewriteInSessionMetadata, Name: "tier", Pattern: "gold"},
+					},
+					RewriteTo: "/beta/orders/$1",
+				},
+				{
+					Condition: "any",
+					Rules: []*URLRewriteRule{
+						{In: URLRewriteInPath, Name: "legacy", Pattern: "v1"},
+						{In: URLRewriteInRequestContext, Name: "remote_addr", Pattern: "^10\\."},
+						{In: URLRewriteInRequestBody, Pattern: "legacy"},
+					},
+					RewriteTo: "tyk://legacy-api/orders/$1",
+				},
+			},
+		}
+
+		var convertedMeta apidef.URLRewriteMeta
+		urlRewrite.ExtractTo(&convertedMeta)
+
+		assert.Equal(t, []apidef.RoutingTrigger{
+			{
+				On: apidef.All,
+				Options: apidef.RoutingTriggerOptions{
+					HeaderMatches:      map[string]apidef.StringRegexMap{"X-Beta": {MatchPattern: "true"}},
+					QueryValMatches:    map[string]apidef.StringRegexMap{"region": {MatchPattern: "eu-.*", Reverse: true}},
+					SessionMetaMatches: map[string]apidef.StringRegexMap{"tier": {MatchPattern: "gold"}},
+				},
+				RewriteTo: "/beta/orders/$1",
+			},
+			{
+				On: apidef.Any,
+				Options: apidef.RoutingTriggerOptions{
+					PathPartMatches:       map[string]apidef.StringRegexMap{"legacy": {MatchPattern: "v1"}},
+					RequestContextMatches: map[string]apidef.StringRegexMap{"remote_addr": {MatchPattern: "^10\\."}},
+					PayloadMatches:        apidef.StringRegexMap{MatchPattern: "legacy"},
+				},
+				RewriteTo: "tyk://legacy-api/orders/$1",
+			},
+		}, convertedMeta.Triggers)
+
+		var resultURLRewrite URLRewrite
+		resultURLRewrite.Fill(convertedMeta)
+
+		assert.Equal(t, urlRewrite, resultURLRewrite)
+	})
+}
+
+func TestDeprecatedOperations(t *testing.T) {
+	paths := openapi3.Paths{
+		"/users": &openapi3.PathItem{
+			Get:    &openapi3.Operation{Deprecated: true},
+			Delete: &openapi3.Operation{},
+		},
+		"/orders": &openapi3.PathItem{
+			Post: &openapi3.Operation{Deprecated: true},
+		},
+	}
+
+	assert.Equal(t, []apidef.DeprecatedMeta{
+		{Path: "/orders", Method: http.MethodPost},
+		{Path: "/users", Method: http.MethodGet},
+	}, DeprecatedOperations(paths))
+	assert.Empty(t, DeprecatedOperations(nil))
+}
@@ -37,16 +37,16 @@ func (x *XTykAPIGateway) ExtractTo(api *apidef.APIDefinition) {
 	x.Upstream.ExtractTo(api)
 	x.Server.ExtractTo(api)
 
-	if x.Middleware != nil {
-		x.Middleware.ExtractTo(api)
-	}
-
 	// This is used to make API calls work before actual versioning implementation.
 	api.VersionData.DefaultVersion = "Default"
 	api.VersionData.NotVersioned = true
 	api.VersionData.Versions = map[string]apidef.VersionInfo{
 		"Default": {},
 	}
+
+	if x.Middleware != nil {
+		x.Middleware.ExtractTo(api)
+	}
 }
 
 type Info struct {
@@ -11,6 +11,8 @@ type Server struct {
 	Slug string `bson:"slug,omitempty" json:"slug,omitempty"`
 	// Authentication contains the configurations related to authentication to the API.
 	Authentication *Authentication `bson:"authentication,omitempty" json:"authentication,omitempty"`
+	// IPAccessControl contains the IP addresses and CIDR ranges allowed and blocked on the API.
+	IPAccessControl *IPAccessControl `bson:"ipAccessControl,omitempty" json:"ipAccessControl,omitempty"`
 }
 
 func (s *Server) Fill(api apidef.APIDefinition) {
@@ -25,6 +27,15 @@ func (s *Server) Fill(api apidef.APIDefinition) {
 	if ShouldOmit(s.Authentication) {
 		s.Authentication = nil
 	}
+
+	if s.IPAccessControl == nil {
+		s.IPAccessControl = &IPAccessControl{}
+	}
+
+	s.IPAccessControl.Fill(api)
+	if ShouldOmit(s.IPAccessControl) {
+		s.IPAccessControl = nil
+	}
 }
 
 func (s *Server) ExtractTo(api *apidef.APIDefinition) {
@@ -36,6 +47,10 @@ func (s *Server) ExtractTo(api *apidef.APIDefinition) {
 	} else {
 		api.UseKeylessAccess = true
 	}
+
+	if s.IPAccessControl != nil {
+		s.IPAccessControl.ExtractTo(api)
+	}
 }
 
 type ListenPath struct {
@@ -58,3 +73,29 @@ func (lp *ListenPath) ExtractTo(api *apidef.APIDefinition) {
 	api.Proxy.ListenPath = lp.Value
 	api.Proxy.StripListenPath = lp.Strip
 }
+
+type IPAccessControl struct {
+	// Enabled enables the IP access control.
+	// Old API Definition: `enable_ip_whitelisting` and `enable_ip_blacklisting`
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// Allow is the list of IP addresses and CIDR ranges, IPv4 or IPv6, allowed to access the API.
+	// Any IP is allowed when empty.
+	// Old API Definition: `allowed_ips`
+	Allow []string `bson:"allow,omitempty" json:"allow,omitempty"`
+	// Block is the list of IP addresses and CIDR ranges, IPv4 or IPv6, blocked from accessing the API.
+	// Old API Definition: `blacklisted_ips`
+	Block []string `bson:"block,omitempty" json:"block,omitempty"`
+}
+
+func (i *IPAccessControl) Fill(api apidef.APIDefinition) {
+	i.Enabled = api.EnableIpWhiteListing || api.EnableIpBlacklisting
+	i.Allow = api.AllowedIPs
+	i.Block = api.BlacklistedIPs
+}
+
+func (i *IPAccessControl) ExtractTo(api *apidef.APIDefinition) {
+	api.EnableIpWhiteListing = i.Enabled && len(i.Allow) > 0
+	api.EnableIpBlacklisting = i.Enabled && len(i.Block) > 0
+	api.AllowedIPs = i.Allow
+	api.BlacklistedIPs = i.Block
+}
@@ -30,3 +30,50 @@ func TestListenPath(t *testing.T) {
 
 	assert.Equal(t, emptyListenPath, resultListenPath)
 }
+
+func TestIPAccessControl(t *testing.T) {
+	var emptyIPAccessControl IPAccessControl
+
+	var convertedAPI apidef.APIDefinition
+	emptyIPAccessControl.ExtractTo(&convertedAPI)
+
+	var resultIPAccessControl IPAccessControl
+	resultIPAccessControl.Fill(convertedAPI)
+
+	assert.Equal(t, emptyIPAccessControl, resultIPAccessControl)
+
+	t.Run("IPv6 ranges", func(t *testing.T) {
+		ipAccessControl := IPAccessControl{
+			Enabled: true,
+			Allow:   []string{"10.0.0.0/8", "2001:db8::/32"},
+			Block:   []string{"2001:db8::1"},
+		}
+
+		var convertedAPI apidef.APIDefinition
+		ipAccessControl.ExtractTo(&convertedAPI)
+		assert.True(t, convertedAPI.EnableIpWhiteListing)
+		assert.True(t, convertedAPI.EnableIpBlacklisting)
+
+		var resultIPAccessControl IPAccessControl
+		resultIPAccessControl.Fill(convertedAPI)
+
+		assert.Equal(t, ipAccessControl, resultIPAccessControl)
+	})
+
+	t.Run("blocked IPs only", func(t *testing.T) {
+		ipAccessControl := IPAccessControl{
+			Enabled: true,
+			Block:   []string{"10.0.0.1"},
+		}
+
+		var convertedAPI apidef.APIDefinition
+		ipAccessControl.ExtractTo(&convertedAPI)
+		assert.False(t, convertedAPI.EnableIpWhiteListing)
+		assert.True(t, convertedAPI.EnableIpBlacklisting)
+
+		var resultIPAccessControl IPAccessControl
+		resultIPAccessControl.Fill(convertedAPI)
+
+		assert.Equal(t, ipAccessControl, resultIPAccessControl)
+	})
+}
@@ -3,7 +3,8 @@ package oas
 import "github.com/TykTechnologies/tyk/apidef"
 
 type Upstream struct {
-	// URL defines the target URL that the request should be proxied to.
+	// URL defines the target URL that the request should be proxied to. A `tyk://` URL loops the
+	// request to another API instead, e.g. `tyk://<api-id>` or `tyk://<api-name>`.
 	// Old API Definition: `proxy.target_url`
 	URL string `bson:"url" json:"url"` // required
 	// ServiceDiscovery contains the configuration related to Service Discovery.
@@ -11,6 +12,9 @@ type Upstream struct {
 	ServiceDiscovery *ServiceDiscovery `bson:"serviceDiscovery,omitempty" json:"serviceDiscovery,omitempty"`
 	// Test contains the configuration related to uptime tests.
 	Test *Test `bson:"test,omitempty" json:"test,omitempty"`
+	// Retry contains the configuration related to retrying failed upstream requests.
+	// Old API Definition: `proxy.retry`
+	Retry *Retry `bson:"retry,omitempty" json:"retry,omitempty"`
 }
 
 func (u *Upstream) Fill(api apidef.APIDefinition) {
@@ -24,6 +28,15 @@ func (u *Upstream) Fill(api apidef.APIDefinition) {
 	if ShouldOmit(u.ServiceDiscovery) {
 		u.ServiceDiscovery = nil
 	}
+
+	if u.Retry == nil {
+		u.Retry = &Retry{}
+	}
+
+	u.Retry.Fill(api.Proxy.Retry)
+	if ShouldOmit(u.Retry) {
+		u.Retry = nil
+	}
 }
 
 func (u *Upstream) ExtractTo(api *apidef.APIDefinition) {
@@ -32,6 +45,10 @@ func (u *Upstream) ExtractTo(api *apidef.APIDefinition) {
 	if u.ServiceDiscovery != nil {
 		u.ServiceDiscovery.ExtractTo(&api.Proxy.ServiceDiscovery)
 	}
+
+	if u.Retry != nil {
+		u.Retry.ExtractTo(&api.Proxy.Retry)
+	}
 }
 
 type ServiceDiscovery struct {
@@ -152,3 +169,47 @@ func (t *Test) ExtractTo(uptimeTests *apidef.UptimeTests) {
 		t.ServiceDiscovery.ExtractTo(&uptimeTests.Config.ServiceDiscovery)
 	}
 }
+
+type Retry struct {
+	// Enabled enables retrying failed upstream requests.
+	// Old API Definition: `proxy.retry.enabled`
+	Enabled bool `bson:"enabled" json:"enabled"` // required
+	// MaxAttempts is the total number of upstream attempts, including the first one.
+	// Old API Definition: `proxy.retry.max_attempts`
+	MaxAttempts int `bson:"maxAttempts,omitempty" json:"maxAttempts,omitempty"`
+	// InitialBackoff is the delay in seconds before the first retry. It is doubled on every subsequent retry.
+	// Old API Definition: `proxy.retry.initial_backoff`
+	InitialBackoff float64 `bson:"initialBackoff,omitempty" json:"initialBackoff,omitempty"`
+	// MaxBackoff is the maximum delay in seconds between two attempts.
+	// Old API Definition: `proxy.retry.max_backoff`
+	MaxBackoff float64 `bson:"maxBackoff,omitempty" json:"maxBackoff,omitempty"`
+	// StatusCodes are the upstream response codes which trigger a retry.
+	// Old API Definition: `proxy.retry.status_codes`
+	StatusCodes []int `bson:"statusCodes,omitempty" json:"statusCodes,omitempty"`
+	// OnConnectionError retries requests which failed without an upstream response.
+	// Old API Definition: `proxy.retry.on_connection_error`
+	OnConnectionError bool `bson:"onConnectionError,omitempty" json:"onConnectionError,omitempty"`
+	// NonIdempotent allows retrying non-idempotent methods such as `POST` and `PATCH`.
+	// Old API Definition: `proxy.retry.non_idempotent`
+	NonIdempotent bool `bson:"nonIdempotent,omitempty" json:"nonIdempotent,omitempty"`
+}
+
+func (r *Retry) Fill(retry apidef.RetryConfig) {
+	r.Enabled = retry.Enabled
+	r.MaxAttempts = retry.MaxAttempts
+	r.InitialBackoff = retry.InitialBackoff
+	r.MaxBackoff = retry.MaxBackoff
+	r.StatusCodes = retry.StatusCodes
+	r.OnConnectionError = retry.OnConnectionError
+	r.NonIdempotent = retry.NonIdempotent
+}
+
+func (r *Retry) ExtractTo(retry *apidef.RetryConfig) {
+	retry.Enabled = r.Enabled
+	retry.MaxAttempts = r.MaxAttempts
+	retry.InitialBackoff = r.InitialBackoff
+	retry.MaxBackoff = r.MaxBackoff
+	retry.StatusCodes = r.StatusCodes
+	retry.OnConnectionError = r.OnConnectionError
+	retry.NonIdempotent = r.NonIdempotent
+}
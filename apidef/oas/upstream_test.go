@@ -42,3 +42,15 @@ func TestTest(t *testing.T) {
 
 	assert.Equal(t, emptyTest, resultTest)
 }
+
+func TestRetry(t *testing.T) {
+	var emptyRetry Retry
+
+	var convertedRetry apidef.RetryConfig
+	emptyRetry.ExtractTo(&convertedRetry)
+
+	var resultRetry Retry
+	resultRetry.Fill(convertedRetry)
+
+	assert.Equal(t, emptyRetry, resultRetry)
+}
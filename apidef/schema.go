@@ -214,6 +214,10 @@ const Schema = `{
                 },
                 "post": {
                     "type": ["array", "null"]
+                },
+                "jsvm_timeout": {
+                    "type": "integer",
+                    "minimum": 0
                 }
             }
         },
@@ -328,6 +332,85 @@ const Schema = `{
                         },
                         "ssl_force_common_name_check": {
                             "type": "boolean"
+                        },
+                        "enable_http2": {
+                            "type": "boolean"
+                        },
+                        "h2c": {
+                            "type": "boolean"
+                        },
+                        "max_idle_conns_per_host": {
+                            "type": "integer",
+                            "minimum": 0
+                        },
+                        "idle_conn_timeout": {
+                            "type": "number",
+                            "minimum": 0
+                        },
+                        "tls_session_cache_size": {
+                            "type": "integer",
+                            "minimum": 0
+                        }
+                    }
+                },
+                "canary": {
+                    "type": ["object", "null"],
+                    "properties": {
+                        "enabled": {
+                            "type": "boolean"
+                        },
+                        "target_url": {
+                            "type": "string"
+                        },
+                        "weight": {
+                            "type": "integer",
+                            "minimum": 0,
+                            "maximum": 100
+                        }
+                    }
+                },
+                "retry": {
+                    "type": ["object", "null"],
+                    "properties": {
+                        "enabled": {
+                            "type": "boolean"
+                        },
+                        "max_attempts": {
+                            "type": "integer",
+                            "minimum": 0
+                        },
+                        "initial_backoff": {
+                            "type": "number",
+                            "minimum": 0
+                        },
+                        "max_backoff": {
+                            "type": "number",
+                            "minimum": 0
+                        },
+                        "status_codes": {
+                            "type": ["array", "null"]
+                        }
+                    }
+                },
+                "consistent_hashing": {
+                    "type": ["object", "null"],
+                    "properties": {
+                        "enabled": {
+                            "type": "boolean"
+                        },
+                        "source": {
+                            "type": "string",
+                            "enum": ["", "key", "header", "path"]
+                        },
+                        "header_name": {
+                            "type": "string"
+                        },
+                        "path_pattern": {
+                            "type": "string"
+                        },
+                        "replicas": {
+                            "type": "integer",
+                            "minimum": 0
                         }
                     }
                 }
@@ -629,6 +712,557 @@ const Schema = `{
             "required": [
                 "enabled"
             ]
+        },
+        "webhook_subscriptions": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "max_per_key": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "allowed_hosts": {
+                    "type": ["array", "null"]
+                },
+                "allowed_events": {
+                    "type": ["array", "null"]
+                },
+                "max_attempts": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "retry_backoff": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "timeout": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "analytics_sampling": {
+            "type": ["object", "null"],
+            "properties": {
+                "sample_rate": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "skip_paths": {
+                    "type": ["array", "null"]
+                }
+            }
+        },
+        "kafka": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "brokers": {
+                    "type": ["array", "null"]
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                },
+                "key_header": {
+                    "type": "string"
+                },
+                "response_topic": {
+                    "type": "string"
+                },
+                "response_timeout": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "use_ssl": {
+                    "type": "boolean"
+                },
+                "ssl_insecure_skip_verify": {
+                    "type": "boolean"
+                },
+                "sasl_mechanism": {
+                    "type": "string",
+                    "enum": ["", "plain", "scram-sha-256", "scram-sha-512"]
+                },
+                "sasl_username": {
+                    "type": "string"
+                },
+                "sasl_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth_consent": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "service_url": {
+                    "type": "string"
+                },
+                "service_timeout": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "broker": {
+            "type": ["object", "null"],
+            "properties": {
+                "upstream_username": {
+                    "type": "string"
+                },
+                "upstream_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "geo_ip_access": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "allowed_countries": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string",
+                        "pattern": "^[A-Za-z]{2}$"
+                    }
+                },
+                "blocked_countries": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string",
+                        "pattern": "^[A-Za-z]{2}$"
+                    }
+                }
+            }
+        },
+        "bot_detection": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "bad_user_agents": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "burst_requests": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "burst_period": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "tag_threshold": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "maximum": 100
+                },
+                "throttle_threshold": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "maximum": 100
+                },
+                "throttle_delay": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "block_threshold": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "maximum": 100
+                }
+            }
+        },
+        "synthetic_monitoring": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "key": {
+                    "type": "string"
+                },
+                "checks": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "name": {
+                                "type": "string"
+                            },
+                            "method": {
+                                "type": "string"
+                            },
+                            "path": {
+                                "type": "string"
+                            },
+                            "headers": {
+                                "type": ["object", "null"]
+                            },
+                            "body": {
+                                "type": "string"
+                            },
+                            "expected_status": {
+                                "type": "integer",
+                                "minimum": 0
+                            },
+                            "timeout": {
+                                "type": "integer",
+                                "minimum": 0
+                            }
+                        },
+                        "required": ["path"]
+                    }
+                }
+            }
+        },
+        "header_limits": {
+            "type": ["object", "null"],
+            "properties": {
+                "max_count": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_header_size": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_total_size": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "compression": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "algorithms": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string",
+                        "enum": ["br", "zstd", "gzip"]
+                    }
+                },
+                "min_size": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "content_types": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "grpc": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "reflection": {
+                    "type": ["object", "null"],
+                    "properties": {
+                        "enabled": {
+                            "type": "boolean"
+                        },
+                        "allowed_tags": {
+                            "type": ["array", "null"],
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "descriptors": {
+                    "type": "string"
+                },
+                "known_methods_only": {
+                    "type": "boolean"
+                },
+                "method_acls": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "method": {
+                                "type": "string",
+                                "pattern": "^[^/]+/[^/]+$"
+                            },
+                            "allowed_tags": {
+                                "type": ["array", "null"],
+                                "items": {
+                                    "type": "string"
+                                }
+                            }
+                        },
+                        "required": ["method"]
+                    }
+                }
+            }
+        },
+        "session_headers": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "headers": {
+                    "type": ["object", "null"],
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "traffic_samples": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "sample_rate": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "mask_headers": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "mask_query_params": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "mask_rules": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "json_path": {
+                                "type": "string"
+                            },
+                            "action": {
+                                "type": "string",
+                                "enum": ["redact", "hash"]
+                            },
+                            "replacement": {
+                                "type": "string"
+                            }
+                        },
+                        "required": ["json_path", "action"]
+                    }
+                },
+                "max_body_size": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "tracing": {
+            "type": ["object", "null"],
+            "properties": {
+                "capture_logs": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "gateway_federation": {
+            "type": ["object", "null"],
+            "properties": {
+                "forward": {
+                    "type": "boolean"
+                },
+                "require": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "async_operations": {
+            "type": ["object", "null"],
+            "properties": {
+                "path": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "concurrency_limit": {
+            "type": ["object", "null"],
+            "properties": {
+                "max_in_flight": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "queue_timeout": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "maintenance": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "status_code": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "maximum": 599
+                },
+                "body": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "retry_after": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "bypass_header": {
+                    "type": "string"
+                },
+                "bypass_value": {
+                    "type": "string"
+                }
+            }
+        },
+        "max_request_body_size": {
+            "type": "integer",
+            "minimum": -1
+        },
+        "error_templates": {
+            "type": ["array", "null"],
+            "items": {
+                "type": "object",
+                "properties": {
+                    "status_code": {
+                        "type": "integer",
+                        "minimum": 0,
+                        "maximum": 599
+                    },
+                    "content_type": {
+                        "type": "string",
+                        "minLength": 1
+                    },
+                    "body": {
+                        "type": "string"
+                    }
+                },
+                "required": ["content_type", "body"]
+            }
+        },
+        "token_exchange": {
+            "type": ["object", "null"],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "token_endpoint": {
+                    "type": "string"
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "client_secret": {
+                    "type": "string"
+                },
+                "audience": {
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "requested_token_type": {
+                    "type": "string"
+                },
+                "header": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "logging": {
+            "type": ["object", "null"],
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "enum": ["", "error", "warn", "info", "debug"]
+                },
+                "redact_headers": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "redact_query_params": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "redact_rules": {
+                    "type": ["array", "null"],
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "json_path": {
+                                "type": "string"
+                            },
+                            "action": {
+                                "type": "string",
+                                "enum": ["redact", "hash"]
+                            },
+                            "replacement": {
+                                "type": "string"
+                            }
+                        },
+                        "required": ["json_path", "action"]
+                    }
+                }
+            }
         }
     },
     "required": [
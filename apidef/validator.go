@@ -2,6 +2,8 @@ package apidef
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"strings"
 )
 
@@ -51,6 +53,7 @@ type ValidationRuleSet []ValidationRule
 
 var DefaultValidationRuleSet = ValidationRuleSet{
 	&RuleUniqueDataSourceNames{},
+	&RuleValidIPAccessEntries{},
 }
 
 func Validate(definition *APIDefinition, ruleSet ValidationRuleSet) ValidationResult {
@@ -91,3 +94,21 @@ func (r *RuleUniqueDataSourceNames) Validate(apiDef *APIDefinition, validationRe
 		usedNames[trimmedName] = true
 	}
 }
+
+var ErrInvalidIPAccessEntry = errors.New("invalid IP address or CIDR range")
+
+type RuleValidIPAccessEntries struct{}
+
+func (r *RuleValidIPAccessEntries) Validate(apiDef *APIDefinition, validationResult *ValidationResult) {
+	for _, entries := range [][]string{apiDef.AllowedIPs, apiDef.BlacklistedIPs} {
+		for _, entry := range entries {
+			if _, _, err := net.ParseCIDR(entry); err == nil {
+				continue
+			}
+			if net.ParseIP(entry) == nil {
+				validationResult.IsValid = false
+				validationResult.AppendError(fmt.Errorf("%w: %q", ErrInvalidIPAccessEntry, entry))
+			}
+		}
+	}
+}
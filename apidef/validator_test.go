@@ -2,6 +2,7 @@ package apidef
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -119,3 +120,36 @@ func TestRuleUniqueDataSourceNames_Validate(t *testing.T) {
 	))
 
 }
+
+func TestRuleValidIPAccessEntries_Validate(t *testing.T) {
+	ruleSet := ValidationRuleSet{
+		&RuleValidIPAccessEntries{},
+	}
+
+	t.Run("should return invalid when an entry is not an IP or a CIDR range", runValidationTest(
+		&APIDefinition{
+			AllowedIPs:     []string{"127.0.0.1", "10.0.0.0/33"},
+			BlacklistedIPs: []string{"::1", "localhost"},
+		},
+		ruleSet,
+		ValidationResult{
+			IsValid: false,
+			Errors: []error{
+				fmt.Errorf("%w: %q", ErrInvalidIPAccessEntry, "10.0.0.0/33"),
+				fmt.Errorf("%w: %q", ErrInvalidIPAccessEntry, "localhost"),
+			},
+		},
+	))
+
+	t.Run("should return valid when entries are IPs and CIDR ranges", runValidationTest(
+		&APIDefinition{
+			AllowedIPs:     []string{"127.0.0.1", "10.0.0.0/8"},
+			BlacklistedIPs: []string{"::1", "fd00::/8"},
+		},
+		ruleSet,
+		ValidationResult{
+			IsValid: true,
+			Errors:  nil,
+		},
+	))
+}
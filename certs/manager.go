@@ -262,6 +262,34 @@ func ExtractCertificateMeta(cert *tls.Certificate, certID string) *CertificateMe
 	}
 }
 
+// CertificateExpiry describes when a certificate expires.
+type CertificateExpiry struct {
+	ID         string    `json:"id"`
+	CommonName string    `json:"common_name"`
+	NotAfter   time.Time `json:"not_after"`
+	// DaysToExpiry is the number of whole days left before the certificate expires, negative once expired.
+	DaysToExpiry int `json:"days_to_expiry"`
+}
+
+// ExtractCertificateExpiry returns the expiry of cert at now, nil for public keys which don't expire.
+func ExtractCertificateExpiry(cert *tls.Certificate, certID string, now time.Time) *CertificateExpiry {
+	if cert.Leaf.NotAfter.IsZero() {
+		return nil
+	}
+
+	left := cert.Leaf.NotAfter.Sub(now)
+	days := int(left / (24 * time.Hour))
+	if left < 0 {
+		days--
+	}
+	return &CertificateExpiry{
+		ID:           certID,
+		CommonName:   cert.Leaf.Subject.CommonName,
+		NotAfter:     cert.Leaf.NotAfter,
+		DaysToExpiry: days,
+	}
+}
+
 func GetCertIDAndChainPEM(certData []byte, secret string) (string, []byte, error) {
 	var keyPEM, keyRaw []byte
 	var publicKeyPem []byte
@@ -396,6 +424,21 @@ func (c *CertificateManager) List(certIDs []string, mode CertificateType) (out [
 	return out
 }
 
+// ExpiringCertificates returns the expiry of the certificates of certIDs which expire before
+// deadline, including those already expired.
+func (c *CertificateManager) ExpiringCertificates(certIDs []string, now, deadline time.Time) []*CertificateExpiry {
+	var out []*CertificateExpiry
+	for i, cert := range c.List(certIDs, CertificateAny) {
+		if cert == nil {
+			continue
+		}
+		if expiry := ExtractCertificateExpiry(cert, certIDs[i], now); expiry != nil && expiry.NotAfter.Before(deadline) {
+			out = append(out, expiry)
+		}
+	}
+	return out
+}
+
 // Returns list of fingerprints
 func (c *CertificateManager) ListPublicKeys(keyIDs []string) (out []string) {
 	var rawKey []byte
@@ -485,10 +528,12 @@ func (c *CertificateManager) ListAllIds(prefix string) (out []string) {
 			out = append(out, strings.TrimPrefix(key, "raw-"))
 		}
 	} else {
-		// If list is not exists, but migrated record exists, it means it just empty
-
-		if _, err := c.storage.GetKey(indexKey + "-migrated"); err == nil {
-			return out
+		// If list is not exists, but migrated record exists, it means it just empty.
+		// Without prefix the keys aren't indexed, so they're always scanned.
+		if prefix != "" {
+			if _, err := c.storage.GetKey(indexKey + "-migrated"); err == nil {
+				return out
+			}
 		}
 
 		keys := c.storage.GetKeys("raw-" + prefix + "*")
@@ -500,7 +545,9 @@ func (c *CertificateManager) ListAllIds(prefix string) (out []string) {
 			out = append(out, strings.TrimPrefix(key, "raw-"))
 		}
 	}
-	c.storage.SetKey(indexKey+"-migrated", "1", 0)
+	if prefix != "" {
+		c.storage.SetKey(indexKey+"-migrated", "1", 0)
+	}
 
 	return out
 }
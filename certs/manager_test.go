@@ -315,3 +315,28 @@ func TestStorageIndex(t *testing.T) {
 		t.Error("Storage index list should have 0 certificates after deleting a certificate")
 	}
 }
+
+func TestExpiringCertificates(t *testing.T) {
+	m := newManager()
+
+	certPem, _ := genCertificateFromCommonName("soon", false)
+	certID, _ := m.Add(certPem, "")
+
+	priv, _ := rsa.GenerateKey(rand.Reader, 2048)
+	privDer, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	publicKeyID, _ := m.Add(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: privDer}), "")
+
+	now := time.Now()
+	ids := []string{certID, publicKeyID, "unknown"}
+
+	expiring := m.ExpiringCertificates(ids, now, now.Add(24*time.Hour))
+	if assert.Len(t, expiring, 1) {
+		assert.Equal(t, certID, expiring[0].ID)
+		assert.Equal(t, "soon", expiring[0].CommonName)
+		assert.Equal(t, 0, expiring[0].DaysToExpiry)
+	}
+	assert.Empty(t, m.ExpiringCertificates(ids, now, now.Add(30*time.Minute)))
+
+	expired := &tls.Certificate{Leaf: &x509.Certificate{NotAfter: now.Add(-time.Hour)}}
+	assert.Equal(t, -1, ExtractCertificateExpiry(expired, "expired", now).DaysToExpiry)
+}
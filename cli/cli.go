@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/TykTechnologies/tyk/cli/linter"
 
@@ -41,12 +42,44 @@ var (
 
 	// DefaultMode is set when default command is used.
 	DefaultMode bool
+	// BenchMode is set when the bench command is used.
+	BenchMode bool
+	// Bench holds the options of the bench command.
+	Bench BenchOptions
 
 	app *kingpin.Application
 
 	log = logger.Get()
 )
 
+// BenchOptions are the options of the bench command, which loads an API on a gateway of its own
+// proxying to a synthetic upstream, and runs a load profile against it.
+type BenchOptions struct {
+	// Conf is the configuration file of the gateway, the default configuration being used if empty.
+	Conf *string
+	// API is the API definition file to load.
+	API *string
+	// Requests is the number of requests to send, 0 to send requests for Duration.
+	Requests *int
+	// Duration is how long to send requests for when Requests is 0.
+	Duration *time.Duration
+	// Warmup is the number of requests sent before measuring.
+	Warmup *int
+	// Concurrency is the number of concurrent clients.
+	Concurrency *int
+	// Rate is the number of requests sent per second, 0 not limiting the rate.
+	Rate   *int
+	Method *string
+	// Path is the path requested, the listen path of the API if empty.
+	Path    *string
+	Headers *map[string]string
+	Body    *string
+	// UpstreamLatency is the time the synthetic upstream takes to respond.
+	UpstreamLatency *time.Duration
+	// UpstreamSize is the size of the responses of the synthetic upstream, in bytes.
+	UpstreamSize *int
+}
+
 // Init sets all flags and subcommands.
 func Init(version string, confPaths []string) {
 	app = kingpin.New(appName, appDesc)
@@ -72,8 +105,14 @@ func Init(version string, confPaths []string) {
 	startCmd.Default()
 
 	// Linter:
-	lintCmd := app.Command("lint", "Runs a linter on Tyk configuration file")
+	lintCmd := app.Command("lint", "Runs a linter on Tyk configuration file, or on API definition and policy files")
+	lintAPIs := lintCmd.Flag("api", "lint a classic or OAS API definition file instead of the configuration file, can be repeated").PlaceHolder("FILE").ExistingFiles()
+	lintPolicies := lintCmd.Flag("policy", "lint a policies file instead of the configuration file, can be repeated").PlaceHolder("FILE").ExistingFiles()
 	lintCmd.Action(func(c *kingpin.ParseContext) error {
+		if len(*lintAPIs) > 0 || len(*lintPolicies) > 0 {
+			os.Exit(lintFiles(*lintAPIs, *lintPolicies))
+		}
+
 		confSchema, err := ioutil.ReadFile("cli/linter/schema.json")
 		if err != nil {
 			return err
@@ -95,6 +134,28 @@ func Init(version string, confPaths []string) {
 		return nil
 	})
 
+	// Benchmark:
+	benchCmd := app.Command("bench", "Runs a load profile against an API loaded on a gateway proxying to a synthetic upstream, reporting the throughput and latencies per middleware")
+	Bench = BenchOptions{
+		Conf:            benchCmd.Flag("conf", "load a named configuration file").PlaceHolder("FILE").String(),
+		API:             benchCmd.Flag("api", "API definition file to benchmark").Required().PlaceHolder("FILE").ExistingFile(),
+		Requests:        benchCmd.Flag("requests", "number of requests to send, 0 to send requests for the duration").Short('n').Default("0").Int(),
+		Duration:        benchCmd.Flag("duration", "how long to send requests for when no number of requests is set").Short('d').Default("10s").Duration(),
+		Warmup:          benchCmd.Flag("warmup", "number of requests sent before measuring").Default("100").Int(),
+		Concurrency:     benchCmd.Flag("concurrency", "number of concurrent clients").Short('c').Default("10").Int(),
+		Rate:            benchCmd.Flag("rate", "requests per second, 0 for no limit").Default("0").Int(),
+		Method:          benchCmd.Flag("method", "request method").Default("GET").String(),
+		Path:            benchCmd.Flag("path", "request path, the listen path of the API by default").String(),
+		Headers:         benchCmd.Flag("header", "request header, can be repeated").PlaceHolder("NAME=VALUE").StringMap(),
+		Body:            benchCmd.Flag("body", "request body").String(),
+		UpstreamLatency: benchCmd.Flag("upstream-latency", "time the synthetic upstream takes to respond").Default("0s").Duration(),
+		UpstreamSize:    benchCmd.Flag("upstream-size", "size of the synthetic upstream responses in bytes").Default("128").Int(),
+	}
+	benchCmd.Action(func(ctx *kingpin.ParseContext) error {
+		BenchMode = true
+		return nil
+	})
+
 	// Add import command:
 	importer.AddTo(app)
 
@@ -102,6 +163,37 @@ func Init(version string, confPaths []string) {
 	bundler.AddTo(app)
 }
 
+// lintFiles lints the API definition and policy files, printing their issues. It returns the exit
+// code, 1 if any file has issues.
+func lintFiles(apiPaths, policyPaths []string) int {
+	code := 0
+	lint := func(path string, run func(string) ([]string, error)) {
+		lines, err := run(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 1
+			return
+		}
+		if len(lines) == 0 {
+			fmt.Printf("found no issues in %s\n", path)
+			return
+		}
+		code = 1
+		fmt.Printf("issues found in %s:\n", path)
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+
+	for _, path := range apiPaths {
+		lint(path, linter.LintAPIDefinition)
+	}
+	for _, path := range policyPaths {
+		lint(path, linter.LintPolicies)
+	}
+	return code
+}
+
 // Parse parses the command-line arguments.
 func Parse() {
 	kingpin.MustParse(app.Parse(os.Args[1:]))
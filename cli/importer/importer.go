@@ -17,7 +17,7 @@ import (
 
 const (
 	cmdName = "import"
-	cmdDesc = "Imports a BluePrint/Swagger/WSDL file"
+	cmdDesc = "Imports a BluePrint/Swagger/WSDL/Postman collection file"
 )
 
 var (
@@ -31,7 +31,10 @@ type Importer struct {
 	swaggerMode    *bool
 	bluePrintMode  *bool
 	wsdlMode       *bool
+	postmanMode    *bool
 	portNames      *string
+	allowList      *bool
+	validateXML    *bool
 	createAPI      *bool
 	orgID          *string
 	upstreamTarget *string
@@ -51,7 +54,10 @@ func AddTo(app *kingpin.Application) {
 	imp.swaggerMode = cmd.Flag("swagger", "Use Swagger mode").Bool()
 	imp.bluePrintMode = cmd.Flag("blueprint", "Use BluePrint mode").Bool()
 	imp.wsdlMode = cmd.Flag("wsdl", "Use WSDL mode").Bool()
+	imp.postmanMode = cmd.Flag("postman", "Use Postman collection mode").Bool()
 	imp.portNames = cmd.Flag("port-names", "Specify port name of each service in the WSDL file. Input format is comma separated list of serviceName:portName").String()
+	imp.allowList = cmd.Flag("allow-list", "Only allow the operations of the WSDL services").Bool()
+	imp.validateXML = cmd.Flag("validate-xml", "Validate the SOAP requests against the schema of the WSDL types").Bool()
 	imp.createAPI = cmd.Flag("create-api", "Creates a new API definition from the blueprint").Bool()
 	imp.orgID = cmd.Flag("org-id", "assign the API Definition to this org_id (required with create-api").String()
 	imp.upstreamTarget = cmd.Flag("upstream-target", "set the upstream target for the definition").PlaceHolder("URL").String()
@@ -81,6 +87,12 @@ func (i *Importer) Import(ctx *kingpin.ParseContext) (err error) {
 			log.Fatal(err)
 			os.Exit(1)
 		}
+	} else if *i.postmanMode {
+		err = i.handlePostmanMode()
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
 	} else {
 		log.Fatal(errUnknownMode)
 		os.Exit(1)
@@ -248,6 +260,8 @@ func (i *Importer) handleWSDLMode() error {
 	}
 
 	w.SetServicePortMapping(serviceportMapping)
+	w.SetAllowList(*i.allowList)
+	w.SetXMLValidation(*i.validateXML)
 
 	if *i.createAPI {
 		//Create new API
@@ -278,6 +292,44 @@ func (i *Importer) handleWSDLMode() error {
 	return nil
 }
 
+func (i *Importer) handlePostmanMode() error {
+	var def *apidef.APIDefinition
+
+	if err := i.validateInput(); err != nil {
+		return err
+	}
+
+	c, err := i.postmanLoadFile(*i.input)
+	if err != nil {
+		return fmt.Errorf("File load error: %v", err)
+	}
+
+	if *i.createAPI {
+		def, err = c.ToAPIDefinition(*i.orgID, *i.upstreamTarget, *i.asMock)
+		if err != nil {
+			return fmt.Errorf("Failed to create API Definition from file: %v", err)
+		}
+	} else {
+		def, err = i.apiDefLoadFile(*i.forAPI)
+		if err != nil {
+			return fmt.Errorf("failed to load and decode file data for API Definition: %v", err)
+		}
+
+		versionData, err := c.ConvertIntoApiVersion(*i.asMock)
+		if err != nil {
+			return fmt.Errorf("Conversion into API Def failed: %v", err)
+		}
+
+		if err := c.InsertIntoAPIDefinitionAsVersion(versionData, def, *i.asVersion); err != nil {
+			return fmt.Errorf("Insertion failed: %v", err)
+		}
+	}
+
+	i.printDef(def)
+
+	return nil
+}
+
 func (i *Importer) printDef(def *apidef.APIDefinition) {
 	asJSON, err := json.MarshalIndent(def, "", "    ")
 	if err != nil {
@@ -325,6 +377,24 @@ func (i *Importer) wsdlLoadFile(path string) (*importer.WSDLDef, error) {
 	return wsdl.(*importer.WSDLDef), nil
 }
 
+func (i *Importer) postmanLoadFile(path string) (*importer.PostmanCollection, error) {
+	collection, err := importer.GetImporterForSource(importer.PostmanSource)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := collection.LoadFrom(f); err != nil {
+		return nil, err
+	}
+
+	return collection.(*importer.PostmanCollection), nil
+}
+
 func (i *Importer) bluePrintLoadFile(path string) (*importer.BluePrintAST, error) {
 	blueprint, err := importer.GetImporterForSource(importer.ApiaryBluePrint)
 	if err != nil {
@@ -0,0 +1,196 @@
+package linter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	schema "github.com/xeipuuv/gojsonschema"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// LintAPIDefinition lints the API definition file at path, either a classic definition checked
+// against the API definition schema, or an OAS document checked against the OpenAPI specification
+// with its x-tyk-api-gateway extension. It returns the issues found, an error when the file can't
+// be read or isn't JSON.
+func LintAPIDefinition(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["openapi"]; ok {
+		return lintOAS(data)
+	}
+	return lintClassicAPIDefinition(data)
+}
+
+func lintClassicAPIDefinition(data []byte) ([]string, error) {
+	result, err := schema.Validate(schema.NewStringLoader(apidef.Schema), schema.NewBytesLoader(data))
+	if err != nil {
+		return nil, err
+	}
+	issues := resultWarns(result)
+
+	var def apidef.APIDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		// the schema reports the fields of the wrong type
+		if len(issues) > 0 {
+			return issues, nil
+		}
+		return []string{err.Error()}, nil
+	}
+	return append(issues, lintDefinition(&def)...), nil
+}
+
+func lintOAS(data []byte) ([]string, error) {
+	doc, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData(data)
+	if err != nil {
+		return []string{"invalid OAS document: " + err.Error()}, nil
+	}
+
+	var issues []string
+	if err := doc.Validate(context.Background()); err != nil {
+		issues = append(issues, "invalid OAS document: "+err.Error())
+	}
+
+	raw, ok := doc.Extensions[oas.ExtensionTykAPIGateway].(json.RawMessage)
+	if !ok {
+		return append(issues, "missing "+oas.ExtensionTykAPIGateway+" extension"), nil
+	}
+
+	var xTykAPIGateway oas.XTykAPIGateway
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&xTykAPIGateway); err != nil {
+		return append(issues, oas.ExtensionTykAPIGateway+": "+err.Error()), nil
+	}
+
+	var def apidef.APIDefinition
+	xTykAPIGateway.ExtractTo(&def)
+	for _, issue := range lintDefinition(&def) {
+		issues = append(issues, oas.ExtensionTykAPIGateway+": "+issue)
+	}
+	return issues, nil
+}
+
+// lintDefinition runs the semantic checks the gateway runs when definitions are added with its API.
+func lintDefinition(def *apidef.APIDefinition) []string {
+	var issues []string
+	if def.APIID == "" {
+		issues = append(issues, "api_id: API ID is required")
+	}
+	if def.Name == "" {
+		issues = append(issues, "name: API name is required")
+	}
+	if def.Proxy.ListenPath == "" {
+		issues = append(issues, "proxy.listen_path: listen path is required")
+	}
+
+	result := apidef.Validate(def, apidef.DefaultValidationRuleSet)
+	return append(issues, result.ErrorStrings()...)
+}
+
+// LintPolicies lints the policies file at path, either a map of policies by ID like the policies
+// file of the gateway, or a single policy like the files of a policies directory.
+func LintPolicies(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["access_rights"]; ok {
+		return lintPolicy("", data), nil
+	}
+
+	ids := make([]string, 0, len(fields))
+	for id := range fields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var issues []string
+	for _, id := range ids {
+		for _, issue := range lintPolicy(id, fields[id]) {
+			issues = append(issues, id+": "+issue)
+		}
+	}
+	return issues, nil
+}
+
+// lintPolicy lints a policy, id being its key in the policies file if any.
+func lintPolicy(id string, data []byte) []string {
+	var policy user.Policy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policy); err != nil {
+		return []string{err.Error()}
+	}
+
+	var issues []string
+	if id == "" && policy.ID == "" {
+		issues = append(issues, "id: policy ID is required")
+	}
+	if id != "" && policy.ID != "" && policy.ID != id {
+		issues = append(issues, fmt.Sprintf("id: policy ID %q doesn't match its key", policy.ID))
+	}
+	if len(policy.AccessRights) == 0 {
+		issues = append(issues, "access_rights: the policy grants access to no API")
+	}
+	issues = append(issues, lintLimit("", user.APILimit{
+		Rate:             policy.Rate,
+		Per:              policy.Per,
+		QuotaMax:         policy.QuotaMax,
+		QuotaRenewalRate: policy.QuotaRenewalRate,
+	})...)
+
+	switch policy.MergeStrategy {
+	case "", user.MergeStrategyMax, user.MergeStrategyMin, user.MergeStrategySum, user.MergeStrategyPriority:
+	default:
+		issues = append(issues, fmt.Sprintf("merge_strategy: unknown strategy %q", policy.MergeStrategy))
+	}
+
+	apiIDs := make([]string, 0, len(policy.AccessRights))
+	for apiID := range policy.AccessRights {
+		apiIDs = append(apiIDs, apiID)
+	}
+	sort.Strings(apiIDs)
+	for _, apiID := range apiIDs {
+		accessRights := policy.AccessRights[apiID]
+		prefix := "access_rights." + apiID + "."
+		if accessRights.APIID != "" && accessRights.APIID != apiID {
+			issues = append(issues, fmt.Sprintf("%sapi_id: API ID %q doesn't match its key", prefix, accessRights.APIID))
+		}
+		issues = append(issues, lintLimit(prefix+"limit.", accessRights.Limit)...)
+	}
+	return issues
+}
+
+func lintLimit(prefix string, limit user.APILimit) []string {
+	var issues []string
+	if limit.Rate < 0 || limit.Per < 0 {
+		issues = append(issues, prefix+"rate: rate and per must not be negative")
+	}
+	if limit.Rate > 0 && limit.Per == 0 {
+		issues = append(issues, prefix+"per: per is required with a rate limit")
+	}
+	if limit.QuotaMax > 0 && limit.QuotaRenewalRate <= 0 {
+		issues = append(issues, prefix+"quota_renewal_rate: renewal rate is required with a quota")
+	}
+	return issues
+}
@@ -0,0 +1,130 @@
+package linter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLintFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "file.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestLintAPIDefinition(t *testing.T) {
+	const oasDoc = `{
+		"openapi": "3.0.3",
+		"info": {"title": "Pets", "version": "1.0.0"},
+		"paths": {},
+		"x-tyk-api-gateway": %s
+	}`
+
+	for _, tc := range []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			"Classic", `{"api_id": "pets", "name": "Pets", "proxy": {"listen_path": "/pets/", "target_url": "http://pets"}, "version_data": {"not_versioned": true, "versions": {}}}`,
+			nil,
+		},
+		{
+			"ClassicFieldTypo", `{"api_id": "pets", "name": "Pets", "proxy": {"listen_path": "/pets/", "target_url": "http://pets"}, "version_data": {"not_versioned": true, "versions": {}}, "use_keyles": true}`,
+			[]string{"use_keyles: Additional property use_keyles is not allowed"},
+		},
+		{
+			"ClassicMissingFields", `{"name": "Pets", "version_data": {"not_versioned": true, "versions": {}}}`,
+			[]string{"proxy: proxy is required", "api_id: API ID is required", "proxy.listen_path: listen path is required"},
+		},
+		{
+			"OAS", fmtOAS(oasDoc, `{"info": {"id": "pets", "name": "Pets", "state": {"active": true}},
+				"upstream": {"url": "http://pets"}, "server": {"listenPath": {"value": "/pets/"}}}`),
+			nil,
+		},
+		{
+			"OASMissingExtension", `{"openapi": "3.0.3", "info": {"title": "Pets", "version": "1.0.0"}, "paths": {}}`,
+			[]string{"missing x-tyk-api-gateway extension"},
+		},
+		{
+			"OASInvalidDocument", fmtOAS(`{"openapi": "3.0.3", "info": {"title": "Pets"}, "paths": {}, "x-tyk-api-gateway": %s}`,
+				`{"info": {"id": "pets", "name": "Pets", "state": {"active": true}}, "upstream": {"url": "http://pets"},
+				"server": {"listenPath": {"value": "/pets/"}}}`),
+			[]string{"invalid OAS document: invalid info: value of version must be a non-empty JSON string"},
+		},
+		{
+			"OASUnknownField", fmtOAS(oasDoc, `{"info": {"id": "pets", "name": "Pets"}, "upstream": {"url": "http://pets"},
+				"server": {"listenPath": {"value": "/pets/"}}, "serverr": {}}`),
+			[]string{`x-tyk-api-gateway: json: unknown field "serverr"`},
+		},
+		{
+			"OASMissingFields", fmtOAS(oasDoc, `{"info": {"name": "Pets"}, "upstream": {"url": "http://pets"}, "server": {}}`),
+			[]string{"x-tyk-api-gateway: api_id: API ID is required", "x-tyk-api-gateway: proxy.listen_path: listen path is required"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			issues, err := LintAPIDefinition(writeLintFile(t, tc.in))
+			require.NoError(t, err)
+			if len(tc.want) == 0 {
+				assert.Empty(t, issues)
+				return
+			}
+			assert.Equal(t, tc.want, issues)
+		})
+	}
+
+	_, err := LintAPIDefinition(writeLintFile(t, `{`))
+	assert.Error(t, err)
+}
+
+func fmtOAS(doc, extension string) string {
+	return fmt.Sprintf(doc, extension)
+}
+
+func TestLintPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			"Map", `{"gold": {"id": "gold", "rate": 10, "per": 1, "access_rights": {"pets": {"api_id": "pets"}}}}`,
+			nil,
+		},
+		{
+			"Single", `{"id": "gold", "access_rights": {"pets": {"limit": {"quota_max": 10, "quota_renewal_rate": 60}}}}`,
+			nil,
+		},
+		{
+			"MapIssues", `{
+				"gold": {"id": "silver", "rate": 10, "access_rights": {"pets": {"api_id": "cats", "limit": {"quota_max": 10}}}},
+				"bronze": {"access_rights": {}, "merge_strategy": "avg", "ratee": 1}
+			}`,
+			[]string{
+				`bronze: json: unknown field "ratee"`,
+				`gold: id: policy ID "silver" doesn't match its key`,
+				"gold: per: per is required with a rate limit",
+				`gold: access_rights.pets.api_id: API ID "cats" doesn't match its key`,
+				"gold: access_rights.pets.limit.quota_renewal_rate: renewal rate is required with a quota",
+			},
+		},
+		{
+			"SingleIssues", `{"access_rights": {}, "merge_strategy": "avg"}`,
+			[]string{
+				"id: policy ID is required",
+				"access_rights: the policy grants access to no API",
+				`merge_strategy: unknown strategy "avg"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			issues, err := LintPolicies(writeLintFile(t, tc.in))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, issues)
+		})
+	}
+}
@@ -69,6 +69,7 @@ const (
 
 type PoliciesConfig struct {
 	// Set this value to `file` to look in the file system for a definition file. Set to `service` to use the Dashboard service.
+	// Set to `kubernetes` to load them from the ConfigMaps and Secrets configured in `kubernetes`.
 	PolicySource string `json:"policy_source"`
 
 	// This option is required if `policies.policy_source` is set to `service`.
@@ -179,10 +180,14 @@ type AnalyticsConfigConfig struct {
 	// This setting can be overridden with an organisation flag, enabed at an API level, or on individual Key level.
 	EnableDetailedRecording bool `json:"enable_detailed_recording"`
 
+	// Set this value to `true` to store a SHA-256 hash of the query of GraphQL requests in the analytics data,
+	// so that identical queries can be grouped without storing the query itself.
+	EnableGraphQLQueryHash bool `json:"enable_graphql_query_hash"`
+
 	// Tyk can store GeoIP information based on MaxMind DB’s to enable GeoIP tracking on inbound request analytics. Set this value to `true` and assign a DB using the `geo_ip_db_path` setting.
 	EnableGeoIP bool `json:"enable_geo_ip"`
 
-	// Path to a MaxMind GeoIP database
+	// Path to a MaxMind GeoIP database, also used by the APIs allowing or denying access by country (`geo_ip_access`).
 	// The analytics GeoIP DB can be replaced on disk. It will cleanly auto-reload every hour.
 	GeoIPDBLocation string `json:"geo_ip_db_path"`
 
@@ -193,8 +198,17 @@ type AnalyticsConfigConfig struct {
 	PoolSize int `json:"pool_size"`
 
 	// Number of records in analytics queue, per worker. Default: 1000.
+	// When the queue is full, for example because Redis slowed down during a traffic spike, the oldest queued records are dropped.
 	RecordsBufferSize uint64 `json:"records_buffer_size"`
 
+	// Maximum number of records each worker writes to Redis in a single pipeline. Workers start with batches of
+	// `records_buffer_size / pool_size` records and grow them up to this size while the queue backs up or Redis is slow.
+	// At most `records_buffer_size + pool_size * max_flush_batch_size` records are held in memory. Defaults to 4 times the initial batch size.
+	MaxFlushBatchSize uint64 `json:"max_flush_batch_size"`
+
+	// Redis write latency, in milliseconds, above which workers flush larger batches less often. Defaults to 100.
+	FlushLatencyThreshold int `json:"flush_latency_threshold"`
+
 	// You can set a time (in seconds) to configure how long analytics are kept if they are not processed. The default is 60 seconds.
 	// This is used to prevent the potential infinite growth of Redis analytics storage.
 	StorageExpirationTime int `json:"storage_expiration_time"`
@@ -222,6 +236,10 @@ type HealthCheckConfig struct {
 type LivenessCheckConfig struct {
 	// Frequence of performing interval healthchecks for Redis, Dashboard, and RPC layer. Default: 10 seconds.
 	CheckDuration time.Duration `json:"check_duration"`
+
+	// DNSCheckHost is a host name resolved by the DNS health check, reported in the `dns` component
+	// of the readiness endpoint `/hello/ready`. The DNS check is disabled if empty.
+	DNSCheckHost string `json:"dns_check_host"`
 }
 
 type DnsCacheConfig struct {
@@ -242,6 +260,18 @@ type DnsCacheConfig struct {
 	// * `random` will instruct your Tyk Gateway to connect to a random IP in a returned IP list and cache the response.
 	// * `no_cache` will instruct your Tyk Gateway to connect to the first IP in a returned IP list and fetch each addresses list without caching on each API endpoint DNS query.
 	MultipleIPsHandleStrategy IPsHandleStrategy `json:"multiple_ips_handle_strategy"`
+
+	// Set this to `true` to cache the records for the TTL returned by the nameservers, capped by `ttl`, instead of `ttl`.
+	// The records are resolved with the nameservers of `/etc/resolv.conf`, falling back to `ttl` if they can't be queried.
+	UseRecordTTL bool `json:"use_record_ttl"`
+
+	// The duration in seconds for which failed resolutions are cached, so that unresolvable hosts aren't looked up on
+	// every request. Failed resolutions aren't cached if 0.
+	NegativeTTL int64 `json:"negative_ttl"`
+
+	// Set this to `true` to keep using the last addresses a host resolved to when its resolution fails. They are cached
+	// for `negative_ttl` so that the resolution is retried, or retried on the next request if `negative_ttl` is 0.
+	Failover bool `json:"failover"`
 }
 
 type MonitorConfig struct {
@@ -324,6 +354,10 @@ type LocalSessionCacheConf struct {
 
 	CachedSessionTimeout int `json:"cached_session_timeout"`
 	CacheSessionEviction int `json:"cached_session_eviction"`
+
+	// Number of seconds the keys not found in the key store are remembered for, the requests with these keys being
+	// rejected without querying Redis. Keys created meanwhile are rejected until then. Disabled when 0.
+	UnknownKeyTimeout int `json:"unknown_key_timeout"`
 }
 
 type HttpServerOptionsConfig struct {
@@ -342,6 +376,9 @@ type HttpServerOptionsConfig struct {
 	// Enable Lets-Encrypt support
 	UseLE_SSL bool `json:"use_ssl_le"`
 
+	// ACME configures the automatic provisioning of the certificates of the custom domains of the APIs
+	ACME ACMEConfig `json:"acme"`
+
 	// Enable HTTP2 protocol handling
 	EnableHttp2 bool `json:"enable_http2"`
 
@@ -379,6 +416,19 @@ type HttpServerOptionsConfig struct {
 
 	// Custom SSL ciphers. See list of ciphers here https://tyk.io/docs/basic-config-and-security/security/tls-and-ssl/#specify-tls-cipher-suites-for-tyk-gateway--tyk-dashboard
 	Ciphers []string `json:"ssl_ciphers"`
+
+	// TLS settings of the connections to specific domains, overriding the settings above. The first policy matching
+	// the server name requested by the client applies.
+	DomainTLSPolicies []DomainTLSPolicy `json:"domain_tls_policies"`
+
+	// Maximum size in bytes of the request line and headers of the requests, rejected with 431 before they are routed to an API.
+	// Defaults to 1MB.
+	MaxHeaderBytes int `json:"max_header_bytes"`
+
+	// Maximum size in bytes of the request bodies, for the APIs not setting `max_request_body_size`. The requests stating
+	// a larger `Content-Length` are rejected with 413 before they are authenticated, the larger streamed bodies when the
+	// limit is reached. Unlimited if 0.
+	MaxRequestBodySize int64 `json:"max_request_body_size"`
 }
 
 type AuthOverrideConf struct {
@@ -418,6 +468,19 @@ type CoProcessConfig struct {
 	// Address of gRPC user
 	CoProcessGRPCServer string `json:"coprocess_grpc_server"`
 
+	// Addresses of more gRPC servers, tried in order when the ones before them are unhealthy.
+	CoProcessGRPCServers []string `json:"coprocess_grpc_servers"`
+
+	// Number of connections opened to each gRPC server, calls are spread across them. Defaults to 1.
+	GRPCConnectionPoolSize int `json:"grpc_connection_pool_size"`
+
+	// Interval in seconds between the health checks of the gRPC servers, when there are several of them. Defaults to 10.
+	GRPCHealthCheckInterval int `json:"grpc_health_check_interval"`
+
+	// Request and response bodies larger than this size, in bytes, are streamed to the gRPC server in chunks of
+	// this size. Streaming is disabled by default.
+	GRPCStreamChunkSize int `json:"grpc_stream_chunk_size"`
+
 	// Maximum message which can be received from a gRPC server
 	GRPCRecvMaxSize int `json:"grpc_recv_max_size"`
 
@@ -454,6 +517,331 @@ type SecurityConfig struct {
 	PinnedPublicKeys map[string]string `json:"pinned_public_keys"`
 
 	Certificates CertificatesConfig `json:"certificates"`
+
+	// Fires events when stored certificates are about to expire.
+	CertificateExpiryMonitor CertificateExpiryMonitorConfig `json:"certificate_expiry_monitor"`
+}
+
+// CertificateExpiryMonitorConfig configures the scans of the stored certificates, which fire the CertificateExpiringSoon
+// and CertificateExpired events as gateway events and as events of the APIs using the certificates.
+type CertificateExpiryMonitorConfig struct {
+	// Number of days before the expiry of a certificate from which events are fired. Defaults to 30.
+	WarningThresholdDays int `json:"warning_threshold_days"`
+
+	// Number of seconds between scans. Defaults to 3600.
+	CheckInterval int64 `json:"check_interval"`
+
+	// Number of seconds before an event is fired again for the same certificate, across the gateways
+	// sharing the Redis. Defaults to 86400.
+	EventCooldown int64 `json:"event_cooldown"`
+}
+
+type BasicAuthHashConfig struct {
+	// Hashing algorithm used for the passwords of new or updated basic auth keys. Possible values: bcrypt, argon2id. Defaults to bcrypt.
+	Algorithm string `json:"algorithm"`
+
+	// The bcrypt cost factor. Defaults to 10.
+	BCryptCost int `json:"bcrypt_cost"`
+
+	// Number of Argon2id passes over the memory. Defaults to 1.
+	Argon2Time uint32 `json:"argon2_time"`
+
+	// Amount of memory used by Argon2id, in KiB. Defaults to 65536 (64 MiB).
+	Argon2Memory uint32 `json:"argon2_memory"`
+
+	// Number of threads used by Argon2id. Defaults to 4.
+	Argon2Threads uint8 `json:"argon2_threads"`
+
+	// Set this to `true` to transparently rehash passwords which were stored with a different algorithm or cost
+	// on the next successful login.
+	RehashOnLogin bool `json:"rehash_on_login"`
+}
+
+type AccessLogsConfig struct {
+	// Set this to `true` to enable access logs.
+	Enabled bool `json:"enabled"`
+
+	// Destinations of the access log records. Defaults to a single `stdout` sink.
+	Sinks []AccessLogSinkConfig `json:"sinks"`
+}
+
+type AccessLogSinkConfig struct {
+	// Sink type. Possible values: stdout, file, syslog.
+	Type string `json:"type"`
+
+	// Path of the log file, used by the `file` sink.
+	Path string `json:"path"`
+
+	// Size in megabytes at which the log file is rotated, used by the `file` sink. Defaults to 100.
+	MaxSizeMB int `json:"max_size_mb"`
+
+	// Number of rotated log files to keep, used by the `file` sink. Defaults to 5.
+	MaxBackups int `json:"max_backups"`
+
+	// Syslog transport, used by the `syslog` sink. Values: tcp, udp or empty for the local syslog daemon.
+	Network string `json:"network"`
+
+	// Syslog server address, used by the `syslog` sink.
+	Address string `json:"address"`
+
+	// Syslog tag, used by the `syslog` sink. Defaults to `tyk-access`.
+	Tag string `json:"tag"`
+}
+
+type RequestIDConfig struct {
+	// Set this to `true` to give every request an ID, available as the `request_id` context variable, in the
+	// analytics records and to the error templates as `.RequestID`.
+	Enabled bool `json:"enabled"`
+
+	// Header of the request ID. The ID of the requests with this header is kept, the others get a generated
+	// one. Defaults to `X-Request-ID`.
+	Header string `json:"header"`
+
+	// Format of the generated IDs. Possible values: uuid, trace. `trace` IDs are 32 hex characters, the format
+	// of the W3C trace context trace IDs. Defaults to uuid.
+	Format string `json:"format"`
+
+	// Set this to `true` to send the request ID to the upstream in the header.
+	InjectUpstream bool `json:"inject_upstream"`
+
+	// Set this to `true` to send the request ID back to the client in the header.
+	InjectResponse bool `json:"inject_response"`
+}
+
+type BillingExportConfig struct {
+	// Set this to `true` to count requests per key and API and export them to a billing system.
+	Enabled bool `json:"enabled"`
+
+	// Length in seconds of the usage periods, aligned on the Unix epoch. Defaults to 3600.
+	Period int64 `json:"period"`
+
+	// Number of seconds the usage of a period and its delivery tracking are kept after the period
+	// ends, so failed exports can be retried. Defaults to 86400.
+	Retention int64 `json:"retention"`
+
+	// Destination of the exports. Possible values: http, object_storage.
+	// `http` POSTs each export to URL with an `Idempotency-Key` header.
+	// `object_storage` PUTs each export as `<object_prefix><period start>.json` under URL,
+	// e.g. a pre-authorised bucket endpoint, so retries overwrite the same object.
+	Target string `json:"target"`
+
+	// Endpoint or bucket URL the exports are delivered to.
+	URL string `json:"url"`
+
+	// Headers added to the delivery requests, e.g. for authentication.
+	Headers map[string]string `json:"headers"`
+
+	// Prefix of the object names, used by the `object_storage` target.
+	ObjectPrefix string `json:"object_prefix"`
+
+	// Timeout in seconds of the delivery requests. Defaults to 30.
+	Timeout int64 `json:"timeout"`
+}
+
+type TrafficSamplesConfig struct {
+	// Bucket URL the samples of the APIs enabling `traffic_samples` are uploaded to, e.g. a
+	// pre-authorised bucket endpoint. Each batch is PUT as a JSON lines object named
+	// `<object_prefix><api id>/<timestamp>-<random>.jsonl`.
+	URL string `json:"url"`
+
+	// Headers added to the upload requests, e.g. for authentication.
+	Headers map[string]string `json:"headers"`
+
+	// Prefix of the object names.
+	ObjectPrefix string `json:"object_prefix"`
+
+	// Number of samples of an API uploaded together. Defaults to 100.
+	BatchSize int `json:"batch_size"`
+
+	// Maximum number of seconds samples are buffered before being uploaded. Defaults to 60.
+	FlushInterval int64 `json:"flush_interval"`
+
+	// Timeout in seconds of the upload requests. Defaults to 30.
+	Timeout int64 `json:"timeout"`
+}
+
+type DebugCaptureConfig struct {
+	// Maximum duration in seconds of the debug captures. Defaults to 3600.
+	MaxTTL int64 `json:"max_ttl"`
+
+	// Maximum number of request and response pairs recorded by a capture. Defaults to 1000.
+	MaxRecords int `json:"max_records"`
+
+	// Number of seconds the records stored in Redis are kept after the capture ends. Defaults to 86400.
+	Retention int64 `json:"retention"`
+
+	// Directory of the records of the captures stored in files, one `<api id>.jsonl` file per API. Required
+	// by the `file` storage.
+	Path string `json:"path"`
+}
+
+type RateLimitStorageConfig struct {
+	// Driver of the storage of the rate limiters. Possible values: redis, memory, memcached, dynamodb.
+	// `redis` is the default and counts the requests of the rolling windows exactly.
+	// `memory` keeps the counters in each Gateway, so limits are enforced per Gateway rather than
+	// across the cluster, e.g. for regional isolation or deployments without Redis.
+	// `memcached` and `dynamodb` share the counters of the cluster. They approximate the rolling
+	// window with the counters of the current and previous fixed windows, weighting the previous one
+	// by its overlap with the rolling window, and may let a few more requests through than `redis`.
+	Driver string `json:"driver"`
+
+	Memcached MemcachedConfig `json:"memcached"`
+
+	DynamoDB DynamoDBConfig `json:"dynamodb"`
+}
+
+// APIDefinitionTemplatingConfig configures the placeholders of API definitions. `${NAME}` is replaced by the
+// variable NAME of Variables, or else by the environment variable NAME if it starts with `TYK_API_VAR_`.
+// APIs using other variables aren't loaded. `${NAME:-default}` falls back to default when NAME isn't set,
+// and `$${NAME}` is kept as `${NAME}`.
+type APIDefinitionTemplatingConfig struct {
+	Enabled   bool              `json:"enabled"`
+	Variables map[string]string `json:"variables"`
+}
+
+// ACMEConfig configures the provisioning of certificates with an ACME certificate authority, such as
+// Let's Encrypt, using the HTTP-01 and TLS-ALPN-01 challenges.
+type ACMEConfig struct {
+	// Enabled requests certificates for the custom domains of the APIs and the Domains which have
+	// no certificate configured. The certificates are stored with the certificate manager and
+	// renewed before they expire.
+	Enabled bool `json:"enabled"`
+	// DirectoryURL is the ACME directory of the certificate authority, Let's Encrypt if empty.
+	DirectoryURL string `json:"directory_url"`
+	// Email is the contact of the ACME account, notified of problems with the certificates.
+	Email string `json:"email"`
+	// Domains are requested certificates in addition to the custom domains of the APIs.
+	Domains []string `json:"domains"`
+	// RenewBefore is the number of days before expiry a certificate is renewed, 30 if 0.
+	RenewBefore int `json:"renew_before"`
+}
+
+// DomainTLSPolicy sets the TLS settings of the connections to some domains, e.g. to host APIs with
+// different compliance requirements on the same gateway.
+type DomainTLSPolicy struct {
+	// Domains are the server names the policy applies to, e.g. api.example.com, or *.example.com
+	// for the subdomains of example.com.
+	Domains []string `json:"domains"`
+	// MinVersion and MaxVersion are the TLS versions allowed, the versions of the HTTP server
+	// options when 0.
+	MinVersion uint16 `json:"min_version"`
+	MaxVersion uint16 `json:"max_version"`
+	// Ciphers are the cipher suites allowed up to TLS 1.2, the ciphers of the HTTP server options
+	// when empty. The TLS 1.3 cipher suites can't be configured.
+	Ciphers []string `json:"ssl_ciphers"`
+	// ALPN are the application protocols negotiated, in order of preference, e.g. h2 and
+	// http/1.1. The protocols of the HTTP server options are used when empty.
+	ALPN []string `json:"alpn"`
+	// ClientAuth is the client certificate requirement: none, request, require, verify_if_given
+	// or require_and_verify. When empty it is set from the APIs of the domain using mutual TLS.
+	ClientAuth string `json:"client_auth"`
+	// ClientCertificates are the certificates, IDs or files, the client certificates are verified
+	// with in addition to the certificates of the APIs of the domain.
+	ClientCertificates []string `json:"client_certificates"`
+}
+
+// GatewayFederationConfig configures the hops between federated gateways. The hops are signed with a secret
+// shared by the gateways, the connections between them can be authenticated with mutual TLS by setting the
+// upstream certificates of the forwarding APIs and the client certificates of the receiving ones.
+type GatewayFederationConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Name of the gateway in the hops, e.g. its region. Defaults to the node ID.
+	Name string `json:"name"`
+
+	// Secret signing and verifying the hop headers.
+	Secret string `json:"secret"`
+
+	// Maximum number of gateways a request goes through before reaching this one. Defaults to 5.
+	MaxHops int `json:"max_hops"`
+
+	// Number of seconds a hop header is valid for. Defaults to 30. A hop header is only accepted once, for the
+	// method, path and host it was signed for.
+	HopTTL int64 `json:"hop_ttl"`
+}
+
+// AppGitConfig configures a Git repository the API definitions are loaded from. The gateway runs the git command,
+// the repository is authenticated with an SSH key or an HTTPS token.
+type AppGitConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// URL of the repository, e.g. `https://github.com/org/apis.git` or `git@github.com:org/apis.git`.
+	URL string `json:"url"`
+
+	// Branch checked out. Defaults to the default branch of the repository.
+	Branch string `json:"branch"`
+
+	// Path of the directory of the API definitions in the repository. Defaults to its root.
+	Path string `json:"path"`
+
+	// Directory the repository is cloned into, it must be empty or a checkout of the repository. Defaults to a
+	// private directory created in the temporary directory, so the checkout of a previous run isn't loaded when
+	// the repository is unreachable.
+	CheckoutDir string `json:"checkout_dir"`
+
+	// Private key file authenticating SSH URLs.
+	SSHKeyPath string `json:"ssh_key_path"`
+
+	// Token authenticating HTTPS URLs, sent as the password of HTTP basic authentication.
+	Token string `json:"token"`
+
+	// Number of seconds between polls of the repository, it isn't polled if 0.
+	PollInterval int `json:"poll_interval"`
+
+	// Secret of the /tyk/git/webhook endpoint, which syncs the repository on push. The endpoint isn't
+	// registered if empty. It is checked against the X-Hub-Signature-256 HMAC of GitHub, or the X-Gitlab-Token
+	// header of GitLab.
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+// KubernetesConfig configures the Kubernetes ConfigMaps and Secrets the API definitions and policies are loaded
+// from. They are selected by label, and their kind set by the `tyk.io/kind` label, `api` or `policy`. Each key
+// ending in `.json` is an API definition or a policy.
+type KubernetesConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Namespace of the ConfigMaps and Secrets. Defaults to the namespace of the gateway pod.
+	Namespace string `json:"namespace"`
+
+	// Label selector of the ConfigMaps and Secrets. Defaults to `tyk.io/kind`.
+	LabelSelector string `json:"label_selector"`
+
+	// URL of the Kubernetes API server. Defaults to the in-cluster address, authenticated with the service
+	// account of the pod.
+	APIServer string `json:"api_server"`
+
+	// Service account token file and CA certificate file. Default to the ones mounted in the pod.
+	TokenFile string `json:"token_file"`
+	CAFile    string `json:"ca_file"`
+}
+
+type MemcachedConfig struct {
+	// Addresses of the memcached servers, as `host:port`. Keys are distributed among them by hash.
+	Addresses []string `json:"addresses"`
+
+	// Timeout in seconds of the connections and commands. Defaults to 1.
+	Timeout float64 `json:"timeout"`
+}
+
+type DynamoDBConfig struct {
+	// Table of the counters. Its partition key must be the `key` string attribute, and its TTL
+	// attribute should be `expires` so expired counters are deleted.
+	Table string `json:"table"`
+
+	// Region of the table. Defaults to the AWS_REGION environment variable.
+	Region string `json:"region"`
+
+	// Credentials of the gateway. Default to the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN environment variables.
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+
+	// Endpoint overrides the endpoint of the region, e.g. with a VPC endpoint.
+	Endpoint string `json:"endpoint"`
+
+	// Timeout in seconds of the requests. Defaults to 1.
+	Timeout float64 `json:"timeout"`
 }
 
 type NewRelicConfig struct {
@@ -566,6 +954,15 @@ type Config struct {
 	// Disable dynamic API and Policy reloads, e.g. it will load new changes only on procecss start.
 	SuppressRedisSignalReload bool `json:"suppress_redis_signal_reload"`
 
+	// Rebuild only the APIs which changed on reloads. The definitions are compared by checksum with the loaded
+	// ones, the unchanged APIs keep their middleware chains. APIs sharing a listen path with another API, and
+	// all APIs when the configuration changed, are rebuilt.
+	EnableIncrementalReload bool `json:"enable_incremental_reload"`
+
+	// The number of APIs whose definitions are parsed and middleware chains built concurrently on reloads.
+	// Defaults to the number of CPUs, set to 1 to load the APIs one after another.
+	APILoadWorkers int `json:"api_load_workers"`
+
 	// Enable Key hashing
 	HashKeys bool `json:"hash_keys"`
 
@@ -578,6 +975,13 @@ type Config struct {
 	// Allows the listing of hashed API keys
 	EnableHashedKeysListing bool `json:"enable_hashed_keys_listing"`
 
+	// Default time in seconds during which a rotated key keeps authenticating, used when a key
+	// rotation request doesn't set `grace_period`. Rotated keys stop authenticating immediately when 0.
+	KeyRotationGracePeriod int64 `json:"key_rotation_grace_period"`
+
+	// Configures how basic auth passwords are hashed.
+	BasicAuthHash BasicAuthHashConfig `json:"basic_auth_hash"`
+
 	// Minimum API token length
 	MinTokenLength int `json:"min_token_length"`
 
@@ -602,6 +1006,14 @@ type Config struct {
 	// See the API section of the Tyk Gateway API for more details.
 	AppPath string `json:"app_path"`
 
+	// Load the API definitions from a Git repository instead of AppPath. The repository is cloned at startup and
+	// synced on poll or webhook, the APIs are reloaded when it changes.
+	AppGit AppGitConfig `json:"app_git"`
+
+	// Load the API definitions, and the policies if `policies.policy_source` is set to `kubernetes`, from the
+	// ConfigMaps and Secrets of a Kubernetes namespace. They are watched, the gateway reloads when they change.
+	Kubernetes KubernetesConfig `json:"kubernetes"`
+
 	// If you are a Tyk Pro user, this option will enable polling the Dashboard service for API definitions.
 	// On startup Tyk will attempt to connect and download any relevant application configurations from from your Dashboard instance.
 	// The files are exactly the same as the JSON files on disk with the exception of a BSON ID supplied by the Dashboard service.
@@ -656,6 +1068,9 @@ type Config struct {
 	// Controls which algorthm to use as a fallback when your distributed rate limiter can't be used.
 	DRLEnableSentinelRateLimiter bool `json:"drl_enable_sentinel_rate_limiter"`
 
+	// Storage of the Redis and sentinel rate limiters, and of the distributed rate limiter when it falls back to them.
+	RateLimitStorage RateLimitStorageConfig `json:"rate_limit_storage"`
+
 	// Allows you to dynamically configure analytics expiration on a per organisation level
 	EnforceOrgDataAge bool `json:"enforce_org_data_age"`
 
@@ -749,6 +1164,10 @@ type Config struct {
 	// Enables you to rename the /hello endpoint
 	HealthCheckEndpointName string `json:"health_check_endpoint_name"`
 
+	// The time in seconds the in-flight requests have to finish when the gateway is drained with the
+	// /tyk/drain endpoint, before it exits. Defaults to 30 seconds.
+	DrainTimeout int `json:"drain_timeout"`
+
 	// Change the expiry time of a refresh token. By default 14 days (in seconds).
 	OauthRefreshExpire int64 `json:"oauth_refresh_token_expire"`
 
@@ -833,6 +1252,24 @@ type Config struct {
 	// Set the execution timeout for JSVM plugins and virtal endpoints
 	JSVMTimeout int `json:"jsvm_timeout"`
 
+	// The number of copies of the JS VM of an API prepared ahead of the requests running its JSVM plugins and
+	// virtual endpoints. Each request runs on a fresh copy discarded afterwards, so that the globals set by a
+	// request don't leak to the others. By default the copies are made by the requests.
+	JSVMPoolSize int `json:"jsvm_pool_size"`
+
+	// Limit the depth of the call stack of the JSVM, e.g. for a runaway recursion to fail instead of exhausting
+	// the memory. 0, the default, doesn't limit it.
+	JSVMStackDepthLimit int `json:"jsvm_stack_depth_limit"`
+
+	// Limit the memory in bytes a run of the JSVM can allocate, runs growing the heap of the gateway by more are
+	// interrupted. The heap of the whole gateway is measured, so the requests running meanwhile count towards the
+	// limit, which should leave room for them. 0, the default, doesn't limit it.
+	JSVMMaxMemory int64 `json:"jsvm_max_memory"`
+
+	// Limit the size in bytes of the data returned by JSVM plugins and virtual endpoints, larger results are
+	// discarded. 0, the default, doesn't limit it.
+	JSVMMaxReturnSize int `json:"jsvm_max_return_size"`
+
 	// Disable virtual endpoints and the code will not be loaded into the VM when the API definition initialises.
 	// This is useful for systems where you want to avoid having third-party code run.
 	DisableVirtualPathBlobs bool `json:"disable_virtual_path_blobs"`
@@ -903,6 +1340,22 @@ type Config struct {
 	// Show 404 HTTP errors in your Gateway application logs
 	Track404Logs bool `json:"track_404_logs"`
 
+	// Access logs produce one structured JSON record per proxied request, separate from the application logs.
+	AccessLogs AccessLogsConfig `json:"access_logs"`
+
+	// Request ID generation and propagation, for the requests to be correlated across systems.
+	RequestID RequestIDConfig `json:"request_id"`
+
+	// Billing export produces per key and API usage rollups for each period and delivers them to a billing system.
+	BillingExport BillingExportConfig `json:"billing_export"`
+
+	// Traffic samples uploads the masked request and response pairs sampled by APIs to object storage.
+	TrafficSamples TrafficSamplesConfig `json:"traffic_samples"`
+
+	// Debug captures record the full request and response pairs of an API for a limited time, started with the
+	// `/tyk/apis/{apiID}/capture` endpoint, to debug issues only reproduced in production.
+	DebugCapture DebugCaptureConfig `json:"debug_capture"`
+
 	// Address of StatsD server. If set enable statsd monitoring.
 	StatsdConnectionString string `json:"statsd_connection_string"`
 	// StatsD prefix
@@ -927,13 +1380,28 @@ type Config struct {
 	// This section enables the use of the KV capabilites to substitute configuration values.
 	// See more details https://tyk.io/docs/tyk-configuration-reference/kv-store/
 	KV struct {
-		Consul ConsulConfig `json:"consul"`
-		Vault  VaultConfig  `json:"vault"`
+		Consul ConsulConfig            `json:"consul"`
+		Vault  VaultConfig             `json:"vault"`
+		AWS    AWSSecretsManagerConfig `json:"aws"`
 	} `json:"kv"`
 
 	// Secrets are key-value pairs that can be accessed in the dashboard via "secrets://"
 	Secrets map[string]string `json:"secrets"`
 
+	// Number of seconds the secret references of API definitions, e.g. `vault://kv/apis/foo#secret`,
+	// are cached for. Cached references are then resolved again, and the APIs reloaded when a
+	// secret changed. Defaults to 300.
+	SecretsCacheTTL int64 `json:"secrets_cache_ttl"`
+
+	// Enables `${NAME}` placeholders in the values of API definitions, resolved when the APIs are loaded so that
+	// a definition can be promoted unchanged across environments. APIs using a variable which isn't set aren't loaded.
+	APIDefinitionTemplating APIDefinitionTemplatingConfig `json:"api_definition_templating"`
+
+	// Chains gateways of different domains, e.g. regional gateways routing to each other: requests proxied to
+	// a federated gateway carry a signed hop header with the identity of the consumer, which federated gateways
+	// verify, rejecting loops and chains longer than the hop limit.
+	GatewayFederation GatewayFederationConfig `json:"gateway_federation"`
+
 	// Override the default error code and or message returned by middleware.
 	// The following message IDs can be used to override the message and error codes:
 	//
@@ -958,6 +1426,21 @@ type Config struct {
 	// ```
 	OverrideMessages map[string]TykError `bson:"override_messages" json:"override_messages"`
 
+	// Templates of the error responses of all the APIs, by status code and content type. The error templates of an API
+	// take precedence over these, see `error_templates` in the API definition.
+	//
+	// Sample Error Templates Setting
+	// ```
+	// "error_templates": [
+	//   {
+	//     "status_code": 429,
+	//     "content_type": "text/html",
+	//     "body": "<p>{{ .Message }}, please retry later</p>"
+	//   }
+	// ]
+	// ```
+	ErrorTemplates []apidef.ErrorTemplate `json:"error_templates"`
+
 	// Cloud flag shows the Gateway runs in Tyk-cloud.
 	Cloud bool `json:"cloud"`
 
@@ -994,6 +1477,21 @@ type VaultConfig struct {
 	KVVersion int `json:"kv_version"`
 }
 
+// AWSSecretsManagerConfig is used to configure the access to AWS Secrets Manager
+type AWSSecretsManagerConfig struct {
+	// Region of the secrets. Defaults to the AWS_REGION environment variable.
+	Region string `json:"region"`
+
+	// Credentials of the gateway. Default to the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN environment variables.
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+
+	// Endpoint overrides the endpoint of the region, e.g. with a VPC endpoint.
+	Endpoint string `json:"endpoint"`
+}
+
 // ConsulConfig is used to configure the creation of a client
 // This is a stripped down version of the Config struct in consul's API client
 type ConsulConfig struct {
@@ -1144,7 +1642,7 @@ func Load(paths []string, conf *Config) error {
 	if err := envconfig.Process(envPrefix, conf); err != nil {
 		return fmt.Errorf("failed to process config env vars: %v", err)
 	}
-	if err := processCustom(envPrefix, conf, loadZipkin, loadJaeger); err != nil {
+	if err := processCustom(envPrefix, conf, loadZipkin, loadJaeger, loadOTLP); err != nil {
 		return fmt.Errorf("failed to process config custom loader: %v", err)
 	}
 	return nil
@@ -47,6 +47,29 @@ type Sampler struct {
 	Mod uint64 `json:"mod"`
 }
 
+// OTLPConfig configuration options used to initialize the OpenTelemetry tracer,
+// which exports spans with the OTLP/HTTP protocol.
+type OTLPConfig struct {
+	// Endpoint is the OTLP/HTTP traces endpoint, defaults to http://localhost:4318/v1/traces
+	Endpoint string `json:"endpoint"`
+	// Headers are added to every export request, for instance to authenticate with the collector
+	Headers map[string]string `json:"headers"`
+	// SampleRatio is the share of new traces which are recorded, from 0 to 1. Zero records
+	// all traces. Traces started upstream of the gateway keep their sampling decision.
+	SampleRatio float64 `json:"sample_ratio"`
+	// BatchSize is the maximum number of spans sent in one export request
+	BatchSize int `json:"batch_size"`
+	// MaxBacklog is the number of finished spans waiting to be exported before new ones are dropped
+	MaxBacklog int `json:"max_backlog"`
+	// FlushInterval is the maximum time in milliseconds a span waits before being exported
+	FlushInterval int `json:"flush_interval"`
+	// Timeout is the export request timeout in seconds
+	Timeout int `json:"timeout"`
+	// LogsEndpoint is the OTLP/HTTP logs endpoint, for instance http://localhost:4318/v1/logs.
+	// When set the gateway logs are exported with the trace and span IDs of the requests.
+	LogsEndpoint string `json:"logs_endpoint"`
+}
+
 // DecodeJSON marshals src to json and tries to unmarshal the result into
 // dest.
 func DecodeJSON(dest, src interface{}) error {
@@ -97,6 +120,39 @@ func loadZipkin(prefix string, c *Config) error {
 	return nil
 }
 
+// loadOTLP tries to load OpenTelemetry configuration from environment variables.
+//
+// list of OpenTelemetry configuration env variables
+//
+// TYK_GW_TRACER_OPTIONS_ENDPOINT
+// TYK_GW_TRACER_OPTIONS_HEADERS
+// TYK_GW_TRACER_OPTIONS_SAMPLERATIO
+// TYK_GW_TRACER_OPTIONS_BATCHSIZE
+// TYK_GW_TRACER_OPTIONS_MAXBACKLOG
+// TYK_GW_TRACER_OPTIONS_FLUSHINTERVAL
+// TYK_GW_TRACER_OPTIONS_TIMEOUT
+// TYK_GW_TRACER_OPTIONS_LOGSENDPOINT
+func loadOTLP(prefix string, c *Config) error {
+	if c.Tracer.Name != "otlp" {
+		return nil
+	}
+	var otlp OTLPConfig
+	if err := DecodeJSON(&otlp, c.Tracer.Options); err != nil {
+		return err
+	}
+	qualifyPrefix := prefix + "_TRACER_OPTIONS"
+	err := envconfig.Process(qualifyPrefix, &otlp)
+	if err != nil {
+		return err
+	}
+	o := make(map[string]interface{})
+	if err := DecodeJSON(&o, otlp); err != nil {
+		return err
+	}
+	c.Tracer.Options = o
+	return nil
+}
+
 // loads jaeger configuration from environment variables.
 //
 // List of jaeger configuration env vars
@@ -23,6 +23,11 @@ class DispatcherStub(object):
                 request_serializer=coprocess__object__pb2.Event.SerializeToString,
                 response_deserializer=coprocess__object__pb2.EventReply.FromString,
                 )
+        self.DispatchStream = channel.stream_unary(
+                '/coprocess.Dispatcher/DispatchStream',
+                request_serializer=coprocess__object__pb2.Object.SerializeToString,
+                response_deserializer=coprocess__object__pb2.Object.FromString,
+                )
 
 
 class DispatcherServicer(object):
@@ -40,6 +45,15 @@ class DispatcherServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def DispatchStream(self, request_iterator, context):
+        """DispatchStream receives the object of a request or response with a large body in several
+        messages: the first one is the object without the body, the next ones carry chunks of the
+        raw body in request.raw_body, or in response.raw_body for response hooks.
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_DispatcherServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -53,6 +67,11 @@ def add_DispatcherServicer_to_server(servicer, server):
                     request_deserializer=coprocess__object__pb2.Event.FromString,
                     response_serializer=coprocess__object__pb2.EventReply.SerializeToString,
             ),
+            'DispatchStream': grpc.stream_unary_rpc_method_handler(
+                    servicer.DispatchStream,
+                    request_deserializer=coprocess__object__pb2.Object.FromString,
+                    response_serializer=coprocess__object__pb2.Object.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'coprocess.Dispatcher', rpc_method_handlers)
@@ -94,3 +113,19 @@ class Dispatcher(object):
             coprocess__object__pb2.EventReply.FromString,
             options, channel_credentials,
             call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def DispatchStream(request_iterator,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.stream_unary(request_iterator, target, '/coprocess.Dispatcher/DispatchStream',
+            coprocess__object__pb2.Object.SerializeToString,
+            coprocess__object__pb2.Object.FromString,
+            options, channel_credentials,
+            call_credentials, compression, wait_for_ready, timeout, metadata)
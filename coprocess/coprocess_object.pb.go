@@ -193,33 +193,34 @@ func init() {
 func init() { proto.RegisterFile("coprocess_object.proto", fileDescriptor_72698a2223f86099) }
 
 var fileDescriptor_72698a2223f86099 = []byte{
-	// 412 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0x4b, 0x6f, 0xd4, 0x30,
-	0x10, 0x80, 0x9b, 0x6e, 0x77, 0x37, 0x99, 0x52, 0x54, 0xcc, 0xcb, 0xa4, 0xa0, 0x86, 0x70, 0xc9,
-	0x29, 0x40, 0x10, 0x0f, 0xb5, 0x57, 0x2a, 0x71, 0x29, 0x48, 0x5e, 0xee, 0x91, 0x9b, 0x8e, 0xb4,
-	0x61, 0x37, 0xb6, 0x89, 0xdd, 0x4a, 0x91, 0xf8, 0xab, 0xfc, 0x17, 0x54, 0xdb, 0xc9, 0x66, 0xd9,
-	0x53, 0x6f, 0xf6, 0xcc, 0xf7, 0x79, 0x1e, 0x09, 0x3c, 0xab, 0xa4, 0x6a, 0x65, 0x85, 0x5a, 0x97,
-	0xf2, 0xea, 0x17, 0x56, 0x26, 0x57, 0xad, 0x34, 0x92, 0x44, 0x43, 0x3c, 0x7e, 0xb3, 0x41, 0x9a,
-	0x5a, 0xd4, 0x65, 0x8b, 0xbf, 0x6f, 0x50, 0x9b, 0x2d, 0x3e, 0x3e, 0xdd, 0x40, 0x2d, 0x6a, 0x25,
-	0x85, 0xc6, 0x6d, 0xe0, 0xd5, 0x06, 0xd0, 0xa8, 0x75, 0x2d, 0x45, 0xa9, 0x0d, 0x37, 0xe8, 0xd3,
-	0xa3, 0x3e, 0x2a, 0xd9, 0x34, 0x52, 0xb8, 0x78, 0xfa, 0x77, 0x02, 0xb3, 0x1f, 0xf6, 0x1d, 0xf2,
-	0x0e, 0xa2, 0xa5, 0x94, 0xab, 0xd2, 0x74, 0x0a, 0x69, 0x90, 0x04, 0xd9, 0xc3, 0xe2, 0x71, 0x3e,
-	0x68, 0xf9, 0x37, 0x29, 0x57, 0x3f, 0x3b, 0x85, 0x2c, 0x5c, 0xfa, 0x13, 0x39, 0xf1, 0x86, 0xe0,
-	0x0d, 0xd2, 0xfd, 0x24, 0xc8, 0x22, 0x97, 0xfc, 0xce, 0x1b, 0x24, 0x9f, 0x60, 0xee, 0x27, 0xa1,
-	0x93, 0x24, 0xc8, 0x0e, 0x8b, 0x97, 0xa3, 0xc7, 0x2e, 0x6b, 0x51, 0x33, 0x97, 0x75, 0xd5, 0x59,
-	0x0f, 0x93, 0xf7, 0x30, 0xf7, 0x03, 0xd0, 0x03, 0xeb, 0x3d, 0x1f, 0x79, 0x0b, 0x97, 0x59, 0xdc,
-	0x4d, 0xc6, 0x7a, 0x8e, 0x9c, 0x43, 0xd8, 0xa0, 0xe1, 0xd7, 0xdc, 0x70, 0x3a, 0x4d, 0x26, 0xd9,
-	0x61, 0x71, 0x3a, 0x72, 0x5c, 0x81, 0xfc, 0xd2, 0x13, 0x17, 0xc2, 0xb4, 0x1d, 0x1b, 0x04, 0xf2,
-	0x16, 0x0e, 0xb4, 0xc2, 0x8a, 0xce, 0xac, 0x78, 0xb2, 0x2b, 0x2e, 0x14, 0x56, 0x4e, 0xb2, 0x20,
-	0xf9, 0x08, 0x61, 0xff, 0x09, 0xe8, 0xdc, 0x76, 0xf8, 0x62, 0x24, 0x31, 0x9f, 0xf2, 0x63, 0x0d,
-	0x68, 0x7c, 0x0e, 0x47, 0x5b, 0x2d, 0x90, 0x63, 0x98, 0xac, 0xb0, 0xb3, 0x9b, 0x8e, 0xd8, 0xdd,
-	0x91, 0x3c, 0x81, 0xe9, 0x2d, 0x5f, 0xdf, 0xf4, 0xbb, 0x74, 0x97, 0xb3, 0xfd, 0x2f, 0x41, 0xfc,
-	0x19, 0xa2, 0xa1, 0x8d, 0xfb, 0x88, 0xe9, 0x6b, 0x98, 0x5e, 0xdc, 0xa2, 0x30, 0x84, 0xc2, 0x5c,
-	0xf1, 0x6e, 0x2d, 0xf9, 0xb5, 0x17, 0xfb, 0x6b, 0xfa, 0x00, 0xc0, 0x22, 0x0c, 0xd5, 0xba, 0x2b,
-	0xfe, 0x00, 0x7c, 0xad, 0xb5, 0xe2, 0xa6, 0x5a, 0x62, 0x4b, 0x0a, 0x08, 0xfb, 0x1b, 0x79, 0xb4,
-	0xb3, 0x9a, 0x78, 0x37, 0x94, 0xee, 0x91, 0x33, 0x38, 0xea, 0x1d, 0x57, 0xfa, 0x78, 0x44, 0xd9,
-	0x48, 0xfc, 0xf4, 0xff, 0x88, 0xad, 0x9d, 0xee, 0x5d, 0xcd, 0xec, 0x5f, 0xf9, 0xe1, 0x5f, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x99, 0x33, 0xf3, 0x0f, 0x37, 0x03, 0x00, 0x00,
+	// 420 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0x49, 0x6f, 0xd4, 0x30,
+	0x14, 0x80, 0x49, 0xa7, 0xb3, 0xe4, 0x95, 0x56, 0xc5, 0x6c, 0x26, 0x05, 0x75, 0x18, 0x2e, 0x39,
+	0x05, 0x08, 0x62, 0x51, 0x7b, 0xa5, 0x12, 0x97, 0x82, 0xe4, 0x70, 0x8f, 0xdc, 0xf4, 0x49, 0x13,
+	0x66, 0x62, 0x9b, 0xd8, 0xad, 0x94, 0x7f, 0xc6, 0x9f, 0xe1, 0xbf, 0xa0, 0x7a, 0xc9, 0x64, 0x98,
+	0x0b, 0xdc, 0xec, 0xf7, 0xbe, 0xcf, 0x6f, 0x49, 0xe0, 0x49, 0x25, 0x55, 0x2b, 0x2b, 0xd4, 0xba,
+	0x94, 0x57, 0x3f, 0xb0, 0x32, 0x99, 0x6a, 0xa5, 0x91, 0x24, 0xee, 0xe3, 0xc9, 0xab, 0x0d, 0xd2,
+	0xd4, 0xa2, 0x2e, 0x5b, 0xfc, 0x79, 0x83, 0xda, 0x6c, 0xf1, 0xc9, 0xe9, 0x06, 0x6a, 0x51, 0x2b,
+	0x29, 0x34, 0x6e, 0x03, 0x2f, 0x36, 0x80, 0x46, 0xad, 0x6b, 0x29, 0x4a, 0x6d, 0xb8, 0x41, 0x9f,
+	0x1e, 0xf4, 0x51, 0xc9, 0xa6, 0x91, 0xc2, 0xc5, 0x17, 0xbf, 0x47, 0x30, 0xf9, 0x66, 0xdf, 0x21,
+	0x6f, 0x20, 0x5e, 0x4a, 0xb9, 0x2a, 0x4d, 0xa7, 0x90, 0x46, 0xf3, 0x28, 0x3d, 0xca, 0x1f, 0x66,
+	0xbd, 0x96, 0x7d, 0x91, 0x72, 0xf5, 0xbd, 0x53, 0xc8, 0x66, 0x4b, 0x7f, 0x22, 0x27, 0xde, 0x10,
+	0xbc, 0x41, 0xba, 0x37, 0x8f, 0xd2, 0xd8, 0x25, 0xbf, 0xf2, 0x06, 0xc9, 0x07, 0x98, 0xfa, 0x49,
+	0xe8, 0x68, 0x1e, 0xa5, 0x07, 0xf9, 0xf3, 0xc1, 0x63, 0x97, 0xb5, 0xa8, 0x99, 0xcb, 0xba, 0xea,
+	0x2c, 0xc0, 0xe4, 0x2d, 0x4c, 0xfd, 0x00, 0x74, 0xdf, 0x7a, 0x4f, 0x07, 0x5e, 0xe1, 0x32, 0xc5,
+	0xdd, 0x64, 0x2c, 0x70, 0xe4, 0x1c, 0x66, 0x0d, 0x1a, 0x7e, 0xcd, 0x0d, 0xa7, 0xe3, 0xf9, 0x28,
+	0x3d, 0xc8, 0x4f, 0x07, 0x8e, 0x2b, 0x90, 0x5d, 0x7a, 0xe2, 0x42, 0x98, 0xb6, 0x63, 0xbd, 0x40,
+	0x5e, 0xc3, 0xbe, 0x56, 0x58, 0xd1, 0x89, 0x15, 0x4f, 0x76, 0xc5, 0x42, 0x61, 0xe5, 0x24, 0x0b,
+	0x92, 0xf7, 0x30, 0x0b, 0x9f, 0x80, 0x4e, 0x6d, 0x87, 0xcf, 0x06, 0x12, 0xf3, 0x29, 0x3f, 0x56,
+	0x8f, 0x26, 0xe7, 0x70, 0xb8, 0xd5, 0x02, 0x39, 0x86, 0xd1, 0x0a, 0x3b, 0xbb, 0xe9, 0x98, 0xdd,
+	0x1d, 0xc9, 0x23, 0x18, 0xdf, 0xf2, 0xf5, 0x4d, 0xd8, 0xa5, 0xbb, 0x9c, 0xed, 0x7d, 0x8a, 0x92,
+	0x8f, 0x10, 0xf7, 0x6d, 0xfc, 0x8f, 0xb8, 0x78, 0x09, 0xe3, 0x8b, 0x5b, 0x14, 0x86, 0x50, 0x98,
+	0x2a, 0xde, 0xad, 0x25, 0xbf, 0xf6, 0x62, 0xb8, 0x2e, 0xee, 0x03, 0x58, 0x84, 0xa1, 0x5a, 0x77,
+	0xf9, 0xaf, 0x08, 0xe0, 0x73, 0xad, 0x15, 0x37, 0xd5, 0x12, 0x5b, 0x92, 0xc3, 0x2c, 0xdc, 0xc8,
+	0x83, 0x9d, 0xdd, 0x24, 0xbb, 0xa1, 0xc5, 0x3d, 0x72, 0x06, 0x87, 0xc1, 0x71, 0xb5, 0x8f, 0x07,
+	0x94, 0x8d, 0x24, 0x8f, 0xff, 0x8e, 0xd8, 0xe2, 0xd6, 0x3d, 0x0a, 0x6e, 0x61, 0x5a, 0xe4, 0xcd,
+	0xbf, 0x56, 0x4d, 0xa3, 0xab, 0x89, 0xfd, 0xa5, 0xdf, 0xfd, 0x19, 0x00, 0xd2, 0xd0, 0x36, 0xac,
+	0x74, 0x03, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -236,6 +237,10 @@ const _ = grpc.SupportPackageIsVersion6
 type DispatcherClient interface {
 	Dispatch(ctx context.Context, in *Object, opts ...grpc.CallOption) (*Object, error)
 	DispatchEvent(ctx context.Context, in *Event, opts ...grpc.CallOption) (*EventReply, error)
+	// DispatchStream receives the object of a request or response with a large body in several
+	// messages: the first one is the object without the body, the next ones carry chunks of the
+	// raw body in request.raw_body, or in response.raw_body for response hooks.
+	DispatchStream(ctx context.Context, opts ...grpc.CallOption) (Dispatcher_DispatchStreamClient, error)
 }
 
 type dispatcherClient struct {
@@ -264,10 +269,48 @@ func (c *dispatcherClient) DispatchEvent(ctx context.Context, in *Event, opts ..
 	return out, nil
 }
 
+func (c *dispatcherClient) DispatchStream(ctx context.Context, opts ...grpc.CallOption) (Dispatcher_DispatchStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Dispatcher_serviceDesc.Streams[0], "/coprocess.Dispatcher/DispatchStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dispatcherDispatchStreamClient{stream}
+	return x, nil
+}
+
+type Dispatcher_DispatchStreamClient interface {
+	Send(*Object) error
+	CloseAndRecv() (*Object, error)
+	grpc.ClientStream
+}
+
+type dispatcherDispatchStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *dispatcherDispatchStreamClient) Send(m *Object) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *dispatcherDispatchStreamClient) CloseAndRecv() (*Object, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Object)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DispatcherServer is the server API for Dispatcher service.
 type DispatcherServer interface {
 	Dispatch(context.Context, *Object) (*Object, error)
 	DispatchEvent(context.Context, *Event) (*EventReply, error)
+	// DispatchStream receives the object of a request or response with a large body in several
+	// messages: the first one is the object without the body, the next ones carry chunks of the
+	// raw body in request.raw_body, or in response.raw_body for response hooks.
+	DispatchStream(Dispatcher_DispatchStreamServer) error
 }
 
 // UnimplementedDispatcherServer can be embedded to have forward compatible implementations.
@@ -280,6 +323,9 @@ func (*UnimplementedDispatcherServer) Dispatch(ctx context.Context, req *Object)
 func (*UnimplementedDispatcherServer) DispatchEvent(ctx context.Context, req *Event) (*EventReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DispatchEvent not implemented")
 }
+func (*UnimplementedDispatcherServer) DispatchStream(srv Dispatcher_DispatchStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method DispatchStream not implemented")
+}
 
 func RegisterDispatcherServer(s *grpc.Server, srv DispatcherServer) {
 	s.RegisterService(&_Dispatcher_serviceDesc, srv)
@@ -321,6 +367,32 @@ func _Dispatcher_DispatchEvent_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Dispatcher_DispatchStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DispatcherServer).DispatchStream(&dispatcherDispatchStreamServer{stream})
+}
+
+type Dispatcher_DispatchStreamServer interface {
+	SendAndClose(*Object) error
+	Recv() (*Object, error)
+	grpc.ServerStream
+}
+
+type dispatcherDispatchStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *dispatcherDispatchStreamServer) SendAndClose(m *Object) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *dispatcherDispatchStreamServer) Recv() (*Object, error) {
+	m := new(Object)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Dispatcher_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "coprocess.Dispatcher",
 	HandlerType: (*DispatcherServer)(nil),
@@ -334,6 +406,12 @@ var _Dispatcher_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Dispatcher_DispatchEvent_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DispatchStream",
+			Handler:       _Dispatcher_DispatchStream_Handler,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "coprocess_object.proto",
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"mime/multipart"
@@ -11,9 +12,13 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"unicode/utf8"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/config"
@@ -32,7 +37,9 @@ const (
 	testHeaderValue = "testvalue"
 )
 
-type dispatcher struct{}
+type dispatcher struct {
+	streamed int32
+}
 
 func (d *dispatcher) grpcError(object *coprocess.Object, errorMsg string) (*coprocess.Object, error) {
 	object.Request.ReturnOverrides.ResponseError = errorMsg
@@ -115,6 +122,46 @@ func (d *dispatcher) DispatchEvent(ctx context.Context, event *coprocess.Event)
 	return &coprocess.EventReply{}, nil
 }
 
+func (d *dispatcher) DispatchStream(stream coprocess.Dispatcher_DispatchStreamServer) error {
+	object, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if object.HookType == coprocess.HookType_Response {
+			object.Response.RawBody = append(object.Response.RawBody, chunk.Response.RawBody...)
+		} else {
+			object.Request.RawBody = append(object.Request.RawBody, chunk.Request.RawBody...)
+		}
+	}
+	if object.Request != nil && utf8.Valid(object.Request.RawBody) {
+		object.Request.Body = string(object.Request.RawBody)
+	}
+	atomic.AddInt32(&d.streamed, 1)
+
+	object, err = d.Dispatch(stream.Context(), object)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(object)
+}
+
+// noStreamDispatcher is a server built before DispatchStream was added.
+type noStreamDispatcher struct {
+	*dispatcher
+}
+
+func (d noStreamDispatcher) DispatchStream(coprocess.Dispatcher_DispatchStreamServer) error {
+	return status.Error(codes.Unimplemented, "method DispatchStream not implemented")
+}
+
 func newTestGRPCServer() (s *grpc.Server) {
 	s = grpc.NewServer(
 		grpc.MaxRecvMsgSize(grpcTestMaxSize),
@@ -459,6 +506,65 @@ func randStringBytes(n int) string {
 	return string(b)
 }
 
+func TestGRPCFailoverAndStreaming(t *testing.T) {
+	d := &dispatcher{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	coprocess.RegisterDispatcherServer(grpcServer, d)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	// nothing listens on the first server
+	ts := gateway.StartTest(nil, gateway.TestConfig{CoprocessConfig: config.CoProcessConfig{
+		EnableCoProcess:        true,
+		CoProcessGRPCServer:    "tcp://127.0.0.1:16501",
+		CoProcessGRPCServers:   []string{"tcp://" + listener.Addr().String()},
+		GRPCConnectionPoolSize: 2,
+		GRPCStreamChunkSize:    16,
+	}})
+	defer ts.Close()
+	loadTestGRPCAPIs(ts)
+
+	keyID := gateway.CreateSession(ts.Gw)
+	headers := map[string]string{"authorization": keyID}
+
+	ts.Run(t, []test.TestCase{
+		{Path: "/grpc-test-api/", Code: http.StatusOK, Headers: headers, BodyMatch: testHeaderValue},
+		{Path: "/grpc-test-api-2/", Code: http.StatusOK, Data: `{"streamed": true}`, Headers: map[string]string{"Content-Type": "application/json"}},
+		{Path: "/grpc-test-api-4/", Code: http.StatusOK, Headers: headers, BodyMatch: "^newbody$"},
+	}...)
+	if streamed := atomic.LoadInt32(&d.streamed); streamed != 2 {
+		t.Fatalf("Expected the request and response bodies to be streamed, got %d streams", streamed)
+	}
+
+	t.Run("server without streaming", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		grpcServer := grpc.NewServer()
+		coprocess.RegisterDispatcherServer(grpcServer, noStreamDispatcher{d})
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		ts := gateway.StartTest(nil, gateway.TestConfig{CoprocessConfig: config.CoProcessConfig{
+			EnableCoProcess:     true,
+			CoProcessGRPCServer: "tcp://" + listener.Addr().String(),
+			GRPCStreamChunkSize: 16,
+		}})
+		defer ts.Close()
+		loadTestGRPCAPIs(ts)
+
+		ts.Run(t, []test.TestCase{
+			{Path: "/grpc-test-api-2/", Code: http.StatusOK, Data: `{"streamed": false}`, Headers: map[string]string{"Content-Type": "application/json"}},
+			{Path: "/grpc-test-api-2/", Code: http.StatusOK, Data: `{"streamed": false}`, Headers: map[string]string{"Content-Type": "application/json"}},
+		}...)
+	})
+}
+
 func TestGRPCIgnore(t *testing.T) {
 	ts, grpcServer := startTykWithGRPC()
 	defer ts.Close()
@@ -39,6 +39,22 @@ const (
 	RequestStatus
 	GraphQLRequest
 	GraphQLIsWebSocketUpgrade
+	UpstreamRetries
+	UpstreamTimeouts
+	AccessLogRecord
+	GraphQLStats
+	GrantedScopes
+	RequestTimings
+	GeoIPCountry
+	BotScore
+	ErrorReason
+	Deprecated
+	TrafficSampled
+	FederationHop
+	ConcurrencyLimited
+	RequestBodyLimit
+	RequestID
+	JWTToken
 )
 
 func setContext(r *http.Request, ctx context.Context) {
@@ -22,7 +22,7 @@ type DialContextFunc func(ctx context.Context, network, address string) (net.Con
 
 // IDnsCacheManager is an interface for abstracting interaction with dns cache. Implemented by DnsCacheManager
 type IDnsCacheManager interface {
-	InitDNSCaching(ttl, checkInterval time.Duration)
+	InitDNSCaching(ttl, checkInterval time.Duration, opts Options)
 	WrapDialer(dialer *net.Dialer) DialContextFunc
 	SetCacheStorage(cache IDnsCacheStorage)
 	CacheStorage() IDnsCacheStorage
@@ -144,13 +144,13 @@ func (m *DnsCacheManager) getRandomIp(ips []string) (string, error) {
 }
 
 // InitDNSCaching initializes manager's cache storage if it wasn't initialized before with provided ttl, checkinterval values
-// Initialized cache storage enables caching of previously hoooked net.Dialer DialContext calls
+// and options. Initialized cache storage enables caching of previously hoooked net.Dialer DialContext calls
 //
 // Otherwise leave storage as is.
-func (m *DnsCacheManager) InitDNSCaching(ttl, checkInterval time.Duration) {
+func (m *DnsCacheManager) InitDNSCaching(ttl, checkInterval time.Duration, opts Options) {
 	if !m.IsCacheEnabled() {
-		logger.Infof("Initializing dns cache with ttl=%s, duration=%s", ttl, checkInterval)
-		storage := NewDnsCacheStorage(ttl, checkInterval)
+		logger.Infof("Initializing dns cache with ttl=%s, duration=%s, options=%+v", ttl, checkInterval, opts)
+		storage := NewDnsCacheStorageWithOptions(ttl, checkInterval, opts)
 		m.SetCacheStorage(IDnsCacheStorage(storage))
 	}
 }
@@ -0,0 +1,139 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	resolvConfPath = "/etc/resolv.conf"
+	// defaultNameserver is queried through the custom dialer of net.DefaultResolver when there is no
+	// resolv.conf, the dialer being free to ignore it.
+	defaultNameserver = "127.0.0.1:53"
+	lookupTimeout     = 5 * time.Second
+)
+
+var (
+	errNoNameservers = errors.New("no nameservers to query")
+	errNoRecords     = errors.New("no A or AAAA records")
+
+	nameserversOnce sync.Once
+	nameservers     []string
+)
+
+func loadNameservers() []string {
+	nameserversOnce.Do(func() {
+		conf, err := dns.ClientConfigFromFile(resolvConfPath)
+		if err != nil {
+			logger.WithError(err).Debug("Couldn't read nameservers, record TTLs are only honoured with a custom resolver dialer")
+			return
+		}
+		for _, server := range conf.Servers {
+			nameservers = append(nameservers, net.JoinHostPort(server, conf.Port))
+		}
+	})
+	return nameservers
+}
+
+// lookupHostTTL resolves the A and AAAA records of host with the lowest TTL of the answers. It
+// queries the nameservers of resolv.conf, through the dialer of net.DefaultResolver if any.
+func lookupHostTTL(host string) ([]string, time.Duration, error) {
+	servers := loadNameservers()
+	resolver := net.DefaultResolver
+	if len(servers) == 0 {
+		if resolver.Dial == nil {
+			return nil, 0, errNoNameservers
+		}
+		servers = []string{defaultNameserver}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	var lastErr error
+	for _, server := range servers {
+		addrs, ttl, err := queryHostTTL(ctx, resolver, server, host)
+		if err == nil {
+			return addrs, ttl, nil
+		}
+		lastErr = err
+	}
+	return nil, 0, lastErr
+}
+
+func queryHostTTL(ctx context.Context, resolver *net.Resolver, server, host string) ([]string, time.Duration, error) {
+	var (
+		addrs  []string
+		minTTL uint32
+		hasTTL bool
+	)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(host), qtype)
+
+		in, err := exchange(ctx, resolver, server, msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		if in.Rcode != dns.RcodeSuccess {
+			return nil, 0, fmt.Errorf("%s query for %s failed: %s", dns.TypeToString[qtype], host, dns.RcodeToString[in.Rcode])
+		}
+
+		for _, rr := range in.Answer {
+			switch record := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, record.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, record.AAAA.String())
+			}
+			if ttl := rr.Header().Ttl; !hasTTL || ttl < minTTL {
+				minTTL, hasTTL = ttl, true
+			}
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, 0, errNoRecords
+	}
+	return addrs, time.Duration(minTTL) * time.Second, nil
+}
+
+func exchange(ctx context.Context, resolver *net.Resolver, server string, msg *dns.Msg) (*dns.Msg, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if resolver.Dial != nil {
+		conn, err = resolver.Dial(ctx, "udp", server)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "udp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	co := &dns.Conn{Conn: conn}
+	if err := co.WriteMsg(msg); err != nil {
+		return nil, err
+	}
+	in, err := co.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	if in.Id != msg.Id {
+		return nil, dns.ErrId
+	}
+	return in, nil
+}
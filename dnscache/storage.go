@@ -2,6 +2,8 @@ package dnscache
 
 import (
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fmt"
@@ -15,14 +17,55 @@ type DnsCacheItem struct {
 	Addrs []string
 }
 
+// Options are the optional behaviours of the dns cache.
+type Options struct {
+	// UseRecordTTL caches the records for their TTL, capped by the expiration of the cache.
+	UseRecordTTL bool
+	// NegativeTTL is how long failed resolutions are cached for, not cached if 0.
+	NegativeTTL time.Duration
+	// Failover serves the last addresses a host resolved to when its resolution fails.
+	Failover bool
+}
+
+// Metrics are the counters of the dns cache.
+type Metrics struct {
+	Entries      int    `json:"entries"`
+	Hits         uint64 `json:"hits"`
+	Misses       uint64 `json:"misses"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Errors       uint64 `json:"errors"`
+	Failovers    uint64 `json:"failovers"`
+}
+
 // DnsCacheStorage is an in-memory cache of auto-purged dns query ip responses
 type DnsCacheStorage struct {
-	cache *cache.Cache
+	// the counters are first for their 64-bit alignment.
+	hits, misses, negativeHits, errors, failovers uint64
+
+	cache      *cache.Cache
+	expiration time.Duration
+	opts       Options
+
+	// negative caches the resolution errors.
+	negative *cache.Cache
+	// lastKnownGood are the last addresses of the hosts, for failover.
+	lastKnownGood   map[string][]string
+	lastKnownGoodMu sync.RWMutex
 }
 
 func NewDnsCacheStorage(expiration, checkInterval time.Duration) *DnsCacheStorage {
-	storage := DnsCacheStorage{cache.New(expiration, checkInterval)}
-	return &storage
+	return NewDnsCacheStorageWithOptions(expiration, checkInterval, Options{})
+}
+
+// NewDnsCacheStorageWithOptions returns a dns cache with the optional behaviours of opts.
+func NewDnsCacheStorageWithOptions(expiration, checkInterval time.Duration, opts Options) *DnsCacheStorage {
+	return &DnsCacheStorage{
+		cache:         cache.New(expiration, checkInterval),
+		expiration:    expiration,
+		opts:          opts,
+		negative:      cache.New(opts.NegativeTTL, checkInterval),
+		lastKnownGood: map[string][]string{},
+	}
 }
 
 // Items returns map of non expired dns cache items
@@ -62,6 +105,7 @@ func (dc *DnsCacheStorage) FetchItem(hostName string) ([]string, error) {
 
 	item, ok := dc.Get(hostName)
 	if ok {
+		atomic.AddUint64(&dc.hits, 1)
 		logger.WithFields(logrus.Fields{
 			"hostName": hostName,
 			"addrs":    item.Addrs,
@@ -69,23 +113,105 @@ func (dc *DnsCacheStorage) FetchItem(hostName string) ([]string, error) {
 		return item.Addrs, nil
 	}
 
-	addrs, err := dc.resolveDNSRecord(hostName)
+	if cachedErr, ok := dc.negative.Get(hostName); ok {
+		atomic.AddUint64(&dc.negativeHits, 1)
+		return nil, cachedErr.(error)
+	}
+
+	atomic.AddUint64(&dc.misses, 1)
+	addrs, ttl, err := dc.resolve(hostName)
 	if err != nil {
-		return nil, err
+		atomic.AddUint64(&dc.errors, 1)
+		return dc.failover(hostName, err)
 	}
 
-	dc.Set(hostName, addrs)
+	dc.set(hostName, addrs, ttl)
 	return addrs, nil
 }
 
+// failover returns the last known good addresses of hostName which failed to resolve with err,
+// caching them for the negative TTL so that the resolution is retried soon. Without failover, or
+// without addresses to fail over to, err is cached for the negative TTL.
+func (dc *DnsCacheStorage) failover(hostName string, err error) ([]string, error) {
+	if dc.opts.Failover {
+		dc.lastKnownGoodMu.RLock()
+		addrs, ok := dc.lastKnownGood[hostName]
+		dc.lastKnownGoodMu.RUnlock()
+
+		if ok {
+			atomic.AddUint64(&dc.failovers, 1)
+			logger.WithError(err).WithFields(logrus.Fields{
+				"hostName": hostName,
+				"addrs":    addrs,
+			}).Warning("Dns resolution failed, using the last known good addresses")
+
+			if dc.opts.NegativeTTL > 0 {
+				dc.cache.Set(hostName, DnsCacheItem{addrs}, dc.opts.NegativeTTL)
+			}
+			return addrs, nil
+		}
+	}
+
+	if dc.opts.NegativeTTL > 0 {
+		dc.negative.Set(hostName, err, cache.DefaultExpiration)
+	}
+	return nil, err
+}
+
 func (dc *DnsCacheStorage) Set(key string, addrs []string) {
-	logger.Debugf("Adding dns record to cache: key=%q, addrs=%q", key, addrs)
-	dc.cache.Set(key, DnsCacheItem{addrs}, cache.DefaultExpiration)
+	dc.set(key, addrs, cache.DefaultExpiration)
+}
+
+func (dc *DnsCacheStorage) set(key string, addrs []string, ttl time.Duration) {
+	logger.Debugf("Adding dns record to cache: key=%q, addrs=%q, ttl=%s", key, addrs, ttl)
+	dc.cache.Set(key, DnsCacheItem{addrs}, ttl)
+
+	dc.lastKnownGoodMu.Lock()
+	dc.lastKnownGood[key] = addrs
+	dc.lastKnownGoodMu.Unlock()
 }
 
 // Clear deletes all records from cache
 func (dc *DnsCacheStorage) Clear() {
 	dc.cache.Flush()
+	dc.negative.Flush()
+
+	dc.lastKnownGoodMu.Lock()
+	dc.lastKnownGood = map[string][]string{}
+	dc.lastKnownGoodMu.Unlock()
+}
+
+// Metrics returns the counters of the cache.
+func (dc *DnsCacheStorage) Metrics() Metrics {
+	return Metrics{
+		Entries:      dc.cache.ItemCount(),
+		Hits:         atomic.LoadUint64(&dc.hits),
+		Misses:       atomic.LoadUint64(&dc.misses),
+		NegativeHits: atomic.LoadUint64(&dc.negativeHits),
+		Errors:       atomic.LoadUint64(&dc.errors),
+		Failovers:    atomic.LoadUint64(&dc.failovers),
+	}
+}
+
+// resolve returns the addresses of host and how long to cache them, the expiration of the cache
+// unless the record TTLs are used.
+func (dc *DnsCacheStorage) resolve(host string) ([]string, time.Duration, error) {
+	if dc.opts.UseRecordTTL {
+		addrs, ttl, err := lookupHostTTL(host)
+		if err == nil {
+			if ttl < time.Second {
+				ttl = time.Second
+			}
+			if dc.expiration > 0 && ttl > dc.expiration {
+				ttl = dc.expiration
+			}
+			return addrs, ttl, nil
+		}
+		logger.WithError(err).WithField("hostName", host).Debug("Couldn't resolve the record TTLs, using the cache expiration")
+	}
+
+	addrs, err := dc.resolveDNSRecord(host)
+	return addrs, cache.DefaultExpiration, err
 }
 
 func (dc *DnsCacheStorage) resolveDNSRecord(host string) ([]string, error) {
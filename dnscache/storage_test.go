@@ -286,3 +286,66 @@ func TestStorageRecordExpiration(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageOptions(t *testing.T) {
+	tearDown := setupTestStorageFetchItem(&configTestStorageFetchItem{t, etcHostsMap, etcHostsErrorMap})
+	defer tearDown()
+
+	t.Run("Should cache records for their TTL capped by expiration", func(t *testing.T) {
+		for _, tc := range []struct {
+			expiration, expected time.Duration
+		}{
+			{expiration: 10 * time.Second, expected: 10 * time.Second},
+			// the mocked records have a TTL of 60s
+			{expiration: 5 * time.Minute, expected: time.Minute},
+		} {
+			dnsCache := NewDnsCacheStorageWithOptions(tc.expiration, 0, Options{UseRecordTTL: true})
+
+			got, err := dnsCache.FetchItem(host)
+			if err != nil || !test.IsDnsRecordsAddrsEqualsTo(got, etcHostsMap[host]) {
+				t.Fatalf("wanted ips %q, got %q. Error: %v", etcHostsMap[host], got, err)
+			}
+
+			item, ok := dnsCache.cache.Items()[host]
+			if !ok {
+				t.Fatalf("Host addresses weren't found in cache; host %q", host)
+			}
+			if ttl := time.Until(time.Unix(0, item.Expiration)); ttl > tc.expected || ttl < tc.expected-time.Second {
+				t.Fatalf("wanted record cached for %s, got %s", tc.expected, ttl)
+			}
+		}
+	})
+
+	t.Run("Should cache failed resolutions for the negative TTL", func(t *testing.T) {
+		dnsCache := NewDnsCacheStorageWithOptions(time.Minute, 0, Options{NegativeTTL: time.Minute})
+
+		for i := 0; i < 2; i++ {
+			if _, err := dnsCache.FetchItem(hostErrorable); err == nil {
+				t.Fatalf("wanted FetchItem error for %q", hostErrorable)
+			}
+		}
+
+		if metrics := dnsCache.Metrics(); metrics.Misses != 1 || metrics.Errors != 1 || metrics.NegativeHits != 1 {
+			t.Fatalf("wanted one miss, error and negative hit, got %+v", metrics)
+		}
+	})
+
+	t.Run("Should fail over to the last known good addresses", func(t *testing.T) {
+		dnsCache := NewDnsCacheStorageWithOptions(time.Minute, 0, Options{Failover: true})
+
+		if _, err := dnsCache.FetchItem(hostErrorable); err == nil {
+			t.Fatalf("wanted FetchItem error without last known good addresses for %q", hostErrorable)
+		}
+
+		dnsCache.Set(hostErrorable, etcHostsMap[host])
+		dnsCache.Delete(hostErrorable)
+
+		got, err := dnsCache.FetchItem(hostErrorable)
+		if err != nil || !test.IsDnsRecordsAddrsEqualsTo(got, etcHostsMap[host]) {
+			t.Fatalf("wanted last known good ips %q, got %q. Error: %v", etcHostsMap[host], got, err)
+		}
+		if metrics := dnsCache.Metrics(); metrics.Failovers != 1 || metrics.Errors != 2 || metrics.Entries != 0 {
+			t.Fatalf("wanted one failover, two errors and no entry, got %+v", metrics)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/accesslog"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/request"
+)
+
+// setupAccessLog replaces the access logger according to conf, flushing the previous one.
+func (gw *Gateway) setupAccessLog(conf config.AccessLogsConfig) {
+	if gw.accessLog != nil {
+		gw.accessLog.Close()
+		gw.accessLog = nil
+	}
+
+	if !conf.Enabled {
+		return
+	}
+
+	logger, err := accesslog.New(conf)
+	if err != nil {
+		mainLog.WithError(err).Error("Could not set up access logs")
+		return
+	}
+
+	gw.accessLog = logger
+}
+
+func ctxGetAccessLogRecord(r *http.Request) *accesslog.Record {
+	if v := r.Context().Value(ctx.AccessLogRecord); v != nil {
+		return v.(*accesslog.Record)
+	}
+	return nil
+}
+
+// accessLogHandler wraps the API handler chain and emits one access log record per request.
+func (gw *Gateway) accessLogHandler(spec *APISpec, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rec := &accesslog.Record{
+			Timestamp: start,
+			APIID:     spec.APIID,
+			APIName:   spec.Name,
+			OrgID:     spec.OrgID,
+			Method:    r.Method,
+			Host:      r.Host,
+			Path:      r.URL.Path,
+			ClientIP:  request.RealIP(r),
+		}
+		setCtxValue(r, ctx.AccessLogRecord, rec)
+
+		rw := &accessLogResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		rec.Status = rw.status
+		rec.BytesWritten = rw.written
+		if token := ctxGetAuthToken(r); token != "" {
+			rec.Key = gw.obfuscateKey(token)
+		}
+		if session := ctxGetSession(r); session != nil {
+			rec.KeyAlias = session.Alias
+		}
+		rec.Finish(start)
+
+		gw.accessLog.Log(*rec)
+	})
+}
+
+// accessLogResponseWriter tracks the status code and size of a response while keeping
+// the optional interfaces of the wrapped writer used by the proxy.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *accessLogResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *accessLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessLogResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+func (w *accessLogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
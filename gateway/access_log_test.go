@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/accesslog"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestAccessLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tyk-access-log")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "access.log")
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AccessLogs = config.AccessLogsConfig{
+			Enabled: true,
+			Sinks:   []config.AccessLogSinkConfig{{Type: accesslog.SinkFile, Path: logPath}},
+		}
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "test"
+		spec.Name = "access log"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.Alias = "consumer"
+		s.AccessRights = map[string]user.AccessDefinition{"test": {
+			APIID: "test", Versions: []string{"v1"},
+		}}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/ok", Headers: map[string]string{headers.Authorization: key}, Code: http.StatusOK},
+		{Path: "/denied", Code: http.StatusUnauthorized},
+	}...)
+
+	records := map[string]accesslog.Record{}
+	assert.Eventually(t, func() bool {
+		data, _ := ioutil.ReadFile(logPath)
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var rec accesslog.Record
+			if json.Unmarshal([]byte(line), &rec) == nil {
+				records[rec.Path] = rec
+			}
+		}
+		return len(records) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	ok := records["/ok"]
+	assert.Equal(t, "test", ok.APIID)
+	assert.Equal(t, "access log", ok.APIName)
+	assert.Equal(t, http.MethodGet, ok.Method)
+	assert.Equal(t, http.StatusOK, ok.Status)
+	assert.Equal(t, "consumer", ok.KeyAlias)
+	assert.NotEmpty(t, ok.Key)
+	assert.NotEqual(t, key, ok.Key, "key must be obfuscated")
+	assert.Equal(t, strings.TrimPrefix(TestHttpAny, "http://"), ok.UpstreamAddress)
+	assert.True(t, ok.BytesWritten > 0)
+	assert.Equal(t, ok.Latency.Total, ok.Latency.Upstream+ok.Latency.Gateway)
+
+	denied := records["/denied"]
+	assert.Equal(t, http.StatusUnauthorized, denied.Status)
+	assert.Empty(t, denied.UpstreamAddress)
+	assert.Empty(t, denied.KeyAlias)
+}
@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const defaultACMERenewBeforeDays = 30
+
+// acmeManager provisions the certificates of the custom domains of the APIs with an ACME
+// certificate authority. The HTTP-01 challenge tokens are shared by the gateways through Redis,
+// the TLS-ALPN-01 challenges are answered by the gateway which requested the certificate.
+type acmeManager struct {
+	gw      *Gateway
+	manager *autocert.Manager
+}
+
+// newACMEManager returns the ACME manager configured by conf, nil if ACME is disabled.
+func (gw *Gateway) newACMEManager(conf config.ACMEConfig) *acmeManager {
+	if !conf.Enabled {
+		return nil
+	}
+
+	store := &storage.RedisCluster{KeyPrefix: "acme-", RedisController: gw.RedisController}
+	store.Connect()
+
+	renewBefore := conf.RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = defaultACMERenewBeforeDays
+	}
+
+	m := &acmeManager{gw: gw}
+	m.manager = &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Cache:       &acmeCache{gw: gw, store: store},
+		HostPolicy:  m.hostPolicy,
+		Email:       conf.Email,
+		RenewBefore: time.Duration(renewBefore) * 24 * time.Hour,
+	}
+	if conf.DirectoryURL != "" {
+		m.manager.Client = &acme.Client{DirectoryURL: conf.DirectoryURL}
+	}
+	return m
+}
+
+// hostPolicy allows the configured domains and the custom domains of the APIs, except patterns.
+func (m *acmeManager) hostPolicy(_ context.Context, host string) error {
+	for _, domain := range m.gw.GetConfig().HttpServerOptions.ACME.Domains {
+		if strings.EqualFold(domain, host) {
+			return nil
+		}
+	}
+
+	m.gw.apisMu.RLock()
+	defer m.gw.apisMu.RUnlock()
+	for _, spec := range m.gw.apisByID {
+		if strings.EqualFold(spec.Domain, host) && !strings.ContainsAny(spec.Domain, "{*") {
+			return nil
+		}
+	}
+	return fmt.Errorf("acme: %s isn't the domain of an API", host)
+}
+
+// getCertificate returns the certificate of the handshakes for the domains allowed by the host
+// policy which have no configured certificate, and of the TLS-ALPN-01 challenges.
+func (m *acmeManager) getCertificate(configured map[string]*tls.Certificate) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if !contains(hello.SupportedProtos, acme.ALPNProto) {
+			if hello.ServerName == "" || configured[hello.ServerName] != nil {
+				return nil, nil
+			}
+			if err := m.hostPolicy(context.Background(), hello.ServerName); err != nil {
+				// falls back to the configured certificates
+				return nil, nil
+			}
+		}
+		return m.manager.GetCertificate(hello)
+	}
+}
+
+// httpHandler answers the HTTP-01 challenges, passing the other requests to next.
+func (m *acmeManager) httpHandler(next http.Handler) http.Handler {
+	return m.manager.HTTPHandler(next)
+}
+
+// acmeCache stores the certificates with the certificate manager, and the account key and the
+// challenge tokens encrypted in store.
+type acmeCache struct {
+	gw    *Gateway
+	store storage.Handler
+}
+
+// acmeCertificateKey reports whether key is the key of a certificate rather than of the account
+// key or of a challenge token.
+func acmeCertificateKey(key string) bool {
+	return !strings.HasSuffix(key, "+key") && !strings.HasSuffix(key, "+http-01") && !strings.HasSuffix(key, "+token")
+}
+
+func (c *acmeCache) secret() []byte {
+	return []byte(rightPad2Len(c.gw.GetConfig().Secret, "=", 32))
+}
+
+func (c *acmeCache) Get(_ context.Context, key string) ([]byte, error) {
+	if !acmeCertificateKey(key) {
+		data, err := c.store.GetKey("data." + key)
+		if err != nil {
+			return nil, autocert.ErrCacheMiss
+		}
+		return []byte(decrypt(c.secret(), data)), nil
+	}
+
+	certID, err := c.store.GetKey("cert." + key)
+	if err != nil {
+		return nil, autocert.ErrCacheMiss
+	}
+	cert := c.gw.CertificateManager.List([]string{certID}, certs.CertificatePrivate)[0]
+	if cert == nil || cert.PrivateKey == nil {
+		return nil, autocert.ErrCacheMiss
+	}
+
+	// autocert expects the private key followed by the certificate chain
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	var data bytes.Buffer
+	pem.Encode(&data, &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	for _, der := range cert.Certificate {
+		pem.Encode(&data, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	return data.Bytes(), nil
+}
+
+func (c *acmeCache) Put(_ context.Context, key string, data []byte) error {
+	if !acmeCertificateKey(key) {
+		return c.store.SetKey("data."+key, encrypt(c.secret(), string(data)), 0)
+	}
+
+	certID, err := c.gw.CertificateManager.Add(data, "")
+	if err != nil {
+		return err
+	}
+	old, _ := c.store.GetKey("cert." + key)
+	if err := c.store.SetKey("cert."+key, certID, 0); err != nil {
+		return err
+	}
+	// the renewed certificate replaces the previous one
+	if old != "" && old != certID {
+		c.gw.CertificateManager.Delete(old, "")
+	}
+	return nil
+}
+
+func (c *acmeCache) Delete(_ context.Context, key string) error {
+	if !acmeCertificateKey(key) {
+		c.store.DeleteKey("data." + key)
+		return nil
+	}
+
+	if certID, err := c.store.GetKey("cert." + key); err == nil {
+		c.gw.CertificateManager.Delete(certID, "")
+	}
+	c.store.DeleteKey("cert." + key)
+	return nil
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestACME(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HttpServerOptions.ACME = config.ACMEConfig{Enabled: true, Domains: []string{"static.example.com"}}
+	})
+	defer ts.Close()
+	require.NotNil(t, ts.Gw.acme)
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "custom-domain"
+		spec.Domain = "api.example.com"
+	}, func(spec *APISpec) {
+		spec.APIID = "pattern"
+		spec.Proxy.ListenPath = "/pattern/"
+		spec.Domain = "{tenant}.example.com"
+	})
+
+	t.Run("host policy", func(t *testing.T) {
+		policy := ts.Gw.acme.hostPolicy
+		assert.NoError(t, policy(context.Background(), "api.example.com"))
+		assert.NoError(t, policy(context.Background(), "static.example.com"))
+		assert.Error(t, policy(context.Background(), "{tenant}.example.com"))
+		assert.Error(t, policy(context.Background(), "other.example.com"))
+	})
+
+	t.Run("configured certificates are preferred", func(t *testing.T) {
+		configured := &tls.Certificate{}
+		getCertificate := ts.Gw.acme.getCertificate(map[string]*tls.Certificate{"api.example.com": configured})
+		for _, name := range []string{"", "api.example.com", "other.example.com"} {
+			cert, err := getCertificate(&tls.ClientHelloInfo{ServerName: name})
+			assert.NoError(t, err)
+			assert.Nil(t, cert, name)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		cache := ts.Gw.acme.manager.Cache
+		ctx := context.Background()
+
+		_, err := cache.Get(ctx, "api.example.com")
+		assert.Equal(t, autocert.ErrCacheMiss, err)
+
+		require.NoError(t, cache.Put(ctx, "acme_account+key", []byte("account key")))
+		data, err := cache.Get(ctx, "acme_account+key")
+		require.NoError(t, err)
+		assert.Equal(t, "account key", string(data))
+
+		certPem, keyPem, _, _ := certs.GenCertificate(&x509.Certificate{DNSNames: []string{"api.example.com"}})
+		require.NoError(t, cache.Put(ctx, "api.example.com", append(keyPem, certPem...)))
+
+		certID, err := ts.Gw.acme.manager.Cache.(*acmeCache).store.GetKey("cert.api.example.com")
+		require.NoError(t, err)
+		assert.Contains(t, ts.Gw.CertificateManager.ListAllIds(""), certID)
+
+		data, err = cache.Get(ctx, "api.example.com")
+		require.NoError(t, err)
+		cert, err := tls.X509KeyPair(data, data)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api.example.com"}, leaf.DNSNames)
+
+		// renewal replaces the certificate
+		certPem, keyPem, _, _ = certs.GenCertificate(&x509.Certificate{DNSNames: []string{"api.example.com"}})
+		require.NoError(t, cache.Put(ctx, "api.example.com", append(keyPem, certPem...)))
+		assert.NotContains(t, ts.Gw.CertificateManager.ListAllIds(""), certID)
+		certID, err = ts.Gw.acme.manager.Cache.(*acmeCache).store.GetKey("cert.api.example.com")
+		require.NoError(t, err)
+
+		require.NoError(t, cache.Delete(ctx, "api.example.com"))
+		_, err = cache.Get(ctx, "api.example.com")
+		assert.Equal(t, autocert.ErrCacheMiss, err)
+		assert.NotContains(t, ts.Gw.CertificateManager.ListAllIds(""), certID)
+	})
+
+	t.Run("TLS-ALPN-01 protocol", func(t *testing.T) {
+		tlsConfig, err := ts.Gw.getTLSConfigForClient(&tls.Config{}, 0)(&tls.ClientHelloInfo{ServerName: "acme-alpn.example.com"})
+		require.NoError(t, err)
+		assert.Contains(t, tlsConfig.NextProtos, acme.ALPNProto)
+		assert.NotNil(t, tlsConfig.GetCertificate)
+	})
+}
@@ -38,9 +38,18 @@ func (n *NetworkStats) Flush() NetworkStats {
 	return s
 }
 
+// Latency holds the timings of a request in milliseconds.
 type Latency struct {
-	Total    int64
+	// Total is the time from the start of the middleware chain until the response was proxied.
+	Total int64
+	// Upstream is the time spent in round trips to the upstream, including retries.
 	Upstream int64
+	// Auth is the time spent by the middleware which authenticated the request.
+	Auth int64
+	// Middleware is the time spent in the rest of the middleware chain.
+	Middleware int64
+	// UpstreamTTFB is the time until the first byte of the upstream response was received.
+	UpstreamTTFB int64
 }
 
 // AnalyticsRecord encodes the details of a request
@@ -74,6 +83,27 @@ type AnalyticsRecord struct {
 	Alias         string
 	TrackPath     bool
 	ExpireAt      time.Time `bson:"expireAt" json:"expireAt"`
+	// RetryCount is the number of times the upstream request was retried.
+	RetryCount   int
+	GraphQLStats GraphQLStats
+	// BotScore is the bot detection score of the request, from 0 to 100.
+	BotScore int
+	// ErrorReason is the code of the reason the gateway rejected the request, when it sets one.
+	ErrorReason string
+	// RequestID is the ID of the request, when request IDs are enabled.
+	RequestID string
+}
+
+// GraphQLStats holds the details of the GraphQL operation of a request.
+type GraphQLStats struct {
+	IsGraphQL     bool
+	OperationName string
+	// OperationType is one of query, mutation or subscription
+	OperationType string
+	// Errors is the number of errors in the GraphQL response
+	Errors int
+	// QueryHash is the hex encoded SHA-256 of the query, set when query hashing is enabled
+	QueryHash string
 }
 
 type GeoData struct {
@@ -184,6 +214,10 @@ type RedisAnalyticsHandler struct {
 	globalConf                  config.Config
 	recordsChan                 chan *AnalyticsRecord
 	workerBufferSize            uint64
+	maxFlushBatchSize           uint64
+	flushLatencyThreshold       time.Duration
+	droppedRecords              uint64
+	lastDropWarning             int64
 	shouldStop                  uint32
 	poolWg                      sync.WaitGroup
 	enableMultipleAnalyticsKeys bool
@@ -208,6 +242,11 @@ func (r *RedisAnalyticsHandler) Init() {
 
 	r.workerBufferSize = recordsBufferSize / uint64(ps)
 	log.WithField("workerBufferSize", r.workerBufferSize).Debug("Analytics pool worker buffer size")
+	r.maxFlushBatchSize = r.globalConf.AnalyticsConfig.MaxFlushBatchSize
+	if r.maxFlushBatchSize == 0 {
+		r.maxFlushBatchSize = 4 * r.workerBufferSize
+	}
+	r.flushLatencyThreshold = time.Duration(r.globalConf.AnalyticsConfig.FlushLatencyThreshold) * time.Millisecond
 	r.enableMultipleAnalyticsKeys = r.Gw.GetConfig().AnalyticsConfig.EnableMultipleAnalyticsKeys
 	r.recordsChan = make(chan *AnalyticsRecord, recordsBufferSize)
 
@@ -242,10 +281,36 @@ func (r *RedisAnalyticsHandler) RecordHit(record *AnalyticsRecord) error {
 	// just send record to channel consumed by pool of workers
 	// leave all data crunching and Redis I/O work for pool workers
 	r.mu.Lock()
-	r.recordsChan <- record
-	r.mu.Unlock()
+	defer r.mu.Unlock()
+	if atomic.LoadUint32(&r.shouldStop) > 0 {
+		// stopped while waiting for the lock, the channel is closed
+		return nil
+	}
+	for {
+		select {
+		case r.recordsChan <- record:
+			return nil
+		default:
+		}
 
-	return nil
+		// the queue is full, make room by dropping the oldest record rather than blocking the request
+		select {
+		case <-r.recordsChan:
+			r.recordDropped()
+		default:
+		}
+	}
+}
+
+func (r *RedisAnalyticsHandler) recordDropped() {
+	dropped := atomic.AddUint64(&r.droppedRecords, 1)
+
+	// warn at most every 10 seconds while records are being dropped
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&r.lastDropWarning)
+	if now-last >= int64(10*time.Second) && atomic.CompareAndSwapInt64(&r.lastDropWarning, last, now) {
+		log.WithField("dropped", dropped).Warning("Analytics queue is full, dropping oldest records")
+	}
 }
 
 func (r *RedisAnalyticsHandler) recordWorker() {
@@ -256,6 +321,14 @@ func (r *RedisAnalyticsHandler) recordWorker() {
 	recordsBuffer := make([][]byte, 0, r.workerBufferSize)
 	rand.Seed(time.Now().Unix())
 
+	tuner := newAnalyticsFlushTuner(int(r.workerBufferSize), int(r.maxFlushBatchSize), r.flushLatencyThreshold)
+	flush := func(analyticKey string) {
+		start := time.Now()
+		r.Store.AppendToSetPipelined(analyticKey, recordsBuffer)
+		tuner.update(time.Since(start), len(r.recordsChan), cap(r.recordsChan))
+		recordsBuffer = recordsBuffer[:0]
+	}
+
 	// read records from channel and process
 	lastSentTs := time.Now()
 	for {
@@ -316,9 +389,9 @@ func (r *RedisAnalyticsHandler) recordWorker() {
 			}
 
 			// identify that buffer is ready to be sent
-			readyToSend = uint64(len(recordsBuffer)) == r.workerBufferSize
+			readyToSend = len(recordsBuffer) >= tuner.batchSize
 
-		case <-time.After(recordsBufferFlushInterval):
+		case <-time.After(tuner.interval):
 			// nothing was received for that period of time
 			// anyways send whatever we have, don't hold data too long in buffer
 			readyToSend = true
@@ -326,8 +399,7 @@ func (r *RedisAnalyticsHandler) recordWorker() {
 
 		// send data to Redis and reset buffer
 		if len(recordsBuffer) > 0 && (readyToSend || time.Since(lastSentTs) >= recordsBufferForcedFlushInterval) {
-			r.Store.AppendToSetPipelined(analyticKey, recordsBuffer)
-			recordsBuffer = recordsBuffer[:0]
+			flush(analyticKey)
 			lastSentTs = time.Now()
 		}
 	}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"time"
+)
+
+const (
+	// minRecordsFlushInterval is the shortest idle time before flushing, used to drain a backed up queue.
+	minRecordsFlushInterval = recordsBufferFlushInterval / 4
+
+	defaultFlushLatencyThreshold = 100 * time.Millisecond
+
+	// queue fill ratios above which batches grow and below which they shrink back.
+	flushQueueHighWatermark = 0.5
+	flushQueueLowWatermark  = 0.1
+)
+
+// analyticsFlushTuner adapts the batch size and idle flush interval of an analytics worker to the
+// depth of the records queue and the latency of Redis writes. Batches grow and are flushed sooner
+// while the queue backs up, and grow and are flushed less often while Redis is slow, so that each
+// round trip carries more records. Both return to their initial values once the queue drains.
+type analyticsFlushTuner struct {
+	baseBatchSize    int
+	maxBatchSize     int
+	latencyThreshold time.Duration
+
+	batchSize int
+	interval  time.Duration
+}
+
+func newAnalyticsFlushTuner(baseBatchSize, maxBatchSize int, latencyThreshold time.Duration) *analyticsFlushTuner {
+	if baseBatchSize < 1 {
+		baseBatchSize = 1
+	}
+	if maxBatchSize < baseBatchSize {
+		maxBatchSize = baseBatchSize
+	}
+	if latencyThreshold <= 0 {
+		latencyThreshold = defaultFlushLatencyThreshold
+	}
+
+	return &analyticsFlushTuner{
+		baseBatchSize:    baseBatchSize,
+		maxBatchSize:     maxBatchSize,
+		latencyThreshold: latencyThreshold,
+		batchSize:        baseBatchSize,
+		interval:         recordsBufferFlushInterval,
+	}
+}
+
+// update adjusts the batch size and interval after a flush which took latency, queued being
+// the number of records waiting in a queue of the given capacity.
+func (t *analyticsFlushTuner) update(latency time.Duration, queued, capacity int) {
+	fill := 0.0
+	if capacity > 0 {
+		fill = float64(queued) / float64(capacity)
+	}
+
+	switch {
+	case latency > t.latencyThreshold:
+		t.batchSize = minInt(t.batchSize*2, t.maxBatchSize)
+		t.interval *= 2
+		if t.interval > recordsBufferForcedFlushInterval {
+			t.interval = recordsBufferForcedFlushInterval
+		}
+	case fill > flushQueueHighWatermark:
+		t.batchSize = minInt(t.batchSize*2, t.maxBatchSize)
+		t.interval = minRecordsFlushInterval
+	case fill < flushQueueLowWatermark:
+		t.batchSize -= (t.batchSize - t.baseBatchSize + 1) / 2
+		if t.batchSize < t.baseBatchSize {
+			t.batchSize = t.baseBatchSize
+		}
+		t.interval = recordsBufferFlushInterval
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyticsFlushTuner(t *testing.T) {
+	tuner := newAnalyticsFlushTuner(100, 400, 50*time.Millisecond)
+	assert.Equal(t, 100, tuner.batchSize)
+	assert.Equal(t, recordsBufferFlushInterval, tuner.interval)
+
+	// backed up queue: bigger batches flushed sooner
+	tuner.update(time.Millisecond, 800, 1000)
+	assert.Equal(t, 200, tuner.batchSize)
+	assert.Equal(t, minRecordsFlushInterval, tuner.interval)
+
+	tuner.update(time.Millisecond, 800, 1000)
+	tuner.update(time.Millisecond, 800, 1000)
+	assert.Equal(t, 400, tuner.batchSize, "batch size is bounded")
+
+	// moderate queue depth keeps the current settings
+	tuner.update(time.Millisecond, 300, 1000)
+	assert.Equal(t, 400, tuner.batchSize)
+	assert.Equal(t, minRecordsFlushInterval, tuner.interval)
+
+	// drained queue: back to the initial settings
+	for i := 0; i < 10; i++ {
+		tuner.update(time.Millisecond, 0, 1000)
+	}
+	assert.Equal(t, 100, tuner.batchSize)
+	assert.Equal(t, recordsBufferFlushInterval, tuner.interval)
+
+	// slow Redis: bigger batches flushed less often
+	tuner.update(time.Second, 0, 1000)
+	assert.Equal(t, 200, tuner.batchSize)
+	assert.Equal(t, 2*recordsBufferFlushInterval, tuner.interval)
+	for i := 0; i < 10; i++ {
+		tuner.update(time.Second, 0, 1000)
+	}
+	assert.Equal(t, recordsBufferForcedFlushInterval, tuner.interval, "interval is bounded")
+
+	// defaults
+	tuner = newAnalyticsFlushTuner(0, 0, 0)
+	assert.Equal(t, 1, tuner.batchSize)
+	assert.Equal(t, 1, tuner.maxBatchSize)
+	assert.Equal(t, defaultFlushLatencyThreshold, tuner.latencyThreshold)
+}
+
+func TestAnalyticsRecordHitDropsOldest(t *testing.T) {
+	r := &RedisAnalyticsHandler{recordsChan: make(chan *AnalyticsRecord, 2)}
+
+	for _, apiID := range []string{"first", "second", "third"} {
+		done := make(chan struct{})
+		go func() {
+			r.RecordHit(&AnalyticsRecord{APIID: apiID})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("RecordHit blocked on a full queue")
+		}
+	}
+
+	assert.Equal(t, uint64(1), r.droppedRecords)
+	assert.Equal(t, "second", (<-r.recordsChan).APIID)
+	assert.Equal(t, "third", (<-r.recordsChan).APIID)
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+var pathParamRegex = regexp.MustCompile(`{([^}]*)}`)
+
+// AnalyticsSampler decides which requests of an API are recorded in analytics.
+type AnalyticsSampler struct {
+	rate      uint64
+	counter   uint64
+	skipPaths []*regexp.Regexp
+}
+
+// NewAnalyticsSampler creates a sampler from the analytics sampling section of an API
+// definition. It returns nil when every request is to be recorded.
+func NewAnalyticsSampler(conf apidef.AnalyticsSampling) (*AnalyticsSampler, error) {
+	if conf.SampleRate < 0 {
+		return nil, errors.New("sample_rate must not be negative")
+	}
+	if conf.SampleRate <= 1 && len(conf.SkipPaths) == 0 {
+		return nil, nil
+	}
+
+	s := &AnalyticsSampler{rate: uint64(conf.SampleRate)}
+	for _, path := range conf.SkipPaths {
+		// anchored, so that skipping `/health` doesn't skip `/api/health-records`
+		re, err := regexp.Compile("^(?:" + pathParamRegex.ReplaceAllString(path, `([^/]*)`) + ")$")
+		if err != nil {
+			return nil, err
+		}
+		s.skipPaths = append(s.skipPaths, re)
+	}
+
+	return s, nil
+}
+
+// ShouldRecord reports whether the request r of spec which completed with code should be
+// recorded. Failed requests are always recorded, successful ones are dropped when they
+// match a skipped path or are not part of the sample.
+func (s *AnalyticsSampler) ShouldRecord(spec *APISpec, r *http.Request, code int) bool {
+	if s == nil || code >= http.StatusBadRequest {
+		return true
+	}
+
+	if code >= http.StatusOK && code < http.StatusMultipleChoices && len(s.skipPaths) > 0 {
+		path := spec.StripListenPath(r, r.URL.Path)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		for _, re := range s.skipPaths {
+			if re.MatchString(path) {
+				return false
+			}
+		}
+	}
+
+	if s.rate > 1 {
+		return atomic.AddUint64(&s.counter, 1)%s.rate == 1
+	}
+
+	return true
+}
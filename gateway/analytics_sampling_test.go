@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestNewAnalyticsSampler(t *testing.T) {
+	s, err := NewAnalyticsSampler(apidef.AnalyticsSampling{SampleRate: 1})
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	_, err = NewAnalyticsSampler(apidef.AnalyticsSampling{SampleRate: -1})
+	assert.Error(t, err)
+
+	_, err = NewAnalyticsSampler(apidef.AnalyticsSampling{SkipPaths: []string{"/health("}})
+	assert.Error(t, err)
+
+	t.Run("skip paths match whole paths", func(t *testing.T) {
+		s, err := NewAnalyticsSampler(apidef.AnalyticsSampling{SkipPaths: []string{"/health", "/status/{component}"}})
+		require.NoError(t, err)
+
+		spec := BuildAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+		})[0]
+		for path, recorded := range map[string]bool{
+			"/health":             false,
+			"/status/db":          false,
+			"/api/health-records": true,
+			"/health/details":     true,
+			"/status/db/details":  true,
+		} {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			assert.Equal(t, recorded, s.ShouldRecord(spec, r, http.StatusOK), path)
+		}
+	})
+}
+
+func TestAnalyticsSampling(t *testing.T) {
+	ts := StartTest(nil, TestConfig{Delay: 20 * time.Millisecond})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/sampled/"
+		spec.AnalyticsSampling = apidef.AnalyticsSampling{
+			SampleRate: 3,
+			SkipPaths:  []string{"^/health$", "^/status/{component}$"},
+		}
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.BlackList = []apidef.EndPointMeta{
+				{Path: "/health", MethodActions: map[string]apidef.EndpointMethodMeta{
+					http.MethodPost: {Action: apidef.NoAction},
+				}},
+			}
+		})
+	})
+
+	// let records to to be sent
+	time.Sleep(recordsBufferFlushInterval + 50)
+	ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+
+	records := func() []AnalyticsRecord {
+		time.Sleep(recordsBufferFlushInterval + 50)
+		results := ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+		records := make([]AnalyticsRecord, len(results))
+		for i, result := range results {
+			require.NoError(t, msgpack.Unmarshal([]byte(result.(string)), &records[i]))
+		}
+		return records
+	}
+
+	t.Run("successful requests are sampled", func(t *testing.T) {
+		for i := 0; i < 6; i++ {
+			_, _ = ts.Run(t, test.TestCase{Path: "/sampled/resource", Code: http.StatusOK})
+		}
+		assert.Len(t, records(), 2)
+	})
+
+	t.Run("successful health checks are skipped", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/sampled/health", Code: http.StatusOK},
+			{Path: "/sampled/status/db", Code: http.StatusOK},
+		}...)
+		assert.Len(t, records(), 0)
+	})
+
+	t.Run("errors are always recorded", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/sampled/health", Code: http.StatusForbidden})
+		}
+		assert.Len(t, records(), 3)
+	})
+}
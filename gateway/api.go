@@ -32,16 +32,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/TykTechnologies/tyk/apidef/importer"
 	"github.com/TykTechnologies/tyk/apidef/oas"
 	"github.com/getkin/kin-openapi/openapi3"
 	uuid "github.com/satori/go.uuid"
@@ -51,9 +54,9 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/ctx"
 	"github.com/TykTechnologies/tyk/headers"
 	"github.com/TykTechnologies/tyk/storage"
@@ -314,32 +317,47 @@ func (gw *Gateway) doAddOrUpdate(keyName string, newSession *user.SessionState,
 // remove from all stores, update to all stores, stores handle quotas separately though because they are localised! Keys will
 // need to be managed by API, but only for GetDetail, GetList, UpdateKey and DeleteKey
 
-func setSessionPassword(session *user.SessionState) {
-	session.BasicAuthData.Hash = user.HashBCrypt
-	newPass, err := bcrypt.GenerateFromPassword([]byte(session.BasicAuthData.Password), 10)
+func setSessionPassword(session *user.SessionState, conf config.BasicAuthHashConfig) {
+	newPass, hashType, err := hashPassword(conf, session.BasicAuthData.Password)
 	if err != nil {
 		log.Error("Could not hash password, setting to plaintext, error was: ", err)
 		session.BasicAuthData.Hash = user.HashPlainText
 		return
 	}
 
-	session.BasicAuthData.Password = string(newPass)
+	session.BasicAuthData.Hash = hashType
+	session.BasicAuthData.Password = newPass
 }
 
 func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed bool) (interface{}, int) {
 	suppressReset := r.URL.Query().Get("suppress_reset") == "1"
 
-	// decode payload
-	newSession := &user.SessionState{}
-
 	contents, _ := ioutil.ReadAll(r.Body)
 	r.Body = ioutil.NopCloser(bytes.NewReader(contents))
 
+	return gw.addOrUpdateKey(keyName, contents, r.Method, suppressReset, isHashed)
+}
+
+// addOrUpdateKey creates the key from the session in contents when method is POST, and updates it when PUT.
+func (gw *Gateway) addOrUpdateKey(keyName string, contents []byte, method string, suppressReset, isHashed bool) (interface{}, int) {
+	// decode payload
+	newSession := &user.SessionState{}
+
 	if err := json.Unmarshal(contents, newSession); err != nil {
 		log.Error("Couldn't decode new session object: ", err)
 		return apiError("Request malformed"), http.StatusBadRequest
 	}
 
+	if err := newSession.ValidateActivity(); err != nil {
+		log.Error("Invalid key activity: ", err)
+		return apiError("Invalid key activity: " + err.Error()), http.StatusBadRequest
+	}
+
+	if err := user.ValidateAccessSpecs(newSession.AccessRights); err != nil {
+		log.Error("Invalid key access rights: ", err)
+		return apiError("Invalid key access rights: " + err.Error()), http.StatusBadRequest
+	}
+
 	mw := BaseMiddleware{Gw: gw}
 	// TODO: handle apply policies error
 	mw.ApplyPolicies(newSession)
@@ -347,7 +365,7 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 
 	// get original session in case of update and preserve fields that SHOULD NOT be updated
 	originalKey := user.SessionState{}
-	if r.Method == http.MethodPut {
+	if method == http.MethodPut {
 		key, found := gw.GlobalSessionManager.SessionDetail(newSession.OrgID, keyName, isHashed)
 		keyName = key.KeyID
 		if !found {
@@ -410,17 +428,17 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 	if newSession.BasicAuthData.Password != "" {
 		// If we are using a basic auth user, then we need to make the keyname explicit against the OrgId in order to differentiate it
 		// Only if it's NEW
-		switch r.Method {
+		switch method {
 		case http.MethodPost:
 			// It's a create, so lets hash the password
-			setSessionPassword(newSession)
+			setSessionPassword(newSession, gw.GetConfig().BasicAuthHash)
 		case http.MethodPut:
 			if originalKey.BasicAuthData.Password != newSession.BasicAuthData.Password {
 				// passwords dont match assume it's new, lets hash it
 				log.Debug("Passwords dont match, original: ", originalKey.BasicAuthData.Password)
 				log.Debug("New: newSession.BasicAuthData.Password")
 				log.Debug("Changing password")
-				setSessionPassword(newSession)
+				setSessionPassword(newSession, gw.GetConfig().BasicAuthHash)
 			}
 		}
 	} else if originalKey.BasicAuthData.Password != "" {
@@ -429,7 +447,7 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 		newSession.BasicAuthData.Password = originalKey.BasicAuthData.Password
 	}
 
-	if r.Method == http.MethodPost || storage.TokenOrg(keyName) != "" {
+	if method == http.MethodPost || storage.TokenOrg(keyName) != "" {
 		// use new key format if key gets created or updating key with new format
 		if err := gw.doAddOrUpdate(keyName, newSession, suppressReset, isHashed); err != nil {
 			return apiError("Failed to create key, ensure security settings are correct."), http.StatusInternalServerError
@@ -452,7 +470,7 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 
 	action := "modified"
 	event := EventTokenUpdated
-	if r.Method == http.MethodPost {
+	if method == http.MethodPost {
 		action = "added"
 		event = EventTokenCreated
 	}
@@ -469,7 +487,7 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 	}
 
 	// add key hash for newly created key
-	if gw.GetConfig().HashKeys && r.Method == http.MethodPost {
+	if gw.GetConfig().HashKeys && method == http.MethodPost {
 		if isHashed {
 			response.KeyHash = keyName
 		} else {
@@ -805,10 +823,28 @@ func (gw *Gateway) handleGetPolicyList() (interface{}, int) {
 	return polIDList, http.StatusOK
 }
 
+// policyIDPattern matches the policy IDs which are valid file names in the policy path.
+var policyIDPattern = regexp.MustCompile(`^[\w-][\w.-]*$`)
+
+// policiesManagedByAPI returns why the policies can't be managed with the REST API, "" if they can.
+// They are persisted as files in the policy path.
+func (gw *Gateway) policiesManagedByAPI() string {
+	policies := gw.GetConfig().Policies
+	switch {
+	case policies.PolicySource == "service":
+		return "Due to enabled service policy source, please use the Dashboard API"
+	case policies.PolicySource == "rpc" || policies.PolicySource == "kubernetes":
+		return "Policies are loaded from " + policies.PolicySource + " and can't be modified"
+	case policies.PolicyPath == "":
+		return "Policies can only be managed when policy_path is set"
+	}
+	return ""
+}
+
 func (gw *Gateway) handleAddOrUpdatePolicy(polID string, r *http.Request) (interface{}, int) {
-	if gw.GetConfig().Policies.PolicySource == "service" {
-		log.Error("Rejected new policy due to PolicySource = service")
-		return apiError("Due to enabled service policy source, please use the Dashboard API"), http.StatusInternalServerError
+	if reason := gw.policiesManagedByAPI(); reason != "" {
+		log.Error("Rejected new policy: ", reason)
+		return apiError(reason), http.StatusInternalServerError
 	}
 
 	newPol := &user.Policy{}
@@ -822,6 +858,22 @@ func (gw *Gateway) handleAddOrUpdatePolicy(polID string, r *http.Request) (inter
 		return apiError("Request ID does not match that in policy! For Update operations these must match."), http.StatusBadRequest
 	}
 
+	if newPol.ID == "" {
+		newPol.ID = polID
+	}
+	if newPol.ID == "" {
+		newPol.ID = strings.Replace(uuid.NewV4().String(), "-", "", -1)
+	}
+	if !policyIDPattern.MatchString(newPol.ID) {
+		log.Error("Invalid policy ID: ", newPol.ID)
+		return apiError("Invalid policy ID, it may only contain letters, digits, '_', '-' and '.'"), http.StatusBadRequest
+	}
+
+	if err := user.ValidateAccessSpecs(newPol.AccessRights); err != nil {
+		log.Error("Invalid policy access rights: ", err)
+		return apiError("Invalid policy access rights: " + err.Error()), http.StatusBadRequest
+	}
+
 	// Create a filename
 	polFilePath := filepath.Join(gw.GetConfig().Policies.PolicyPath, newPol.ID+".json")
 
@@ -841,6 +893,9 @@ func (gw *Gateway) handleAddOrUpdatePolicy(polID string, r *http.Request) (inter
 		action = "added"
 	}
 
+	// the policies are loaded again with the next reload
+	gw.reloadURLStructure(nil)
+
 	response := apiModifyKeySuccess{
 		Key:    newPol.ID,
 		Status: "ok",
@@ -851,12 +906,23 @@ func (gw *Gateway) handleAddOrUpdatePolicy(polID string, r *http.Request) (inter
 }
 
 func (gw *Gateway) handleDeletePolicy(polID string) (interface{}, int) {
+	if reason := gw.policiesManagedByAPI(); reason != "" {
+		log.Error("Rejected policy deletion: ", reason)
+		return apiError(reason), http.StatusInternalServerError
+	}
+	if !policyIDPattern.MatchString(polID) {
+		return apiError("Invalid policy ID, it may only contain letters, digits, '_', '-' and '.'"), http.StatusBadRequest
+	}
+
 	// Generate a filename
 	defFilePath := filepath.Join(gw.GetConfig().Policies.PolicyPath, polID+".json")
 
 	// If it exists, delete it
 	if _, err := os.Stat(defFilePath); err != nil {
 		log.Warningf("Error describing named file: %v ", err)
+		if os.IsNotExist(err) {
+			return apiError("Policy not found"), http.StatusNotFound
+		}
 		return apiError("Delete failed"), http.StatusInternalServerError
 	}
 
@@ -865,6 +931,9 @@ func (gw *Gateway) handleDeletePolicy(polID string) (interface{}, int) {
 		return apiError("Delete failed"), http.StatusInternalServerError
 	}
 
+	// the policies are loaded again with the next reload
+	gw.reloadURLStructure(nil)
+
 	response := apiModifyKeySuccess{
 		Key:    polID,
 		Status: "ok",
@@ -880,7 +949,7 @@ func (gw *Gateway) handleGetAPIList() (interface{}, int) {
 	apiIDList := make([]*apidef.APIDefinition, len(gw.apisByID))
 	c := 0
 	for _, apiSpec := range gw.apisByID {
-		apiIDList[c] = apiSpec.APIDefinition
+		apiIDList[c] = apiSpec.withSecretReferences()
 		c++
 	}
 	return apiIDList, http.StatusOK
@@ -891,7 +960,7 @@ func (gw *Gateway) handleGetAPI(apiID string, oasTyped bool) (interface{}, int)
 		if oasTyped {
 			return &spec.OAS, http.StatusOK
 		} else {
-			return spec.APIDefinition, http.StatusOK
+			return spec.withSecretReferences(), http.StatusOK
 		}
 	}
 
@@ -902,6 +971,20 @@ func (gw *Gateway) handleGetAPI(apiID string, oasTyped bool) (interface{}, int)
 	return apiError("API not found"), http.StatusNotFound
 }
 
+// postmanExportHandler exports the allowed operations of an API to a Postman collection. The
+// base_url query parameter is the gateway URL the requests of the collection are sent to.
+func (gw *Gateway) postmanExportHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, importer.NewPostmanCollection(spec.APIDefinition, r.URL.Query().Get("base_url")))
+}
+
 func (gw *Gateway) handleAddOrUpdateApi(apiID string, r *http.Request, fs afero.Fs, oasTyped bool) (interface{}, int) {
 	if gw.GetConfig().UseDBAppConfigs {
 		log.Error("Rejected new API Definition due to UseDBAppConfigs = true")
@@ -1151,6 +1234,9 @@ func (gw *Gateway) keyHandler(w http.ResponseWriter, r *http.Request) {
 				// try to use legacy key format
 				obj, code = gw.handleGetDetail(origKeyName, apiID, orgID, isHashed)
 			}
+		} else if terms := keySearchTerms(r.URL.Query()); len(terms) > 0 {
+			// Search keys by alias and metadata, which unlike listing works with hashed keys
+			obj, code = gw.handleSearchKeys(terms)
 		} else {
 			// Return list of keys
 			if gwConfig.HashKeys {
@@ -1488,6 +1574,18 @@ func (gw *Gateway) createKeyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := newSession.ValidateActivity(); err != nil {
+		log.Error("Invalid key activity: ", err)
+		doJSONWrite(w, http.StatusBadRequest, apiError("Invalid key activity: "+err.Error()))
+		return
+	}
+
+	if err := user.ValidateAccessSpecs(newSession.AccessRights); err != nil {
+		log.Error("Invalid key access rights: ", err)
+		doJSONWrite(w, http.StatusBadRequest, apiError("Invalid key access rights: "+err.Error()))
+		return
+	}
+
 	newKey := gw.keyGen.GenerateAuthKey(newSession.OrgID)
 	if newSession.HMACEnabled {
 		newSession.HmacSecret = gw.keyGen.GenerateHMACSecret()
@@ -1653,6 +1751,20 @@ type NewClientRequest struct {
 	ClientSecret      string      `json:"secret"`
 	MetaData          interface{} `json:"meta_data"`
 	Description       string      `json:"description"`
+	// RateLimit limits the requests made with all the tokens issued to the client.
+	RateLimit *OAuthClientRateLimit `json:"rate_limit,omitempty"`
+}
+
+// OAuthClientRotationRequest is the body of a client secret rotation request.
+type OAuthClientRotationRequest struct {
+	// GracePeriod is the time in seconds during which the previous secret keeps authenticating the
+	// client, it stops immediately when 0.
+	GracePeriod int64 `json:"grace_period"`
+}
+
+// validOAuthClientRateLimit reports whether limit is empty or can be enforced.
+func validOAuthClientRateLimit(limit *OAuthClientRateLimit) bool {
+	return limit == nil || limit.Rate == 0 || (limit.Rate > 0 && limit.Per > 0)
 }
 
 func oauthClientStorageID(clientID string) string {
@@ -1671,6 +1783,11 @@ func (gw *Gateway) createOauthClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validOAuthClientRateLimit(newOauthClient.RateLimit) {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Rate limit must have a positive rate and per"))
+		return
+	}
+
 	// Allow the client ID to be set
 	cleanSting := newOauthClient.ClientID
 
@@ -1692,6 +1809,7 @@ func (gw *Gateway) createOauthClient(w http.ResponseWriter, r *http.Request) {
 		PolicyID:          newOauthClient.PolicyID,
 		MetaData:          newOauthClient.MetaData,
 		Description:       newOauthClient.Description,
+		RateLimit:         newOauthClient.RateLimit,
 	}
 
 	storageID := oauthClientStorageID(newClient.GetId())
@@ -1808,6 +1926,7 @@ func (gw *Gateway) createOauthClient(w http.ResponseWriter, r *http.Request) {
 		PolicyID:          newClient.GetPolicyID(),
 		MetaData:          newClient.GetUserData(),
 		Description:       newClient.GetDescription(),
+		RateLimit:         newClient.GetRateLimit(),
 	}
 
 	log.WithFields(logrus.Fields{
@@ -1823,7 +1942,11 @@ func (gw *Gateway) createOauthClient(w http.ResponseWriter, r *http.Request) {
 	doJSONWrite(w, http.StatusOK, clientData)
 }
 
-func (gw *Gateway) rotateOauthClient(keyName, apiID string) (interface{}, int) {
+func (gw *Gateway) rotateOauthClient(keyName, apiID string, req OAuthClientRotationRequest) (interface{}, int) {
+	if req.GracePeriod < 0 {
+		return apiError("Grace period can't be negative"), http.StatusBadRequest
+	}
+
 	// check API
 	apiSpec := gw.getApiSpec(apiID)
 	if apiSpec == nil {
@@ -1845,6 +1968,11 @@ func (gw *Gateway) rotateOauthClient(keyName, apiID string) (interface{}, int) {
 		PolicyID:          client.GetPolicyID(),
 		MetaData:          client.GetUserData(),
 		Description:       client.GetDescription(),
+		RateLimit:         client.GetRateLimit(),
+	}
+	if req.GracePeriod > 0 {
+		updatedClient.PreviousSecret = client.GetSecret()
+		updatedClient.PreviousSecretExpires = time.Now().Unix() + req.GracePeriod
 	}
 
 	err = apiSpec.OAuthManager.OsinServer.Storage.SetClient(storageID, apiSpec.OrgID, &updatedClient, true)
@@ -1869,6 +1997,7 @@ func (gw *Gateway) rotateOauthClient(keyName, apiID string) (interface{}, int) {
 		PolicyID:          updatedClient.GetPolicyID(),
 		MetaData:          updatedClient.GetUserData(),
 		Description:       updatedClient.GetDescription(),
+		RateLimit:         updatedClient.GetRateLimit(),
 	}
 
 	return replyData, http.StatusOK
@@ -1887,6 +2016,10 @@ func (gw *Gateway) updateOauthClient(keyName, apiID string, r *http.Request) (in
 		return apiError("Unmarshalling failed"), http.StatusInternalServerError
 	}
 
+	if !validOAuthClientRateLimit(updateClientData.RateLimit) {
+		return apiError("Rate limit must have a positive rate and per"), http.StatusBadRequest
+	}
+
 	// check API
 	apiSpec := gw.getApiSpec(apiID)
 	if apiSpec == nil {
@@ -1922,6 +2055,11 @@ func (gw *Gateway) updateOauthClient(keyName, apiID string, r *http.Request) (in
 		PolicyID:          updateClientData.PolicyID,          // update
 		MetaData:          updateClientData.MetaData,          // update
 		Description:       updateClientData.Description,       // update
+		RateLimit:         updateClientData.RateLimit,         // update
+	}
+	if c, ok := client.(*OAuthClient); ok {
+		updatedClient.PreviousSecret = c.PreviousSecret
+		updatedClient.PreviousSecretExpires = c.PreviousSecretExpires
 	}
 
 	err = apiSpec.OAuthManager.OsinServer.Storage.SetClient(storageID, apiSpec.OrgID, &updatedClient, true)
@@ -1946,6 +2084,7 @@ func (gw *Gateway) updateOauthClient(keyName, apiID string, r *http.Request) (in
 		PolicyID:          updatedClient.GetPolicyID(),
 		MetaData:          updatedClient.GetUserData(),
 		Description:       updatedClient.GetDescription(),
+		RateLimit:         updatedClient.GetRateLimit(),
 	}
 
 	return replyData, http.StatusOK
@@ -2023,7 +2162,14 @@ func (gw *Gateway) rotateOauthClientHandler(w http.ResponseWriter, r *http.Reque
 	apiID := mux.Vars(r)["apiID"]
 	keyName := mux.Vars(r)["keyName"]
 
-	obj, code := gw.rotateOauthClient(keyName, apiID)
+	// the body is optional, the previous secret stops authenticating immediately by default
+	var req OAuthClientRotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	obj, code := gw.rotateOauthClient(keyName, apiID, req)
 
 	doJSONWrite(w, code, obj)
 }
@@ -2178,6 +2324,7 @@ func (gw *Gateway) getOauthClientDetails(keyName, apiID string) (interface{}, in
 		PolicyID:          clientData.GetPolicyID(),
 		MetaData:          clientData.GetUserData(),
 		Description:       clientData.GetDescription(),
+		RateLimit:         clientData.GetRateLimit(),
 	}
 
 	log.WithFields(logrus.Fields{
@@ -2238,6 +2385,11 @@ const oauthClientIdEmpty = "client_id is required"
 const oauthClientSecretEmpty = "client_secret is required"
 const oauthClientSecretWrong = "client secret is wrong"
 const oauthTokenEmpty = "token is required"
+const oauthClientAuthFailed = "client authentication failed"
+const oauthTokenClientMismatch = "token was not issued to the client"
+const oauthUserCodeEmpty = "user_code is required"
+const oauthUserCodeNotFound = "user code not found or expired"
+const oauthUserCodeUsed = "user code was already used"
 
 func (gw *Gateway) getApiClients(apiID string) ([]ExtendedOsinClientInterface, apiStatusMessage, int) {
 	var err error
@@ -2290,6 +2442,7 @@ func (gw *Gateway) getOauthClients(apiID string) (interface{}, int) {
 			PolicyID:          osinClient.GetPolicyID(),
 			MetaData:          osinClient.GetUserData(),
 			Description:       osinClient.GetDescription(),
+			RateLimit:         osinClient.GetRateLimit(),
 		}
 
 		clients = append(clients, reportableClientData)
@@ -2418,12 +2571,15 @@ func (gw *Gateway) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var revoked []string
 	for _, apiID := range apis {
 		storage, _, err := gw.GetStorageForApi(apiID)
 		if err == nil {
-			RevokeToken(storage, token, tokenTypeHint)
+			revoked = append(revoked, RevokeToken(storage, token, tokenTypeHint)...)
 		}
 	}
+	gw.notifyTokensRevoked(revoked)
+
 	doJSONWrite(w, http.StatusOK, apiOk("token revoked successfully"))
 }
 
@@ -2688,6 +2844,35 @@ func ctxGetGraphQLRequest(r *http.Request) (gqlRequest *gql.Request) {
 	return nil
 }
 
+func ctxSetGraphQLStats(r *http.Request, stats *GraphQLStats) {
+	setCtxValue(r, ctx.GraphQLStats, stats)
+}
+
+func ctxGetGraphQLStatsRef(r *http.Request) *GraphQLStats {
+	if v := r.Context().Value(ctx.GraphQLStats); v != nil {
+		return v.(*GraphQLStats)
+	}
+	return nil
+}
+
+func ctxGetGraphQLStats(r *http.Request) GraphQLStats {
+	if stats := ctxGetGraphQLStatsRef(r); stats != nil {
+		return *stats
+	}
+	return GraphQLStats{}
+}
+
+func ctxSetGrantedScopes(r *http.Request, scopes []string) {
+	setCtxValue(r, ctx.GrantedScopes, scopes)
+}
+
+func ctxGetGrantedScopes(r *http.Request) []string {
+	if v := r.Context().Value(ctx.GrantedScopes); v != nil {
+		return v.([]string)
+	}
+	return nil
+}
+
 func ctxSetGraphQLIsWebSocketUpgrade(r *http.Request, isWebSocketUpgrade bool) {
 	setCtxValue(r, ctx.GraphQLIsWebSocketUpgrade, isWebSocketUpgrade)
 }
@@ -2805,6 +2990,52 @@ func ctxGetRequestStatus(r *http.Request) (stat RequestStatus) {
 	return
 }
 
+func ctxSetUpstreamRetries(r *http.Request, retries int) {
+	setCtxValue(r, ctx.UpstreamRetries, retries)
+}
+
+func ctxSetGeoIPCountry(r *http.Request, country string) {
+	setCtxValue(r, ctx.GeoIPCountry, country)
+}
+
+func ctxGetGeoIPCountry(r *http.Request) string {
+	if v := r.Context().Value(ctx.GeoIPCountry); v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
+func ctxGetUpstreamRetries(r *http.Request) int {
+	if v := r.Context().Value(ctx.UpstreamRetries); v != nil {
+		return v.(int)
+	}
+	return 0
+}
+
+// ctxSetErrorReason sets the code recorded in analytics of the reason the request is rejected.
+func ctxSetErrorReason(r *http.Request, reason string) {
+	setCtxValue(r, ctx.ErrorReason, reason)
+}
+
+func ctxGetErrorReason(r *http.Request) string {
+	if v := r.Context().Value(ctx.ErrorReason); v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
+// ctxSetRequestID sets the ID of the request, see requestIDHandler.
+func ctxSetRequestID(r *http.Request, id string) {
+	setCtxValue(r, ctx.RequestID, id)
+}
+
+func ctxGetRequestID(r *http.Request) string {
+	if v := r.Context().Value(ctx.RequestID); v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
 var createOauthClientSecret = func() string {
 	secret := uuid.NewV4()
 	return base64.StdEncoding.EncodeToString([]byte(secret.String()))
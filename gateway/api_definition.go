@@ -33,6 +33,7 @@ import (
 	"github.com/TykTechnologies/gojsonschema"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
 	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/headers"
 	"github.com/TykTechnologies/tyk/regexp"
@@ -84,6 +85,16 @@ const (
 	ValidateJSONRequest
 	Internal
 	GoPlugin
+	ResponseSizeLimit
+	RequiredScopes
+	QueryTransformed
+	BodyMasked
+	BodyMaskedResponse
+	ValidateXMLRequest
+	ValidateMultipartRequest
+	Deprecated
+	Composite
+	Async
 )
 
 // RequestStatus is a custom type to avoid collisions
@@ -96,6 +107,7 @@ const (
 	VersionWhiteListStatusNotFound RequestStatus = "WhiteListStatus for path not found"
 	VersionExpired                 RequestStatus = "Api Version has expired, please check documentation or contact administrator"
 	EndPointNotAllowed             RequestStatus = "Requested endpoint is forbidden"
+	EndPointNotFound               RequestStatus = "Requested endpoint not found"
 	StatusOkAndIgnore              RequestStatus = "Everything OK, passing and not filtering"
 	StatusOk                       RequestStatus = "Everything OK, passing"
 	StatusCached                   RequestStatus = "Cached path"
@@ -117,6 +129,16 @@ const (
 	StatusValidateJSON             RequestStatus = "Validate JSON"
 	StatusInternal                 RequestStatus = "Internal path"
 	StatusGoPlugin                 RequestStatus = "Go plugin"
+	StatusResponseSizeControlled   RequestStatus = "Response Size Limited"
+	StatusRequiredScopes           RequestStatus = "Required Scopes"
+	StatusQueryTransformed         RequestStatus = "Query transformed"
+	StatusBodyMasked               RequestStatus = "Body masked"
+	StatusBodyMaskedResponse       RequestStatus = "Body masked on response"
+	StatusValidateXML              RequestStatus = "Validate XML"
+	StatusValidateMultipart        RequestStatus = "Validate multipart"
+	StatusDeprecated               RequestStatus = "Deprecated endpoint"
+	StatusComposite                RequestStatus = "Composite endpoint"
+	StatusAsync                    RequestStatus = "Async endpoint"
 )
 
 // URLSpec represents a flattened specification for URLs, used to check if a proxy URL
@@ -133,15 +155,24 @@ type URLSpec struct {
 	TransformJQResponseAction TransformJQSpec
 	InjectHeaders             apidef.HeaderInjectionMeta
 	InjectHeadersResponse     apidef.HeaderInjectionMeta
+	TransformQuery            apidef.QueryTransformMeta
+	BodyMasking               BodyMaskingSpec
 	HardTimeout               apidef.HardTimeoutMeta
 	CircuitBreaker            ExtendedCircuitBreakerMeta
 	URLRewrite                *apidef.URLRewriteMeta
 	VirtualPathSpec           apidef.VirtualMeta
 	RequestSize               apidef.RequestSizeMeta
+	ResponseSize              apidef.ResponseSizeMeta
+	RequiredScopes            apidef.RequiredScopesMeta
 	MethodTransform           apidef.MethodTransformMeta
 	TrackEndpoint             apidef.TrackEndpointMeta
 	DoNotTrackEndpoint        apidef.TrackEndpointMeta
 	ValidatePathMeta          apidef.ValidatePathMeta
+	ValidateXML               ValidateXMLSpec
+	ValidateMultipart         apidef.ValidateMultipartMeta
+	Deprecated                DeprecatedSpec
+	Composite                 CompositeSpec
+	Async                     apidef.AsyncMeta
 	Internal                  apidef.InternalMeta
 	GoPluginMeta              GoPluginMiddleware
 
@@ -171,17 +202,43 @@ type APISpec struct {
 	OAS openapi3.Swagger
 	sync.RWMutex
 
-	RxPaths                  map[string][]URLSpec
-	WhiteListEnabled         map[string]bool
-	target                   *url.URL
-	AuthManager              SessionHandler
-	OAuthManager             *OAuthManager
-	OrgSessionManager        SessionHandler
-	EventPaths               map[apidef.TykEvent][]config.TykEventHandler
-	Health                   HealthChecker
-	JSVM                     JSVM
-	ResponseChain            []TykResponseHandler
-	RoundRobin               RoundRobin
+	RxPaths           map[string][]URLSpec
+	WhiteListEnabled  map[string]bool
+	target            *url.URL
+	AuthManager       SessionHandler
+	OAuthManager      *OAuthManager
+	OrgSessionManager SessionHandler
+	EventPaths        map[apidef.TykEvent][]config.TykEventHandler
+	Health            HealthChecker
+	JSVM              JSVM
+	ResponseChain     []TykResponseHandler
+	RoundRobin        RoundRobin
+	Canary            *CanaryRouter
+	IPAccess          *IPAccessList
+	ipAccessOnce      sync.Once
+	MaintenanceMode   *MaintenanceMode
+	// ErrorResponseTemplates are the error templates of the API and the global ones, nil if there are none.
+	ErrorResponseTemplates *ErrorResponseTemplates
+	HashBalancer      *ConsistentHashBalancer
+	AnalyticsSampler  *AnalyticsSampler
+	TrafficSampler    *TrafficSampler
+	// LogRedactor redacts the logs and traces of the API, nil if nothing is redacted.
+	LogRedactor       *logRedactor
+	UpstreamStats     *UpstreamStats
+	KafkaProxy        *KafkaProxy
+	wasmPlugins       []*wasmPlugin
+	secrets           []apiSecret
+	// template is the definition with its placeholders, templateErr why they couldn't be resolved.
+	template    []byte
+	templateErr error
+	// asyncProxy delivers the queued requests of the async endpoints.
+	asyncProxy ReturningHttpHandler
+	// routes are the routes of the middleware chain, mounted as they are when another API is reloaded alone.
+	routes                   *mux.Router
+	// checksum identifies the definition and configuration the API was fetched with, listenHash
+	// its domain and listen path before it was loaded. They are set for incremental reloads.
+	checksum                 string
+	listenHash               string
 	URLRewriteEnabled        bool
 	CircuitBreakerEnabled    bool
 	EnforcedTimeoutEnabled   bool
@@ -229,6 +286,16 @@ func (s *APISpec) Release() {
 		s.GraphQLExecutor.CancelV2()
 	}
 
+	// stop consuming Kafka replies
+	if s.KafkaProxy != nil {
+		s.KafkaProxy.Close()
+	}
+
+	// free the runtimes of wasm plugins
+	for _, plugin := range s.wasmPlugins {
+		plugin.close()
+	}
+
 	// release all other resources associated with spec
 }
 
@@ -236,7 +303,7 @@ func (s *APISpec) Release() {
 func (s *APISpec) Validate() error {
 	// For tcp services we need to make sure we can bind to the port.
 	switch s.Protocol {
-	case "tcp", "tls":
+	case "tcp", "tls", "mqtt", "mqtts", "amqp", "amqps":
 		return s.validateTCP()
 	default:
 		return s.validateHTTP()
@@ -270,6 +337,8 @@ func (a APIDefinitionLoader) MakeSpec(def *apidef.APIDefinition, logger *logrus.
 		logger = logrus.NewEntry(log)
 	}
 
+	spec.templateErr = a.Gw.resolveAPITemplate(spec, def)
+
 	// parse version expiration time stamps
 	for key, ver := range def.VersionData.Versions {
 		if ver.Expires == "" || ver.Expires == "-1" {
@@ -436,11 +505,7 @@ func (a APIDefinitionLoader) FromDashboardService(endpoint string) ([]*APISpec,
 	}
 
 	// Process
-	var specs []*APISpec
-	for _, def := range apiDefs {
-		spec := a.MakeSpec(def, nil)
-		specs = append(specs, spec)
-	}
+	specs := a.makeSpecs(apiDefs)
 
 	// Set the nonce
 	a.Gw.ServiceNonceMutex.Lock()
@@ -493,7 +558,6 @@ func (a APIDefinitionLoader) processRPCDefinitions(apiCollection string, gw *Gat
 		return nil, err
 	}
 
-	var specs []*APISpec
 	for _, def := range apiDefs {
 		def.DecodeFromDB()
 
@@ -506,12 +570,21 @@ func (a APIDefinitionLoader) processRPCDefinitions(apiCollection string, gw *Gat
 
 			def.Proxy.ListenPath = newListenPath
 		}
-
-		spec := a.MakeSpec(def, nil)
-		specs = append(specs, spec)
 	}
 
-	return specs, nil
+	return a.makeSpecs(apiDefs), nil
+}
+
+// makeSpecs makes the specs of defs concurrently, in their order.
+func (a APIDefinitionLoader) makeSpecs(defs []*apidef.APIDefinition) []*APISpec {
+	if len(defs) == 0 {
+		return nil
+	}
+	specs := make([]*APISpec, len(defs))
+	forEachConcurrently(len(defs), a.Gw.apiLoadWorkers(), func(i int) {
+		specs[i] = a.MakeSpec(defs[i], nil)
+	})
+	return specs
 }
 
 func (a APIDefinitionLoader) ParseDefinition(r io.Reader) (api apidef.APIDefinition) {
@@ -537,36 +610,52 @@ func (a APIDefinitionLoader) GetOASFilepath(path string) string {
 // FromDir will load APIDefinitions from a directory on the filesystem. Definitions need
 // to be the JSON representation of APIDefinition object
 func (a APIDefinitionLoader) FromDir(dir string) []*APISpec {
-	var specs []*APISpec
 	// Grab json files from directory
 	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	defPaths := paths[:0]
 	for _, path := range paths {
-		if strings.HasSuffix(path, "-oas.json") {
-			continue
+		if !strings.HasSuffix(path, "-oas.json") {
+			defPaths = append(defPaths, path)
 		}
+	}
 
-		log.Info("Loading API Specification from ", path)
-		f, err := os.Open(path)
-		if err != nil {
-			log.Error("Couldn't open api configuration file: ", err)
-			continue
+	// the definitions are parsed concurrently, and kept in the order of their files
+	loaded := make([]*APISpec, len(defPaths))
+	forEachConcurrently(len(defPaths), a.Gw.apiLoadWorkers(), func(i int) {
+		loaded[i] = a.loadDefFromPath(defPaths[i])
+	})
+
+	var specs []*APISpec
+	for _, spec := range loaded {
+		if spec != nil {
+			specs = append(specs, spec)
 		}
+	}
+	return specs
+}
 
-		def := a.ParseDefinition(f)
-		spec := a.MakeSpec(&def, nil)
+// loadDefFromPath makes the spec of the definition file at path, and of its OAS file if any.
+func (a APIDefinitionLoader) loadDefFromPath(path string) *APISpec {
+	log.Info("Loading API Specification from ", path)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("Couldn't open api configuration file: ", err)
+		return nil
+	}
 
-		_, _ = f.Seek(0, io.SeekStart)
-		_ = f.Close()
+	def := a.ParseDefinition(f)
+	spec := a.MakeSpec(&def, nil)
 
-		f, err = os.Open(a.GetOASFilepath(path))
-		if err == nil {
-			spec.OAS = a.ParseOAS(f)
-			_ = f.Close()
-		}
+	_, _ = f.Seek(0, io.SeekStart)
+	_ = f.Close()
 
-		specs = append(specs, spec)
+	f, err = os.Open(a.GetOASFilepath(path))
+	if err == nil {
+		spec.OAS = a.ParseOAS(f)
+		_ = f.Close()
+		a.compileOASDeprecations(spec)
 	}
-	return specs
+	return spec
 }
 
 func (a APIDefinitionLoader) getPathSpecs(apiVersionDef apidef.VersionInfo, conf config.Config) ([]URLSpec, bool) {
@@ -589,7 +678,7 @@ func (a APIDefinitionLoader) generateRegex(stringSpec string, newSpec *URLSpec,
 	if newSpec.IgnoreCase || conf.IgnoreEndpointCase {
 		asRegexStr = "(?i)" + asRegexStr
 	}
-	asRegex, _ := regexp.Compile(asRegexStr)
+	asRegex, _ := regexp.CompileShared(asRegexStr)
 	newSpec.Status = specType
 	newSpec.Spec = asRegex
 }
@@ -699,9 +788,16 @@ func (a APIDefinitionLoader) compileTransformPathSpec(paths []apidef.TemplateMet
 		case apidef.UseBlob:
 			log.Debug("-- Blob mode")
 			newTransformSpec.Template, err = a.loadBlobTemplate(stringSpec.TemplateData.TemplateSource)
+		case apidef.UseJSONToXML:
+			log.Debug("-- JSON to XML mode")
+			if root := stringSpec.TemplateData.TemplateSource; root != "" && !isXMLName(root) {
+				err = fmt.Errorf("%q is not a valid XML root element name", root)
+			}
+		case apidef.UseXMLToJSON:
+			log.Debug("-- XML to JSON mode")
 		default:
 			log.Warning("[Transform Templates] No template mode defined! Found: ", stringSpec.TemplateData.Mode)
-			err = errors.New("No valid template mode defined, must be either 'file' or 'blob'")
+			err = errors.New("No valid template mode defined, must be one of 'file', 'blob', 'json_to_xml' or 'xml_to_json'")
 		}
 
 		if stat == Transformed {
@@ -793,6 +889,77 @@ func (a APIDefinitionLoader) compileRequestSizePathSpec(paths []apidef.RequestSi
 	return urlSpec
 }
 
+func (a APIDefinitionLoader) compileResponseSizePathSpec(paths []apidef.ResponseSizeMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.ResponseSize = stringSpec
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+// compileBodyMaskingPathSpec compiles the masking rules of endpoints, only endpoints masking
+// responses are compiled for BodyMaskedResponse. APIs with invalid rules aren't loaded, see
+// validateBodyMasking.
+func (a APIDefinitionLoader) compileBodyMaskingPathSpec(paths []apidef.BodyMaskingMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		if stat == BodyMaskedResponse && !stringSpec.MaskResponse {
+			continue
+		}
+
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.BodyMasking = BodyMaskingSpec{BodyMaskingMeta: stringSpec}
+		for _, rule := range stringSpec.Rules {
+			mask, err := newBodyMask(rule)
+			if err != nil {
+				log.WithError(err).WithField("path", stringSpec.Path).Error("Invalid body masking rule")
+				continue
+			}
+			newSpec.BodyMasking.masks = append(newSpec.BodyMasking.masks, mask)
+		}
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+func (a APIDefinitionLoader) compileQueryTransformPathSpec(paths []apidef.QueryTransformMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.TransformQuery = stringSpec
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+func (a APIDefinitionLoader) compileRequiredScopesPathSpec(paths []apidef.RequiredScopesMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.RequiredScopes = stringSpec
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
 func (a APIDefinitionLoader) compileCircuitBreakerPathSpec(paths []apidef.CircuitBreakerMeta, stat URLStatus, apiSpec *APISpec, conf config.Config) []URLSpec {
 	// transform an extended configuration URL into an array of URLSpecs
 	// This way we can iterate the whole array once, on match we break with status
@@ -968,6 +1135,133 @@ func (a APIDefinitionLoader) compileValidateJSONPathspathSpec(paths []apidef.Val
 	return urlSpec
 }
 
+func (a APIDefinitionLoader) compileValidateXMLPathSpec(paths []apidef.ValidateXMLMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.ValidateXML = ValidateXMLSpec{ValidateXMLMeta: stringSpec}
+
+		schema, err := compileXMLSchema([]byte(stringSpec.Schema))
+		if err != nil {
+			log.WithError(err).WithField("path", stringSpec.Path).Error("Invalid XML schema")
+		} else {
+			newSpec.ValidateXML.schema = schema
+		}
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+func (a APIDefinitionLoader) compileValidateMultipartPathSpec(paths []apidef.ValidateMultipartMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		if stringSpec.Disabled {
+			continue
+		}
+
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.ValidateMultipart = stringSpec
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+func (a APIDefinitionLoader) compileDeprecatedPathSpec(paths []apidef.DeprecatedMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		if stringSpec.Disabled {
+			continue
+		}
+
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.Deprecated = DeprecatedSpec{DeprecatedMeta: stringSpec}
+
+		var err error
+		if stringSpec.Date != "" {
+			if newSpec.Deprecated.date, err = time.Parse(time.RFC3339, stringSpec.Date); err != nil {
+				log.WithError(err).WithField("path", stringSpec.Path).Error("Invalid deprecation date")
+			}
+		}
+		if stringSpec.Sunset != "" {
+			if newSpec.Deprecated.sunset, err = time.Parse(time.RFC3339, stringSpec.Sunset); err != nil {
+				log.WithError(err).WithField("path", stringSpec.Path).Error("Invalid sunset date")
+			}
+		}
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+// compileOASDeprecations adds the operations marked deprecated in the OAS document of spec to the
+// deprecated endpoints of its versions, after those of the extended paths which take precedence.
+func (a APIDefinitionLoader) compileOASDeprecations(spec *APISpec) {
+	deprecated := oas.DeprecatedOperations(spec.OAS.Paths)
+	if len(deprecated) == 0 {
+		return
+	}
+
+	urlSpecs := a.compileDeprecatedPathSpec(deprecated, Deprecated, a.Gw.GetConfig())
+	for name, paths := range spec.RxPaths {
+		spec.RxPaths[name] = append(paths, urlSpecs...)
+	}
+}
+
+func (a APIDefinitionLoader) compileCompositePathSpec(paths []apidef.CompositeMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		if stringSpec.Disabled {
+			continue
+		}
+
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.Composite = CompositeSpec{CompositeMeta: stringSpec}
+
+		if stringSpec.Template != "" {
+			tmpl, err := apidef.Template.New("").Funcs(a.filterSprigFuncs()).Parse(stringSpec.Template)
+			if err != nil {
+				log.WithError(err).WithField("path", stringSpec.Path).Error("Invalid composite template")
+				continue
+			}
+			newSpec.Composite.template = tmpl
+		}
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
+func (a APIDefinitionLoader) compileAsyncPathSpec(paths []apidef.AsyncMeta, stat URLStatus, conf config.Config) []URLSpec {
+	urlSpec := []URLSpec{}
+
+	for _, stringSpec := range paths {
+		if stringSpec.Disabled {
+			continue
+		}
+
+		newSpec := URLSpec{}
+		a.generateRegex(stringSpec.Path, &newSpec, stat, conf)
+		newSpec.Async = stringSpec
+
+		urlSpec = append(urlSpec, newSpec)
+	}
+
+	return urlSpec
+}
+
 func (a APIDefinitionLoader) compileUnTrackedEndpointPathspathSpec(paths []apidef.TrackEndpointMeta, stat URLStatus, conf config.Config) []URLSpec {
 	urlSpec := []URLSpec{}
 
@@ -1009,15 +1303,25 @@ func (a APIDefinitionLoader) getExtendedPathSpecs(apiVersionDef apidef.VersionIn
 	transformJQResponsePaths := a.compileTransformJQPathSpec(apiVersionDef.ExtendedPaths.TransformJQResponse, TransformedJQResponse)
 	headerTransformPaths := a.compileInjectedHeaderSpec(apiVersionDef.ExtendedPaths.TransformHeader, HeaderInjected, conf)
 	headerTransformPathsOnResponse := a.compileInjectedHeaderSpec(apiVersionDef.ExtendedPaths.TransformResponseHeader, HeaderInjectedResponse, conf)
+	queryTransformPaths := a.compileQueryTransformPathSpec(apiVersionDef.ExtendedPaths.TransformQuery, QueryTransformed, conf)
+	bodyMaskingPaths := a.compileBodyMaskingPathSpec(apiVersionDef.ExtendedPaths.BodyMasking, BodyMasked, conf)
+	bodyMaskingResponsePaths := a.compileBodyMaskingPathSpec(apiVersionDef.ExtendedPaths.BodyMasking, BodyMaskedResponse, conf)
 	hardTimeouts := a.compileTimeoutPathSpec(apiVersionDef.ExtendedPaths.HardTimeouts, HardTimeout, conf)
 	circuitBreakers := a.compileCircuitBreakerPathSpec(apiVersionDef.ExtendedPaths.CircuitBreaker, CircuitBreaker, apiSpec, conf)
 	urlRewrites := a.compileURLRewritesPathSpec(apiVersionDef.ExtendedPaths.URLRewrite, URLRewrite, conf)
 	virtualPaths := a.compileVirtualPathspathSpec(apiVersionDef.ExtendedPaths.Virtual, VirtualPath, apiSpec, conf)
 	requestSizes := a.compileRequestSizePathSpec(apiVersionDef.ExtendedPaths.SizeLimit, RequestSizeLimit, conf)
+	responseSizes := a.compileResponseSizePathSpec(apiVersionDef.ExtendedPaths.ResponseSizeLimit, ResponseSizeLimit, conf)
+	requiredScopes := a.compileRequiredScopesPathSpec(apiVersionDef.ExtendedPaths.RequiredScopes, RequiredScopes, conf)
 	methodTransforms := a.compileMethodTransformSpec(apiVersionDef.ExtendedPaths.MethodTransforms, MethodTransformed, conf)
 	trackedPaths := a.compileTrackedEndpointPathspathSpec(apiVersionDef.ExtendedPaths.TrackEndpoints, RequestTracked, conf)
 	unTrackedPaths := a.compileUnTrackedEndpointPathspathSpec(apiVersionDef.ExtendedPaths.DoNotTrackEndpoints, RequestNotTracked, conf)
 	validateJSON := a.compileValidateJSONPathspathSpec(apiVersionDef.ExtendedPaths.ValidateJSON, ValidateJSONRequest, conf)
+	validateXML := a.compileValidateXMLPathSpec(apiVersionDef.ExtendedPaths.ValidateXML, ValidateXMLRequest, conf)
+	validateMultipart := a.compileValidateMultipartPathSpec(apiVersionDef.ExtendedPaths.ValidateMultipart, ValidateMultipartRequest, conf)
+	deprecated := a.compileDeprecatedPathSpec(apiVersionDef.ExtendedPaths.Deprecated, Deprecated, conf)
+	composite := a.compileCompositePathSpec(apiVersionDef.ExtendedPaths.Composite, Composite, conf)
+	async := a.compileAsyncPathSpec(apiVersionDef.ExtendedPaths.Async, Async, conf)
 	internalPaths := a.compileInternalPathspathSpec(apiVersionDef.ExtendedPaths.Internal, Internal, conf)
 	goPlugins := a.compileGopluginPathspathSpec(apiVersionDef.ExtendedPaths.GoPlugin, GoPlugin, apiSpec, conf)
 
@@ -1032,16 +1336,26 @@ func (a APIDefinitionLoader) getExtendedPathSpecs(apiVersionDef apidef.VersionIn
 	combinedPath = append(combinedPath, transformJQResponsePaths...)
 	combinedPath = append(combinedPath, headerTransformPaths...)
 	combinedPath = append(combinedPath, headerTransformPathsOnResponse...)
+	combinedPath = append(combinedPath, queryTransformPaths...)
+	combinedPath = append(combinedPath, bodyMaskingPaths...)
+	combinedPath = append(combinedPath, bodyMaskingResponsePaths...)
 	combinedPath = append(combinedPath, hardTimeouts...)
 	combinedPath = append(combinedPath, circuitBreakers...)
 	combinedPath = append(combinedPath, urlRewrites...)
 	combinedPath = append(combinedPath, requestSizes...)
+	combinedPath = append(combinedPath, responseSizes...)
+	combinedPath = append(combinedPath, requiredScopes...)
 	combinedPath = append(combinedPath, goPlugins...)
 	combinedPath = append(combinedPath, virtualPaths...)
 	combinedPath = append(combinedPath, methodTransforms...)
 	combinedPath = append(combinedPath, trackedPaths...)
 	combinedPath = append(combinedPath, unTrackedPaths...)
 	combinedPath = append(combinedPath, validateJSON...)
+	combinedPath = append(combinedPath, validateXML...)
+	combinedPath = append(combinedPath, validateMultipart...)
+	combinedPath = append(combinedPath, deprecated...)
+	combinedPath = append(combinedPath, composite...)
+	combinedPath = append(combinedPath, async...)
 	combinedPath = append(combinedPath, internalPaths...)
 
 	return combinedPath, len(whiteListPaths) > 0
@@ -1101,6 +1415,26 @@ func (a *APISpec) getURLStatus(stat URLStatus) RequestStatus {
 		return StatusInternal
 	case GoPlugin:
 		return StatusGoPlugin
+	case ResponseSizeLimit:
+		return StatusResponseSizeControlled
+	case RequiredScopes:
+		return StatusRequiredScopes
+	case QueryTransformed:
+		return StatusQueryTransformed
+	case BodyMasked:
+		return StatusBodyMasked
+	case BodyMaskedResponse:
+		return StatusBodyMaskedResponse
+	case ValidateXMLRequest:
+		return StatusValidateXML
+	case ValidateMultipartRequest:
+		return StatusValidateMultipart
+	case Deprecated:
+		return StatusDeprecated
+	case Composite:
+		return StatusComposite
+	case Async:
+		return StatusAsync
 
 	default:
 		log.Error("URL Status was not one of Ignored, Blacklist or WhiteList! Blocking.")
@@ -1137,8 +1471,9 @@ func (a *APISpec) URLAllowedAndIgnored(r *http.Request, rxPaths []URLSpec, white
 			}
 		}
 
+		// Internal endpoints are hidden from public requests, as if they didn't exist
 		if r.Method == rxPaths[i].Internal.Method && rxPaths[i].Status == Internal && !ctxLoopingEnabled(r) {
-			return EndPointNotAllowed, nil
+			return EndPointNotFound, nil
 		}
 
 		if whiteListStatus {
@@ -1187,7 +1522,7 @@ func (a *APISpec) CheckSpecMatchesStatus(r *http.Request, rxPaths []URLSpec, mod
 
 	//If url-rewrite middleware was used, call response middleware of original path and not of rewritten path
 	// context variable UrlRewritePath is set by rewrite middleware
-	if mode == TransformedJQResponse || mode == HeaderInjectedResponse || mode == TransformedResponse {
+	if mode == TransformedJQResponse || mode == HeaderInjectedResponse || mode == TransformedResponse || mode == BodyMaskedResponse {
 		matchPath = ctxGetUrlRewritePath(r)
 		method = ctxGetRequestMethod(r)
 		if matchPath == "" {
@@ -1248,7 +1583,7 @@ func (a *APISpec) CheckSpecMatchesStatus(r *http.Request, rxPaths []URLSpec, mod
 			}
 		case HardTimeout:
 			if r.Method == rxPaths[i].HardTimeout.Method {
-				return true, &rxPaths[i].HardTimeout.TimeOut
+				return true, &rxPaths[i].HardTimeout
 			}
 		case CircuitBreaker:
 			if method == rxPaths[i].CircuitBreaker.Method {
@@ -1266,6 +1601,22 @@ func (a *APISpec) CheckSpecMatchesStatus(r *http.Request, rxPaths []URLSpec, mod
 			if method == rxPaths[i].RequestSize.Method {
 				return true, &rxPaths[i].RequestSize
 			}
+		case ResponseSizeLimit:
+			if method == rxPaths[i].ResponseSize.Method {
+				return true, &rxPaths[i].ResponseSize
+			}
+		case RequiredScopes:
+			if method == rxPaths[i].RequiredScopes.Method {
+				return true, &rxPaths[i].RequiredScopes
+			}
+		case QueryTransformed:
+			if method == rxPaths[i].TransformQuery.Method {
+				return true, &rxPaths[i].TransformQuery
+			}
+		case BodyMasked, BodyMaskedResponse:
+			if method == rxPaths[i].BodyMasking.Method {
+				return true, &rxPaths[i].BodyMasking
+			}
 		case MethodTransformed:
 			if method == rxPaths[i].MethodTransform.Method {
 				return true, &rxPaths[i].MethodTransform
@@ -1282,6 +1633,26 @@ func (a *APISpec) CheckSpecMatchesStatus(r *http.Request, rxPaths []URLSpec, mod
 			if method == rxPaths[i].ValidatePathMeta.Method {
 				return true, &rxPaths[i].ValidatePathMeta
 			}
+		case ValidateXMLRequest:
+			if method == rxPaths[i].ValidateXML.Method {
+				return true, &rxPaths[i].ValidateXML
+			}
+		case ValidateMultipartRequest:
+			if method == rxPaths[i].ValidateMultipart.Method {
+				return true, &rxPaths[i].ValidateMultipart
+			}
+		case Deprecated:
+			if method == rxPaths[i].Deprecated.Method {
+				return true, &rxPaths[i].Deprecated
+			}
+		case Composite:
+			if method == rxPaths[i].Composite.Method {
+				return true, &rxPaths[i].Composite
+			}
+		case Async:
+			if method == rxPaths[i].Async.Method {
+				return true, &rxPaths[i].Async
+			}
 		case Internal:
 			if method == rxPaths[i].Internal.Method {
 				return true, &rxPaths[i].Internal
@@ -1350,7 +1721,7 @@ func (a *APISpec) RequestValid(r *http.Request) (bool, RequestStatus) {
 	// not expired, let's check path info
 	status, _ = a.URLAllowedAndIgnored(r, versionPaths, whiteListStatus)
 	switch status {
-	case EndPointNotAllowed:
+	case EndPointNotAllowed, EndPointNotFound:
 		return false, status
 	case StatusRedirectFlowByReply:
 		return true, status
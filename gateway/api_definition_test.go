@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -17,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/regexp"
 	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/user"
 	redis "github.com/go-redis/redis/v8"
@@ -958,3 +960,73 @@ func TestAPIDefinitionLoader_Template(t *testing.T) {
 		executeAndAssert(t, temp)
 	})
 }
+
+// versionedWhiteListAPI returns an API with versions white listing the same paths.
+func versionedWhiteListAPI(versions, paths int) func(spec *APISpec) {
+	return func(spec *APISpec) {
+		spec.VersionData.NotVersioned = false
+		spec.VersionData.Versions = map[string]apidef.VersionInfo{}
+		for v := 0; v < versions; v++ {
+			version := apidef.VersionInfo{Name: fmt.Sprintf("v%d", v), UseExtendedPaths: true}
+			for p := 0; p < paths; p++ {
+				version.ExtendedPaths.WhiteList = append(version.ExtendedPaths.WhiteList, apidef.EndPointMeta{
+					Path:          fmt.Sprintf("/resource-%d/{id}", p),
+					MethodActions: map[string]apidef.EndpointMethodMeta{http.MethodGet: {Action: apidef.NoAction}},
+				})
+			}
+			spec.VersionData.Versions[version.Name] = version
+		}
+	}
+}
+
+func TestURLRegexCache(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "shared-regexes"
+		versionedWhiteListAPI(2, 1)(spec)
+	})[0]
+
+	v0, v1 := spec.RxPaths["v0"][0].Spec, spec.RxPaths["v1"][0].Spec
+	assert.True(t, v0.Regexp == v1.Regexp, "the versions should share the regex")
+
+	shared := v0.String()
+	used := func() bool {
+		found := false
+		regexp.SweepShared(func(expr string) bool {
+			found = found || expr == shared
+			return true
+		})
+		return found
+	}
+	assert.True(t, used())
+
+	ts.Gw.BuildAndLoadAPI()
+	assert.False(t, used(), "the regex of the removed API should be swept")
+}
+
+func BenchmarkURLRegexCache(b *testing.B) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	def := BuildAPI(versionedWhiteListAPI(20, 50))[0].APIDefinition
+	loader := APIDefinitionLoader{Gw: ts.Gw}
+	makeSpec := func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			clone := *def
+			loader.MakeSpec(&clone, nil)
+		}
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		regexp.ResetCache(0, true)
+		makeSpec(b)
+	})
+	b.Run("uncached", func(b *testing.B) {
+		regexp.ResetCache(0, false)
+		defer regexp.ResetCache(0, true)
+		makeSpec(b)
+	})
+}
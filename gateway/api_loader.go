@@ -8,12 +8,14 @@ import (
 	"net/url"
 	"path"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
@@ -21,6 +23,7 @@ import (
 
 	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/coprocess"
+	"github.com/TykTechnologies/tyk/regexp"
 	"github.com/TykTechnologies/tyk/storage"
 	"github.com/TykTechnologies/tyk/trace"
 )
@@ -116,6 +119,16 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		"api_name": spec.Name,
 	})
 
+	redactor, err := newLogRedactor(spec.Logging)
+	if err != nil {
+		logger.WithError(err).Error("Invalid log redaction rules of the API")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+	spec.LogRedactor = redactor
+	logger = apiLogger(logger, spec)
+
 	var coprocessLog = logger.WithFields(logrus.Fields{
 		"prefix": "coprocess",
 	})
@@ -138,6 +151,20 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		spec.TagHeaders = lowerCaseHeaders
 	}
 
+	if spec.templateErr != nil {
+		logger.WithError(spec.templateErr).Error("Could not resolve the template variables of the API")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+
+	if err := gw.resolveAPISecrets(spec); err != nil {
+		logger.WithError(err).Error("Could not resolve the secret references of the API")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+
 	if gw.skipSpecBecauseInvalid(spec, logger) {
 		logger.Warning("Spec not valid, skipped!")
 		chainDef.Skip = true
@@ -192,10 +219,8 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 	case RPCStorageEngine:
 		authStore = gs.rpcAuthStore
 		orgStore = gs.rpcOrgStore
+		// the gateway enforces it too, see enforceOrgDataAge
 		spec.GlobalConfig.EnforceOrgDataAge = true
-		globalConf := gw.GetConfig()
-		globalConf.EnforceOrgDataAge = true
-		gw.SetConfig(globalConf)
 	}
 
 	sessionStore := gs.redisStore
@@ -232,8 +257,8 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		spec.JSVM.LoadJSPaths(mwPaths, prefix)
 	}
 
-	//  if bundle was used - fix paths for goplugin-type custom middle-wares
-	if mwDriver == apidef.GoPluginDriver && prefix != "" {
+	//  if bundle was used - fix paths for goplugin-type and wasm custom middle-wares
+	if (mwDriver == apidef.GoPluginDriver || mwDriver == apidef.WasmDriver) && prefix != "" {
 		mwAuthCheckFunc.Path = filepath.Join(prefix, mwAuthCheckFunc.Path)
 		fixFuncPath(prefix, mwPreFuncs)
 		fixFuncPath(prefix, mwPostFuncs)
@@ -269,8 +294,95 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 	// Already vetted
 	spec.target, _ = url.Parse(spec.Proxy.TargetURL)
 
+	canary, err := NewCanaryRouter(spec.Proxy.Canary)
+	if err != nil {
+		logger.WithError(err).Error("Invalid canary configuration, canary routing disabled")
+		canary, _ = NewCanaryRouter(apidef.CanaryConfig{})
+	}
+	spec.Canary = canary
+
+	maintenanceConf := spec.APIDefinition.Maintenance
+	if conf, ok := gw.maintenanceOverride(spec.APIID); ok {
+		maintenanceConf = conf
+	}
+	maintenance, err := NewMaintenanceMode(maintenanceConf)
+	if err != nil {
+		logger.WithError(err).Error("Invalid maintenance configuration, maintenance disabled")
+		maintenance, _ = NewMaintenanceMode(apidef.MaintenanceConfig{})
+	}
+	spec.MaintenanceMode = maintenance
+
+	spec.ErrorResponseTemplates = NewErrorResponseTemplates(spec.ErrorTemplates, spec.GlobalConfig.ErrorTemplates, logger)
+
+	spec.IPAccess, err = NewIPAccessList(spec.AllowedIPs, spec.BlacklistedIPs)
+	if err != nil {
+		logger.WithError(err).Error("Invalid IP access entry")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+
+	if err := validateBodyMasking(spec.APIDefinition); err != nil {
+		logger.WithError(err).Error("Invalid body masking rule")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+
+	if err := validateXMLSchemas(spec.APIDefinition); err != nil {
+		logger.WithError(err).Error("Invalid XML schema")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+
+	if spec.BotDetection.Enabled {
+		if _, err := compileBadUserAgents(spec.BotDetection.BadUserAgents); err != nil {
+			logger.WithError(err).Error("Invalid bot detection configuration")
+			logger.Warning("Spec not valid, skipped!")
+			chainDef.Skip = true
+			return &chainDef
+		}
+	}
+
+	spec.HashBalancer = nil
+	if spec.Proxy.ConsistentHashing.Enabled {
+		spec.HashBalancer, err = NewConsistentHashBalancer(spec.Proxy.ConsistentHashing)
+		if err != nil {
+			logger.WithError(err).Error("Invalid consistent hashing configuration, falling back to round robin")
+		}
+	}
+
+	spec.UpstreamStats = NewUpstreamStats()
+
+	spec.AnalyticsSampler, err = NewAnalyticsSampler(spec.AnalyticsSampling)
+	if err != nil {
+		logger.WithError(err).Error("Invalid analytics sampling configuration")
+		logger.Warning("Spec not valid, skipped!")
+		chainDef.Skip = true
+		return &chainDef
+	}
+
+	spec.TrafficSampler, err = NewTrafficSampler(spec.APIDefinition)
+	if err != nil {
+		logger.WithError(err).Error("Invalid traffic samples configuration, no samples will be captured")
+	}
+
 	var proxy ReturningHttpHandler
-	if enableVersionOverrides {
+	spec.KafkaProxy = nil
+	if spec.Kafka.Enabled {
+		logger.Info("Kafka upstream enabled")
+		if spec.KafkaProxy, err = gw.NewKafkaProxy(spec, logger); err != nil {
+			logger.WithError(err).Error("Invalid Kafka configuration")
+			logger.Warning("Spec not valid, skipped!")
+			chainDef.Skip = true
+			return &chainDef
+		}
+	}
+
+	if spec.KafkaProxy != nil {
+		proxy = spec.KafkaProxy
+	} else if enableVersionOverrides {
 		logger.Info("Multi target enabled")
 		proxy = gw.NewMultiTargetProxy(spec, logger)
 	} else {
@@ -315,6 +427,8 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 					APILevel:       true,
 				},
 			)
+		} else if mwDriver == apidef.WasmDriver {
+			gw.mwAppendEnabled(&chainArray, &WasmMiddleware{BaseMiddleware: baseMid, Definition: obj})
 		} else if mwDriver != apidef.OttoDriver {
 			coprocessLog.Debug("Registering coprocess middleware, hook name: ", obj.Name, "hook type: Pre", ", driver: ", mwDriver)
 			gw.mwAppendEnabled(&chainArray, &CoProcessMiddleware{baseMid, coprocess.HookType_Pre, obj.Name, mwDriver, obj.RawBodyOnly, nil})
@@ -323,12 +437,18 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		}
 	}
 
+	gw.mwAppendEnabled(&chainArray, &MaintenanceMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &VersionCheck{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &DeprecationMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &RateCheckMW{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &IPWhiteListMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &IPBlackListMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &GeoIPAccessMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &BotDetectionMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &CertificateCheckMW{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &GatewayFederationMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &OrganizationMonitor{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &HeaderLimitsMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &RequestSizeLimitMiddleware{baseMid})
 	gw.mwAppendEnabled(&chainArray, &MiddlewareContextVars{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &TrackEndpointMiddleware{baseMid})
@@ -406,6 +526,8 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 						APILevel:       true,
 					},
 				)
+			} else if mwDriver == apidef.WasmDriver {
+				gw.mwAppendEnabled(&chainArray, &WasmMiddleware{BaseMiddleware: baseMid, Definition: obj})
 			} else {
 				coprocessLog.Debug("Registering coprocess middleware, hook name: ", obj.Name, "hook type: Pre", ", driver: ", mwDriver)
 				gw.mwAppendEnabled(&chainArray, &CoProcessMiddleware{baseMid, coprocess.HookType_PostKeyAuth, obj.Name, mwDriver, obj.RawBodyOnly, nil})
@@ -416,10 +538,13 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		gw.mwAppendEnabled(&chainArray, &KeyExpired{baseMid})
 		gw.mwAppendEnabled(&chainArray, &AccessRightsCheck{baseMid})
 		gw.mwAppendEnabled(&chainArray, &GranularAccessMiddleware{baseMid})
+		gw.mwAppendEnabled(&chainArray, &RequiredScopesMiddleware{BaseMiddleware: baseMid})
 		gw.mwAppendEnabled(&chainArray, &RateLimitAndQuotaCheck{baseMid})
 	}
 
+	gw.mwAppendEnabled(&chainArray, &GRPCAccessMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &RateLimitForAPI{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &WebhookSubscriptionMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &GraphQLMiddleware{BaseMiddleware: baseMid})
 	if !spec.UseKeylessAccess {
 		gw.mwAppendEnabled(&chainArray, &GraphQLComplexityMiddleware{BaseMiddleware: baseMid})
@@ -427,12 +552,19 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 	}
 
 	gw.mwAppendEnabled(&chainArray, &ValidateJSON{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &ValidateXML{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &ValidateMultipart{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &TransformMiddleware{baseMid})
 	gw.mwAppendEnabled(&chainArray, &TransformJQMiddleware{baseMid})
 	gw.mwAppendEnabled(&chainArray, &TransformHeaders{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &SessionHeadersMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &TransformQuery{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &URLRewriteMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &TransformMethod{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &BodyMasking{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &VirtualEndpoint{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &CompositeEndpoint{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &TokenExchangeMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &RequestSigning{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &GoPluginMiddleware{BaseMiddleware: baseMid})
 
@@ -447,6 +579,8 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 					APILevel:       true,
 				},
 			)
+		} else if mwDriver == apidef.WasmDriver {
+			gw.mwAppendEnabled(&chainArray, &WasmMiddleware{BaseMiddleware: baseMid, Definition: obj})
 		} else if mwDriver != apidef.OttoDriver {
 			coprocessLog.Debug("Registering coprocess middleware, hook name: ", obj.Name, "hook type: Post", ", driver: ", mwDriver)
 			gw.mwAppendEnabled(&chainArray, &CoProcessMiddleware{baseMid, coprocess.HookType_Post, obj.Name, mwDriver, obj.RawBodyOnly, nil})
@@ -454,6 +588,8 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 			chainArray = append(chainArray, gw.createDynamicMiddleware(obj.Name, false, obj.RequireSession, baseMid))
 		}
 	}
+	gw.mwAppendEnabled(&chainArray, &AsyncMiddleware{BaseMiddleware: baseMid})
+
 	//Do not add middlewares after cache middleware.
 	//It will not get executed
 	gw.mwAppendEnabled(&chainArray, &RedisCacheMiddleware{BaseMiddleware: baseMid, CacheStore: &cacheStore})
@@ -464,6 +600,7 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		var simpleArray []alice.Constructor
 		gw.mwAppendEnabled(&simpleArray, &IPWhiteListMiddleware{baseMid})
 		gw.mwAppendEnabled(&simpleArray, &IPBlackListMiddleware{BaseMiddleware: baseMid})
+		gw.mwAppendEnabled(&simpleArray, &GeoIPAccessMiddleware{BaseMiddleware: baseMid})
 		gw.mwAppendEnabled(&simpleArray, &OrganizationMonitor{BaseMiddleware: baseMid})
 		gw.mwAppendEnabled(&simpleArray, &VersionCheck{BaseMiddleware: baseMid})
 		simpleArray = append(simpleArray, authArray...)
@@ -479,6 +616,14 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 
 	logger.Debug("Setting Listen Path: ", spec.Proxy.ListenPath)
 
+	chain = gw.concurrencyLimitHandler(baseMid, chain)
+	chain = gw.bodySizeLimitHandler(baseMid, chain)
+
+	if gw.accessLog != nil {
+		chain = gw.accessLogHandler(spec, chain)
+	}
+	chain = gw.requestIDHandler(chain)
+
 	if trace.IsEnabled() {
 		chainDef.ThisHandler = trace.Handle(spec.Name, chain)
 	} else {
@@ -621,6 +766,11 @@ func (gw *Gateway) loadGlobalApps() {
 	specs := make([]*APISpec, len(gw.apiSpecs))
 	copy(specs, gw.apiSpecs)
 	gw.apisMu.RUnlock()
+
+	if gw.GetConfig().EnableIncrementalReload {
+		gw.loadAppsIncrementally(specs)
+		return
+	}
 	gw.loadApps(specs)
 }
 
@@ -656,7 +806,8 @@ func (gw *Gateway) fuzzyFindAPI(search string) *APISpec {
 	return nil
 }
 
-func (gw *Gateway) loadHTTPService(spec *APISpec, apisByListen map[string]int, gs *generalStores, muxer *proxyMux) http.Handler {
+// httpServiceRouter returns the router of the port and host spec listens on.
+func (gw *Gateway) httpServiceRouter(spec *APISpec, muxer *proxyMux) *mux.Router {
 	gwConfig := gw.GetConfig()
 	port := gwConfig.ListenPort
 	if spec.ListenPort != 0 {
@@ -677,8 +828,12 @@ func (gw *Gateway) loadHTTPService(spec *APISpec, apisByListen map[string]int, g
 		mainLog.Info("API hostname set: ", hostname)
 		router = router.Host(hostname).Subrouter()
 	}
+	return router
+}
 
-	subrouter := router.PathPrefix(spec.Proxy.ListenPath).Subrouter()
+// buildHTTPService builds the middleware chain of spec on its subrouter. The chains of different
+// APIs may be built concurrently, as long as their subrouters were created beforehand.
+func (gw *Gateway) buildHTTPService(spec *APISpec, apisByListen map[string]int, gs *generalStores, subrouter *mux.Router) http.Handler {
 	chainObj := gw.processSpec(spec, apisByListen, gs, subrouter, logrus.NewEntry(log))
 	if chainObj.Skip {
 		return chainObj.ThisHandler
@@ -688,10 +843,33 @@ func (gw *Gateway) loadHTTPService(spec *APISpec, apisByListen map[string]int, g
 		subrouter.Handle(rateLimitEndpoint, chainObj.RateLimitChain)
 	}
 
-	subrouter.NewRoute().Handler(chainObj.ThisHandler)
+	if spec.streamsMultipart() {
+		subrouter.NewRoute().Handler(&streamedBodyHandler{chainObj.ThisHandler})
+	} else {
+		subrouter.NewRoute().Handler(chainObj.ThisHandler)
+	}
+	spec.routes = subrouter
 	return chainObj.ThisHandler
 }
 
+// mountLoadedService mounts the services of a loaded spec on muxer, without rebuilding them.
+func (gw *Gateway) mountLoadedService(spec *APISpec, muxer *proxyMux, handles *sync.Map) {
+	switch spec.Protocol {
+	case "", "http", "https", "h2c":
+		if handle, ok := gw.apisHandlesByID.Load(spec.APIID); ok {
+			handles.Store(spec.APIID, handle)
+		}
+		// skipped specs have no routes
+		if spec.routes != nil {
+			// the path was already cleaned by the port router
+			spec.routes.SkipClean(true)
+			gw.httpServiceRouter(spec, muxer).PathPrefix(spec.Proxy.ListenPath).Handler(spec.routes)
+		}
+	case "tcp", "tls", "mqtt", "mqtts", "amqp", "amqps":
+		muxer.addTCPService(spec, gw.brokerModifier(spec), gw)
+	}
+}
+
 func (gw *Gateway) loadTCPService(spec *APISpec, gs *generalStores, muxer *proxyMux) {
 	// Initialise the auth and session managers (use Redis for now)
 	authStore := gs.redisStore
@@ -704,10 +882,8 @@ func (gw *Gateway) loadTCPService(spec *APISpec, gs *generalStores, muxer *proxy
 	case RPCStorageEngine:
 		authStore = gs.rpcAuthStore
 		orgStore = gs.rpcOrgStore
+		// the gateway enforces it too, see enforceOrgDataAge
 		spec.GlobalConfig.EnforceOrgDataAge = true
-		gwConfig := gw.GetConfig()
-		gwConfig.EnforceOrgDataAge = true
-		gw.SetConfig(gwConfig)
 	}
 
 	sessionStore := gs.redisStore
@@ -719,7 +895,7 @@ func (gw *Gateway) loadTCPService(spec *APISpec, gs *generalStores, muxer *proxy
 	// Health checkers are initialised per spec so that each API handler has it's own connection and redis storage pool
 	spec.Init(authStore, sessionStore, gs.healthStore, orgStore)
 
-	muxer.addTCPService(spec, nil, gw)
+	muxer.addTCPService(spec, gw.brokerModifier(spec), gw)
 }
 
 type generalStores struct {
@@ -794,8 +970,100 @@ func (gw *Gateway) loadGraphQLPlayground(spec *APISpec, subrouter *mux.Router) {
 	})
 }
 
+// sweepURLRegexes drops the shared URL regexes no loaded API uses anymore and reports the cache statistics.
+func (gw *Gateway) sweepURLRegexes() {
+	used := make(map[string]bool)
+	gw.apisMu.RLock()
+	for _, spec := range gw.apisByID {
+		for _, urlSpecs := range spec.RxPaths {
+			for _, urlSpec := range urlSpecs {
+				if urlSpec.Spec != nil {
+					used[urlSpec.Spec.String()] = true
+				}
+			}
+		}
+	}
+	gw.apisMu.RUnlock()
+	regexp.SweepShared(func(expr string) bool {
+		return used[expr]
+	})
+
+	stats := regexp.SharedStats()
+	mainLog.Debugf("URL regex cache: %d regexes, %d hits, %d misses, %.2f hit rate",
+		stats.Size, stats.Hits, stats.Misses, stats.HitRate())
+	if instrumentationEnabled {
+		job := instrument.NewJob("URLRegexCache")
+		job.Gauge("size", float64(stats.Size))
+		job.Gauge("hits", float64(stats.Hits))
+		job.Gauge("misses", float64(stats.Misses))
+		job.Gauge("hit_rate", stats.HitRate())
+	}
+}
+
+// enforceOrgDataAge enforces the data age of the organisations once an API authenticates with the
+// RPC storage engine. The configuration of the gateway is changed before the APIs are loaded, as
+// they are loaded concurrently.
+func (gw *Gateway) enforceOrgDataAge(specs []*APISpec) {
+	if gw.GetConfig().EnforceOrgDataAge {
+		return
+	}
+	for _, spec := range specs {
+		if spec.AuthProvider.StorageEngine == RPCStorageEngine {
+			gwConf := gw.GetConfig()
+			gwConf.EnforceOrgDataAge = true
+			gw.SetConfig(gwConf)
+			return
+		}
+	}
+}
+
+// apiLoadWorkers returns the number of APIs loaded concurrently.
+func (gw *Gateway) apiLoadWorkers() int {
+	if workers := gw.GetConfig().APILoadWorkers; workers > 0 {
+		return workers
+	}
+	return runtime.NumCPU()
+}
+
+// forEachConcurrently calls fn with the indexes up to n on at most workers goroutines, and returns
+// once all the calls returned.
+func forEachConcurrently(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
 // Create the individual API (app) specs based on live configurations and assign middleware
 func (gw *Gateway) loadApps(specs []*APISpec) {
+	gw.loadAppsReusing(specs, nil)
+}
+
+// loadAppsReusing loads specs like loadApps, except the specs of loaded which are mounted
+// as they are instead of being rebuilt.
+func (gw *Gateway) loadAppsReusing(specs []*APISpec, loaded map[string]*APISpec) {
 	mainLog.Info("Loading API configurations.")
 
 	tmpSpecRegister := make(map[string]*APISpec)
@@ -828,7 +1096,12 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 
 	gs := gw.prepareStorage()
 	shouldTrace := trace.IsEnabled()
-	for _, spec := range specs {
+	gw.enforceOrgDataAge(specs)
+
+	// The routers of the HTTP services are created in order, as the first matching route is used,
+	// then their middleware chains are built concurrently.
+	subrouters := make([]*mux.Router, len(specs))
+	for i, spec := range specs {
 		func() {
 			defer func() {
 				// recover from panic if one occured. Set err to nil otherwise.
@@ -837,6 +1110,12 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 				}
 			}()
 
+			if loaded[spec.APIID] == spec {
+				tmpSpecRegister[spec.APIID] = spec
+				gw.mountLoadedService(spec, muxer, tmpSpecHandles)
+				return
+			}
+
 			if spec.ListenPort != spec.GlobalConfig.ListenPort {
 				mainLog.Info("API bind on custom port:", spec.ListenPort)
 			}
@@ -858,13 +1137,32 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 						mainLog.Infof("Intialized tracer  api_name=%q", spec.Name)
 					}
 				}
-				tmpSpecHandles.Store(spec.APIID, gw.loadHTTPService(spec, apisByListen, &gs, muxer))
-			case "tcp", "tls":
+				subrouters[i] = gw.httpServiceRouter(spec, muxer).PathPrefix(spec.Proxy.ListenPath).Subrouter()
+			case "tcp", "tls", "mqtt", "mqtts", "amqp", "amqps":
 				gw.loadTCPService(spec, &gs, muxer)
 			}
 		}()
 	}
 
+	forEachConcurrently(len(specs), gw.apiLoadWorkers(), func(i int) {
+		spec := specs[i]
+		if subrouters[i] == nil {
+			return
+		}
+		defer func() {
+			if err := recover(); err != nil {
+				log.Errorf("Panic while loading an API: %v, panic: %v, stacktrace: %v", spec.APIDefinition, err, string(debug.Stack()))
+			}
+		}()
+
+		start := time.Now()
+		tmpSpecHandles.Store(spec.APIID, gw.buildHTTPService(spec, apisByListen, &gs, subrouters[i]))
+		mainLog.WithFields(logrus.Fields{
+			"api_id":   spec.APIID,
+			"api_name": spec.Name,
+		}).Debugf("API loaded in %v", time.Since(start))
+	})
+
 	gw.DefaultProxyMux.swap(muxer, gw)
 
 	// Swap in the new register
@@ -872,7 +1170,9 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 
 	// release current specs resources before overwriting map
 	for _, curSpec := range gw.apisByID {
-		curSpec.Release()
+		if tmpSpecRegister[curSpec.APIID] != curSpec {
+			curSpec.Release()
+		}
 	}
 
 	gw.apisByID = tmpSpecRegister
@@ -880,6 +1180,8 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 
 	gw.apisMu.Unlock()
 
+	globalLogRedaction.setLogRedaction(tmpSpecRegister)
+
 	mainLog.Debug("Checker host list")
 
 	// Kick off our host checkers
@@ -889,6 +1191,8 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 
 	mainLog.Debug("Checker host Done")
 
+	gw.sweepURLRegexes()
+
 	mainLog.Info("Initialised API Definitions")
 
 }
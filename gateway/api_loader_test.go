@@ -1,18 +1,27 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"path"
 	_ "path"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/user"
 
 	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/trace"
+	"github.com/TykTechnologies/tyk/trace/otlp"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,6 +61,111 @@ func TestOpenTracing(t *testing.T) {
 	})
 }
 
+func TestOpenTelemetryTracing(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		exported    string
+		traceParent string
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		exported += string(body)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceParent = r.Header.Get("traceparent")
+		mu.Unlock()
+	}))
+	defer upstream.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+	trace.SetInit(trace.Init)
+	trace.SetupTracing(otlp.Name, map[string]interface{}{"endpoint": collector.URL, "flush_interval": 10})
+	defer trace.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "otel"
+		spec.Name = "otel"
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.UseKeylessAccess = false
+	})
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.Alias = "otel-alias"
+		s.AccessRights = map[string]user.AccessDefinition{"otel": {APIID: "otel"}}
+	})
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_, _ = ts.Run(t, test.TestCase{Path: "/", Headers: map[string]string{"Authorization": key, "traceparent": incoming}, Code: http.StatusOK})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Contains(exported, `"AuthKey"`) && strings.Contains(exported, `"name":"/"`)
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Regexp(t, `^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$`, traceParent, "trace context should be propagated upstream")
+	assert.Contains(t, exported, `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, exported, `{"key":"api_id","value":{"stringValue":"otel"}}`)
+	assert.Contains(t, exported, `{"key":"key_alias","value":{"stringValue":"otel-alias"}}`)
+}
+
+func TestOpenTelemetryLogCorrelation(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		exported string
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		exported += string(body)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+	trace.SetInit(trace.Init)
+	trace.SetupTracing(otlp.Name, map[string]interface{}{"endpoint": collector.URL, "flush_interval": 10})
+	defer trace.Close()
+
+	hooks := log.ReplaceHooks(make(logrus.LevelHooks))
+	defer log.ReplaceHooks(hooks)
+	log.AddHook(trace.SpanLogHook{})
+	level := log.GetLevel()
+	defer log.SetLevel(level)
+	log.SetLevel(logrus.InfoLevel)
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "otel-logs"
+		spec.Name = "otel-logs"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+		spec.Tracing.CaptureLogs = true
+	})
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_, _ = ts.Run(t, test.TestCase{Path: "/", Headers: map[string]string{"traceparent": incoming}, Code: http.StatusUnauthorized,
+		BodyMatch: `"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",\s+"span_id": "[0-9a-f]{16}"`})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Contains(exported, `"AuthKey"`)
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, exported, `{"key":"message","value":{"stringValue":"Attempted access with malformed header, no auth header found."}}`)
+}
+
 func TestInternalAPIUsage(t *testing.T) {
 	g := StartTest(nil)
 	defer g.Close()
@@ -367,3 +481,132 @@ func TestTykRateLimitsStatusOfAPI(t *testing.T) {
 
 	_, _ = g.Run(t, test.TestCase{Path: "/my-api/tyk/rate-limits/", Headers: authHeader, BodyMatch: bodyMatch, Code: http.StatusOK})
 }
+
+func TestForEachConcurrently(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 20} {
+		var called, inFlight, maxInFlight int32
+		seen := make([]int32, 10)
+		forEachConcurrently(len(seen), workers, func(i int) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&seen[i], 1)
+			atomic.AddInt32(&called, 1)
+			atomic.AddInt32(&inFlight, -1)
+		})
+
+		assert.Equal(t, int32(len(seen)), called)
+		for i := range seen {
+			assert.Equal(t, int32(1), seen[i])
+		}
+		limit := workers
+		if limit < 1 {
+			limit = 1
+		}
+		assert.LessOrEqual(t, maxInFlight, int32(limit))
+	}
+}
+
+func TestEnforceOrgDataAge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	specs := []*APISpec{{APIDefinition: &apidef.APIDefinition{}}}
+	gw.enforceOrgDataAge(specs)
+	assert.False(t, gw.GetConfig().EnforceOrgDataAge)
+
+	specs = append(specs, &APISpec{APIDefinition: &apidef.APIDefinition{
+		AuthProvider: apidef.AuthProviderMeta{StorageEngine: RPCStorageEngine},
+	}})
+	gw.enforceOrgDataAge(specs)
+	assert.True(t, gw.GetConfig().EnforceOrgDataAge)
+}
+
+func TestConcurrentPluginLoading(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.APILoadWorkers = 8
+	}, TestConfig{
+		CoprocessConfig: config.CoProcessConfig{
+			EnableCoProcess:  true,
+			PythonPathPrefix: pkgPath,
+			PythonVersion:    "3.5",
+		}})
+	defer ts.Close()
+
+	bundles := map[string]string{
+		"python": ts.RegisterBundle("python_concurrent", overrideResponsePython),
+		"jsvm":   ts.RegisterBundle("jsvm_concurrent", overrideResponseJSVM),
+		"grpc":   ts.RegisterBundle("grpc_concurrent", grpcBundleWithAuthCheck),
+	}
+
+	var ids []string
+	var gens []func(spec *APISpec)
+	for i := 0; i < 4; i++ {
+		for kind, bundle := range bundles {
+			id, bundle := fmt.Sprintf("%s-%d", kind, i), bundle
+			ids = append(ids, id)
+			gens = append(gens, func(spec *APISpec) {
+				spec.APIID = id
+				spec.Proxy.ListenPath = "/" + id + "/"
+				spec.CustomMiddlewareBundle = bundle
+			})
+		}
+
+		id := fmt.Sprintf("goplugin-%d", i)
+		ids = append(ids, id)
+		gens = append(gens, func(spec *APISpec) {
+			spec.APIID = id
+			spec.Proxy.ListenPath = "/" + id + "/"
+			spec.CustomMiddleware = apidef.MiddlewareSection{
+				Driver: apidef.GoPluginDriver,
+				Pre:    []apidef.MiddlewareDefinition{{Name: "MyPluginPre", Path: "../test/goplugins/goplugins.so"}},
+			}
+		})
+	}
+	ts.Gw.BuildAndLoadAPI(gens...)
+
+	for _, id := range ids {
+		_, ok := ts.Gw.apisHandlesByID.Load(id)
+		assert.True(t, ok, id)
+	}
+}
+
+func TestConcurrentAPILoading(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.APILoadWorkers = 4
+	})
+	defer ts.Close()
+
+	var gens []func(spec *APISpec)
+	for i := 0; i < 20; i++ {
+		i := i
+		gens = append(gens, func(spec *APISpec) {
+			spec.APIID = fmt.Sprintf("api-%d", i)
+			spec.Proxy.ListenPath = fmt.Sprintf("/api-%d/", i)
+		})
+	}
+	// the protected API is mounted before the shorter listen path
+	gens = append(gens, func(spec *APISpec) {
+		spec.APIID = "protected"
+		spec.Proxy.ListenPath = "/api-1/protected/"
+		spec.UseKeylessAccess = false
+	})
+	ts.Gw.BuildAndLoadAPI(gens...)
+
+	for i := 0; i < 20; i++ {
+		_, ok := ts.Gw.apisHandlesByID.Load(fmt.Sprintf("api-%d", i))
+		assert.True(t, ok)
+	}
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/api-0/", Code: http.StatusOK},
+		{Path: "/api-19/", Code: http.StatusOK},
+		{Path: "/api-1/", Code: http.StatusOK},
+		{Path: "/api-1/protected/", Code: http.StatusUnauthorized},
+	}...)
+}
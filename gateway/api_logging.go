@@ -0,0 +1,280 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// logRedactedBody replaces the bodies which can't be redacted, not being JSON.
+const logRedactedBody = "<body omitted, it couldn't be redacted>"
+
+// logRedactor redacts the headers, query parameters and JSON body fields listed by the logging
+// section of an API definition from the logs and traces of the API. A nil redactor redacts nothing.
+type logRedactor struct {
+	headers     map[string]bool
+	queryParams map[string]bool
+	// queryParamsRe matches the configured query parameters in the URLs of the log messages, the
+	// first group being the name of the parameter.
+	queryParamsRe *regexp.Regexp
+	masks         []bodyMask
+}
+
+// newLogRedactor creates the redactor of the logging section of an API definition. It returns nil
+// when nothing is redacted.
+func newLogRedactor(conf apidef.LoggingConfig) (*logRedactor, error) {
+	if len(conf.RedactHeaders) == 0 && len(conf.RedactQueryParams) == 0 && len(conf.RedactRules) == 0 {
+		return nil, nil
+	}
+
+	l := &logRedactor{
+		headers:     make(map[string]bool),
+		queryParams: make(map[string]bool),
+	}
+	for _, name := range conf.RedactHeaders {
+		l.headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	var names []string
+	for _, name := range conf.RedactQueryParams {
+		l.queryParams[name] = true
+		names = append(names, regexp.QuoteMeta(url.QueryEscape(name)))
+	}
+	if len(names) > 0 {
+		l.queryParamsRe = regexp.MustCompile(`((?:^|[?&;])(?:` + strings.Join(names, "|") + `)=)[^&#\s"']*`)
+	}
+
+	for _, rule := range conf.RedactRules {
+		// tokenizing would store the values of the logs in Redis
+		if rule.Action != apidef.BodyMaskRedact && rule.Action != apidef.BodyMaskHash {
+			return nil, fmt.Errorf("logging doesn't support the body masking action %q", rule.Action)
+		}
+		mask, err := newBodyMask(rule)
+		if err != nil {
+			return nil, err
+		}
+		l.masks = append(l.masks, mask)
+	}
+
+	return l, nil
+}
+
+// header returns a copy of h with the values of the redacted headers replaced.
+func (l *logRedactor) header(h http.Header) http.Header {
+	if l == nil || len(l.headers) == 0 || h == nil {
+		return h
+	}
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if l.headers[http.CanonicalHeaderKey(name)] {
+			values = []string{bodyMaskDefaultRedaction}
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+// query returns rawQuery with the values of the redacted query parameters replaced.
+func (l *logRedactor) query(rawQuery string) string {
+	if l == nil || len(l.queryParams) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return l.text(rawQuery)
+	}
+	for name, v := range values {
+		if l.queryParams[name] {
+			for i := range v {
+				v[i] = bodyMaskDefaultRedaction
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// text redacts the query parameters of the URLs in s.
+func (l *logRedactor) text(s string) string {
+	if l == nil || l.queryParamsRe == nil {
+		return s
+	}
+	return l.queryParamsRe.ReplaceAllString(s, "${1}"+bodyMaskDefaultRedaction)
+}
+
+// body returns body with its redacted fields masked, or a placeholder if it isn't JSON.
+func (l *logRedactor) body(body []byte) []byte {
+	if l == nil || len(l.masks) == 0 || len(body) == 0 {
+		return body
+	}
+	masked, err := maskBody(body, l.masks, nil, nil)
+	if err != nil {
+		return []byte(logRedactedBody)
+	}
+	return masked
+}
+
+// request returns a redacted copy of r, to be dumped. The body of r is restored once read.
+func (l *logRedactor) request(r *http.Request) *http.Request {
+	if l == nil {
+		return r
+	}
+	redacted := r.Clone(r.Context())
+	redacted.Header = l.header(r.Header)
+	redacted.URL.RawQuery = l.query(r.URL.RawQuery)
+	if len(l.masks) > 0 && r.Body != nil {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		body = l.body(body)
+		redacted.Body = ioutil.NopCloser(bytes.NewReader(body))
+		redacted.ContentLength = int64(len(body))
+	}
+	return redacted
+}
+
+// response returns a redacted copy of res, to be dumped. The body of res is restored once read.
+func (l *logRedactor) response(res *http.Response) *http.Response {
+	if l == nil {
+		return res
+	}
+	redacted := *res
+	redacted.Header = l.header(res.Header)
+	if len(l.masks) > 0 && res.Body != nil {
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+		body = l.body(body)
+		redacted.Body = ioutil.NopCloser(bytes.NewReader(body))
+		redacted.ContentLength = int64(len(body))
+	}
+	return &redacted
+}
+
+// logRedactionHook redacts the log entries of an API before the other hooks and the formatter
+// see them.
+type logRedactionHook struct {
+	redactor *logRedactor
+}
+
+func (h *logRedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logRedactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactor.text(entry.Message)
+
+	// the fields are shared with the entry the log was written with
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			value = h.redactor.text(v)
+		case http.Header:
+			value = h.redactor.header(v)
+		case *url.URL:
+			if v != nil {
+				u := *v
+				u.RawQuery = h.redactor.query(v.RawQuery)
+				value = &u
+			}
+		case json.RawMessage:
+			value = json.RawMessage(h.redactor.body(v))
+		case []byte:
+			value = h.redactor.body(v)
+		}
+		data[key] = value
+	}
+	entry.Data = data
+	return nil
+}
+
+// globalLogRedaction redacts the logs written with the loggers of the gateway rather than those of
+// an API, e.g. by the helpers shared by the APIs, with the redaction of every loaded API.
+var globalLogRedaction = &sharedLogRedactionHook{}
+
+func init() {
+	log.AddHook(globalLogRedaction)
+	rawLog.AddHook(globalLogRedaction)
+}
+
+// sharedLogRedactionHook redacts the log entries with a redactor replaced as APIs are loaded.
+type sharedLogRedactionHook struct {
+	redactor atomic.Value // *logRedactor
+}
+
+func (h *sharedLogRedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *sharedLogRedactionHook) Fire(entry *logrus.Entry) error {
+	redactor, _ := h.redactor.Load().(*logRedactor)
+	if redactor == nil {
+		return nil
+	}
+	hook := logRedactionHook{redactor: redactor}
+	return hook.Fire(entry)
+}
+
+// setLogRedaction redacts the logs of the gateway with the redaction of every spec.
+func (h *sharedLogRedactionHook) setLogRedaction(specs map[string]*APISpec) {
+	var conf apidef.LoggingConfig
+	for _, spec := range specs {
+		if spec.LogRedactor == nil {
+			continue
+		}
+		conf.RedactHeaders = append(conf.RedactHeaders, spec.Logging.RedactHeaders...)
+		conf.RedactQueryParams = append(conf.RedactQueryParams, spec.Logging.RedactQueryParams...)
+		conf.RedactRules = append(conf.RedactRules, spec.Logging.RedactRules...)
+	}
+
+	redactor, err := newLogRedactor(conf)
+	if err != nil {
+		// the rules of every spec were validated once loaded
+		mainLog.WithError(err).Error("Couldn't redact the logs of the gateway")
+		return
+	}
+	h.redactor.Store(redactor)
+}
+
+// apiLogger returns the logger of spec, derived from base with the log level and the redaction of
+// the logging section of the API definition. It returns base when the API doesn't set them.
+func apiLogger(base *logrus.Entry, spec *APISpec) *logrus.Entry {
+	conf := spec.Logging
+	if conf.Level == "" && spec.LogRedactor == nil {
+		return base
+	}
+
+	level := base.Logger.GetLevel()
+	if conf.Level != "" {
+		if apiLevel, err := parseConfigLogLevel(conf.Level); err != nil {
+			base.WithError(err).Error("Invalid API log level, using the log level of the gateway")
+		} else {
+			level = apiLevel
+		}
+	}
+
+	logger := logrus.New()
+	logger.Out = base.Logger.Out
+	logger.Formatter = base.Logger.Formatter
+	logger.ReportCaller = base.Logger.ReportCaller
+	logger.ExitFunc = base.Logger.ExitFunc
+	logger.SetLevel(level)
+	// the hooks of the gateway, e.g. sending the logs to Sentry, fire after the redaction
+	if spec.LogRedactor != nil {
+		logger.AddHook(&logRedactionHook{redactor: spec.LogRedactor})
+	}
+	for lvl, hooks := range base.Logger.Hooks {
+		logger.Hooks[lvl] = append(logger.Hooks[lvl], hooks...)
+	}
+
+	return logrus.NewEntry(logger).WithFields(base.Data)
+}
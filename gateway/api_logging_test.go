@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestLogRedactor(t *testing.T) {
+	l, err := newLogRedactor(apidef.LoggingConfig{Level: "debug"})
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+	assert.Equal(t, "a=1", l.query("a=1"), "a nil redactor must redact nothing")
+
+	_, err = newLogRedactor(apidef.LoggingConfig{
+		RedactRules: []apidef.BodyMaskRule{{JSONPath: "$.card", Action: apidef.BodyMaskTokenize}},
+	})
+	assert.Error(t, err, "tokenizing isn't supported")
+
+	l, err = newLogRedactor(apidef.LoggingConfig{
+		RedactHeaders:     []string{"x-ssn"},
+		RedactQueryParams: []string{"email"},
+		RedactRules:       []apidef.BodyMaskRule{{JSONPath: "$.card", Action: apidef.BodyMaskRedact}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.Header{"X-Ssn": {"****"}, "Accept": {"*/*"}}, l.header(http.Header{"X-Ssn": {"123"}, "Accept": {"*/*"}}))
+	assert.Equal(t, "email=%2A%2A%2A%2A&page=1", l.query("email=a@b.c&page=1"))
+	assert.Equal(t, "Outbound request URL: http://upstream/get?page=1&email=**** done",
+		l.text("Outbound request URL: http://upstream/get?page=1&email=a@b.c done"))
+	assert.Equal(t, `{"card":"****"}`, string(l.body([]byte(`{"card":"4111111111"}`))))
+	assert.Equal(t, logRedactedBody, string(l.body([]byte("plain"))))
+
+	t.Run("requests", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/get?email=a@b.c", strings.NewReader(`{"card":"4111111111"}`))
+		r.Header.Set("X-Ssn", "123")
+
+		redacted := l.request(r)
+		assert.Equal(t, "****", redacted.Header.Get("X-Ssn"))
+		assert.Equal(t, "email=%2A%2A%2A%2A", redacted.URL.RawQuery)
+		body, _ := ioutil.ReadAll(redacted.Body)
+		assert.Equal(t, `{"card":"****"}`, string(body))
+
+		assert.Equal(t, "123", r.Header.Get("X-Ssn"), "the request must not be changed")
+		assert.Equal(t, "email=a@b.c", r.URL.RawQuery)
+		body, _ = ioutil.ReadAll(r.Body)
+		assert.Equal(t, `{"card":"4111111111"}`, string(body))
+	})
+}
+
+func TestAPILogger(t *testing.T) {
+	var out bytes.Buffer
+	base := logrus.New()
+	base.Out = &out
+	base.Formatter = &logrus.JSONFormatter{}
+	base.SetLevel(logrus.InfoLevel)
+	baseEntry := logrus.NewEntry(base).WithField("api_id", "test")
+
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{}}
+	assert.Equal(t, baseEntry, apiLogger(baseEntry, spec))
+
+	spec.Logging = apidef.LoggingConfig{Level: "debug", RedactHeaders: []string{"X-Ssn"}, RedactQueryParams: []string{"email"}}
+	var err error
+	spec.LogRedactor, err = newLogRedactor(spec.Logging)
+	require.NoError(t, err)
+
+	logger := apiLogger(baseEntry, spec)
+	headers := http.Header{"X-Ssn": {"123"}}
+	logger.WithFields(logrus.Fields{
+		"headers": headers,
+		"url":     &url.URL{Path: "/get", RawQuery: "email=a@b.c"},
+	}).Debug("Requested /get?email=a@b.c")
+
+	assert.Contains(t, out.String(), `"api_id":"test"`)
+	assert.Contains(t, out.String(), `"msg":"Requested /get?email=****"`)
+	assert.Contains(t, out.String(), `"X-Ssn":["****"]`)
+	assert.NotContains(t, out.String(), "a@b.c")
+	assert.Equal(t, "123", headers.Get("X-Ssn"), "the fields must not be changed")
+	assert.Equal(t, logrus.InfoLevel, base.GetLevel(), "the level of the gateway must not be changed")
+
+	out.Reset()
+	baseEntry.Debug("gateway debug log")
+	assert.Empty(t, out.String())
+}
+
+func TestSharedLogRedactionHook(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &out
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	hook := &sharedLogRedactionHook{}
+	logger.AddHook(hook)
+
+	logger.Info("Requested /get?email=a@b.c")
+	assert.Contains(t, out.String(), "a@b.c", "nothing must be redacted before APIs are loaded")
+
+	redacted := &APISpec{APIDefinition: &apidef.APIDefinition{
+		Logging: apidef.LoggingConfig{RedactHeaders: []string{"X-Ssn"}, RedactQueryParams: []string{"email"}},
+	}}
+	var err error
+	redacted.LogRedactor, err = newLogRedactor(redacted.Logging)
+	require.NoError(t, err)
+	hook.setLogRedaction(map[string]*APISpec{
+		"redacted": redacted,
+		"plain":    {APIDefinition: &apidef.APIDefinition{}},
+	})
+
+	out.Reset()
+	logger.WithField("headers", http.Header{"X-Ssn": {"123"}}).Info("Requested /get?email=a@b.c")
+	assert.Contains(t, out.String(), `"msg":"Requested /get?email=****"`)
+	assert.Contains(t, out.String(), `"X-Ssn":["****"]`)
+
+	hook.setLogRedaction(map[string]*APISpec{})
+	out.Reset()
+	logger.Info("Requested /get?email=a@b.c")
+	assert.Contains(t, out.String(), "a@b.c", "the redaction of unloaded APIs must be dropped")
+}
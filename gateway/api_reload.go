@@ -0,0 +1,278 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// loopingReferenceRE matches the name or ID of the API a looping URL targets.
+var loopingReferenceRE = regexp.MustCompile(`tyk://([^/"?#\s\\]+)`)
+
+// loopingReferences returns the names or IDs of the other APIs spec loops to.
+func loopingReferences(spec *APISpec) []string {
+	data, err := json.Marshal(spec.APIDefinition)
+	if err != nil {
+		return nil
+	}
+
+	var refs []string
+	for _, match := range loopingReferenceRE.FindAllStringSubmatch(string(data), -1) {
+		ref := match[1]
+		if ref != "self" && !contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// isLoopingTarget reports whether the looping reference ref targets spec.
+func (s *APISpec) isLoopingTarget(ref string) bool {
+	return s.APIID == ref || s.Id.Hex() == ref || strings.EqualFold(APILoopingName(s.Name), ref)
+}
+
+// checkLoopingReferences checks that the APIs of others looping to current, the loaded definition
+// of the reloaded API, still target its new definition spec, and that the APIs spec loops to are
+// loaded. current or spec are nil if the API isn't loaded or was removed.
+func checkLoopingReferences(current, spec *APISpec, others map[string]*APISpec) error {
+	if current != nil {
+		var dependents []string
+		for id, other := range others {
+			for _, ref := range loopingReferences(other) {
+				if current.isLoopingTarget(ref) && (spec == nil || !spec.isLoopingTarget(ref)) {
+					dependents = append(dependents, id)
+					break
+				}
+			}
+		}
+		if len(dependents) > 0 {
+			sort.Strings(dependents)
+			return fmt.Errorf("APIs %s loop to the API", strings.Join(dependents, ", "))
+		}
+	}
+
+	if spec != nil {
+		for _, ref := range loopingReferences(spec) {
+			if spec.isLoopingTarget(ref) {
+				continue
+			}
+			found := false
+			for _, other := range others {
+				if other.isLoopingTarget(ref) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("the API loops to %s which isn't loaded", ref)
+			}
+		}
+	}
+	return nil
+}
+
+var errReloadAPINotFound = errors.New("API not found")
+
+// reloadAPI reloads the definition of the API apiID from the source of the definitions, rebuilding
+// its middleware chain only. The other APIs keep theirs, and the other gateways aren't notified.
+// The API is unloaded if it was removed from the source.
+func (gw *Gateway) reloadAPI(apiID string) (int, error) {
+	gw.reloadMu.Lock()
+	defer gw.reloadMu.Unlock()
+
+	specs, err := gw.fetchAPISpecs()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	var spec *APISpec
+	for _, s := range specs {
+		if s.APIID == apiID {
+			spec = s
+			break
+		}
+	}
+
+	gw.apisMu.RLock()
+	current := gw.apisByID[apiID]
+	loaded := make(map[string]*APISpec, len(gw.apisByID))
+	for id, s := range gw.apisByID {
+		if id != apiID {
+			loaded[id] = s
+		}
+	}
+	gw.apisMu.RUnlock()
+
+	if spec == nil && current == nil {
+		return http.StatusNotFound, errReloadAPINotFound
+	}
+	if err := checkLoopingReferences(current, spec, loaded); err != nil {
+		return http.StatusConflict, err
+	}
+
+	ids := make([]string, 0, len(loaded))
+	for id := range loaded {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	reloaded := make([]*APISpec, 0, len(loaded)+1)
+	for _, id := range ids {
+		reloaded = append(reloaded, loaded[id])
+	}
+	if spec != nil {
+		reloaded = append(reloaded, spec)
+	}
+
+	gw.loadAppsReusing(reloaded, loaded)
+
+	// the next full reload starts from the reloaded definitions
+	gw.apisMu.Lock()
+	apiSpecs := make([]*APISpec, 0, len(gw.apiSpecs)+1)
+	for _, s := range gw.apiSpecs {
+		if s.APIID != apiID {
+			apiSpecs = append(apiSpecs, s)
+		}
+	}
+	if spec != nil {
+		apiSpecs = append(apiSpecs, spec)
+	}
+	gw.apiSpecs = apiSpecs
+	tlsConfigCache.Flush()
+	gw.apisMu.Unlock()
+
+	return http.StatusOK, nil
+}
+
+// checksumAPISpecs sets the checksums of the fetched specs, over their definitions and the gateway
+// configuration they are built with, and their listen hashes.
+func (gw *Gateway) checksumAPISpecs(specs []*APISpec) {
+	conf := gw.GetConfig()
+	confData, err := json.Marshal(&conf)
+	if err != nil {
+		mainLog.WithError(err).Warning("Couldn't checksum the configuration, rebuilding all APIs")
+		return
+	}
+	confSum := sha256.Sum256(confData)
+
+	for _, spec := range specs {
+		spec.listenHash = generateDomainPath(spec.Domain, spec.Proxy.ListenPath)
+
+		def, err := json.Marshal(spec.APIDefinition)
+		if err != nil {
+			continue
+		}
+		oas, err := json.Marshal(&spec.OAS)
+		if err != nil {
+			continue
+		}
+		h := sha256.New()
+		h.Write(confSum[:])
+		h.Write(def)
+		h.Write(oas)
+		spec.checksum = hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// unchangedAPISpecs returns the loaded specs of the APIs of specs which didn't change, by API ID.
+// APIs sharing a listen path are always rebuilt, as their order of mounting matters.
+func (gw *Gateway) unchangedAPISpecs(specs []*APISpec) map[string]*APISpec {
+	gw.apisMu.RLock()
+	loaded := make(map[string]*APISpec, len(gw.apisByID))
+	for id, spec := range gw.apisByID {
+		loaded[id] = spec
+	}
+	gw.apisMu.RUnlock()
+
+	ids := make(map[string]int, len(specs))
+	listens := make(map[string]int, len(specs))
+	for _, spec := range specs {
+		ids[spec.APIID]++
+		listens[spec.listenHash]++
+	}
+	loadedListens := make(map[string]int, len(loaded))
+	for _, spec := range loaded {
+		loadedListens[spec.listenHash]++
+	}
+
+	unchanged := make(map[string]*APISpec)
+	for _, spec := range specs {
+		current := loaded[spec.APIID]
+		if current == nil || spec.checksum == "" || spec.checksum != current.checksum {
+			continue
+		}
+		if ids[spec.APIID] > 1 || listens[spec.listenHash] > 1 || loadedListens[current.listenHash] > 1 {
+			continue
+		}
+		if gw.apiSecretsChanged(current) {
+			continue
+		}
+		unchanged[spec.APIID] = current
+	}
+	return unchanged
+}
+
+// loadAppsIncrementally loads specs rebuilding only the added and changed APIs, the unchanged ones
+// keep their loaded spec and middleware chain.
+func (gw *Gateway) loadAppsIncrementally(specs []*APISpec) {
+	unchanged := gw.unchangedAPISpecs(specs)
+	ids := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		ids[spec.APIID] = true
+		if current, ok := unchanged[spec.APIID]; ok {
+			specs[i] = current
+		}
+	}
+
+	gw.apisMu.RLock()
+	removed := 0
+	for id := range gw.apisByID {
+		if !ids[id] {
+			removed++
+		}
+	}
+	gw.apisMu.RUnlock()
+
+	mainLog.WithFields(logrus.Fields{
+		"rebuilt":   len(specs) - len(unchanged),
+		"unchanged": len(unchanged),
+		"removed":   removed,
+	}).Info("Reloading APIs incrementally.")
+
+	gw.loadAppsReusing(specs, unchanged)
+
+	// the next reload compares with the loaded specs
+	apiSpecs := make([]*APISpec, len(specs))
+	copy(apiSpecs, specs)
+	gw.apisMu.Lock()
+	gw.apiSpecs = apiSpecs
+	gw.apisMu.Unlock()
+}
+
+func (gw *Gateway) reloadAPIHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	code, err := gw.reloadAPI(apiID)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "api",
+			"api_id": apiID,
+			"err":    err,
+		}).Error("API reload failed.")
+		doJSONWrite(w, code, apiError(err.Error()))
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+		"api_id": apiID,
+	}).Info("API reloaded.")
+	doJSONWrite(w, http.StatusOK, apiOk(""))
+}
@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestReloadAPI(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "reloaded"
+		spec.Proxy.ListenPath = "/reloaded/"
+	}, func(spec *APISpec) {
+		spec.APIID = "target"
+		spec.Proxy.ListenPath = "/target/"
+	}, func(spec *APISpec) {
+		spec.APIID = "looping"
+		spec.Proxy.ListenPath = "/looping/"
+		spec.Proxy.TargetURL = "tyk://target"
+	})
+
+	target := ts.Gw.getApiSpec("target")
+	targetHandle, _ := ts.Gw.apisHandlesByID.Load("target")
+
+	// the definitions source only has the definitions written here
+	writeAPI := func(gens ...func(spec *APISpec)) {
+		spec := BuildAPI(gens...)[0]
+		data, err := json.Marshal(spec.APIDefinition)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(ts.Gw.GetConfig().AppPath, spec.APIID+".json"), data, 0644))
+	}
+	reload := func(apiID string, code int) {
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/reload/api/" + apiID, AdminAuth: true, Code: code})
+	}
+
+	t.Run("changed API", func(t *testing.T) {
+		writeAPI(func(spec *APISpec) {
+			spec.APIID = "reloaded"
+			spec.Proxy.ListenPath = "/changed/"
+		})
+		reload("reloaded", http.StatusOK)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/changed/", Code: http.StatusOK},
+			{Path: "/reloaded/", Code: http.StatusNotFound},
+			{Path: "/target/", Code: http.StatusOK},
+			{Path: "/looping/", Code: http.StatusOK},
+		}...)
+
+		// the other APIs aren't rebuilt
+		assert.True(t, target == ts.Gw.getApiSpec("target"))
+		handle, _ := ts.Gw.apisHandlesByID.Load("target")
+		assert.Equal(t, reflect.ValueOf(targetHandle).Pointer(), reflect.ValueOf(handle).Pointer())
+	})
+
+	t.Run("looping reference", func(t *testing.T) {
+		reload("target", http.StatusConflict)
+		_, _ = ts.Run(t, test.TestCase{Path: "/target/", Code: http.StatusOK})
+
+		writeAPI(func(spec *APISpec) {
+			spec.APIID = "looping"
+			spec.Proxy.ListenPath = "/looping/"
+			spec.Proxy.TargetURL = "tyk://unknown"
+		})
+		reload("looping", http.StatusConflict)
+	})
+
+	t.Run("removed API", func(t *testing.T) {
+		writeAPI(func(spec *APISpec) {
+			spec.APIID = "looping"
+			spec.Proxy.ListenPath = "/looping/"
+		})
+		reload("looping", http.StatusOK)
+		reload("target", http.StatusOK)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/target/", Code: http.StatusNotFound},
+			{Path: "/changed/", Code: http.StatusOK},
+			{Path: "/looping/", Code: http.StatusOK},
+		}...)
+		assert.Nil(t, ts.Gw.getApiSpec("target"))
+	})
+
+	t.Run("unknown API", func(t *testing.T) {
+		reload("unknown", http.StatusNotFound)
+	})
+}
+
+func TestIncrementalReload(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnableIncrementalReload = true
+	})
+	defer ts.Close()
+
+	os.Setenv("TYK_SECRET_INCREMENTAL", "secret")
+	defer os.Unsetenv("TYK_SECRET_INCREMENTAL")
+
+	writeAPI := func(gens ...func(spec *APISpec)) {
+		spec := BuildAPI(func(spec *APISpec) {
+			for _, gen := range gens {
+				gen(spec)
+			}
+		})[0]
+		data, err := json.Marshal(spec.APIDefinition)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(ts.Gw.GetConfig().AppPath, spec.APIID+".json"), data, 0644))
+	}
+	api := func(apiID, listenPath string) func(spec *APISpec) {
+		return func(spec *APISpec) {
+			spec.APIID = apiID
+			spec.Proxy.ListenPath = listenPath
+		}
+	}
+	handle := func(apiID string) uintptr {
+		h, ok := ts.Gw.apisHandlesByID.Load(apiID)
+		require.True(t, ok)
+		return reflect.ValueOf(h).Pointer()
+	}
+
+	writeAPI(api("unchanged", "/unchanged/"))
+	writeAPI(api("changed", "/changed/"))
+	writeAPI(api("secret", "/secret/"), func(spec *APISpec) {
+		spec.RequestSigning.Secret = "env://incremental"
+	})
+	writeAPI(api("removed", "/removed/"))
+	ts.Gw.DoReload()
+
+	unchanged, unchangedHandle := ts.Gw.getApiSpec("unchanged"), handle("unchanged")
+	changed, secret := ts.Gw.getApiSpec("changed"), ts.Gw.getApiSpec("secret")
+	require.NotNil(t, unchanged)
+	assert.NotEmpty(t, unchanged.checksum)
+
+	t.Run("only changed APIs rebuilt", func(t *testing.T) {
+		writeAPI(api("changed", "/changed/"), func(spec *APISpec) {
+			spec.Name = "changed"
+		})
+		require.NoError(t, os.Remove(filepath.Join(ts.Gw.GetConfig().AppPath, "removed.json")))
+		writeAPI(api("added", "/added/"))
+		ts.Gw.DoReload()
+
+		assert.True(t, unchanged == ts.Gw.getApiSpec("unchanged"))
+		assert.Equal(t, unchangedHandle, handle("unchanged"))
+		assert.True(t, secret == ts.Gw.getApiSpec("secret"))
+		assert.False(t, changed == ts.Gw.getApiSpec("changed"))
+		assert.Equal(t, "changed", ts.Gw.getApiSpec("changed").Name)
+		assert.Nil(t, ts.Gw.getApiSpec("removed"))
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/unchanged/", Code: http.StatusOK},
+			{Path: "/changed/", Code: http.StatusOK},
+			{Path: "/added/", Code: http.StatusOK},
+			{Path: "/removed/", Code: http.StatusNotFound},
+		}...)
+	})
+
+	t.Run("changed secret", func(t *testing.T) {
+		os.Setenv("TYK_SECRET_INCREMENTAL", "renewed")
+		ts.Gw.secretsCache.Flush()
+		ts.Gw.DoReload()
+
+		assert.True(t, unchanged == ts.Gw.getApiSpec("unchanged"))
+		assert.False(t, secret == ts.Gw.getApiSpec("secret"))
+		assert.Equal(t, "renewed", ts.Gw.getApiSpec("secret").RequestSigning.Secret)
+	})
+
+	t.Run("shared listen path", func(t *testing.T) {
+		writeAPI(api("shared", "/unchanged/"))
+		ts.Gw.DoReload()
+		shared := ts.Gw.getApiSpec("unchanged")
+		assert.False(t, unchanged == shared)
+
+		ts.Gw.DoReload()
+		assert.False(t, shared == ts.Gw.getApiSpec("unchanged"))
+	})
+
+	t.Run("changed configuration", func(t *testing.T) {
+		added := ts.Gw.getApiSpec("added")
+		conf := ts.Gw.GetConfig()
+		conf.HideGeneratorHeader = !conf.HideGeneratorHeader
+		ts.Gw.SetConfig(conf)
+		ts.Gw.DoReload()
+
+		assert.False(t, added == ts.Gw.getApiSpec("added"))
+	})
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const defaultSecretsCacheTTL = 300
+
+// secretReferenceSchemes are the prefixes of the values of API definitions resolved from a secret
+// store, e.g. `vault://kv/apis/foo#secret`.
+var secretReferenceSchemes = []string{"vault://", "aws://", "consul://", "env://", "secrets://"}
+
+// apiSecret is a field of an API definition resolved from a secret reference.
+type apiSecret struct {
+	field     string
+	reference string
+	value     string
+}
+
+func isSecretReference(value string) bool {
+	for _, scheme := range secretReferenceSchemes {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachAPISecret calls fn with the fields of def which may hold secrets, named after their JSON
+// path. Values changed by fn are written back to def.
+func forEachAPISecret(def *apidef.APIDefinition, fn func(field string, value *string)) {
+	fn("jwt_source", &def.JWTSource)
+	fn("auth.signature.secret", &def.Auth.Signature.Secret)
+	for name, conf := range def.AuthConfigs {
+		secret := conf.Signature.Secret
+		fn("auth_configs."+name+".signature.secret", &conf.Signature.Secret)
+		if conf.Signature.Secret != secret {
+			def.AuthConfigs[name] = conf
+		}
+	}
+	fn("request_signing.secret", &def.RequestSigning.Secret)
+	fn("broker.upstream_password", &def.Broker.UpstreamPassword)
+}
+
+// resolveAPISecrets replaces the secret references of spec with their values. The API must not be
+// loaded if it fails, as its references would otherwise be used as secrets.
+func (gw *Gateway) resolveAPISecrets(spec *APISpec) error {
+	var err error
+	forEachAPISecret(spec.APIDefinition, func(field string, value *string) {
+		if err != nil || !isSecretReference(*value) {
+			return
+		}
+
+		resolved, resolveErr := gw.resolveSecretReference(*value)
+		if resolveErr != nil {
+			err = fmt.Errorf("could not resolve %s: %v", field, resolveErr)
+			return
+		}
+		spec.secrets = append(spec.secrets, apiSecret{field: field, reference: *value, value: resolved})
+		*value = resolved
+	})
+	return err
+}
+
+// resolveSecretReference returns the value of ref, cached for secrets_cache_ttl.
+func (gw *Gateway) resolveSecretReference(ref string) (string, error) {
+	if cached, ok := gw.secretsCache.Get(ref); ok {
+		return cached.(string), nil
+	}
+
+	value, err := gw.fetchSecretReference(ref)
+	if err != nil {
+		return "", err
+	}
+	gw.secretsCache.Set(ref, value, gw.secretsCacheTTL())
+	return value, nil
+}
+
+// fetchSecretReference fetches the value of ref from its store. Unlike kvStore, it fails when the
+// store isn't available or the secret doesn't exist.
+func (gw *Gateway) fetchSecretReference(ref string) (string, error) {
+	if strings.HasPrefix(ref, "env://") {
+		name := fmt.Sprintf("TYK_SECRET_%s", strings.ToUpper(strings.TrimPrefix(ref, "env://")))
+		if value, ok := os.LookupEnv(name); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	value, err := gw.kvStore(ref)
+	if err != nil {
+		return "", err
+	}
+	if value == ref {
+		return "", errors.New("secret store is not available")
+	}
+	return value, nil
+}
+
+func (gw *Gateway) secretsCacheTTL() time.Duration {
+	ttl := gw.GetConfig().SecretsCacheTTL
+	if ttl <= 0 {
+		ttl = defaultSecretsCacheTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// secretsRenewalLoop renews the secrets of the loaded APIs every secrets_cache_ttl.
+func (gw *Gateway) secretsRenewalLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(gw.secretsCacheTTL()):
+			gw.renewAPISecrets()
+		}
+	}
+}
+
+// renewAPISecrets fetches again the secrets of the loaded APIs, and reloads them if one changed.
+// Secrets which can't be fetched keep their current value.
+func (gw *Gateway) renewAPISecrets() {
+	gw.apisMu.RLock()
+	specs := make([]*APISpec, len(gw.apiSpecs))
+	copy(specs, gw.apiSpecs)
+	gw.apisMu.RUnlock()
+
+	fetched := make(map[string]string)
+	changed := false
+	for _, spec := range specs {
+		for _, secret := range spec.secrets {
+			value, ok := fetched[secret.reference]
+			if !ok {
+				var err error
+				value, err = gw.fetchSecretReference(secret.reference)
+				if err != nil {
+					log.WithError(err).WithField("api_id", spec.APIID).
+						Warningf("Could not renew the secret of %s, keeping its current value", secret.field)
+					continue
+				}
+				fetched[secret.reference] = value
+				gw.secretsCache.Set(secret.reference, value, gw.secretsCacheTTL())
+			}
+			if value != secret.value {
+				changed = true
+			}
+		}
+	}
+
+	if changed {
+		log.Info("Secrets of APIs changed, reloading")
+		gw.reloadURLStructure(nil)
+	}
+}
+
+// apiSecretsChanged reports whether a secret of the loaded spec has a new value, or can't be
+// resolved anymore.
+func (gw *Gateway) apiSecretsChanged(spec *APISpec) bool {
+	for _, secret := range spec.secrets {
+		value, err := gw.resolveSecretReference(secret.reference)
+		if err != nil || value != secret.value {
+			return true
+		}
+	}
+	return false
+}
+
+// withSecretReferences returns a copy of the definition of spec with its secret references and
+// template placeholders in place of their values, to not expose them.
+func (spec *APISpec) withSecretReferences() *apidef.APIDefinition {
+	if spec.template != nil {
+		// the template was kept before the secrets were resolved
+		def := &apidef.APIDefinition{}
+		if err := json.Unmarshal(spec.template, def); err == nil {
+			return def
+		}
+	}
+	if len(spec.secrets) == 0 {
+		return spec.APIDefinition
+	}
+
+	def := *spec.APIDefinition
+	def.AuthConfigs = make(map[string]apidef.AuthConfig, len(spec.AuthConfigs))
+	for name, conf := range spec.AuthConfigs {
+		def.AuthConfigs[name] = conf
+	}
+
+	references := make(map[string]string, len(spec.secrets))
+	for _, secret := range spec.secrets {
+		references[secret.field] = secret.reference
+	}
+	forEachAPISecret(&def, func(field string, value *string) {
+		if ref, ok := references[field]; ok {
+			*value = ref
+		}
+	})
+	return &def
+}
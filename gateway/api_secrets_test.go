@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestAPISecretReferences(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Secrets = map[string]string{"signature": "signature-secret"}
+	})
+	defer ts.Close()
+
+	os.Setenv("TYK_SECRET_JWT_SOURCE", "jwt-secret")
+	defer os.Unsetenv("TYK_SECRET_JWT_SOURCE")
+
+	specs := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "secrets"
+		spec.Proxy.ListenPath = "/secrets/"
+		spec.JWTSource = "env://jwt_source"
+		spec.Auth.Signature.Secret = "secrets://signature"
+		spec.AuthConfigs = map[string]apidef.AuthConfig{"hmac": {Signature: apidef.SignatureConfig{Secret: "secrets://signature"}}}
+		spec.RequestSigning.Secret = "plain"
+	})
+	spec := specs[0]
+
+	t.Run("resolved at load time", func(t *testing.T) {
+		assert.Equal(t, "jwt-secret", spec.JWTSource)
+		assert.Equal(t, "signature-secret", spec.Auth.Signature.Secret)
+		assert.Equal(t, "signature-secret", spec.AuthConfigs["hmac"].Signature.Secret)
+		assert.Equal(t, "plain", spec.RequestSigning.Secret)
+	})
+
+	t.Run("references returned by the API", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Path:      "/tyk/apis/secrets",
+			AdminAuth: true,
+			Code:      http.StatusOK,
+			BodyMatchFunc: func(body []byte) bool {
+				return !strings.Contains(string(body), "signature-secret") && !strings.Contains(string(body), "jwt-secret") &&
+					strings.Contains(string(body), `"jwt_source":"env://jwt_source"`)
+			},
+		})
+
+		assert.Equal(t, "signature-secret", spec.AuthConfigs["hmac"].Signature.Secret)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		conf := ts.Gw.GetConfig()
+		conf.Secrets = map[string]string{"signature": "changed"}
+		ts.Gw.SetConfig(conf)
+
+		value, err := ts.Gw.resolveSecretReference("secrets://signature")
+		require.NoError(t, err)
+		assert.Equal(t, "signature-secret", value)
+	})
+
+	t.Run("renewed", func(t *testing.T) {
+		ts.Gw.ReloadTestCase.Enable()
+		defer ts.Gw.ReloadTestCase.Disable()
+
+		ts.Gw.renewAPISecrets()
+		ts.Gw.ReloadTestCase.EnsureQueued(t)
+
+		value, err := ts.Gw.resolveSecretReference("secrets://signature")
+		require.NoError(t, err)
+		assert.Equal(t, "changed", value)
+	})
+
+	t.Run("skipped when unresolved", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "unresolved"
+			spec.Proxy.ListenPath = "/unresolved/"
+			spec.UseKeylessAccess = false
+			spec.Auth.Signature.Secret = "env://missing"
+		})
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/unresolved/", Code: http.StatusNotFound})
+	})
+}
+
+func TestFetchSecretReference(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	_, err := ts.Gw.fetchSecretReference("env://missing")
+	assert.Error(t, err)
+
+	_, err = ts.Gw.fetchSecretReference("secrets://missing")
+	assert.Error(t, err)
+
+	// stores which can't be set up don't resolve references
+	_, err = ts.Gw.fetchSecretReference("aws://apis/foo#secret")
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// apiTemplateEnvPrefix is the prefix of the environment variables API definitions can use. Other
+// environment variables, such as the secrets of the gateway, aren't exposed to them.
+const apiTemplateEnvPrefix = "TYK_API_VAR_"
+
+// apiTemplateVariable matches `${NAME}`, `${NAME:-default}` and their escaped form `$${NAME}`.
+var apiTemplateVariable = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// resolveAPITemplate replaces the placeholders of def with their variables when templating is
+// enabled, keeping the definition with its placeholders in spec.
+func (gw *Gateway) resolveAPITemplate(spec *APISpec, def *apidef.APIDefinition) error {
+	if !gw.GetConfig().APIDefinitionTemplating.Enabled {
+		return nil
+	}
+
+	template, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(template, []byte("${")) {
+		return nil
+	}
+	spec.template = template
+
+	return resolveTemplateValue(reflect.ValueOf(def).Elem(), "", gw.expandTemplateVariables)
+}
+
+// resolveTemplateValue calls expand with the strings of v, named after their JSON path, and sets
+// them to the result.
+func resolveTemplateValue(v reflect.Value, path string, expand func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() || !strings.Contains(v.String(), "${") {
+			return nil
+		}
+		value, err := expand(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %v", strings.TrimPrefix(path, "."), err)
+		}
+		v.SetString(value)
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return resolveTemplateValue(v.Elem(), path, expand)
+		}
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return nil
+		}
+		// the value of an interface can't be set, it's replaced by a copy
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := resolveTemplateValue(elem, path, expand); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			fieldPath := path + "." + name
+			if field.Anonymous {
+				fieldPath = path
+			}
+			if err := resolveTemplateValue(v.Field(i), fieldPath, expand); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveTemplateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", expand); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// the values of a map can't be set, they're replaced by copies
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := resolveTemplateValue(elem, fmt.Sprintf("%s.%v", path, key), expand); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
+
+// expandTemplateVariables replaces the placeholders of s, failing when a variable isn't one of the
+// configured variables nor an environment variable with apiTemplateEnvPrefix, or when a variable
+// without default isn't set.
+func (gw *Gateway) expandTemplateVariables(s string) (string, error) {
+	variables := gw.GetConfig().APIDefinitionTemplating.Variables
+
+	var err error
+	expanded := apiTemplateVariable.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if strings.HasPrefix(placeholder, "$$") {
+			return placeholder[1:]
+		}
+		match := apiTemplateVariable.FindStringSubmatch(placeholder)
+		name, def := match[1], match[2]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		if !strings.HasPrefix(name, apiTemplateEnvPrefix) {
+			if err == nil {
+				err = fmt.Errorf("variable %s is not allowed, environment variables must start with %s", name, apiTemplateEnvPrefix)
+			}
+			return placeholder
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if def != "" {
+			return strings.TrimPrefix(def, ":-")
+		}
+		if err == nil {
+			err = fmt.Errorf("variable %s is not set", name)
+		}
+		return placeholder
+	})
+	return expanded, err
+}
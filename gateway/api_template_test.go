@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestAPIDefinitionTemplating(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.APIDefinitionTemplating.Enabled = true
+		globalConf.APIDefinitionTemplating.Variables = map[string]string{"UPSTREAM": TestHttpAny}
+	})
+	defer ts.Close()
+
+	os.Setenv("TYK_API_VAR_TEST_ENV", "staging")
+	defer os.Unsetenv("TYK_API_VAR_TEST_ENV")
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "templated"
+		spec.Proxy.ListenPath = "/${TYK_API_VAR_TEST_ENV}/"
+		spec.Proxy.TargetURL = "${UPSTREAM}"
+		spec.GlobalRateLimit.Rate = 0
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.GlobalHeaders = map[string]string{
+				"X-Region":  "${TYK_API_VAR_TEST_REGION:-eu}",
+				"X-Literal": "$${UPSTREAM}",
+			}
+		})
+	}, func(spec *APISpec) {
+		spec.APIID = "unresolved"
+		spec.Proxy.ListenPath = "/unresolved/"
+		spec.Proxy.TargetURL = "${TYK_API_VAR_TEST_MISSING}"
+	}, func(spec *APISpec) {
+		spec.APIID = "not-allowed"
+		spec.Proxy.ListenPath = "/not-allowed/"
+		spec.Proxy.TargetURL = "${PATH:-" + TestHttpAny + "}"
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/staging/", Code: http.StatusOK, BodyMatch: `"X-Region":"eu"`},
+		{Path: "/staging/", Code: http.StatusOK, BodyMatch: `"X-Literal":"\${UPSTREAM}"`},
+		{Path: "/unresolved/", Code: http.StatusNotFound},
+		{Path: "/not-allowed/", Code: http.StatusNotFound},
+	}...)
+
+	t.Run("placeholders returned by the API", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/apis/templated", AdminAuth: true, Code: http.StatusOK})
+		var def apidef.APIDefinition
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&def))
+		assert.Equal(t, "/${TYK_API_VAR_TEST_ENV}/", def.Proxy.ListenPath)
+		assert.Equal(t, "${UPSTREAM}", def.Proxy.TargetURL)
+
+		spec := ts.Gw.getApiSpec("templated")
+		assert.Equal(t, TestHttpAny, spec.Proxy.TargetURL)
+	})
+}
+
+func TestResolveTemplateValue(t *testing.T) {
+	expand := func(s string) (string, error) {
+		return "resolved", nil
+	}
+
+	def := apidef.APIDefinition{
+		Proxy:      apidef.ProxyConfig{TargetURL: "${A}"},
+		Tags:       []string{"plain", "${B}"},
+		ConfigData: map[string]interface{}{"nested": map[string]interface{}{"key": "${C}"}, "number": 1},
+	}
+	require.NoError(t, resolveTemplateValue(reflect.ValueOf(&def).Elem(), "", expand))
+	assert.Equal(t, "resolved", def.Proxy.TargetURL)
+	assert.Equal(t, []string{"plain", "resolved"}, def.Tags)
+	assert.Equal(t, map[string]interface{}{"nested": map[string]interface{}{"key": "resolved"}, "number": 1}, def.ConfigData)
+
+	gw := &Gateway{}
+	gw.SetConfig(config.Config{})
+	def.Proxy.TargetURL = "http://${TYK_API_VAR_TEST_MISSING}"
+	err := resolveTemplateValue(reflect.ValueOf(&def).Elem(), "", gw.expandTemplateVariables)
+	assert.EqualError(t, err, "proxy.target_url: variable TYK_API_VAR_TEST_MISSING is not set")
+
+	os.Setenv("TYK_TEST_TEMPLATE_SECRET", "secret")
+	defer os.Unsetenv("TYK_TEST_TEMPLATE_SECRET")
+	def.Proxy.TargetURL = "http://${TYK_TEST_TEMPLATE_SECRET}"
+	err = resolveTemplateValue(reflect.ValueOf(&def).Elem(), "", gw.expandTemplateVariables)
+	assert.EqualError(t, err, "proxy.target_url: variable TYK_TEST_TEMPLATE_SECRET is not allowed, environment variables must start with TYK_API_VAR_")
+}
@@ -127,6 +127,70 @@ func TestPolicyAPI(t *testing.T) {
 	})
 }
 
+func TestPolicyAPIManagement(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "tyk-policies-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("policy path not set", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Path: "/tyk/policies/default-test", AdminAuth: true, Method: http.MethodPost,
+			Data: defaultTestPol, BodyMatch: "policy_path is set", Code: http.StatusInternalServerError})
+	})
+
+	globalConf := ts.Gw.GetConfig()
+	globalConf.Policies.PolicyPath = dir
+	globalConf.Policies.PolicySource = "file"
+	ts.Gw.SetConfig(globalConf)
+
+	t.Run("invalid ID", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/tyk/policies", AdminAuth: true, Method: http.MethodPost,
+				Data: `{"id": "../escaped"}`, BodyMatch: "Invalid policy ID", Code: http.StatusBadRequest},
+			{Path: "/tyk/policies/bad:id", AdminAuth: true, Method: http.MethodDelete, Code: http.StatusBadRequest},
+		}...)
+		_, err := os.Stat(filepath.Join(dir, "..", "escaped.json"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("ID from the path or generated", func(t *testing.T) {
+		ts.Gw.ReloadTestCase.Enable()
+		defer ts.Gw.ReloadTestCase.Disable()
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/tyk/policies/from-path", AdminAuth: true, Method: http.MethodPost,
+			Data: `{"rate": 10, "per": 1}`, BodyMatch: `"key":"from-path"`, Code: http.StatusOK})
+		ts.Gw.ReloadTestCase.EnsureQueued(t)
+
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/policies", AdminAuth: true, Method: http.MethodPost,
+			Data: `{"rate": 10, "per": 1}`, Code: http.StatusOK})
+		var created apiModifyKeySuccess
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		assert.NotEmpty(t, created.Key)
+
+		ts.Gw.DoReload()
+		assert.Equal(t, "from-path", ts.Gw.getPolicy("from-path").ID)
+		assert.Equal(t, created.Key, ts.Gw.getPolicy(created.Key).ID)
+	})
+
+	t.Run("delete missing policy", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Path: "/tyk/policies/missing", AdminAuth: true, Method: http.MethodDelete,
+			BodyMatch: "Policy not found", Code: http.StatusNotFound})
+	})
+
+	t.Run("read only source", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.Policies.PolicySource = "rpc"
+		ts.Gw.SetConfig(globalConf)
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/tyk/policies/from-path", AdminAuth: true, Method: http.MethodDelete,
+			Code: http.StatusInternalServerError})
+		_, err := os.Stat(filepath.Join(dir, "from-path.json"))
+		assert.NoError(t, err)
+	})
+}
+
 func TestHealthCheckEndpoint(t *testing.T) {
 	ts := StartTest(nil)
 	defer ts.Close()
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+var appGitLog = log.WithField("prefix", "app-git")
+
+// appGitCheckoutDir returns the directory the API definitions repository is cloned into, creating
+// a private temporary directory when none is configured. It must be called with appGitMu held.
+func (gw *Gateway) appGitCheckoutDir() (string, error) {
+	if dir := gw.GetConfig().AppGit.CheckoutDir; dir != "" {
+		return dir, nil
+	}
+	if gw.appGitDir == "" {
+		dir, err := ioutil.TempDir("", "tyk-app-git-")
+		if err != nil {
+			return "", err
+		}
+		gw.appGitDir = dir
+	}
+	return gw.appGitDir, nil
+}
+
+// checkAppGitCheckoutDir refuses to clone into dir when it has files but isn't a checkout, so
+// that they aren't lost.
+func checkAppGitCheckoutDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		return fmt.Errorf("%s isn't empty and isn't a git checkout", dir)
+	}
+	return nil
+}
+
+// appGitDefinitionsDir returns the directory of the API definitions in the checkout of the
+// repository, which is cloned if it wasn't yet. A checkout left by a previous run is used when the
+// repository can't be reached.
+func (gw *Gateway) appGitDefinitionsDir() (string, error) {
+	conf := gw.GetConfig().AppGit
+
+	gw.appGitMu.Lock()
+	synced := gw.appGitRevision != ""
+	checkoutDir, err := gw.appGitCheckoutDir()
+	gw.appGitMu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(checkoutDir, filepath.FromSlash(conf.Path))
+	if synced {
+		return dir, nil
+	}
+
+	if _, err := gw.syncAppGit(); err != nil {
+		if _, statErr := os.Stat(filepath.Join(checkoutDir, ".git")); statErr != nil {
+			return "", err
+		}
+		appGitLog.WithError(err).Warning("Couldn't sync the API definitions repository, loading the previous checkout")
+	}
+	return dir, nil
+}
+
+// syncAppGit clones the API definitions repository, or fetches and checks out its branch, and
+// reports whether the checked out revision changed.
+func (gw *Gateway) syncAppGit() (bool, error) {
+	gw.appGitMu.Lock()
+	defer gw.appGitMu.Unlock()
+
+	conf := gw.GetConfig().AppGit
+	dir, err := gw.appGitCheckoutDir()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := checkAppGitCheckoutDir(dir); err != nil {
+			return false, err
+		}
+		args := []string{"clone", "--depth", "1"}
+		if conf.Branch != "" {
+			args = append(args, "--branch", conf.Branch)
+		}
+		if _, err := runAppGit(conf, "", append(args, "--", conf.URL, dir)...); err != nil {
+			return false, err
+		}
+	} else {
+		ref := conf.Branch
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := runAppGit(conf, dir, "remote", "set-url", "origin", conf.URL); err != nil {
+			return false, err
+		}
+		if _, err := runAppGit(conf, dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return false, err
+		}
+		if _, err := runAppGit(conf, dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return false, err
+		}
+	}
+
+	revision, err := runAppGit(conf, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	changed := revision != gw.appGitRevision
+	if changed {
+		appGitLog.WithField("revision", revision).Info("Checked out the API definitions repository")
+	}
+	gw.appGitRevision = revision
+	return changed, nil
+}
+
+// runAppGit runs git in dir and returns its trimmed output. The token is passed in the environment
+// rather than the arguments or the remote URL, for it not to show in the process list or the
+// checkout.
+func runAppGit(conf config.AppGitConfig, dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if conf.SSHKeyPath != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %q -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new", conf.SSHKeyPath))
+	}
+	if conf.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + conf.Token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// appGitLoop polls the API definitions repository, and reloads the APIs when it changed.
+func (gw *Gateway) appGitLoop(ctx context.Context) {
+	conf := gw.GetConfig().AppGit
+	if !conf.Enabled || conf.PollInterval <= 0 {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(conf.PollInterval) * time.Second):
+			gw.appGitSyncAndReload()
+		}
+	}
+}
+
+func (gw *Gateway) appGitSyncAndReload() error {
+	changed, err := gw.syncAppGit()
+	if err != nil {
+		appGitLog.WithError(err).Error("Couldn't sync the API definitions repository")
+		return err
+	}
+	if changed {
+		gw.reloadURLStructure(nil)
+	}
+	return nil
+}
+
+// validAppGitWebhook checks the GitHub signature or the GitLab token of a webhook request.
+func validAppGitWebhook(r *http.Request, body []byte, secret string) bool {
+	if token := r.Header.Get("X-Gitlab-Token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+
+	signature := strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func (gw *Gateway) appGitWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Couldn't read the request body"))
+		return
+	}
+	if !validAppGitWebhook(r, body, gw.GetConfig().AppGit.WebhookSecret) {
+		log.WithFields(logrus.Fields{
+			"prefix": "api",
+			"origin": r.RemoteAddr,
+		}).Warning("Attempted API definitions webhook with an invalid signature.")
+		doJSONWrite(w, http.StatusForbidden, apiError("Invalid webhook signature"))
+		return
+	}
+
+	if err := gw.appGitSyncAndReload(); err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't sync the API definitions repository"))
+		return
+	}
+	doJSONWrite(w, http.StatusOK, apiOk("synced"))
+}
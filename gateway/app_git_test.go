@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestAppGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "tyk-app-git-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	repo := filepath.Join(dir, "repo")
+
+	git := func(args ...string) {
+		out, err := runAppGit(config.AppGitConfig{}, repo, args...)
+		require.NoError(t, err, out)
+	}
+	commitAPI := func(apiID, listenPath string) {
+		spec := BuildAPI(func(spec *APISpec) {
+			spec.APIID = apiID
+			spec.Proxy.ListenPath = listenPath
+		})[0]
+		data, err := json.Marshal(spec.APIDefinition)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(repo, "apis", apiID+".json"), data, 0644))
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Add "+apiID)
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "apis"), 0755))
+	git("init", "-q")
+	git("checkout", "-q", "-b", "main")
+	commitAPI("git-1", "/git-1/")
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AppGit = config.AppGitConfig{
+			Enabled:       true,
+			URL:           "file://" + repo,
+			Branch:        "main",
+			Path:          "apis",
+			CheckoutDir:   filepath.Join(dir, "checkout"),
+			WebhookSecret: "secret",
+		}
+	})
+	defer ts.Close()
+
+	ts.Gw.DoReload()
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/git-1/", Code: http.StatusOK},
+		{Path: "/git-2/", Code: http.StatusNotFound},
+	}...)
+
+	commitAPI("git-2", "/git-2/")
+
+	payload := `{"ref":"refs/heads/main"}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(payload))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/tyk/git/webhook", Data: payload,
+			Headers: map[string]string{"X-Hub-Signature-256": "sha256=00"}, Code: http.StatusForbidden},
+		{Method: http.MethodPost, Path: "/tyk/git/webhook", Data: payload,
+			Headers: map[string]string{"X-Gitlab-Token": "wrong"}, Code: http.StatusForbidden},
+		{Method: http.MethodPost, Path: "/tyk/git/webhook", Data: payload,
+			Headers: map[string]string{"X-Hub-Signature-256": signature}, Code: http.StatusOK},
+	}...)
+
+	ts.Gw.DoReload()
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/git-1/", Code: http.StatusOK},
+		{Path: "/git-2/", Code: http.StatusOK},
+	}...)
+
+	t.Run("unchanged", func(t *testing.T) {
+		changed, err := ts.Gw.syncAppGit()
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+}
+
+func TestAppGitCheckoutDir(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	dir, err := gw.appGitCheckoutDir()
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	again, _ := gw.appGitCheckoutDir()
+	assert.Equal(t, dir, again)
+
+	file := filepath.Join(dir, "keep.txt")
+	require.NoError(t, ioutil.WriteFile(file, []byte("keep"), 0600))
+	conf := gw.GetConfig()
+	conf.AppGit.CheckoutDir = dir
+	conf.AppGit.URL = "file:///nonexistent"
+	gw.SetConfig(conf)
+
+	_, err = gw.syncAppGit()
+	assert.Error(t, err, "a directory with files which isn't a checkout must not be cloned into")
+	assert.FileExists(t, file)
+
+	assert.NoError(t, checkAppGitCheckoutDir(filepath.Join(dir, "missing")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0700))
+	assert.NoError(t, checkAppGitCheckoutDir(filepath.Join(dir, "empty")))
+}
@@ -0,0 +1,602 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	defaultAsyncOperationsPath = "/operations"
+	defaultAsyncOperationsTTL  = 24 * 60 * 60
+	defaultAsyncMaxAttempts    = 3
+	defaultAsyncRetryBackoff   = 1.0
+	asyncMaxRetryBackoff       = time.Minute
+
+	// asyncDeliveryConcurrency limits the number of requests delivered in parallel per gateway.
+	asyncDeliveryConcurrency = 20
+	asyncConsumerGroup       = "tyk-async"
+	// asyncClaimIdle is how long a request stays read but unacknowledged before another gateway
+	// takes it over, as the gateway which read it probably stopped.
+	asyncClaimIdle     = 5 * time.Minute
+	asyncClaimInterval = time.Minute
+)
+
+const (
+	AsyncOperationQueued    = "queued"
+	AsyncOperationRetrying  = "retrying"
+	AsyncOperationCompleted = "completed"
+	AsyncOperationFailed    = "failed"
+)
+
+var errAsyncOperationNotFound = errors.New("Operation not found")
+
+// AsyncOperation is a request of an async endpoint, delivered to the upstream in the background.
+type AsyncOperation struct {
+	ID       string                  `json:"id"`
+	APIID    string                  `json:"api_id"`
+	Owner    string                  `json:"-"`
+	Status   string                  `json:"status"`
+	Attempts int                     `json:"attempts"`
+	Created  time.Time               `json:"created"`
+	Updated  time.Time               `json:"updated"`
+	Error    string                  `json:"error,omitempty"`
+	Response *AsyncOperationResponse `json:"response,omitempty"`
+}
+
+// asyncOperationRecord is the stored form of an operation, which unlike the API form keeps the owner.
+type asyncOperationRecord struct {
+	AsyncOperation
+	Owner string `json:"owner"`
+}
+
+// AsyncOperationResponse is the upstream response of an operation. Body is base64 encoded when it
+// isn't valid UTF-8.
+type AsyncOperationResponse struct {
+	Code       int         `json:"code"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body"`
+	BodyBase64 bool        `json:"body_base64,omitempty"`
+}
+
+// asyncRequest is a queued request of an operation.
+type asyncRequest struct {
+	OperationID  string      `json:"operation_id"`
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Host         string      `json:"host"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	RemoteAddr   string      `json:"remote_addr"`
+	Attempt      int         `json:"attempt"`
+	MaxAttempts  int         `json:"max_attempts"`
+	RetryBackoff float64     `json:"retry_backoff"`
+	NotBefore    time.Time   `json:"not_before"`
+}
+
+// AsyncOperationManager stores operations and queues their requests in Redis streams, one per API,
+// read by the gateways of the cluster as a consumer group.
+type AsyncOperationManager struct {
+	Gw *Gateway
+
+	store storage.Handler
+	queue *storage.RedisCluster
+	sem   chan struct{}
+
+	// groups are the streams whose consumer group was created
+	groups map[string]bool
+}
+
+func NewAsyncOperationManager(gw *Gateway) *AsyncOperationManager {
+	store := &storage.RedisCluster{KeyPrefix: "async.operations.", RedisController: gw.RedisController}
+	store.Connect()
+	queue := &storage.RedisCluster{KeyPrefix: "async.requests.", RedisController: gw.RedisController}
+	queue.Connect()
+
+	return &AsyncOperationManager{
+		Gw:     gw,
+		store:  store,
+		queue:  queue,
+		sem:    make(chan struct{}, asyncDeliveryConcurrency),
+		groups: map[string]bool{},
+	}
+}
+
+func asyncOperationKey(apiID, id string) string {
+	return apiID + "." + id
+}
+
+func asyncOperationsTTL(spec *APISpec) int64 {
+	if spec == nil || spec.AsyncOperations.TTL <= 0 {
+		return defaultAsyncOperationsTTL
+	}
+	return spec.AsyncOperations.TTL
+}
+
+func (m *AsyncOperationManager) save(op AsyncOperation, ttl int64) error {
+	data, err := json.Marshal(asyncOperationRecord{AsyncOperation: op, Owner: op.Owner})
+	if err != nil {
+		return err
+	}
+	return m.store.SetKey(asyncOperationKey(op.APIID, op.ID), string(data), ttl)
+}
+
+// Get returns an operation of an API.
+func (m *AsyncOperationManager) Get(apiID, id string) (AsyncOperation, bool) {
+	data, err := m.store.GetKey(asyncOperationKey(apiID, id))
+	if err != nil {
+		return AsyncOperation{}, false
+	}
+
+	var record asyncOperationRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return AsyncOperation{}, false
+	}
+	record.AsyncOperation.Owner = record.Owner
+	return record.AsyncOperation, true
+}
+
+// Enqueue stores a queued operation for req and adds req to the queue of the API.
+func (m *AsyncOperationManager) Enqueue(spec *APISpec, owner string, req asyncRequest) (AsyncOperation, error) {
+	now := time.Now()
+	op := AsyncOperation{
+		ID:      uuid.NewV4().String(),
+		APIID:   spec.APIID,
+		Owner:   owner,
+		Status:  AsyncOperationQueued,
+		Created: now,
+		Updated: now,
+	}
+	if err := m.save(op, asyncOperationsTTL(spec)); err != nil {
+		return op, err
+	}
+
+	req.OperationID = op.ID
+	req.Attempt = 1
+	return op, m.push(spec.APIID, req)
+}
+
+func (m *AsyncOperationManager) push(apiID string, req asyncRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = m.queue.StreamAdd(apiID, map[string]interface{}{"request": string(data)})
+	return err
+}
+
+// asyncRetryBackoff is the delay before the retry following attempt, doubled on each attempt.
+func asyncRetryBackoff(backoff float64, attempt int) time.Duration {
+	if backoff <= 0 {
+		backoff = defaultAsyncRetryBackoff
+	}
+	delay := time.Duration(backoff * float64(time.Second))
+	for i := 1; i < attempt && delay < asyncMaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > asyncMaxRetryBackoff {
+		delay = asyncMaxRetryBackoff
+	}
+	return delay
+}
+
+// asyncStreams returns the IDs of the loaded APIs with async endpoints, which are the names of
+// their streams.
+func (gw *Gateway) asyncStreams() []string {
+	gw.apisMu.RLock()
+	defer gw.apisMu.RUnlock()
+
+	var streams []string
+	for apiID, spec := range gw.apisByID {
+		if spec.asyncProxy != nil {
+			streams = append(streams, apiID)
+		}
+	}
+	return streams
+}
+
+// asyncOperationsLoop delivers the queued requests of the loaded APIs until ctx is done.
+func (gw *Gateway) asyncOperationsLoop(ctx context.Context) {
+	m := gw.asyncOperations
+	consumer := gw.GetNodeID()
+	if consumer == "" {
+		consumer = "gateway"
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	lastClaim := time.Now()
+	for ctx.Err() == nil {
+		var streams []string
+		for _, stream := range gw.asyncStreams() {
+			if !m.groups[stream] {
+				if err := m.queue.StreamCreateGroup(stream, asyncConsumerGroup); err != nil {
+					log.WithError(err).WithField("api_id", stream).Error("Could not create async requests consumer group")
+					continue
+				}
+				m.groups[stream] = true
+			}
+			streams = append(streams, stream)
+		}
+
+		if len(streams) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		// messages are only read for the free delivery slots, the others stay in the streams for
+		// the other gateways
+		slots := m.acquireSlots(ctx)
+		if slots == 0 {
+			continue
+		}
+
+		messages, err := m.queue.StreamReadGroup(streams, asyncConsumerGroup, consumer, int64(slots), time.Second)
+		if err != nil {
+			m.releaseSlots(slots)
+			log.WithError(err).Error("Could not read async requests")
+			// the streams may have been removed with their groups
+			m.groups = map[string]bool{}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		if len(messages) < slots && time.Since(lastClaim) >= asyncClaimInterval {
+			lastClaim = time.Now()
+			for _, stream := range streams {
+				free := slots - len(messages)
+				if free == 0 {
+					break
+				}
+				claimed, err := m.queue.StreamClaimIdle(stream, asyncConsumerGroup, consumer, asyncClaimIdle, int64(free))
+				if err != nil {
+					log.WithError(err).WithField("api_id", stream).Error("Could not claim idle async requests")
+					continue
+				}
+				messages = append(messages, claimed...)
+			}
+		}
+
+		m.releaseSlots(slots - len(messages))
+		for _, msg := range messages {
+			wg.Add(1)
+			go func(msg storage.StreamMessage) {
+				defer wg.Done()
+				defer m.releaseSlots(1)
+				m.process(ctx, msg)
+			}(msg)
+		}
+	}
+}
+
+// acquireSlots waits for a free delivery slot and takes it with the other free ones, returning
+// how many were taken. It returns 0 when ctx is done.
+func (m *AsyncOperationManager) acquireSlots(ctx context.Context) int {
+	select {
+	case m.sem <- struct{}{}:
+	case <-ctx.Done():
+		return 0
+	}
+
+	slots := 1
+	for slots < cap(m.sem) {
+		select {
+		case m.sem <- struct{}{}:
+			slots++
+		default:
+			return slots
+		}
+	}
+	return slots
+}
+
+func (m *AsyncOperationManager) releaseSlots(slots int) {
+	for i := 0; i < slots; i++ {
+		<-m.sem
+	}
+}
+
+// process delivers the request of msg once due, acknowledging msg when delivered or requeued. It
+// runs in a delivery slot taken by asyncOperationsLoop.
+func (m *AsyncOperationManager) process(ctx context.Context, msg storage.StreamMessage) {
+	var req asyncRequest
+	data, _ := msg.Values["request"].(string)
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		log.WithError(err).WithField("api_id", msg.Stream).Error("Dropping malformed async request")
+		m.ack(msg)
+		return
+	}
+
+	if wait := time.Until(req.NotBefore); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			// left pending, it's claimed by another gateway
+			return
+		}
+	}
+
+	m.deliver(msg.Stream, req)
+	m.ack(msg)
+}
+
+func (m *AsyncOperationManager) ack(msg storage.StreamMessage) {
+	if err := m.queue.StreamAck(msg.Stream, asyncConsumerGroup, msg.ID); err != nil {
+		log.WithError(err).WithField("api_id", msg.Stream).Error("Could not acknowledge async request")
+	}
+}
+
+// deliver sends req to the upstream of the API and updates its operation, queueing a retry when
+// the upstream fails and attempts remain.
+func (m *AsyncOperationManager) deliver(apiID string, req asyncRequest) {
+	logger := log.WithFields(logrus.Fields{
+		"prefix":       "async",
+		"api_id":       apiID,
+		"operation_id": req.OperationID,
+	})
+
+	op, found := m.Get(apiID, req.OperationID)
+	if !found {
+		logger.Warning("Dropping async request of an expired operation")
+		return
+	}
+	op.Attempts = req.Attempt
+	op.Updated = time.Now()
+
+	spec := m.Gw.getApiSpec(apiID)
+	ttl := asyncOperationsTTL(spec)
+	if spec == nil || spec.asyncProxy == nil {
+		op.Status = AsyncOperationFailed
+		op.Error = "API is not loaded"
+		if err := m.save(op, ttl); err != nil {
+			logger.WithError(err).Error("Could not save async operation")
+		}
+		return
+	}
+
+	resp, err := m.send(spec, req)
+	op.Response = resp
+	if err == nil && resp.Code >= http.StatusInternalServerError {
+		err = fmt.Errorf("upstream responded with status %d", resp.Code)
+	}
+
+	switch {
+	case err == nil:
+		op.Status = AsyncOperationCompleted
+		op.Error = ""
+		logger.Debug("Async request delivered")
+	case req.Attempt >= req.MaxAttempts:
+		op.Status = AsyncOperationFailed
+		op.Error = err.Error()
+		logger.WithError(err).Warning("Async request delivery failed")
+	default:
+		op.Status = AsyncOperationRetrying
+		op.Error = err.Error()
+		logger.WithError(err).Debug("Async request delivery attempt ", req.Attempt, " failed")
+
+		retry := req
+		retry.Attempt++
+		retry.NotBefore = time.Now().Add(asyncRetryBackoff(req.RetryBackoff, req.Attempt))
+		if err := m.push(apiID, retry); err != nil {
+			logger.WithError(err).Error("Could not queue async request retry")
+			op.Status = AsyncOperationFailed
+		}
+	}
+
+	if err := m.save(op, ttl); err != nil {
+		logger.WithError(err).Error("Could not save async operation")
+	}
+}
+
+// send proxies req to the upstream of spec, recording the response.
+func (m *AsyncOperationManager) send(spec *APISpec, req asyncRequest) (*AsyncOperationResponse, error) {
+	r, err := http.NewRequest(req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = req.Header
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	r.Host = req.Host
+	r.RemoteAddr = req.RemoteAddr
+
+	rec := httptest.NewRecorder()
+	spec.asyncProxy.ServeHTTP(rec, r)
+
+	resp := &AsyncOperationResponse{Code: rec.Code, Headers: rec.Header()}
+	if body := rec.Body.Bytes(); utf8.Valid(body) {
+		resp.Body = string(body)
+	} else {
+		resp.Body = base64.StdEncoding.EncodeToString(body)
+		resp.BodyBase64 = true
+	}
+	return resp, nil
+}
+
+// AsyncMiddleware answers the requests of async endpoints with 202 Accepted once queued, and
+// serves their operations to the API consumers which made them.
+type AsyncMiddleware struct {
+	BaseMiddleware
+	sh SuccessHandler
+}
+
+func (m *AsyncMiddleware) Name() string {
+	return "AsyncMiddleware"
+}
+
+func (m *AsyncMiddleware) Init() {
+	m.sh = SuccessHandler{m.BaseMiddleware}
+	// the requests are delivered by the worker, after the middleware chain
+	m.Spec.asyncProxy = m.Proxy
+}
+
+func (m *AsyncMiddleware) EnabledForSpec() bool {
+	for _, version := range m.Spec.VersionData.Versions {
+		if len(version.ExtendedPaths.Async) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *AsyncMiddleware) operationsPath() string {
+	basePath := m.Spec.AsyncOperations.Path
+	if basePath == "" {
+		basePath = defaultAsyncOperationsPath
+	}
+	return "/" + strings.Trim(basePath, "/")
+}
+
+// asyncRequestHeader returns a copy of h without the credentials of the client, which aren't
+// stored with the queued requests.
+func asyncRequestHeader(spec *APISpec, h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range []string{headers.Authorization, "Proxy-Authorization", headers.XTykAuthorization, "Cookie"} {
+		h.Del(name)
+	}
+	for _, config := range spec.AuthConfigs {
+		if config.AuthHeaderName != "" {
+			h.Del(config.AuthHeaderName)
+		}
+	}
+	if spec.Auth.AuthHeaderName != "" {
+		h.Del(spec.Auth.AuthHeaderName)
+	}
+	return h
+}
+
+// upstreamPath strips the version and listen path from target as SuccessHandler does, the worker
+// calling the proxy directly.
+func (m *AsyncMiddleware) upstreamPath(r *http.Request, target *url.URL) {
+	versionDef := m.Spec.VersionDefinition
+	if !m.Spec.VersionData.NotVersioned && versionDef.Location == "url" && versionDef.StripPath {
+		part := m.Spec.getVersionFromRequest(r)
+		target.Path = strings.Replace(target.Path, part+"/", "", 1)
+		target.RawPath = strings.Replace(target.RawPath, part+"/", "", 1)
+	}
+
+	if m.Spec.Proxy.StripListenPath {
+		target.Path = m.Spec.StripListenPath(r, target.Path)
+		target.RawPath = m.Spec.StripListenPath(r, target.RawPath)
+	}
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *AsyncMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	// operations belong to the key which made the request, or to anyone on keyless APIs
+	var owner string
+	if token := ctxGetAuthToken(r); token != "" {
+		owner = storage.HashStr(token)
+	}
+
+	basePath := m.operationsPath()
+	reqPath := "/" + strings.TrimPrefix(m.Spec.StripListenPath(r, r.URL.Path), "/")
+	if r.Method == http.MethodGet && strings.HasPrefix(reqPath, basePath+"/") {
+		id := strings.Trim(strings.TrimPrefix(reqPath, basePath), "/")
+		op, found := m.Gw.asyncOperations.Get(m.Spec.APIID, id)
+		if !found || op.Owner != owner {
+			return errAsyncOperationNotFound, http.StatusNotFound
+		}
+		doJSONWrite(w, http.StatusOK, op)
+		return nil, mwStatusRespond
+	}
+
+	versionInfo, _ := m.Spec.Version(r)
+	versionPaths := m.Spec.RxPaths[versionInfo.Name]
+	found, meta := m.Spec.CheckSpecMatchesStatus(r, versionPaths, Async)
+	if !found {
+		return nil, http.StatusOK
+	}
+	t1 := time.Now()
+	asyncMeta := meta.(*apidef.AsyncMeta)
+
+	target := *r.URL
+	if newURL := ctxGetURLRewriteTarget(r); newURL != nil {
+		target = *newURL
+	}
+	// loops stay in the gateway, they're served synchronously
+	if target.Scheme == LoopScheme {
+		return nil, http.StatusOK
+	}
+	m.upstreamPath(r, &target)
+	method := r.Method
+	if newMethod := ctxGetTransformRequestMethod(r); newMethod != "" {
+		method = newMethod
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return errors.New("Could not read request body"), http.StatusBadRequest
+	}
+
+	maxAttempts := asyncMeta.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultAsyncMaxAttempts
+	}
+	op, err := m.Gw.asyncOperations.Enqueue(m.Spec, owner, asyncRequest{
+		Method:       method,
+		URL:          target.String(),
+		Host:         r.Host,
+		Header:       asyncRequestHeader(m.Spec, r.Header),
+		Body:         body,
+		RemoteAddr:   r.RemoteAddr,
+		MaxAttempts:  maxAttempts,
+		RetryBackoff: asyncMeta.RetryBackoff,
+	})
+	if err != nil {
+		m.Logger().WithError(err).Error("Could not queue async request")
+		return errors.New("Could not queue request"), http.StatusServiceUnavailable
+	}
+
+	statusURL := path.Join("/", m.Spec.Proxy.ListenPath, basePath, op.ID)
+	respBody, _ := json.Marshal(map[string]string{
+		"id":         op.ID,
+		"status":     op.Status,
+		"status_url": statusURL,
+	})
+
+	session := ctxGetSession(r)
+	res := m.Gw.forceResponse(w, r, &VMResponseObject{
+		Response: ResponseObject{
+			Body: string(respBody),
+			Headers: map[string]string{
+				headers.ContentType: headers.ApplicationJSON,
+				headers.Location:    statusURL,
+			},
+			Code: http.StatusAccepted,
+		},
+	}, m.Spec, session, false, m.Logger())
+	if res != nil {
+		m.sh.RecordHit(r, Latency{Total: int64(DurationToMillisecond(time.Since(t1)))}, res.StatusCode, res)
+	}
+
+	return nil, mwStatusRespond
+}
@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestAsyncEndpoints(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ts.Gw.asyncOperationsLoop(ctx)
+
+	var attempts int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "async"
+		spec.Proxy.ListenPath = "/async/"
+		spec.Proxy.StripListenPath = true
+		spec.Proxy.TargetURL = upstream.URL
+		spec.UseKeylessAccess = false
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.ExtendedPaths.Async = []apidef.AsyncMeta{
+				{Path: "/orders", Method: http.MethodPost},
+				{Path: "/flaky", Method: http.MethodPost, RetryBackoff: 0.01},
+				{Path: "/failing", Method: http.MethodPost, MaxAttempts: 2, RetryBackoff: 0.01},
+			}
+		})
+	})
+
+	createKey := func() map[string]string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"async": {APIID: "async", Versions: []string{"v1"}}}
+		})
+		return map[string]string{headers.Authorization: key}
+	}
+	authHeader, otherHeader := createKey(), createKey()
+
+	enqueue := func(t *testing.T, path string) string {
+		t.Helper()
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: path, Data: "payload", Headers: authHeader, Code: http.StatusAccepted})
+		var accepted map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+		assert.Equal(t, AsyncOperationQueued, accepted["status"])
+		assert.Equal(t, "/async/operations/"+accepted["id"], accepted["status_url"])
+		assert.Equal(t, accepted["status_url"], resp.Header.Get(headers.Location))
+		return accepted["id"]
+	}
+	waitFor := func(t *testing.T, id, status string) AsyncOperation {
+		t.Helper()
+		var op AsyncOperation
+		assert.Eventually(t, func() bool {
+			resp, _ := ts.Run(t, test.TestCase{Path: "/async/operations/" + id, Headers: authHeader, Code: http.StatusOK})
+			op = AsyncOperation{}
+			_ = json.NewDecoder(resp.Body).Decode(&op)
+			return op.Status == status
+		}, 5*time.Second, 20*time.Millisecond)
+		return op
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		id := enqueue(t, "/async/orders")
+		op := waitFor(t, id, AsyncOperationCompleted)
+		assert.Equal(t, 1, op.Attempts)
+		require.NotNil(t, op.Response)
+		assert.Equal(t, http.StatusCreated, op.Response.Code)
+		assert.Equal(t, "POST /orders payload", op.Response.Body)
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		id := enqueue(t, "/async/flaky")
+		op := waitFor(t, id, AsyncOperationCompleted)
+		assert.Equal(t, 2, op.Attempts)
+		assert.Empty(t, op.Error)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "async"
+			spec.Proxy.ListenPath = "/async/"
+			spec.Proxy.TargetURL = "http://127.0.0.1:1"
+			spec.UseKeylessAccess = false
+			UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+				v.ExtendedPaths.Async = []apidef.AsyncMeta{{Path: "/failing", Method: http.MethodPost, MaxAttempts: 2, RetryBackoff: 0.01}}
+			})
+		})
+
+		id := enqueue(t, "/async/failing")
+		op := waitFor(t, id, AsyncOperationFailed)
+		assert.Equal(t, 2, op.Attempts)
+		assert.NotEmpty(t, op.Error)
+	})
+
+	t.Run("operations of other keys", func(t *testing.T) {
+		id := enqueue(t, "/async/failing")
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/async/operations/" + id, Headers: otherHeader, Code: http.StatusNotFound},
+			{Path: "/async/operations/unknown", Headers: authHeader, Code: http.StatusNotFound},
+			{Path: "/async/operations/" + id, Code: http.StatusUnauthorized},
+		}...)
+	})
+}
+
+func TestAsyncRetryBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, asyncRetryBackoff(0, 1))
+	assert.Equal(t, 4*time.Second, asyncRetryBackoff(1, 3))
+	assert.Equal(t, asyncMaxRetryBackoff, asyncRetryBackoff(1, 10))
+}
+
+func TestAsyncRequestHeader(t *testing.T) {
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{}}
+	spec.Auth.AuthHeaderName = "X-Api-Key"
+
+	h := http.Header{}
+	h.Set(headers.Authorization, "Bearer token")
+	h.Set("X-Api-Key", "key")
+	h.Set("Cookie", "session=1")
+	h.Set(headers.ContentType, headers.ApplicationJSON)
+
+	stored := asyncRequestHeader(spec, h)
+	assert.Equal(t, http.Header{headers.ContentType: {headers.ApplicationJSON}}, stored)
+	assert.Equal(t, "Bearer token", h.Get(headers.Authorization), "the request headers are unchanged")
+}
+
+func TestAsyncDeliverySlots(t *testing.T) {
+	m := &AsyncOperationManager{sem: make(chan struct{}, 3)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.Equal(t, 3, m.acquireSlots(ctx))
+	m.releaseSlots(1)
+	assert.Equal(t, 1, m.acquireSlots(ctx))
+
+	cancel()
+	assert.Equal(t, 0, m.acquireSlots(ctx), "no slot is free")
+}
@@ -29,6 +29,8 @@ type SessionHandler interface {
 type DefaultSessionManager struct {
 	store storage.Handler
 	orgID string
+	// index is the index the sessions are searched with, if any
+	index *keyIndex
 	Gw    *Gateway `json:"-"`
 }
 
@@ -37,8 +39,12 @@ func (b *DefaultSessionManager) Init(store storage.Handler) {
 	b.store.Connect()
 }
 
-// KeyExpired checks if a key has expired, if the value of user.SessionState.Expires is 0, it will be ignored
+// KeyExpired checks if a key has expired, if the value of user.SessionState.Expires is 0, it will be ignored.
+// Keys past the end of their activity period are expired too.
 func (b *DefaultSessionManager) KeyExpired(newSession *user.SessionState) bool {
+	if newSession.NoLongerActive(time.Now()) {
+		return true
+	}
 	if newSession.Expires >= 1 {
 		return time.Now().After(time.Unix(newSession.Expires, 0))
 	}
@@ -82,7 +88,7 @@ func (b *DefaultSessionManager) clearCacheForKey(keyName string, hashed bool) {
 	}
 
 	// Delete gateway's cache immediately
-	b.Gw.SessionCache.Delete(cacheKey)
+	b.Gw.deleteCachedSession(cacheKey)
 
 	// Notify gateways in cluster to flush cache
 	n := Notification{
@@ -96,35 +102,82 @@ func (b *DefaultSessionManager) clearCacheForKey(keyName string, hashed bool) {
 // UpdateSession updates the session state in the storage engine
 func (b *DefaultSessionManager) UpdateSession(keyName string, session *user.SessionState,
 	resetTTLTo int64, hashed bool) error {
-	defer b.clearCacheForKey(keyName, hashed)
-
+	session.Migrate()
 	v, err := json.Marshal(session)
 	if err != nil {
 		log.Error("Error marshalling session for sync update")
 		return err
 	}
 
-	// sync update
-	if hashed {
-		keyName = b.store.GetKeyPrefix() + keyName
-		err = b.store.SetRawKey(keyName, string(v), resetTTLTo)
+	id := keyName
+	if !hashed {
+		id = storage.HashKey(keyName, b.Gw.GetConfig().HashKeys)
+	}
+
+	// sync update, notifying the gateways of the cluster to flush their cached copy in the same
+	// round trip when possible
+	if publisher, message, ok := b.sessionPublisher(id); ok {
+		defer b.Gw.deleteCachedSession(id)
+
+		channel := b.Gw.MainNotifier.channel
+		if hashed {
+			err = publisher.SetRawKeyAndPublish(b.store.GetKeyPrefix()+keyName, string(v), resetTTLTo, channel, message)
+		} else {
+			err = publisher.SetKeyAndPublish(keyName, string(v), resetTTLTo, channel, message)
+		}
 	} else {
-		err = b.store.SetKey(keyName, string(v), resetTTLTo)
+		defer b.clearCacheForKey(keyName, hashed)
+
+		if hashed {
+			err = b.store.SetRawKey(b.store.GetKeyPrefix()+keyName, string(v), resetTTLTo)
+		} else {
+			err = b.store.SetKey(keyName, string(v), resetTTLTo)
+		}
+	}
+
+	if err == nil && b.index != nil {
+		b.index.update(id, session)
 	}
 
 	return err
 }
 
+// sessionPublisher returns the store of the sessions if it's the Redis the notifications of the
+// gateway are published to, with the notification flushing the session cached as cacheKey.
+func (b *DefaultSessionManager) sessionPublisher(cacheKey string) (*storage.RedisCluster, string, bool) {
+	store, ok := b.store.(*storage.RedisCluster)
+	notifier := b.Gw.MainNotifier.store
+	if !ok || notifier == nil || store.RedisController != notifier.RedisController {
+		return nil, "", false
+	}
+
+	message, ok := b.Gw.MainNotifier.message(Notification{
+		Command: KeySpaceUpdateNotification,
+		Payload: cacheKey,
+		Gw:      b.Gw,
+	})
+	return store, message, ok
+}
+
 // RemoveSession removes session from storage
 func (b *DefaultSessionManager) RemoveSession(orgID string, keyName string, hashed bool) bool {
 	defer b.clearCacheForKey(keyName, hashed)
 
 	if hashed {
+		if b.index != nil {
+			b.index.remove(keyName)
+		}
 		return b.store.DeleteRawKey(b.store.GetKeyPrefix() + keyName)
 	} else {
 		// support both old and new key hashing
+		token := b.Gw.generateToken(orgID, keyName)
+		if b.index != nil {
+			hashKeys := b.Gw.GetConfig().HashKeys
+			b.index.remove(storage.HashKey(keyName, hashKeys))
+			b.index.remove(storage.HashKey(token, hashKeys))
+		}
 		res1 := b.store.DeleteKey(keyName)
-		res2 := b.store.DeleteKey(b.Gw.generateToken(orgID, keyName))
+		res2 := b.store.DeleteKey(token)
 		return res1 || res2
 	}
 }
@@ -177,6 +230,8 @@ func (b *DefaultSessionManager) SessionDetail(orgID string, keyName string, hash
 		log.Error("Couldn't unmarshal session object (may be cache miss): ", err)
 		return user.SessionState{}, false
 	}
+	// sessions are upgraded lazily, they're saved in the current format on their next update
+	session.Migrate()
 	session.KeyID = keyId
 	return session.Clone(), true
 }
@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	defaultBCryptCost    = 10
+	defaultArgon2Time    = 1
+	defaultArgon2Memory  = 64 * 1024
+	defaultArgon2Threads = 4
+
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+var (
+	errInvalidArgon2Hash = errors.New("invalid argon2id hash")
+	errPasswordMismatch  = errors.New("password mismatch")
+)
+
+// basicAuthHashStats counts the basic auth logins with passwords not hashed as configured.
+type basicAuthHashStats struct {
+	legacyHashLogins uint64
+	rehashed         uint64
+	rehashFailures   uint64
+}
+
+// BasicAuthHashMetrics are the metrics of the migration of the basic auth passwords to the
+// configured hashing, reported with the health checks.
+type BasicAuthHashMetrics struct {
+	// LegacyHashLogins are the successful logins with a password hashed with another algorithm or
+	// cost than configured, or not hashed.
+	LegacyHashLogins uint64 `json:"legacy_hash_logins"`
+	// Rehashed are the passwords migrated to the configured hashing on login.
+	Rehashed       uint64 `json:"rehashed"`
+	RehashFailures uint64 `json:"rehash_failures"`
+}
+
+func (gw *Gateway) basicAuthHashMetrics() BasicAuthHashMetrics {
+	return BasicAuthHashMetrics{
+		LegacyHashLogins: atomic.LoadUint64(&gw.basicAuthHashStats.legacyHashLogins),
+		Rehashed:         atomic.LoadUint64(&gw.basicAuthHashStats.rehashed),
+		RehashFailures:   atomic.LoadUint64(&gw.basicAuthHashStats.rehashFailures),
+	}
+}
+
+// usesBasicAuth reports whether any loaded API authenticates with basic auth.
+func (gw *Gateway) usesBasicAuth() bool {
+	gw.apisMu.RLock()
+	defer gw.apisMu.RUnlock()
+
+	for _, spec := range gw.apisByID {
+		if spec.UseBasicAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBasicAuthHashConfig returns an error if conf sets an unsupported algorithm or cost, which
+// would fail hashing the passwords of every key created.
+func validateBasicAuthHashConfig(conf config.BasicAuthHashConfig) error {
+	conf = basicAuthHashConfig(conf)
+
+	switch user.HashType(conf.Algorithm) {
+	case user.HashBCrypt:
+		if conf.BCryptCost < bcrypt.MinCost || conf.BCryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("basic auth bcrypt cost %d is not between %d and %d", conf.BCryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case user.HashArgon2id:
+	default:
+		return fmt.Errorf("unsupported basic auth hash algorithm %q, must be %s or %s", conf.Algorithm, user.HashBCrypt, user.HashArgon2id)
+	}
+	return nil
+}
+
+// basicAuthHashConfig returns conf with defaults applied to unset values.
+func basicAuthHashConfig(conf config.BasicAuthHashConfig) config.BasicAuthHashConfig {
+	if conf.Algorithm == "" {
+		conf.Algorithm = string(user.HashBCrypt)
+	}
+	if conf.BCryptCost == 0 {
+		conf.BCryptCost = defaultBCryptCost
+	}
+	if conf.Argon2Time == 0 {
+		conf.Argon2Time = defaultArgon2Time
+	}
+	if conf.Argon2Memory == 0 {
+		conf.Argon2Memory = defaultArgon2Memory
+	}
+	if conf.Argon2Threads == 0 {
+		conf.Argon2Threads = defaultArgon2Threads
+	}
+	return conf
+}
+
+// hashPassword hashes password with the configured algorithm.
+func hashPassword(conf config.BasicAuthHashConfig, password string) (string, user.HashType, error) {
+	conf = basicAuthHashConfig(conf)
+
+	switch user.HashType(conf.Algorithm) {
+	case user.HashBCrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), conf.BCryptCost)
+		return string(hash), user.HashBCrypt, err
+	case user.HashArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", user.HashPlainText, err
+		}
+
+		key := argon2.IDKey([]byte(password), salt, conf.Argon2Time, conf.Argon2Memory, conf.Argon2Threads, argon2KeyLen)
+		hash := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+			conf.Argon2Memory, conf.Argon2Time, conf.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+		return hash, user.HashArgon2id, nil
+	default:
+		return "", user.HashPlainText, fmt.Errorf("unsupported basic auth hash algorithm %q", conf.Algorithm)
+	}
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2Hash decodes a hash in the `$argon2id$v=19$m=65536,t=1,p=4$salt$key` format.
+func parseArgon2Hash(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != string(user.HashArgon2id) {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errInvalidArgon2Hash
+	}
+
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+
+	return p, nil
+}
+
+// comparePasswordHash checks password against a hash of the given type, returning nil on match.
+func comparePasswordHash(hashType user.HashType, hash, password []byte) error {
+	switch hashType {
+	case user.HashBCrypt:
+		return bcrypt.CompareHashAndPassword(hash, password)
+	case user.HashArgon2id:
+		p, err := parseArgon2Hash(string(hash))
+		if err != nil {
+			return err
+		}
+
+		key := argon2.IDKey(password, p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		if subtle.ConstantTimeCompare(key, p.key) != 1 {
+			return errPasswordMismatch
+		}
+		return nil
+	case user.HashPlainText:
+		if subtle.ConstantTimeCompare(hash, password) != 1 {
+			return errPasswordMismatch
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported basic auth hash type %q", hashType)
+	}
+}
+
+// passwordNeedsRehash reports whether the stored password uses a different algorithm or cost than configured.
+func passwordNeedsRehash(conf config.BasicAuthHashConfig, data user.BasicAuthData) bool {
+	conf = basicAuthHashConfig(conf)
+
+	if data.Hash != user.HashType(conf.Algorithm) {
+		return true
+	}
+
+	switch data.Hash {
+	case user.HashBCrypt:
+		cost, err := bcrypt.Cost([]byte(data.Password))
+		return err != nil || cost != conf.BCryptCost
+	case user.HashArgon2id:
+		p, err := parseArgon2Hash(data.Password)
+		return err != nil || p.memory != conf.Argon2Memory || p.time != conf.Argon2Time || p.threads != conf.Argon2Threads
+	}
+
+	return false
+}
@@ -0,0 +1,347 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TykTechnologies/tyk/cli"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// benchUpstream is the name the time to the first byte of the upstream responses is recorded with.
+const benchUpstream = "upstream"
+
+// benchStats records the time spent in every middleware, and waiting for the upstream, while
+// benchmarking the gateway.
+type benchStats struct {
+	mu      sync.Mutex
+	order   []string
+	samples map[string][]time.Duration
+}
+
+func newBenchStats() *benchStats {
+	return &benchStats{samples: map[string][]time.Duration{}}
+}
+
+func (b *benchStats) add(name string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.samples[name]; !ok {
+		b.order = append(b.order, name)
+	}
+	b.samples[name] = append(b.samples[name], d)
+}
+
+// reset drops the samples, keeping the order the middlewares were first seen in.
+func (b *benchStats) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name := range b.samples {
+		b.samples[name] = nil
+	}
+}
+
+// benchResult is the outcome of the measured requests of a benchmark.
+type benchResult struct {
+	elapsed   time.Duration
+	latencies []time.Duration
+	codes     map[int]int
+	errors    int
+}
+
+// benchDurations summarises a set of durations.
+type benchDurations struct {
+	count                    int
+	mean, p50, p90, p99, max time.Duration
+}
+
+func summariseDurations(samples []time.Duration) benchDurations {
+	if len(samples) == 0 {
+		return benchDurations{}
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	percentile := func(p float64) time.Duration {
+		return sorted[int(float64(len(sorted)-1)*p)]
+	}
+	return benchDurations{
+		count: len(sorted),
+		mean:  total / time.Duration(len(sorted)),
+		p50:   percentile(0.5),
+		p90:   percentile(0.9),
+		p99:   percentile(0.99),
+		max:   sorted[len(sorted)-1],
+	}
+}
+
+// runBench runs the bench command, returning the exit code.
+func runBench(ctx context.Context, cancel context.CancelFunc, opts cli.BenchOptions) int {
+	if *opts.Concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "concurrency must be at least 1")
+		return 1
+	}
+	if *opts.Requests <= 0 && *opts.Duration <= 0 {
+		fmt.Fprintln(os.Stderr, "a number of requests or a duration is required")
+		return 1
+	}
+
+	gwConfig := config.Default
+	if *opts.Conf != "" {
+		gwConfig = config.Config{}
+		if err := config.Load([]string{*opts.Conf}, &gwConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "couldn't load the configuration: %v\n", err)
+			return 1
+		}
+	}
+
+	upstream := newBenchUpstream(*opts.UpstreamLatency, *opts.UpstreamSize)
+	defer upstream.Close()
+
+	gw, spec, err := startBenchGateway(ctx, cancel, gwConfig, *opts.API, upstream.URL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	path := *opts.Path
+	if path == "" {
+		path = spec.Proxy.ListenPath
+	}
+	target := fmt.Sprintf("http://%s:%d%s", gw.GetConfig().ListenAddress, gw.GetConfig().ListenPort, path)
+
+	client := &http.Client{Transport: &http.Transport{
+		MaxIdleConns:        *opts.Concurrency,
+		MaxIdleConnsPerHost: *opts.Concurrency,
+	}}
+	send := func() (int, error) {
+		req, err := http.NewRequest(*opts.Method, target, bytes.NewBufferString(*opts.Body))
+		if err != nil {
+			return 0, err
+		}
+		for name, value := range *opts.Headers {
+			req.Header.Set(name, value)
+		}
+		if host, ok := (*opts.Headers)["Host"]; ok {
+			req.Host = host
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if _, err := send(); err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't reach the gateway: %v\n", err)
+		return 1
+	}
+	if *opts.Warmup > 0 {
+		benchLoad(send, *opts.Warmup, 0, *opts.Concurrency, *opts.Rate)
+	}
+	gw.benchStats.reset()
+
+	result := benchLoad(send, *opts.Requests, *opts.Duration, *opts.Concurrency, *opts.Rate)
+	printBenchReport(os.Stdout, spec, target, result, gw.benchStats)
+	if result.errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newBenchUpstream returns the synthetic upstream, responding with size bytes after latency.
+func newBenchUpstream(latency time.Duration, size int) *httptest.Server {
+	if size < 0 {
+		size = 0
+	}
+	body := bytes.Repeat([]byte("x"), size)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+	}))
+}
+
+// startBenchGateway starts a gateway listening on a free local port, serving the API definition
+// at path only, proxied to upstreamURL.
+func startBenchGateway(ctx context.Context, cancel context.CancelFunc, gwConfig config.Config, path, upstreamURL string) (*Gateway, *APISpec, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	gwConfig.ListenAddress = "127.0.0.1"
+	gwConfig.ListenPort = port
+	gwConfig.ControlAPIPort = 0
+	gwConfig.HttpServerOptions.UseSSL = false
+	gwConfig.UseDBAppConfigs = false
+	gwConfig.SlaveOptions.UseRPC = false
+	gwConfig.UptimeTests.Disable = true
+
+	gw := NewGateway(gwConfig, ctx, cancel)
+	gw.afterConfSetup()
+	gw.SetNodeID("bench-" + strconv.Itoa(os.Getpid()))
+	gw.setupGlobals()
+	gw.setupPortsWhitelist()
+	gw.keyGen = DefaultKeyGenerator{Gw: gw}
+	gw.benchStats = newBenchStats()
+
+	conf := gw.GetConfig()
+	go gw.RedisController.ConnectToRedis(gw.ctx, nil, &conf)
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	if !gw.RedisController.WaitConnect(waitCtx) {
+		mainLog.Warn("Couldn't connect to Redis, keys, limits and analytics won't work")
+	}
+	waitCancel()
+
+	if !conf.SupressDefaultOrgStore {
+		gw.DefaultOrgStore.Init(gw.getGlobalStorageHandler("orgkey.", false))
+		gw.DefaultQuotaStore.Init(gw.getGlobalStorageHandler("orgkey.", false))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	loader := APIDefinitionLoader{Gw: gw}
+	def := loader.ParseDefinition(f)
+	if def.APIID == "" || def.Proxy.ListenPath == "" {
+		return nil, nil, fmt.Errorf("%s is not an API definition with an api_id and a listen path", path)
+	}
+	def.Proxy.TargetURL = upstreamURL
+	def.Proxy.EnableLoadBalancing = false
+	def.Proxy.ServiceDiscovery.UseDiscoveryService = false
+	def.Proxy.CheckHostAgainstUptimeTests = false
+	def.Domain = ""
+	def.ListenPort = 0
+	def.Protocol = ""
+
+	gw.loadApps([]*APISpec{loader.MakeSpec(&def, nil)})
+	spec := gw.getApiSpec(def.APIID)
+	if spec == nil {
+		return nil, nil, fmt.Errorf("couldn't load the API definition %s", path)
+	}
+	return gw, spec, nil
+}
+
+// benchLoad sends requests with concurrent clients, at most rate per second if rate is positive,
+// until the number of requests is sent, or for duration when requests is 0.
+func benchLoad(send func() (int, error), requests int, duration time.Duration, concurrency, rate int) benchResult {
+	var ticks <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var deadline time.Time
+	if requests <= 0 {
+		deadline = time.Now().Add(duration)
+	}
+
+	var sent int64
+	next := func() bool {
+		if ticks != nil {
+			<-ticks
+		}
+		if requests > 0 {
+			return atomic.AddInt64(&sent, 1) <= int64(requests)
+		}
+		return time.Now().Before(deadline)
+	}
+
+	var mu sync.Mutex
+	result := benchResult{codes: map[int]int{}}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next() {
+				begin := time.Now()
+				code, err := send()
+				latency := time.Since(begin)
+
+				mu.Lock()
+				if err != nil {
+					result.errors++
+				} else {
+					result.codes[code]++
+					result.latencies = append(result.latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	result.elapsed = time.Since(start)
+	return result
+}
+
+func printBenchReport(w io.Writer, spec *APISpec, target string, result benchResult, stats *benchStats) {
+	total := summariseDurations(result.latencies)
+
+	fmt.Fprintf(w, "API %s (%s), requesting %s\n", spec.Name, spec.APIID, target)
+	fmt.Fprintf(w, "%d requests in %v, %.1f requests/s, %d errors\n",
+		total.count+result.errors, result.elapsed.Round(time.Millisecond),
+		float64(total.count)/result.elapsed.Seconds(), result.errors)
+
+	codes := make([]int, 0, len(result.codes))
+	for code := range result.codes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %d: %d\n", code, result.codes[code])
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tcalls\tmean\tp50\tp90\tp99\tmax\t")
+	row := func(name string, d benchDurations) {
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\t%v\t\n", name, d.count,
+			d.mean.Round(time.Microsecond), d.p50.Round(time.Microsecond), d.p90.Round(time.Microsecond),
+			d.p99.Round(time.Microsecond), d.max.Round(time.Microsecond))
+	}
+	row("total", total)
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	row(benchUpstream, summariseDurations(stats.samples[benchUpstream]))
+	for _, name := range stats.order {
+		if name != benchUpstream {
+			row(name, summariseDurations(stats.samples[name]))
+		}
+	}
+	_ = tw.Flush()
+}
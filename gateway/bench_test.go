@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummariseDurations(t *testing.T) {
+	assert.Equal(t, benchDurations{}, summariseDurations(nil))
+
+	var samples []time.Duration
+	for i := 100; i > 0; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	summary := summariseDurations(samples)
+	assert.Equal(t, 100, summary.count)
+	assert.Equal(t, 50500*time.Microsecond, summary.mean)
+	assert.Equal(t, 50*time.Millisecond, summary.p50)
+	assert.Equal(t, 90*time.Millisecond, summary.p90)
+	assert.Equal(t, 99*time.Millisecond, summary.p99)
+	assert.Equal(t, 100*time.Millisecond, summary.max)
+	assert.Equal(t, 100*time.Millisecond, samples[0], "the samples must not be sorted in place")
+}
+
+func TestBenchLoad(t *testing.T) {
+	upstream := newBenchUpstream(0, 16)
+	defer upstream.Close()
+
+	var calls int64
+	send := func() (int, error) {
+		atomic.AddInt64(&calls, 1)
+		resp, err := http.Get(upstream.URL)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, bytes.Repeat([]byte("x"), 16), body)
+		return resp.StatusCode, nil
+	}
+
+	t.Run("Requests", func(t *testing.T) {
+		result := benchLoad(send, 25, 0, 4, 0)
+		assert.Equal(t, int64(25), atomic.LoadInt64(&calls))
+		assert.Equal(t, map[int]int{http.StatusOK: 25}, result.codes)
+		assert.Len(t, result.latencies, 25)
+		assert.Zero(t, result.errors)
+	})
+
+	t.Run("Duration", func(t *testing.T) {
+		result := benchLoad(send, 0, 100*time.Millisecond, 2, 50)
+		require.NotEmpty(t, result.latencies)
+		// 50 requests per second allow 5 or 6 requests in 100ms
+		assert.LessOrEqual(t, len(result.latencies), 7)
+		assert.GreaterOrEqual(t, result.elapsed, 100*time.Millisecond)
+	})
+}
+
+func TestBenchStats(t *testing.T) {
+	stats := newBenchStats()
+	stats.add("VersionCheck", time.Millisecond)
+	stats.add(benchUpstream, 2*time.Millisecond)
+	stats.add("VersionCheck", time.Millisecond)
+	assert.Equal(t, []string{"VersionCheck", benchUpstream}, stats.order)
+	assert.Len(t, stats.samples["VersionCheck"], 2)
+
+	stats.reset()
+	assert.Empty(t, stats.samples["VersionCheck"])
+	assert.Equal(t, []string{"VersionCheck", benchUpstream}, stats.order)
+}
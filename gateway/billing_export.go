@@ -0,0 +1,322 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	billingKeyPrefix = "billing-"
+
+	billingTargetHTTP          = "http"
+	billingTargetObjectStorage = "object_storage"
+
+	defaultBillingPeriod    = 3600
+	defaultBillingRetention = 86400
+	defaultBillingTimeout   = 30
+
+	// billingExportDelay leaves time to the requests in flight at the end of a period to be counted.
+	billingExportDelay = 10 * time.Second
+	// billingRetryInterval is the minimum time between two delivery attempts of a period.
+	billingRetryInterval = 60 * time.Second
+
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+var billingLog = log.WithField("prefix", "billing")
+
+// BillingUsageRecord is the usage of an API by a key during a period.
+type BillingUsageRecord struct {
+	OrgID    string `json:"org_id"`
+	APIID    string `json:"api_id"`
+	KeyID    string `json:"key_id"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+}
+
+// BillingExport is the payload delivered for a period. Its idempotency key is the same on every
+// attempt, so billing systems can discard duplicate deliveries.
+type BillingExport struct {
+	IdempotencyKey string               `json:"idempotency_key"`
+	PeriodStart    time.Time            `json:"period_start"`
+	PeriodEnd      time.Time            `json:"period_end"`
+	Usage          []BillingUsageRecord `json:"usage"`
+}
+
+// BillingDelivery tracks the delivery of the export of a period.
+type BillingDelivery struct {
+	IdempotencyKey string    `json:"idempotency_key"`
+	PeriodStart    time.Time `json:"period_start"`
+	Records        int       `json:"records"`
+	Attempts       int       `json:"attempts"`
+	Delivered      bool      `json:"delivered"`
+	DeliveredAt    time.Time `json:"delivered_at,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+func billingPeriod(conf config.BillingExportConfig) int64 {
+	if conf.Period > 0 {
+		return conf.Period
+	}
+	return defaultBillingPeriod
+}
+
+func billingRetention(conf config.BillingExportConfig) int64 {
+	if conf.Retention > 0 {
+		return conf.Retention
+	}
+	return defaultBillingRetention
+}
+
+func (gw *Gateway) billingStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: billingKeyPrefix, RedisController: gw.RedisController}
+}
+
+// recordBillingUsage counts a request of a key to spec in the current period. It doesn't depend
+// on the analytics settings, so usage is complete even when analytics are sampled or disabled.
+func (gw *Gateway) recordBillingUsage(r *http.Request, spec *APISpec, code int) {
+	conf := gw.GetConfig()
+	if !conf.BillingExport.Enabled {
+		return
+	}
+
+	token := ctxGetAuthToken(r)
+	if token == "" {
+		return
+	}
+	keyID := storage.HashKey(token, conf.HashKeys)
+
+	period := billingPeriod(conf.BillingExport)
+	start := time.Now().Unix() / period * period
+	expire := period + billingRetention(conf.BillingExport)
+
+	store := gw.billingStore()
+	prefix := fmt.Sprintf("%susage:%d:%s:%s:", billingKeyPrefix, start, spec.OrgID, spec.APIID)
+	store.IncrememntWithExpire(prefix+"requests:"+keyID, expire)
+	if code >= http.StatusBadRequest {
+		store.IncrememntWithExpire(prefix+"errors:"+keyID, expire)
+	}
+}
+
+// billingExportLoop exports the usage of the periods which ended until ctx is done.
+func (gw *Gateway) billingExportLoop(ctx context.Context) {
+	tick := time.NewTicker(billingExportDelay)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-tick.C:
+			if gw.GetConfig().BillingExport.Enabled {
+				gw.exportBillingUsage(ctx, t)
+			}
+		}
+	}
+}
+
+// exportBillingUsage delivers the usage of the periods ended before now. Usage is deleted once
+// delivered, failed deliveries are retried until the usage expires.
+func (gw *Gateway) exportBillingUsage(ctx context.Context, now time.Time) {
+	conf := gw.GetConfig().BillingExport
+	period := billingPeriod(conf)
+	store := gw.billingStore()
+
+	periods := make(map[int64]map[string]string)
+	for key, value := range store.GetKeysAndValuesWithFilter("usage:") {
+		parts := strings.SplitN(key, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		start, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if time.Unix(start+period, 0).Add(billingExportDelay).After(now) {
+			continue
+		}
+		if periods[start] == nil {
+			periods[start] = make(map[string]string)
+		}
+		periods[start][key] = value
+	}
+
+	for start, usage := range periods {
+		// a single node delivers a period at a time, the lock also spaces retries out
+		lockTTL := int64(billingRetryInterval / time.Second)
+		if store.IncrememntWithExpire(fmt.Sprintf("%slock:%d", billingKeyPrefix, start), lockTTL) != 1 {
+			continue
+		}
+		gw.deliverBillingPeriod(ctx, store, time.Unix(start, 0).UTC(), time.Duration(period)*time.Second, usage)
+	}
+}
+
+func (gw *Gateway) deliverBillingPeriod(ctx context.Context, store *storage.RedisCluster, start time.Time, period time.Duration, usage map[string]string) {
+	conf := gw.GetConfig().BillingExport
+	export := BillingExport{
+		IdempotencyKey: billingIdempotencyKey(start, period),
+		PeriodStart:    start,
+		PeriodEnd:      start.Add(period),
+		Usage:          billingUsageRecords(usage),
+	}
+
+	deliveryKey := fmt.Sprintf("delivery:%d", start.Unix())
+	delivery := BillingDelivery{IdempotencyKey: export.IdempotencyKey, PeriodStart: start}
+	if value, err := store.GetKey(deliveryKey); err == nil {
+		json.Unmarshal([]byte(value), &delivery)
+	}
+	delivery.Records = len(export.Usage)
+	delivery.Attempts++
+
+	logger := billingLog.WithFields(logrus.Fields{
+		"period_start":    start,
+		"idempotency_key": export.IdempotencyKey,
+	})
+
+	if err := gw.sendBillingExport(ctx, conf, export); err != nil {
+		logger.WithError(err).Error("Failed to deliver billing export")
+		delivery.LastError = err.Error()
+	} else {
+		logger.WithField("records", delivery.Records).Info("Delivered billing export")
+		delivery.Delivered = true
+		delivery.DeliveredAt = time.Now().UTC()
+		delivery.LastError = ""
+
+		keys := make([]string, 0, len(usage))
+		for key := range usage {
+			keys = append(keys, key)
+		}
+		store.DeleteKeys(keys)
+	}
+
+	data, _ := json.Marshal(delivery)
+	if err := store.SetKey(deliveryKey, string(data), billingRetention(conf)); err != nil {
+		logger.WithError(err).Error("Failed to track billing export delivery")
+	}
+}
+
+// billingUsageRecords rolls the counters of a period up by org, API and key, in a stable order.
+func billingUsageRecords(usage map[string]string) []BillingUsageRecord {
+	byKey := make(map[[3]string]*BillingUsageRecord)
+	for key, value := range usage {
+		// usage:<period start>:<org ID>:<API ID>:<requests|errors>:<key ID>
+		parts := strings.SplitN(key, ":", 6)
+		if len(parts) != 6 {
+			continue
+		}
+		count, _ := strconv.ParseInt(value, 10, 64)
+
+		id := [3]string{parts[2], parts[3], parts[5]}
+		rec, ok := byKey[id]
+		if !ok {
+			rec = &BillingUsageRecord{OrgID: id[0], APIID: id[1], KeyID: id[2]}
+			byKey[id] = rec
+		}
+		switch parts[4] {
+		case "requests":
+			rec.Requests = count
+		case "errors":
+			rec.Errors = count
+		}
+	}
+
+	records := make([]BillingUsageRecord, 0, len(byKey))
+	for _, rec := range byKey {
+		records = append(records, *rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.OrgID != b.OrgID {
+			return a.OrgID < b.OrgID
+		}
+		if a.APIID != b.APIID {
+			return a.APIID < b.APIID
+		}
+		return a.KeyID < b.KeyID
+	})
+	return records
+}
+
+func billingIdempotencyKey(start time.Time, period time.Duration) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("tyk-billing:%d:%d", start.Unix(), int64(period/time.Second))))
+	return hex.EncodeToString(sum[:])
+}
+
+func (gw *Gateway) sendBillingExport(ctx context.Context, conf config.BillingExportConfig, export BillingExport) error {
+	body, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+
+	method, url := http.MethodPost, conf.URL
+	switch conf.Target {
+	case billingTargetHTTP, "":
+	case billingTargetObjectStorage:
+		method = http.MethodPut
+		url = strings.TrimSuffix(url, "/") + "/" + conf.ObjectPrefix + export.PeriodStart.Format(time.RFC3339) + ".json"
+	default:
+		return fmt.Errorf("unknown billing export target %q", conf.Target)
+	}
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultBillingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, value := range conf.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set(headers.ContentType, headers.ApplicationJSON)
+	req.Header.Set(idempotencyKeyHeader, export.IdempotencyKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("billing export target responded %s", resp.Status)
+	}
+	return nil
+}
+
+// billingDeliveriesHandler lists the tracked deliveries, latest period first.
+func (gw *Gateway) billingDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries := make([]BillingDelivery, 0)
+	for _, value := range gw.billingStore().GetKeysAndValuesWithFilter("delivery:") {
+		var delivery BillingDelivery
+		if err := json.Unmarshal([]byte(value), &delivery); err == nil {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].PeriodStart.After(deliveries[j].PeriodStart)
+	})
+
+	doJSONWrite(w, http.StatusOK, deliveries)
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+type billingTarget struct {
+	sync.Mutex
+	server   *httptest.Server
+	status   int
+	requests []*http.Request
+	exports  []BillingExport
+}
+
+func newBillingTarget() *billingTarget {
+	target := &billingTarget{status: http.StatusOK}
+	target.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target.Lock()
+		defer target.Unlock()
+
+		var export BillingExport
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &export)
+		target.requests = append(target.requests, r)
+		target.exports = append(target.exports, export)
+		w.WriteHeader(target.status)
+	}))
+	return target
+}
+
+func TestBillingExport(t *testing.T) {
+	target := newBillingTarget()
+	defer target.server.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.BillingExport = config.BillingExportConfig{
+			Enabled: true,
+			Period:  60,
+			URL:     target.server.URL,
+			Headers: map[string]string{"Authorization": "Bearer billing"},
+		}
+	})
+	defer ts.Close()
+
+	spec := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "billed"
+		spec.OrgID = "org"
+		spec.UseKeylessAccess = false
+		spec.DoNotTrack = true
+		spec.Proxy.ListenPath = "/"
+	})[0]
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{spec.APIID: {APIID: spec.APIID}}
+	})
+	authorization := map[string]string{"Authorization": key}
+
+	// the billing keys of other tests would end up in the exports
+	store := ts.Gw.billingStore()
+	store.DeleteScanMatch(billingKeyPrefix + "*")
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: authorization, Code: http.StatusOK},
+		{Path: "/", Headers: authorization, Code: http.StatusOK},
+		{Path: "/errors/404", Headers: authorization, Code: http.StatusNotFound},
+		{Path: "/", Code: http.StatusUnauthorized},
+	}...)
+
+	afterPeriod := time.Now().Add(2 * time.Minute)
+
+	t.Run("current period isn't exported", func(t *testing.T) {
+		ts.Gw.exportBillingUsage(context.Background(), time.Now())
+		assert.Empty(t, target.exports)
+	})
+
+	t.Run("failed delivery is retried", func(t *testing.T) {
+		target.status = http.StatusServiceUnavailable
+		ts.Gw.exportBillingUsage(context.Background(), afterPeriod)
+		require.Len(t, target.exports, 1)
+
+		// retries are spaced out
+		ts.Gw.exportBillingUsage(context.Background(), afterPeriod)
+		require.Len(t, target.exports, 1)
+
+		deliveries := billingDeliveries(t, ts)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.False(t, deliveries[0].Delivered)
+		assert.Contains(t, deliveries[0].LastError, "503")
+	})
+
+	t.Run("delivery", func(t *testing.T) {
+		store.DeleteScanMatch(billingKeyPrefix + "lock:*")
+		target.status = http.StatusOK
+		ts.Gw.exportBillingUsage(context.Background(), afterPeriod)
+		require.Len(t, target.exports, 2)
+
+		req, export := target.requests[1], target.exports[1]
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "Bearer billing", req.Header.Get("Authorization"))
+		assert.Equal(t, export.IdempotencyKey, req.Header.Get(idempotencyKeyHeader))
+		assert.Equal(t, target.exports[0].IdempotencyKey, export.IdempotencyKey, "retries should keep the idempotency key")
+		assert.Equal(t, time.Minute, export.PeriodEnd.Sub(export.PeriodStart))
+		assert.Equal(t, []BillingUsageRecord{{OrgID: "org", APIID: "billed", KeyID: key, Requests: 3, Errors: 1}}, export.Usage)
+
+		deliveries := billingDeliveries(t, ts)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.True(t, deliveries[0].Delivered)
+		assert.Empty(t, deliveries[0].LastError)
+		assert.Equal(t, 1, deliveries[0].Records)
+
+		// delivered usage isn't exported again
+		store.DeleteScanMatch(billingKeyPrefix + "lock:*")
+		ts.Gw.exportBillingUsage(context.Background(), afterPeriod)
+		assert.Len(t, target.exports, 2)
+	})
+}
+
+func TestBillingExportObjectStorage(t *testing.T) {
+	target := newBillingTarget()
+	defer target.server.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.BillingExport = config.BillingExportConfig{
+			Enabled:      true,
+			Target:       billingTargetObjectStorage,
+			URL:          target.server.URL + "/bucket/",
+			ObjectPrefix: "usage/",
+		}
+	})
+	defer ts.Close()
+
+	store := ts.Gw.billingStore()
+	store.DeleteScanMatch(billingKeyPrefix + "*")
+
+	start := time.Now().Add(-2*time.Hour).Unix() / 3600 * 3600
+	store.IncrememntWithExpire(billingKeyPrefix+"usage:"+strconv.FormatInt(start, 10)+":org:api:requests:key", 0)
+
+	ts.Gw.exportBillingUsage(context.Background(), time.Now())
+	require.Len(t, target.requests, 1)
+
+	req := target.requests[0]
+	assert.Equal(t, http.MethodPut, req.Method)
+	assert.Equal(t, "/bucket/usage/"+time.Unix(start, 0).UTC().Format(time.RFC3339)+".json", req.URL.Path)
+	assert.NotEmpty(t, req.Header.Get(idempotencyKeyHeader))
+	assert.Equal(t, []BillingUsageRecord{{OrgID: "org", APIID: "api", KeyID: "key", Requests: 1}}, target.exports[0].Usage)
+}
+
+func billingDeliveries(t *testing.T, ts *Test) []BillingDelivery {
+	resp, err := ts.Run(t, test.TestCase{Path: "/tyk/billing/deliveries", AdminAuth: true, Code: http.StatusOK})
+	require.NoError(t, err)
+
+	var deliveries []BillingDelivery
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deliveries))
+	return deliveries
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/ctx"
+)
+
+const (
+	errorReasonBodySizeExceeded = "body_size_exceeded"
+	msgBodySizeExceeded         = "Request body is too large"
+)
+
+var errBodySizeExceeded = errors.New("request body is too large")
+
+// limitedBody fails reading the request body past the limit, so bodies of unknown or understated
+// size are never read whole.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodySizeExceeded
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		return int(b.remaining), errBodySizeExceeded
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// requestBodyLimit returns the maximum size of the request bodies of the API, 0 if unlimited.
+func requestBodyLimit(spec *APISpec) int64 {
+	if spec.MaxRequestBodySize != 0 {
+		return spec.MaxRequestBodySize
+	}
+	return spec.GlobalConfig.HttpServerOptions.MaxRequestBodySize
+}
+
+// bodySizeLimitHandler rejects the requests with a body larger than the limit of the API with 413,
+// before any middleware. The requests stating a larger Content-Length are rejected right away, the
+// bodies of unknown size are read up front so that the middleware reading them never fail past the
+// limit. Only the streamed multipart uploads fail to be read past the limit, by the reverse proxy.
+func (gw *Gateway) bodySizeLimitHandler(base BaseMiddleware, next http.Handler) http.Handler {
+	limit := requestBodyLimit(base.Spec)
+	if limit <= 0 {
+		return next
+	}
+
+	handler := ErrorHandler{base}
+	reject := func(w http.ResponseWriter, r *http.Request, size int64) {
+		base.Logger().WithFields(logrus.Fields{
+			"size":  size,
+			"limit": limit,
+		}).Info("Attempted access with large request body, blocked.")
+		ctxSetErrorReason(r, errorReasonBodySizeExceeded)
+		handler.HandleError(w, r, msgBodySizeExceeded, http.StatusRequestEntityTooLarge, true)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			reject(w, r, r.ContentLength)
+			return
+		}
+
+		switch r.Body.(type) {
+		case nil, nopCloser:
+			// buffered by the proxy muxer, its size is the Content-Length
+		default:
+			if r.Body == http.NoBody {
+				break
+			}
+			body := &limitedBody{ReadCloser: r.Body, remaining: limit}
+			if _, ok := multipartBoundary(r); ok && base.Spec.streamsMultipart() {
+				r.Body = body
+				setCtxValue(r, ctx.RequestBodyLimit, body)
+				break
+			}
+
+			data, err := ioutil.ReadAll(body)
+			r.Body.Close()
+			if errors.Is(err, errBodySizeExceeded) {
+				reject(w, r, -1)
+				return
+			}
+			if err != nil {
+				base.Logger().WithError(err).Error("Failed to read the request body")
+				handler.HandleError(w, r, "Failed to read the request body", http.StatusBadRequest, true)
+				return
+			}
+			r.Body = nopCloser{bytes.NewReader(data)}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestBodySizeExceeded returns whether reading the body of r failed because it is larger than
+// the limit of the API, in which case the request should be rejected with 413. It sets the error
+// reason of r if so.
+func requestBodySizeExceeded(r *http.Request, err error) bool {
+	body, _ := r.Context().Value(ctx.RequestBodyLimit).(*limitedBody)
+	if !errors.Is(err, errBodySizeExceeded) && (body == nil || !body.exceeded) {
+		return false
+	}
+
+	ctxSetErrorReason(r, errorReasonBodySizeExceeded)
+	return true
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestLimitedBody(t *testing.T) {
+	body := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("12345")), remaining: 5}
+	data, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", string(data))
+	assert.False(t, body.exceeded)
+
+	body = &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("123456")), remaining: 5}
+	data, err = ioutil.ReadAll(body)
+	assert.Equal(t, errBodySizeExceeded, err)
+	assert.Equal(t, "12345", string(data))
+	assert.True(t, body.exceeded)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.False(t, requestBodySizeExceeded(r, io.ErrUnexpectedEOF))
+	assert.True(t, requestBodySizeExceeded(r, errBodySizeExceeded))
+}
+
+func TestBodySizeLimit(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HttpServerOptions.MaxRequestBodySize = 10
+		globalConf.HttpServerOptions.MaxHeaderBytes = 1 << 10
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "global-limit"
+		spec.Proxy.ListenPath = "/global/"
+		spec.UseKeylessAccess = false
+	}, func(spec *APISpec) {
+		spec.APIID = "api-limit"
+		spec.Proxy.ListenPath = "/api/"
+		spec.MaxRequestBodySize = 20
+	}, func(spec *APISpec) {
+		spec.APIID = "validate-json"
+		spec.Proxy.ListenPath = "/validate/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.ExtendedPaths.ValidateJSON = []apidef.ValidatePathMeta{{
+				Method: http.MethodPost,
+				Path:   "/",
+				Schema: map[string]interface{}{"type": "object"},
+			}}
+		})
+	}, func(spec *APISpec) {
+		spec.APIID = "unlimited"
+		spec.Proxy.ListenPath = "/unlimited/"
+		spec.MaxRequestBodySize = -1
+	})
+
+	large := strings.Repeat("a", 15)
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/global/", Data: large, Code: http.StatusRequestEntityTooLarge},
+		{Method: http.MethodPost, Path: "/global/", Data: "small", Code: http.StatusUnauthorized},
+		{Method: http.MethodPost, Path: "/api/", Data: large, Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/", Data: large + large, Code: http.StatusRequestEntityTooLarge},
+		{Method: http.MethodPost, Path: "/unlimited/", Data: large + large, Code: http.StatusOK},
+		{Path: "/api/", Headers: map[string]string{"X-Large": strings.Repeat("a", 8<<10)},
+			Code: http.StatusRequestHeaderFieldsTooLarge},
+	}...)
+
+	t.Run("streamed body", func(t *testing.T) {
+		for _, path := range []string{"/api/", "/validate/"} {
+			body := `{"a":"` + large + large + `"}`
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+path, ioutil.NopCloser(strings.NewReader(body)))
+			req.ContentLength = -1
+
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, path)
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/validate/", ioutil.NopCloser(strings.NewReader(`{"a":1}`)))
+		req.ContentLength = -1
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "bodies within the limit must still be read")
+	})
+}
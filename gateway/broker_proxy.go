@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/tcp"
+)
+
+var (
+	errBrokerMissingKey   = errors.New("Authorization field missing")
+	errBrokerKeyNotFound  = errors.New("Key not authorised")
+	errBrokerKeyExpired   = errors.New("Key has expired, please renew")
+	errBrokerAPIForbidden = errors.New("Access to this API has been disallowed")
+)
+
+// brokerModifier returns the modifier authenticating the sessions opened on MQTT and AMQP
+// services, nil for other protocols.
+func (gw *Gateway) brokerModifier(spec *APISpec) *tcp.Modifier {
+	switch spec.Protocol {
+	case "mqtt", "mqtts":
+		return &tcp.Modifier{Handshake: tcp.MQTTHandshake(gw.brokerAuthenticator(spec))}
+	case "amqp", "amqps":
+		return &tcp.Modifier{Handshake: tcp.AMQPHandshake(gw.brokerAuthenticator(spec))}
+	}
+	return nil
+}
+
+// brokerAuthenticator authenticates broker sessions with the key or client certificate of the
+// client and opens the upstream session with the credentials configured for the API.
+func (gw *Gateway) brokerAuthenticator(spec *APISpec) tcp.Authenticator {
+	return func(conn net.Conn, creds tcp.Credentials) (tcp.Credentials, error) {
+		if err := gw.authenticateBrokerSession(spec, conn, creds); err != nil {
+			log.WithFields(logrus.Fields{
+				"prefix": "broker",
+				"api_id": spec.APIID,
+				"origin": conn.RemoteAddr().String(),
+			}).WithError(err).Warning("Rejected broker session")
+			return creds, err
+		}
+
+		if spec.Broker.UpstreamUsername != "" || spec.Broker.UpstreamPassword != "" {
+			return tcp.Credentials{
+				Username: spec.Broker.UpstreamUsername,
+				Password: spec.Broker.UpstreamPassword,
+			}, nil
+		}
+		return creds, nil
+	}
+}
+
+func (gw *Gateway) authenticateBrokerSession(spec *APISpec, conn net.Conn, creds tcp.Credentials) error {
+	if spec.UseKeylessAccess {
+		return nil
+	}
+
+	// the session and certificate checks are shared with HTTP APIs, which expect a request
+	r, _ := http.NewRequest(http.MethodConnect, "/", nil)
+	r.RemoteAddr = conn.RemoteAddr().String()
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		r.TLS = &state
+	}
+
+	if spec.UseMutualTLSAuth {
+		certIDs := append(spec.ClientCertificates, spec.GlobalConfig.Security.Certificates.API...)
+		return gw.CertificateManager.ValidateRequestCertificate(certIDs, r)
+	}
+
+	key := creds.Password
+	if key == "" {
+		key = creds.Username
+	}
+	if key == "" {
+		return errBrokerMissingKey
+	}
+
+	base := BaseMiddleware{Spec: spec, Gw: gw}
+	session, found := base.CheckSessionAndIdentityForValidKey(key, r)
+	if !found || session.IsInactive {
+		return errBrokerKeyNotFound
+	}
+	if spec.AuthManager.KeyExpired(&session) {
+		return errBrokerKeyExpired
+	}
+	if len(session.AccessRights) > 0 {
+		if _, ok := session.AccessRights[spec.APIID]; !ok {
+			return errBrokerAPIForbidden
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+// mqttConnectPacket returns an MQTT 3.1.1 CONNECT packet with a username and password.
+func mqttConnectPacket(username, password string) []byte {
+	body := append(mqttString("MQTT"), 4, 0xC2, 0, 60)
+	body = append(body, mqttString("client-1")...)
+	body = append(body, mqttString(username)...)
+	body = append(body, mqttString(password)...)
+	// remaining length, encoded on two bytes as keys are longer than 127 bytes
+	return append([]byte{0x10, byte(len(body)&0x7f) | 0x80, byte(len(body) >> 7)}, body...)
+}
+
+func TestMQTTBrokerProxy(t *testing.T) {
+	// the broker only accepts the upstream credentials configured for the API
+	upstreamCreds := append(mqttString("broker"), mqttString("secret")...)
+	broker, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer broker.Close()
+	go func() {
+		for {
+			conn, err := broker.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 1024)
+			n, _ := conn.Read(buf)
+			if bytes.HasSuffix(buf[:n], upstreamCreds) {
+				conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+			} else {
+				conn.Write([]byte{0x20, 0x02, 0x00, 0x04})
+			}
+			conn.Close()
+		}
+	}()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxyAddr := l.Addr().String()
+	_, portS, _ := net.SplitHostPort(proxyAddr)
+	port, _ := strconv.Atoi(portS)
+	l.Close()
+	ts.EnablePort(port, "mqtt")
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "mqtt"
+		spec.Protocol = "mqtt"
+		spec.ListenPort = port
+		spec.UseKeylessAccess = false
+		spec.Proxy.TargetURL = "mqtt://" + broker.Addr().String()
+		spec.Broker = apidef.BrokerProxyConfig{UpstreamUsername: "broker", UpstreamPassword: "secret"}
+	})
+
+	key := CreateSession(ts.Gw, func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"mqtt": {APIID: "mqtt"}}
+	})
+	otherKey := CreateSession(ts.Gw, func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"other": {APIID: "other"}}
+	})
+
+	connack := func(t *testing.T, username, password string) []byte {
+		t.Helper()
+		conn, err := net.Dial("tcp", proxyAddr)
+		require.NoError(t, err)
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		_, err = conn.Write(mqttConnectPacket(username, password))
+		require.NoError(t, err)
+		resp := make([]byte, 4)
+		n, _ := conn.Read(resp)
+		return resp[:n]
+	}
+
+	accepted := []byte{0x20, 0x02, 0x00, 0x00}
+	notAuthorized := []byte{0x20, 0x02, 0x00, 0x05}
+
+	assert.Equal(t, accepted, connack(t, "device-1", key), "key as password")
+	assert.Equal(t, accepted, connack(t, key, ""), "key as username")
+	assert.Equal(t, notAuthorized, connack(t, "device-1", "invalid"))
+	assert.Equal(t, notAuthorized, connack(t, "device-1", otherKey), "key without access to the API")
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+var errCanaryWeight = errors.New("canary weight must be between 0 and 100")
+
+// CanaryRouter decides, per request, whether traffic should go to the canary
+// upstream of an API. Its configuration can be changed at runtime without
+// reloading the API.
+type CanaryRouter struct {
+	mu     sync.RWMutex
+	conf   apidef.CanaryConfig
+	target *url.URL
+}
+
+// NewCanaryRouter creates a router from the canary section of an API definition.
+func NewCanaryRouter(conf apidef.CanaryConfig) (*CanaryRouter, error) {
+	c := &CanaryRouter{}
+	if err := c.Update(conf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update replaces the canary configuration.
+func (c *CanaryRouter) Update(conf apidef.CanaryConfig) error {
+	if conf.Weight < 0 || conf.Weight > 100 {
+		return errCanaryWeight
+	}
+
+	var target *url.URL
+	if conf.TargetURL != "" {
+		var err error
+		if target, err = url.Parse(conf.TargetURL); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.conf = conf
+	c.target = target
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Config returns the current canary configuration.
+func (c *CanaryRouter) Config() apidef.CanaryConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conf
+}
+
+// Target returns the canary upstream if r should be routed to it, nil otherwise.
+func (c *CanaryRouter) Target(r *http.Request) *url.URL {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.conf.Enabled || c.target == nil {
+		return nil
+	}
+
+	if c.conf.HeaderName != "" {
+		if value := r.Header.Get(c.conf.HeaderName); value != "" &&
+			(c.conf.HeaderValue == "" || value == c.conf.HeaderValue) {
+			return c.target
+		}
+	}
+
+	if c.conf.CookieName != "" {
+		if cookie, err := r.Cookie(c.conf.CookieName); err == nil &&
+			(c.conf.CookieValue == "" || cookie.Value == c.conf.CookieValue) {
+			return c.target
+		}
+	}
+
+	if c.conf.Weight > 0 && rand.Intn(100) < c.conf.Weight {
+		return c.target
+	}
+
+	return nil
+}
+
+func (gw *Gateway) canaryHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil || spec.Canary == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		doJSONWrite(w, http.StatusOK, spec.Canary.Config())
+		return
+	}
+
+	conf := spec.Canary.Config()
+	if err := json.NewDecoder(r.Body).Decode(&conf); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	if err := spec.Canary.Update(conf); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+		"api_id": apiID,
+		"weight": conf.Weight,
+	}).Info("Canary configuration updated")
+
+	doJSONWrite(w, http.StatusOK, conf)
+}
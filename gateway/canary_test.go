@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestCanaryRouting(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "canary"
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = TestHttpAny + "/stable"
+		spec.Proxy.Canary = apidef.CanaryConfig{
+			Enabled:     true,
+			TargetURL:   TestHttpAny + "/canary",
+			HeaderName:  "X-Canary",
+			HeaderValue: "yes",
+			CookieName:  "canary",
+		}
+	})
+
+	t.Run("no weight", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/stable"`},
+			{Path: "/", Headers: map[string]string{"X-Canary": "no"}, Code: http.StatusOK, BodyMatch: `"Url":"/stable"`},
+			{Path: "/", Headers: map[string]string{"X-Canary": "yes"}, Code: http.StatusOK, BodyMatch: `"Url":"/canary"`},
+			{Path: "/", Cookies: []*http.Cookie{{Name: "canary", Value: "1"}}, Code: http.StatusOK, BodyMatch: `"Url":"/canary"`},
+			// canary choice must not stick to subsequent requests
+			{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/stable"`},
+		}...)
+	})
+
+	t.Run("update weight at runtime", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPut, Path: "/tyk/apis/canary/canary", AdminAuth: true, Data: `{"weight":100}`, Code: http.StatusOK, BodyMatch: `"weight":100`},
+			{Method: http.MethodGet, Path: "/tyk/apis/canary/canary", AdminAuth: true, Code: http.StatusOK, BodyMatch: `"header_name":"X-Canary"`},
+			{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/canary"`},
+			{Method: http.MethodPut, Path: "/tyk/apis/canary/canary", AdminAuth: true, Data: `{"weight":0}`, Code: http.StatusOK},
+			{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/stable"`},
+		}...)
+	})
+
+	t.Run("invalid update", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPut, Path: "/tyk/apis/canary/canary", AdminAuth: true, Data: `{"weight":101}`, Code: http.StatusBadRequest},
+			{Method: http.MethodPut, Path: "/tyk/apis/canary/canary", AdminAuth: true, Data: `{`, Code: http.StatusBadRequest},
+			{Method: http.MethodGet, Path: "/tyk/apis/unknown/canary", AdminAuth: true, Code: http.StatusNotFound},
+		}...)
+	})
+}
+
+func TestCanaryRouter_Target(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+
+	var nilRouter *CanaryRouter
+	assert.Nil(t, nilRouter.Target(r))
+
+	router, err := NewCanaryRouter(apidef.CanaryConfig{TargetURL: "http://canary", Weight: 100})
+	assert.NoError(t, err)
+	assert.Nil(t, router.Target(r), "disabled router should not route")
+
+	assert.NoError(t, router.Update(apidef.CanaryConfig{Enabled: true, TargetURL: "http://canary", Weight: 100}))
+	assert.Equal(t, "canary", router.Target(r).Host)
+
+	_, err = NewCanaryRouter(apidef.CanaryConfig{Weight: -1})
+	assert.Equal(t, errCanaryWeight, err)
+}
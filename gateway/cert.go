@@ -19,6 +19,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pmylund/go-cache"
+	"golang.org/x/crypto/acme"
 )
 
 type APICertificateStatusMessage struct {
@@ -406,6 +407,11 @@ func (gw *Gateway) getTLSConfigForClient(baseConfig *tls.Config, listenPort int)
 			}
 		}
 
+		if gw.acme != nil {
+			newConfig.GetCertificate = gw.acme.getCertificate(newConfig.NameToCertificate)
+			newConfig.NextProtos = append(append([]string{}, newConfig.NextProtos...), acme.ALPNProto)
+		}
+
 		newConfig.ClientAuth = tls.NoClientCert
 
 		for key, clientAuth := range domainRequireCert {
@@ -420,12 +426,90 @@ func (gw *Gateway) getTLSConfigForClient(baseConfig *tls.Config, listenPort int)
 			newConfig.ClientAuth = domainRequireCert[""]
 		}
 
+		if policy := domainTLSPolicy(gwConfig.HttpServerOptions.DomainTLSPolicies, hello.ServerName); policy != nil {
+			gw.applyDomainTLSPolicy(newConfig, policy)
+		}
+
 		// Cache the config
 		tlsConfigCache.Set(hello.ServerName+listenPortStr, newConfig, cache.DefaultExpiration)
 		return newConfig, nil
 	}
 }
 
+// tlsClientAuthTypes are the client certificate requirements of the domain TLS policies.
+var tlsClientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// domainTLSPolicy returns the first policy with a domain matching serverName, nil if none does.
+func domainTLSPolicy(policies []config.DomainTLSPolicy, serverName string) *config.DomainTLSPolicy {
+	serverName = strings.ToLower(serverName)
+	for i := range policies {
+		for _, domain := range policies[i].Domains {
+			domain = strings.ToLower(domain)
+			if domain == serverName {
+				return &policies[i]
+			}
+			// wildcards match a single label
+			if strings.HasPrefix(domain, "*.") {
+				label := strings.TrimSuffix(serverName, domain[1:])
+				if label != serverName && label != "" && !strings.Contains(label, ".") {
+					return &policies[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyDomainTLSPolicy overrides the settings of conf with those set by policy.
+func (gw *Gateway) applyDomainTLSPolicy(conf *tls.Config, policy *config.DomainTLSPolicy) {
+	if policy.MinVersion != 0 {
+		conf.MinVersion = policy.MinVersion
+	}
+	if policy.MaxVersion != 0 {
+		conf.MaxVersion = policy.MaxVersion
+	}
+	if conf.MaxVersion != 0 && conf.MinVersion > conf.MaxVersion {
+		conf.MaxVersion = conf.MinVersion
+	}
+
+	if len(policy.Ciphers) > 0 {
+		conf.CipherSuites = getCipherAliases(policy.Ciphers)
+	}
+
+	if len(policy.ALPN) > 0 {
+		conf.NextProtos = append([]string{}, policy.ALPN...)
+		if gw.acme != nil {
+			conf.NextProtos = append(conf.NextProtos, acme.ALPNProto)
+		}
+	}
+
+	if policy.ClientAuth != "" {
+		clientAuth, ok := tlsClientAuthTypes[policy.ClientAuth]
+		if !ok {
+			log.WithField("client_auth", policy.ClientAuth).Warning("Unknown client auth of domain TLS policy, ignoring")
+		} else {
+			conf.ClientAuth = clientAuth
+		}
+	}
+
+	if len(policy.ClientCertificates) > 0 {
+		if conf.ClientCAs == nil {
+			conf.ClientCAs = x509.NewCertPool()
+		}
+		for _, cert := range gw.CertificateManager.List(policy.ClientCertificates, certs.CertificatePublic) {
+			if cert != nil {
+				conf.ClientCAs.AddCert(cert.Leaf)
+			}
+		}
+	}
+}
+
 func (gw *Gateway) certHandler(w http.ResponseWriter, r *http.Request) {
 	certID := mux.Vars(r)["certID"]
 
@@ -450,6 +534,20 @@ func (gw *Gateway) certHandler(w http.ResponseWriter, r *http.Request) {
 			orgID := r.URL.Query().Get("org_id")
 
 			certIds := gw.CertificateManager.ListAllIds(orgID)
+			if r.URL.Query().Get("mode") == "detailed" {
+				expiry := make([]*certs.CertificateExpiry, 0, len(certIds))
+				now := time.Now()
+				for i, cert := range gw.CertificateManager.List(certIds, certs.CertificateAny) {
+					if cert == nil {
+						continue
+					}
+					if e := certs.ExtractCertificateExpiry(cert, certIds[i], now); e != nil {
+						expiry = append(expiry, e)
+					}
+				}
+				doJSONWrite(w, http.StatusOK, &APIAllCertificateExpiry{expiry})
+				return
+			}
 			doJSONWrite(w, http.StatusOK, &APIAllCertificates{certIds})
 			return
 		}
@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	defaultCertExpiryWarningDays   = 30
+	defaultCertExpiryCheckInterval = 60 * 60
+	defaultCertExpiryEventCooldown = 24 * 60 * 60
+	// certExpiryInitialDelay lets the APIs load before the first scan, to know how certificates are used.
+	certExpiryInitialDelay = time.Minute
+	// certExpiryKeyPrefix prefixes the cooldowns of the expiry events of each certificate.
+	certExpiryKeyPrefix = "cert-expiry."
+)
+
+// APIAllCertificateExpiry is the detailed listing of the certificates, without the public keys
+// which don't expire.
+type APIAllCertificateExpiry struct {
+	Certs []*certs.CertificateExpiry `json:"certs"`
+}
+
+// certificateUsage is how a certificate is used by the gateway and its APIs.
+type certificateUsage struct {
+	usages []string
+	specs  []*APISpec
+}
+
+func (u *certificateUsage) add(usage string, spec *APISpec) {
+	if !contains(u.usages, usage) {
+		u.usages = append(u.usages, usage)
+	}
+	if spec == nil {
+		return
+	}
+	for _, s := range u.specs {
+		if s == spec {
+			return
+		}
+	}
+	u.specs = append(u.specs, spec)
+}
+
+// certificateUsages returns how the certificates are used by the gateway configuration and the
+// loaded APIs, by certificate ID.
+func (gw *Gateway) certificateUsages() map[string]*certificateUsage {
+	usages := map[string]*certificateUsage{}
+	add := func(id, usage string, spec *APISpec) {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return
+		}
+		if usages[id] == nil {
+			usages[id] = &certificateUsage{}
+		}
+		usages[id].add(usage, spec)
+	}
+	addPinned := func(pinned map[string]string, spec *APISpec) {
+		for _, ids := range pinned {
+			for _, id := range strings.Split(ids, ",") {
+				add(id, "pinned", spec)
+			}
+		}
+	}
+
+	conf := gw.GetConfig()
+	for _, id := range conf.HttpServerOptions.SSLCertificates {
+		add(id, "server", nil)
+	}
+	for _, id := range conf.Security.Certificates.API {
+		add(id, "server", nil)
+	}
+	for _, id := range conf.Security.Certificates.ControlAPI {
+		add(id, "client", nil)
+	}
+	for _, id := range conf.Security.Certificates.Upstream {
+		add(id, "upstream", nil)
+	}
+	addPinned(conf.Security.PinnedPublicKeys, nil)
+
+	gw.apisMu.RLock()
+	defer gw.apisMu.RUnlock()
+	for _, spec := range gw.apisByID {
+		for _, id := range spec.Certificates {
+			add(id, "server", spec)
+		}
+		for _, id := range spec.ClientCertificates {
+			add(id, "client", spec)
+		}
+		for _, id := range spec.UpstreamCertificates {
+			add(id, "upstream", spec)
+		}
+		addPinned(spec.PinnedPublicKeys, spec)
+	}
+
+	return usages
+}
+
+// checkCertificateExpiry fires an event for each certificate which expired or expires within the
+// warning threshold, once per cooldown across the gateways sharing store.
+func (gw *Gateway) checkCertificateExpiry(store storage.Handler, now time.Time) {
+	conf := gw.GetConfig().Security.CertificateExpiryMonitor
+	days := conf.WarningThresholdDays
+	if days <= 0 {
+		days = defaultCertExpiryWarningDays
+	}
+	cooldown := conf.EventCooldown
+	if cooldown <= 0 {
+		cooldown = defaultCertExpiryEventCooldown
+	}
+
+	usages := gw.certificateUsages()
+	ids := gw.CertificateManager.ListAllIds("")
+	for id := range usages {
+		if !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	deadline := now.Add(time.Duration(days) * 24 * time.Hour)
+	for _, expiry := range gw.CertificateManager.ExpiringCertificates(ids, now, deadline) {
+		event := EventCertificateExpiring
+		message := fmt.Sprintf("Certificate %s expires in %d days", expiry.ID, expiry.DaysToExpiry)
+		if expiry.NotAfter.Before(now) {
+			event = EventCertificateExpired
+			message = fmt.Sprintf("Certificate %s expired", expiry.ID)
+		}
+
+		// the store doesn't prefix the keys it increments
+		if store.IncrememntWithExpire(certExpiryKeyPrefix+string(event)+"."+expiry.ID, cooldown) != 1 {
+			continue
+		}
+
+		meta := EventCertificateExpiryMeta{
+			EventMetaDefault: EventMetaDefault{Message: message},
+			CertID:           expiry.ID,
+			CommonName:       expiry.CommonName,
+			NotAfter:         expiry.NotAfter,
+			DaysToExpiry:     expiry.DaysToExpiry,
+		}
+		usage := usages[expiry.ID]
+		if usage != nil {
+			meta.Usages = usage.usages
+			for _, spec := range usage.specs {
+				meta.APIIDs = append(meta.APIIDs, spec.APIID)
+			}
+		}
+
+		log.WithFields(logrus.Fields{
+			"prefix":    "certs",
+			"cert_id":   expiry.ID,
+			"not_after": expiry.NotAfter,
+			"usages":    meta.Usages,
+			"api_ids":   meta.APIIDs,
+		}).Warning(message)
+
+		gw.FireSystemEvent(event, meta)
+		if usage != nil {
+			for _, spec := range usage.specs {
+				spec.FireEvent(event, meta)
+			}
+		}
+	}
+}
+
+// certificateExpiryLoop scans the certificates for expiry until ctx is done.
+func (gw *Gateway) certificateExpiryLoop(ctx context.Context) {
+	store := &storage.RedisCluster{KeyPrefix: certExpiryKeyPrefix, RedisController: gw.RedisController}
+	store.Connect()
+
+	wait := certExpiryInitialDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		gw.checkCertificateExpiry(store, time.Now())
+
+		interval := gw.GetConfig().Security.CertificateExpiryMonitor.CheckInterval
+		if interval <= 0 {
+			interval = defaultCertExpiryCheckInterval
+		}
+		wait = time.Duration(interval) * time.Second
+	}
+}
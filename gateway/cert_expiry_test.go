@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestCertificateExpiryMonitor(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	// generated certificates expire in an hour
+	clientCertPem, _, _, _ := certs.GenCertificate(&x509.Certificate{})
+	clientCertID, err := ts.Gw.CertificateManager.Add(clientCertPem, "")
+	require.NoError(t, err)
+	defer ts.Gw.CertificateManager.Delete(clientCertID, "")
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "expiring"
+		spec.UseMutualTLSAuth = true
+		spec.ClientCertificates = []string{clientCertID}
+	})
+
+	events := make(chan config.EventMessage, 2)
+	spec := ts.Gw.getApiSpec("expiring")
+	spec.EventPaths = map[apidef.TykEvent][]config.TykEventHandler{
+		EventCertificateExpiring: {&testEventHandler{func(em config.EventMessage) { events <- em }}},
+	}
+
+	store := &storage.RedisCluster{KeyPrefix: certExpiryKeyPrefix, RedisController: ts.Gw.RedisController}
+	store.Connect()
+	defer store.DeleteScanMatch("*")
+
+	now := time.Now()
+	ts.Gw.checkCertificateExpiry(store, now)
+
+	select {
+	case em := <-events:
+		meta, ok := em.Meta.(EventCertificateExpiryMeta)
+		require.True(t, ok)
+		assert.Equal(t, clientCertID, meta.CertID)
+		assert.Equal(t, 0, meta.DaysToExpiry)
+		assert.Equal(t, []string{"client"}, meta.Usages)
+		assert.Equal(t, []string{"expiring"}, meta.APIIDs)
+	case <-time.After(time.Second):
+		t.Fatal("expiry event wasn't fired")
+	}
+
+	t.Run("cooldown", func(t *testing.T) {
+		_, err := store.GetRawKey(certExpiryKeyPrefix + string(EventCertificateExpiring) + "." + clientCertID)
+		assert.NoError(t, err, "the cooldown should be prefixed")
+
+		ts.Gw.checkCertificateExpiry(store, now)
+		select {
+		case <-events:
+			t.Fatal("expiry event was fired again within the cooldown")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("detailed listing", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/certs?mode=detailed", AdminAuth: true, Code: http.StatusOK})
+		var listing APIAllCertificateExpiry
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&listing))
+		require.Len(t, listing.Certs, 1)
+		assert.Equal(t, clientCertID, listing.Certs[0].ID)
+		assert.WithinDuration(t, now.Add(time.Hour), listing.Certs[0].NotAfter, time.Minute)
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/tyk/certs", AdminAuth: true, Code: http.StatusOK,
+			BodyMatch: `"certs":\["` + clientCertID + `"\]`})
+	})
+}
@@ -1174,3 +1174,97 @@ func TestCipherSuites(t *testing.T) {
 		ts.Run(t, test.TestCase{Client: client, Path: "/", ErrorMatch: "tls: handshake failure"})
 	})
 }
+
+func TestDomainTLSPolicies(t *testing.T) {
+	_, _, combinedPEM, _ := certs.GenServerCertificate()
+	serverCertID, _, _ := certs.GetCertIDAndChainPEM(combinedPEM, "")
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HttpServerOptions.UseSSL = true
+		globalConf.HttpServerOptions.SSLCertificates = []string{serverCertID}
+		globalConf.HttpServerOptions.DomainTLSPolicies = []config.DomainTLSPolicy{
+			{Domains: []string{"strict.localhost"}, MinVersion: tls.VersionTLS12, ALPN: []string{"http/1.1"}},
+			{Domains: []string{"*.mtls.localhost"}, ClientAuth: "require"},
+		}
+	})
+	defer ts.Close()
+
+	serverCertID, _ = ts.Gw.CertificateManager.Add(combinedPEM, "")
+	defer ts.Gw.CertificateManager.Delete(serverCertID, "")
+	ts.ReloadGatewayProxy()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+	})
+
+	client := func(serverName string, maxVersion uint16) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true,
+			MaxVersion:         maxVersion,
+		}}}
+	}
+
+	t.Run("Min version", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Client: client("strict.localhost", tls.VersionTLS11), Path: "/", ErrorMatch: "tls: protocol version not supported"},
+			{Client: client("strict.localhost", tls.VersionTLS12), Path: "/", Code: http.StatusOK},
+			{Client: client("other.localhost", tls.VersionTLS11), Path: "/", Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("Client auth", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Client: client("api.mtls.localhost", tls.VersionTLS12), Path: "/", ErrorMatch: badcertErr},
+			{Client: client("mtls.localhost", tls.VersionTLS12), Path: "/", Code: http.StatusOK},
+		}...)
+	})
+}
+
+func TestDomainTLSPolicy(t *testing.T) {
+	policies := []config.DomainTLSPolicy{
+		{Domains: []string{"api.example.com"}, MinVersion: tls.VersionTLS12},
+		{Domains: []string{"*.example.com"}, MinVersion: tls.VersionTLS13},
+	}
+
+	for serverName, want := range map[string]*config.DomainTLSPolicy{
+		"api.example.com":   &policies[0],
+		"API.example.com":   &policies[0],
+		"www.example.com":   &policies[1],
+		"example.com":       nil,
+		"a.b.example.com":   nil,
+		"www.example.com.X": nil,
+		"":                  nil,
+	} {
+		if got := domainTLSPolicy(policies, serverName); got != want {
+			t.Errorf("domainTLSPolicy(%q) = %v, want %v", serverName, got, want)
+		}
+	}
+
+	gw := &Gateway{}
+	conf := &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12, NextProtos: []string{"h2", "http/1.1"}}
+	gw.applyDomainTLSPolicy(conf, &config.DomainTLSPolicy{
+		MinVersion: tls.VersionTLS13,
+		Ciphers:    []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		ALPN:       []string{"http/1.1"},
+		ClientAuth: "require_and_verify",
+	})
+	if conf.MinVersion != tls.VersionTLS13 || conf.MaxVersion != tls.VersionTLS13 {
+		t.Errorf("versions %x-%x, want TLS 1.3 only", conf.MinVersion, conf.MaxVersion)
+	}
+	if len(conf.CipherSuites) != 1 || conf.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("cipher suites %v", conf.CipherSuites)
+	}
+	if len(conf.NextProtos) != 1 || conf.NextProtos[0] != "http/1.1" {
+		t.Errorf("ALPN protocols %v", conf.NextProtos)
+	}
+	if conf.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("client auth %v", conf.ClientAuth)
+	}
+
+	// unknown requirements are ignored
+	gw.applyDomainTLSPolicy(conf, &config.DomainTLSPolicy{ClientAuth: "always"})
+	if conf.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("client auth %v", conf.ClientAuth)
+	}
+}
@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+const defaultCompressionMinSize = 1024
+
+var (
+	defaultCompressionAlgorithms   = []string{"br", "zstd", "gzip"}
+	defaultCompressionContentTypes = []string{"text/*", "application/json", "application/xml", "application/javascript", "image/svg+xml"}
+)
+
+type compressionEncoder interface {
+	io.WriteCloser
+	Reset(io.Writer)
+}
+
+// compressionEncoders pools the encoders of each algorithm, as they are expensive to allocate.
+var compressionEncoders = map[string]*sync.Pool{
+	"br": {New: func() interface{} {
+		return brotli.NewWriter(nil)
+	}},
+	"zstd": {New: func() interface{} {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// decompressReader returns a reader of the content of body encoded with encoding.
+func decompressReader(encoding string, body io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case "deflate":
+		return flate.NewReader(body), nil
+	case "br":
+		return ioutil.NopCloser(brotli.NewReader(body)), nil
+	case "zstd":
+		reader, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return reader.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// decodedBody reads the decoded content of a body, closing both on Close.
+type decodedBody struct {
+	io.ReadCloser
+	encoded io.Closer
+}
+
+func (b decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.encoded.Close()
+}
+
+// compressResponse negotiates the encoding of res with the client of req and compresses its body
+// on the fly. Responses already encoded in a way the client doesn't accept are decoded first.
+func compressResponse(conf apidef.CompressionConfig, req *http.Request, res *http.Response) {
+	switch {
+	case req.Method == http.MethodHead,
+		res.StatusCode < http.StatusOK,
+		res.StatusCode == http.StatusNoContent,
+		res.StatusCode == http.StatusPartialContent,
+		res.StatusCode == http.StatusNotModified,
+		strings.Contains(res.Header.Get(headers.CacheControl), "no-transform"),
+		!compressibleContentType(conf.ContentTypes, res.Header.Get(headers.ContentType)):
+		return
+	}
+
+	addVary(res.Header, headers.AcceptEncoding)
+	accepted := acceptedEncodings(req.Header.Get(headers.AcceptEncoding))
+
+	if current := strings.ToLower(res.Header.Get(headers.ContentEncoding)); current != "" && current != "identity" {
+		if encodingQuality(accepted, current) > 0 {
+			return
+		}
+		decoded, err := decompressReader(current, res.Body)
+		if err != nil {
+			log.WithError(err).Debug("Response encoding not accepted by the client can't be decoded")
+			return
+		}
+		res.Body = decodedBody{ReadCloser: decoded, encoded: res.Body}
+		res.Header.Del(headers.ContentEncoding)
+		res.Header.Del(headers.ContentLength)
+		res.ContentLength = -1
+	}
+
+	encoding := negotiateEncoding(accepted, conf.Algorithms)
+	if encoding == "" {
+		return
+	}
+
+	minSize := conf.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if res.ContentLength >= 0 {
+		if res.ContentLength < minSize {
+			return
+		}
+	} else {
+		// read ahead streamed bodies to know if they reach the minimum size
+		buffered := bufio.NewReaderSize(res.Body, int(minSize))
+		_, err := buffered.Peek(int(minSize))
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{buffered, res.Body}
+		if err != nil {
+			return
+		}
+	}
+
+	res.Body = convertStream(res.Body, compressStream(encoding))
+	res.Header.Set(headers.ContentEncoding, encoding)
+	res.Header.Del(headers.ContentLength)
+	res.ContentLength = -1
+}
+
+func compressStream(encoding string) bodyConverter {
+	return func(dst io.Writer, src io.Reader) error {
+		pool := compressionEncoders[encoding]
+		enc := pool.Get().(compressionEncoder)
+		enc.Reset(dst)
+
+		_, err := io.Copy(enc, src)
+		if closeErr := enc.Close(); err == nil {
+			err = closeErr
+		}
+		pool.Put(enc)
+
+		return err
+	}
+}
+
+// acceptedEncodings parses an Accept-Encoding header into the quality of each coding.
+func acceptedEncodings(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		accepted[coding] = quality
+	}
+	return accepted
+}
+
+// encodingQuality returns the quality of coding for the client, "*" applying to unlisted codings.
+func encodingQuality(accepted map[string]float64, coding string) float64 {
+	if quality, ok := accepted[coding]; ok {
+		return quality
+	}
+	return accepted["*"]
+}
+
+// negotiateEncoding returns the algorithm of highest quality for the client, the first in order of
+// preference on ties, or "" if the client accepts none.
+func negotiateEncoding(accepted map[string]float64, algorithms []string) string {
+	if len(algorithms) == 0 {
+		algorithms = defaultCompressionAlgorithms
+	}
+
+	var best string
+	var bestQuality float64
+	for _, algorithm := range algorithms {
+		if _, ok := compressionEncoders[algorithm]; !ok {
+			continue
+		}
+		if quality := encodingQuality(accepted, algorithm); quality > bestQuality {
+			best, bestQuality = algorithm, quality
+		}
+	}
+	return best
+}
+
+// compressibleContentType reports whether contentType matches one of types. Media types with a
+// structured syntax suffix, e.g. application/problem+json, also match the type of their suffix.
+// Event streams are never compressed, as events mustn't be held in the buffers of the encoder.
+func compressibleContentType(types []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	if len(types) == 0 {
+		types = defaultCompressionContentTypes
+	}
+
+	candidates := []string{mediaType}
+	if i := strings.LastIndex(mediaType, "+"); i != -1 {
+		candidates = append(candidates, mediaType[:strings.Index(mediaType, "/")+1]+mediaType[i+1:])
+	}
+
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		for _, candidate := range candidates {
+			if t == candidate || (strings.HasSuffix(t, "/*") && strings.HasPrefix(candidate, strings.TrimSuffix(t, "*"))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values(headers.Vary) {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	h.Add(headers.Vary, name)
+}
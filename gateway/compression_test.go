@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		accept     string
+		algorithms []string
+		want       string
+	}{
+		{accept: "", want: ""},
+		{accept: "identity", want: ""},
+		{accept: "gzip, deflate, br", want: "br"},
+		{accept: "gzip;q=0.5, zstd", want: "zstd"},
+		{accept: "GZIP", want: "gzip"},
+		{accept: "*", want: "br"},
+		{accept: "*;q=0.1, br;q=0", want: "zstd"},
+		{accept: "br, gzip", algorithms: []string{"gzip", "br"}, want: "gzip"},
+		{accept: "br", algorithms: []string{"gzip"}, want: ""},
+		{accept: "deflate", algorithms: []string{"deflate"}, want: ""},
+	} {
+		t.Run(tc.accept, func(t *testing.T) {
+			assert.Equal(t, tc.want, negotiateEncoding(acceptedEncodings(tc.accept), tc.algorithms))
+		})
+	}
+}
+
+func TestCompressibleContentType(t *testing.T) {
+	assert.True(t, compressibleContentType(nil, "application/json; charset=utf-8"))
+	assert.True(t, compressibleContentType(nil, "text/html"))
+	assert.True(t, compressibleContentType(nil, "application/problem+json"))
+	assert.False(t, compressibleContentType(nil, "image/png"))
+	assert.False(t, compressibleContentType(nil, "text/event-stream"))
+	assert.False(t, compressibleContentType(nil, ""))
+
+	assert.True(t, compressibleContentType([]string{"image/*"}, "image/png"))
+	assert.False(t, compressibleContentType([]string{"image/*"}, "application/json"))
+}
+
+func TestCompressBufferRoundTrip(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", "br", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			var in bytes.Buffer
+			in.WriteString("compressible compressible compressible")
+
+			out := compressBuffer(in, encoding)
+			reader, err := decompressReader(encoding, &out)
+			require.NoError(t, err)
+			plain, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, "compressible compressible compressible", string(plain))
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	largeBody := strings.Repeat(`{"field":"compressible value"}`, 100)
+
+	// the upstream answers with the size, content type and encoding of the query
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		body := largeBody[:size]
+
+		contentType := r.URL.Query().Get("type")
+		if contentType == "" {
+			contentType = headers.ApplicationJSON
+		}
+		w.Header().Set(headers.ContentType, contentType)
+
+		if r.URL.Query().Get("encoding") == "gzip" {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(body))
+			zw.Close()
+			body = buf.String()
+			w.Header().Set(headers.ContentEncoding, "gzip")
+		}
+		if r.URL.Query().Get("stream") == "" {
+			w.Header().Set(headers.ContentLength, strconv.Itoa(len(body)))
+		}
+		w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Compression = apidef.CompressionConfig{Enabled: true}
+	})
+
+	decoded := func(encoding string, size int) func([]byte) bool {
+		return func(body []byte) bool {
+			reader, err := decompressReader(encoding, bytes.NewReader(body))
+			if err != nil {
+				return false
+			}
+			plain, err := ioutil.ReadAll(reader)
+			return err == nil && string(plain) == largeBody[:size]
+		}
+	}
+	plain := func(size int) func([]byte) bool {
+		return func(body []byte) bool {
+			return string(body) == largeBody[:size]
+		}
+	}
+	acceptEncoding := func(value string) map[string]string {
+		return map[string]string{headers.AcceptEncoding: value}
+	}
+
+	t.Run("negotiated encoding", func(t *testing.T) {
+		for _, encoding := range []string{"br", "zstd", "gzip"} {
+			resp, _ := ts.Run(t, test.TestCase{
+				Path:          "/?size=3000",
+				Headers:       acceptEncoding(encoding + ", identity;q=0.5"),
+				Code:          http.StatusOK,
+				HeadersMatch:  map[string]string{headers.ContentEncoding: encoding, headers.Vary: headers.AcceptEncoding},
+				BodyMatchFunc: decoded(encoding, 3000),
+			})
+			assert.Empty(t, resp.Header.Get(headers.ContentLength))
+		}
+	})
+
+	t.Run("streamed body", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/?size=3000&stream=1", Headers: acceptEncoding("br"), Code: http.StatusOK,
+				HeadersMatch: map[string]string{headers.ContentEncoding: "br"}, BodyMatchFunc: decoded("br", 3000)},
+			{Path: "/?size=100&stream=1", Headers: acceptEncoding("br"), Code: http.StatusOK,
+				HeadersNotMatch: map[string]string{headers.ContentEncoding: "br"}, BodyMatchFunc: plain(100)},
+		}...)
+	})
+
+	t.Run("below minimum size", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Path:            "/?size=100",
+			Headers:         acceptEncoding("br"),
+			Code:            http.StatusOK,
+			HeadersNotMatch: map[string]string{headers.ContentEncoding: "br"},
+			BodyMatchFunc:   plain(100),
+		})
+	})
+
+	t.Run("content type not compressed", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Path:            "/?size=3000&type=image/png",
+			Headers:         acceptEncoding("br"),
+			Code:            http.StatusOK,
+			HeadersNotMatch: map[string]string{headers.ContentEncoding: "br"},
+			BodyMatchFunc:   plain(3000),
+		})
+	})
+
+	t.Run("upstream encoding accepted", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Path:          "/?size=3000&encoding=gzip",
+			Headers:       acceptEncoding("br, gzip"),
+			Code:          http.StatusOK,
+			HeadersMatch:  map[string]string{headers.ContentEncoding: "gzip"},
+			BodyMatchFunc: decoded("gzip", 3000),
+		})
+	})
+
+	t.Run("upstream encoding not accepted", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/?size=3000&encoding=gzip", Headers: acceptEncoding("zstd"), Code: http.StatusOK,
+				HeadersMatch: map[string]string{headers.ContentEncoding: "zstd"}, BodyMatchFunc: decoded("zstd", 3000)},
+			{Path: "/?size=3000&encoding=gzip", Headers: acceptEncoding("identity"), Code: http.StatusOK,
+				HeadersNotMatch: map[string]string{headers.ContentEncoding: "gzip"}, BodyMatchFunc: plain(3000)},
+		}...)
+	})
+
+	t.Run("decompressed for transforms", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.Compression = apidef.CompressionConfig{Enabled: true, Algorithms: []string{"zstd"}, MinSize: 10}
+			spec.ResponseProcessors = []apidef.ResponseProcessor{{Name: "response_body_transform"}}
+			UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+				v.UseExtendedPaths = true
+				v.ExtendedPaths.TransformResponse = []apidef.TemplateMeta{{
+					Path:   "/",
+					Method: http.MethodGet,
+					TemplateData: apidef.TemplateData{
+						Mode:           apidef.UseBlob,
+						TemplateSource: "eyJ0cmFuc2Zvcm1lZCI6dHJ1ZX0=", // {"transformed":true}
+					},
+				}}
+			})
+		})
+
+		_, _ = ts.Run(t, test.TestCase{
+			Path:         "/?size=3000&encoding=gzip",
+			Headers:      acceptEncoding("zstd"),
+			Code:         http.StatusOK,
+			HeadersMatch: map[string]string{headers.ContentEncoding: "zstd"},
+			BodyMatchFunc: func(body []byte) bool {
+				reader, err := decompressReader("zstd", bytes.NewReader(body))
+				if err != nil {
+					return false
+				}
+				plain, _ := ioutil.ReadAll(reader)
+				return string(plain) == `{"transformed":true}`
+			},
+		})
+	})
+}
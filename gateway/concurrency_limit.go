@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/ctx"
+)
+
+const concurrencyLimitedTag = "concurrency-limited"
+
+// concurrencyLimitHandler caps the requests to the API processed by next at the same time. The
+// requests over the limit wait for a slot up to the queue timeout, and are rejected with 503 after.
+func (gw *Gateway) concurrencyLimitHandler(base BaseMiddleware, next http.Handler) http.Handler {
+	limit := base.Spec.ConcurrencyLimit
+	if limit.MaxInFlight <= 0 {
+		return next
+	}
+
+	slots := make(chan struct{}, limit.MaxInFlight)
+	timeout := time.Duration(limit.QueueTimeout) * time.Millisecond
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acquireSlot(slots, timeout, r) {
+			base.Logger().WithField("path", r.URL.Path).Warning("API concurrency limit reached, request rejected.")
+			ctxSetConcurrencyLimited(r)
+			handler := ErrorHandler{base}
+			handler.HandleError(w, r, "API concurrency limit reached", http.StatusServiceUnavailable, true)
+			return
+		}
+		defer func() { <-slots }()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquireSlot takes a slot, waiting up to timeout for one to be released. It gives up when the
+// client goes away.
+func acquireSlot(slots chan struct{}, timeout time.Duration, r *http.Request) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if timeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+	return false
+}
+
+func ctxSetConcurrencyLimited(r *http.Request) {
+	setCtxValue(r, ctx.ConcurrencyLimited, true)
+}
+
+func ctxGetConcurrencyLimited(r *http.Request) bool {
+	v, _ := r.Context().Value(ctx.ConcurrencyLimited).(bool)
+	return v
+}
+
+// concurrencyLimitTags adds the analytics tag of the requests rejected by the concurrency limit to
+// the tags of r.
+func concurrencyLimitTags(r *http.Request, tags []string) []string {
+	if ctxGetConcurrencyLimited(r) {
+		return append(tags, concurrencyLimitedTag)
+	}
+	return tags
+}
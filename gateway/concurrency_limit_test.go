@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/slow") {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	loadAPI := func(queueTimeout int64) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/limited/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.ConcurrencyLimit.MaxInFlight = 1
+			spec.ConcurrencyLimit.QueueTimeout = queueTimeout
+		}, func(spec *APISpec) {
+			spec.APIID = "other"
+			spec.Proxy.ListenPath = "/other/"
+			spec.Proxy.TargetURL = upstream.URL
+		})
+	}
+	slowRequest := func() chan int {
+		code := make(chan int)
+		go func() {
+			resp, err := http.Get(ts.URL + "/limited/slow")
+			if err != nil {
+				code <- 0
+				return
+			}
+			resp.Body.Close()
+			code <- resp.StatusCode
+		}()
+		time.Sleep(100 * time.Millisecond)
+		return code
+	}
+
+	t.Run("rejected over the limit", func(t *testing.T) {
+		loadAPI(0)
+		inFlight := slowRequest()
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/limited/", Code: http.StatusServiceUnavailable, BodyMatch: "API concurrency limit reached"},
+			{Path: "/other/", Code: http.StatusOK},
+		}...)
+
+		release <- struct{}{}
+		assert.Equal(t, http.StatusOK, <-inFlight)
+		_, _ = ts.Run(t, test.TestCase{Path: "/limited/", Code: http.StatusOK})
+	})
+
+	t.Run("queued until a slot is released", func(t *testing.T) {
+		loadAPI(2000)
+		inFlight := slowRequest()
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			release <- struct{}{}
+		}()
+		_, _ = ts.Run(t, test.TestCase{Path: "/limited/", Code: http.StatusOK})
+		assert.Equal(t, http.StatusOK, <-inFlight)
+	})
+
+	t.Run("analytics tag", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		assert.Equal(t, []string{"key"}, concurrencyLimitTags(r, []string{"key"}))
+
+		ctxSetConcurrencyLimited(r)
+		assert.Equal(t, []string{"key", "concurrency-limited"}, concurrencyLimitTags(r, []string{"key"}))
+	})
+}
@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/cli"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// reloadableConfigFields are the paths of the configuration fields which are applied without a
+// restart. The fields read from the global configuration of the APIs are applied by reloading the
+// APIs, the other ones are read from the configuration when used.
+var reloadableConfigFields = []string{
+	"log_level",
+	"enable_key_logging",
+	"hide_generator_header",
+	"close_connections",
+	"proxy_default_timeout",
+	"proxy_close_connections",
+	"max_idle_connections_per_host",
+	"enforce_org_quotas",
+	"allow_master_keys",
+	"track_404_logs",
+	"enable_bundle_downloader",
+	"analytics_config.enable_detailed_recording",
+	// compiled again by the reload of the APIs
+	"error_templates",
+}
+
+// ConfigReloadReport lists the configuration fields changed by a reload of the configuration
+// file, by JSON path.
+type ConfigReloadReport struct {
+	Status string `json:"status"`
+	// Applied are the fields applied at runtime.
+	Applied []string `json:"applied"`
+	// RestartRequired are the fields which need a restart of the gateway to be applied.
+	RestartRequired []string `json:"restart_required"`
+}
+
+// configField returns the field of conf at the JSON path, invalid if there is none.
+func configField(conf reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		if conf.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		field := reflect.Value{}
+		for i := 0; i < conf.NumField(); i++ {
+			if strings.Split(conf.Type().Field(i).Tag.Get("json"), ",")[0] == name {
+				field = conf.Field(i)
+				break
+			}
+		}
+		if !field.IsValid() {
+			return field
+		}
+		conf = field
+	}
+	return conf
+}
+
+// flattenConfig adds the leaves of the JSON encoded configuration conf to fields by path. Arrays
+// are leaves.
+func flattenConfig(prefix string, conf interface{}, fields map[string]interface{}) {
+	object, ok := conf.(map[string]interface{})
+	if !ok || prefix != "" && len(object) == 0 {
+		fields[prefix] = conf
+		return
+	}
+	for k, v := range object {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		flattenConfig(path, v, fields)
+	}
+}
+
+// changedConfigFields returns the paths of the leaves changed between the configurations.
+func changedConfigFields(from, to config.Config) ([]string, error) {
+	fields := make([]map[string]interface{}, 2)
+	for i, conf := range []config.Config{from, to} {
+		data, err := json.Marshal(conf)
+		if err != nil {
+			return nil, err
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil, err
+		}
+		fields[i] = map[string]interface{}{}
+		flattenConfig("", decoded, fields[i])
+	}
+
+	var changed []string
+	for path, v := range fields[0] {
+		if w, ok := fields[1][path]; !ok || !reflect.DeepEqual(v, w) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range fields[1] {
+		if _, ok := fields[0][path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// reloadableConfigField returns the reloadable field path is part of, "" if it isn't reloadable.
+func reloadableConfigField(path string) string {
+	for _, field := range reloadableConfigFields {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return field
+		}
+	}
+	return ""
+}
+
+// reloadConfig reads the configuration file again and applies the reloadable fields changed since
+// it was last loaded. The other changed fields are reported, and applied on the next restart.
+func (gw *Gateway) reloadConfig() (*ConfigReloadReport, error) {
+	gw.confReloadMu.Lock()
+	defer gw.confReloadMu.Unlock()
+
+	var loaded config.Config
+	if err := config.Load(confPaths, &loaded); err != nil {
+		return nil, err
+	}
+	changed, err := changedConfigFields(gw.loadedConf, loaded)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConfigReloadReport{Status: "ok", Applied: []string{}, RestartRequired: []string{}}
+	var applied []string
+	for _, path := range changed {
+		if field := reloadableConfigField(path); field != "" {
+			if !contains(applied, field) {
+				applied = append(applied, field)
+			}
+			continue
+		}
+		report.RestartRequired = append(report.RestartRequired, path)
+	}
+
+	if contains(applied, "log_level") {
+		if _, err := parseConfigLogLevel(loaded.LogLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	conf := gw.GetConfig()
+	current, reloaded := reflect.ValueOf(&conf).Elem(), reflect.ValueOf(&loaded).Elem()
+	for _, field := range applied {
+		configField(current, field).Set(configField(reloaded, field))
+	}
+	gw.SetConfig(conf)
+	report.Applied = append(report.Applied, applied...)
+
+	if contains(applied, "log_level") && os.Getenv("TYK_LOGLEVEL") == "" && (cli.DebugMode == nil || !*cli.DebugMode) {
+		level, _ := parseConfigLogLevel(conf.LogLevel)
+		log.SetLevel(level)
+	}
+
+	// the fields needing a restart are reported until the restart
+	for _, field := range applied {
+		configField(reflect.ValueOf(&gw.loadedConf).Elem(), field).Set(configField(reloaded, field))
+	}
+
+	if len(applied) > 0 {
+		// the APIs get the new configuration
+		gw.reloadURLStructure(nil)
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":           "main",
+		"applied":          report.Applied,
+		"restart_required": report.RestartRequired,
+	}).Info("Configuration reloaded.")
+
+	return report, nil
+}
+
+// parseConfigLogLevel returns the log level of the log_level configuration field.
+func parseConfigLogLevel(level string) (logrus.Level, error) {
+	switch strings.ToLower(level) {
+	case "", "info":
+		return logrus.InfoLevel, nil
+	case "error":
+		return logrus.ErrorLevel, nil
+	case "warn":
+		return logrus.WarnLevel, nil
+	case "debug":
+		return logrus.DebugLevel, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q, must be error, warn, debug or info", level)
+}
+
+func (gw *Gateway) reloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := gw.reloadConfig()
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "api",
+			"err":    err,
+		}).Error("Configuration reload failed.")
+		doJSONWrite(w, http.StatusInternalServerError, apiError(err.Error()))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, report)
+}
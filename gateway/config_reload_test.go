@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestReloadConfig(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "tyk-conf-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tyk.conf")
+
+	oldPaths := confPaths
+	confPaths = []string{path}
+	defer func() { confPaths = oldPaths }()
+
+	writeConf := func(conf config.Config) {
+		data, err := json.Marshal(conf)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	}
+	reload := func(code int) ConfigReloadReport {
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/reload-config", AdminAuth: true, Code: code})
+		var report ConfigReloadReport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		return report
+	}
+
+	conf := ts.Gw.GetConfig()
+	writeConf(conf)
+	require.NoError(t, config.Load(confPaths, &ts.Gw.loadedConf))
+
+	listenPort := conf.ListenPort
+	conf.HideGeneratorHeader = true
+	conf.ProxyDefaultTimeout = 5
+	conf.ErrorTemplates = []apidef.ErrorTemplate{{ContentType: "text/plain", Body: "{{ .Message }}"}}
+	conf.ListenPort++
+	writeConf(conf)
+
+	report := reload(http.StatusOK)
+	assert.Equal(t, []string{"error_templates", "hide_generator_header", "proxy_default_timeout"}, report.Applied)
+	assert.Equal(t, []string{"listen_port"}, report.RestartRequired)
+
+	assert.True(t, ts.Gw.GetConfig().HideGeneratorHeader)
+	assert.Equal(t, float64(5), ts.Gw.GetConfig().ProxyDefaultTimeout)
+	assert.Equal(t, conf.ErrorTemplates, ts.Gw.GetConfig().ErrorTemplates)
+	assert.Equal(t, listenPort, ts.Gw.GetConfig().ListenPort)
+
+	t.Run("fields needing a restart are reported until the restart", func(t *testing.T) {
+		report := reload(http.StatusOK)
+		assert.Empty(t, report.Applied)
+		assert.Equal(t, []string{"listen_port"}, report.RestartRequired)
+	})
+
+	t.Run("invalid log level", func(t *testing.T) {
+		conf.LogLevel = "verbose"
+		conf.HideGeneratorHeader = false
+		writeConf(conf)
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/reload-config", AdminAuth: true,
+			Code: http.StatusInternalServerError})
+		assert.True(t, ts.Gw.GetConfig().HideGeneratorHeader)
+	})
+}
@@ -0,0 +1,175 @@
+package gateway
+
+import (
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+const defaultHashRingReplicas = 100
+
+var errHashNoTargets = errors.New("no upstream targets to hash onto")
+
+// hashRing places every target at a number of points on a ring of 32-bit hashes. A value is
+// served by the first target point following its own hash, so adding or removing a target
+// only remaps the values which were adjacent to its points.
+type hashRing struct {
+	points []uint32
+	hosts  map[uint32]string
+	size   int
+}
+
+func newHashRing(hosts []string, replicas int) *hashRing {
+	ring := &hashRing{
+		points: make([]uint32, 0, len(hosts)*replicas),
+		hosts:  make(map[uint32]string, len(hosts)*replicas),
+		size:   len(hosts),
+	}
+
+	for _, host := range hosts {
+		for i := 0; i < replicas; i++ {
+			point := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + "#" + host))
+			if _, exists := ring.hosts[point]; exists {
+				continue
+			}
+			ring.hosts[point] = host
+			ring.points = append(ring.points, point)
+		}
+	}
+
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+
+	return ring
+}
+
+// Hosts returns the distinct targets in ring order starting from the point owning value,
+// the first one being the preferred target.
+func (h *hashRing) Hosts(value string) []string {
+	if len(h.points) == 0 {
+		return nil
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(value))
+	start := sort.Search(len(h.points), func(i int) bool { return h.points[i] >= hash })
+
+	hosts := make([]string, 0, h.size)
+	seen := make(map[string]bool, h.size)
+	for i := 0; i < len(h.points) && len(hosts) < h.size; i++ {
+		host := h.hosts[h.points[(start+i)%len(h.points)]]
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+// ConsistentHashBalancer picks upstream targets by consistently hashing a request attribute.
+type ConsistentHashBalancer struct {
+	conf        apidef.ConsistentHashingConfig
+	pathPattern *regexp.Regexp
+
+	mu      sync.Mutex
+	ringKey string
+	ring    *hashRing
+}
+
+// NewConsistentHashBalancer creates a balancer from the consistent hashing section of an API definition.
+func NewConsistentHashBalancer(conf apidef.ConsistentHashingConfig) (*ConsistentHashBalancer, error) {
+	if conf.Replicas <= 0 {
+		conf.Replicas = defaultHashRingReplicas
+	}
+
+	b := &ConsistentHashBalancer{conf: conf}
+
+	switch conf.Source {
+	case apidef.HashSourceKey, "":
+	case apidef.HashSourceHeader:
+		if conf.HeaderName == "" {
+			return nil, errors.New("header_name is required for the header source")
+		}
+	case apidef.HashSourcePath:
+		var err error
+		if b.pathPattern, err = regexp.Compile(conf.PathPattern); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unknown consistent hashing source " + conf.Source)
+	}
+
+	return b, nil
+}
+
+// Value returns the request attribute to hash, or an empty string if r does not have it.
+func (b *ConsistentHashBalancer) Value(r *http.Request) string {
+	switch b.conf.Source {
+	case apidef.HashSourceHeader:
+		return r.Header.Get(b.conf.HeaderName)
+	case apidef.HashSourcePath:
+		matches := b.pathPattern.FindStringSubmatch(r.URL.Path)
+		if len(matches) > 1 {
+			return matches[1]
+		}
+		if len(matches) == 1 {
+			return matches[0]
+		}
+		return ""
+	default:
+		if session := ctxGetSession(r); session != nil && session.Alias != "" {
+			return session.Alias
+		}
+		return ctxGetAuthToken(r)
+	}
+}
+
+// Hosts returns the targets of hostList ordered by preference for value.
+func (b *ConsistentHashBalancer) Hosts(hostList *apidef.HostList, value string) []string {
+	hosts := hostList.All()
+	ringKey := strings.Join(hosts, "\n")
+
+	b.mu.Lock()
+	// the host list only changes with service discovery, rebuild the ring when it does
+	if b.ring == nil || b.ringKey != ringKey {
+		b.ring = newHashRing(hosts, b.conf.Replicas)
+		b.ringKey = ringKey
+	}
+	ring := b.ring
+	b.mu.Unlock()
+
+	return ring.Hosts(value)
+}
+
+// nextHashedTarget returns the target for r when consistent hashing applies to it, falling
+// back to nextTarget otherwise. Targets failing uptime tests are skipped in ring order.
+func (gw *Gateway) nextHashedTarget(targetData *apidef.HostList, spec *APISpec, r *http.Request) (string, error) {
+	if spec.HashBalancer == nil {
+		return gw.nextTarget(targetData, spec)
+	}
+
+	value := spec.HashBalancer.Value(r)
+	if value == "" {
+		return gw.nextTarget(targetData, spec)
+	}
+
+	hosts := spec.HashBalancer.Hosts(targetData, value)
+	if len(hosts) == 0 {
+		return "", errHashNoTargets
+	}
+
+	for _, gotHost := range hosts {
+		host := EnsureTransport(gotHost, spec.Protocol)
+		if !spec.Proxy.CheckHostAgainstUptimeTests || gw.GlobalHostChecker.store == nil || !gw.GlobalHostChecker.HostDown(host) {
+			return host, nil
+		}
+	}
+
+	return "", errors.New("all hosts are down, uptime tests are failing")
+}
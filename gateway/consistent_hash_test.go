@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestHashRing(t *testing.T) {
+	hosts := []string{"http://a", "http://b", "http://c", "http://d"}
+	ring := newHashRing(hosts, defaultHashRingReplicas)
+
+	assert.Nil(t, newHashRing(nil, defaultHashRingReplicas).Hosts("value"))
+
+	before := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		value := "entity-" + strconv.Itoa(i)
+		got := ring.Hosts(value)
+		assert.ElementsMatch(t, hosts, got, "all targets should be returned as fallbacks")
+		assert.Equal(t, got, ring.Hosts(value), "the same value must always land on the same target")
+		before[value] = got[0]
+		counts[got[0]]++
+	}
+	for _, host := range hosts {
+		assert.True(t, counts[host] > 100, "values should be spread over all targets")
+	}
+
+	// removing a target must only remap the values it owned
+	ring = newHashRing([]string{"http://a", "http://b", "http://d"}, defaultHashRingReplicas)
+	for value, host := range before {
+		if host != "http://c" {
+			assert.Equal(t, host, ring.Hosts(value)[0])
+		}
+	}
+}
+
+func TestConsistentHashBalancer_Value(t *testing.T) {
+	_, err := NewConsistentHashBalancer(apidef.ConsistentHashingConfig{Source: apidef.HashSourceHeader})
+	assert.Error(t, err)
+	_, err = NewConsistentHashBalancer(apidef.ConsistentHashingConfig{Source: apidef.HashSourcePath, PathPattern: "("})
+	assert.Error(t, err)
+	_, err = NewConsistentHashBalancer(apidef.ConsistentHashingConfig{Source: "cookie"})
+	assert.Error(t, err)
+
+	r, _ := http.NewRequest(http.MethodGet, "/tenants/acme/orders", nil)
+	r.Header.Set("X-Tenant", "acme")
+
+	b, err := NewConsistentHashBalancer(apidef.ConsistentHashingConfig{Source: apidef.HashSourceHeader, HeaderName: "X-Tenant"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", b.Value(r))
+
+	b, err = NewConsistentHashBalancer(apidef.ConsistentHashingConfig{Source: apidef.HashSourcePath, PathPattern: "^/tenants/([^/]+)"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", b.Value(r))
+
+	b, err = NewConsistentHashBalancer(apidef.ConsistentHashingConfig{Source: apidef.HashSourceKey})
+	assert.NoError(t, err)
+	assert.Empty(t, b.Value(r))
+}
+
+func TestConsistentHashLoadBalancing(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	targets := []string{TestHttpAny + "/a", TestHttpAny + "/b", TestHttpAny + "/c"}
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.EnableLoadBalancing = true
+		spec.Proxy.Targets = targets
+		spec.Proxy.ConsistentHashing = apidef.ConsistentHashingConfig{
+			Enabled:    true,
+			Source:     apidef.HashSourceHeader,
+			HeaderName: "X-Tenant",
+		}
+	})
+
+	ring := newHashRing(targets, defaultHashRingReplicas)
+	for _, tenant := range []string{"acme", "globex", "initech"} {
+		target := ring.Hosts(tenant)[0]
+		bodyMatch := `"Url":"` + strings.TrimPrefix(target, TestHttpAny)
+		for i := 0; i < 3; i++ {
+			_, _ = ts.Run(t, test.TestCase{Path: "/", Headers: map[string]string{"X-Tenant": tenant}, Code: http.StatusOK, BodyMatch: bodyMatch})
+		}
+	}
+
+	// requests without the header are balanced with round robin
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/a`},
+		{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/b`},
+		{Path: "/", Code: http.StatusOK, BodyMatch: `"Url":"/c`},
+	}...)
+}
@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk/certs"
+)
+
+// ContextVarFunc returns the value of a context variable for a request, ok being false when the
+// variable doesn't apply to the request, e.g. a TLS variable for a plain HTTP request.
+type ContextVarFunc func(r *http.Request) (value interface{}, ok bool)
+
+// ContextVarRegistry holds the context variables computed for every request of the APIs with
+// context variables enabled, along with the built-in request variables like path or request_id.
+// The variables are available to the transforms and URL rewrites as $tyk_context.<name>.
+//
+// They are computed before authentication, the JWT claims and the token being added by the auth
+// middlewares afterwards.
+type ContextVarRegistry struct {
+	mu   sync.RWMutex
+	vars map[string]ContextVarFunc
+}
+
+// ContextVars is the registry of the gateway, plugins register their own variables with it, e.g.
+// from their init function.
+var ContextVars = NewContextVarRegistry()
+
+// Names of the TLS client certificate context variables.
+const (
+	ctxVarTLSClientCertCN          = "tls_client_cert_cn"
+	ctxVarTLSClientCertFingerprint = "tls_client_cert_fingerprint"
+	ctxVarTLSClientCertSerial      = "tls_client_cert_serial"
+	ctxVarTLSClientCertIssuerCN    = "tls_client_cert_issuer_cn"
+	ctxVarTLSClientCertNotAfter    = "tls_client_cert_not_after"
+)
+
+// builtinContextVars are set by the context variables middleware itself, they can't be registered.
+var builtinContextVars = map[string]bool{
+	"request_data": true,
+	"headers":      true,
+	"headers_Host": true,
+	"path_parts":   true,
+	"path":         true,
+	"remote_addr":  true,
+	"request_id":   true,
+	"geo_country":  true,
+	"token":        true,
+}
+
+// builtinContextVarPrefixes are the prefixes of the variables set by the middlewares.
+var builtinContextVarPrefixes = []string{"headers_", "cookies_", "jwt_claims_", "federation_"}
+
+// NewContextVarRegistry returns a registry with the TLS client certificate variables.
+func NewContextVarRegistry() *ContextVarRegistry {
+	c := &ContextVarRegistry{vars: map[string]ContextVarFunc{}}
+
+	c.vars[ctxVarTLSClientCertCN] = tlsClientCertVar(func(leaf *x509.Certificate) interface{} {
+		return leaf.Subject.CommonName
+	})
+	c.vars[ctxVarTLSClientCertFingerprint] = tlsClientCertVar(func(leaf *x509.Certificate) interface{} {
+		return certs.HexSHA256(leaf.Raw)
+	})
+	c.vars[ctxVarTLSClientCertSerial] = tlsClientCertVar(func(leaf *x509.Certificate) interface{} {
+		return leaf.SerialNumber.String()
+	})
+	c.vars[ctxVarTLSClientCertIssuerCN] = tlsClientCertVar(func(leaf *x509.Certificate) interface{} {
+		return leaf.Issuer.CommonName
+	})
+	c.vars[ctxVarTLSClientCertNotAfter] = tlsClientCertVar(func(leaf *x509.Certificate) interface{} {
+		return leaf.NotAfter.UTC().Format(time.RFC3339)
+	})
+
+	return c
+}
+
+// Register adds a context variable. It fails when the name is taken, by a built-in variable or
+// a registered one.
+func (c *ContextVarRegistry) Register(name string, fn ContextVarFunc) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("context variable name and function are required")
+	}
+	if builtinContextVars[name] {
+		return fmt.Errorf("context variable %q is built-in", name)
+	}
+	for _, prefix := range builtinContextVarPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("context variable %q uses the built-in prefix %q", name, prefix)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.vars[name]; ok {
+		return fmt.Errorf("context variable %q is already registered", name)
+	}
+	c.vars[name] = fn
+	return nil
+}
+
+// Unregister removes a context variable.
+func (c *ContextVarRegistry) Unregister(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.vars, name)
+}
+
+// Names returns the names of the registered variables, sorted.
+func (c *ContextVarRegistry) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.vars))
+	for name := range c.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apply adds the registered variables applying to r to data.
+func (c *ContextVarRegistry) apply(r *http.Request, data map[string]interface{}) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for name, fn := range c.vars {
+		if value, ok := fn(r); ok {
+			data[name] = value
+		}
+	}
+}
+
+// tlsClientCertVar returns a variable of the leaf certificate of the client, for the mutual TLS
+// requests.
+func tlsClientCertVar(field func(leaf *x509.Certificate) interface{}) ContextVarFunc {
+	return func(r *http.Request) (interface{}, bool) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil, false
+		}
+		return field(r.TLS.PeerCertificates[0]), true
+	}
+}
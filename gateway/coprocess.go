@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -23,9 +24,19 @@ import (
 
 var (
 	supportedDrivers = []apidef.MiddlewareDriver{apidef.PythonDriver, apidef.LuaDriver, apidef.GrpcDriver}
-	loadedDrivers    = map[apidef.MiddlewareDriver]coprocess.Dispatcher{}
+	// loadedDriversMu guards loadedDrivers, the dispatchers being loaded with the APIs using them,
+	// which are loaded concurrently.
+	loadedDriversMu sync.RWMutex
+	loadedDrivers   = map[apidef.MiddlewareDriver]coprocess.Dispatcher{}
 )
 
+// loadedDriver returns the dispatcher of driver, nil if it isn't loaded.
+func loadedDriver(driver apidef.MiddlewareDriver) coprocess.Dispatcher {
+	loadedDriversMu.RLock()
+	defer loadedDriversMu.RUnlock()
+	return loadedDrivers[driver]
+}
+
 // CoProcessMiddleware is the basic CP middleware struct.
 type CoProcessMiddleware struct {
 	BaseMiddleware
@@ -58,7 +69,7 @@ func DoCoprocessReload() {
 	log.WithFields(logrus.Fields{
 		"prefix": "coprocess",
 	}).Info("Reloading middlewares")
-	if dispatcher := loadedDrivers[apidef.PythonDriver]; dispatcher != nil {
+	if dispatcher := loadedDriver(apidef.PythonDriver); dispatcher != nil {
 		dispatcher.Reload()
 	}
 }
@@ -239,9 +250,11 @@ func (gw *Gateway) CoProcessInit() {
 	}
 
 	// Load gRPC dispatcher:
-	if gw.GetConfig().CoProcessOptions.CoProcessGRPCServer != "" {
-		var err error
-		loadedDrivers[apidef.GrpcDriver], err = gw.NewGRPCDispatcher()
+	if len(gw.grpcServers()) > 0 {
+		dispatcher, err := gw.NewGRPCDispatcher()
+		loadedDriversMu.Lock()
+		loadedDrivers[apidef.GrpcDriver] = dispatcher
+		loadedDriversMu.Unlock()
 		if err == nil {
 			log.WithFields(logrus.Fields{
 				"prefix": "coprocess",
@@ -279,7 +292,7 @@ func (m *CoProcessMiddleware) EnabledForSpec() bool {
 		return false
 	}
 
-	if d, _ := loadedDrivers[m.Spec.CustomMiddleware.Driver]; d == nil {
+	if loadedDriver(m.Spec.CustomMiddleware.Driver) == nil {
 		log.WithFields(logrus.Fields{
 			"prefix": "coprocess",
 		}).Errorf("Driver '%s' isn't loaded", m.Spec.CustomMiddleware.Driver)
@@ -568,7 +581,7 @@ func (h *CustomMiddlewareResponseHook) HandleResponse(rw http.ResponseWriter, re
 }
 
 func (c *CoProcessor) Dispatch(object *coprocess.Object) (*coprocess.Object, error) {
-	dispatcher := loadedDrivers[c.Middleware.MiddlewareDriver]
+	dispatcher := loadedDriver(c.Middleware.MiddlewareDriver)
 	if dispatcher == nil {
 		err := fmt.Errorf("Couldn't dispatch request, driver '%s' isn't available", c.Middleware.MiddlewareDriver)
 		return nil, err
@@ -101,25 +101,38 @@ func (b *Bundle) AddToSpec() {
 	b.Spec.CustomMiddleware = b.Manifest.CustomMiddleware
 
 	// Load Python interpreter if the
-	if loadedDrivers[b.Spec.CustomMiddleware.Driver] == nil && b.Spec.CustomMiddleware.Driver == apidef.PythonDriver {
-		var err error
-		loadedDrivers[apidef.PythonDriver], err = NewPythonDispatcher(b.Gw.GetConfig())
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"prefix": "coprocess",
-			}).WithError(err).Error("Couldn't load Python dispatcher")
-			return
-		}
-		log.WithFields(logrus.Fields{
-			"prefix": "coprocess",
-		}).Info("Python dispatcher was initialized")
+	if b.Spec.CustomMiddleware.Driver == apidef.PythonDriver && !b.loadPythonDispatcher() {
+		return
 	}
-	dispatcher := loadedDrivers[b.Spec.CustomMiddleware.Driver]
+	dispatcher := loadedDriver(b.Spec.CustomMiddleware.Driver)
 	if dispatcher != nil {
 		dispatcher.HandleMiddlewareCache(&b.Manifest, b.Path)
 	}
 }
 
+// loadPythonDispatcher loads the Python dispatcher once, reporting whether it's loaded.
+func (b *Bundle) loadPythonDispatcher() bool {
+	loadedDriversMu.Lock()
+	defer loadedDriversMu.Unlock()
+
+	if loadedDrivers[apidef.PythonDriver] != nil {
+		return true
+	}
+
+	dispatcher, err := NewPythonDispatcher(b.Gw.GetConfig())
+	loadedDrivers[apidef.PythonDriver] = dispatcher
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "coprocess",
+		}).WithError(err).Error("Couldn't load Python dispatcher")
+		return false
+	}
+	log.WithFields(logrus.Fields{
+		"prefix": "coprocess",
+	}).Info("Python dispatcher was initialized")
+	return true
+}
+
 // BundleGetter is used for downloading bundle data, see HttpBundleGetter for reference.
 type BundleGetter interface {
 	Get() ([]byte, error)
@@ -335,6 +348,9 @@ func (gw *Gateway) loadBundle(spec *APISpec) error {
 		return bundleError(spec, nil, "No bundle base URL set, skipping bundle")
 	}
 
+	gw.bundleMu.Lock()
+	defer gw.bundleMu.Unlock()
+
 	// get bundle destination on disk
 	destPath := gw.getBundleDestPath(spec)
 
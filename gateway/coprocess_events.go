@@ -42,7 +42,7 @@ func (l *CoProcessEventHandler) Init(handlerConf interface{}) error {
 }
 
 func (l *CoProcessEventHandler) HandleEvent(em config.EventMessage) {
-	dispatcher := loadedDrivers[l.Spec.CustomMiddleware.Driver]
+	dispatcher := loadedDriver(l.Spec.CustomMiddleware.Driver)
 	if dispatcher == nil {
 		return
 	}
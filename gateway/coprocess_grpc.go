@@ -4,28 +4,84 @@ import (
 	"errors"
 	"net"
 	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/coprocess"
 )
 
-var (
-	grpcConnection *grpc.ClientConn
-	grpcClient     coprocess.DispatcherClient
+const (
+	defaultGRPCHealthCheckInterval = 10
+	grpcHealthCheckTimeout         = 5 * time.Second
 )
 
+// grpcServer is a gRPC server along with the pool of connections to it.
+type grpcServer struct {
+	address string
+	conns   []*grpc.ClientConn
+	clients []coprocess.DispatcherClient
+	next    uint32
+
+	healthy int32
+	// noStream is set once the server answered it doesn't implement DispatchStream.
+	noStream int32
+}
+
+// client returns the client of the next connection of the pool.
+func (s *grpcServer) client() coprocess.DispatcherClient {
+	i := atomic.AddUint32(&s.next, 1)
+	return s.clients[i%uint32(len(s.clients))]
+}
+
+func (s *grpcServer) isHealthy() bool {
+	return atomic.LoadInt32(&s.healthy) == 1
+}
+
+// setHealthy returns whether the health of the server changed.
+func (s *grpcServer) setHealthy(healthy bool) bool {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	return atomic.SwapInt32(&s.healthy, v) != v
+}
+
+// checkHealth calls the standard gRPC health service of the server. Servers not implementing
+// it are healthy as long as they answer.
+func (s *grpcServer) checkHealth(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, grpcHealthCheckTimeout)
+	defer cancel()
+
+	res, err := grpc_health_v1.NewHealthClient(s.conns[0]).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return true
+	}
+	return err == nil && res.Status == grpc_health_v1.HealthCheckResponse_SERVING
+}
+
+func (s *grpcServer) close() {
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
 // GRPCDispatcher implements a coprocess.Dispatcher
 type GRPCDispatcher struct {
 	coprocess.Dispatcher
+	servers   []*grpcServer
+	chunkSize int
 }
 
-func (gw *Gateway) dialer(addr string, timeout time.Duration) (net.Conn, error) {
-	grpcURL, err := url.Parse(gw.GetConfig().CoProcessOptions.CoProcessGRPCServer)
+func grpcDial(address string, timeout time.Duration) (net.Conn, error) {
+	grpcURL, err := url.Parse(address)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"prefix": "coprocess",
@@ -33,21 +89,188 @@ func (gw *Gateway) dialer(addr string, timeout time.Duration) (net.Conn, error)
 		return nil, err
 	}
 
-	if grpcURL == nil || gw.GetConfig().CoProcessOptions.CoProcessGRPCServer == "" {
-		errString := "No gRPC URL is set!"
+	return net.DialTimeout(grpcURL.Scheme, address[len(grpcURL.Scheme)+3:], timeout)
+}
+
+// grpcServers returns the addresses of the gRPC servers, in order of preference.
+func (gw *Gateway) grpcServers() []string {
+	var addresses []string
+	if address := gw.GetConfig().CoProcessOptions.CoProcessGRPCServer; address != "" {
+		addresses = append(addresses, address)
+	}
+	for _, address := range gw.GetConfig().CoProcessOptions.CoProcessGRPCServers {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// Dispatch takes a CoProcessMessage and sends it to the CP.
+func (d *GRPCDispatcher) Dispatch(object *coprocess.Object) (*coprocess.Object, error) {
+	var res *coprocess.Object
+	err := d.call(func(server *grpcServer) (err error) {
+		res, err = d.dispatch(server, object)
+		return err
+	})
+	return res, err
+}
+
+func (d *GRPCDispatcher) dispatch(server *grpcServer, object *coprocess.Object) (*coprocess.Object, error) {
+	if d.chunkSize > 0 && atomic.LoadInt32(&server.noStream) == 0 && len(objectRawBody(object)) > d.chunkSize {
+		res, err := dispatchStream(server.client(), object, d.chunkSize)
+		if status.Code(err) != codes.Unimplemented {
+			return res, err
+		}
+
+		atomic.StoreInt32(&server.noStream, 1)
 		log.WithFields(logrus.Fields{
 			"prefix": "coprocess",
-		}).Error(errString)
-		return nil, errors.New(errString)
+			"server": server.address,
+		}).Warning("gRPC server doesn't implement DispatchStream, sending bodies in a single message")
 	}
 
-	grpcURLString := gw.GetConfig().CoProcessOptions.CoProcessGRPCServer[len(grpcURL.Scheme)+3:]
-	return net.DialTimeout(grpcURL.Scheme, grpcURLString, timeout)
+	return server.client().Dispatch(context.Background(), object)
 }
 
-// Dispatch takes a CoProcessMessage and sends it to the CP.
-func (d *GRPCDispatcher) Dispatch(object *coprocess.Object) (*coprocess.Object, error) {
-	return grpcClient.Dispatch(context.Background(), object)
+// objectRawBody returns the body the hook of object works on.
+func objectRawBody(object *coprocess.Object) []byte {
+	if object.HookType == coprocess.HookType_Response {
+		if object.Response != nil {
+			return object.Response.RawBody
+		}
+		return nil
+	}
+	if object.Request != nil {
+		return object.Request.RawBody
+	}
+	return nil
+}
+
+// dispatchStream sends object without its body, then the raw body in chunks of chunkSize.
+func dispatchStream(client coprocess.DispatcherClient, object *coprocess.Object, chunkSize int) (*coprocess.Object, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.DispatchStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	head := *object
+	var body []byte
+	var chunk func([]byte) *coprocess.Object
+	if object.HookType == coprocess.HookType_Response {
+		response := *object.Response
+		body, response.RawBody, response.Body = response.RawBody, nil, ""
+		head.Response = &response
+		chunk = func(b []byte) *coprocess.Object {
+			return &coprocess.Object{Response: &coprocess.ResponseObject{RawBody: b}}
+		}
+	} else {
+		request := *object.Request
+		body, request.RawBody, request.Body = request.RawBody, nil, ""
+		head.Request = &request
+		chunk = func(b []byte) *coprocess.Object {
+			return &coprocess.Object{Request: &coprocess.MiniRequestObject{RawBody: b}}
+		}
+	}
+
+	// the server may answer before reading the whole stream, Send then returns io.EOF and
+	// CloseAndRecv the answer
+	if err := stream.Send(&head); err == nil {
+		for len(body) > 0 {
+			n := chunkSize
+			if n > len(body) {
+				n = len(body)
+			}
+			if err := stream.Send(chunk(body[:n])); err != nil {
+				break
+			}
+			body = body[n:]
+		}
+	}
+
+	return stream.CloseAndRecv()
+}
+
+// call calls fn with the healthy servers first, in order, failing over to the next one while
+// servers are unavailable.
+func (d *GRPCDispatcher) call(fn func(*grpcServer) error) error {
+	var err error
+	for _, server := range d.serversByHealth() {
+		if err = fn(server); status.Code(err) != codes.Unavailable {
+			return err
+		}
+
+		if server.setHealthy(false) && len(d.servers) > 1 {
+			log.WithFields(logrus.Fields{
+				"prefix": "coprocess",
+				"server": server.address,
+			}).WithError(err).Warning("gRPC server is unavailable, failing over")
+		}
+	}
+	return err
+}
+
+func (d *GRPCDispatcher) serversByHealth() []*grpcServer {
+	if len(d.servers) == 1 {
+		return d.servers
+	}
+
+	servers := make([]*grpcServer, 0, len(d.servers))
+	for _, server := range d.servers {
+		if server.isHealthy() {
+			servers = append(servers, server)
+		}
+	}
+	for _, server := range d.servers {
+		if !server.isHealthy() {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// checkHealth updates the health of the servers until ctx is done, then closes their connections.
+func (d *GRPCDispatcher) checkHealth(ctx context.Context, interval time.Duration) {
+	defer func() {
+		for _, server := range d.servers {
+			server.close()
+		}
+	}()
+
+	if len(d.servers) == 1 {
+		<-ctx.Done()
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, server := range d.servers {
+			healthy := server.checkHealth(ctx)
+			if !server.setHealthy(healthy) || ctx.Err() != nil {
+				continue
+			}
+
+			logger := log.WithFields(logrus.Fields{
+				"prefix": "coprocess",
+				"server": server.address,
+			})
+			if healthy {
+				logger.Info("gRPC server is healthy again")
+			} else {
+				logger.Warning("gRPC server is unhealthy")
+			}
+		}
+	}
 }
 
 // DispatchEvent dispatches a Tyk event.
@@ -56,7 +279,10 @@ func (d *GRPCDispatcher) DispatchEvent(eventJSON []byte) {
 		Payload: string(eventJSON),
 	}
 
-	_, err := grpcClient.DispatchEvent(context.Background(), eventObject)
+	err := d.call(func(server *grpcServer) error {
+		_, err := server.client().DispatchEvent(context.Background(), eventObject)
+		return err
+	})
 
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -86,24 +312,51 @@ func (gw *Gateway) grpcCallOpts() grpc.DialOption {
 
 // NewGRPCDispatcher wraps all the actions needed for this CP.
 func (gw *Gateway) NewGRPCDispatcher() (coprocess.Dispatcher, error) {
-	if gw.GetConfig().CoProcessOptions.CoProcessGRPCServer == "" {
+	addresses := gw.grpcServers()
+	if len(addresses) == 0 {
 		return nil, errors.New("No gRPC URL is set")
 	}
-	var err error
-	grpcConnection, err = grpc.Dial("",
-		gw.grpcCallOpts(),
-		grpc.WithInsecure(),
-		grpc.WithDialer(gw.dialer),
-	)
 
-	grpcClient = coprocess.NewDispatcherClient(grpcConnection)
+	opts := gw.GetConfig().CoProcessOptions
+	poolSize := opts.GRPCConnectionPoolSize
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	interval := opts.GRPCHealthCheckInterval
+	if interval <= 0 {
+		interval = defaultGRPCHealthCheckInterval
+	}
+
+	d := &GRPCDispatcher{chunkSize: opts.GRPCStreamChunkSize}
+	for _, address := range addresses {
+		address := address
+		server := &grpcServer{address: address, healthy: 1}
+		d.servers = append(d.servers, server)
 
-	if err != nil {
+		for i := 0; i < poolSize; i++ {
+			conn, err := grpc.Dial("",
+				gw.grpcCallOpts(),
+				grpc.WithInsecure(),
+				grpc.WithDialer(func(_ string, timeout time.Duration) (net.Conn, error) {
+					return grpcDial(address, timeout)
+				}),
+			)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"prefix": "coprocess",
+				}).Error(err)
+				for _, server := range d.servers {
+					server.close()
+				}
+				return nil, err
+			}
 
-		log.WithFields(logrus.Fields{
-			"prefix": "coprocess",
-		}).Error(err)
-		return nil, err
+			server.conns = append(server.conns, conn)
+			server.clients = append(server.clients, coprocess.NewDispatcherClient(conn))
+		}
 	}
-	return &GRPCDispatcher{}, nil
+
+	go d.checkHealth(gw.ctx, time.Duration(interval)*time.Second)
+
+	return d, nil
 }
@@ -0,0 +1,366 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	debugCaptureKeyPrefix = "debug-capture-"
+
+	debugCaptureStorageRedis = "redis"
+	debugCaptureStorageFile  = "file"
+
+	defaultDebugCaptureMaxTTL     = 3600
+	defaultDebugCaptureMaxRecords = 1000
+	defaultDebugCaptureRetention  = 86400
+)
+
+var debugCaptureLog = log.WithField("prefix", "debug-capture")
+
+// DebugCaptureRequest starts the debug capture of an API. The auth headers and the headers and
+// query parameters masked by the traffic samples of the API are always masked.
+type DebugCaptureRequest struct {
+	// TTL is the number of seconds the requests are captured for.
+	TTL int64 `json:"ttl"`
+	// Storage is where the records are kept, redis or file. Defaults to redis.
+	Storage string `json:"storage"`
+	// MaxRecords is the number of requests after which the capture stops, defaults to the maximum
+	// of the gateway configuration.
+	MaxRecords      int      `json:"max_records"`
+	MaskHeaders     []string `json:"mask_headers"`
+	MaskQueryParams []string `json:"mask_query_params"`
+}
+
+// DebugCaptureStatus is the state of the debug capture of an API.
+type DebugCaptureStatus struct {
+	APIID      string    `json:"api_id"`
+	Active     bool      `json:"active"`
+	Storage    string    `json:"storage"`
+	Started    time.Time `json:"started"`
+	Expires    time.Time `json:"expires"`
+	MaxRecords int       `json:"max_records"`
+	Records    int64     `json:"records"`
+}
+
+type debugCapture struct {
+	apiID      string
+	storage    string
+	started    time.Time
+	expires    time.Time
+	maxRecords int64
+	records    int64
+	sampler    *TrafficSampler
+
+	// fileMu serialises the writes to the file of the records
+	fileMu sync.Mutex
+}
+
+func (c *debugCapture) active(now time.Time) bool {
+	return now.Before(c.expires) && atomic.LoadInt64(&c.records) < c.maxRecords
+}
+
+func (c *debugCapture) status() DebugCaptureStatus {
+	records := atomic.LoadInt64(&c.records)
+	if records > c.maxRecords {
+		records = c.maxRecords
+	}
+	return DebugCaptureStatus{
+		APIID:      c.apiID,
+		Active:     c.active(time.Now()),
+		Storage:    c.storage,
+		Started:    c.started,
+		Expires:    c.expires,
+		MaxRecords: int(c.maxRecords),
+		Records:    records,
+	}
+}
+
+// debugCaptures are the debug captures of the APIs. They are kept by the gateway rather than the
+// API specs, so the captures survive reloads, and they are local to each gateway.
+type debugCaptures struct {
+	mu       sync.RWMutex
+	captures map[string]*debugCapture
+}
+
+func (d *debugCaptures) get(apiID string) *debugCapture {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.captures[apiID]
+}
+
+func (d *debugCaptures) set(capture *debugCapture) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.captures == nil {
+		d.captures = make(map[string]*debugCapture)
+	}
+	d.captures[capture.apiID] = capture
+}
+
+func (d *debugCaptures) remove(apiID string) *debugCapture {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	capture := d.captures[apiID]
+	delete(d.captures, apiID)
+	return capture
+}
+
+// capturing reports whether the requests of the API are captured.
+func (d *debugCaptures) capturing(apiID string) bool {
+	capture := d.get(apiID)
+	return capture != nil && capture.active(time.Now())
+}
+
+func (gw *Gateway) debugCaptureStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: debugCaptureKeyPrefix, RedisController: gw.RedisController}
+}
+
+// debugCaptureFile returns the file of the records of an API.
+func (gw *Gateway) debugCaptureFile(apiID string) string {
+	return filepath.Join(gw.GetConfig().DebugCapture.Path, url.PathEscape(apiID)+".jsonl")
+}
+
+// recordDebugCapture records the request r of spec and its response while the API is captured.
+func (gw *Gateway) recordDebugCapture(r *http.Request, spec *APISpec, code int, timing Latency, res *http.Response) {
+	capture := gw.debugCaptures.get(spec.APIID)
+	if capture == nil || !capture.active(time.Now()) {
+		return
+	}
+	// reserves a record, the capture stops once the maximum is reached
+	if atomic.AddInt64(&capture.records, 1) > capture.maxRecords {
+		return
+	}
+
+	record, err := json.Marshal(capture.sampler.capture(r, spec, code, timing, res))
+	if err != nil {
+		debugCaptureLog.WithError(err).Error("Couldn't encode debug capture record")
+		return
+	}
+
+	if err := gw.storeDebugCaptureRecord(capture, record); err != nil {
+		debugCaptureLog.WithError(err).WithField("api_id", spec.APIID).Error("Couldn't store debug capture record")
+	}
+}
+
+func (gw *Gateway) storeDebugCaptureRecord(capture *debugCapture, record []byte) error {
+	if capture.storage == debugCaptureStorageFile {
+		capture.fileMu.Lock()
+		defer capture.fileMu.Unlock()
+
+		f, err := os.OpenFile(gw.debugCaptureFile(capture.apiID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(record, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	retention := gw.GetConfig().DebugCapture.Retention
+	if retention <= 0 {
+		retention = defaultDebugCaptureRetention
+	}
+	store := gw.debugCaptureStore()
+	store.AppendToSet(capture.apiID, string(record))
+	return store.SetExp(capture.apiID, int64(time.Until(capture.expires).Seconds())+retention)
+}
+
+// debugCaptureRecords returns the records of the capture, oldest first.
+func (gw *Gateway) debugCaptureRecords(capture *debugCapture) ([]json.RawMessage, error) {
+	records := []json.RawMessage{}
+
+	if capture.storage == debugCaptureStorageFile {
+		capture.fileMu.Lock()
+		defer capture.fileMu.Unlock()
+
+		f, err := os.Open(gw.debugCaptureFile(capture.apiID))
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 16<<20)
+		for scanner.Scan() {
+			records = append(records, json.RawMessage(append([]byte(nil), scanner.Bytes()...)))
+		}
+		return records, scanner.Err()
+	}
+
+	values, err := gw.debugCaptureStore().GetListRange(capture.apiID, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		records = append(records, json.RawMessage(value))
+	}
+	return records, nil
+}
+
+func (gw *Gateway) deleteDebugCaptureRecords(capture *debugCapture) {
+	if capture.storage == debugCaptureStorageFile {
+		capture.fileMu.Lock()
+		defer capture.fileMu.Unlock()
+		if err := os.Remove(gw.debugCaptureFile(capture.apiID)); err != nil && !os.IsNotExist(err) {
+			debugCaptureLog.WithError(err).Error("Couldn't remove debug capture file")
+		}
+		return
+	}
+	gw.debugCaptureStore().DeleteKey(capture.apiID)
+}
+
+// newDebugCapture validates req against the limits of the gateway configuration.
+func (gw *Gateway) newDebugCapture(spec *APISpec, req DebugCaptureRequest) (*debugCapture, error) {
+	conf := gw.GetConfig().DebugCapture
+
+	maxTTL := conf.MaxTTL
+	if maxTTL <= 0 {
+		maxTTL = defaultDebugCaptureMaxTTL
+	}
+	if req.TTL <= 0 || req.TTL > maxTTL {
+		return nil, fmt.Errorf("ttl must be between 1 and %d seconds", maxTTL)
+	}
+
+	maxRecords := conf.MaxRecords
+	if maxRecords <= 0 {
+		maxRecords = defaultDebugCaptureMaxRecords
+	}
+	if req.MaxRecords < 0 || req.MaxRecords > maxRecords {
+		return nil, fmt.Errorf("max_records must be between 0 and %d", maxRecords)
+	}
+	if req.MaxRecords > 0 {
+		maxRecords = req.MaxRecords
+	}
+
+	switch req.Storage {
+	case "":
+		req.Storage = debugCaptureStorageRedis
+	case debugCaptureStorageRedis:
+	case debugCaptureStorageFile:
+		if conf.Path == "" {
+			return nil, errors.New("debug_capture.path isn't configured, the file storage is unavailable")
+		}
+	default:
+		return nil, fmt.Errorf("unknown storage %q", req.Storage)
+	}
+
+	// the masking of the traffic samples and the log redaction, all the requests being recorded
+	def := *spec.APIDefinition
+	def.TrafficSamples = apidef.TrafficSamplesConfig{
+		Enabled:         true,
+		MaskHeaders:     append(append(append([]string{}, spec.TrafficSamples.MaskHeaders...), spec.Logging.RedactHeaders...), req.MaskHeaders...),
+		MaskQueryParams: append(append(append([]string{}, spec.TrafficSamples.MaskQueryParams...), spec.Logging.RedactQueryParams...), req.MaskQueryParams...),
+		MaskRules:       append(append([]apidef.BodyMaskRule{}, spec.TrafficSamples.MaskRules...), spec.Logging.RedactRules...),
+		MaxBodySize:     spec.TrafficSamples.MaxBodySize,
+	}
+	sampler, err := NewTrafficSampler(&def)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &debugCapture{
+		apiID:      spec.APIID,
+		storage:    req.Storage,
+		started:    now,
+		expires:    now.Add(time.Duration(req.TTL) * time.Second),
+		maxRecords: int64(maxRecords),
+		sampler:    sampler,
+	}, nil
+}
+
+// debugCaptureHandler starts, stops or reports the debug capture of an API. Starting a capture
+// replaces the previous one and its records, stopping it deletes its records.
+func (gw *Gateway) debugCaptureHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	switch r.Method {
+	case http.MethodGet:
+		capture := gw.debugCaptures.get(apiID)
+		if capture == nil {
+			doJSONWrite(w, http.StatusNotFound, apiError("Debug capture not found"))
+			return
+		}
+		doJSONWrite(w, http.StatusOK, capture.status())
+
+	case http.MethodPut:
+		spec := gw.getApiSpec(apiID)
+		if spec == nil {
+			doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+			return
+		}
+
+		var req DebugCaptureRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+			return
+		}
+		capture, err := gw.newDebugCapture(spec, req)
+		if err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+			return
+		}
+
+		if previous := gw.debugCaptures.get(apiID); previous != nil {
+			gw.deleteDebugCaptureRecords(previous)
+		}
+		gw.debugCaptures.set(capture)
+
+		debugCaptureLog.WithFields(logrus.Fields{
+			"api_id":  apiID,
+			"storage": capture.storage,
+			"expires": capture.expires,
+		}).Info("Debug capture started.")
+
+		doJSONWrite(w, http.StatusOK, capture.status())
+
+	case http.MethodDelete:
+		capture := gw.debugCaptures.remove(apiID)
+		if capture == nil {
+			doJSONWrite(w, http.StatusNotFound, apiError("Debug capture not found"))
+			return
+		}
+		gw.deleteDebugCaptureRecords(capture)
+
+		debugCaptureLog.WithField("api_id", apiID).Info("Debug capture deleted.")
+		doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{Key: apiID, Status: "ok", Action: "deleted"})
+	}
+}
+
+// debugCaptureRecordsHandler returns the records of the debug capture of an API, oldest first.
+func (gw *Gateway) debugCaptureRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	capture := gw.debugCaptures.get(mux.Vars(r)["apiID"])
+	if capture == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("Debug capture not found"))
+		return
+	}
+
+	records, err := gw.debugCaptureRecords(capture)
+	if err != nil {
+		debugCaptureLog.WithError(err).Error("Couldn't read debug capture records")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't read the records"))
+		return
+	}
+	doJSONWrite(w, http.StatusOK, records)
+}
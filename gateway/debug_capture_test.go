@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestDebugCapture_File(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{DebugCapture: config.DebugCaptureConfig{Path: t.TempDir(), MaxTTL: 60}}, ctx, cancel)
+
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{APIID: "api"}}
+	gw.apisByID = map[string]*APISpec{"api": spec}
+
+	call := func(handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/tyk/apis/api/capture", strings.NewReader(body))
+		r = mux.SetURLVars(r, map[string]string{"apiID": "api"})
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, call(gw.debugCaptureHandler, http.MethodGet, "").Code)
+	assert.Equal(t, http.StatusBadRequest, call(gw.debugCaptureHandler, http.MethodPut, `{"ttl": 120}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(gw.debugCaptureHandler, http.MethodPut, `{"ttl": 10, "storage": "s3"}`).Code)
+
+	w := call(gw.debugCaptureHandler, http.MethodPut, `{"ttl": 10, "storage": "file", "max_records": 2, "mask_headers": ["X-Secret"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, gw.debugCaptures.capturing("api"))
+
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/path?q=1", strings.NewReader(`{"a": 1}`))
+		r.Header.Set("Authorization", "secret")
+		r.Header.Set("X-Secret", "secret")
+		nopCloseRequestBody(r)
+		res := &http.Response{StatusCode: http.StatusCreated, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("created"))}
+		gw.recordDebugCapture(r, spec, http.StatusCreated, Latency{Total: 5}, res)
+	}
+	assert.False(t, gw.debugCaptures.capturing("api"))
+
+	var status DebugCaptureStatus
+	require.NoError(t, json.NewDecoder(call(gw.debugCaptureHandler, http.MethodGet, "").Body).Decode(&status))
+	assert.Equal(t, int64(2), status.Records)
+	assert.False(t, status.Active)
+
+	var records []TrafficSample
+	require.NoError(t, json.NewDecoder(call(gw.debugCaptureRecordsHandler, http.MethodGet, "").Body).Decode(&records))
+	require.Len(t, records, 2)
+	assert.Equal(t, "/path", records[0].Request.Path)
+	assert.Equal(t, `{"a": 1}`, records[0].Request.Body)
+	assert.Equal(t, bodyMaskDefaultRedaction, records[0].Request.Headers.Get("Authorization"))
+	assert.Equal(t, bodyMaskDefaultRedaction, records[0].Request.Headers.Get("X-Secret"))
+	assert.Equal(t, http.StatusCreated, records[0].Response.Code)
+	assert.Equal(t, "created", records[0].Response.Body)
+
+	assert.Equal(t, http.StatusOK, call(gw.debugCaptureHandler, http.MethodDelete, "").Code)
+	assert.NoFileExists(t, gw.debugCaptureFile("api"))
+	assert.Equal(t, http.StatusNotFound, call(gw.debugCaptureRecordsHandler, http.MethodGet, "").Code)
+}
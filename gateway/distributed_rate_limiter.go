@@ -30,7 +30,9 @@ func (gw *Gateway) startRateLimitNotifications() {
 			case <-gw.ctx.Done():
 				return
 			default:
-				if gw.GetNodeID() != "" {
+				if gw.isDraining() {
+					// the other gateways drop this one when its notifications stop
+				} else if gw.GetNodeID() != "" {
 					gw.NotifyCurrentServerStatus()
 				} else {
 					log.Warning("Node not registered yet, skipping DRL Notification")
@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultDrainTimeout = 30
+
+func (gw *Gateway) isDraining() bool {
+	return atomic.LoadInt32(&gw.draining) == 1
+}
+
+// drain stops the gateway accepting new connections and waits for the in-flight requests to
+// finish, up to the drain timeout. The gateway leaves the uptime tests and the distributed rate
+// limiter first, for the other gateways to take over. The drained channel is closed once done,
+// and the gateway exits.
+func (gw *Gateway) drain() {
+	defer close(gw.drained)
+
+	if !gw.GetConfig().UptimeTests.Disable {
+		gw.GlobalHostChecker.Deregister()
+	}
+
+	timeout := gw.GetConfig().DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	if err := gw.DefaultProxyMux.shutdown(ctx); err != nil {
+		mainLog.WithError(err).Warning("In-flight requests didn't finish before the drain timeout")
+		return
+	}
+	mainLog.Info("In-flight requests finished")
+}
+
+func (gw *Gateway) drainHandler(w http.ResponseWriter, r *http.Request) {
+	if !atomic.CompareAndSwapInt32(&gw.draining, 0, 1) {
+		doJSONWrite(w, http.StatusConflict, apiError("The gateway is already draining"))
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+	}).Info("Draining the gateway.")
+
+	// the servers wait for this request to finish
+	go gw.drain()
+	doJSONWrite(w, http.StatusAccepted, apiOk("draining"))
+}
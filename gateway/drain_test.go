@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestDrain(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.DrainTimeout = 5
+	})
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/slow/"
+		spec.Proxy.TargetURL = upstream.URL
+	})
+
+	inFlight := make(chan int)
+	go func() {
+		resp, err := http.Get(ts.URL + "/slow/")
+		if err != nil {
+			inFlight <- 0
+			return
+		}
+		resp.Body.Close()
+		inFlight <- resp.StatusCode
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/drain", AdminAuth: true, Code: http.StatusAccepted})
+	assert.True(t, ts.Gw.isDraining())
+
+	// the in-flight request finishes
+	select {
+	case code := <-inFlight:
+		assert.Equal(t, http.StatusOK, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight request didn't finish")
+	}
+
+	select {
+	case <-ts.Gw.drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("gateway wasn't drained")
+	}
+
+	// new connections aren't accepted
+	_, err := http.Get(ts.URL + "/slow/")
+	require.Error(t, err)
+}
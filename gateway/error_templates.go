@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/trace"
+)
+
+// errorTemplateData is what the error templates are rendered with.
+type errorTemplateData struct {
+	Message     string
+	StatusCode  int
+	APIID       string
+	APIName     string
+	Method      string
+	Path        string
+	TraceID     string
+	SpanID      string
+	RequestID   string
+	ContextVars map[string]interface{}
+}
+
+// errorTemplateSet are error templates by status code, 0 for any status, and content type.
+type errorTemplateSet map[int]map[string]TemplateExecutor
+
+// parseErrorTemplate parses the error template body of the content type. The values of HTML
+// templates are escaped, as the messages and paths of the errors can come from the client.
+func parseErrorTemplate(contentType, body string) (TemplateExecutor, error) {
+	if contentType == "text/html" || contentType == "application/xhtml+xml" {
+		return htmltemplate.New(contentType).Funcs(apidef.TemplateFuncs).Option("missingkey=zero").Parse(body)
+	}
+	return apidef.Template.New(contentType).Option("missingkey=zero").Parse(body)
+}
+
+func newErrorTemplateSet(templates []apidef.ErrorTemplate, logger *logrus.Entry) errorTemplateSet {
+	set := errorTemplateSet{}
+	for _, t := range templates {
+		contentType := strings.ToLower(t.ContentType)
+		tmpl, err := parseErrorTemplate(contentType, t.Body)
+		if err != nil {
+			logger.WithError(err).WithFields(logrus.Fields{
+				"status_code":  t.StatusCode,
+				"content_type": t.ContentType,
+			}).Error("Invalid error template, template skipped")
+			continue
+		}
+
+		if set[t.StatusCode] == nil {
+			set[t.StatusCode] = map[string]TemplateExecutor{}
+		}
+		set[t.StatusCode][contentType] = tmpl
+	}
+	return set
+}
+
+// ErrorResponseTemplates are the error templates of an API, taking precedence over the global ones.
+type ErrorResponseTemplates struct {
+	api, global errorTemplateSet
+}
+
+// NewErrorResponseTemplates compiles the error templates of an API and the global ones. Invalid
+// templates are skipped.
+func NewErrorResponseTemplates(api, global []apidef.ErrorTemplate, logger *logrus.Entry) *ErrorResponseTemplates {
+	if len(api) == 0 && len(global) == 0 {
+		return nil
+	}
+
+	return &ErrorResponseTemplates{
+		api:    newErrorTemplateSet(api, logger),
+		global: newErrorTemplateSet(global, logger),
+	}
+}
+
+// Lookup returns the template of the error responses with status code to r and its content type,
+// nil if there is none. The templates of the status code are looked up before the ones of any
+// status, in the API templates first.
+func (e *ErrorResponseTemplates) Lookup(r *http.Request, code int) (TemplateExecutor, string) {
+	if e == nil {
+		return nil, ""
+	}
+
+	for _, set := range []errorTemplateSet{e.api, e.global} {
+		for _, status := range []int{code, 0} {
+			if templates := set[status]; len(templates) > 0 {
+				contentType := negotiateContentType(r, templates)
+				return templates[contentType], contentType
+			}
+		}
+	}
+	return nil, ""
+}
+
+// negotiateContentType returns the content type of templates preferred by the Accept header of r.
+// When any content type is accepted, it prefers the content type of r, then JSON.
+func negotiateContentType(r *http.Request, templates map[string]TemplateExecutor) string {
+	available := make([]string, 0, len(templates))
+	for contentType := range templates {
+		available = append(available, contentType)
+	}
+	sort.Strings(available)
+
+	for _, accepted := range acceptedMediaRanges(r.Header.Get(headers.Accept)) {
+		if accepted == "*/*" {
+			break
+		}
+		for _, contentType := range available {
+			if mediaRangeMatches(accepted, contentType) {
+				return contentType
+			}
+		}
+	}
+
+	if requestType, _, err := mime.ParseMediaType(r.Header.Get(headers.ContentType)); err == nil {
+		if _, ok := templates[requestType]; ok {
+			return requestType
+		}
+	}
+
+	if _, ok := templates[headers.ApplicationJSON]; ok {
+		return headers.ApplicationJSON
+	}
+	return available[0]
+}
+
+// acceptedMediaRanges returns the media ranges of an Accept header, most preferred first. The
+// ranges with a quality of 0 are left out.
+func acceptedMediaRanges(accept string) []string {
+	type mediaRange struct {
+		value   string
+		quality float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		value, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{value: value, quality: quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	values := make([]string, len(ranges))
+	for i := range ranges {
+		values[i] = ranges[i].value
+	}
+	return values
+}
+
+// mediaRangeMatches returns whether contentType is in the media range, e.g. text/*.
+func mediaRangeMatches(mediaRange, contentType string) bool {
+	if mediaRange == contentType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(contentType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+// renderErrorTemplate renders the error response of r with tmpl.
+func renderErrorTemplate(tmpl TemplateExecutor, r *http.Request, spec *APISpec, errMsg string, errCode int) ([]byte, error) {
+	data := errorTemplateData{
+		Message:    errMsg,
+		StatusCode: errCode,
+		APIID:      spec.APIID,
+		APIName:    spec.Name,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+	data.TraceID, data.SpanID = trace.IDs(r.Context())
+	data.RequestID = ctxGetRequestID(r)
+	if spec.EnableContextVars {
+		data.ContextVars = ctxGetData(r)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
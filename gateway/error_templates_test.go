@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestErrorResponseTemplates_Lookup(t *testing.T) {
+	assert.Nil(t, NewErrorResponseTemplates(nil, nil, log.WithField("prefix", "test")))
+
+	templates := NewErrorResponseTemplates([]apidef.ErrorTemplate{
+		{StatusCode: 403, ContentType: "application/json", Body: `{"forbidden": true}`},
+		{StatusCode: 403, ContentType: "Text/HTML", Body: `<p>forbidden</p>`},
+		{StatusCode: 403, ContentType: "application/xml", Body: `<forbidden/>`},
+		{StatusCode: 404, ContentType: "application/json", Body: `{{ .Invalid`},
+	}, []apidef.ErrorTemplate{
+		{ContentType: "text/plain", Body: `error`},
+		{StatusCode: 403, ContentType: "text/plain", Body: `forbidden`},
+	}, log.WithField("prefix", "test"))
+
+	for _, tc := range []struct {
+		name                string
+		code                int
+		accept, contentType string
+		expected            string
+	}{
+		{name: "accepted", code: 403, accept: "text/html", expected: "text/html"},
+		{name: "quality", code: 403, accept: "application/json;q=0.5, application/xml", expected: "application/xml"},
+		{name: "refused", code: 403, accept: "application/xml;q=0, text/*", expected: "text/html"},
+		{name: "request content type", code: 403, accept: "*/*", contentType: "application/xml; charset=utf-8", expected: "application/xml"},
+		{name: "JSON fallback", code: 403, accept: "image/png", expected: "application/json"},
+		{name: "global any status", code: 401, expected: "text/plain"},
+		{name: "invalid skipped", code: 404, expected: "text/plain"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tc.accept)
+			r.Header.Set("Content-Type", tc.contentType)
+
+			tmpl, contentType := templates.Lookup(r, tc.code)
+			assert.NotNil(t, tmpl)
+			assert.Equal(t, tc.expected, contentType)
+		})
+	}
+}
+
+func TestRenderErrorTemplate(t *testing.T) {
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{}}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	for _, tc := range []struct {
+		contentType, body string
+		expected          string
+	}{
+		{"text/html", `<p>{{ .Message }}</p>`, `<p>&lt;script&gt;</p>`},
+		{"text/plain", `{{ .Message }}`, `<script>`},
+		{"application/json", `{"error": {{ jsonMarshal .Message }}}`, `{"error": "\u003cscript\u003e"}`},
+	} {
+		t.Run(tc.contentType, func(t *testing.T) {
+			tmpl, err := parseErrorTemplate(tc.contentType, tc.body)
+			require.NoError(t, err)
+
+			body, err := renderErrorTemplate(tmpl, r, spec, "<script>", http.StatusForbidden)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(body))
+		})
+	}
+}
+
+func TestErrorTemplates(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.ErrorTemplates = []apidef.ErrorTemplate{
+			{ContentType: "text/plain", Body: `{{ .StatusCode }} {{ .Message }}`},
+		}
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "error-templates"
+		spec.Name = "Orders"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+		spec.ErrorTemplates = []apidef.ErrorTemplate{
+			{StatusCode: http.StatusUnauthorized, ContentType: "application/json",
+				Body: `{"api": {{ jsonMarshal .APIName }}, "error": {{ jsonMarshal .Message }}}`},
+			{StatusCode: http.StatusUnauthorized, ContentType: "text/html", Body: `<p>{{ .Message }} on {{ .Path }}</p>`},
+		}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/orders", Code: http.StatusUnauthorized, BodyMatch: `^\{"api": "Orders", "error": "Authorization field missing"\}$`,
+			HeadersMatch: map[string]string{"Content-Type": "application/json"}},
+		{Path: "/orders", Headers: map[string]string{"Accept": "text/html"}, Code: http.StatusUnauthorized,
+			BodyMatch: `^<p>Authorization field missing on /orders</p>$`, HeadersMatch: map[string]string{"Content-Type": "text/html"}},
+		{Path: "/orders", Headers: map[string]string{"Authorization": "unknown"}, Code: http.StatusForbidden,
+			BodyMatch: `^403 Access to this API has been disallowed$`, HeadersMatch: map[string]string{"Content-Type": "text/plain"}},
+	}...)
+}
@@ -29,11 +29,15 @@ const (
 	EventOrgRateLimitExceeded apidef.TykEvent = "OrgRateLimitExceeded"
 	EventTriggerExceeded      apidef.TykEvent = "TriggerExceeded"
 	EventBreakerTriggered     apidef.TykEvent = "BreakerTriggered"
+	EventResponseSizeExceeded apidef.TykEvent = "ResponseSizeExceeded"
 	EventHOSTDOWN             apidef.TykEvent = "HostDown"
 	EventHOSTUP               apidef.TykEvent = "HostUp"
 	EventTokenCreated         apidef.TykEvent = "TokenCreated"
 	EventTokenUpdated         apidef.TykEvent = "TokenUpdated"
 	EventTokenDeleted         apidef.TykEvent = "TokenDeleted"
+	EventSyntheticCheckFailed apidef.TykEvent = "SyntheticCheckFailed"
+	EventCertificateExpiring  apidef.TykEvent = "CertificateExpiringSoon"
+	EventCertificateExpired   apidef.TykEvent = "CertificateExpired"
 )
 
 // EventMetaDefault is a standard embedded struct to be used with custom event metadata types, gives an interface for
@@ -65,6 +69,41 @@ type EventCurcuitBreakerMeta struct {
 	CircuitEvent circuit.BreakerEvent
 }
 
+// EventResponseSizeExceededMeta is the metadata structure for an upstream response
+// aborted because it exceeded the response size limit.
+type EventResponseSizeExceededMeta struct {
+	EventMetaDefault
+	Path      string
+	APIID     string
+	Upstream  string
+	SizeLimit int64
+}
+
+// EventSyntheticCheckFailedMeta is the metadata structure for a synthetic check which didn't
+// get its expected response.
+type EventSyntheticCheckFailedMeta struct {
+	EventMetaDefault
+	APIID          string
+	Check          string
+	Path           string
+	ResponseCode   int
+	ExpectedStatus int
+	Error          string
+}
+
+// EventCertificateExpiryMeta is the metadata structure for a stored certificate which expired or
+// is about to.
+type EventCertificateExpiryMeta struct {
+	EventMetaDefault
+	CertID       string
+	CommonName   string
+	NotAfter     time.Time
+	DaysToExpiry int
+	// Usages are how the certificate is used: client, upstream, pinned or server.
+	Usages []string
+	APIIDs []string
+}
+
 // EventVersionFailureMeta is the metadata structure for an auth failure (EventKeyExpired)
 type EventVersionFailureMeta struct {
 	EventMetaDefault
@@ -116,13 +155,15 @@ func (gw *Gateway) EventHandlerByName(handlerConf apidef.EventHandlerTriggerConf
 			h := &JSVMEventHandler{Spec: spec, Gw: gw}
 			err := h.Init(conf)
 			if err == nil {
+				gw.globalEventsJSVMMu.Lock()
 				gw.GlobalEventsJSVM.LoadJSPaths([]string{conf["path"].(string)}, "")
+				gw.globalEventsJSVMMu.Unlock()
 			}
 			return h, err
 		}
 	case EH_CoProcessHandler:
 		if spec != nil {
-			dispatcher := loadedDrivers[spec.CustomMiddleware.Driver]
+			dispatcher := loadedDriver(spec.CustomMiddleware.Driver)
 			if dispatcher == nil {
 				return nil, errors.New("no plugin driver is available")
 			}
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	defaultFederationMaxHops = 5
+	defaultFederationHopTTL  = 30
+	// federationNonceKeyPrefix prefixes the nonces of the hops received, which are rejected when reused.
+	federationNonceKeyPrefix = "federation-nonce-"
+)
+
+var (
+	errFederationHopInvalid  = errors.New("Invalid federation hop")
+	errFederationHopReused   = errors.New("Federation hop already used")
+	errFederationHopExpired  = errors.New("Federation hop expired")
+	errFederationHopRequired = errors.New("Access to this resource is restricted to federated gateways")
+	errFederationLoop        = errors.New("Federation loop detected")
+	errFederationHopLimit    = errors.New("Federation hop limit exceeded")
+)
+
+// FederationIdentity identifies the consumer of a request at the gateway the request entered the
+// federation through.
+type FederationIdentity struct {
+	OrgID   string `json:"org_id,omitempty"`
+	KeyHash string `json:"key_hash,omitempty"`
+	Alias   string `json:"alias,omitempty"`
+}
+
+// FederationHop is the signed context of a request proxied between federated gateways. A hop is
+// only valid once, for the request it was signed for.
+type FederationHop struct {
+	// Hops are the names of the gateways the request went through, starting with the one it
+	// entered the federation through.
+	Hops     []string           `json:"hops"`
+	Identity FederationIdentity `json:"identity"`
+	Expires  int64              `json:"exp"`
+	Nonce    string             `json:"nonce"`
+	// Method, Path and Target are the method, path and host of the proxied request.
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Target string `json:"target"`
+}
+
+// federationName is the name of the gateway in the hops.
+func (gw *Gateway) federationName() string {
+	if name := gw.GetConfig().GatewayFederation.Name; name != "" {
+		return name
+	}
+	return gw.GetNodeID()
+}
+
+func federationSignature(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// signFederationHop encodes hop as the base64 encoded JSON payload and its signature, separated by a dot.
+func signFederationHop(secret string, hop FederationHop) (string, error) {
+	payload, err := json.Marshal(hop)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(federationSignature(secret, payload)), nil
+}
+
+// verifyFederationHop decodes a hop header signed with secret for the request r.
+func verifyFederationHop(secret, header string, r *http.Request, now time.Time) (FederationHop, error) {
+	var hop FederationHop
+	parts := strings.Split(header, ".")
+	if len(parts) != 2 {
+		return hop, errFederationHopInvalid
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return hop, errFederationHopInvalid
+	}
+	signature, err := enc.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, federationSignature(secret, payload)) {
+		return hop, errFederationHopInvalid
+	}
+
+	if err := json.Unmarshal(payload, &hop); err != nil || len(hop.Hops) == 0 || hop.Nonce == "" {
+		return hop, errFederationHopInvalid
+	}
+	if hop.Method != r.Method || hop.Path != r.URL.Path || hop.Target != r.Host {
+		return hop, errFederationHopInvalid
+	}
+	if now.Unix() > hop.Expires {
+		return hop, errFederationHopExpired
+	}
+	return hop, nil
+}
+
+// setFederationHop sets the hop header of a request proxied to a federated gateway, adding this
+// gateway to the hops the request came through. The identity is the one of the gateway the request
+// entered the federation through, or else the one of the session of the request.
+func (gw *Gateway) setFederationHop(req *http.Request) {
+	conf := gw.GetConfig().GatewayFederation
+	if !conf.Enabled || conf.Secret == "" {
+		log.Warning("Gateway federation isn't configured, the request is proxied without hop")
+		return
+	}
+
+	var hop FederationHop
+	if prev := ctxGetFederationHop(req); prev != nil {
+		hop.Hops = append(hop.Hops, prev.Hops...)
+		hop.Identity = prev.Identity
+	} else if session := ctxGetSession(req); session != nil {
+		hop.Identity = FederationIdentity{OrgID: session.OrgID, Alias: session.Alias}
+		// the key is always hashed, the gateways of the federation don't share keys
+		if session.KeyID != "" {
+			hop.Identity.KeyHash = storage.HashStr(session.KeyID)
+		}
+	}
+	hop.Hops = append(hop.Hops, gw.federationName())
+
+	hop.Nonce = uuid.NewV4().String()
+	hop.Method, hop.Path, hop.Target = req.Method, req.URL.Path, req.Host
+	if hop.Target == "" {
+		hop.Target = req.URL.Host
+	}
+
+	ttl := conf.HopTTL
+	if ttl <= 0 {
+		ttl = defaultFederationHopTTL
+	}
+	hop.Expires = time.Now().Unix() + ttl
+
+	header, err := signFederationHop(conf.Secret, hop)
+	if err != nil {
+		log.WithError(err).Error("Could not sign federation hop")
+		return
+	}
+	req.Header.Set(headers.XTykFederation, header)
+}
+
+func ctxSetFederationHop(r *http.Request, hop *FederationHop) {
+	setCtxValue(r, ctx.FederationHop, hop)
+}
+
+func ctxGetFederationHop(r *http.Request) *FederationHop {
+	if v := r.Context().Value(ctx.FederationHop); v != nil {
+		return v.(*FederationHop)
+	}
+	return nil
+}
+
+// GatewayFederationMiddleware verifies the hops of requests proxied by federated gateways.
+type GatewayFederationMiddleware struct {
+	BaseMiddleware
+	nonceStore storage.Handler
+}
+
+func (m *GatewayFederationMiddleware) Name() string {
+	return "GatewayFederationMiddleware"
+}
+
+func (m *GatewayFederationMiddleware) EnabledForSpec() bool {
+	if !m.Gw.GetConfig().GatewayFederation.Enabled && !m.Spec.GatewayFederation.Require {
+		return false
+	}
+
+	m.nonceStore = &storage.RedisCluster{KeyPrefix: federationNonceKeyPrefix, RedisController: m.Gw.RedisController}
+	return true
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *GatewayFederationMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	header := r.Header.Get(headers.XTykFederation)
+	if header == "" {
+		if m.Spec.GatewayFederation.Require {
+			return errFederationHopRequired, http.StatusForbidden
+		}
+		return nil, http.StatusOK
+	}
+	// the hop is forwarded in the context, signed again if the upstream is federated
+	r.Header.Del(headers.XTykFederation)
+
+	conf := m.Gw.GetConfig().GatewayFederation
+	if !conf.Enabled || conf.Secret == "" {
+		return errFederationHopInvalid, http.StatusForbidden
+	}
+
+	now := time.Now()
+	hop, err := verifyFederationHop(conf.Secret, header, r, now)
+	if err != nil {
+		m.Logger().WithError(err).Warning("Rejected federation hop")
+		return err, http.StatusForbidden
+	}
+
+	// the nonce is kept until the hop expires, the store doesn't prefix the keys it increments
+	if m.nonceStore.IncrememntWithExpire(federationNonceKeyPrefix+hop.Nonce, hop.Expires-now.Unix()+1) != 1 {
+		m.Logger().WithError(errFederationHopReused).Warning("Rejected federation hop")
+		return errFederationHopReused, http.StatusForbidden
+	}
+
+	maxHops := conf.MaxHops
+	if maxHops <= 0 {
+		maxHops = defaultFederationMaxHops
+	}
+	if contains(hop.Hops, m.Gw.federationName()) {
+		m.Logger().WithField("hops", hop.Hops).Warning("Federation loop detected")
+		return errFederationLoop, http.StatusLoopDetected
+	}
+	if len(hop.Hops) > maxHops {
+		return errFederationHopLimit, http.StatusLoopDetected
+	}
+
+	ctxSetFederationHop(r, &hop)
+	return nil, http.StatusOK
+}
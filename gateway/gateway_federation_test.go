@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestGatewayFederation(t *testing.T) {
+	const secret = "federation-secret"
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.GatewayFederation = config.GatewayFederationConfig{Enabled: true, Name: "eu", Secret: secret, MaxHops: 2}
+	})
+	defer ts.Close()
+
+	hopRequests := make(chan *http.Request, 1)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hopRequests <- r
+	}))
+	defer peer.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "edge"
+		spec.Proxy.ListenPath = "/edge/"
+		spec.Proxy.TargetURL = peer.URL
+		spec.UseKeylessAccess = false
+		spec.GatewayFederation.Forward = true
+	}, func(spec *APISpec) {
+		spec.APIID = "core"
+		spec.Proxy.ListenPath = "/core/"
+		spec.GatewayFederation.Require = true
+		spec.EnableContextVars = true
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.GlobalHeaders = map[string]string{
+				"X-Origin": "$tyk_context.federation_origin",
+				"X-Alias":  "$tyk_context.federation_alias",
+			}
+		})
+	})
+
+	session, key := ts.CreateSession(func(s *user.SessionState) {
+		s.Alias = "consumer"
+		s.AccessRights = map[string]user.AccessDefinition{"edge": {APIID: "edge"}}
+	})
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/edge/", Headers: map[string]string{headers.Authorization: key}, Code: http.StatusOK})
+	peerReq := <-hopRequests
+
+	hop, err := verifyFederationHop(secret, peerReq.Header.Get(headers.XTykFederation), peerReq, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eu"}, hop.Hops)
+	assert.Equal(t, FederationIdentity{OrgID: session.OrgID, KeyHash: storage.HashStr(key), Alias: "consumer"}, hop.Identity)
+	assert.NotEmpty(t, hop.Nonce)
+	assert.Equal(t, http.MethodGet, hop.Method)
+	assert.Equal(t, "/", hop.Path)
+	assert.Equal(t, strings.TrimPrefix(peer.URL, "http://"), hop.Target)
+
+	hopHeader := func(header string) map[string]string {
+		return map[string]string{headers.XTykFederation: header}
+	}
+	// sign signs a hop from the gateways for a request to path of this gateway
+	sign := func(path string, expires int64, hops ...string) string {
+		header, err := signFederationHop(secret, FederationHop{Hops: hops, Identity: hop.Identity, Expires: expires,
+			Nonce: uuid.NewV4().String(), Method: http.MethodGet, Path: path, Target: strings.TrimPrefix(ts.URL, "http://")})
+		require.NoError(t, err)
+		return header
+	}
+	valid := time.Now().Unix() + 30
+
+	t.Run("loop", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Path: "/core/", Headers: hopHeader(sign("/core/", valid, "eu")), Code: http.StatusLoopDetected})
+	})
+
+	conf := ts.Gw.GetConfig()
+	conf.GatewayFederation.Name = "us"
+	ts.Gw.SetConfig(conf)
+
+	header := sign("/core/", valid, "eu")
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/core/", Headers: hopHeader(header), Code: http.StatusOK,
+			BodyMatch: `"X-Origin":"eu"`, BodyNotMatch: headers.XTykFederation},
+		{Path: "/core/", Headers: hopHeader(header), Code: http.StatusForbidden, BodyMatch: errFederationHopReused.Error()},
+		{Path: "/core/", Headers: hopHeader(sign("/core/", valid, "eu")), Code: http.StatusOK, BodyMatch: `"X-Alias":"consumer"`},
+		{Path: "/core/", Code: http.StatusForbidden},
+		{Path: "/core/", Headers: hopHeader(sign("/core/", valid, "eu", "asia", "africa")), Code: http.StatusLoopDetected},
+		{Path: "/core/", Headers: hopHeader(sign("/core/", valid, "eu") + "x"), Code: http.StatusForbidden},
+		{Path: "/core/", Headers: hopHeader(sign("/core/other", valid, "eu")), Code: http.StatusForbidden},
+		{Method: http.MethodPost, Path: "/core/", Headers: hopHeader(sign("/core/", valid, "eu")), Code: http.StatusForbidden},
+	}...)
+
+	t.Run("expired", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Path: "/core/", Headers: hopHeader(sign("/core/", time.Now().Unix()-1, "eu")), Code: http.StatusForbidden})
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		other, err := signFederationHop("other", FederationHop{Hops: []string{"eu"}, Expires: valid, Nonce: uuid.NewV4().String(),
+			Method: http.MethodGet, Path: "/core/", Target: strings.TrimPrefix(ts.URL, "http://")})
+		require.NoError(t, err)
+		_, _ = ts.Run(t, test.TestCase{Path: "/core/", Headers: hopHeader(other), Code: http.StatusForbidden})
+	})
+}
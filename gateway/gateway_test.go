@@ -1190,6 +1190,8 @@ func TestGatewayHealthCheck(t *testing.T) {
 		t.Run("Without APIs", func(t *testing.T) {
 			_, _ = ts.Run(t, []test.TestCase{
 				{Path: "/hello", BodyMatch: `"status":"pass"`, Code: http.StatusOK},
+				{Path: "/hello/live", BodyMatch: `"status":"pass"`, Code: http.StatusOK},
+				{Path: "/hello/ready", BodyMatch: `"redis":\{"status":"pass".*"observedUnit":"ms"`, Code: http.StatusOK},
 			}...)
 		})
 
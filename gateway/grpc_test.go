@@ -884,3 +884,33 @@ func TestGRPC_Stream_H2C(t *testing.T) {
 	// gRPC client
 	testGRPCStreamClient(t, "localhost:6666", grpc.WithInsecure())
 }
+
+func TestHTTP2_h2CTransportOption(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "h2c-transport"
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Proxy.Transport.H2C = true
+		spec.Proxy.Transport.MaxIdleConnsPerHost = 500
+		spec.Proxy.Transport.IdleConnTimeout = 90
+		spec.Proxy.Transport.TLSSessionCacheSize = 64
+	})
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusOK, BodyMatch: "^HTTP/2.0$"})
+
+	transport := ts.Gw.getApiSpec("h2c-transport").HTTPTransport.transport
+	if transport.MaxIdleConnsPerHost != 500 || transport.IdleConnTimeout != 90*time.Second {
+		t.Errorf("expected 500 idle conns per host kept 90s, got %d kept %s", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if transport.TLSClientConfig.ClientSessionCache == nil {
+		t.Error("expected TLS sessions to be cached")
+	}
+}
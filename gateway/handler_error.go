@@ -17,6 +17,7 @@ import (
 
 	"github.com/TykTechnologies/tyk/headers"
 	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/trace"
 )
 
 const (
@@ -29,6 +30,7 @@ const (
 	MsgBearerMailformed    = "Bearer token malformed"
 	MsgKeyNotAuthorized    = "Key not authorised"
 	MsgOauthClientRevoked  = "Key not authorised. OAuth client access was revoked"
+	MsgOauthClientLimited  = "OAuth client rate limit exceeded"
 )
 
 var errCustomBodyResponse = errors.New("errCustomBodyResponse")
@@ -81,6 +83,11 @@ func defaultTykErrors() {
 		Message: MsgOauthClientRevoked,
 		Code:    http.StatusForbidden,
 	}
+
+	TykErrors[ErrOAuthClientRateLimited] = config.TykError{
+		Message: MsgOauthClientLimited,
+		Code:    http.StatusTooManyRequests,
+	}
 }
 
 func overrideTykErrors(gw *Gateway) {
@@ -104,6 +111,9 @@ func overrideTykErrors(gw *Gateway) {
 // APIError is generic error object returned if there is something wrong with the request
 type APIError struct {
 	Message template.HTML
+	// TraceID and SpanID correlate the error with the trace of the request, if traced.
+	TraceID string
+	SpanID  string
 }
 
 // ErrorHandler is invoked whenever there is an issue with a proxied request, most middleware will invoke
@@ -123,6 +133,13 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 	defer e.Base().UpdateRequestSession(r)
 	response := &http.Response{}
 
+	if writeResponse && errMsg != errCustomBodyResponse.Error() {
+		if tmpl, contentType := e.Spec.ErrorResponseTemplates.Lookup(r, errCode); tmpl != nil {
+			e.writeTemplatedError(w, r, response, tmpl, contentType, errMsg, errCode)
+			writeResponse = false
+		}
+	}
+
 	if writeResponse {
 		var templateExtension string
 		contentType := r.Header.Get(headers.ContentType)
@@ -183,7 +200,8 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 			var tmplExecutor TemplateExecutor
 			tmplExecutor = tmpl
 
-			apiError := APIError{template.HTML(template.JSEscapeString(errMsg))}
+			apiError := APIError{Message: template.HTML(template.JSEscapeString(errMsg))}
+			apiError.TraceID, apiError.SpanID = trace.IDs(r.Context())
 			if contentType == headers.ApplicationXML || contentType == headers.TextXML {
 				apiError.Message = template.HTML(errMsg)
 
@@ -204,10 +222,16 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 		pprof.WriteHeapProfile(memProfFile)
 	}
 
+	e.Gw.recordBillingUsage(r, e.Spec, errCode)
+
 	if e.Spec.DoNotTrack || ctxGetDoNotTrack(r) {
 		return
 	}
 
+	if !e.Spec.AnalyticsSampler.ShouldRecord(e.Spec, r, errCode) {
+		return
+	}
+
 	// Track the key ID if it exists
 	token := ctxGetAuthToken(r)
 	var alias string
@@ -251,6 +275,10 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 			tags = append(tags, e.Spec.Tags...)
 		}
 
+		tags = botDetectionTags(e.Spec, ctxGetBotScore(r), tags)
+		tags = deprecationTags(r, tags)
+		tags = concurrencyLimitTags(r, tags)
+
 		rawRequest := ""
 		rawResponse := ""
 		if recordDetail(r, e.Spec) {
@@ -279,6 +307,8 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 			host = e.Spec.target.Host
 		}
 
+		latency := latencyBreakdown(r, Latency{})
+
 		record := AnalyticsRecord{
 			r.Method,
 			host,
@@ -298,8 +328,8 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 			e.Spec.APIID,
 			e.Spec.OrgID,
 			oauthClientID,
-			0,
-			Latency{},
+			latency.Total,
+			latency,
 			rawRequest,
 			rawResponse,
 			ip,
@@ -309,10 +339,18 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 			alias,
 			trackEP,
 			t,
+			ctxGetUpstreamRetries(r),
+			ctxGetGraphQLStats(r),
+			ctxGetBotScore(r),
+			ctxGetErrorReason(r),
+			ctxGetRequestID(r),
 		}
 
 		if e.Spec.GlobalConfig.AnalyticsConfig.EnableGeoIP {
 			record.GetGeo(ip, e.Gw)
+		} else {
+			// resolved by the GeoIP access control
+			record.Geo.Country.ISOCode = ctxGetGeoIPCountry(r)
 		}
 
 		expiresAfter := e.Spec.ExpireAnalyticsAfter
@@ -341,3 +379,30 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 		pprof.WriteHeapProfile(memProfFile)
 	}
 }
+
+// writeTemplatedError writes the error response rendered with an error template of the API or the
+// global ones, in the content type negotiated with the client.
+func (e *ErrorHandler) writeTemplatedError(w http.ResponseWriter, r *http.Request, response *http.Response, tmpl TemplateExecutor, contentType, errMsg string, errCode int) {
+	body, err := renderErrorTemplate(tmpl, r, e.Spec, errMsg, errCode)
+	if err != nil {
+		e.Logger().WithError(err).Error("Could not render the error template")
+	}
+
+	response.Header = http.Header{}
+	response.StatusCode = errCode
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	w.Header().Set(headers.ContentType, contentType)
+	response.Header.Set(headers.ContentType, contentType)
+	if !e.Spec.GlobalConfig.HideGeneratorHeader {
+		w.Header().Add(headers.XGenerator, "tyk.io")
+		response.Header.Add(headers.XGenerator, "tyk.io")
+	}
+	if e.Spec.GlobalConfig.CloseConnections {
+		w.Header().Add(headers.Connection, "close")
+		response.Header.Add(headers.Connection, "close")
+	}
+
+	w.WriteHeader(errCode)
+	w.Write(body)
+}
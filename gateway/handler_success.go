@@ -28,6 +28,8 @@ type ProxyResponse struct {
 	// UpstreamLatency the time it takes to do roundtrip to upstream. Total time
 	// taken for the gateway to receive response from upstream host.
 	UpstreamLatency time.Duration
+	// Retries is the number of times the upstream request was retried.
+	Retries int
 }
 
 type ReturningHttpHandler interface {
@@ -116,11 +118,18 @@ func getSessionTags(session *user.SessionState) []string {
 }
 
 func (s *SuccessHandler) RecordHit(r *http.Request, timing Latency, code int, responseCopy *http.Response) {
+	s.Gw.recordBillingUsage(r, s.Spec, code)
+	s.Gw.recordTrafficSample(r, s.Spec, code, timing, responseCopy)
+	s.Gw.recordDebugCapture(r, s.Spec, code, timing, responseCopy)
 
 	if s.Spec.DoNotTrack || ctxGetDoNotTrack(r) {
 		return
 	}
 
+	if !s.Spec.AnalyticsSampler.ShouldRecord(s.Spec, r, code) {
+		return
+	}
+
 	ip := request.RealIP(r)
 	if s.Spec.GlobalConfig.StoreAnalytics(ip) {
 
@@ -154,6 +163,10 @@ func (s *SuccessHandler) RecordHit(r *http.Request, timing Latency, code int, re
 			tags = append(tags, s.Spec.Tags...)
 		}
 
+		tags = botDetectionTags(s.Spec, ctxGetBotScore(r), tags)
+		tags = deprecationTags(r, tags)
+		tags = concurrencyLimitTags(r, tags)
+
 		rawRequest := ""
 		rawResponse := ""
 
@@ -227,10 +240,18 @@ func (s *SuccessHandler) RecordHit(r *http.Request, timing Latency, code int, re
 			alias,
 			trackEP,
 			t,
+			ctxGetUpstreamRetries(r),
+			ctxGetGraphQLStats(r),
+			ctxGetBotScore(r),
+			"",
+			ctxGetRequestID(r),
 		}
 
 		if s.Spec.GlobalConfig.AnalyticsConfig.EnableGeoIP {
 			record.GetGeo(ip, s.Gw)
+		} else {
+			// resolved by the GeoIP access control
+			record.Geo.Country.ISOCode = ctxGetGeoIPCountry(r)
 		}
 
 		expiresAfter := s.Spec.ExpireAnalyticsAfter
@@ -331,10 +352,11 @@ func (s *SuccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) *http
 	log.Debug("Upstream request took (ms): ", millisec)
 
 	if resp.Response != nil {
-		latency := Latency{
+		latency := latencyBreakdown(r, Latency{
 			Total:    int64(millisec),
 			Upstream: int64(DurationToMillisecond(resp.UpstreamLatency)),
-		}
+		})
+		ctxSetUpstreamRetries(r, resp.Retries)
 		s.RecordHit(r, latency, resp.Response.StatusCode, resp.Response)
 	}
 	log.Debug("Done proxy")
@@ -371,10 +393,11 @@ func (s *SuccessHandler) ServeHTTPWithCache(w http.ResponseWriter, r *http.Reque
 	log.Debug("Upstream request took (ms): ", millisec)
 
 	if inRes.Response != nil {
-		latency := Latency{
+		latency := latencyBreakdown(r, Latency{
 			Total:    int64(millisec),
 			Upstream: int64(DurationToMillisecond(inRes.UpstreamLatency)),
-		}
+		})
+		ctxSetUpstreamRetries(r, inRes.Retries)
 		s.RecordHit(r, latency, inRes.Response.StatusCode, inRes.Response)
 	}
 
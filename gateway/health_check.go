@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/dnscache"
 	"github.com/TykTechnologies/tyk/rpc"
 
 	"github.com/TykTechnologies/tyk/headers"
@@ -32,6 +36,14 @@ const (
 	System                             = "system"
 )
 
+const (
+	livenessCheckPath  = "/live"
+	readinessCheckPath = "/ready"
+
+	// dnsHealthCheckTimeout bounds the resolution of the DNS check host.
+	dnsHealthCheckTimeout = 5 * time.Second
+)
+
 var (
 	healthCheckInfo atomic.Value
 	healthCheckLock sync.Mutex
@@ -64,12 +76,20 @@ type HealthCheckItem struct {
 	ComponentType string            `json:"componentType,omitempty"`
 	ComponentID   string            `json:"componentId,omitempty"`
 	Time          string            `json:"time"`
+	// ObservedValue is the latency of the check in ObservedUnit.
+	ObservedValue float64 `json:"observedValue"`
+	ObservedUnit  string  `json:"observedUnit,omitempty"`
+	// Metrics are the metrics of the component, the metrics of the connection pools for Redis.
+	Metrics interface{} `json:"metrics,omitempty"`
 }
 
 func (gw *Gateway) initHealthCheck(ctx context.Context) {
 	setCurrentHealthCheckInfo(make(map[string]HealthCheckItem, 3))
 
 	go func(ctx context.Context) {
+		// the first checks are gathered right away so that readiness doesn't wait for the ticker
+		gw.gatherHealthChecks()
+
 		var n = gw.GetConfig().LivenessCheck.CheckDuration
 
 		if n == 0 {
@@ -100,49 +120,51 @@ type SafeHealthCheck struct {
 	mux  sync.Mutex
 }
 
-func (gw *Gateway) gatherHealthChecks() {
-	allInfos := SafeHealthCheck{info: make(map[string]HealthCheckItem, 3)}
-
-	redisStore := storage.RedisCluster{KeyPrefix: "livenesscheck-", RedisController: gw.RedisController}
-
-	key := "tyk-liveness-probe"
-
-	var wg sync.WaitGroup
-
+// add runs the health check of the component name in the background, timing it.
+func (h *SafeHealthCheck) add(wg *sync.WaitGroup, name string, componentType HealthCheckComponentType, check func(item *HealthCheckItem)) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
 		var checkItem = HealthCheckItem{
 			Status:        Pass,
-			ComponentType: Datastore,
+			ComponentType: string(componentType),
 			Time:          time.Now().Format(time.RFC3339),
+			ObservedUnit:  "ms",
 		}
 
+		start := time.Now()
+		check(&checkItem)
+		checkItem.ObservedValue = float64(time.Since(start)) / float64(time.Millisecond)
+
+		h.mux.Lock()
+		h.info[name] = checkItem
+		h.mux.Unlock()
+	}()
+}
+
+func (gw *Gateway) gatherHealthChecks() {
+	allInfos := SafeHealthCheck{info: make(map[string]HealthCheckItem, 5)}
+	conf := gw.GetConfig()
+
+	redisStore := storage.RedisCluster{KeyPrefix: "livenesscheck-", RedisController: gw.RedisController}
+
+	key := "tyk-liveness-probe"
+
+	var wg sync.WaitGroup
+
+	allInfos.add(&wg, "redis", Datastore, func(checkItem *HealthCheckItem) {
 		err := redisStore.SetRawKey(key, key, 10)
 		if err != nil {
 			mainLog.WithField("liveness-check", true).WithError(err).Error("Redis health check failed")
 			checkItem.Output = err.Error()
 			checkItem.Status = Fail
 		}
+		checkItem.Metrics = gw.RedisController.Metrics()
+	})
 
-		allInfos.mux.Lock()
-		allInfos.info["redis"] = checkItem
-		allInfos.mux.Unlock()
-	}()
-
-	if gw.GetConfig().UseDBAppConfigs {
-		wg.Add(1)
-
-		go func() {
-			defer wg.Done()
-
-			var checkItem = HealthCheckItem{
-				Status:        Pass,
-				ComponentType: Datastore,
-				Time:          time.Now().Format(time.RFC3339),
-			}
-
+	if conf.UseDBAppConfigs {
+		allInfos.add(&wg, "dashboard", System, func(checkItem *HealthCheckItem) {
 			if gw.DashService == nil {
 				err := errors.New("Dashboard service not initialized")
 				mainLog.WithField("liveness-check", true).Error(err)
@@ -153,39 +175,43 @@ func (gw *Gateway) gatherHealthChecks() {
 				checkItem.Output = err.Error()
 				checkItem.Status = Fail
 			}
-
-			checkItem.ComponentType = System
-
-			allInfos.mux.Lock()
-			allInfos.info["dashboard"] = checkItem
-			allInfos.mux.Unlock()
-		}()
+		})
 	}
 
-	if gw.GetConfig().Policies.PolicySource == "rpc" {
-
-		wg.Add(1)
-
-		go func() {
-			defer wg.Done()
-
-			var checkItem = HealthCheckItem{
-				Status:        Pass,
-				ComponentType: Datastore,
-				Time:          time.Now().Format(time.RFC3339),
-			}
-
+	if conf.Policies.PolicySource == "rpc" {
+		allInfos.add(&wg, "rpc", System, func(checkItem *HealthCheckItem) {
 			if !rpc.Login() {
 				checkItem.Output = "Could not connect to RPC"
 				checkItem.Status = Fail
 			}
+		})
+	}
+
+	if conf.LivenessCheck.DNSCheckHost != "" || gw.dnsCacheManager != nil && gw.dnsCacheManager.IsCacheEnabled() {
+		allInfos.add(&wg, "dns", System, func(checkItem *HealthCheckItem) {
+			gw.checkDNSHealth(conf.LivenessCheck.DNSCheckHost, checkItem)
+		})
+	}
 
-			checkItem.ComponentType = System
+	var certIDs []string
+	certIDs = append(certIDs, conf.HttpServerOptions.SSLCertificates...)
+	certIDs = append(certIDs, conf.Security.Certificates.API...)
+	if len(certIDs) > 0 && gw.CertificateManager != nil {
+		allInfos.add(&wg, "certificates", Component, func(checkItem *HealthCheckItem) {
+			gw.checkCertificatesHealth(certIDs, checkItem)
+		})
+	}
+
+	if !conf.LocalSessionCache.DisableCacheSessionState {
+		allInfos.add(&wg, "session_cache", Component, func(checkItem *HealthCheckItem) {
+			checkItem.Metrics = gw.sessionCacheMetrics()
+		})
+	}
 
-			allInfos.mux.Lock()
-			allInfos.info["rpc"] = checkItem
-			allInfos.mux.Unlock()
-		}()
+	if gw.usesBasicAuth() {
+		allInfos.add(&wg, "basic_auth_hash", Component, func(checkItem *HealthCheckItem) {
+			checkItem.Metrics = gw.basicAuthHashMetrics()
+		})
 	}
 
 	wg.Wait()
@@ -195,6 +221,62 @@ func (gw *Gateway) gatherHealthChecks() {
 	allInfos.mux.Unlock()
 }
 
+// checkDNSHealth resolves host if set, through the DNS cache when enabled, reporting the metrics
+// of the cache.
+func (gw *Gateway) checkDNSHealth(host string, checkItem *HealthCheckItem) {
+	cacheEnabled := gw.dnsCacheManager != nil && gw.dnsCacheManager.IsCacheEnabled()
+
+	if host != "" {
+		var err error
+		if cacheEnabled {
+			_, err = gw.dnsCacheManager.CacheStorage().FetchItem(host)
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), dnsHealthCheckTimeout)
+			defer cancel()
+			_, err = net.DefaultResolver.LookupHost(ctx, host)
+		}
+
+		if err != nil {
+			mainLog.WithField("liveness-check", true).WithError(err).Error("DNS health check failed")
+			checkItem.Output = err.Error()
+			checkItem.Status = Fail
+		}
+	}
+
+	if cacheEnabled {
+		if storage, ok := gw.dnsCacheManager.CacheStorage().(*dnscache.DnsCacheStorage); ok {
+			checkItem.Metrics = storage.Metrics()
+		}
+	}
+}
+
+// checkCertificatesHealth fails if any of the server certificates can't be loaded from the
+// certificate store, and warns if any expired.
+func (gw *Gateway) checkCertificatesHealth(certIDs []string, checkItem *HealthCheckItem) {
+	var missing []string
+	for i, cert := range gw.CertificateManager.List(certIDs, certs.CertificatePrivate) {
+		if cert == nil {
+			missing = append(missing, certIDs[i])
+		}
+	}
+	if len(missing) > 0 {
+		checkItem.Output = "Could not load certificates: " + strings.Join(missing, ", ")
+		checkItem.Status = Fail
+		return
+	}
+
+	now := time.Now()
+	if expired := gw.CertificateManager.ExpiringCertificates(certIDs, now, now); len(expired) > 0 {
+		ids := make([]string, len(expired))
+		for i, expiry := range expired {
+			ids[i] = expiry.ID
+		}
+		checkItem.Output = "Expired certificates: " + strings.Join(ids, ", ")
+		checkItem.Status = Warn
+	}
+	checkItem.Metrics = map[string]int{"certificates": len(certIDs)}
+}
+
 func (gw *Gateway) liveCheckHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		doJSONWrite(w, http.StatusMethodNotAllowed, apiError(http.StatusText(http.StatusMethodNotAllowed)))
@@ -233,6 +315,62 @@ func (gw *Gateway) liveCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 	res.Status = status
 
+	gw.writeHealthCheck(w, http.StatusOK, res)
+}
+
+// livenessCheckHandler reports whether the gateway process is up, regardless of its components,
+// for the probes restarting unresponsive gateways.
+func (gw *Gateway) livenessCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		doJSONWrite(w, http.StatusMethodNotAllowed, apiError(http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+
+	gw.writeHealthCheck(w, http.StatusOK, HealthCheckResponse{
+		Status:      Pass,
+		Version:     VERSION,
+		Description: "Tyk GW",
+	})
+}
+
+// readinessCheckHandler reports whether the gateway can serve traffic, failing with 503 as long
+// as the components weren't checked yet or any failed. Components with warnings don't fail it.
+func (gw *Gateway) readinessCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		doJSONWrite(w, http.StatusMethodNotAllowed, apiError(http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+
+	checks := getHealthCheckInfo()
+
+	res := HealthCheckResponse{
+		Status:      Pass,
+		Version:     VERSION,
+		Description: "Tyk GW",
+		Details:     checks,
+	}
+
+	if len(checks) == 0 {
+		res.Status = Fail
+		res.Output = "Health checks pending"
+	}
+	for _, v := range checks {
+		switch {
+		case v.Status == Fail:
+			res.Status = Fail
+		case v.Status == Warn && res.Status == Pass:
+			res.Status = Warn
+		}
+	}
+
+	code := http.StatusOK
+	if res.Status == Fail {
+		code = http.StatusServiceUnavailable
+	}
+	gw.writeHealthCheck(w, code, res)
+}
+
+func (gw *Gateway) writeHealthCheck(w http.ResponseWriter, code int, res HealthCheckResponse) {
 	w.Header().Set("Content-Type", headers.ApplicationJSON)
 
 	// If this option is not set, or is explicitly set to false, add the mascot headers
@@ -240,6 +378,6 @@ func (gw *Gateway) liveCheckHandler(w http.ResponseWriter, r *http.Request) {
 		addMascotHeaders(w)
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(res)
 }
@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestReadinessCheckHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	defer setCurrentHealthCheckInfo(map[string]HealthCheckItem{})
+
+	for _, tc := range []struct {
+		name     string
+		checks   map[string]HealthCheckItem
+		code     int
+		expected HealthCheckStatus
+	}{
+		{name: "pending", checks: map[string]HealthCheckItem{}, code: http.StatusServiceUnavailable, expected: Fail},
+		{name: "pass", checks: map[string]HealthCheckItem{"redis": {Status: Pass}}, code: http.StatusOK, expected: Pass},
+		{name: "warn", checks: map[string]HealthCheckItem{"redis": {Status: Pass}, "certificates": {Status: Warn}},
+			code: http.StatusOK, expected: Warn},
+		{name: "fail", checks: map[string]HealthCheckItem{"redis": {Status: Fail}, "certificates": {Status: Warn}},
+			code: http.StatusServiceUnavailable, expected: Fail},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setCurrentHealthCheckInfo(tc.checks)
+
+			w := httptest.NewRecorder()
+			gw.readinessCheckHandler(w, httptest.NewRequest(http.MethodGet, "/hello/ready", nil))
+			assert.Equal(t, tc.code, w.Code)
+
+			var res HealthCheckResponse
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			assert.Equal(t, tc.expected, res.Status)
+
+			w = httptest.NewRecorder()
+			gw.livenessCheckHandler(w, httptest.NewRequest(http.MethodGet, "/hello/live", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
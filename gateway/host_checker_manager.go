@@ -135,6 +135,13 @@ func (hc *HostCheckerManager) checkPollerLoop(ctx context.Context) {
 	}
 }
 
+func (hc *HostCheckerManager) pollerCacheKey() string {
+	if group := hc.Gw.GetConfig().UptimeTests.PollerGroup; group != "" {
+		return PollerCacheKey + "." + group
+	}
+	return PollerCacheKey
+}
+
 func (hc *HostCheckerManager) AmIPolling() bool {
 	if hc.store == nil {
 		log.WithFields(logrus.Fields{
@@ -142,10 +149,7 @@ func (hc *HostCheckerManager) AmIPolling() bool {
 		}).Error("No storage instance set for uptime tests! Disabling poller...")
 		return false
 	}
-	pollerCacheKey := PollerCacheKey
-	if hc.Gw.GetConfig().UptimeTests.PollerGroup != "" {
-		pollerCacheKey = pollerCacheKey + "." + hc.Gw.GetConfig().UptimeTests.PollerGroup
-	}
+	pollerCacheKey := hc.pollerCacheKey()
 
 	activeInstance, err := hc.store.GetKey(pollerCacheKey)
 	if err != nil {
@@ -221,6 +225,22 @@ func (hc *HostCheckerManager) StopPoller() {
 	hc.checkerMu.Unlock()
 }
 
+// Deregister stops the uptime tests of this gateway. If it was the active poller, another gateway
+// of the poller group takes over on its next check instead of after the poller key expires.
+func (hc *HostCheckerManager) Deregister() {
+	hc.stopLoop = true
+	if hc.pollerStarted {
+		hc.StopPoller()
+		hc.pollerStarted = false
+	}
+	if hc.store == nil {
+		return
+	}
+	if activeInstance, err := hc.store.GetKey(hc.pollerCacheKey()); err == nil && activeInstance == hc.Id {
+		hc.store.DeleteKey(hc.pollerCacheKey())
+	}
+}
+
 func (hc *HostCheckerManager) getHostKey(report HostHealthReport) string {
 	return PoolerHostSentinelKeyPrefix + report.MetaData[UnHealthyHostMetaDataHostKey]
 }
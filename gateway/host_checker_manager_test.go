@@ -89,6 +89,36 @@ func TestAmIPolling(t *testing.T) {
 
 }
 
+func TestHostCheckerManagerDeregister(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	redisStorage := &storage.RedisCluster{KeyPrefix: "host-checker-test:", RedisController: ts.Gw.RedisController}
+	redisStorage.DeleteKey(PollerCacheKey)
+	hc := HostCheckerManager{Gw: ts.Gw}
+	hc.Init(redisStorage)
+	hc2 := HostCheckerManager{Gw: ts.Gw}
+	hc2.Init(redisStorage)
+
+	if !hc.AmIPolling() || hc2.AmIPolling() {
+		t.Fatal("the first host checker manager should be polling")
+	}
+
+	hc.Deregister()
+	if !hc.stopLoop {
+		t.Error("the poller loop should be stopped")
+	}
+	if !hc2.AmIPolling() {
+		t.Error("the other host checker manager should take over")
+	}
+
+	// deregistering a manager which isn't polling keeps the active poller
+	hc.Deregister()
+	if activeInstance, _ := redisStorage.GetKey(PollerCacheKey); activeInstance != hc2.Id {
+		t.Errorf("%q : value expected %v got %v", PollerCacheKey, hc2.Id, activeInstance)
+	}
+}
+
 func TestGenerateCheckerId(t *testing.T) {
 	ts := StartTest(nil)
 	defer ts.Close()
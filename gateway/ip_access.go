@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// IPAccessEntries are IP addresses and CIDR ranges, IPv4 or IPv6, allowed and blocked on an API.
+type IPAccessEntries struct {
+	Allow []string `json:"allow"`
+	Block []string `json:"block"`
+}
+
+type ipAccessEntry struct {
+	value string
+	net   *net.IPNet
+}
+
+// IPAccessList checks the IP of requests against the allowed and blocked entries of an API.
+// Entries can be added and removed at runtime without reloading the API.
+type IPAccessList struct {
+	mu      sync.RWMutex
+	allowed []ipAccessEntry
+	blocked []ipAccessEntry
+}
+
+// NewIPAccessList creates a list from the allowed and blocked IPs of an API definition. Invalid
+// entries are skipped, the returned error reports the first of them.
+func NewIPAccessList(allowed, blocked []string) (*IPAccessList, error) {
+	l := &IPAccessList{}
+	var firstErr error
+	for _, list := range []struct {
+		values  []string
+		entries *[]ipAccessEntry
+	}{{allowed, &l.allowed}, {blocked, &l.blocked}} {
+		for _, value := range list.values {
+			entry, err := parseIPAccessEntry(value)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			*list.entries = append(*list.entries, entry)
+		}
+	}
+	return l, firstErr
+}
+
+// ipAccessList returns the IP access list of the API, created from its definition if the API
+// wasn't loaded with one.
+func (a *APISpec) ipAccessList() *IPAccessList {
+	a.ipAccessOnce.Do(func() {
+		if a.IPAccess == nil {
+			a.IPAccess, _ = NewIPAccessList(a.AllowedIPs, a.BlacklistedIPs)
+		}
+	})
+	return a.IPAccess
+}
+
+// parseIPAccessEntry parses a CIDR range or a single IP, matched as a /32 or /128 range.
+func parseIPAccessEntry(value string) (ipAccessEntry, error) {
+	if _, ipNet, err := net.ParseCIDR(value); err == nil {
+		return ipAccessEntry{value: value, net: ipNet}, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return ipAccessEntry{}, fmt.Errorf("invalid IP address or CIDR range %q", value)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	bits := len(ip) * 8
+	return ipAccessEntry{value: value, net: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+}
+
+func ipAccessContains(entries []ipAccessEntry, ip net.IP) bool {
+	for _, entry := range entries {
+		if entry.net.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed returns whether ip is in the allowed entries, any IP is allowed when there are none.
+func (l *IPAccessList) Allowed(ip net.IP) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.allowed) == 0 || ipAccessContains(l.allowed, ip)
+}
+
+// Blocked returns whether ip is in the blocked entries.
+func (l *IPAccessList) Blocked(ip net.IP) bool {
+	if l == nil {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return ipAccessContains(l.blocked, ip)
+}
+
+// Entries returns the allowed and blocked entries.
+func (l *IPAccessList) Entries() IPAccessEntries {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := IPAccessEntries{Allow: []string{}, Block: []string{}}
+	for _, entry := range l.allowed {
+		entries.Allow = append(entries.Allow, entry.value)
+	}
+	for _, entry := range l.blocked {
+		entries.Block = append(entries.Block, entry.value)
+	}
+	return entries
+}
+
+// Add adds entries which aren't already in the list. Nothing is added if an entry is invalid.
+func (l *IPAccessList) Add(entries IPAccessEntries) error {
+	allowed, err := parseIPAccessEntries(entries.Allow)
+	if err != nil {
+		return err
+	}
+	blocked, err := parseIPAccessEntries(entries.Block)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.allowed = addIPAccessEntries(l.allowed, allowed)
+	l.blocked = addIPAccessEntries(l.blocked, blocked)
+	return nil
+}
+
+// Remove removes the entries matching the same ranges as entries. Nothing is removed if an
+// entry is invalid.
+func (l *IPAccessList) Remove(entries IPAccessEntries) error {
+	allowed, err := parseIPAccessEntries(entries.Allow)
+	if err != nil {
+		return err
+	}
+	blocked, err := parseIPAccessEntries(entries.Block)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.allowed = removeIPAccessEntries(l.allowed, allowed)
+	l.blocked = removeIPAccessEntries(l.blocked, blocked)
+	return nil
+}
+
+func parseIPAccessEntries(values []string) ([]ipAccessEntry, error) {
+	entries := make([]ipAccessEntry, 0, len(values))
+	for _, value := range values {
+		entry, err := parseIPAccessEntry(value)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func findIPAccessEntry(entries []ipAccessEntry, entry ipAccessEntry) int {
+	for i, e := range entries {
+		if e.net.String() == entry.net.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+func addIPAccessEntries(entries, add []ipAccessEntry) []ipAccessEntry {
+	for _, entry := range add {
+		if findIPAccessEntry(entries, entry) == -1 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func removeIPAccessEntries(entries, remove []ipAccessEntry) []ipAccessEntry {
+	for _, entry := range remove {
+		if i := findIPAccessEntry(entries, entry); i != -1 {
+			entries = append(entries[:i], entries[i+1:]...)
+		}
+	}
+	return entries
+}
+
+// ipAccessHandler lists, adds and removes the IPs allowed and blocked on an API. Changes apply
+// until the API is reloaded, the API definition must be updated to keep them.
+func (gw *Gateway) ipAccessHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil || spec.IPAccess == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		doJSONWrite(w, http.StatusOK, spec.IPAccess.Entries())
+		return
+	}
+
+	var entries IPAccessEntries
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	if len(entries.Allow) > 0 && !spec.EnableIpWhiteListing {
+		doJSONWrite(w, http.StatusBadRequest, apiError("IP whitelisting is not enabled for this API"))
+		return
+	}
+	if len(entries.Block) > 0 && !spec.EnableIpBlacklisting {
+		doJSONWrite(w, http.StatusBadRequest, apiError("IP blacklisting is not enabled for this API"))
+		return
+	}
+
+	update, action := spec.IPAccess.Add, "added"
+	if r.Method == http.MethodDelete {
+		update, action = spec.IPAccess.Remove, "removed"
+	}
+	if err := update(entries); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+		"api_id": apiID,
+		"allow":  entries.Allow,
+		"block":  entries.Block,
+	}).Info("IP access entries " + action)
+
+	doJSONWrite(w, http.StatusOK, spec.IPAccess.Entries())
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestIPAccessList(t *testing.T) {
+	list, err := NewIPAccessList([]string{"10.0.0.0/8", "2001:db8::/32", "::1", "invalid"}, []string{"10.1.0.0/16", "2001:db8::1"})
+	assert.Error(t, err, "invalid entries should be reported")
+	assert.Equal(t, []string{"10.0.0.0/8", "2001:db8::/32", "::1"}, list.Entries().Allow)
+
+	for _, tc := range []struct {
+		ip               string
+		allowed, blocked bool
+	}{
+		{"10.0.0.1", true, false},
+		{"::ffff:10.0.0.1", true, false},
+		{"10.1.0.1", true, true},
+		{"192.168.0.1", false, false},
+		{"::1", true, false},
+		{"2001:db8:1::1", true, false},
+		{"2001:db8::1", true, true},
+		{"2001:db9::1", false, false},
+	} {
+		ip := net.ParseIP(tc.ip)
+		assert.Equal(t, tc.allowed, list.Allowed(ip), tc.ip)
+		assert.Equal(t, tc.blocked, list.Blocked(ip), tc.ip)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		list, _ := NewIPAccessList(nil, nil)
+		assert.True(t, list.Allowed(net.ParseIP("192.168.0.1")), "any IP is allowed without allowed entries")
+
+		require.NoError(t, list.Add(IPAccessEntries{Allow: []string{"10.0.0.1", "10.0.0.1/32"}, Block: []string{"fd00::/8"}}))
+		assert.Equal(t, IPAccessEntries{Allow: []string{"10.0.0.1"}, Block: []string{"fd00::/8"}}, list.Entries())
+		assert.False(t, list.Allowed(net.ParseIP("192.168.0.1")))
+		assert.True(t, list.Blocked(net.ParseIP("fd12::1")))
+
+		assert.Error(t, list.Add(IPAccessEntries{Allow: []string{"10.0.0.2", "10.0.0.300"}}))
+		assert.False(t, list.Allowed(net.ParseIP("10.0.0.2")), "nothing should be added when an entry is invalid")
+
+		require.NoError(t, list.Remove(IPAccessEntries{Allow: []string{"10.0.0.1/32"}, Block: []string{"fd00::/8"}}))
+		assert.Equal(t, IPAccessEntries{Allow: []string{}, Block: []string{}}, list.Entries())
+	})
+}
+
+func TestIPAccessAPI(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "ip-access"
+		spec.Proxy.ListenPath = "/"
+		spec.EnableIpWhiteListing = true
+		spec.EnableIpBlacklisting = true
+		spec.BlacklistedIPs = []string{"2001:db8::/32"}
+	})
+
+	fromIP := func(ip string) map[string]string {
+		return map[string]string{"X-Real-IP": ip}
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: fromIP("10.0.0.1"), Code: http.StatusOK},
+		{Path: "/", Headers: fromIP("2001:db8::1"), Code: http.StatusForbidden},
+		{Method: http.MethodPost, Path: "/tyk/apis/ip-access/ip-access", Data: `{"allow":["192.168.0.0/16"]}`,
+			AdminAuth: true, Code: http.StatusOK},
+		{Path: "/", Headers: fromIP("10.0.0.1"), Code: http.StatusForbidden},
+		{Path: "/", Headers: fromIP("192.168.1.1"), Code: http.StatusOK},
+		{Method: http.MethodDelete, Path: "/tyk/apis/ip-access/ip-access", Data: `{"block":["2001:db8::/32"]}`,
+			AdminAuth: true, Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/tyk/apis/ip-access/ip-access", Data: `{"allow":["2001:db8::/32"]}`,
+			AdminAuth: true, Code: http.StatusOK},
+		{Path: "/", Headers: fromIP("2001:db8::1"), Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/tyk/apis/ip-access/ip-access", Data: `{"block":["not an IP"]}`,
+			AdminAuth: true, Code: http.StatusBadRequest},
+		{Method: http.MethodPost, Path: "/tyk/apis/unknown/ip-access", Data: `{}`, AdminAuth: true, Code: http.StatusNotFound},
+	}...)
+
+	resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/apis/ip-access/ip-access", AdminAuth: true, Code: http.StatusOK})
+	var entries IPAccessEntries
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&entries))
+	assert.Equal(t, IPAccessEntries{Allow: []string{"192.168.0.0/16", "2001:db8::/32"}, Block: []string{}}, entries)
+
+	t.Run("invalid entries", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "ip-access"
+			spec.Proxy.ListenPath = "/"
+			spec.EnableIpWhiteListing = true
+			spec.AllowedIPs = []string{"10.0.0.0/8", "invalid"}
+		})
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Headers: fromIP("10.0.0.1"), Code: http.StatusNotFound})
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "ip-access"
+			spec.Proxy.ListenPath = "/"
+		})
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/apis/ip-access/ip-access",
+			Data: `{"allow":["10.0.0.1"]}`, AdminAuth: true, Code: http.StatusBadRequest})
+	})
+}
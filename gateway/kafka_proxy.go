@@ -0,0 +1,262 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/kafka"
+)
+
+// Headers of the messages published by KafkaProxy and of the replies it consumes.
+const (
+	kafkaHeaderCorrelationID = "correlation-id"
+	kafkaHeaderReplyTopic    = "reply-topic"
+	kafkaHeaderMethod        = "method"
+	kafkaHeaderPath          = "path"
+	kafkaHeaderQuery         = "query"
+	kafkaHeaderContentType   = "content-type"
+	kafkaHeaderAPIID         = "api-id"
+	// kafkaHeaderStatus is the HTTP status code of a reply, 200 when missing.
+	kafkaHeaderStatus = "status"
+
+	// correlationIDHeader returns the correlation ID of published requests to clients.
+	correlationIDHeader = "X-Correlation-ID"
+
+	defaultKafkaResponseTimeout = 30 * time.Second
+)
+
+var errKafkaProxyClosed = errors.New("kafka proxy closed")
+
+// KafkaProxy publishes requests to a Kafka topic instead of proxying them to an HTTP upstream,
+// optionally waiting for the reply published with the same correlation ID to a response topic.
+type KafkaProxy struct {
+	Gw     *Gateway
+	spec   *APISpec
+	logger *logrus.Entry
+	client kafka.Client
+
+	ErrorHandler ErrorHandler
+
+	mu      sync.Mutex
+	replies kafka.Subscription
+	pending map[string]chan kafka.Message
+	closed  bool
+}
+
+func (gw *Gateway) NewKafkaProxy(spec *APISpec, logger *logrus.Entry) (*KafkaProxy, error) {
+	conf := kafka.Config{
+		Brokers:       spec.Kafka.Brokers,
+		ClientID:      spec.Kafka.ClientID,
+		SASLMechanism: spec.Kafka.SASLMechanism,
+		SASLUsername:  spec.Kafka.SASLUsername,
+		SASLPassword:  spec.Kafka.SASLPassword,
+	}
+	if spec.Kafka.UseSSL {
+		conf.TLS = &tls.Config{InsecureSkipVerify: spec.Kafka.SSLInsecureSkipVerify}
+	}
+	client, err := gw.newKafkaClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &KafkaProxy{
+		Gw:      gw,
+		spec:    spec,
+		logger:  logger.WithField("prefix", "kafka"),
+		client:  client,
+		pending: make(map[string]chan kafka.Message),
+	}
+	p.ErrorHandler.BaseMiddleware = BaseMiddleware{Spec: spec, Gw: gw}
+
+	if spec.Kafka.ResponseTopic != "" {
+		// replies are only delivered once subscribed, failures are retried on the next request
+		if _, err := p.subscription(); err != nil {
+			p.logger.WithError(err).Error("Failed to subscribe to response topic")
+		}
+	}
+
+	return p, nil
+}
+
+// subscription returns the subscription to the response topic, subscribing if needed.
+func (p *KafkaProxy) subscription() (kafka.Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, errKafkaProxyClosed
+	}
+	if p.replies != nil {
+		return p.replies, nil
+	}
+
+	sub, err := p.client.Subscribe(p.spec.Kafka.ResponseTopic, p.deliver, p.logger)
+	if err != nil {
+		return nil, err
+	}
+	p.replies = sub
+	return sub, nil
+}
+
+func (p *KafkaProxy) deliver(m kafka.Message) {
+	id := string(m.Header(kafkaHeaderCorrelationID))
+
+	p.mu.Lock()
+	reply, ok := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	if !ok {
+		// the reply of another gateway or of a request which timed out
+		p.logger.WithField("correlation_id", id).Debug("Ignoring unexpected reply")
+		return
+	}
+	reply <- m
+}
+
+func (p *KafkaProxy) await(id string) chan kafka.Message {
+	reply := make(chan kafka.Message, 1)
+	p.mu.Lock()
+	p.pending[id] = reply
+	p.mu.Unlock()
+	return reply
+}
+
+func (p *KafkaProxy) forget(id string) {
+	p.mu.Lock()
+	delete(p.pending, id)
+	p.mu.Unlock()
+}
+
+func (p *KafkaProxy) responseTimeout() time.Duration {
+	if p.spec.Kafka.ResponseTimeout > 0 {
+		return time.Duration(p.spec.Kafka.ResponseTimeout * float64(time.Second))
+	}
+	return defaultKafkaResponseTimeout
+}
+
+func (p *KafkaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) ProxyResponse {
+	return p.serve(w, r)
+}
+
+func (p *KafkaProxy) ServeHTTPForCache(w http.ResponseWriter, r *http.Request) ProxyResponse {
+	return p.serve(w, r)
+}
+
+func (p *KafkaProxy) CopyResponse(dst io.Writer, src io.Reader, flushInterval time.Duration) {
+	io.Copy(dst, src)
+}
+
+func (p *KafkaProxy) serve(w http.ResponseWriter, r *http.Request) ProxyResponse {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		p.ErrorHandler.HandleError(w, r, "There was a problem proxying the request", http.StatusInternalServerError, true)
+		return ProxyResponse{}
+	}
+
+	// stripping the listen path can leave a relative path
+	path := r.URL.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	id := uuid.NewV4().String()
+	msg := kafka.Message{
+		Value: body,
+		Headers: []kafka.Header{
+			{Key: kafkaHeaderCorrelationID, Value: []byte(id)},
+			{Key: kafkaHeaderMethod, Value: []byte(r.Method)},
+			{Key: kafkaHeaderPath, Value: []byte(path)},
+			{Key: kafkaHeaderQuery, Value: []byte(r.URL.RawQuery)},
+			{Key: kafkaHeaderContentType, Value: []byte(r.Header.Get(headers.ContentType))},
+			{Key: kafkaHeaderAPIID, Value: []byte(p.spec.APIID)},
+		},
+	}
+	if key := p.spec.Kafka.KeyHeader; key != "" && r.Header.Get(key) != "" {
+		msg.Key = []byte(r.Header.Get(key))
+	}
+
+	var reply chan kafka.Message
+	if p.spec.Kafka.ResponseTopic != "" {
+		if _, err := p.subscription(); err != nil {
+			p.logger.WithError(err).Error("Failed to subscribe to response topic")
+			p.ErrorHandler.HandleError(w, r, "There was a problem proxying the request", http.StatusBadGateway, true)
+			return ProxyResponse{}
+		}
+
+		msg.Headers = append(msg.Headers, kafka.Header{Key: kafkaHeaderReplyTopic, Value: []byte(p.spec.Kafka.ResponseTopic)})
+		// registered before publishing so that fast replies are not missed
+		reply = p.await(id)
+		defer p.forget(id)
+	}
+
+	start := time.Now()
+	if err := p.client.Produce(p.spec.Kafka.Topic, msg); err != nil {
+		p.logger.WithError(err).Error("Failed to publish request")
+		p.ErrorHandler.HandleError(w, r, "There was a problem proxying the request", http.StatusBadGateway, true)
+		return ProxyResponse{UpstreamLatency: time.Since(start)}
+	}
+
+	res := &VMResponseObject{}
+	res.Response.Headers = map[string]string{correlationIDHeader: id}
+
+	if reply == nil {
+		ack, _ := json.Marshal(map[string]string{"status": "accepted", "correlation_id": id})
+		res.Response.Code = http.StatusAccepted
+		res.Response.Body = string(ack)
+		res.Response.Headers[headers.ContentType] = headers.ApplicationJSON
+		return p.respond(w, r, res, time.Since(start))
+	}
+
+	timeout := time.NewTimer(p.responseTimeout())
+	defer timeout.Stop()
+
+	select {
+	case m := <-reply:
+		res.Response.Code = http.StatusOK
+		if status, err := strconv.Atoi(string(m.Header(kafkaHeaderStatus))); err == nil && status >= 100 && status < 600 {
+			res.Response.Code = status
+		}
+		if contentType := m.Header(kafkaHeaderContentType); contentType != nil {
+			res.Response.Headers[headers.ContentType] = string(contentType)
+		}
+		res.Response.Body = string(m.Value)
+		return p.respond(w, r, res, time.Since(start))
+	case <-timeout.C:
+		p.ErrorHandler.HandleError(w, r, "Upstream service reached hard timeout.", http.StatusGatewayTimeout, true)
+	case <-r.Context().Done():
+		p.ErrorHandler.HandleError(w, r, "Client closed request", 499, true)
+	}
+	return ProxyResponse{UpstreamLatency: time.Since(start)}
+}
+
+func (p *KafkaProxy) respond(w http.ResponseWriter, r *http.Request, res *VMResponseObject, latency time.Duration) ProxyResponse {
+	resp := p.Gw.forceResponse(w, r, res, p.spec, ctxGetSession(r), false, p.logger)
+	return ProxyResponse{Response: resp, UpstreamLatency: latency}
+}
+
+// Close stops consuming replies and closes the connections to the brokers.
+func (p *KafkaProxy) Close() {
+	p.mu.Lock()
+	p.closed = true
+	replies := p.replies
+	p.replies = nil
+	p.mu.Unlock()
+
+	if replies != nil {
+		replies.Close()
+	}
+	p.client.Close()
+}
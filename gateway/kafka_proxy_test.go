@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/kafka"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+// mockKafkaClient is an in-memory cluster shared by the clients it is returned as. Messages are
+// delivered to the subscriptions of their topic when published.
+type mockKafkaClient struct {
+	mu            sync.Mutex
+	topics        map[string][]kafka.Message
+	subscriptions map[string][]*mockKafkaSubscription
+}
+
+func newMockKafkaClient() *mockKafkaClient {
+	return &mockKafkaClient{
+		topics:        make(map[string][]kafka.Message),
+		subscriptions: make(map[string][]*mockKafkaSubscription),
+	}
+}
+
+func (c *mockKafkaClient) Produce(topic string, msgs ...kafka.Message) error {
+	c.mu.Lock()
+	subs := c.subscriptions[topic]
+	for _, m := range msgs {
+		m.Topic = topic
+		m.Offset = int64(len(c.topics[topic]))
+		c.topics[topic] = append(c.topics[topic], m)
+		for _, sub := range subs {
+			go sub.deliver(m)
+		}
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *mockKafkaClient) Subscribe(topic string, handler func(kafka.Message), _ kafka.Logger) (kafka.Subscription, error) {
+	sub := &mockKafkaSubscription{handler: handler}
+	c.mu.Lock()
+	c.subscriptions[topic] = append(c.subscriptions[topic], sub)
+	c.mu.Unlock()
+	return sub, nil
+}
+
+func (c *mockKafkaClient) Close() error {
+	return nil
+}
+
+// Messages returns the messages published to topic.
+func (c *mockKafkaClient) Messages(topic string) []kafka.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]kafka.Message(nil), c.topics[topic]...)
+}
+
+type mockKafkaSubscription struct {
+	mu      sync.Mutex
+	handler func(kafka.Message)
+	closed  bool
+}
+
+func (s *mockKafkaSubscription) deliver(m kafka.Message) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if !closed {
+		s.handler(m)
+	}
+}
+
+func (s *mockKafkaSubscription) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func TestKafkaProxy(t *testing.T) {
+	broker := newMockKafkaClient()
+
+	// echoes requests published to "requests" back to their reply topic
+	sub, err := broker.Subscribe("requests", func(m kafka.Message) {
+		replyTopic := string(m.Header(kafkaHeaderReplyTopic))
+		if replyTopic == "" {
+			return
+		}
+		broker.Produce(replyTopic, kafka.Message{
+			Value: append([]byte("reply to "), m.Value...),
+			Headers: []kafka.Header{
+				{Key: kafkaHeaderCorrelationID, Value: m.Header(kafkaHeaderCorrelationID)},
+				{Key: kafkaHeaderStatus, Value: []byte("201")},
+				{Key: kafkaHeaderContentType, Value: []byte("text/plain")},
+			},
+		})
+	}, log)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+	ts.Gw.newKafkaClient = func(kafka.Config) (kafka.Client, error) {
+		return broker, nil
+	}
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "events"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/events/"
+		spec.Proxy.StripListenPath = true
+		spec.Kafka.Enabled = true
+		spec.Kafka.Brokers = []string{"localhost:9092"}
+		spec.Kafka.Topic = "events"
+		spec.Kafka.KeyHeader = "X-Partition-Key"
+	}, func(spec *APISpec) {
+		spec.APIID = "rpc"
+		spec.Proxy.ListenPath = "/rpc/"
+		spec.Kafka.Enabled = true
+		spec.Kafka.Brokers = []string{"localhost:9092"}
+		spec.Kafka.Topic = "requests"
+		spec.Kafka.ResponseTopic = "replies"
+	}, func(spec *APISpec) {
+		spec.APIID = "unanswered"
+		spec.Proxy.ListenPath = "/unanswered/"
+		spec.Kafka.Enabled = true
+		spec.Kafka.Brokers = []string{"localhost:9092"}
+		spec.Kafka.Topic = "ignored"
+		spec.Kafka.ResponseTopic = "no-replies"
+		spec.Kafka.ResponseTimeout = 0.1
+	})
+
+	key := CreateSession(ts.Gw)
+
+	t.Run("publish", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/events/orders", Data: `{"id":1}`, Code: http.StatusUnauthorized},
+			{Method: http.MethodPost, Path: "/events/orders?source=web", Data: `{"id":2}`, Code: http.StatusAccepted,
+				Headers:      map[string]string{"Authorization": key, "X-Partition-Key": "customer-1", "Content-Type": "application/json"},
+				HeadersMatch: map[string]string{"Content-Type": "application/json"},
+				BodyMatch:    `"status":"accepted"`},
+		}...)
+
+		msgs := broker.Messages("events")
+		require.Len(t, msgs, 1)
+		msg := msgs[0]
+		assert.Equal(t, `{"id":2}`, string(msg.Value))
+		assert.Equal(t, "customer-1", string(msg.Key))
+		assert.Equal(t, http.MethodPost, string(msg.Header(kafkaHeaderMethod)))
+		assert.Equal(t, "/orders", string(msg.Header(kafkaHeaderPath)))
+		assert.Equal(t, "source=web", string(msg.Header(kafkaHeaderQuery)))
+		assert.Equal(t, "application/json", string(msg.Header(kafkaHeaderContentType)))
+		assert.Equal(t, "events", string(msg.Header(kafkaHeaderAPIID)))
+		assert.NotEmpty(t, msg.Header(kafkaHeaderCorrelationID))
+		assert.Nil(t, msg.Header(kafkaHeaderReplyTopic))
+	})
+
+	t.Run("request reply", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/rpc/", Data: "ping", Code: http.StatusCreated,
+			HeadersMatch: map[string]string{"Content-Type": "text/plain"},
+			BodyMatch:    "^reply to ping$"})
+
+		msgs := broker.Messages("requests")
+		require.Len(t, msgs, 1)
+		assert.Equal(t, string(msgs[0].Header(kafkaHeaderCorrelationID)), resp.Header.Get(correlationIDHeader))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Path: "/unanswered/", Code: http.StatusGatewayTimeout})
+		assert.Len(t, broker.Messages("ignored"), 1)
+	})
+
+	t.Run("released on reload", func(t *testing.T) {
+		spec := ts.Gw.getApiSpec("rpc")
+		require.NotNil(t, spec.KafkaProxy)
+
+		ts.Gw.DoReload()
+		_, err := spec.KafkaProxy.subscription()
+		assert.Equal(t, errKafkaProxyClosed, err)
+	})
+}
+
+func TestKafkaProxyBrokerUnavailable(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Kafka.Enabled = true
+		spec.Kafka.Brokers = []string{"127.0.0.1:1"}
+		spec.Kafka.Topic = "events"
+	})
+
+	start := time.Now()
+	_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusBadGateway})
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestKafkaProxyInvalidConfig(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Kafka.Enabled = true
+		spec.Kafka.Brokers = []string{"localhost:9092"}
+		spec.Kafka.SASLMechanism = "gssapi"
+		spec.Kafka.Topic = "events"
+	})
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusNotFound})
+}
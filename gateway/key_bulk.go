@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	bulkKeyCreate = "create"
+	bulkKeyUpdate = "update"
+	bulkKeyRevoke = "revoke"
+
+	// bulkKeyOperationsLimit is the maximum number of operations of a bulk request.
+	bulkKeyOperationsLimit = 1000
+)
+
+// BulkKeyRequest is the body of a bulk key request.
+type BulkKeyRequest struct {
+	Operations []BulkKeyOperation `json:"operations"`
+}
+
+// BulkKeyOperation creates, updates or revokes a key.
+type BulkKeyOperation struct {
+	// Action is create, update or revoke.
+	Action string `json:"action"`
+	// Key is the key to update or revoke, or a custom value for the key to create, generated when empty.
+	Key string `json:"key"`
+	// Hashed is true when Key is the hash of the key to update or revoke.
+	Hashed bool   `json:"hashed"`
+	OrgID  string `json:"org_id"`
+	// SuppressReset keeps the quota and rate limit counters of an updated key.
+	SuppressReset bool `json:"suppress_reset"`
+	// Session is the session of the key to create or update.
+	Session json.RawMessage `json:"session,omitempty"`
+}
+
+// apiBulkKeyResult is the result of an operation of a bulk key request. Status is skipped when
+// the operation wasn't applied because another operation of the request is invalid.
+type apiBulkKeyResult struct {
+	Action  string `json:"action"`
+	Key     string `json:"key,omitempty"`
+	KeyHash string `json:"key_hash,omitempty"`
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type apiBulkKeyResponse struct {
+	// Status is ok when all the operations succeeded, error when none was applied and partial otherwise.
+	Status  string             `json:"status"`
+	Results []apiBulkKeyResult `json:"results"`
+}
+
+// bulkKeyHandler applies a batch of key operations. All the operations are validated before any
+// is applied, so that an invalid operation fails the whole batch. An operation failing once applied
+// doesn't roll back the others, its result reporting the failure.
+func (gw *Gateway) bulkKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req BulkKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+	if len(req.Operations) == 0 {
+		doJSONWrite(w, http.StatusBadRequest, apiError("No operations"))
+		return
+	}
+	if len(req.Operations) > bulkKeyOperationsLimit {
+		doJSONWrite(w, http.StatusBadRequest, apiError(fmt.Sprintf("Too many operations, the limit is %d", bulkKeyOperationsLimit)))
+		return
+	}
+
+	resp := apiBulkKeyResponse{Status: "ok", Results: make([]apiBulkKeyResult, len(req.Operations))}
+
+	valid := true
+	for i, op := range req.Operations {
+		resp.Results[i] = apiBulkKeyResult{Action: op.Action, Key: op.Key, Status: "skipped"}
+		if err := gw.validateBulkKeyOperation(op); err != nil {
+			resp.Results[i].Status = "error"
+			resp.Results[i].Code = http.StatusBadRequest
+			resp.Results[i].Message = err.Error()
+			valid = false
+		}
+	}
+	if !valid {
+		resp.Status = "error"
+		doJSONWrite(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	failed := 0
+	for i, op := range req.Operations {
+		resp.Results[i] = gw.applyBulkKeyOperation(op)
+		if resp.Results[i].Status != "ok" {
+			failed++
+		}
+	}
+	switch failed {
+	case 0:
+	case len(req.Operations):
+		resp.Status = "error"
+	default:
+		resp.Status = "partial"
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":     "api",
+		"operations": len(req.Operations),
+		"failed":     failed,
+	}).Info("Applied bulk key operations.")
+
+	doJSONWrite(w, http.StatusOK, resp)
+}
+
+func (gw *Gateway) validateBulkKeyOperation(op BulkKeyOperation) error {
+	if op.Hashed && !gw.GetConfig().HashKeys {
+		return errors.New("key requested by hash but key hashing is not enabled")
+	}
+
+	switch op.Action {
+	case bulkKeyCreate, bulkKeyUpdate:
+	case bulkKeyRevoke:
+		if op.Key == "" {
+			return errors.New("revoke requires a key")
+		}
+		if _, found := gw.GlobalSessionManager.SessionDetail(op.OrgID, op.Key, op.Hashed); !found {
+			return errors.New("key not found")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", op.Action)
+	}
+
+	var session user.SessionState
+	if err := json.Unmarshal(op.Session, &session); err != nil {
+		return fmt.Errorf("session malformed: %v", err)
+	}
+	if err := session.ValidateActivity(); err != nil {
+		return fmt.Errorf("invalid key activity: %v", err)
+	}
+	if len(session.AccessRights) == 0 && len(session.ApplyPolicies) == 0 && !gw.GetConfig().AllowMasterKeys {
+		return errors.New("keys must have at least one access rights record set")
+	}
+	for apiID := range session.AccessRights {
+		if gw.getApiSpec(apiID) == nil {
+			return fmt.Errorf("API %s doesn't exist", apiID)
+		}
+	}
+
+	if op.Action == bulkKeyUpdate {
+		if op.Key == "" {
+			return errors.New("update requires a key")
+		}
+		if _, found := gw.GlobalSessionManager.SessionDetail(session.OrgID, op.Key, op.Hashed); !found {
+			return errors.New("key not found")
+		}
+	}
+	return nil
+}
+
+func (gw *Gateway) applyBulkKeyOperation(op BulkKeyOperation) apiBulkKeyResult {
+	var obj interface{}
+	var code int
+	switch op.Action {
+	case bulkKeyCreate:
+		obj, code = gw.addOrUpdateKey(op.Key, op.Session, http.MethodPost, false, op.Hashed)
+	case bulkKeyUpdate:
+		obj, code = gw.addOrUpdateKey(op.Key, op.Session, http.MethodPut, op.SuppressReset, op.Hashed)
+	case bulkKeyRevoke:
+		if op.Hashed {
+			obj, code = gw.handleDeleteHashedKeyWithLogs(op.Key, op.OrgID, "", true)
+		} else {
+			obj, code = gw.handleDeleteKey(op.Key, op.OrgID, "", true)
+		}
+	}
+
+	result := apiBulkKeyResult{Action: op.Action, Key: op.Key, Status: "ok", Code: code}
+	switch obj := obj.(type) {
+	case apiModifyKeySuccess:
+		result.Key = obj.Key
+		result.KeyHash = obj.KeyHash
+	case apiStatusMessage:
+		result.Status = obj.Status
+		result.Message = obj.Message
+	}
+	if code != http.StatusOK {
+		result.Status = "error"
+	}
+	return result
+}
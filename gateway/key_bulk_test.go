@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestBulkKeys(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "bulk"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+	})
+
+	session := func(rate float64) json.RawMessage {
+		s := user.NewSessionState()
+		s.Rate = rate
+		s.Per = 60
+		s.AccessRights = map[string]user.AccessDefinition{"bulk": {APIID: "bulk"}}
+		data, _ := json.Marshal(s)
+		return data
+	}
+	bulk := func(t *testing.T, code int, ops ...BulkKeyOperation) apiBulkKeyResponse {
+		t.Helper()
+		data, _ := json.Marshal(BulkKeyRequest{Operations: ops})
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/keys/bulk", Data: data, AdminAuth: true, Code: code})
+		var bulkResp apiBulkKeyResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&bulkResp))
+		require.Len(t, bulkResp.Results, len(ops))
+		return bulkResp
+	}
+
+	resp := bulk(t, http.StatusOK,
+		BulkKeyOperation{Action: bulkKeyCreate, Session: session(10)},
+		BulkKeyOperation{Action: bulkKeyCreate, Key: "custom-bulk-key", Session: session(10)},
+		BulkKeyOperation{Action: bulkKeyCreate, Session: session(10)},
+	)
+	assert.Equal(t, "ok", resp.Status)
+	for _, result := range resp.Results {
+		assert.Equal(t, "ok", result.Status)
+		assert.Equal(t, http.StatusOK, result.Code)
+		assert.NotEmpty(t, result.Key)
+	}
+	generated, custom, revoked := resp.Results[0].Key, resp.Results[1].Key, resp.Results[2].Key
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: map[string]string{"Authorization": generated}, Code: http.StatusOK},
+		{Path: "/", Headers: map[string]string{"Authorization": custom}, Code: http.StatusOK},
+	}...)
+
+	t.Run("invalid operation fails the batch", func(t *testing.T) {
+		resp := bulk(t, http.StatusBadRequest,
+			BulkKeyOperation{Action: bulkKeyRevoke, Key: generated},
+			BulkKeyOperation{Action: bulkKeyUpdate, Key: "unknown", Session: session(10)},
+			BulkKeyOperation{Action: "rotate", Key: generated},
+		)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, "skipped", resp.Results[0].Status)
+		assert.Equal(t, "key not found", resp.Results[1].Message)
+		assert.Equal(t, `unknown action "rotate"`, resp.Results[2].Message)
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Headers: map[string]string{"Authorization": generated}, Code: http.StatusOK})
+	})
+
+	t.Run("update and revoke", func(t *testing.T) {
+		resp := bulk(t, http.StatusOK,
+			BulkKeyOperation{Action: bulkKeyUpdate, Key: custom, Session: session(1)},
+			BulkKeyOperation{Action: bulkKeyRevoke, Key: revoked},
+		)
+		assert.Equal(t, "ok", resp.Status)
+		assert.Equal(t, custom, resp.Results[0].Key)
+
+		updated, found := ts.Gw.GlobalSessionManager.SessionDetail("", custom, false)
+		require.True(t, found)
+		assert.Equal(t, float64(1), updated.Rate)
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Headers: map[string]string{"Authorization": revoked}, Code: http.StatusForbidden})
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/tyk/keys/bulk", Data: `{"operations": []}`, AdminAuth: true, Code: http.StatusBadRequest},
+		{Method: http.MethodPost, Path: "/tyk/keys/bulk", Data: `{"operations": [{"action": "create"}]}`, AdminAuth: true,
+			Code: http.StatusBadRequest, BodyMatch: "session malformed"},
+	}...)
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pmylund/go-cache"
+
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const keyIndexMetaPrefix = "meta."
+
+// keyIndex is a secondary index of the keys by alias and metadata, which unlike listing finds keys
+// when key hashing is enabled. Keys are indexed by the ID under which they're stored, which is their
+// hash when key hashing is enabled.
+type keyIndex struct {
+	store storage.Handler
+	// indexed are the terms the keys were recently indexed under by this gateway, so that the
+	// updates of the sessions which don't change their terms don't read the index.
+	indexed *cache.Cache
+}
+
+func newKeyIndex(gw *Gateway) *keyIndex {
+	store := &storage.RedisCluster{KeyPrefix: "key-index.", RedisController: gw.RedisController}
+	store.Connect()
+	return &keyIndex{store: store, indexed: cache.New(time.Minute, 5*time.Minute)}
+}
+
+func keyIndexAliasTerm(alias string) string {
+	return "alias." + alias
+}
+
+func keyIndexMetaTerm(name, value string) string {
+	return keyIndexMetaPrefix + name + "." + value
+}
+
+// keyIndexTerms returns the terms of session: its alias and its scalar metadata.
+func keyIndexTerms(session *user.SessionState) []string {
+	var terms []string
+	if session.Alias != "" {
+		terms = append(terms, keyIndexAliasTerm(session.Alias))
+	}
+	for name, value := range session.MetaData {
+		switch value.(type) {
+		case string, bool, float64, int, int64:
+			terms = append(terms, keyIndexMetaTerm(name, fmt.Sprint(value)))
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// terms returns the terms under which id is indexed.
+func (i *keyIndex) terms(id string) []string {
+	data, err := i.store.GetKey("key." + id)
+	if err != nil {
+		return nil
+	}
+	var terms []string
+	_ = json.Unmarshal([]byte(data), &terms)
+	return terms
+}
+
+// update indexes id under the terms of session, removing it from those it no longer has.
+func (i *keyIndex) update(id string, session *user.SessionState) {
+	terms := keyIndexTerms(session)
+	joined := strings.Join(terms, "\n")
+	if indexed, ok := i.indexed.Get(id); ok && indexed.(string) == joined {
+		return
+	}
+
+	old := i.terms(id)
+	if joined == strings.Join(old, "\n") {
+		i.indexed.Set(id, joined, cache.DefaultExpiration)
+		return
+	}
+
+	for _, term := range old {
+		if !contains(terms, term) {
+			i.store.RemoveFromSet(term, id)
+		}
+	}
+	for _, term := range terms {
+		if !contains(old, term) {
+			i.store.AddToSet(term, id)
+		}
+	}
+
+	if len(terms) == 0 {
+		i.store.DeleteKey("key." + id)
+		i.indexed.Set(id, joined, cache.DefaultExpiration)
+		return
+	}
+	data, _ := json.Marshal(terms)
+	if err := i.store.SetKey("key."+id, string(data), 0); err != nil {
+		log.WithError(err).Error("Could not index key")
+		return
+	}
+	i.indexed.Set(id, joined, cache.DefaultExpiration)
+}
+
+// remove removes id from the index.
+func (i *keyIndex) remove(id string) {
+	i.indexed.Delete(id)
+	for _, term := range i.terms(id) {
+		i.store.RemoveFromSet(term, id)
+	}
+	i.store.DeleteKey("key." + id)
+}
+
+// search returns the IDs of the keys indexed under all terms.
+func (i *keyIndex) search(terms []string) []string {
+	var ids []string
+	for n, term := range terms {
+		members, err := i.store.GetSet(term)
+		if err != nil {
+			return nil
+		}
+
+		var matched []string
+		for _, id := range members {
+			if n == 0 || contains(ids, id) {
+				matched = append(matched, id)
+			}
+		}
+		ids = matched
+		if len(ids) == 0 {
+			break
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// keySearchTerms returns the index terms of the alias and meta.<name> parameters of query.
+func keySearchTerms(query url.Values) []string {
+	var terms []string
+	for param, values := range query {
+		for _, value := range values {
+			switch {
+			case param == "alias":
+				terms = append(terms, keyIndexAliasTerm(value))
+			case strings.HasPrefix(param, keyIndexMetaPrefix) && len(param) > len(keyIndexMetaPrefix):
+				terms = append(terms, keyIndexMetaTerm(strings.TrimPrefix(param, keyIndexMetaPrefix), value))
+			}
+		}
+	}
+	return terms
+}
+
+// handleSearchKeys lists the keys matching all terms, dropping from the index the keys which
+// expired since they were indexed.
+func (gw *Gateway) handleSearchKeys(terms []string) (interface{}, int) {
+	keys := make([]string, 0)
+	for _, id := range gw.keyIndex.search(terms) {
+		if _, found := gw.GlobalSessionManager.SessionDetail("", id, true); !found {
+			gw.keyIndex.remove(id)
+			continue
+		}
+		keys = append(keys, id)
+	}
+
+	return apiAllKeys{keys}, http.StatusOK
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmylund/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestKeySearch(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HashKeys = true
+		globalConf.EnableHashedKeysListing = false
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "search"
+		spec.UseKeylessAccess = false
+	})
+
+	createKey := func(alias, team string) (string, string) {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.Alias = alias
+			s.MetaData = map[string]interface{}{"team": team, "tier": 2, "nested": map[string]interface{}{"a": "b"}}
+			s.AccessRights = map[string]user.AccessDefinition{"search": {APIID: "search"}}
+		})
+		return key, storage.HashKey(key, true)
+	}
+	search := func(t *testing.T, query url.Values) []string {
+		t.Helper()
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/keys?" + query.Encode(), AdminAuth: true, Code: http.StatusOK})
+		var keys apiAllKeys
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+		return keys.APIKeys
+	}
+
+	aliceKey, alice := createKey("alice", "payments")
+	_, bob := createKey("bob", "payments")
+	_, carol := createKey("carol", "search")
+
+	assert.Equal(t, []string{alice}, search(t, url.Values{"alias": {"alice"}}))
+	assert.ElementsMatch(t, []string{alice, bob}, search(t, url.Values{"meta.team": {"payments"}}))
+	assert.Equal(t, []string{bob}, search(t, url.Values{"meta.team": {"payments"}, "alias": {"bob"}}))
+	assert.ElementsMatch(t, []string{alice, bob, carol}, search(t, url.Values{"meta.tier": {"2"}}))
+	assert.Empty(t, search(t, url.Values{"meta.team": {"search"}, "alias": {"bob"}}))
+	assert.Empty(t, search(t, url.Values{"meta.nested": {"map[a:b]"}}))
+
+	// listing without search parameters is still disabled
+	_, _ = ts.Run(t, test.TestCase{Path: "/tyk/keys", AdminAuth: true, Code: http.StatusNotFound})
+
+	t.Run("update", func(t *testing.T) {
+		session, found := ts.Gw.GlobalSessionManager.SessionDetail("", alice, true)
+		require.True(t, found)
+		session.Alias = "alice-renamed"
+		session.MetaData = map[string]interface{}{"team": "search"}
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPut, Path: "/tyk/keys/" + aliceKey, Data: session, AdminAuth: true, Code: http.StatusOK})
+
+		assert.Empty(t, search(t, url.Values{"alias": {"alice"}}))
+		assert.Equal(t, []string{alice}, search(t, url.Values{"alias": {"alice-renamed"}}))
+		assert.ElementsMatch(t, []string{alice, carol}, search(t, url.Values{"meta.team": {"search"}}))
+		assert.Equal(t, []string{bob}, search(t, url.Values{"meta.team": {"payments"}}))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodDelete, Path: "/tyk/keys/" + bob + "?hashed=1", AdminAuth: true, Code: http.StatusOK})
+		assert.Empty(t, search(t, url.Values{"alias": {"bob"}}))
+	})
+
+	t.Run("expired keys are dropped", func(t *testing.T) {
+		store := ts.Gw.GlobalSessionManager.Store()
+		require.True(t, store.DeleteRawKey(store.GetKeyPrefix()+carol))
+
+		assert.Equal(t, []string{alice}, search(t, url.Values{"meta.team": {"search"}}))
+		assert.Empty(t, ts.Gw.keyIndex.terms(carol))
+	})
+}
+
+func TestKeyIndexUpdate(t *testing.T) {
+	session := &user.SessionState{Alias: "alice", MetaData: map[string]interface{}{"team": "payments"}}
+	index := &keyIndex{indexed: cache.New(time.Minute, time.Minute)}
+	index.indexed.Set("alice", "alias.alice\nmeta.team.payments", cache.DefaultExpiration)
+
+	// the store isn't used when the terms are those indexed by this gateway
+	assert.NotPanics(t, func() { index.update("alice", session) })
+}
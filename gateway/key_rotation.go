@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	keyRotationStatsPrefix = "key-rotation-"
+	// keyRotationStatsRetention keeps the usage of a rotated key available after its grace period.
+	keyRotationStatsRetention = 24 * 60 * 60
+)
+
+// KeyRotationRequest is the body of a key rotation request.
+type KeyRotationRequest struct {
+	// GracePeriod is the time in seconds during which the rotated key keeps authenticating, it
+	// stops authenticating immediately when 0. Defaults to key_rotation_grace_period.
+	GracePeriod int64 `json:"grace_period"`
+	// NewKey is a custom value for the new key, generated when empty.
+	NewKey string `json:"new_key"`
+}
+
+// apiKeyRotationStatus describes the rotation of a key and how the key it replaced is still used.
+type apiKeyRotationStatus struct {
+	Key             string `json:"key,omitempty"`
+	KeyHash         string `json:"key_hash,omitempty"`
+	PreviousKeyHash string `json:"previous_key_hash"`
+	GraceExpires    int64  `json:"grace_expires"`
+	// PreviousKeyRequests is the number of requests authenticated with the previous key since the rotation.
+	PreviousKeyRequests int64 `json:"previous_key_requests"`
+	// PreviousKeyLastUsed is the Unix time of the last request authenticated with the previous key, 0 if unused.
+	PreviousKeyLastUsed int64 `json:"previous_key_last_used"`
+}
+
+func (gw *Gateway) keyRotationHandler(w http.ResponseWriter, r *http.Request) {
+	keyName := mux.Vars(r)["keyName"]
+	isHashed := r.URL.Query().Get("hashed") != ""
+	orgID := r.URL.Query().Get("org_id")
+
+	if isHashed && !gw.GetConfig().HashKeys {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Key requested by hash but key hashing is not enabled"))
+		return
+	}
+
+	var obj interface{}
+	var code int
+	switch r.Method {
+	case http.MethodPost:
+		// the body is optional, the grace period defaults to the configured one
+		req := KeyRotationRequest{GracePeriod: gw.GetConfig().KeyRotationGracePeriod}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+			return
+		}
+		obj, code = gw.handleRotateKey(keyName, orgID, isHashed, req)
+	case http.MethodGet:
+		obj, code = gw.handleGetKeyRotation(keyName, orgID, isHashed)
+	}
+
+	doJSONWrite(w, code, obj)
+}
+
+// handleRotateKey moves the session of keyName to a new key. The rotated key keeps authenticating
+// with the session of the new key until the end of the grace period.
+func (gw *Gateway) handleRotateKey(keyName, orgID string, isHashed bool, req KeyRotationRequest) (interface{}, int) {
+	if req.GracePeriod < 0 {
+		return apiError("Grace period can't be negative"), http.StatusBadRequest
+	}
+
+	session, ok := gw.GlobalSessionManager.SessionDetail(orgID, keyName, isHashed)
+	if !ok {
+		return apiError("Key not found"), http.StatusNotFound
+	}
+	if session.KeyRotation.NextKeyHash != "" {
+		return apiError("Key has already been rotated"), http.StatusBadRequest
+	}
+
+	hashKeys := gw.GetConfig().HashKeys
+	keyID := session.KeyID
+	oldHash := keyID
+	if !isHashed {
+		oldHash = storage.HashKey(keyID, hashKeys)
+	}
+
+	newKey := req.NewKey
+	if newKey == "" {
+		newKey = gw.keyGen.GenerateAuthKey(session.OrgID)
+	} else if _, exists := gw.GlobalSessionManager.SessionDetail(session.OrgID, newKey, false); exists {
+		return apiError("Key already exists"), http.StatusBadRequest
+	}
+	newHash := storage.HashKey(newKey, hashKeys)
+	graceExpires := time.Now().Unix() + req.GracePeriod
+
+	newSession := session.Clone()
+	newSession.KeyID = ""
+	newSession.KeyRotation = user.KeyRotation{PreviousKeyHash: oldHash, GraceExpires: graceExpires}
+	gw.carryOverQuota(oldHash, newHash, &newSession)
+	if err := gw.doAddOrUpdate(newKey, &newSession, true, false); err != nil {
+		return apiError("Failed to rotate key - " + err.Error()), http.StatusInternalServerError
+	}
+
+	if req.GracePeriod == 0 {
+		gw.GlobalSessionManager.RemoveSession(orgID, keyID, isHashed)
+	} else {
+		session.KeyID = ""
+		session.KeyRotation = user.KeyRotation{NextKeyHash: newHash, GraceExpires: graceExpires}
+		if err := gw.GlobalSessionManager.UpdateSession(keyID, &session, req.GracePeriod, isHashed); err != nil {
+			return apiError("Failed to rotate key - " + err.Error()), http.StatusInternalServerError
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":       "api",
+		"key":          gw.obfuscateKey(keyID),
+		"new_key":      gw.obfuscateKey(newKey),
+		"grace_period": req.GracePeriod,
+		"status":       "ok",
+	}).Info("Rotated key.")
+
+	status := apiKeyRotationStatus{
+		Key:             newKey,
+		PreviousKeyHash: oldHash,
+		GraceExpires:    graceExpires,
+	}
+	if hashKeys {
+		status.KeyHash = newHash
+	}
+	return status, http.StatusOK
+}
+
+func (gw *Gateway) handleGetKeyRotation(keyName, orgID string, isHashed bool) (interface{}, int) {
+	session, ok := gw.GlobalSessionManager.SessionDetail(orgID, keyName, isHashed)
+	if !ok {
+		return apiError("Key not found"), http.StatusNotFound
+	}
+	if session.KeyRotation.PreviousKeyHash == "" {
+		return apiError("Key has not replaced a rotated key"), http.StatusNotFound
+	}
+
+	hash := session.KeyID
+	if !isHashed {
+		hash = storage.HashKey(session.KeyID, gw.GetConfig().HashKeys)
+	}
+
+	store := gw.GlobalSessionManager.Store()
+	status := apiKeyRotationStatus{
+		PreviousKeyHash: session.KeyRotation.PreviousKeyHash,
+		GraceExpires:    session.KeyRotation.GraceExpires,
+	}
+	if gw.GetConfig().HashKeys {
+		status.KeyHash = hash
+	}
+	if v, err := store.GetRawKey(keyRotationStatsPrefix + hash + ".requests"); err == nil {
+		status.PreviousKeyRequests, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, err := store.GetRawKey(keyRotationStatsPrefix + hash + ".last_used"); err == nil {
+		status.PreviousKeyLastUsed, _ = strconv.ParseInt(v, 10, 64)
+	}
+	return status, http.StatusOK
+}
+
+// carryOverQuota copies the quota used by the rotated key to the new key, so that rotating a key
+// doesn't renew its quota.
+func (gw *Gateway) carryOverQuota(oldHash, newHash string, session *user.SessionState) {
+	ttl := session.QuotaRenews - time.Now().Unix()
+	if session.QuotaMax == -1 || ttl <= 0 {
+		return
+	}
+	store := gw.GlobalSessionManager.Store()
+	if used, err := store.GetRawKey(QuotaKeyPrefix + oldHash); err == nil {
+		store.SetRawKey(QuotaKeyPrefix+newHash, used, ttl)
+	}
+}
+
+// rotatedKeySession returns the session of the key which replaced the rotated key of session,
+// keeping the ID of the rotated key and sharing the rate limits and quota of the new key.
+func (t BaseMiddleware) rotatedKeySession(session user.SessionState) (user.SessionState, bool) {
+	rotation := session.KeyRotation
+	now := time.Now().Unix()
+	if now > rotation.GraceExpires {
+		t.Gw.GlobalSessionManager.RemoveSession(t.Spec.OrgID, session.KeyID, false)
+		return user.SessionState{KeyID: session.KeyID}, false
+	}
+
+	next, found := t.Gw.GlobalSessionManager.SessionDetail(t.Spec.OrgID, rotation.NextKeyHash, true)
+	if !found {
+		return user.SessionState{KeyID: session.KeyID}, false
+	}
+	next.KeyID = session.KeyID
+	next.KeyRotation = rotation
+	next.SetKeyHash(rotation.NextKeyHash)
+	if err := t.ApplyPolicies(&next); err != nil {
+		t.Logger().Error(err)
+		return next, false
+	}
+
+	t.Logger().WithField("key", t.Gw.obfuscateKey(session.KeyID)).Debug("Request authenticated with rotated key")
+	t.Gw.recordRotatedKeyUse(rotation, now)
+	return next, true
+}
+
+// recordRotatedKeyUse counts the requests authenticated with a rotated key, so that it can be
+// retired once they stop.
+func (gw *Gateway) recordRotatedKeyUse(rotation user.KeyRotation, now int64) {
+	ttl := rotation.GraceExpires - now + keyRotationStatsRetention
+	store := gw.GlobalSessionManager.Store()
+	store.IncrememntWithExpire(keyRotationStatsPrefix+rotation.NextKeyHash+".requests", ttl)
+	store.SetRawKey(keyRotationStatsPrefix+rotation.NextKeyHash+".last_used", strconv.FormatInt(now, 10), ttl)
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestKeyRotation(t *testing.T) {
+	for _, hashKeys := range []bool{false, true} {
+		t.Run("hash keys "+strconv.FormatBool(hashKeys), func(t *testing.T) {
+			testKeyRotation(t, hashKeys)
+		})
+	}
+}
+
+func testKeyRotation(t *testing.T, hashKeys bool) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HashKeys = hashKeys
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "rotation"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+	})
+
+	createKey := func() string {
+		return CreateSession(ts.Gw, func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"rotation": {APIID: "rotation"}}
+		})
+	}
+	auth := func(key string) map[string]string {
+		return map[string]string{"Authorization": key}
+	}
+	rotate := func(t *testing.T, key string, grace int64) apiKeyRotationStatus {
+		t.Helper()
+		body, _ := json.Marshal(KeyRotationRequest{GracePeriod: grace})
+		resp, _ := ts.Run(t, test.TestCase{
+			Method: http.MethodPost, Path: "/tyk/keys/" + key + "/rotate", Data: body,
+			AdminAuth: true, Code: http.StatusOK,
+		})
+		var status apiKeyRotationStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		require.NotEmpty(t, status.Key)
+		return status
+	}
+
+	t.Run("grace period", func(t *testing.T) {
+		oldKey := createKey()
+		status := rotate(t, oldKey, 60)
+		assert.InDelta(t, time.Now().Unix()+60, status.GraceExpires, 2)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: auth(status.Key), Code: http.StatusOK},
+			{Path: "/", Headers: auth(oldKey), Code: http.StatusOK},
+			{Path: "/", Headers: auth(oldKey), Code: http.StatusOK},
+			// a rotated key can't be rotated again
+			{Method: http.MethodPost, Path: "/tyk/keys/" + oldKey + "/rotate", Data: `{"grace_period": 60}`,
+				AdminAuth: true, Code: http.StatusBadRequest},
+		}...)
+
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/keys/" + status.Key + "/rotate", AdminAuth: true, Code: http.StatusOK})
+		var usage apiKeyRotationStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+		assert.Equal(t, status.PreviousKeyHash, usage.PreviousKeyHash)
+		assert.Equal(t, int64(2), usage.PreviousKeyRequests)
+		assert.InDelta(t, time.Now().Unix(), usage.PreviousKeyLastUsed, 2)
+	})
+
+	t.Run("grace period expired", func(t *testing.T) {
+		oldKey := createKey()
+		status := rotate(t, oldKey, 60)
+
+		session, found := ts.Gw.GlobalSessionManager.SessionDetail("", oldKey, false)
+		require.True(t, found)
+		session.KeyRotation.GraceExpires = time.Now().Unix() - 1
+		require.NoError(t, ts.Gw.GlobalSessionManager.UpdateSession(oldKey, &session, 60, false))
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: auth(oldKey), Code: http.StatusForbidden},
+			{Path: "/", Headers: auth(status.Key), Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("without grace period", func(t *testing.T) {
+		oldKey := createKey()
+		status := rotate(t, oldKey, 0)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: auth(oldKey), Code: http.StatusForbidden},
+			{Path: "/", Headers: auth(status.Key), Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("default grace period", func(t *testing.T) {
+		conf := ts.Gw.GetConfig()
+		conf.KeyRotationGracePeriod = 60
+		ts.Gw.SetConfig(conf)
+		defer func() {
+			conf.KeyRotationGracePeriod = 0
+			ts.Gw.SetConfig(conf)
+		}()
+
+		oldKey := createKey()
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/keys/" + oldKey + "/rotate", AdminAuth: true, Code: http.StatusOK})
+		var status apiKeyRotationStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.InDelta(t, time.Now().Unix()+60, status.GraceExpires, 2)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: auth(oldKey), Code: http.StatusOK},
+			{Path: "/", Headers: auth(status.Key), Code: http.StatusOK},
+		}...)
+
+		// an explicit grace period overrides the default
+		status = rotate(t, createKey(), 0)
+		assert.InDelta(t, time.Now().Unix(), status.GraceExpires, 2)
+	})
+
+	t.Run("not rotated", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/tyk/keys/" + createKey() + "/rotate", AdminAuth: true, Code: http.StatusNotFound},
+			{Method: http.MethodPost, Path: "/tyk/keys/unknown/rotate", Data: `{}`, AdminAuth: true, Code: http.StatusNotFound},
+		}...)
+	})
+}
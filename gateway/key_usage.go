@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	rateLimiterNone     = "none"
+	rateLimiterSentinel = "sentinel"
+	rateLimiterRolling  = "rolling"
+	rateLimiterDRL      = "drl"
+)
+
+// apiKeyUsage is the live usage of a key for each API it can access.
+type apiKeyUsage struct {
+	KeyHash string                    `json:"key_hash,omitempty"`
+	APIs    map[string]apiKeyAPIUsage `json:"apis"`
+}
+
+type apiKeyAPIUsage struct {
+	APIName string           `json:"api_name,omitempty"`
+	Quota   apiKeyQuotaUsage `json:"quota"`
+	Rate    apiKeyRateUsage  `json:"rate"`
+}
+
+// apiKeyQuotaUsage is the state of a quota, Max and Remaining being -1 when unlimited.
+type apiKeyQuotaUsage struct {
+	Max         int64 `json:"max"`
+	Used        int64 `json:"used"`
+	Remaining   int64 `json:"remaining"`
+	RenewalRate int64 `json:"renewal_rate"`
+	// Renews is the Unix time at which the quota is renewed, 0 if it isn't.
+	Renews int64 `json:"renews"`
+}
+
+type apiKeyRateUsage struct {
+	Rate float64 `json:"rate"`
+	Per  float64 `json:"per"`
+	// Limiter is the rate limiter applied to the key: none, sentinel, rolling or drl.
+	Limiter string `json:"limiter"`
+	// Window is the consumption of the rolling window, unavailable for the distributed rate
+	// limiter which keeps its buckets in the memory of each gateway.
+	Window *apiKeyRateWindow `json:"window,omitempty"`
+}
+
+type apiKeyRateWindow struct {
+	Requests  int  `json:"requests"`
+	Remaining int  `json:"remaining"`
+	Blocked   bool `json:"blocked"`
+	// ResetsAt is the Unix time at which the oldest request leaves the window, 0 if unknown.
+	ResetsAt int64 `json:"resets_at,omitempty"`
+}
+
+func (gw *Gateway) keyUsageHandler(w http.ResponseWriter, r *http.Request) {
+	keyName := mux.Vars(r)["keyName"]
+	isHashed := r.URL.Query().Get("hashed") != ""
+	orgID := r.URL.Query().Get("org_id")
+
+	obj, code := gw.handleGetKeyUsage(keyName, orgID, isHashed)
+	doJSONWrite(w, code, obj)
+}
+
+func (gw *Gateway) handleGetKeyUsage(keyName, orgID string, isHashed bool) (interface{}, int) {
+	gwConfig := gw.GetConfig()
+	if isHashed && !gwConfig.HashKeys {
+		return apiError("Key requested by hash but key hashing is not enabled"), http.StatusBadRequest
+	}
+
+	session, ok := gw.GlobalSessionManager.SessionDetail(orgID, keyName, isHashed)
+	if !ok {
+		return apiError("Key not found"), http.StatusNotFound
+	}
+
+	hash := session.KeyID
+	if !isHashed {
+		hash = storage.HashKey(session.KeyID, gwConfig.HashKeys)
+	}
+	session.SetKeyHash(hash)
+
+	mw := BaseMiddleware{Gw: gw}
+	if err := mw.ApplyPolicies(&session); err != nil {
+		return apiError("Failed to apply policies - " + err.Error()), http.StatusInternalServerError
+	}
+
+	usage := apiKeyUsage{APIs: make(map[string]apiKeyAPIUsage, len(session.AccessRights))}
+	if gwConfig.HashKeys {
+		usage.KeyHash = hash
+	}
+	for apiID, rights := range session.AccessRights {
+		spec := gw.getApiSpec(apiID)
+		if spec == nil {
+			spec = &APISpec{APIDefinition: &apidef.APIDefinition{APIID: apiID, Name: rights.APIName}}
+		}
+		accessDef, scope, err := GetAccessDefinitionByAPIIDOrSession(&session, spec)
+		if err != nil {
+			continue
+		}
+
+		usage.APIs[apiID] = apiKeyAPIUsage{
+			APIName: spec.Name,
+			Quota:   gw.quotaUsage(hash, scope, accessDef.Limit, spec.DisableQuota),
+			Rate:    gw.rateUsage(hash, scope, accessDef.Limit, spec.DisableRateLimit),
+		}
+	}
+	return usage, http.StatusOK
+}
+
+// quotaUsage reads the quota counter of the key with hash, as incremented by RedisQuotaExceeded.
+func (gw *Gateway) quotaUsage(hash, scope string, limit user.APILimit, disabled bool) apiKeyQuotaUsage {
+	if disabled || limit.QuotaMax == -1 || limit.QuotaMax == 0 {
+		return apiKeyQuotaUsage{Max: -1, Remaining: -1}
+	}
+
+	usage := apiKeyQuotaUsage{
+		Max:         limit.QuotaMax,
+		Remaining:   limit.QuotaMax,
+		RenewalRate: limit.QuotaRenewalRate,
+		Renews:      limit.QuotaRenews,
+	}
+	if scope != "" {
+		scope += "-"
+	}
+	quotaKey := QuotaKeyPrefix + scope + hash
+
+	store := gw.GlobalSessionManager.Store()
+	used, err := store.GetRawKey(quotaKey)
+	if err != nil {
+		return usage
+	}
+	usage.Used, _ = strconv.ParseInt(used, 10, 64)
+	usage.Remaining = limit.QuotaMax - usage.Used
+	if usage.Remaining < 0 {
+		usage.Remaining = 0
+	}
+	if ttl, err := store.GetExp(quotaKey); err == nil && ttl > 0 {
+		usage.Renews = time.Now().Unix() + ttl
+	}
+	return usage
+}
+
+// rateUsage reads the rolling window of the key with hash, as written by doRollingWindowWrite.
+func (gw *Gateway) rateUsage(hash, scope string, limit user.APILimit, disabled bool) apiKeyRateUsage {
+	usage := apiKeyRateUsage{Rate: limit.Rate, Per: limit.Per, Limiter: rateLimiterNone}
+	if disabled || limit.Rate <= 0 {
+		return usage
+	}
+
+	gwConfig := gw.GetConfig()
+	switch {
+	case gwConfig.EnableSentinelRateLimiter:
+		usage.Limiter = rateLimiterSentinel
+	case gwConfig.EnableRedisRollingLimiter:
+		usage.Limiter = rateLimiterRolling
+	default:
+		usage.Limiter = rateLimiterDRL
+		return usage
+	}
+
+	if scope != "" {
+		scope += "-"
+	}
+	rateLimiterKey := RateLimitKeyPrefix + scope + hash
+	store := gw.SessionLimiter.store(gw.GlobalSessionManager.Store())
+
+	requests, values := store.GetRollingWindow(rateLimiterKey, int64(limit.Per), gwConfig.EnableNonTransactionalRateLimiter)
+	window := &apiKeyRateWindow{Requests: requests, Remaining: int(limit.Rate) - requests}
+	if window.Remaining < 0 {
+		window.Remaining = 0
+	}
+	if _, err := store.GetRawKey(rateLimiterKey + ".BLOCKED"); err == nil {
+		window.Blocked = true
+		window.Remaining = 0
+	}
+	// the members of the rolling window are the times of the requests in nanoseconds
+	if len(values) > 0 {
+		if oldest, ok := values[0].(string); ok {
+			if ns, err := strconv.ParseInt(oldest, 10, 64); err == nil {
+				window.ResetsAt = time.Unix(0, ns).Add(time.Duration(limit.Per) * time.Second).Unix()
+			}
+		}
+	}
+	usage.Window = window
+	return usage
+}
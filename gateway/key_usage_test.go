@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestKeyUsage(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnableRedisRollingLimiter = true
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "usage"
+		spec.Name = "Usage"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/usage/"
+	}, func(spec *APISpec) {
+		spec.APIID = "unlimited"
+		spec.UseKeylessAccess = false
+		spec.DisableQuota = true
+		spec.DisableRateLimit = true
+		spec.Proxy.ListenPath = "/unlimited/"
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.Rate = 5
+		s.Per = 60
+		s.QuotaMax = 10
+		s.QuotaRenewalRate = 3600
+		s.AccessRights = map[string]user.AccessDefinition{
+			"usage":     {APIID: "usage"},
+			"unlimited": {APIID: "unlimited"},
+		}
+	})
+	authHeaders := map[string]string{"Authorization": key}
+
+	getUsage := func(t *testing.T, key string) apiKeyUsage {
+		t.Helper()
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/keys/" + key + "/usage", AdminAuth: true, Code: http.StatusOK})
+		var usage apiKeyUsage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+		return usage
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/usage/", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/usage/", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/usage/", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/unlimited/", Headers: authHeaders, Code: http.StatusOK},
+	}...)
+
+	usage := getUsage(t, key)
+	require.Len(t, usage.APIs, 2)
+
+	limited := usage.APIs["usage"]
+	assert.Equal(t, "Usage", limited.APIName)
+	assert.Equal(t, int64(10), limited.Quota.Max)
+	assert.Equal(t, int64(3), limited.Quota.Used)
+	assert.Equal(t, int64(7), limited.Quota.Remaining)
+	assert.InDelta(t, time.Now().Unix()+3600, limited.Quota.Renews, 2)
+
+	assert.Equal(t, rateLimiterRolling, limited.Rate.Limiter)
+	require.NotNil(t, limited.Rate.Window)
+	assert.Equal(t, 3, limited.Rate.Window.Requests)
+	assert.Equal(t, 2, limited.Rate.Window.Remaining)
+	assert.False(t, limited.Rate.Window.Blocked)
+	assert.InDelta(t, time.Now().Unix()+60, limited.Rate.Window.ResetsAt, 2)
+
+	unlimited := usage.APIs["unlimited"]
+	assert.Equal(t, apiKeyQuotaUsage{Max: -1, Remaining: -1}, unlimited.Quota)
+	assert.Equal(t, rateLimiterNone, unlimited.Rate.Limiter)
+	assert.Nil(t, unlimited.Rate.Window)
+
+	t.Run("distributed rate limiter", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.EnableRedisRollingLimiter = false
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.EnableRedisRollingLimiter = true
+			ts.Gw.SetConfig(globalConf)
+		}()
+
+		limited := getUsage(t, key).APIs["usage"]
+		assert.Equal(t, rateLimiterDRL, limited.Rate.Limiter)
+		assert.Nil(t, limited.Rate.Window)
+		assert.Equal(t, int64(3), limited.Quota.Used)
+	})
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/tyk/keys/unknown/usage", AdminAuth: true, Code: http.StatusNotFound})
+}
@@ -0,0 +1,347 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	kubernetesKindLabel         = "tyk.io/kind"
+	kubernetesKindAPI           = "api"
+	kubernetesKindPolicy        = "policy"
+	kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesRequestTimeout    = 10 * time.Second
+	kubernetesRetryInterval     = 5 * time.Second
+)
+
+// kubernetesResources are the resources holding definitions.
+var kubernetesResources = []string{"configmaps", "secrets"}
+
+var kubernetesLog = log.WithField("prefix", "kubernetes")
+
+// kubernetesObject is a ConfigMap or a Secret. The data of Secrets is base64 encoded.
+type kubernetesObject struct {
+	Metadata struct {
+		Name            string            `json:"name"`
+		Labels          map[string]string `json:"labels"`
+		ResourceVersion string            `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+type kubernetesList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []kubernetesObject `json:"items"`
+}
+
+type kubernetesEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// kubernetesClient lists and watches the ConfigMaps and Secrets of a namespace with the REST API of
+// the Kubernetes API server.
+type kubernetesClient struct {
+	server        string
+	namespace     string
+	labelSelector string
+	token         string
+	client        *http.Client
+}
+
+func newKubernetesClient(conf config.KubernetesConfig) (*kubernetesClient, error) {
+	c := &kubernetesClient{
+		server:        strings.TrimSuffix(conf.APIServer, "/"),
+		namespace:     conf.Namespace,
+		labelSelector: conf.LabelSelector,
+	}
+	if c.server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("the Kubernetes API server isn't set and the gateway isn't running in a cluster")
+		}
+		c.server = "https://" + net.JoinHostPort(host, port)
+	}
+	if c.labelSelector == "" {
+		c.labelSelector = kubernetesKindLabel
+	}
+
+	if c.namespace == "" {
+		namespace, err := ioutil.ReadFile(filepath.Join(kubernetesServiceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("the namespace isn't set and can't be read from the service account: %v", err)
+		}
+		c.namespace = strings.TrimSpace(string(namespace))
+	}
+
+	tokenFile := conf.TokenFile
+	if tokenFile == "" {
+		tokenFile = filepath.Join(kubernetesServiceAccountDir, "token")
+	}
+	if token, err := ioutil.ReadFile(tokenFile); err == nil {
+		c.token = strings.TrimSpace(string(token))
+	} else if conf.TokenFile != "" {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{}
+	caFile := conf.CAFile
+	if caFile == "" {
+		caFile = filepath.Join(kubernetesServiceAccountDir, "ca.crt")
+	}
+	if ca, err := ioutil.ReadFile(caFile); err == nil {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate in the CA file %s", caFile)
+		}
+	} else if conf.CAFile != "" {
+		return nil, err
+	}
+	// no client timeout, the watches are long lived
+	c.client = &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}}
+
+	return c, nil
+}
+
+func (c *kubernetesClient) get(ctx context.Context, resource string, query url.Values) (*http.Response, error) {
+	query.Set("labelSelector", c.labelSelector)
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/%s?%s", c.server, url.PathEscape(c.namespace), resource, query.Encode())
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("request for the %s failed with status %d: %s", resource, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp, nil
+}
+
+func (c *kubernetesClient) list(resource string) (*kubernetesList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kubernetesRequestTimeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, resource, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	list := &kubernetesList{}
+	if err := json.NewDecoder(resp.Body).Decode(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// watch calls changed on each change of the resources after resourceVersion, until the watch ends.
+// It returns the resource version of the last change.
+func (c *kubernetesClient) watch(ctx context.Context, resource, resourceVersion string, changed func()) (string, error) {
+	resp, err := c.get(ctx, resource, url.Values{"watch": {"1"}, "resourceVersion": {resourceVersion}})
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event kubernetesEvent
+		if err := dec.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return resourceVersion, nil
+			}
+			return resourceVersion, err
+		}
+		if event.Type == "ERROR" {
+			// e.g. the resource version is too old, the resources are listed again
+			return resourceVersion, fmt.Errorf("watch error: %s", event.Object)
+		}
+
+		var obj kubernetesObject
+		if err := json.Unmarshal(event.Object, &obj); err != nil {
+			return resourceVersion, err
+		}
+		resourceVersion = obj.Metadata.ResourceVersion
+		if event.Type != "BOOKMARK" {
+			changed()
+		}
+	}
+}
+
+// definitions returns the definitions of kind in the ConfigMaps and Secrets, sorted by name.
+func (c *kubernetesClient) definitions(kind string) ([][]byte, error) {
+	type definition struct {
+		name string
+		data []byte
+	}
+	var defs []definition
+	for _, resource := range kubernetesResources {
+		list, err := c.list(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range list.Items {
+			if obj.Metadata.Labels[kubernetesKindLabel] != kind {
+				continue
+			}
+			for key, value := range obj.Data {
+				if !strings.HasSuffix(key, ".json") {
+					continue
+				}
+				data := []byte(value)
+				if resource == "secrets" {
+					if data, err = base64.StdEncoding.DecodeString(value); err != nil {
+						kubernetesLog.WithError(err).Errorf("Couldn't decode %s of the secret %s", key, obj.Metadata.Name)
+						continue
+					}
+				}
+				defs = append(defs, definition{name: resource + "/" + obj.Metadata.Name + "/" + key, data: data})
+			}
+		}
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].name < defs[j].name })
+	out := make([][]byte, len(defs))
+	for i, def := range defs {
+		kubernetesLog.Info("Loading definition from ", def.name)
+		out[i] = def.data
+	}
+	return out, nil
+}
+
+// FromKubernetes loads the API definitions of the ConfigMaps and Secrets labelled as APIs.
+func (a APIDefinitionLoader) FromKubernetes(conf config.KubernetesConfig) ([]*APISpec, error) {
+	c, err := newKubernetesClient(conf)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := c.definitions(kubernetesKindAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []*APISpec
+	for _, data := range defs {
+		def := apidef.APIDefinition{}
+		if err := json.Unmarshal(data, &def); err != nil {
+			kubernetesLog.WithError(err).Error("Couldn't unmarshal API configuration")
+			continue
+		}
+		specs = append(specs, a.MakeSpec(&def, nil))
+	}
+	return specs, nil
+}
+
+// LoadPoliciesFromKubernetes loads the policies of the ConfigMaps and Secrets labelled as policies.
+func LoadPoliciesFromKubernetes(conf config.KubernetesConfig) (map[string]user.Policy, error) {
+	c, err := newKubernetesClient(conf)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := c.definitions(kubernetesKindPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]user.Policy)
+	for _, data := range defs {
+		pol := user.Policy{}
+		if err := json.Unmarshal(data, &pol); err != nil {
+			kubernetesLog.WithError(err).Error("Couldn't unmarshal policy configuration")
+			continue
+		}
+		policies[pol.ID] = pol
+	}
+	return policies, nil
+}
+
+// kubernetesWatchLoop watches the ConfigMaps and Secrets, and reloads the gateway when they change.
+func (gw *Gateway) kubernetesWatchLoop(ctx context.Context) {
+	conf := gw.GetConfig().Kubernetes
+	if !conf.Enabled {
+		return
+	}
+	c, err := newKubernetesClient(conf)
+	if err != nil {
+		kubernetesLog.WithError(err).Error("Couldn't watch the Kubernetes definitions")
+		return
+	}
+
+	for _, resource := range kubernetesResources {
+		go gw.kubernetesWatch(ctx, c, resource)
+	}
+}
+
+// kubernetesWatch watches the resource from where the last watch ended. The resource is listed
+// again after errors, and the gateway reloaded as changes may have been missed.
+func (gw *Gateway) kubernetesWatch(ctx context.Context, c *kubernetesClient, resource string) {
+	reload := func() { gw.reloadURLStructure(nil) }
+
+	var resourceVersion string
+	listed := false
+	for {
+		var err error
+		if resourceVersion == "" {
+			var list *kubernetesList
+			if list, err = c.list(resource); err == nil {
+				if listed {
+					reload()
+				}
+				listed = true
+				resourceVersion = list.Metadata.ResourceVersion
+			}
+		}
+		if err == nil {
+			resourceVersion, err = c.watch(ctx, resource, resourceVersion, reload)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			continue
+		}
+
+		kubernetesLog.WithError(err).Warningf("Watch of the %s interrupted, retrying", resource)
+		resourceVersion = ""
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(kubernetesRetryInterval):
+		}
+	}
+}
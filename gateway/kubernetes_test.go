@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// fakeKubernetes serves the ConfigMaps and Secrets of the tyk namespace, and streams the events sent
+// to the configmaps watch.
+type fakeKubernetes struct {
+	mu        sync.Mutex
+	objects   map[string][]map[string]interface{}
+	version   int
+	events    chan map[string]interface{}
+	watchOpen chan struct{}
+}
+
+func (k *fakeKubernetes) set(resource, name, kind string, data map[string]string) map[string]interface{} {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.version++
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"labels":          map[string]string{kubernetesKindLabel: kind},
+			"resourceVersion": strconv.Itoa(k.version),
+		},
+		"data": data,
+	}
+	k.objects[resource] = append(k.objects[resource], obj)
+	return obj
+}
+
+func (k *fakeKubernetes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var resource string
+	if _, err := fmt.Sscanf(r.URL.Path, "/api/v1/namespaces/tyk/%s", &resource); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("watch") == "1" {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if resource != "configmaps" {
+			<-r.Context().Done()
+			return
+		}
+		k.watchOpen <- struct{}{}
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case obj := <-k.events:
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"type": "MODIFIED", "object": obj})
+				w.(http.Flusher).Flush()
+			}
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"metadata": map[string]string{"resourceVersion": strconv.Itoa(k.version)},
+		"items":    k.objects[resource],
+	})
+}
+
+func TestKubernetes(t *testing.T) {
+	k := &fakeKubernetes{
+		objects:   map[string][]map[string]interface{}{},
+		events:    make(chan map[string]interface{}),
+		watchOpen: make(chan struct{}, 1),
+	}
+	server := httptest.NewServer(k)
+	defer server.Close()
+
+	tokenFile, err := ioutil.TempFile("", "tyk-kubernetes-token-")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, _ = tokenFile.WriteString("test-token\n")
+	tokenFile.Close()
+
+	apiDefinition := func(apiID, listenPath string) string {
+		spec := BuildAPI(func(spec *APISpec) {
+			spec.APIID = apiID
+			spec.Proxy.ListenPath = listenPath
+		})[0]
+		data, err := json.Marshal(spec.APIDefinition)
+		require.NoError(t, err)
+		return string(data)
+	}
+	policy, err := json.Marshal(user.Policy{ID: "k8s-policy", Rate: 100, Per: 1})
+	require.NoError(t, err)
+
+	k.set("configmaps", "apis", kubernetesKindAPI, map[string]string{
+		"first.json": apiDefinition("k8s-1", "/k8s-1/"),
+		"README.md":  "not a definition",
+	})
+	k.set("secrets", "policies", kubernetesKindPolicy, map[string]string{
+		"policy.json": base64.StdEncoding.EncodeToString(policy),
+	})
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Kubernetes = config.KubernetesConfig{
+			Enabled:   true,
+			Namespace: "tyk",
+			APIServer: server.URL,
+			TokenFile: tokenFile.Name(),
+		}
+		globalConf.Policies.PolicySource = "kubernetes"
+	})
+	defer ts.Close()
+
+	ts.Gw.DoReload()
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/k8s-1/", Code: http.StatusOK},
+		{Path: "/k8s-2/", Code: http.StatusNotFound},
+	}...)
+	assert.Equal(t, float64(100), ts.Gw.getPolicy("k8s-policy").Rate)
+
+	ts.Gw.ReloadTestCase.Enable()
+	defer ts.Gw.ReloadTestCase.Disable()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ts.Gw.kubernetesWatchLoop(ctx)
+	select {
+	case <-k.watchOpen:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configmaps weren't watched")
+	}
+
+	k.events <- k.set("configmaps", "more-apis", kubernetesKindAPI, map[string]string{
+		"second.json": apiDefinition("k8s-2", "/k8s-2/"),
+	})
+	ts.Gw.ReloadTestCase.EnsureQueued(t)
+	ts.Gw.ReloadTestCase.TickOk(t)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/k8s-1/", Code: http.StatusOK},
+		{Path: "/k8s-2/", Code: http.StatusOK},
+	}...)
+}
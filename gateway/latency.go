@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/ctx"
+)
+
+// requestTimings accumulates where the gateway spends time while handling a request.
+type requestTimings struct {
+	start        time.Time
+	auth         time.Duration
+	middleware   time.Duration
+	upstreamTTFB time.Duration
+}
+
+func ctxGetRequestTimings(r *http.Request) *requestTimings {
+	if v := r.Context().Value(ctx.RequestTimings); v != nil {
+		return v.(*requestTimings)
+	}
+	return nil
+}
+
+// ctxStartRequestTimings starts tracking the timings of r unless already tracked.
+func ctxStartRequestTimings(r *http.Request, start time.Time) *requestTimings {
+	if timings := ctxGetRequestTimings(r); timings != nil {
+		return timings
+	}
+
+	timings := &requestTimings{start: start}
+	setCtxValue(r, ctx.RequestTimings, timings)
+	return timings
+}
+
+// addMiddleware records the time taken by a middleware, which counts as authentication when
+// it attached the session to the request.
+func (t *requestTimings) addMiddleware(d time.Duration, authenticated bool) {
+	if authenticated {
+		t.auth += d
+	} else {
+		t.middleware += d
+	}
+}
+
+// latencyBreakdown completes latency with the timings tracked for r.
+func latencyBreakdown(r *http.Request, latency Latency) Latency {
+	timings := ctxGetRequestTimings(r)
+	if timings == nil {
+		return latency
+	}
+
+	latency.Total = int64(DurationToMillisecond(time.Since(timings.start)))
+	latency.Auth = int64(DurationToMillisecond(timings.auth))
+	latency.Middleware = int64(DurationToMillisecond(timings.middleware))
+	latency.UpstreamTTFB = int64(DurationToMillisecond(timings.upstreamTTFB))
+	return latency
+}
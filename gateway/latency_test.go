@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestLatencyBreakdown(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, Latency{Total: 5, Upstream: 3}, latencyBreakdown(r, Latency{Total: 5, Upstream: 3}))
+
+	timings := ctxStartRequestTimings(r, time.Now().Add(-100*time.Millisecond))
+	assert.Equal(t, timings, ctxStartRequestTimings(r, time.Now()), "timings should be started once")
+
+	timings.addMiddleware(20*time.Millisecond, true)
+	timings.addMiddleware(10*time.Millisecond, false)
+	timings.addMiddleware(5*time.Millisecond, false)
+	timings.upstreamTTFB = 40 * time.Millisecond
+
+	latency := latencyBreakdown(r, Latency{Total: 50, Upstream: 45})
+	assert.GreaterOrEqual(t, latency.Total, int64(100))
+	assert.Equal(t, int64(45), latency.Upstream)
+	assert.Equal(t, int64(20), latency.Auth)
+	assert.Equal(t, int64(15), latency.Middleware)
+	assert.Equal(t, int64(40), latency.UpstreamTTFB)
+}
+
+func TestLatencyBreakdown_Analytics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	ts := StartTest(nil, TestConfig{Delay: 20 * time.Millisecond})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+	})
+
+	key := CreateSession(ts.Gw)
+
+	// let records to to be sent
+	time.Sleep(recordsBufferFlushInterval + 50)
+	ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: map[string]string{"Authorization": key}, Code: http.StatusOK},
+		{Path: "/", Code: http.StatusUnauthorized},
+	}...)
+
+	time.Sleep(recordsBufferFlushInterval + 50)
+	results := ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+	require.Len(t, results, 2)
+
+	records := map[int]AnalyticsRecord{}
+	for _, result := range results {
+		var record AnalyticsRecord
+		require.NoError(t, msgpack.Unmarshal([]byte(result.(string)), &record))
+		records[record.ResponseCode] = record
+	}
+
+	ok := records[http.StatusOK].Latency
+	assert.GreaterOrEqual(t, ok.UpstreamTTFB, int64(50))
+	assert.GreaterOrEqual(t, ok.Upstream, ok.UpstreamTTFB)
+	assert.GreaterOrEqual(t, ok.Total, ok.Upstream+ok.Auth+ok.Middleware)
+	assert.Equal(t, ok.Total, records[http.StatusOK].RequestTime)
+
+	failed := records[http.StatusUnauthorized].Latency
+	assert.Zero(t, failed.Upstream)
+	assert.Zero(t, failed.UpstreamTTFB)
+	assert.Equal(t, failed.Total, records[http.StatusUnauthorized].RequestTime)
+}
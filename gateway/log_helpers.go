@@ -6,6 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/trace"
 )
 
 // identifies that field value was hidden before output to the log
@@ -39,6 +40,11 @@ func (gw *Gateway) getLogEntryForRequest(logger *logrus.Entry, r *http.Request,
 			fields["key"] = gw.obfuscateKey(key)
 		}
 	}
+	// correlate the log with the trace of the request
+	if traceID, spanID := trace.IDs(r.Context()); traceID != "" {
+		fields["trace_id"] = traceID
+		fields["span_id"] = spanID
+	}
 	// add to log additional fields if any passed
 	for key, val := range data {
 		fields[key] = val
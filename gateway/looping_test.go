@@ -82,7 +82,7 @@ func TestLooping(t *testing.T) {
 			{Method: "POST", Path: "/xml", Data: getAction, BodyMatch: `"Method":"GET"`},
 
 			// Internal endpoint can be accessed only via looping
-			{Method: "GET", Path: "/get_action", Code: 403},
+			{Method: "GET", Path: "/get_action", Code: 404},
 
 			{Method: "POST", Path: "/get_action", Code: 403},
 		}...)
@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"text/template"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+const defaultMaintenanceBody = `{"error": "The API is under maintenance"}`
+
+var errMaintenanceStatusCode = errors.New("maintenance status code must be between 100 and 599")
+
+// maintenanceData is what the template of the maintenance response body is rendered with.
+type maintenanceData struct {
+	APIID   string
+	APIName string
+	Method  string
+	Path    string
+}
+
+// MaintenanceMode decides whether requests to an API are responded with the maintenance
+// response. Its configuration can be changed at runtime without reloading the API.
+type MaintenanceMode struct {
+	mu   sync.RWMutex
+	conf apidef.MaintenanceConfig
+	body *template.Template
+}
+
+// NewMaintenanceMode creates the maintenance mode from the maintenance section of an API definition.
+func NewMaintenanceMode(conf apidef.MaintenanceConfig) (*MaintenanceMode, error) {
+	m := &MaintenanceMode{}
+	if err := m.Update(conf); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Update replaces the maintenance configuration.
+func (m *MaintenanceMode) Update(conf apidef.MaintenanceConfig) error {
+	if conf.StatusCode != 0 && (conf.StatusCode < 100 || conf.StatusCode > 599) {
+		return errMaintenanceStatusCode
+	}
+
+	text := conf.Body
+	if text == "" {
+		text = defaultMaintenanceBody
+	}
+	body, err := apidef.Template.New("maintenance").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.conf = conf
+	m.body = body
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Config returns the current maintenance configuration.
+func (m *MaintenanceMode) Config() apidef.MaintenanceConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.conf
+}
+
+// applies returns whether r should be responded with the maintenance response.
+func (m *MaintenanceMode) applies(r *http.Request) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.conf.Enabled {
+		return false
+	}
+
+	if m.conf.BypassHeader != "" {
+		if value := r.Header.Get(m.conf.BypassHeader); value != "" &&
+			(m.conf.BypassValue == "" || value == m.conf.BypassValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// respond writes the maintenance response of r to w.
+func (m *MaintenanceMode) respond(w http.ResponseWriter, r *http.Request, spec *APISpec) error {
+	m.mu.RLock()
+	conf, body := m.conf, m.body
+	m.mu.RUnlock()
+
+	var buf bytes.Buffer
+	err := body.Execute(&buf, maintenanceData{
+		APIID:   spec.APIID,
+		APIName: spec.Name,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+	})
+	if err != nil {
+		return err
+	}
+
+	contentType := conf.ContentType
+	if contentType == "" {
+		contentType = headers.ApplicationJSON
+	}
+	statusCode := conf.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set(headers.ContentType, contentType)
+	if conf.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(conf.RetryAfter, 10))
+	}
+	w.WriteHeader(statusCode)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// MaintenanceMiddleware responds to the requests to an API under maintenance, unless they carry
+// the bypass header.
+type MaintenanceMiddleware struct {
+	BaseMiddleware
+}
+
+func (m *MaintenanceMiddleware) Name() string {
+	return "MaintenanceMiddleware"
+}
+
+func (m *MaintenanceMiddleware) EnabledForSpec() bool {
+	// the maintenance can be enabled at runtime, on any API
+	return m.Spec.MaintenanceMode != nil
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *MaintenanceMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	if !m.Spec.MaintenanceMode.applies(r) {
+		return nil, http.StatusOK
+	}
+
+	m.Logger().WithField("path", r.URL.Path).Debug("API under maintenance, request not proxied.")
+	if err := m.Spec.MaintenanceMode.respond(w, r, m.Spec); err != nil {
+		m.Logger().WithError(err).Error("Could not write the maintenance response")
+	}
+
+	return nil, mwStatusRespond
+}
+
+// maintenanceOverride returns the maintenance configuration of the API set with the gateway API,
+// which is kept when the API is reloaded.
+func (gw *Gateway) maintenanceOverride(apiID string) (apidef.MaintenanceConfig, bool) {
+	gw.maintenanceMu.RLock()
+	defer gw.maintenanceMu.RUnlock()
+
+	conf, ok := gw.maintenanceOverrides[apiID]
+	return conf, ok
+}
+
+func (gw *Gateway) setMaintenanceOverride(apiID string, conf *apidef.MaintenanceConfig) {
+	gw.maintenanceMu.Lock()
+	defer gw.maintenanceMu.Unlock()
+
+	if conf == nil {
+		delete(gw.maintenanceOverrides, apiID)
+		return
+	}
+	if gw.maintenanceOverrides == nil {
+		gw.maintenanceOverrides = make(map[string]apidef.MaintenanceConfig)
+	}
+	gw.maintenanceOverrides[apiID] = *conf
+}
+
+// maintenanceHandler gets and sets the maintenance configuration of an API. The configuration
+// set overrides the one of the API definition until it is deleted, also across reloads.
+func (gw *Gateway) maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil || spec.MaintenanceMode == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	var conf apidef.MaintenanceConfig
+	switch r.Method {
+	case http.MethodGet:
+		doJSONWrite(w, http.StatusOK, spec.MaintenanceMode.Config())
+		return
+	case http.MethodDelete:
+		conf = spec.APIDefinition.Maintenance
+	default:
+		conf = spec.MaintenanceMode.Config()
+		if err := json.NewDecoder(r.Body).Decode(&conf); err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+			return
+		}
+	}
+
+	if err := spec.MaintenanceMode.Update(conf); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		gw.setMaintenanceOverride(apiID, nil)
+	} else {
+		gw.setMaintenanceOverride(apiID, &conf)
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":  "api",
+		"api_id":  apiID,
+		"enabled": conf.Enabled,
+	}).Info("Maintenance configuration updated")
+
+	doJSONWrite(w, http.StatusOK, conf)
+}
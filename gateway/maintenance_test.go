@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	_, err := NewMaintenanceMode(apidef.MaintenanceConfig{StatusCode: 99})
+	assert.Equal(t, errMaintenanceStatusCode, err)
+	_, err = NewMaintenanceMode(apidef.MaintenanceConfig{Body: "{{ .APIID "})
+	assert.Error(t, err)
+
+	m, err := NewMaintenanceMode(apidef.MaintenanceConfig{
+		Enabled:      true,
+		Body:         `<p>{{ .APIName }} is down, {{ .Method }} {{ .Path }} unavailable</p>`,
+		ContentType:  "text/html",
+		RetryAfter:   120,
+		BypassHeader: "X-Bypass",
+		BypassValue:  "secret",
+	})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	assert.True(t, m.applies(r))
+	r.Header.Set("X-Bypass", "wrong")
+	assert.True(t, m.applies(r))
+	r.Header.Set("X-Bypass", "secret")
+	assert.False(t, m.applies(r))
+
+	w := httptest.NewRecorder()
+	require.NoError(t, m.respond(w, r, &APISpec{APIDefinition: &apidef.APIDefinition{Name: "Orders"}}))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Equal(t, "<p>Orders is down, GET /orders unavailable</p>", w.Body.String())
+}
+
+func TestMaintenanceAPI(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	loadAPI := func() {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "maintenance"
+			spec.Proxy.ListenPath = "/"
+			spec.Maintenance.StatusCode = http.StatusTeapot
+		})
+	}
+	loadAPI()
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Code: http.StatusOK},
+		{Method: http.MethodPut, Path: "/tyk/apis/maintenance/maintenance", Data: `{"enabled":true,"bypass_header":"X-Bypass"}`,
+			AdminAuth: true, Code: http.StatusOK},
+		{Path: "/", Code: http.StatusTeapot, BodyMatch: `The API is under maintenance`},
+		{Path: "/", Headers: map[string]string{"X-Bypass": "1"}, Code: http.StatusOK},
+		{Method: http.MethodPut, Path: "/tyk/apis/maintenance/maintenance", Data: `{"status_code":1000}`,
+			AdminAuth: true, Code: http.StatusBadRequest},
+		{Method: http.MethodGet, Path: "/tyk/apis/maintenance/maintenance", AdminAuth: true, Code: http.StatusOK,
+			BodyMatch: `"status_code":418`},
+		{Method: http.MethodPut, Path: "/tyk/apis/unknown/maintenance", Data: `{}`, AdminAuth: true, Code: http.StatusNotFound},
+	}...)
+
+	t.Run("kept on reload", func(t *testing.T) {
+		loadAPI()
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusTeapot})
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodDelete, Path: "/tyk/apis/maintenance/maintenance", AdminAuth: true, Code: http.StatusOK},
+			{Path: "/", Code: http.StatusOK},
+		}...)
+
+		loadAPI()
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusOK})
+	})
+}
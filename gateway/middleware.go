@@ -17,6 +17,8 @@ import (
 	"github.com/gocraft/health"
 	"github.com/justinas/alice"
 	newrelic "github.com/newrelic/go-agent"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
 	"github.com/paulbellamy/ratecounter"
 	"github.com/pmylund/go-cache"
 	"github.com/sirupsen/logrus"
@@ -67,7 +69,32 @@ func (tr TraceMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request,
 		)
 		defer span.Finish()
 		setContext(r, ctx)
-		return tr.TykMiddleware.ProcessRequest(w, r, conf)
+		// the logs of the middleware are correlated with its span
+		tr.SetRequestLogger(r)
+
+		base := tr.Base()
+		if base.Spec != nil {
+			span.SetTag("api_id", base.Spec.APIID)
+			span.SetTag("api_name", base.Spec.Name)
+			span.SetTag("org_id", base.Spec.OrgID)
+		}
+
+		err, code := tr.TykMiddleware.ProcessRequest(w, r, conf)
+
+		// auth middlewares attach the session while processing the request
+		if session := ctxGetSession(r); session != nil && base.Gw != nil {
+			span.SetTag("key", base.Gw.obfuscateKey(ctxGetAuthToken(r)))
+			if session.Alias != "" {
+				span.SetTag("key_alias", session.Alias)
+			}
+		}
+		if err != nil {
+			ext.Error.Set(span, true)
+			ext.HTTPStatusCode.Set(span, uint16(code))
+			span.LogFields(otlog.Error(err))
+		}
+
+		return err, code
 	}
 
 	return tr.TykMiddleware.ProcessRequest(w, r, conf)
@@ -135,7 +162,15 @@ func (gw *Gateway) createMiddleware(actualMW TykMiddleware) func(http.Handler) h
 				return
 			}
 
+			timings := ctxStartRequestTimings(r, startTime)
+			hadSession := ctxGetSession(r) != nil
+
 			err, errCode := mw.ProcessRequest(w, r, mwConf)
+			elapsed := time.Since(startTime)
+			timings.addMiddleware(elapsed, !hadSession && ctxGetSession(r) != nil)
+			if gw.benchStats != nil {
+				gw.benchStats.add(mw.Name(), elapsed)
+			}
 			if err != nil {
 				// GoPluginMiddleware are expected to send response in case of error
 				// but we still want to record error
@@ -219,6 +254,10 @@ func (t *BaseMiddleware) SetName(name string) {
 
 func (t *BaseMiddleware) SetRequestLogger(r *http.Request) {
 	t.logger = t.Gw.getLogEntryForRequest(t.Logger(), r, ctxGetAuthToken(r), nil)
+	// the logs are added to the span of the context by trace.SpanLogHook
+	if t.Spec != nil && t.Spec.Tracing.CaptureLogs {
+		t.logger = t.logger.WithContext(r.Context())
+	}
 }
 
 func (t BaseMiddleware) Init() {}
@@ -286,6 +325,12 @@ func (t BaseMiddleware) UpdateRequestSession(r *http.Request) bool {
 		return false
 	}
 
+	// requests authenticated with a rotated key use the session of the key replacing it, which
+	// isn't stored under the rotated key
+	if session.KeyRotation.NextKeyHash != "" {
+		return false
+	}
+
 	lifetime := session.Lifetime(t.Spec.SessionLifetime, t.Gw.GetConfig().ForceGlobalSessionLifetime, t.Gw.GetConfig().GlobalSessionLifetime)
 	if err := t.Gw.GlobalSessionManager.UpdateSession(token, session, lifetime, false); err != nil {
 		t.Logger().WithError(err).Error("Can't update session")
@@ -313,17 +358,18 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 	}
 
 	didQuota, didRateLimit, didACL, didComplexity := make(map[string]bool), make(map[string]bool), make(map[string]bool), make(map[string]bool)
-	policies := session.PolicyIDs()
-
-	for _, polID := range policies {
-		t.Gw.policiesMu.RLock()
-		policy, ok := t.Gw.policiesByID[polID]
-		t.Gw.policiesMu.RUnlock()
-		if !ok {
-			err := fmt.Errorf("policy not found: %q", polID)
-			t.Logger().Error(err)
-			return err
-		}
+	policies, strategy, err := t.policiesToApply(session.PolicyIDs())
+	if err != nil {
+		return err
+	}
+
+	// the limits merged by a strategy other than max, which are merged once per policy at the
+	// session level
+	mergeLimits := strategy != user.MergeStrategyMax
+	var sessionLimit user.APILimit
+	didSessionQuota, didSessionRateLimit := false, false
+
+	for _, policy := range policies {
 		// Check ownership, policy org owner must be the same as API,
 		// otherwise you could overwrite a session key with a policy from a different org!
 		if t.Spec != nil && policy.OrgID != t.Spec.OrgID {
@@ -393,19 +439,8 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 					if r, ok := rights[k]; ok {
 						r.Versions = appendIfMissing(rights[k].Versions, v.Versions...)
 
-						for _, u := range v.AllowedURLs {
-							found := false
-							for ai, au := range r.AllowedURLs {
-								if u.URL == au.URL {
-									found = true
-									r.AllowedURLs[ai].Methods = append(au.Methods, u.Methods...)
-								}
-							}
-
-							if !found {
-								r.AllowedURLs = append(r.AllowedURLs, v.AllowedURLs...)
-							}
-						}
+						r.AllowedURLs = mergeAccessSpecs(r.AllowedURLs, v.AllowedURLs)
+						r.DeniedURLs = mergeAccessSpecs(r.DeniedURLs, v.DeniedURLs)
 
 						for _, t := range v.RestrictedTypes {
 							for ri, rt := range r.RestrictedTypes {
@@ -441,7 +476,10 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 					ar.Limit.SetBy = policy.ID
 				}
 
-				if !usePartitions || policy.Partitions.Quota {
+				if (!usePartitions || policy.Partitions.Quota) && mergeLimits {
+					mergeQuota(strategy, &ar.Limit, policy, !didQuota[k])
+					didQuota[k] = true
+				} else if !usePartitions || policy.Partitions.Quota {
 					didQuota[k] = true
 					if greaterThanInt64(policy.QuotaMax, ar.Limit.QuotaMax) {
 
@@ -461,22 +499,25 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 				}
 
 				if !usePartitions || policy.Partitions.RateLimit {
-					didRateLimit[k] = true
-
-					if greaterThanFloat64(policy.Rate, ar.Limit.Rate) {
-						ar.Limit.Rate = policy.Rate
-						//if policy.Partitions.RateLimit then we must set this value in the global data of the key
-						if greaterThanFloat64(policy.Rate, session.Rate) || policy.Partitions.RateLimit {
-							session.Rate = policy.Rate
+					if mergeLimits {
+						mergeRateLimit(strategy, &ar.Limit, policy, !didRateLimit[k])
+					} else {
+						if greaterThanFloat64(policy.Rate, ar.Limit.Rate) {
+							ar.Limit.Rate = policy.Rate
+							//if policy.Partitions.RateLimit then we must set this value in the global data of the key
+							if greaterThanFloat64(policy.Rate, session.Rate) || policy.Partitions.RateLimit {
+								session.Rate = policy.Rate
+							}
 						}
-					}
 
-					if policy.Per > ar.Limit.Per {
-						ar.Limit.Per = policy.Per
-						if policy.Per > session.Per {
-							session.Per = policy.Per
+						if policy.Per > ar.Limit.Per {
+							ar.Limit.Per = policy.Per
+							if policy.Per > session.Per {
+								session.Per = policy.Per
+							}
 						}
 					}
+					didRateLimit[k] = true
 
 					if policy.ThrottleRetryLimit > ar.Limit.ThrottleRetryLimit {
 						ar.Limit.ThrottleRetryLimit = policy.ThrottleRetryLimit
@@ -514,11 +555,29 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 				}
 			}
 
+			if mergeLimits {
+				if !usePartitions || policy.Partitions.Quota {
+					mergeQuota(strategy, &sessionLimit, policy, !didSessionQuota)
+					didSessionQuota = true
+					session.QuotaMax = sessionLimit.QuotaMax
+					session.QuotaRenewalRate = sessionLimit.QuotaRenewalRate
+				}
+
+				if !usePartitions || policy.Partitions.RateLimit {
+					mergeRateLimit(strategy, &sessionLimit, policy, !didSessionRateLimit)
+					didSessionRateLimit = true
+					session.Rate = sessionLimit.Rate
+					session.Per = sessionLimit.Per
+				}
+			}
+
 			// Master policy case
 			if len(policy.AccessRights) == 0 {
 				if !usePartitions || policy.Partitions.RateLimit {
-					session.Rate = policy.Rate
-					session.Per = policy.Per
+					if !mergeLimits {
+						session.Rate = policy.Rate
+						session.Per = policy.Per
+					}
 					session.ThrottleInterval = policy.ThrottleInterval
 					session.ThrottleRetryLimit = policy.ThrottleRetryLimit
 				}
@@ -527,7 +586,7 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 					session.MaxQueryDepth = policy.MaxQueryDepth
 				}
 
-				if !usePartitions || policy.Partitions.Quota {
+				if (!usePartitions || policy.Partitions.Quota) && !mergeLimits {
 					session.QuotaMax = policy.QuotaMax
 					session.QuotaRenewalRate = policy.QuotaRenewalRate
 				}
@@ -645,8 +704,17 @@ func (t BaseMiddleware) ApplyPolicies(session *user.SessionState) error {
 }
 
 // CheckSessionAndIdentityForValidKey will check first the Session store for a valid key, if not found, it will try
-// the Auth Handler, if not found it will fail
+// the Auth Handler, if not found it will fail. Keys rotated out within their grace period return the session of
+// the key replacing them.
 func (t BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string, r *http.Request) (user.SessionState, bool) {
+	session, found := t.checkSessionAndIdentityForValidKey(originalKey, r)
+	if found && session.KeyRotation.NextKeyHash != "" {
+		return t.rotatedKeySession(session)
+	}
+	return session, found
+}
+
+func (t BaseMiddleware) checkSessionAndIdentityForValidKey(originalKey string, r *http.Request) (user.SessionState, bool) {
 	key := originalKey
 	minLength := t.Spec.GlobalConfig.MinTokenLength
 	if minLength == 0 {
@@ -676,13 +744,20 @@ func (t BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string, r
 				t.Logger().Error(err)
 				return session, false
 			}
+			t.Gw.countSessionCacheLookup("hit")
 			return session, true
 		}
+		if t.Gw.isUnknownKey(cacheKey) {
+			t.Logger().Debug("--> Key known as not found in local cache")
+			t.Gw.countSessionCacheLookup("unknown_key_hit")
+			return user.SessionState{KeyID: key}, false
+		}
+		t.Gw.countSessionCacheLookup("miss")
 	}
 
 	// Check session store
 	t.Logger().Debug("Querying keystore")
-	session, found := t.Gw.GlobalSessionManager.SessionDetail(t.Spec.OrgID, key, false)
+	session, found := t.Gw.loadSession(t.Gw.GlobalSessionManager, t.Spec.OrgID, key)
 
 	if found {
 		if t.Spec.GlobalConfig.HashKeys {
@@ -737,6 +812,9 @@ func (t BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string, r
 	} else {
 		// defaulting
 		session.KeyID = key
+		if !t.Spec.GlobalConfig.LocalSessionCache.DisableCacheSessionState {
+			t.Gw.rememberUnknownKey(cacheKey)
+		}
 	}
 
 	return session, found
@@ -824,6 +902,8 @@ func (gw *Gateway) responseProcessorByName(name string) TykResponseHandler {
 		return &CustomMiddlewareResponseHook{Gw: gw}
 	case "goplugin_res_hook":
 		return &ResponseGoPluginMiddleware{}
+	case "wasm_res_hook":
+		return &ResponseWasmMiddleware{}
 	}
 
 	return nil
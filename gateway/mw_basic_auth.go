@@ -7,11 +7,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/gocraft/health"
 	cache "github.com/pmylund/go-cache"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/sync/singleflight"
 
 	"github.com/TykTechnologies/murmur3"
@@ -200,8 +201,8 @@ func (k *BasicAuthKeyIsValid) ProcessRequest(w http.ResponseWriter, r *http.Requ
 	}
 
 	switch session.BasicAuthData.Hash {
-	case user.HashBCrypt:
-		if err := k.compareHashAndPassword(session.BasicAuthData.Password, password, logger); err != nil {
+	case user.HashBCrypt, user.HashArgon2id:
+		if err := k.compareHashAndPassword(session.BasicAuthData.Hash, session.BasicAuthData.Password, password, logger); err != nil {
 			logger.Warn("Attempted access with existing user, failed password check.")
 			return k.handleAuthFail(w, r, token)
 		}
@@ -212,6 +213,17 @@ func (k *BasicAuthKeyIsValid) ProcessRequest(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
+	hashConf := k.Gw.GetConfig().BasicAuthHash
+	if passwordNeedsRehash(hashConf, session.BasicAuthData) {
+		atomic.AddUint64(&k.Gw.basicAuthHashStats.legacyHashLogins, 1)
+		job := instrument.NewJob("BasicAuthHash")
+		job.EventKv("legacy_hash", health.Kvs{"api_id": k.Spec.APIID, "hash_type": string(session.BasicAuthData.Hash)})
+
+		if hashConf.RehashOnLogin {
+			k.rehashPassword(r, &session, keyName, password, logger)
+		}
+	}
+
 	// Set session state on context, we will need it later
 	switch k.Spec.BaseIdentityProvidedBy {
 	case apidef.BasicAuthUser, apidef.UnsetAuth:
@@ -232,8 +244,40 @@ func (k *BasicAuthKeyIsValid) handleAuthFail(w http.ResponseWriter, r *http.Requ
 	return k.requestForBasicAuth(w, "User not authorised")
 }
 
-func (k *BasicAuthKeyIsValid) doBcryptWithCache(cacheDuration time.Duration, hashedPassword []byte, password []byte) error {
-	if err := bcrypt.CompareHashAndPassword(hashedPassword, password); err != nil {
+// rehashPassword migrates the stored password of a session to the configured hashing algorithm.
+func (k *BasicAuthKeyIsValid) rehashPassword(r *http.Request, session *user.SessionState, keyName, password string, logger *logrus.Entry) {
+	hashConf := k.Gw.GetConfig().BasicAuthHash
+
+	newPass, hashType, err := hashPassword(hashConf, password)
+	if err != nil {
+		atomic.AddUint64(&k.Gw.basicAuthHashStats.rehashFailures, 1)
+		logger.WithError(err).Error("Could not rehash basic auth password")
+		return
+	}
+
+	session.BasicAuthData.Password = newPass
+	session.BasicAuthData.Hash = hashType
+
+	lifetime := session.Lifetime(k.Spec.SessionLifetime, k.Gw.GetConfig().ForceGlobalSessionLifetime, k.Gw.GetConfig().GlobalSessionLifetime)
+	if err := k.Gw.GlobalSessionManager.UpdateSession(keyName, session, lifetime, false); err != nil {
+		atomic.AddUint64(&k.Gw.basicAuthHashStats.rehashFailures, 1)
+		logger.WithError(err).Error("Could not store rehashed basic auth password")
+		return
+	}
+	atomic.AddUint64(&k.Gw.basicAuthHashStats.rehashed, 1)
+
+	if !k.Spec.GlobalConfig.LocalSessionCache.DisableCacheSessionState {
+		k.Gw.SessionCache.Set(session.KeyHash(), session.Clone(), cache.DefaultExpiration)
+	}
+
+	job := instrument.NewJob("BasicAuthHash")
+	job.EventKv("rehashed", health.Kvs{"api_id": k.Spec.APIID, "hash_type": string(hashType)})
+
+	logger.Info("Basic auth password rehashed.")
+}
+
+func (k *BasicAuthKeyIsValid) doCompareWithCache(cacheDuration time.Duration, hashType user.HashType, hashedPassword []byte, password []byte) error {
+	if err := comparePasswordHash(hashType, hashedPassword, password); err != nil {
 		return err
 	}
 
@@ -244,13 +288,13 @@ func (k *BasicAuthKeyIsValid) doBcryptWithCache(cacheDuration time.Duration, has
 	return nil
 }
 
-func (k *BasicAuthKeyIsValid) compareHashAndPassword(hash string, password string, logEntry *logrus.Entry) error {
+func (k *BasicAuthKeyIsValid) compareHashAndPassword(hashType user.HashType, hash string, password string, logEntry *logrus.Entry) error {
 	passwordBytes := []byte(password)
 	hashBytes := []byte(hash)
 
 	if k.Spec.BasicAuth.DisableCaching {
 		logEntry.Debug("cache disabled")
-		return bcrypt.CompareHashAndPassword(hashBytes, passwordBytes)
+		return comparePasswordHash(hashType, hashBytes, passwordBytes)
 	}
 
 	cacheTTL := defaultBasicAuthTTL // set a default TTL, then override based on BasicAuth.CacheTTL
@@ -260,9 +304,9 @@ func (k *BasicAuthKeyIsValid) compareHashAndPassword(hash string, password strin
 
 	cachedPass, inCache := basicAuthCache.Get(hash)
 	if !inCache {
-		logEntry.Debug("cache enabled: miss: bcrypt")
+		logEntry.Debug("cache enabled: miss: ", hashType)
 		_, err, _ := cacheGroup.Do(hash+"."+password, func() (interface{}, error) {
-			return nil, k.doBcryptWithCache(cacheTTL, hashBytes, passwordBytes)
+			return nil, k.doCompareWithCache(cacheTTL, hashType, hashBytes, passwordBytes)
 		})
 
 		return err
@@ -272,8 +316,8 @@ func (k *BasicAuthKeyIsValid) compareHashAndPassword(hash string, password strin
 	hasher.Write(passwordBytes)
 	if cachedPass.(string) != string(hasher.Sum(nil)) {
 
-		logEntry.Warn("cache enabled: hit: failed auth: bcrypt")
-		return bcrypt.CompareHashAndPassword(hashBytes, passwordBytes)
+		logEntry.Warn("cache enabled: hit: failed auth: ", hashType)
+		return comparePasswordHash(hashType, hashBytes, passwordBytes)
 	}
 
 	logEntry.Debug("cache enabled: hit: success")
@@ -7,6 +7,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/storage"
 
 	"github.com/TykTechnologies/tyk/test"
@@ -285,3 +288,99 @@ func BenchmarkBasicAuth_CacheDisabled(b *testing.B) {
 		}...)
 	}
 }
+
+func TestBasicAuthHashAlgorithm(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.BasicAuthHash.Algorithm = string(user.HashArgon2id)
+		globalConf.BasicAuthHash.Argon2Memory = 1024
+	})
+	defer ts.Close()
+
+	session := ts.testPrepareBasicAuth(false)
+
+	validPassword := map[string]string{"Authorization": genAuthHeader("user", "password")}
+	wrongPassword := map[string]string{"Authorization": genAuthHeader("user", "wrong")}
+
+	ts.Run(t, []test.TestCase{
+		{Method: "POST", Path: "/tyk/keys/defaultuser", Data: session, AdminAuth: true, Code: 200},
+		{Method: "GET", Path: "/tyk/keys/defaultuser?api_id=test", AdminAuth: true, Code: 200, BodyMatch: `"hash_type":"argon2id"`},
+		{Method: "GET", Path: "/", Headers: validPassword, Code: 200},
+		{Method: "GET", Path: "/", Headers: wrongPassword, Code: 401},
+	}...)
+}
+
+func TestBasicAuthRehashOnLogin(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	session := ts.testPrepareBasicAuth(true)
+
+	validPassword := map[string]string{"Authorization": genAuthHeader("user", "password")}
+
+	ts.Run(t, []test.TestCase{
+		{Method: "POST", Path: "/tyk/keys/defaultuser", Data: session, AdminAuth: true, Code: 200},
+		{Method: "GET", Path: "/tyk/keys/defaultuser?api_id=test", AdminAuth: true, Code: 200, BodyMatch: `"hash_type":"bcrypt"`},
+	}...)
+
+	globalConf := ts.Gw.GetConfig()
+	globalConf.BasicAuthHash.Algorithm = string(user.HashArgon2id)
+	globalConf.BasicAuthHash.Argon2Memory = 1024
+	ts.Gw.SetConfig(globalConf)
+
+	// without rehash_on_login the legacy hash is kept
+	ts.Run(t, []test.TestCase{
+		{Method: "GET", Path: "/", Headers: validPassword, Code: 200},
+		{Method: "GET", Path: "/tyk/keys/defaultuser?api_id=test", AdminAuth: true, Code: 200, BodyMatch: `"hash_type":"bcrypt"`},
+	}...)
+	assert.Equal(t, BasicAuthHashMetrics{LegacyHashLogins: 1}, ts.Gw.basicAuthHashMetrics())
+
+	globalConf.BasicAuthHash.RehashOnLogin = true
+	ts.Gw.SetConfig(globalConf)
+
+	ts.Run(t, []test.TestCase{
+		{Method: "GET", Path: "/", Headers: validPassword, Code: 200},
+		{Method: "GET", Path: "/tyk/keys/defaultuser?api_id=test", AdminAuth: true, Code: 200, BodyMatch: `"hash_type":"argon2id"`},
+		{Method: "GET", Path: "/", Headers: validPassword, Code: 200},
+		{Method: "GET", Path: "/", Headers: map[string]string{"Authorization": genAuthHeader("user", "wrong")}, Code: 401},
+	}...)
+	assert.Equal(t, BasicAuthHashMetrics{LegacyHashLogins: 2, Rehashed: 1}, ts.Gw.basicAuthHashMetrics())
+
+	ts.Gw.gatherHealthChecks()
+	assert.Equal(t, ts.Gw.basicAuthHashMetrics(), getHealthCheckInfo()["basic_auth_hash"].Metrics)
+}
+
+func TestValidateBasicAuthHashConfig(t *testing.T) {
+	assert.NoError(t, validateBasicAuthHashConfig(config.BasicAuthHashConfig{}))
+	assert.NoError(t, validateBasicAuthHashConfig(config.BasicAuthHashConfig{Algorithm: string(user.HashArgon2id)}))
+	assert.Error(t, validateBasicAuthHashConfig(config.BasicAuthHashConfig{Algorithm: "md5"}))
+	assert.Error(t, validateBasicAuthHashConfig(config.BasicAuthHashConfig{BCryptCost: 2}))
+	assert.Error(t, validateBasicAuthHashConfig(config.BasicAuthHashConfig{BCryptCost: 32}))
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	conf := config.BasicAuthHashConfig{BCryptCost: 4}
+
+	hash, hashType, err := hashPassword(conf, "password")
+	assert.NoError(t, err)
+	assert.Equal(t, user.HashBCrypt, hashType)
+	assert.NoError(t, comparePasswordHash(hashType, []byte(hash), []byte("password")))
+
+	data := user.BasicAuthData{Password: hash, Hash: hashType}
+	assert.False(t, passwordNeedsRehash(conf, data))
+	assert.True(t, passwordNeedsRehash(config.BasicAuthHashConfig{BCryptCost: 5}, data))
+	assert.True(t, passwordNeedsRehash(conf, user.BasicAuthData{Password: "password"}))
+
+	conf = config.BasicAuthHashConfig{Algorithm: string(user.HashArgon2id), Argon2Memory: 1024}
+	assert.True(t, passwordNeedsRehash(conf, data))
+
+	hash, hashType, err = hashPassword(conf, "password")
+	assert.NoError(t, err)
+	assert.Equal(t, user.HashArgon2id, hashType)
+	assert.NoError(t, comparePasswordHash(hashType, []byte(hash), []byte("password")))
+	assert.Error(t, comparePasswordHash(hashType, []byte(hash), []byte("wrong")))
+
+	data = user.BasicAuthData{Password: hash, Hash: hashType}
+	assert.False(t, passwordNeedsRehash(conf, data))
+	conf.Argon2Time = 2
+	assert.True(t, passwordNeedsRehash(conf, data))
+}
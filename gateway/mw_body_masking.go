@@ -0,0 +1,314 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	bodyMaskTokenPrefix       = "body-mask-token."
+	bodyMaskDefaultRedaction  = "****"
+	bodyMaskTokenRandomLength = 16
+)
+
+var errBodyNotJSON = errors.New("body is not valid JSON")
+
+// BodyMaskingSpec is an endpoint body masking configuration with its compiled rules.
+type BodyMaskingSpec struct {
+	apidef.BodyMaskingMeta
+	masks []bodyMask
+}
+
+// bodyMaskSegment is a step of a JSONPath, key is a field name, an array index or `*`.
+type bodyMaskSegment struct {
+	key string
+	// recursive matches key at any depth, as `..key` does.
+	recursive bool
+}
+
+func (s bodyMaskSegment) matches(key string) bool {
+	return s.key == "*" || s.key == key
+}
+
+type bodyMask struct {
+	apidef.BodyMaskRule
+	segments []bodyMaskSegment
+}
+
+func newBodyMask(rule apidef.BodyMaskRule) (bodyMask, error) {
+	switch rule.Action {
+	case apidef.BodyMaskRedact, apidef.BodyMaskHash, apidef.BodyMaskTokenize:
+	default:
+		return bodyMask{}, fmt.Errorf("unknown body masking action %q", rule.Action)
+	}
+
+	segments, err := parseBodyMaskPath(rule.JSONPath)
+	if err != nil {
+		return bodyMask{}, err
+	}
+	return bodyMask{BodyMaskRule: rule, segments: segments}, nil
+}
+
+// parseBodyMaskPath parses the JSONPath subset supported by body masking: field names, array
+// indexes, `*` wildcards and `..` recursive descent, e.g. `$.items[*].card` or `$..email`.
+func parseBodyMaskPath(path string) ([]bodyMaskSegment, error) {
+	invalid := fmt.Errorf("invalid body masking JSONPath %q", path)
+
+	rest := strings.TrimPrefix(path, "$")
+	var segments []bodyMaskSegment
+	for rest != "" {
+		var seg bodyMaskSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, invalid
+			}
+			seg.key = rest[1:end]
+			if _, err := strconv.Atoi(seg.key); err != nil && seg.key != "*" {
+				return nil, invalid
+			}
+			rest = rest[end+1:]
+			segments = append(segments, seg)
+			continue
+		default:
+			return nil, invalid
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end == -1 {
+			end = len(rest)
+		}
+		if seg.key = rest[:end]; seg.key == "" {
+			return nil, invalid
+		}
+		rest = rest[end:]
+		segments = append(segments, seg)
+	}
+
+	if len(segments) == 0 {
+		return nil, invalid
+	}
+	return segments, nil
+}
+
+// applyBodyMask replaces the values of node selected by segments with the result of mask.
+func applyBodyMask(node interface{}, segments []bodyMaskSegment, mask func(interface{}) (interface{}, error)) (interface{}, error) {
+	if len(segments) == 0 {
+		return mask(node)
+	}
+
+	seg := segments[0]
+	update := func(key string, child interface{}) (interface{}, error) {
+		var err error
+		// descend first, so that a match containing deeper matches is masked as a whole last
+		if seg.recursive {
+			if child, err = applyBodyMask(child, segments, mask); err != nil {
+				return nil, err
+			}
+		}
+		if seg.matches(key) {
+			return applyBodyMask(child, segments[1:], mask)
+		}
+		return child, nil
+	}
+
+	var err error
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, child := range n {
+			if n[key], err = update(key, child); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, child := range n {
+			if n[i], err = update(strconv.Itoa(i), child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return node, nil
+}
+
+// validateBodyMasking returns the first invalid body masking rule of the versions of def.
+func validateBodyMasking(def *apidef.APIDefinition) error {
+	for _, version := range def.VersionData.Versions {
+		for _, meta := range version.ExtendedPaths.BodyMasking {
+			for _, rule := range meta.Rules {
+				if _, err := newBodyMask(rule); err != nil {
+					return fmt.Errorf("%s %s: %v", meta.Method, meta.Path, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// maskBody applies masks to the JSON body, tokenized values are kept in store and hashed values
+// are HMACs keyed with hashKey, so that they can't be reversed by hashing guesses.
+func maskBody(body []byte, masks []bodyMask, store storage.Handler, hashKey []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, errBodyNotJSON
+	}
+
+	var err error
+	for _, m := range masks {
+		if data, err = applyBodyMask(data, m.segments, m.maskValue(store, hashKey)); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(data)
+}
+
+func (m bodyMask) maskValue(store storage.Handler, hashKey []byte) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		switch m.Action {
+		case apidef.BodyMaskHash:
+			mac := hmac.New(sha256.New, hashKey)
+			mac.Write(bodyMaskValueBytes(value))
+			return hex.EncodeToString(mac.Sum(nil)), nil
+		case apidef.BodyMaskTokenize:
+			random := make([]byte, bodyMaskTokenRandomLength)
+			if _, err := rand.Read(random); err != nil {
+				return nil, err
+			}
+			token := "tok_" + hex.EncodeToString(random)
+			if err := store.SetKey(token, string(bodyMaskValueBytes(value)), m.TokenTTL); err != nil {
+				return nil, err
+			}
+			return token, nil
+		default:
+			if m.Replacement == "" {
+				return bodyMaskDefaultRedaction, nil
+			}
+			return m.Replacement, nil
+		}
+	}
+}
+
+// bodyMaskValueBytes returns strings as they are and other values JSON encoded.
+func bodyMaskValueBytes(value interface{}) []byte {
+	if s, ok := value.(string); ok {
+		return []byte(s)
+	}
+	b, _ := json.Marshal(value)
+	return b
+}
+
+func (gw *Gateway) bodyMaskTokenStore() storage.Handler {
+	return &storage.RedisCluster{KeyPrefix: bodyMaskTokenPrefix, RedisController: gw.RedisController}
+}
+
+// BodyMasking is a middleware that redacts, hashes or tokenizes fields of the JSON request body
+// before it's proxied upstream
+type BodyMasking struct {
+	BaseMiddleware
+}
+
+func (b *BodyMasking) Name() string {
+	return "BodyMasking"
+}
+
+func (b *BodyMasking) EnabledForSpec() bool {
+	for _, version := range b.Spec.VersionData.Versions {
+		if len(version.ExtendedPaths.BodyMasking) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (b *BodyMasking) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	vInfo, _ := b.Spec.Version(r)
+	versionPaths := b.Spec.RxPaths[vInfo.Name]
+	found, meta := b.Spec.CheckSpecMatchesStatus(r, versionPaths, BodyMasked)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return errors.New("could not read request body"), http.StatusBadRequest
+	}
+	if len(body) == 0 {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return nil, http.StatusOK
+	}
+
+	// a request which can't be masked is rejected rather than proxied with the sensitive fields
+	masked, err := maskBody(body, meta.(*BodyMaskingSpec).masks, b.Gw.bodyMaskTokenStore(), []byte(b.Gw.GetConfig().Secret))
+	if err == errBodyNotJSON {
+		return errors.New("request body is not valid JSON"), http.StatusBadRequest
+	}
+	if err != nil {
+		b.Logger().WithError(err).Error("Could not mask request body")
+		return errors.New("could not mask request body"), http.StatusInternalServerError
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(masked))
+	r.ContentLength = int64(len(masked))
+	nopCloseRequestBody(r)
+	return nil, http.StatusOK
+}
+
+// maskResponseBody applies the body masking rules of req to res. The response is replaced by a
+// 502 error when it can't be masked, in which case it returns false.
+func (p *ReverseProxy) maskResponseBody(rw http.ResponseWriter, req, logreq *http.Request, res *http.Response) bool {
+	vInfo, _ := p.TykAPISpec.Version(req)
+	if vInfo == nil || len(vInfo.ExtendedPaths.BodyMasking) == 0 || res.StatusCode == http.StatusSwitchingProtocols {
+		return true
+	}
+
+	versionPaths := p.TykAPISpec.RxPaths[vInfo.Name]
+	found, meta := p.TykAPISpec.CheckSpecMatchesStatus(req, versionPaths, BodyMaskedResponse)
+	if !found {
+		return true
+	}
+
+	respBody := respBodyReader(req, res)
+	body, err := ioutil.ReadAll(respBody)
+	respBody.Close()
+	res.Body.Close()
+	if err == nil && len(body) > 0 {
+		body, err = maskBody(body, meta.(*BodyMaskingSpec).masks, p.Gw.bodyMaskTokenStore(), []byte(p.Gw.GetConfig().Secret))
+	}
+	if err != nil {
+		p.logger.WithError(err).Error("Could not mask upstream response body")
+		p.ErrorHandler.HandleError(rw, logreq, "There was a problem proxying the request", http.StatusBadGateway, true)
+		return false
+	}
+
+	// Re-compress if original upstream response was compressed
+	var bodyBuffer bytes.Buffer
+	bodyBuffer.Write(body)
+	bodyBuffer = compressBuffer(bodyBuffer, res.Header.Get("Content-Encoding"))
+
+	res.ContentLength = int64(bodyBuffer.Len())
+	res.Header.Set("Content-Length", strconv.Itoa(bodyBuffer.Len()))
+	res.Body = ioutil.NopCloser(&bodyBuffer)
+	return true
+}
@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestMaskBody(t *testing.T) {
+	body := []byte(`{"card":{"number":"4111111111111111","cvv":123},"items":[{"email":"a@example.com"},{"email":"b@example.com"}],"contact":{"email":"c@example.com"},"amount":10.50}`)
+
+	for _, tc := range []struct {
+		name     string
+		rule     apidef.BodyMaskRule
+		expected string
+	}{
+		{"redact field", apidef.BodyMaskRule{JSONPath: "$.card.number", Action: apidef.BodyMaskRedact},
+			`"card":{"cvv":123,"number":"****"}`},
+		{"redact with replacement", apidef.BodyMaskRule{JSONPath: "$.card.cvv", Action: apidef.BodyMaskRedact, Replacement: "XXX"},
+			`"card":{"cvv":"XXX","number":"4111111111111111"}`},
+		{"array wildcard", apidef.BodyMaskRule{JSONPath: "$.items[*].email", Action: apidef.BodyMaskRedact},
+			`"items":[{"email":"****"},{"email":"****"}]`},
+		{"array index", apidef.BodyMaskRule{JSONPath: "$.items[1].email", Action: apidef.BodyMaskRedact},
+			`"items":[{"email":"a@example.com"},{"email":"****"}]`},
+		{"recursive descent", apidef.BodyMaskRule{JSONPath: "$..email", Action: apidef.BodyMaskRedact},
+			`"contact":{"email":"****"},"items":[{"email":"****"},{"email":"****"}]`},
+		{"hash", apidef.BodyMaskRule{JSONPath: "$.card.number", Action: apidef.BodyMaskHash},
+			`"number":"d6c005134ac50dec0e01cbc4aeaf3fbdb511c4ceeb55f909c29def3b0cffba36"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mask, err := newBodyMask(tc.rule)
+			require.NoError(t, err)
+
+			masked, err := maskBody(body, []bodyMask{mask}, nil, []byte("secret"))
+			require.NoError(t, err)
+			assert.Contains(t, string(masked), tc.expected)
+			assert.Contains(t, string(masked), `"amount":10.50`, "numbers should be kept as they are")
+		})
+	}
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, rule := range []apidef.BodyMaskRule{
+			{JSONPath: "card.number", Action: apidef.BodyMaskRedact},
+			{JSONPath: "$", Action: apidef.BodyMaskRedact},
+			{JSONPath: "$.items[first]", Action: apidef.BodyMaskRedact},
+			{JSONPath: "$.card", Action: "encrypt"},
+		} {
+			_, err := newBodyMask(rule)
+			assert.Error(t, err, rule.JSONPath)
+		}
+
+		def := &apidef.APIDefinition{}
+		def.VersionData.Versions = map[string]apidef.VersionInfo{"v1": {}}
+		assert.NoError(t, validateBodyMasking(def))
+
+		version := def.VersionData.Versions["v1"]
+		version.ExtendedPaths.BodyMasking = []apidef.BodyMaskingMeta{{Path: "/payments", Method: http.MethodPost,
+			Rules: []apidef.BodyMaskRule{{JSONPath: "$.card", Action: "encrypt"}}}}
+		def.VersionData.Versions["v1"] = version
+		assert.EqualError(t, validateBodyMasking(def), `POST /payments: unknown body masking action "encrypt"`)
+	})
+
+	t.Run("hash keyed with the secret", func(t *testing.T) {
+		mask, err := newBodyMask(apidef.BodyMaskRule{JSONPath: "$.card.number", Action: apidef.BodyMaskHash})
+		require.NoError(t, err)
+
+		masked, err := maskBody(body, []bodyMask{mask}, nil, []byte("other secret"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(masked), `"number":"d6c005134ac50dec0e01cbc4aeaf3fbdb511c4ceeb55f909c29def3b0cffba36"`)
+	})
+
+	t.Run("not JSON", func(t *testing.T) {
+		_, err := maskBody([]byte("card=4111111111111111"), nil, nil, nil)
+		assert.Equal(t, errBodyNotJSON, err)
+	})
+}
+
+func TestBodyMasking(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"account":{"iban":"GB33BUKB20201555555555","owner":"Jane"}}`))
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.BodyMasking = []apidef.BodyMaskingMeta{{
+				Path:   "/payments",
+				Method: http.MethodPost,
+				Rules: []apidef.BodyMaskRule{
+					{JSONPath: "$.card.number", Action: apidef.BodyMaskTokenize, TokenTTL: 60},
+					{JSONPath: "$.card.cvv", Action: apidef.BodyMaskRedact},
+				},
+			}}
+		})
+	}, func(spec *APISpec) {
+		spec.APIID = "masked-response"
+		spec.Proxy.ListenPath = "/accounts/"
+		spec.Proxy.TargetURL = upstream.URL
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.BodyMasking = []apidef.BodyMaskingMeta{{
+				Path:         "/{id}",
+				Method:       http.MethodGet,
+				Rules:        []apidef.BodyMaskRule{{JSONPath: "$.account.iban", Action: apidef.BodyMaskRedact}},
+				MaskResponse: true,
+			}}
+		})
+	}, func(spec *APISpec) {
+		spec.APIID = "invalid-masking"
+		spec.Proxy.ListenPath = "/invalid/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.BodyMasking = []apidef.BodyMaskingMeta{{
+				Path:   "/payments",
+				Method: http.MethodPost,
+				Rules:  []apidef.BodyMaskRule{{JSONPath: "card.number", Action: apidef.BodyMaskRedact}},
+			}}
+		})
+	})
+
+	resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/payments",
+		Data: `{"card":{"number":"4111111111111111","cvv":"123"}}`, Code: http.StatusOK})
+	var upstreamReq TestHttpResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&upstreamReq))
+
+	var forwarded struct {
+		Card struct {
+			Number, CVV string
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(upstreamReq.Body), &forwarded))
+	assert.Equal(t, "****", forwarded.Card.CVV)
+	require.True(t, strings.HasPrefix(forwarded.Card.Number, "tok_"))
+
+	original, err := ts.Gw.bodyMaskTokenStore().GetKey(forwarded.Card.Number)
+	require.NoError(t, err)
+	assert.Equal(t, "4111111111111111", original, "tokenized values should be kept")
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/payments", Data: `card=4111111111111111`, Code: http.StatusBadRequest},
+		{Method: http.MethodPost, Path: "/other", Data: `{"card":{"cvv":"123"}}`, Code: http.StatusOK,
+			BodyMatch: `\\"cvv\\":\\"123\\"`},
+		{Path: "/accounts/1", Code: http.StatusOK, BodyMatch: `"iban":"\*\*\*\*"`},
+		{Method: http.MethodPost, Path: "/invalid/payments", Data: `{"card":{"number":"4111111111111111"}}`, Code: http.StatusNotFound},
+	}...)
+}
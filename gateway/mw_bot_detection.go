@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/regexp"
+	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	botScoreHeader = "X-Tyk-Bot-Score"
+	botTag         = "bot"
+	// botBurstKeyPrefix prefixes the request counters of the clients of each API.
+	botBurstKeyPrefix = "bot-detection-"
+
+	botScoreMissingUserAgent = 40
+	botScoreBadUserAgent     = 60
+	botScoreMissingAccept    = 15
+	// botScoreBrowserAnomaly scores user agents claiming to be a browser without sending the
+	// headers every browser sends.
+	botScoreBrowserAnomaly = 20
+	botScoreBurst          = 40
+	botScoreMax            = 100
+)
+
+// scannerUserAgents matches the user agents of common vulnerability scanners.
+var scannerUserAgents = regexp.MustCompile(`(?i)sqlmap|nikto|nmap|masscan|zgrab|dirbuster|gobuster|wpscan|nuclei|acunetix|nessus`)
+
+// BotDetectionMiddleware scores requests on how likely they are to come from a bot, and tags,
+// throttles or blocks them above the thresholds of the API
+type BotDetectionMiddleware struct {
+	BaseMiddleware
+	badUserAgents []*regexp.Regexp
+	burstStore    storage.Handler
+}
+
+func (b *BotDetectionMiddleware) Name() string {
+	return "BotDetectionMiddleware"
+}
+
+func (b *BotDetectionMiddleware) EnabledForSpec() bool {
+	if !b.Spec.BotDetection.Enabled {
+		return false
+	}
+
+	// We'll init here, the patterns were validated when the API was loaded
+	var err error
+	if b.badUserAgents, err = compileBadUserAgents(b.Spec.BotDetection.BadUserAgents); err != nil {
+		b.Logger().WithError(err).Error("Invalid bad user agent pattern")
+	}
+	b.burstStore = &storage.RedisCluster{KeyPrefix: botBurstKeyPrefix, RedisController: b.Gw.RedisController}
+
+	return true
+}
+
+// compileBadUserAgents compiles the bad user agent patterns of an API, after the patterns of the
+// common scanners.
+func compileBadUserAgents(patterns []string) ([]*regexp.Regexp, error) {
+	badUserAgents := []*regexp.Regexp{scannerUserAgents}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return badUserAgents, fmt.Errorf("bad user agent %q: %v", pattern, err)
+		}
+		badUserAgents = append(badUserAgents, re)
+	}
+	return badUserAgents, nil
+}
+
+// score returns the bot score of r.
+func (b *BotDetectionMiddleware) score(r *http.Request) int {
+	score := 0
+
+	userAgent := r.Header.Get(headers.UserAgent)
+	if userAgent == "" {
+		score += botScoreMissingUserAgent
+	}
+	for _, re := range b.badUserAgents {
+		if userAgent != "" && re.MatchString(userAgent) {
+			score += botScoreBadUserAgent
+			break
+		}
+	}
+	if r.Header.Get(headers.Accept) == "" {
+		score += botScoreMissingAccept
+	}
+	if strings.HasPrefix(userAgent, "Mozilla/") && r.Header.Get("Accept-Language") == "" {
+		score += botScoreBrowserAnomaly
+	}
+
+	conf := b.Spec.BotDetection
+	if conf.BurstRequests > 0 && conf.BurstPeriod > 0 {
+		// the store doesn't prefix the keys it increments
+		key := botBurstKeyPrefix + b.Spec.APIID + "-" + request.RealIP(r)
+		if b.burstStore.IncrememntWithExpire(key, conf.BurstPeriod) > conf.BurstRequests {
+			score += botScoreBurst
+		}
+	}
+
+	if score > botScoreMax {
+		score = botScoreMax
+	}
+	return score
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (b *BotDetectionMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	// the score is only set by the gateway
+	r.Header.Del(botScoreHeader)
+
+	score := b.score(r)
+	ctxSetBotScore(r, score)
+
+	conf := b.Spec.BotDetection
+	if botThresholdReached(score, conf.BlockThreshold) {
+		b.Logger().WithField("score", score).Info("Request blocked by bot detection")
+		// Report in health check
+		reportHealthValue(b.Spec, BlockedRequestLog, "-1")
+
+		return errors.New("request blocked"), http.StatusForbidden
+	}
+
+	if botThresholdReached(score, conf.TagThreshold) {
+		r.Header.Set(botScoreHeader, strconv.Itoa(score))
+	}
+
+	if botThresholdReached(score, conf.ThrottleThreshold) && conf.ThrottleDelay > 0 {
+		b.Logger().WithField("score", score).Debug("Request throttled by bot detection")
+		select {
+		case <-time.After(time.Duration(conf.ThrottleDelay) * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}
+
+	return nil, http.StatusOK
+}
+
+// botThresholdReached returns whether score reached threshold, thresholds are disabled when 0.
+func botThresholdReached(score, threshold int) bool {
+	return threshold > 0 && score >= threshold
+}
+
+// botDetectionTags adds the bot analytics tag to the tags of requests scored from the tag
+// threshold of spec.
+func botDetectionTags(spec *APISpec, score int, tags []string) []string {
+	if spec.BotDetection.Enabled && botThresholdReached(score, spec.BotDetection.TagThreshold) {
+		return append(tags, botTag)
+	}
+	return tags
+}
+
+func ctxSetBotScore(r *http.Request, score int) {
+	setCtxValue(r, ctx.BotScore, score)
+}
+
+func ctxGetBotScore(r *http.Request) int {
+	if v := r.Context().Value(ctx.BotScore); v != nil {
+		return v.(int)
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestBotDetection(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "bots"
+		spec.Proxy.ListenPath = "/"
+		spec.BotDetection = apidef.BotDetectionConfig{
+			Enabled:        true,
+			BadUserAgents:  []string{"^evil-bot"},
+			TagThreshold:   15,
+			BlockThreshold: 60,
+		}
+	}, func(spec *APISpec) {
+		spec.APIID = "bursts"
+		spec.Proxy.ListenPath = "/bursts/"
+		spec.BotDetection = apidef.BotDetectionConfig{
+			Enabled:        true,
+			BurstRequests:  2,
+			BurstPeriod:    60,
+			BlockThreshold: 40,
+		}
+	}, func(spec *APISpec) {
+		spec.APIID = "invalid"
+		spec.Proxy.ListenPath = "/invalid/"
+		spec.BotDetection = apidef.BotDetectionConfig{
+			Enabled:       true,
+			BadUserAgents: []string{"evil-bot("},
+		}
+	})
+
+	client := func(userAgent, accept string) map[string]string {
+		return map[string]string{"User-Agent": userAgent, "Accept": accept}
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: client("my-client/1.0", "*/*"), Code: http.StatusOK, BodyNotMatch: "X-Tyk-Bot-Score"},
+		{Path: "/", Headers: map[string]string{"User-Agent": "my-client/1.0", "Accept": "*/*", "X-Tyk-Bot-Score": "0"},
+			Code: http.StatusOK, BodyNotMatch: "X-Tyk-Bot-Score"},
+		{Path: "/", Headers: client("my-client/1.0", ""), Code: http.StatusOK, BodyMatch: `"X-Tyk-Bot-Score":"15"`},
+		{Path: "/", Headers: client("Mozilla/5.0", "text/html"), Code: http.StatusOK, BodyMatch: `"X-Tyk-Bot-Score":"20"`},
+		{Path: "/", Headers: client("", ""), Code: http.StatusOK, BodyMatch: `"X-Tyk-Bot-Score":"55"`},
+		{Path: "/", Headers: client("sqlmap/1.5", "*/*"), Code: http.StatusForbidden},
+		{Path: "/", Headers: client("evil-bot/2", "*/*"), Code: http.StatusForbidden},
+
+		{Path: "/bursts/", Headers: client("my-client/1.0", "*/*"), Code: http.StatusOK},
+		{Path: "/bursts/", Headers: client("my-client/1.0", "*/*"), Code: http.StatusOK},
+		{Path: "/bursts/", Headers: client("my-client/1.0", "*/*"), Code: http.StatusForbidden},
+
+		{Path: "/invalid/", Headers: client("my-client/1.0", "*/*"), Code: http.StatusNotFound},
+	}...)
+
+	spec := ts.Gw.getApiSpec("bots")
+	assert.Equal(t, []string{"bot"}, botDetectionTags(spec, 15, nil))
+	assert.Empty(t, botDetectionTags(spec, 14, nil))
+}
+
+func TestCompileBadUserAgents(t *testing.T) {
+	badUserAgents, err := compileBadUserAgents([]string{"^evil-bot"})
+	assert.NoError(t, err)
+	assert.Len(t, badUserAgents, 2)
+
+	_, err = compileBadUserAgents([]string{"^evil-bot", "evil-bot("})
+	assert.Error(t, err)
+}
@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+// CompositeSpec is the compiled composite endpoint.
+type CompositeSpec struct {
+	apidef.CompositeMeta
+	template *template.Template
+}
+
+// compositeResult is the response of a request of a composite endpoint.
+type compositeResult struct {
+	body interface{}
+	err  error
+}
+
+// CompositeEndpoint responds to the requests of composite endpoints with the JSON responses of
+// internal APIs, called in parallel and merged according to the template of the endpoint.
+type CompositeEndpoint struct {
+	BaseMiddleware
+	sh SuccessHandler
+}
+
+func (m *CompositeEndpoint) Name() string {
+	return "CompositeEndpoint"
+}
+
+func (m *CompositeEndpoint) Init() {
+	m.sh = SuccessHandler{m.BaseMiddleware}
+}
+
+func (m *CompositeEndpoint) EnabledForSpec() bool {
+	for _, version := range m.Spec.VersionData.Versions {
+		if len(version.ExtendedPaths.Composite) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *CompositeEndpoint) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	versionInfo, _ := m.Spec.Version(r)
+	versionPaths := m.Spec.RxPaths[versionInfo.Name]
+	found, meta := m.Spec.CheckSpecMatchesStatus(r, versionPaths, Composite)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	t1 := time.Now()
+	spec := meta.(*CompositeSpec)
+
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = m.Spec.GlobalConfig.ProxyDefaultTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout*float64(time.Second)))
+	}
+	defer cancel()
+
+	results := make([]compositeResult, len(spec.Requests))
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := range spec.Requests {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i].body, results[i].err = m.doCompositeRequest(ctx, r, spec.Requests[i])
+			}(i)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		m.Logger().WithField("path", r.URL.Path).Error("Composite endpoint timed out after ", timeout, "s")
+		return errors.New("Composite endpoint timed out"), http.StatusGatewayTimeout
+	}
+
+	data := make(map[string]interface{}, len(spec.Requests))
+	for i, req := range spec.Requests {
+		if err := results[i].err; err != nil {
+			if !req.Optional {
+				m.Logger().WithError(err).WithField("request", req.Name).Error("Composite request failed")
+				return fmt.Errorf("Composite request %s failed", req.Name), http.StatusBadGateway
+			}
+			m.Logger().WithError(err).WithField("request", req.Name).Warning("Optional composite request failed")
+		}
+		data[req.Name] = results[i].body
+	}
+
+	var body []byte
+	if spec.template == nil {
+		var err error
+		if body, err = json.Marshal(data); err != nil {
+			return err, http.StatusInternalServerError
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := spec.template.Execute(&buf, data); err != nil {
+			m.Logger().WithError(err).Error("Could not render composite template")
+			return errors.New("Error during composite endpoint execution. Contact Administrator for more details."), http.StatusInternalServerError
+		}
+		body = buf.Bytes()
+	}
+
+	session := ctxGetSession(r)
+	res := m.Gw.forceResponse(w, r, &VMResponseObject{
+		Response: ResponseObject{
+			Body:    string(body),
+			Headers: map[string]string{headers.ContentType: headers.ApplicationJSON},
+			Code:    http.StatusOK,
+		},
+	}, m.Spec, session, false, m.Logger())
+	if res != nil {
+		m.sh.RecordHit(r, Latency{Total: int64(DurationToMillisecond(time.Since(t1)))}, res.StatusCode, res)
+	}
+
+	return nil, mwStatusRespond
+}
+
+// doCompositeRequest calls the internal API of req with the headers of r, returning the parsed JSON
+// response, or the raw body if it isn't JSON.
+func (m *CompositeEndpoint) doCompositeRequest(ctx context.Context, r *http.Request, req apidef.CompositeRequest) (interface{}, error) {
+	target, err := url.Parse(m.Gw.replaceTykVariables(r, req.URL, true))
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != LoopScheme {
+		return nil, fmt.Errorf("not a %s:// URL: %s", LoopScheme, req.URL)
+	}
+
+	var handler http.Handler
+	var found bool
+	if target.Hostname() == "self" {
+		var h interface{}
+		if h, found = m.Gw.apisHandlesByID.Load(m.Spec.APIID); found {
+			handler = h.(http.Handler)
+		}
+	} else {
+		handler, found = m.Gw.findInternalHttpHandlerByNameOrID(target.Hostname())
+	}
+	if !found {
+		return nil, fmt.Errorf("couldn't detect target %s", target.Hostname())
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	subReq, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	subReq = subReq.WithContext(ctx)
+	subReq.RemoteAddr = r.RemoteAddr
+	subReq.Header = r.Header.Clone()
+	subReq.Header.Del(headers.ContentLength)
+	ignoreCanonical := m.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey
+	for name, value := range req.Headers {
+		setCustomHeader(subReq.Header, name, m.Gw.replaceTykVariables(r, value, false), ignoreCanonical)
+	}
+
+	// the requests are loops, bound by the loop limit and exempt from rate limits and quotas
+	ctxSetLoopLevel(subReq, ctxLoopLevel(r))
+	ctxSetLoopLimit(subReq, ctxLoopLevelLimit(r))
+	if _, err := isLoop(subReq); err != nil {
+		return nil, err
+	}
+	ctxIncLoopLevel(subReq, 0)
+	subReq.URL.Scheme = "http"
+	subReq.URL.Host = r.Host
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, subReq)
+
+	if rec.Code < http.StatusOK || rec.Code >= http.StatusBadRequest {
+		return nil, fmt.Errorf("unexpected status code %d", rec.Code)
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		return rec.Body.String(), nil
+	}
+
+	return body, nil
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestCompositeEndpoint(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer slow.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Name = "users"
+		spec.APIID = "users"
+		spec.Proxy.ListenPath = "/users-api/"
+		spec.Internal = true
+	}, func(spec *APISpec) {
+		spec.Name = "orders"
+		spec.APIID = "orders"
+		spec.Proxy.ListenPath = "/orders-api/"
+		spec.Internal = true
+		spec.UseKeylessAccess = false
+	}, func(spec *APISpec) {
+		spec.Name = "slow"
+		spec.APIID = "slow"
+		spec.Proxy.ListenPath = "/slow-api/"
+		spec.Proxy.TargetURL = slow.URL
+		spec.Internal = true
+	}, func(spec *APISpec) {
+		spec.APIID = "bff"
+		spec.Proxy.ListenPath = "/bff/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.Composite = []apidef.CompositeMeta{
+				{
+					Path:   "/merged",
+					Method: http.MethodGet,
+					Requests: []apidef.CompositeRequest{
+						{Name: "user", URL: "tyk://users/users/1", Headers: map[string]string{"X-Composite": "bff"}},
+						{Name: "orders", URL: "tyk://orders/orders?user=1"},
+					},
+				},
+				{
+					Path:   "/templated",
+					Method: http.MethodGet,
+					Requests: []apidef.CompositeRequest{
+						{Name: "user", URL: "tyk://users/users/1"},
+						{Name: "missing", URL: "tyk://unknown/", Optional: true},
+					},
+					Template: `{"user":{{jsonMarshal .user.URI}},"missing":{{jsonMarshal .missing}}}`,
+				},
+				{
+					Path:   "/failed",
+					Method: http.MethodGet,
+					Requests: []apidef.CompositeRequest{
+						{Name: "user", URL: "tyk://users/users/1"},
+						{Name: "missing", URL: "tyk://unknown/"},
+					},
+				},
+				{
+					Path:   "/slow",
+					Method: http.MethodGet,
+					Requests: []apidef.CompositeRequest{
+						{Name: "user", URL: "tyk://users/users/1"},
+						{Name: "slow", URL: "tyk://slow/"},
+					},
+					Timeout: 0.05,
+				},
+				{
+					Path:     "/disabled",
+					Method:   http.MethodGet,
+					Disabled: true,
+					Requests: []apidef.CompositeRequest{{Name: "user", URL: "tyk://users/users/1"}},
+				},
+			}
+		})
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"orders": {APIID: "orders"}}
+	})
+	authHeaders := map[string]string{"Authorization": key}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		// the internal APIs can't be reached directly
+		{Path: "/users-api/users/1", Code: http.StatusNotFound},
+		{Path: "/bff/merged", Headers: authHeaders, Code: http.StatusOK, HeadersMatch: map[string]string{"Content-Type": "application/json"}},
+		{Path: "/bff/merged", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"user":\{.*"URI":"/users/1"`},
+		{Path: "/bff/merged", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"orders":\{.*"URI":"/orders\?user=1"`},
+		{Path: "/bff/merged", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"X-Composite":"bff"`},
+		// the headers of the request are sent to the internal APIs
+		{Path: "/bff/merged", Code: http.StatusBadGateway, BodyMatch: "Composite request orders failed"},
+		{Path: "/bff/templated", Code: http.StatusOK, BodyMatch: `^\{"user":"/users/1","missing":null\}$`},
+		{Path: "/bff/failed", Code: http.StatusBadGateway, BodyMatch: "Composite request missing failed"},
+		{Path: "/bff/slow", Code: http.StatusGatewayTimeout, BodyMatch: "Composite endpoint timed out"},
+		{Path: "/bff/disabled", Code: http.StatusOK, BodyMatch: `"URI":"/bff/disabled"`},
+	}...)
+}
@@ -33,7 +33,25 @@ func (m *MiddlewareContextVars) ProcessRequest(w http.ResponseWriter, r *http.Re
 		"path_parts":   strings.Split(r.URL.Path, "/"), // Path parts
 		"path":         r.URL.Path,                     // path data
 		"remote_addr":  request.RealIP(r),              // IP
-		"request_id":   uuid.NewV4().String(),          //Correlation ID
+	}
+
+	// Correlation ID
+	if id := ctxGetRequestID(r); id != "" {
+		contextDataObject["request_id"] = id
+	} else {
+		contextDataObject["request_id"] = uuid.NewV4().String()
+	}
+
+	if country := ctxGetGeoIPCountry(r); country != "" {
+		contextDataObject["geo_country"] = country
+	}
+
+	if hop := ctxGetFederationHop(r); hop != nil {
+		contextDataObject["federation_hops"] = strings.Join(hop.Hops, ",")
+		contextDataObject["federation_origin"] = hop.Hops[0]
+		contextDataObject["federation_org_id"] = hop.Identity.OrgID
+		contextDataObject["federation_key_hash"] = hop.Identity.KeyHash
+		contextDataObject["federation_alias"] = hop.Identity.Alias
 	}
 
 	for hname, vals := range r.Header {
@@ -46,6 +64,8 @@ func (m *MiddlewareContextVars) ProcessRequest(w http.ResponseWriter, r *http.Re
 		contextDataObject[name] = c.Value
 	}
 
+	ContextVars.apply(r, contextDataObject)
+
 	ctxSetData(r, contextDataObject)
 
 	return nil, http.StatusOK
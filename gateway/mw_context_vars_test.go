@@ -1,15 +1,23 @@
 package gateway
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/certs"
 	"github.com/TykTechnologies/tyk/test"
 )
 
@@ -241,3 +249,50 @@ func BenchmarkContextVarsMiddlewareProcessRequest(b *testing.B) {
 		})
 	}
 }
+
+func TestContextVarsRegistry(t *testing.T) {
+	registry := NewContextVarRegistry()
+
+	assert.Error(t, registry.Register("path", func(r *http.Request) (interface{}, bool) { return nil, false }))
+	assert.Error(t, registry.Register("jwt_claims_sub", func(r *http.Request) (interface{}, bool) { return nil, false }))
+	assert.Error(t, registry.Register(ctxVarTLSClientCertCN, func(r *http.Request) (interface{}, bool) { return nil, false }))
+	assert.NoError(t, registry.Register("method", func(r *http.Request) (interface{}, bool) { return r.Method, true }))
+	assert.Contains(t, registry.Names(), "method")
+
+	leaf := &x509.Certificate{
+		Raw:          []byte("raw"),
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "client"},
+		Issuer:       pkix.Name{CommonName: "ca"},
+		NotAfter:     time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+
+	data := map[string]interface{}{}
+	registry.apply(req, data)
+	assert.Equal(t, map[string]interface{}{
+		"method":                       http.MethodPost,
+		ctxVarTLSClientCertCN:          "client",
+		ctxVarTLSClientCertFingerprint: certs.HexSHA256([]byte("raw")),
+		ctxVarTLSClientCertSerial:      "42",
+		ctxVarTLSClientCertIssuerCN:    "ca",
+		ctxVarTLSClientCertNotAfter:    "2030-01-02T03:04:05Z",
+	}, data)
+
+	registry.Unregister("method")
+	data = map[string]interface{}{}
+	registry.apply(httptest.NewRequest(http.MethodGet, "/", nil), data)
+	assert.Empty(t, data)
+}
+
+func TestFlattenJWTClaim(t *testing.T) {
+	cnt := map[string]interface{}{}
+	flattenJWTClaim(cnt, "jwt_claims_address", map[string]interface{}{
+		"country": "FR",
+		"geo":     map[string]interface{}{"lat": 1.5},
+	})
+	assert.Equal(t, "FR", cnt["jwt_claims_address_country"])
+	assert.Equal(t, 1.5, cnt["jwt_claims_address_geo_lat"])
+	assert.Contains(t, cnt, "jwt_claims_address_geo")
+}
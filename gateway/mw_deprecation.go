@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+const deprecatedTag = "deprecated"
+
+// DeprecatedSpec is the compiled deprecation of an endpoint.
+type DeprecatedSpec struct {
+	apidef.DeprecatedMeta
+	date   time.Time
+	sunset time.Time
+}
+
+// DeprecationMiddleware adds the deprecation headers to the responses of deprecated endpoints and
+// blocks their requests after the sunset date.
+type DeprecationMiddleware struct {
+	BaseMiddleware
+}
+
+func (m *DeprecationMiddleware) Name() string {
+	return "DeprecationMiddleware"
+}
+
+func (m *DeprecationMiddleware) EnabledForSpec() bool {
+	for _, paths := range m.Spec.RxPaths {
+		for _, path := range paths {
+			if path.Status == Deprecated {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *DeprecationMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	versionInfo, _ := m.Spec.Version(r)
+	versionPaths := m.Spec.RxPaths[versionInfo.Name]
+	found, meta := m.Spec.CheckSpecMatchesStatus(r, versionPaths, Deprecated)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	spec := meta.(*DeprecatedSpec)
+	ctxSetDeprecated(r)
+
+	h := w.Header()
+	warning := "Deprecated endpoint"
+	if spec.date.IsZero() {
+		// without date as in the drafts of RFC 9745
+		h.Set(headers.Deprecation, "true")
+	} else {
+		h.Set(headers.Deprecation, fmt.Sprintf("@%d", spec.date.Unix()))
+	}
+	if !spec.sunset.IsZero() {
+		sunset := spec.sunset.UTC().Format(http.TimeFormat)
+		h.Set(headers.Sunset, sunset)
+		warning += ", removed after " + sunset
+	}
+	if spec.Link != "" {
+		h.Add(headers.Link, fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, spec.Link))
+	}
+	h.Set(headers.Warning, fmt.Sprintf(`299 - %q`, warning))
+
+	if !spec.sunset.IsZero() && !time.Now().Before(spec.sunset) {
+		m.Logger().WithField("path", r.URL.Path).Info("Attempted access to an endpoint past its sunset date, blocked.")
+		return errors.New("This endpoint has been removed"), http.StatusGone
+	}
+
+	return nil, http.StatusOK
+}
+
+func ctxSetDeprecated(r *http.Request) {
+	setCtxValue(r, ctx.Deprecated, true)
+}
+
+func ctxGetDeprecated(r *http.Request) bool {
+	v, _ := r.Context().Value(ctx.Deprecated).(bool)
+	return v
+}
+
+// deprecationTags adds the analytics tag of deprecated endpoints to the tags of r.
+func deprecationTags(r *http.Request, tags []string) []string {
+	if ctxGetDeprecated(r) {
+		return append(tags, deprecatedTag)
+	}
+	return tags
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestDeprecation(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	past := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	future := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.Deprecated = []apidef.DeprecatedMeta{
+				{Path: "/deprecated", Method: http.MethodGet, Date: past.Format(time.RFC3339), Link: "https://example.com/migration"},
+				{Path: "/sunset", Method: http.MethodGet, Sunset: future.Format(time.RFC3339)},
+				{Path: "/removed", Method: http.MethodGet, Sunset: past.Format(time.RFC3339)},
+				{Path: "/disabled", Method: http.MethodGet, Disabled: true},
+			}
+		})
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/deprecated", Code: http.StatusOK, HeadersMatch: map[string]string{
+			headers.Deprecation: "@" + strconv.FormatInt(past.Unix(), 10),
+			headers.Link:        `<https://example.com/migration>; rel="deprecation"; type="text/html"`,
+			headers.Warning:     `299 - "Deprecated endpoint"`,
+		}},
+		{Path: "/deprecated", Method: http.MethodPost, Code: http.StatusOK, HeadersNotMatch: map[string]string{headers.Warning: `299 - "Deprecated endpoint"`}},
+		{Path: "/sunset", Code: http.StatusOK, HeadersMatch: map[string]string{
+			headers.Deprecation: "true",
+			headers.Sunset:      future.Format(http.TimeFormat),
+		}},
+		{Path: "/removed", Code: http.StatusGone, BodyMatch: "This endpoint has been removed",
+			HeadersMatch: map[string]string{headers.Sunset: past.Format(http.TimeFormat)}},
+		{Path: "/disabled", Code: http.StatusOK, HeadersNotMatch: map[string]string{headers.Deprecation: "true"}},
+	}...)
+}
+
+func TestDeprecation_OAS(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "oas-deprecation"
+		spec.Proxy.ListenPath = "/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.Deprecated = []apidef.DeprecatedMeta{
+				{Path: "/users/{id}", Method: http.MethodDelete, Sunset: "2000-01-01T00:00:00Z"},
+			}
+		})
+	})[0]
+	doc := openapi3.Swagger{Paths: openapi3.Paths{
+		"/users/{id}": &openapi3.PathItem{
+			Get:    &openapi3.Operation{Deprecated: true},
+			Put:    &openapi3.Operation{},
+			Delete: &openapi3.Operation{Deprecated: true},
+		},
+	}}
+
+	// the OAS document is loaded from the file next to the definition
+	appPath, err := ioutil.TempDir("", "apps")
+	require.NoError(t, err)
+	defer os.RemoveAll(appPath)
+	for name, value := range map[string]interface{}{"oas-deprecation.json": spec.APIDefinition, "oas-deprecation-oas.json": doc} {
+		raw, err := json.Marshal(value)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(appPath, name), raw, 0644))
+	}
+	conf := ts.Gw.GetConfig()
+	conf.AppPath = appPath
+	ts.Gw.SetConfig(conf)
+	ts.Gw.DoReload()
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/users/1", Code: http.StatusOK, HeadersMatch: map[string]string{headers.Deprecation: "true"}},
+		{Path: "/users/1", Method: http.MethodPut, Code: http.StatusOK, HeadersNotMatch: map[string]string{headers.Deprecation: "true"}},
+		// the sunset date of the extended paths takes precedence
+		{Path: "/users/1", Method: http.MethodDelete, Code: http.StatusGone},
+	}...)
+}
+
+func TestDeprecationTags(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, []string{"key"}, deprecationTags(r, []string{"key"}))
+
+	ctxSetDeprecated(r)
+	assert.Equal(t, []string{"key", "deprecated"}, deprecationTags(r, []string{"key"}))
+}
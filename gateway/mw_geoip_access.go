@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	maxminddb "github.com/oschwald/maxminddb-golang"
+
+	"github.com/TykTechnologies/tyk/request"
+)
+
+// geoIPReader looks up IPs in a MaxMind database.
+type geoIPReader interface {
+	Lookup(ip net.IP, result interface{}) error
+}
+
+// geoIPDatabase returns the database resolving the country of clients, shared with analytics
+// when GeoIP analytics are enabled. It returns nil when no database could be opened.
+func (gw *Gateway) geoIPDatabase() geoIPReader {
+	gw.geoIPOnce.Do(func() {
+		if gw.geoIPDB != nil {
+			return
+		}
+		if gw.analytics.GeoIPDB != nil {
+			gw.geoIPDB = gw.analytics.GeoIPDB
+			return
+		}
+
+		path := gw.GetConfig().AnalyticsConfig.GeoIPDBLocation
+		if path == "" {
+			log.Error("GeoIP access control is enabled but no GeoIP database is configured")
+			return
+		}
+		db, err := maxminddb.Open(path)
+		if err != nil {
+			log.WithError(err).Error("Failed to init GeoIP Database")
+			return
+		}
+		gw.geoIPDB = db
+	})
+	return gw.geoIPDB
+}
+
+// geoIPCountry returns the ISO code of the country of ip, empty when unknown.
+func (gw *Gateway) geoIPCountry(ip net.IP) string {
+	db := gw.geoIPDatabase()
+	if db == nil || ip == nil {
+		return ""
+	}
+
+	var record GeoData
+	if err := db.Lookup(ip, &record); err != nil {
+		log.WithError(err).Debug("GeoIP lookup failed")
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
+// GeoIPAccessMiddleware allows or denies requests by the country of the client IP
+type GeoIPAccessMiddleware struct {
+	BaseMiddleware
+}
+
+func (g *GeoIPAccessMiddleware) Name() string {
+	return "GeoIPAccessMiddleware"
+}
+
+func (g *GeoIPAccessMiddleware) EnabledForSpec() bool {
+	return g.Spec.GeoIPAccess.Enabled
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (g *GeoIPAccessMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	remoteIP := request.RealIP(r)
+	country := g.Gw.geoIPCountry(net.ParseIP(remoteIP))
+	if country != "" {
+		// used by analytics and context variables
+		ctxSetGeoIPCountry(r, country)
+	}
+
+	conf := g.Spec.GeoIPAccess
+	if containsCountry(conf.BlockedCountries, country) ||
+		(len(conf.AllowedCountries) > 0 && !containsCountry(conf.AllowedCountries, country)) {
+		g.Logger().WithField("country", country).Debug("Access denied by country")
+
+		// Fire Authfailed Event
+		AuthFailed(g, r, remoteIP)
+		// Report in health check
+		reportHealthValue(g.Spec, KeyFailure, "-1")
+
+		return errors.New("access from this country has been disallowed"), http.StatusForbidden
+	}
+
+	return nil, http.StatusOK
+}
+
+func containsCountry(countries []string, country string) bool {
+	if country == "" {
+		return false
+	}
+	for _, c := range countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
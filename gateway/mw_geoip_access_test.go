@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+// testGeoIPReader resolves the countries of IPs from a map.
+type testGeoIPReader map[string]string
+
+func (t testGeoIPReader) Lookup(ip net.IP, result interface{}) error {
+	result.(*GeoData).Country.ISOCode = t[ip.String()]
+	return nil
+}
+
+func TestGeoIPAccess(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.geoIPDB = testGeoIPReader{
+		"81.2.69.1":    "GB",
+		"2.3.0.1":      "FR",
+		"175.16.199.1": "CN",
+	}
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/allow/"
+		spec.EnableContextVars = true
+		spec.GeoIPAccess = apidef.GeoIPAccessConfig{Enabled: true, AllowedCountries: []string{"gb", "FR"}}
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.GlobalHeaders = map[string]string{"X-Country": "$tyk_context.geo_country"}
+		})
+	}, func(spec *APISpec) {
+		spec.APIID = "block"
+		spec.Proxy.ListenPath = "/block/"
+		spec.GeoIPAccess = apidef.GeoIPAccessConfig{Enabled: true, BlockedCountries: []string{"CN"}}
+	})
+
+	fromIP := func(ip string) map[string]string {
+		return map[string]string{"X-Real-IP": ip}
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/allow/", Headers: fromIP("81.2.69.1"), Code: http.StatusOK, BodyMatch: `"X-Country":"GB"`},
+		{Path: "/allow/", Headers: fromIP("2.3.0.1"), Code: http.StatusOK},
+		{Path: "/allow/", Headers: fromIP("175.16.199.1"), Code: http.StatusForbidden},
+		{Path: "/allow/", Headers: fromIP("10.0.0.1"), Code: http.StatusForbidden, BodyMatch: "access from this country has been disallowed"},
+		{Path: "/block/", Headers: fromIP("175.16.199.1"), Code: http.StatusForbidden},
+		{Path: "/block/", Headers: fromIP("81.2.69.1"), Code: http.StatusOK},
+		{Path: "/block/", Headers: fromIP("10.0.0.1"), Code: http.StatusOK},
+	}...)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/TykTechnologies/tyk/apidef"
@@ -109,6 +110,10 @@ func (m *GoPluginMiddleware) EnabledForSpec() bool {
 	return false
 }
 
+// goPluginLoadMu serialises the loading of Go plugins, as the APIs using them are loaded
+// concurrently.
+var goPluginLoadMu sync.Mutex
+
 func (m *GoPluginMiddleware) loadPlugin() bool {
 	m.logger = log.WithFields(logrus.Fields{
 		"mwPath":       m.Path,
@@ -122,7 +127,10 @@ func (m *GoPluginMiddleware) loadPlugin() bool {
 
 	// try to load plugin
 	var err error
-	if m.handler, err = goplugin.GetHandler(m.Path, m.SymbolName); err != nil {
+	goPluginLoadMu.Lock()
+	m.handler, err = goplugin.GetHandler(m.Path, m.SymbolName)
+	goPluginLoadMu.Unlock()
+	if err != nil {
 		m.logger.WithError(err).Error("Could not load Go-plugin")
 		return false
 	}
@@ -3,11 +3,16 @@ package gateway
 import (
 	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/tyk/regexp"
+	"github.com/TykTechnologies/tyk/user"
 )
 
-// GranularAccessMiddleware will check if a URL is specifically enabled for the key
+// GranularAccessMiddleware will check if a URL is specifically enabled for the key. Denied URLs
+// take precedence over allowed URLs, and rules restricted to other versions of the API are ignored.
 type GranularAccessMiddleware struct {
 	BaseMiddleware
 }
@@ -30,31 +35,103 @@ func (m *GranularAccessMiddleware) ProcessRequest(w http.ResponseWriter, r *http
 		return nil, http.StatusOK
 	}
 
-	if len(sessionVersionData.AllowedURLs) == 0 {
+	if len(sessionVersionData.AllowedURLs) == 0 && len(sessionVersionData.DeniedURLs) == 0 {
 		return nil, http.StatusOK
 	}
 
+	versionInfo, _ := m.Spec.Version(r)
+
+	for _, accessSpec := range sessionVersionData.DeniedURLs {
+		// a deny rule which can't be checked denies access
+		if matches, err := accessSpecMatches(logger, accessSpec, versionInfo.Name, r); matches || err != nil {
+			logger.Info("Attempted access to denied endpoint (Granular).")
+			return errors.New("Access to this resource has been disallowed"), http.StatusForbidden
+		}
+	}
+
+	restricted := false
 	for _, accessSpec := range sessionVersionData.AllowedURLs {
-		logger.Debug("Checking: ", r.URL.Path, " Against:", accessSpec.URL)
-		asRegex, err := regexp.Compile(accessSpec.URL)
-		if err != nil {
-			logger.WithError(err).Error("Regex error")
-			return nil, http.StatusOK
+		if !accessSpecAppliesToVersion(accessSpec, versionInfo.Name) {
+			continue
 		}
+		restricted = true
 
-		match := asRegex.MatchString(r.URL.Path)
-		if match {
-			logger.Debug("Match!")
-			for _, method := range accessSpec.Methods {
-				if method == r.Method {
-					return nil, http.StatusOK
-				}
-			}
+		if matches, _ := accessSpecMatches(logger, accessSpec, versionInfo.Name, r); matches {
+			return nil, http.StatusOK
 		}
 	}
 
+	if !restricted {
+		return nil, http.StatusOK
+	}
+
 	logger.Info("Attempted access to unauthorised endpoint (Granular).")
 
 	return errors.New("Access to this resource has been disallowed"), http.StatusForbidden
+}
 
+// accessSpecMatches reports whether the path and method of r match accessSpec for the version. It
+// fails when the URL of accessSpec isn't a valid regular expression.
+func accessSpecMatches(logger *logrus.Entry, accessSpec user.AccessSpec, version string, r *http.Request) (bool, error) {
+	if !accessSpecAppliesToVersion(accessSpec, version) {
+		return false, nil
+	}
+
+	logger.Debug("Checking: ", r.URL.Path, " Against:", accessSpec.URL)
+	asRegex, err := regexp.Compile(accessSpec.URL)
+	if err != nil {
+		logger.WithError(err).Error("Regex error")
+		return false, err
+	}
+
+	if !asRegex.MatchString(r.URL.Path) {
+		return false, nil
+	}
+
+	logger.Debug("Match!")
+	for _, method := range accessSpec.Methods {
+		if method == "*" || strings.EqualFold(method, r.Method) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func accessSpecAppliesToVersion(accessSpec user.AccessSpec, version string) bool {
+	return len(accessSpec.Versions) == 0 || contains(accessSpec.Versions, version)
+}
+
+// mergeAccessSpecs merges the rules of policies, adding the methods of the rules of the same URL and
+// versions.
+func mergeAccessSpecs(specs, newSpecs []user.AccessSpec) []user.AccessSpec {
+	for _, newSpec := range newSpecs {
+		found := false
+		for i, spec := range specs {
+			if spec.URL == newSpec.URL && sameStringSet(spec.Versions, newSpec.Versions) {
+				found = true
+				specs[i].Methods = append(spec.Methods, newSpec.Methods...)
+			}
+		}
+
+		if !found {
+			specs = append(specs, newSpec)
+		}
+	}
+
+	return specs
+}
+
+func sameStringSet(a, b []string) bool {
+	for _, item := range a {
+		if !contains(b, item) {
+			return false
+		}
+	}
+	for _, item := range b {
+		if !contains(a, item) {
+			return false
+		}
+	}
+	return true
 }
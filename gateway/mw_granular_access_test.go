@@ -1,9 +1,15 @@
 package gateway
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/headers"
 	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/user"
@@ -131,3 +137,97 @@ func TestGranularAccessMiddleware_ProcessRequest(t *testing.T) {
 		})
 	})
 }
+
+func TestGranularAccessMiddleware_DeniedURLsAndVersions(t *testing.T) {
+	g := StartTest(nil)
+	defer g.Close()
+
+	api := g.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+		spec.VersionData.NotVersioned = false
+		spec.VersionDefinition.Location = headerLocation
+		spec.VersionDefinition.Key = "X-API-Version"
+		spec.VersionData.Versions["v1"] = apidef.VersionInfo{Name: "v1"}
+		spec.VersionData.Versions["v2"] = apidef.VersionInfo{Name: "v2"}
+	})[0]
+
+	pID := g.CreatePolicy(func(p *user.Policy) {
+		p.AccessRights = map[string]user.AccessDefinition{
+			api.APIID: {
+				APIID:    api.APIID,
+				Versions: []string{"v1", "v2"},
+				AllowedURLs: []user.AccessSpec{
+					{URL: "^/users", Methods: []string{"*"}},
+					{URL: "^/orders", Methods: []string{"get"}, Versions: []string{"v2"}},
+				},
+				DeniedURLs: []user.AccessSpec{
+					{URL: "^/users/admin", Methods: []string{"*"}},
+					{URL: "^/users", Methods: []string{http.MethodDelete}, Versions: []string{"v1"}},
+				},
+			},
+		}
+	})
+
+	_, key := g.CreateSession(func(s *user.SessionState) {
+		s.ApplyPolicies = []string{pID}
+	})
+
+	version := func(v string) map[string]string {
+		return map[string]string{headers.Authorization: key, "X-API-Version": v}
+	}
+
+	_, _ = g.Run(t, []test.TestCase{
+		{Path: "/users/1", Method: http.MethodPut, Headers: version("v1"), Code: http.StatusOK},
+		{Path: "/users/admin", Method: http.MethodGet, Headers: version("v1"), Code: http.StatusForbidden},
+		{Path: "/users/1", Method: http.MethodDelete, Headers: version("v1"), Code: http.StatusForbidden},
+		{Path: "/users/1", Method: http.MethodDelete, Headers: version("v2"), Code: http.StatusOK},
+		{Path: "/orders", Method: http.MethodGet, Headers: version("v1"), Code: http.StatusForbidden},
+		{Path: "/orders", Method: http.MethodGet, Headers: version("v2"), Code: http.StatusOK},
+		{Path: "/orders", Method: http.MethodPost, Headers: version("v2"), Code: http.StatusForbidden},
+	}...)
+
+	t.Run("invalid URLs rejected", func(t *testing.T) {
+		session := CreateStandardSession()
+		session.AccessRights = map[string]user.AccessDefinition{api.APIID: {
+			APIID:      api.APIID,
+			Versions:   []string{"v1"},
+			DeniedURLs: []user.AccessSpec{{URL: "^/users/admin(", Methods: []string{"*"}}},
+		}}
+		body, _ := json.Marshal(session)
+
+		_, _ = g.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/keys/create", Data: string(body),
+			Code: http.StatusBadRequest, BodyMatch: "Invalid key access rights"})
+	})
+}
+
+func TestAccessSpecMatches(t *testing.T) {
+	logger := logrus.NewEntry(log)
+	r := httptest.NewRequest(http.MethodGet, "/users/admin", nil)
+
+	matches, err := accessSpecMatches(logger, user.AccessSpec{URL: "^/users", Methods: []string{"*"}}, "v1", r)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+
+	matches, err = accessSpecMatches(logger, user.AccessSpec{URL: "^/users(", Methods: []string{"*"}}, "v1", r)
+	assert.Error(t, err, "rules which can't be checked should be reported, denying access")
+	assert.False(t, matches)
+}
+
+func TestMergeAccessSpecs(t *testing.T) {
+	merged := mergeAccessSpecs([]user.AccessSpec{
+		{URL: "/users", Methods: []string{"GET"}},
+		{URL: "/orders", Methods: []string{"GET"}, Versions: []string{"v1"}},
+	}, []user.AccessSpec{
+		{URL: "/users", Methods: []string{"POST"}},
+		{URL: "/orders", Methods: []string{"POST"}, Versions: []string{"v2"}},
+		{URL: "/companies", Methods: []string{"GET"}},
+	})
+
+	assert.Equal(t, []user.AccessSpec{
+		{URL: "/users", Methods: []string{"GET", "POST"}},
+		{URL: "/orders", Methods: []string{"GET"}, Versions: []string{"v1"}},
+		{URL: "/orders", Methods: []string{"POST"}, Versions: []string{"v2"}},
+		{URL: "/companies", Methods: []string{"GET"}},
+	}, merged)
+}
@@ -1,11 +1,16 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/buger/jsonparser"
 	"github.com/gorilla/websocket"
 	"github.com/jensneuse/abstractlogger"
 	"github.com/sirupsen/logrus"
@@ -229,6 +234,14 @@ func (m *GraphQLMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Reques
 
 	defer ctxSetGraphQLRequest(r, &gqlRequest)
 
+	// the stats are shared with the proxy, which counts the errors of the response
+	stats := &GraphQLStats{IsGraphQL: true, OperationName: gqlRequest.OperationName}
+	if m.Spec.GlobalConfig.AnalyticsConfig.EnableGraphQLQueryHash {
+		sum := sha256.Sum256([]byte(gqlRequest.Query))
+		stats.QueryHash = hex.EncodeToString(sum[:])
+	}
+	ctxSetGraphQLStats(r, stats)
+
 	normalizationResult, err := gqlRequest.Normalize(m.Spec.GraphQLExecutor.Schema)
 	if err != nil {
 		m.Logger().Errorf("Error while normalizing GraphQL request: '%s'", err)
@@ -236,9 +249,14 @@ func (m *GraphQLMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Reques
 	}
 
 	if normalizationResult.Errors != nil && normalizationResult.Errors.Count() > 0 {
+		stats.Errors = normalizationResult.Errors.Count()
 		return m.writeGraphQLError(w, normalizationResult.Errors)
 	}
 
+	if opType, err := gqlRequest.OperationType(); err == nil {
+		stats.OperationType = graphQLOperationTypeName(opType)
+	}
+
 	validationResult, err := gqlRequest.ValidateForSchema(m.Spec.GraphQLExecutor.Schema)
 	if err != nil {
 		m.Logger().Errorf("Error while validating GraphQL request: '%s'", err)
@@ -246,12 +264,46 @@ func (m *GraphQLMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Reques
 	}
 
 	if validationResult.Errors != nil && validationResult.Errors.Count() > 0 {
+		stats.Errors = validationResult.Errors.Count()
 		return m.writeGraphQLError(w, validationResult.Errors)
 	}
 
 	return nil, http.StatusOK
 }
 
+func graphQLOperationTypeName(opType gql.OperationType) string {
+	switch opType {
+	case gql.OperationTypeQuery:
+		return "query"
+	case gql.OperationTypeMutation:
+		return "mutation"
+	case gql.OperationTypeSubscription:
+		return "subscription"
+	}
+	return ""
+}
+
+// countGraphQLErrors sets the number of errors in the GraphQL response res on the
+// analytics stats of the request. The body is buffered so that it can still be sent.
+func countGraphQLErrors(stats *GraphQLStats, res *http.Response) {
+	if stats == nil || res == nil || res.Body == nil || res.Header.Get(headers.ContentEncoding) != "" {
+		return
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	errorsCount := 0
+	_, _ = jsonparser.ArrayEach(body, func([]byte, jsonparser.ValueType, int, error) {
+		errorsCount++
+	}, "errors")
+	stats.Errors = errorsCount
+}
+
 func (m *GraphQLMiddleware) writeGraphQLError(w http.ResponseWriter, errors gql.Errors) (error, int) {
 	w.Header().Set(headers.ContentType, headers.ApplicationJSON)
 	w.WriteHeader(http.StatusBadRequest)
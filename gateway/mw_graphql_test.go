@@ -1,16 +1,22 @@
 package gateway
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/headers"
 	"github.com/TykTechnologies/tyk/user"
 
@@ -1070,3 +1076,63 @@ type Review {
 	author: User!
 	product: Product!
 }`
+
+func TestGraphQLMiddleware_Analytics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.ContentType, headers.ApplicationJSON)
+		_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"first"},{"message":"second"}]}`))
+	}))
+	defer upstream.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AnalyticsConfig.EnableGraphQLQueryHash = true
+	}, TestConfig{Delay: 20 * time.Millisecond})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.GraphQL.Enabled = true
+		spec.GraphQL.ExecutionMode = apidef.GraphQLExecutionModeProxyOnly
+		spec.GraphQL.Schema = "schema { query: Query } type Query { hello: String }"
+	})
+
+	// let records to to be sent
+	time.Sleep(recordsBufferFlushInterval + 50)
+	ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+
+	records := func() []AnalyticsRecord {
+		time.Sleep(recordsBufferFlushInterval + 50)
+		results := ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+		records := make([]AnalyticsRecord, len(results))
+		for i, result := range results {
+			require.NoError(t, msgpack.Unmarshal([]byte(result.(string)), &records[i]))
+		}
+		return records
+	}
+
+	t.Run("successful request", func(t *testing.T) {
+		query := "query Hello { hello }"
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Data: gql.Request{OperationName: "Hello", Query: query}, BodyMatch: `"second"`, Code: http.StatusOK})
+
+		recs := records()
+		require.Len(t, recs, 1)
+		sum := sha256.Sum256([]byte(query))
+		assert.Equal(t, GraphQLStats{
+			IsGraphQL:     true,
+			OperationName: "Hello",
+			OperationType: "query",
+			Errors:        2,
+			QueryHash:     hex.EncodeToString(sum[:]),
+		}, recs[0].GraphQLStats)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Data: gql.Request{Query: "query { goodbye }"}, Code: http.StatusBadRequest})
+
+		recs := records()
+		require.Len(t, recs, 1)
+		assert.True(t, recs[0].GraphQLStats.IsGraphQL)
+		assert.Equal(t, 1, recs[0].GraphQLStats.Errors)
+	})
+}
@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+const (
+	// grpcDiscoveryInterval is the minimum time between two attempts to discover the methods of
+	// the upstream with its reflection service.
+	grpcDiscoveryInterval = 10 * time.Second
+	grpcDiscoveryTimeout  = 5 * time.Second
+)
+
+// grpcReflectionServices are the versions of the server reflection service.
+var grpcReflectionServices = []string{"grpc.reflection.v1alpha.ServerReflection", "grpc.reflection.v1.ServerReflection"}
+
+// GRPCAccessMiddleware controls the access to the server reflection service and to the methods
+// of the gRPC services of the upstream.
+type GRPCAccessMiddleware struct {
+	BaseMiddleware
+
+	methodsMu     sync.Mutex
+	methods       map[string]bool
+	lastDiscovery time.Time
+}
+
+func (m *GRPCAccessMiddleware) Name() string {
+	return "GRPCAccessMiddleware"
+}
+
+func (m *GRPCAccessMiddleware) EnabledForSpec() bool {
+	return m.Spec.GRPC.Enabled
+}
+
+func (m *GRPCAccessMiddleware) Init() {
+	if m.Spec.GRPC.Descriptors == "" {
+		return
+	}
+
+	methods, err := grpcMethodsFromDescriptors(m.Spec.GRPC.Descriptors)
+	if err != nil {
+		m.Logger().WithError(err).Error("Could not load gRPC descriptors")
+		return
+	}
+	m.setMethods(methods)
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *GRPCAccessMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	if !strings.HasPrefix(r.Header.Get(headers.ContentType), "application/grpc") {
+		return nil, http.StatusOK
+	}
+	conf := m.Spec.GRPC
+	method := strings.TrimPrefix(r.URL.Path, "/")
+
+	if isGRPCReflection(method) {
+		if !conf.Reflection.Enabled || !m.sessionHasTag(r, conf.Reflection.AllowedTags) {
+			m.Logger().Info("Attempted access to gRPC server reflection, blocked.")
+			return errors.New("gRPC server reflection is not allowed"), http.StatusForbidden
+		}
+		return nil, http.StatusOK
+	}
+
+	if conf.KnownMethodsOnly {
+		methods := m.knownMethods()
+		if methods == nil {
+			return errors.New("gRPC methods of the upstream are unknown"), http.StatusServiceUnavailable
+		}
+		if !methods[method] {
+			return errors.New("Unknown gRPC method"), http.StatusNotFound
+		}
+	}
+
+	for _, acl := range conf.MethodACLs {
+		if !grpcMethodMatches(acl.Method, method) {
+			continue
+		}
+		if !m.sessionHasTag(r, acl.AllowedTags) {
+			m.Logger().WithField("method", method).Info("Attempted access to unauthorised gRPC method.")
+			return errors.New("Access to this gRPC method has been disallowed"), http.StatusForbidden
+		}
+		break
+	}
+
+	return nil, http.StatusOK
+}
+
+// sessionHasTag reports whether the session of r has one of tags, always true without tags.
+func (m *GRPCAccessMiddleware) sessionHasTag(r *http.Request, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	session := ctxGetSession(r)
+	if session == nil {
+		return false
+	}
+	for _, tag := range tags {
+		for _, sessionTag := range session.Tags {
+			if tag == sessionTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// knownMethods returns the methods of the descriptors, or discovers them with the reflection
+// service of the upstream. It returns nil until they are known.
+func (m *GRPCAccessMiddleware) knownMethods() map[string]bool {
+	m.methodsMu.Lock()
+	defer m.methodsMu.Unlock()
+
+	if m.methods != nil || m.Spec.GRPC.Descriptors != "" || time.Since(m.lastDiscovery) < grpcDiscoveryInterval {
+		return m.methods
+	}
+	m.lastDiscovery = time.Now()
+
+	target, err := url.Parse(m.Spec.Proxy.TargetURL)
+	if err != nil {
+		m.Logger().WithError(err).Error("Invalid gRPC upstream URL")
+		return nil
+	}
+	insecureSkipVerify := m.Gw.GetConfig().ProxySSLInsecureSkipVerify || m.Spec.Proxy.Transport.SSLInsecureSkipVerify
+
+	ctx, cancel := context.WithTimeout(context.Background(), grpcDiscoveryTimeout)
+	defer cancel()
+	methods, err := discoverGRPCMethods(ctx, target, insecureSkipVerify)
+	if err != nil {
+		m.Logger().WithError(err).Error("Could not discover gRPC methods of the upstream")
+		return nil
+	}
+
+	m.logUnmatchedACLs(methods)
+	m.methods = methods
+	return m.methods
+}
+
+func (m *GRPCAccessMiddleware) setMethods(methods map[string]bool) {
+	m.methodsMu.Lock()
+	defer m.methodsMu.Unlock()
+
+	m.logUnmatchedACLs(methods)
+	m.methods = methods
+}
+
+// logUnmatchedACLs warns about the ACLs matching none of methods, likely misspelled.
+func (m *GRPCAccessMiddleware) logUnmatchedACLs(methods map[string]bool) {
+	for _, acl := range m.Spec.GRPC.MethodACLs {
+		matched := false
+		for method := range methods {
+			if grpcMethodMatches(acl.Method, method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			m.Logger().WithField("method", acl.Method).Warning("gRPC method ACL doesn't match any method of the services")
+		}
+	}
+}
+
+// grpcMethodMatches reports whether method, `package.Service/Method`, matches pattern, which may
+// be `package.Service/*`.
+func grpcMethodMatches(pattern, method string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(method, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == method
+}
+
+func isGRPCReflection(method string) bool {
+	for _, service := range grpcReflectionServices {
+		if strings.HasPrefix(method, service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcMethodsFromDescriptors returns the methods of the services of a base64 encoded
+// FileDescriptorSet.
+func grpcMethodsFromDescriptors(encoded string) (map[string]bool, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptor.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	methods := make(map[string]bool)
+	for _, file := range set.File {
+		addGRPCMethods(methods, file, nil)
+	}
+	return methods, nil
+}
+
+// addGRPCMethods adds the methods of the services of file to methods, of the given services only
+// if not nil.
+func addGRPCMethods(methods map[string]bool, file *descriptor.FileDescriptorProto, services map[string]bool) {
+	for _, service := range file.Service {
+		name := service.GetName()
+		if file.GetPackage() != "" {
+			name = file.GetPackage() + "." + name
+		}
+		if services != nil && !services[name] {
+			continue
+		}
+		for _, method := range service.Method {
+			methods[name+"/"+method.GetName()] = true
+		}
+	}
+}
+
+// discoverGRPCMethods lists the methods of the services of the upstream with its reflection
+// service.
+func discoverGRPCMethods(ctx context.Context, target *url.URL, insecureSkipVerify bool) (map[string]bool, error) {
+	opts := []grpc.DialOption{grpc.WithBlock()}
+	if target.Scheme == "https" {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: insecureSkipVerify})))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	conn, err := grpc.DialContext(ctx, target.Host, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	reflect := func(req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return nil, fmt.Errorf("reflection error: %s", errResp.ErrorMessage)
+		}
+		return resp, nil
+	}
+
+	resp, err := reflect(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]bool)
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services[service.Name] = true
+	}
+
+	methods := make(map[string]bool)
+	for service := range services {
+		if isGRPCReflection(service + "/") {
+			continue
+		}
+		resp, err := reflect(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			var file descriptor.FileDescriptorProto
+			if err := proto.Unmarshal(raw, &file); err != nil {
+				return nil, err
+			}
+			addGRPCMethods(methods, &file, services)
+		}
+	}
+
+	return methods, nil
+}
@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	pb "google.golang.org/grpc/examples/helloworld/helloworld"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestGRPCMethodsFromDescriptors(t *testing.T) {
+	set := &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{{
+		Package: proto.String("helloworld"),
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name:   proto.String("Greeter"),
+			Method: []*descriptor.MethodDescriptorProto{{Name: proto.String("SayHello")}, {Name: proto.String("SayBye")}},
+		}},
+	}}}
+	raw, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	methods, err := grpcMethodsFromDescriptors(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"helloworld.Greeter/SayHello": true, "helloworld.Greeter/SayBye": true}, methods)
+
+	_, err = grpcMethodsFromDescriptors("not base64")
+	assert.Error(t, err)
+}
+
+func TestGRPCAccess(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ls := openListener(t)
+	port := ls.Addr().(*net.TCPAddr).Port
+	ls.Close()
+	ts.EnablePort(port, "h2c")
+
+	target, s := startGRPCServerH2C(t, func(t *testing.T, s *grpc.Server) {
+		setupHelloSVC(t, s)
+		reflection.Register(s)
+	})
+	defer target.Close()
+	defer s.Stop()
+
+	load := func(conf apidef.GRPCConfig) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "grpc-access"
+			spec.Proxy.ListenPath = "/"
+			spec.UseKeylessAccess = false
+			spec.Proxy.TargetURL = toTarget(t, "h2c", target)
+			spec.ListenPort = port
+			spec.Protocol = "h2c"
+			spec.GRPC = conf
+		})
+	}
+	key := func(tags ...string) string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"grpc-access": {APIID: "grpc-access"}}
+			s.Tags = tags
+		})
+		return key
+	}
+
+	// connections don't survive the reloads of the APIs
+	dial := func(key string) (*grpc.ClientConn, context.Context, func()) {
+		conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", port), grpc.WithInsecure())
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", key)
+		return conn, ctx, func() {
+			cancel()
+			conn.Close()
+		}
+	}
+	call := func(key, method string) codes.Code {
+		conn, ctx, done := dial(key)
+		defer done()
+		err := conn.Invoke(ctx, "/helloworld.Greeter/"+method, &pb.HelloRequest{Name: "Tyk"}, &pb.HelloReply{})
+		return status.Code(err)
+	}
+	listServices := func(key string) codes.Code {
+		conn, ctx, done := dial(key)
+		defer done()
+		stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		if err != nil {
+			return status.Code(err)
+		}
+		err = stream.Send(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		return status.Code(err)
+	}
+
+	internalKey, greeterKey, plainKey := key("internal"), key("greeter"), key()
+
+	t.Run("disabled", func(t *testing.T) {
+		load(apidef.GRPCConfig{})
+
+		assert.Equal(t, codes.OK, listServices(plainKey))
+		assert.Equal(t, codes.OK, call(plainKey, "SayHello"))
+	})
+
+	t.Run("reflection blocked", func(t *testing.T) {
+		load(apidef.GRPCConfig{Enabled: true})
+
+		assert.Equal(t, codes.PermissionDenied, listServices(internalKey))
+		assert.Equal(t, codes.OK, call(plainKey, "SayHello"))
+	})
+
+	t.Run("reflection for internal keys", func(t *testing.T) {
+		load(apidef.GRPCConfig{Enabled: true, Reflection: apidef.GRPCReflectionConfig{Enabled: true, AllowedTags: []string{"internal"}}})
+
+		assert.Equal(t, codes.OK, listServices(internalKey))
+		assert.Equal(t, codes.PermissionDenied, listServices(plainKey))
+	})
+
+	t.Run("method ACLs", func(t *testing.T) {
+		load(apidef.GRPCConfig{Enabled: true, MethodACLs: []apidef.GRPCMethodACL{
+			{Method: "helloworld.Greeter/*", AllowedTags: []string{"greeter"}},
+		}})
+
+		assert.Equal(t, codes.OK, call(greeterKey, "SayHello"))
+		assert.Equal(t, codes.PermissionDenied, call(plainKey, "SayHello"))
+	})
+
+	t.Run("known methods discovered with reflection", func(t *testing.T) {
+		load(apidef.GRPCConfig{Enabled: true, KnownMethodsOnly: true})
+
+		assert.Equal(t, codes.OK, call(plainKey, "SayHello"))
+		assert.Equal(t, codes.Unimplemented, call(plainKey, "Unknown"))
+	})
+}
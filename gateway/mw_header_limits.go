@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Analytics reasons of the requests rejected by the header limits.
+const (
+	errorReasonHeaderCountExceeded = "header_count_exceeded"
+	errorReasonHeaderSizeExceeded  = "header_size_exceeded"
+	errorReasonHeadersSizeExceeded = "headers_size_exceeded"
+)
+
+// HeaderLimitsMiddleware rejects requests with more or larger headers than allowed by the API
+type HeaderLimitsMiddleware struct {
+	BaseMiddleware
+}
+
+func (h *HeaderLimitsMiddleware) Name() string {
+	return "HeaderLimitsMiddleware"
+}
+
+func (h *HeaderLimitsMiddleware) EnabledForSpec() bool {
+	conf := h.Spec.HeaderLimits
+	return conf.MaxCount > 0 || conf.MaxHeaderSize > 0 || conf.MaxTotalSize > 0
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (h *HeaderLimitsMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	conf := h.Spec.HeaderLimits
+
+	count, total := 0, int64(0)
+	for name, values := range r.Header {
+		for _, value := range values {
+			size := int64(len(name) + len(value))
+			if conf.MaxHeaderSize > 0 && size > conf.MaxHeaderSize {
+				return h.reject(r, errorReasonHeaderSizeExceeded, fmt.Errorf("request header %s is too large", name))
+			}
+			count++
+			total += size
+		}
+	}
+
+	if conf.MaxCount > 0 && count > conf.MaxCount {
+		return h.reject(r, errorReasonHeaderCountExceeded, errors.New("too many request headers"))
+	}
+	if conf.MaxTotalSize > 0 && total > conf.MaxTotalSize {
+		return h.reject(r, errorReasonHeadersSizeExceeded, errors.New("request headers are too large"))
+	}
+
+	return nil, http.StatusOK
+}
+
+func (h *HeaderLimitsMiddleware) reject(r *http.Request, reason string, err error) (error, int) {
+	h.Logger().WithFields(logrus.Fields{"reason": reason}).Info("Attempted access with request headers over the limits, blocked.")
+	ctxSetErrorReason(r, reason)
+
+	return err, http.StatusRequestHeaderFieldsTooLarge
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestHeaderLimits(t *testing.T) {
+	ts := StartTest(nil, TestConfig{
+		Delay: 20 * time.Millisecond,
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.HeaderLimits = apidef.HeaderLimitsConfig{MaxCount: 8, MaxHeaderSize: 64, MaxTotalSize: 256}
+	}, func(spec *APISpec) {
+		spec.APIID = "unlimited"
+		spec.Proxy.ListenPath = "/unlimited/"
+	})
+
+	manyHeaders := map[string]string{}
+	for _, name := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		manyHeaders["X-"+name] = "1"
+	}
+	largeHeaders := map[string]string{}
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		largeHeaders["X-"+name] = strings.Repeat("a", 60)
+	}
+
+	time.Sleep(recordsBufferFlushInterval + 50)
+	ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Code: http.StatusOK},
+		{Path: "/", Headers: map[string]string{"X-Large": strings.Repeat("a", 60)}, Code: http.StatusRequestHeaderFieldsTooLarge,
+			BodyMatch: "request header X-Large is too large"},
+		{Path: "/", Headers: manyHeaders, Code: http.StatusRequestHeaderFieldsTooLarge, BodyMatch: "too many request headers"},
+		{Path: "/", Headers: largeHeaders, Code: http.StatusRequestHeaderFieldsTooLarge, BodyMatch: "request headers are too large"},
+		{Path: "/unlimited/", Headers: largeHeaders, Code: http.StatusOK},
+	}...)
+
+	time.Sleep(recordsBufferFlushInterval + 50)
+	results := ts.Gw.analytics.Store.GetAndDeleteSet(analyticsKeyName)
+	require.Len(t, results, 5)
+
+	reasons := map[string]int{}
+	for _, result := range results {
+		var record AnalyticsRecord
+		require.NoError(t, msgpack.Unmarshal([]byte(result.(string)), &record))
+		reasons[record.ErrorReason]++
+	}
+	assert.Equal(t, map[string]int{
+		"":                             2,
+		errorReasonHeaderSizeExceeded:  1,
+		errorReasonHeaderCountExceeded: 1,
+		errorReasonHeadersSizeExceeded: 1,
+	}, reasons)
+}
@@ -17,33 +17,18 @@ func (i *IPBlackListMiddleware) Name() string {
 	return "IPBlackListMiddleware"
 }
 
+// EnabledForSpec doesn't depend on the blocked IPs, as they can be added without reloading the API.
 func (i *IPBlackListMiddleware) EnabledForSpec() bool {
-	return i.Spec.EnableIpBlacklisting && len(i.Spec.BlacklistedIPs) > 0
+	return i.Spec.EnableIpBlacklisting
 }
 
 // ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
 func (i *IPBlackListMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
 	remoteIP := net.ParseIP(request.RealIP(r))
 
-	// Enabled, check incoming IP address
-	for _, ip := range i.Spec.BlacklistedIPs {
-		// Might be CIDR, try this one first then fallback to IP parsing later
-		blockedIP, blockedNet, err := net.ParseCIDR(ip)
-		if err != nil {
-			blockedIP = net.ParseIP(ip)
-		}
-
-		// Check CIDR if possible
-		if blockedNet != nil && blockedNet.Contains(remoteIP) {
-
-			return i.handleError(r, remoteIP.String())
-		}
-
-		// We parse the IP to manage IPv4 and IPv6 easily
-		if blockedIP.Equal(remoteIP) {
-
-			return i.handleError(r, remoteIP.String())
-		}
+	// Enabled, check incoming IP address, IPv4 or IPv6, against the blocked IPs and CIDR ranges
+	if i.Spec.ipAccessList().Blocked(remoteIP) {
+		return i.handleError(r, remoteIP.String())
 	}
 
 	return nil, http.StatusOK
@@ -6,15 +6,19 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/gocraft/health"
 	"github.com/robertkrimen/otto"
 	_ "github.com/robertkrimen/otto/underscore"
 
@@ -176,44 +180,16 @@ func (d *DynamicMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Reques
 
 	// Run the middleware
 	middlewareClassname := d.MiddlewareClassName
-	vm := d.Spec.JSVM.VM.Copy()
-	vm.Interrupt = make(chan func(), 1)
 	logger.Debug("Running: ", middlewareClassname)
-	// buffered, leaving no chance of a goroutine leak since the
-	// spawned goroutine will send 0 or 1 values.
-	ret := make(chan otto.Value, 1)
-	errRet := make(chan error, 1)
-	go func() {
-		defer func() {
-			// the VM executes the panic func that gets it
-			// to stop, so we must recover here to not crash
-			// the whole Go program.
-			recover()
-		}()
-		returnRaw, err := vm.Run(middlewareClassname + `.DoProcessRequest(` + string(requestAsJson) + `, ` + string(sessionAsJson) + `, ` + specAsJson + `);`)
-		ret <- returnRaw
-		errRet <- err
-	}()
-	var returnRaw otto.Value
-	t := time.NewTimer(d.Spec.JSVM.Timeout)
-	select {
-	case returnRaw = <-ret:
-		if err := <-errRet; err != nil {
-			logger.WithError(err).Error("Failed to run JS middleware")
-			return nil, http.StatusOK
-		}
-		t.Stop()
-	case <-t.C:
-		t.Stop()
+	returnDataStr, err := d.Spec.JSVM.Run(middlewareClassname + `.DoProcessRequest(` + string(requestAsJson) + `, ` + string(sessionAsJson) + `, ` + specAsJson + `);`)
+	if err == errJSVMTimeout {
 		logger.Error("JS middleware timed out after ", d.Spec.JSVM.Timeout)
-		vm.Interrupt <- func() {
-			// only way to stop the VM is to send it a func
-			// that panics.
-			panic("stop")
-		}
 		return nil, http.StatusOK
 	}
-	returnDataStr, _ := returnRaw.ToString()
+	if err != nil {
+		logger.WithError(err).Error("Failed to run JS middleware")
+		return nil, http.StatusOK
+	}
 
 	// Decode the return object
 	newRequestData := VMReturnObject{}
@@ -319,9 +295,27 @@ type JSVM struct {
 	Log     *logrus.Entry  `json:"-"` // logger used by the JS code
 	RawLog  *logrus.Logger `json:"-"` // logger used by `rawlog` func to avoid formatting
 	Gw      *Gateway       `json:"-"`
+
+	// pool keeps copies of VM prepared for the next runs, if jsvm_pool_size is set. refilling is
+	// 1 while a goroutine fills it.
+	pool          chan *otto.Otto
+	refilling     int32
+	maxReturnSize int
+	maxMemory     uint64
 }
 
-const defaultJSVMTimeout = 5
+const (
+	defaultJSVMTimeout = 5
+	// jsvmMemoryCheckInterval is how often the memory allocated during a run is checked against
+	// jsvm_max_memory.
+	jsvmMemoryCheckInterval = 10 * time.Millisecond
+)
+
+var (
+	errJSVMTimeout        = errors.New("JS execution timed out")
+	errJSVMReturnTooLong  = errors.New("JS execution returned more data than jsvm_max_return_size")
+	errJSVMMemoryExceeded = errors.New("JS execution allocated more memory than jsvm_max_memory")
+)
 
 // Init creates the JSVM with the core library and sets up a default
 // timeout.
@@ -355,7 +349,10 @@ func (j *JSVM) Init(spec *APISpec, logger *logrus.Entry, gw *Gateway) {
 	// Add environment API
 	j.LoadTykJSApi()
 
-	if jsvmTimeout := gw.GetConfig().JSVMTimeout; jsvmTimeout <= 0 {
+	if spec != nil && spec.CustomMiddleware.JSVMTimeout > 0 {
+		j.Timeout = time.Duration(spec.CustomMiddleware.JSVMTimeout) * time.Second
+		logger.Debugf("API JSVM timeout: %v", j.Timeout)
+	} else if jsvmTimeout := gw.GetConfig().JSVMTimeout; jsvmTimeout <= 0 {
 		j.Timeout = time.Duration(defaultJSVMTimeout) * time.Second
 		logger.Debugf("Default JSVM timeout used: %v", j.Timeout)
 	} else {
@@ -363,10 +360,172 @@ func (j *JSVM) Init(spec *APISpec, logger *logrus.Entry, gw *Gateway) {
 		logger.Debugf("Custom JSVM timeout: %v", j.Timeout)
 	}
 
+	conf := gw.GetConfig()
+	if conf.JSVMStackDepthLimit > 0 {
+		vm.SetStackDepthLimit(conf.JSVMStackDepthLimit)
+	}
+	if conf.JSVMPoolSize > 0 {
+		j.pool = make(chan *otto.Otto, conf.JSVMPoolSize)
+	}
+	j.maxReturnSize = conf.JSVMMaxReturnSize
+	if conf.JSVMMaxMemory > 0 {
+		j.maxMemory = uint64(conf.JSVMMaxMemory)
+	}
+
 	j.Log = logger // use the global logger by default
 	j.RawLog = rawLog
 }
 
+// acquireVM returns a fresh copy of VM, prepared ahead by the pool if jsvm_pool_size is set. The
+// copies are used by a single run, so that the globals set by a request don't leak to the others.
+func (j *JSVM) acquireVM() *otto.Otto {
+	if j.pool == nil {
+		return j.copyVM()
+	}
+
+	// the copies replacing the ones taken are prepared off the request path, by a single goroutine
+	if atomic.CompareAndSwapInt32(&j.refilling, 0, 1) {
+		go j.refillPool()
+	}
+	select {
+	case vm := <-j.pool:
+		return vm
+	default:
+		return j.copyVM()
+	}
+}
+
+func (j *JSVM) copyVM() *otto.Otto {
+	vm := j.VM.Copy()
+	vm.Interrupt = make(chan func(), 1)
+	return vm
+}
+
+// refillPool adds fresh copies of VM to the pool until it is full.
+func (j *JSVM) refillPool() {
+	defer atomic.StoreInt32(&j.refilling, 0)
+	for len(j.pool) < cap(j.pool) {
+		select {
+		case j.pool <- j.copyVM():
+		default:
+			return
+		}
+	}
+}
+
+// watchMemory closes the returned channel when the heap grew by more than jsvm_max_memory since it
+// was called, until stop is closed. The heap of the whole process is measured, the allocations of
+// the other requests running meanwhile count towards the limit.
+func (j *JSVM) watchMemory(stop <-chan struct{}) <-chan struct{} {
+	exceeded := make(chan struct{})
+	go func() {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		start := stats.HeapAlloc
+
+		tick := time.NewTicker(jsvmMemoryCheckInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-tick.C:
+			}
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > start && stats.HeapAlloc-start > j.maxMemory {
+				close(exceeded)
+				return
+			}
+		}
+	}()
+	return exceeded
+}
+
+// Run runs expr on a fresh copy of the VM and returns its result as a string. The run is
+// interrupted after the timeout or once it allocated jsvm_max_memory, and its panics are recovered.
+func (j *JSVM) Run(expr string) (string, error) {
+	type result struct {
+		value otto.Value
+		err   error
+	}
+	// buffered, leaving no chance of a goroutine leak since the
+	// spawned goroutine will send 1 value.
+	done := make(chan result, 1)
+
+	vm := j.acquireVM()
+	start := time.Now()
+	go func() {
+		defer func() {
+			// the VM executes the panic func that gets it
+			// to stop, so we must recover here to not crash
+			// the whole Go program.
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("JS execution panicked: %v", r)}
+			}
+		}()
+		value, err := vm.Run(expr)
+		done <- result{value, err}
+	}()
+
+	interrupt := func() {
+		vm.Interrupt <- func() {
+			// only way to stop the VM is to send it a func
+			// that panics.
+			panic("stop")
+		}
+	}
+
+	// nil, never ready, when the memory isn't limited
+	var memoryExceeded <-chan struct{}
+	if j.maxMemory > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		memoryExceeded = j.watchMemory(stop)
+	}
+
+	var res result
+	t := time.NewTimer(j.Timeout)
+	select {
+	case res = <-done:
+		t.Stop()
+	case <-t.C:
+		interrupt()
+		res.err = errJSVMTimeout
+	case <-memoryExceeded:
+		t.Stop()
+		interrupt()
+		res.err = errJSVMMemoryExceeded
+	}
+	j.recordExecution(time.Since(start), res.err)
+	if res.err != nil {
+		return "", res.err
+	}
+
+	out, err := res.value.ToString()
+	if err != nil {
+		return "", err
+	}
+	if j.maxReturnSize > 0 && len(out) > j.maxReturnSize {
+		return "", errJSVMReturnTooLong
+	}
+	return out, nil
+}
+
+// recordExecution reports the execution time of a run to the instrumentation.
+func (j *JSVM) recordExecution(elapsed time.Duration, err error) {
+	if !instrumentationEnabled {
+		return
+	}
+	meta := health.Kvs{}
+	if j.Spec != nil {
+		meta["api_id"] = j.Spec.APIID
+	}
+	if err != nil {
+		meta["error"] = err.Error()
+	}
+	instrument.NewJob("JSVMExecution").TimingKv("exec_time", elapsed.Nanoseconds(), meta)
+}
+
 // LoadJSPaths will load JS classes and functionality in to the VM by file
 func (j *JSVM) LoadJSPaths(paths []string, prefix string) {
 	for _, mwPath := range paths {
@@ -14,7 +14,9 @@ import (
 
 	"github.com/TykTechnologies/tyk/config"
 
+	"github.com/robertkrimen/otto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/TykTechnologies/tyk/ctx"
 	"github.com/TykTechnologies/tyk/user"
@@ -216,6 +218,103 @@ leakMid.NewProcessRequest(function(request, session) {
 	}
 }
 
+func TestJSVMRun(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	newJSVM := func(t *testing.T, confFn func(conf *config.Config)) *JSVM {
+		conf := ts.Gw.GetConfig()
+		confFn(&conf)
+		ts.Gw.SetConfig(conf)
+
+		jsvm := &JSVM{}
+		jsvm.Init(nil, logrus.NewEntry(log), ts.Gw)
+		_, err := jsvm.VM.Run(`
+var counter = 0
+function count() { counter++; return String(counter) }
+function fail() { counter++; throw new Error("failed") }
+function recurse(n) { return recurse(n + 1) }`)
+		require.NoError(t, err)
+		return jsvm
+	}
+	run := func(t *testing.T, jsvm *JSVM, expr string) string {
+		out, err := jsvm.Run(expr)
+		require.NoError(t, err)
+		return out
+	}
+
+	t.Run("copy per run", func(t *testing.T) {
+		jsvm := newJSVM(t, func(conf *config.Config) {})
+		assert.Equal(t, "1", run(t, jsvm, "count()"))
+		assert.Equal(t, "1", run(t, jsvm, "count()"))
+	})
+
+	t.Run("pooled copies", func(t *testing.T) {
+		jsvm := newJSVM(t, func(conf *config.Config) {
+			conf.JSVMPoolSize = 1
+		})
+		// the globals set by a run don't leak to the next ones
+		assert.Equal(t, "1", run(t, jsvm, "count()"))
+		assert.Eventually(t, func() bool { return len(jsvm.pool) == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, "1", run(t, jsvm, "count()"))
+
+		_, err := jsvm.Run("fail()")
+		assert.Error(t, err)
+		assert.Eventually(t, func() bool { return len(jsvm.pool) == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, "1", run(t, jsvm, "count()"))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		jsvm := newJSVM(t, func(conf *config.Config) {
+			conf.JSVMPoolSize = 0
+		})
+		jsvm.Timeout = time.Millisecond
+		_, err := jsvm.Run("while (true) {}")
+		assert.Equal(t, errJSVMTimeout, err)
+
+		spec := BuildAPI(func(spec *APISpec) {
+			spec.CustomMiddleware.JSVMTimeout = 2
+		})[0]
+		jsvm.Init(spec, logrus.NewEntry(log), ts.Gw)
+		assert.Equal(t, 2*time.Second, jsvm.Timeout)
+	})
+
+	t.Run("panic recovered", func(t *testing.T) {
+		jsvm := newJSVM(t, func(conf *config.Config) {})
+		require.NoError(t, jsvm.VM.Set("explode", func(call otto.FunctionCall) otto.Value {
+			panic("exploded")
+		}))
+		_, err := jsvm.Run("explode()")
+		assert.Error(t, err)
+		assert.NotEqual(t, errJSVMTimeout, err)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		jsvm := newJSVM(t, func(conf *config.Config) {
+			conf.JSVMStackDepthLimit = 100
+			conf.JSVMMaxReturnSize = 5
+		})
+		_, err := jsvm.Run("recurse(0)")
+		assert.Error(t, err)
+		assert.NotEqual(t, errJSVMTimeout, err)
+
+		assert.Equal(t, "12345", run(t, jsvm, `"12345"`))
+		_, err = jsvm.Run(`"123456"`)
+		assert.Equal(t, errJSVMReturnTooLong, err)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		jsvm := newJSVM(t, func(conf *config.Config) {
+			conf.JSVMStackDepthLimit = 0
+			conf.JSVMMaxReturnSize = 0
+			conf.JSVMMaxMemory = 10 << 20
+		})
+		_, err := jsvm.Run("var a = []; while (true) { a.push('allocated ' + a.length) }")
+		assert.Equal(t, errJSVMMemoryExceeded, err)
+		assert.Equal(t, "1", run(t, jsvm, "count()"))
+	})
+}
+
 func TestJSVMConfigData(t *testing.T) {
 	ts := StartTest(nil)
 	defer ts.Close()
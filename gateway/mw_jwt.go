@@ -21,6 +21,7 @@ import (
 	jose "github.com/square/go-jose"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
 	"github.com/TykTechnologies/tyk/storage"
 
 	"github.com/TykTechnologies/tyk/user"
@@ -560,10 +561,7 @@ func (k *JWTMiddleware) processCentralisedJWT(r *http.Request, token *jwt.Token)
 
 	// apply policies from scope if scope-to-policy mapping is specified for this API
 	if len(k.Spec.JWTScopeToPolicyMapping) != 0 {
-		scopeClaimName := k.Spec.JWTScopeClaimName
-		if scopeClaimName == "" {
-			scopeClaimName = "scope"
-		}
+		scopeClaimName := k.scopeClaimName()
 
 		if scope := getScopeFromClaim(claims, scopeClaimName); scope != nil {
 			polIDs := []string{
@@ -709,9 +707,9 @@ func (k *JWTMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 		// No header value, fail
 		logger.Info("Attempted access with malformed header, no JWT auth header found.")
 
-		log.Debug("Looked in: ", config.AuthHeaderName)
-		log.Debug("Raw data was: ", rawJWT)
-		log.Debug("Headers are: ", r.Header)
+		logger.Debug("Looked in: ", config.AuthHeaderName)
+		logger.Debug("Raw data was: ", rawJWT)
+		logger.Debug("Headers are: ", k.Spec.LogRedactor.header(r.Header))
 
 		k.reportLoginFailure(tykId, r)
 		return errors.New("Authorization field missing"), http.StatusBadRequest
@@ -778,6 +776,8 @@ func (k *JWTMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 		}
 
 		// Token is valid - let's move on
+		ctxSetGrantedScopes(r, getScopeFromClaim(token.Claims.(jwt.MapClaims), k.scopeClaimName()))
+		ctxSetJWTToken(r, token)
 
 		// Are we mapping to a central JWT Secret?
 		if k.Spec.JWTSource != "" {
@@ -817,6 +817,15 @@ func ParseRSAPublicKey(data []byte) (interface{}, error) {
 	return pub, err
 }
 
+// scopeClaimName returns the name of the claim holding the scopes of the token, for the JWT and
+// OpenID Connect middleware.
+func (t BaseMiddleware) scopeClaimName() string {
+	if t.Spec.JWTScopeClaimName != "" {
+		return t.Spec.JWTScopeClaimName
+	}
+	return "scope"
+}
+
 func (k *JWTMiddleware) timeValidateJWTClaims(c jwt.MapClaims) *jwt.ValidationError {
 	vErr := new(jwt.ValidationError)
 	now := time.Now().Unix()
@@ -844,6 +853,18 @@ func (k *JWTMiddleware) timeValidateJWTClaims(c jwt.MapClaims) *jwt.ValidationEr
 	return vErr
 }
 
+// ctxSetJWTToken sets the JWT the request was authenticated with, once validated.
+func ctxSetJWTToken(r *http.Request, token *jwt.Token) {
+	setCtxValue(r, ctx.JWTToken, token)
+}
+
+func ctxGetJWTToken(r *http.Request) *jwt.Token {
+	if v := r.Context().Value(ctx.JWTToken); v != nil {
+		return v.(*jwt.Token)
+	}
+	return nil
+}
+
 func ctxSetJWTContextVars(s *APISpec, r *http.Request, token *jwt.Token) {
 	// Flatten claims and add to context
 	if !s.EnableContextVars {
@@ -860,6 +881,7 @@ func ctxSetJWTContextVars(s *APISpec, r *http.Request, token *jwt.Token) {
 		for claimName, claimValue := range token.Claims.(jwt.MapClaims) {
 			claim := claimPrefix + claimName
 			cnt[claim] = claimValue
+			flattenJWTClaim(cnt, claim, claimValue)
 		}
 
 		// Key data
@@ -869,6 +891,20 @@ func ctxSetJWTContextVars(s *APISpec, r *http.Request, token *jwt.Token) {
 	}
 }
 
+// flattenJWTClaim adds the fields of the object claims, e.g. jwt_claims_address_country for the
+// country of the address claim.
+func flattenJWTClaim(cnt map[string]interface{}, claim string, value interface{}) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, fieldValue := range fields {
+		field := claim + "_" + name
+		cnt[field] = fieldValue
+		flattenJWTClaim(cnt, field, fieldValue)
+	}
+}
+
 func (gw *Gateway) generateSessionFromPolicy(policyID, orgID string, enforceOrg bool) (user.SessionState, error) {
 	gw.policiesMu.RLock()
 	policy, ok := gw.policiesByID[policyID]
@@ -3,8 +3,10 @@ package gateway
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/user"
 )
 
 // KeyExpired middleware will check if the requesting key is expired or not. It makes use of the authManager to do so.
@@ -46,7 +48,7 @@ func (k *KeyExpired) ProcessRequest(w http.ResponseWriter, r *http.Request, _ in
 	}
 
 	if !k.Spec.AuthManager.KeyExpired(session) {
-		return nil, http.StatusOK
+		return k.checkSchedule(session)
 	}
 	logger.Info("Attempted access from expired key.")
 
@@ -61,3 +63,22 @@ func (k *KeyExpired) ProcessRequest(w http.ResponseWriter, r *http.Request, _ in
 
 	return errors.New("Key has expired, please renew"), http.StatusUnauthorized
 }
+
+// checkSchedule rejects the keys used before their activity period starts or outside the windows
+// of their schedule.
+func (k *KeyExpired) checkSchedule(session *user.SessionState) (error, int) {
+	now := time.Now()
+	if session.NotActiveYet(now) {
+		k.Logger().Info("Attempted access from key not active yet.")
+		reportHealthValue(k.Spec, KeyFailure, "-1")
+		return errors.New("Key is not active yet"), http.StatusForbidden
+	}
+
+	if !session.Schedule.ActiveAt(now) {
+		k.Logger().Info("Attempted access from key outside of its schedule.")
+		reportHealthValue(k.Spec, KeyFailure, "-1")
+		return errors.New("Key is not active at this time"), http.StatusForbidden
+	}
+
+	return nil, http.StatusOK
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestKeyActivity(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "scheduled"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+	})
+
+	now := time.Now().UTC()
+	createKey := func(fn func(s *user.SessionState)) map[string]string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"scheduled": {APIID: "scheduled"}}
+			fn(s)
+		})
+		return map[string]string{"Authorization": key}
+	}
+
+	notYetActive := createKey(func(s *user.SessionState) {
+		s.ActiveFrom = now.Add(time.Hour).Unix()
+	})
+	noLongerActive := createKey(func(s *user.SessionState) {
+		s.ActiveUntil = now.Add(-time.Hour).Unix()
+	})
+	active := createKey(func(s *user.SessionState) {
+		s.ActiveFrom = now.Add(-time.Hour).Unix()
+		s.ActiveUntil = now.Add(time.Hour).Unix()
+	})
+	inWindow := createKey(func(s *user.SessionState) {
+		s.Schedule.Windows = []user.ScheduleWindow{{
+			Start: now.Add(-time.Hour).Format("15:04"),
+			End:   now.Add(time.Hour).Format("15:04"),
+		}}
+	})
+	outOfWindow := createKey(func(s *user.SessionState) {
+		s.Schedule.Windows = []user.ScheduleWindow{{
+			Start: now.Add(time.Hour).Format("15:04"),
+			End:   now.Add(2 * time.Hour).Format("15:04"),
+		}}
+	})
+
+	invalidSchedule := CreateStandardSession()
+	invalidSchedule.Schedule.Timezone = "Mars/Olympus_Mons"
+	invalidScheduleJSON, _ := json.Marshal(invalidSchedule)
+	invalidPeriod := CreateStandardSession()
+	invalidPeriod.ActiveFrom = now.Unix()
+	invalidPeriod.ActiveUntil = now.Add(-time.Hour).Unix()
+	invalidPeriodJSON, _ := json.Marshal(invalidPeriod)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: notYetActive, Code: http.StatusForbidden, BodyMatch: "Key is not active yet"},
+		{Path: "/", Headers: noLongerActive, Code: http.StatusUnauthorized, BodyMatch: "Key has expired, please renew"},
+		{Path: "/", Headers: active, Code: http.StatusOK},
+		{Path: "/", Headers: inWindow, Code: http.StatusOK},
+		{Path: "/", Headers: outOfWindow, Code: http.StatusForbidden, BodyMatch: "Key is not active at this time"},
+		{Method: http.MethodPost, Path: "/tyk/keys/create", Data: string(invalidScheduleJSON), AdminAuth: true,
+			Code: http.StatusBadRequest, BodyMatch: "invalid timezone"},
+		{Method: http.MethodPost, Path: "/tyk/keys", Data: string(invalidPeriodJSON), AdminAuth: true,
+			Code: http.StatusBadRequest, BodyMatch: "active_until must be after active_from"},
+	}...)
+}
+
+func TestScheduleActiveAt(t *testing.T) {
+	// Monday
+	at := func(clock string) time.Time {
+		tm, _ := time.Parse("2006-01-02 15:04", "2024-01-01 "+clock)
+		return tm
+	}
+
+	businessHours := user.Schedule{Windows: []user.ScheduleWindow{{Days: []string{"mon", "Tuesday"}, Start: "09:00", End: "17:00"}}}
+	assert.NoError(t, businessHours.Validate())
+	assert.True(t, businessHours.ActiveAt(at("09:00")))
+	assert.False(t, businessHours.ActiveAt(at("17:00")))
+	assert.False(t, businessHours.ActiveAt(at("08:59")))
+	assert.True(t, businessHours.ActiveAt(at("12:00").AddDate(0, 0, 1)))
+	assert.False(t, businessHours.ActiveAt(at("12:00").AddDate(0, 0, 2)))
+
+	overnight := user.Schedule{Windows: []user.ScheduleWindow{{Days: []string{"sun"}, Start: "22:00", End: "06:00"}}}
+	assert.True(t, overnight.ActiveAt(at("05:59")))
+	assert.False(t, overnight.ActiveAt(at("22:00")))
+	assert.True(t, overnight.ActiveAt(at("22:00").AddDate(0, 0, -1)))
+
+	inTimezone := user.Schedule{Timezone: "America/New_York", Windows: []user.ScheduleWindow{{Start: "09:00", End: "17:00"}}}
+	assert.NoError(t, inTimezone.Validate())
+	assert.False(t, inTimezone.ActiveAt(at("09:00")))
+	assert.True(t, inTimezone.ActiveAt(at("14:00")))
+
+	assert.True(t, user.Schedule{}.ActiveAt(at("03:00")))
+	assert.Error(t, user.Schedule{Windows: []user.ScheduleWindow{{Days: []string{"someday"}, Start: "09:00", End: "17:00"}}}.Validate())
+	assert.Error(t, user.Schedule{Windows: []user.ScheduleWindow{{Start: "9am", End: "17:00"}}}.Validate())
+	assert.Error(t, user.Schedule{Windows: []user.ScheduleWindow{{Start: "09:00", End: "09:00"}}}.Validate())
+}
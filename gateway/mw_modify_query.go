@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// TransformQuery is a middleware that adds, removes and renames the query parameters of a request
+type TransformQuery struct {
+	BaseMiddleware
+}
+
+func (t *TransformQuery) Name() string {
+	return "TransformQuery"
+}
+
+func (t *TransformQuery) EnabledForSpec() bool {
+	for _, version := range t.Spec.VersionData.Versions {
+		if len(version.ExtendedPaths.TransformQuery) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (t *TransformQuery) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	vInfo, _ := t.Spec.Version(r)
+	versionPaths := t.Spec.RxPaths[vInfo.Name]
+	found, meta := t.Spec.CheckSpecMatchesStatus(r, versionPaths, QueryTransformed)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	qmeta := meta.(*apidef.QueryTransformMeta)
+	query := r.URL.Query()
+
+	for oldName, newName := range qmeta.RenameParams {
+		values, ok := query[oldName]
+		if !ok {
+			continue
+		}
+		delete(query, oldName)
+		query[newName] = append(query[newName], values...)
+	}
+
+	for _, name := range qmeta.DeleteParams {
+		query.Del(name)
+	}
+
+	for name, value := range qmeta.AddParams {
+		if _, ok := query[name]; !ok {
+			query.Set(name, t.Gw.replaceTykVariables(r, value, false))
+		}
+	}
+
+	r.URL.RawQuery = query.Encode()
+	return nil, http.StatusOK
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestTransformQuery(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.EnableContextVars = true
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.TransformQuery = []apidef.QueryTransformMeta{{
+				Path:         "/search",
+				Method:       http.MethodGet,
+				RenameParams: map[string]string{"q": "query"},
+				DeleteParams: []string{"debug"},
+				AddParams: map[string]string{
+					"limit":  "10",
+					"origin": "$tyk_context.remote_addr",
+				},
+			}}
+		})
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/search?q=tyk&debug=1", Code: http.StatusOK,
+			BodyMatch: `"Url":"/search\?limit=10\\u0026origin=127.0.0.1\\u0026query=tyk"`},
+		{Path: "/search?q=a&query=b&limit=50", Code: http.StatusOK,
+			BodyMatch: `"Url":"/search\?limit=50\\u0026origin=127.0.0.1\\u0026query=b\\u0026query=a"`},
+		{Method: http.MethodPost, Path: "/search?q=tyk&debug=1", Code: http.StatusOK,
+			BodyMatch: `"Url":"/search\?q=tyk\\u0026debug=1"`},
+		{Path: "/other?debug=1", Code: http.StatusOK, BodyMatch: `"Url":"/other\?debug=1"`},
+	}...)
+}
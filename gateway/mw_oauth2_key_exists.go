@@ -2,10 +2,16 @@ package gateway
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lonelycode/osin"
+
 	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
 
 	"github.com/TykTechnologies/tyk/apidef"
 )
@@ -19,6 +25,7 @@ const (
 	ErrOAuthAuthorizationFieldMalformed = "oauth.auth_field_malformed"
 	ErrOAuthKeyNotFound                 = "oauth.key_not_found"
 	ErrOAuthClientDeleted               = "oauth.client_deleted"
+	ErrOAuthClientRateLimited           = "oauth.client_rate_limited"
 )
 
 func init() {
@@ -41,6 +48,11 @@ func init() {
 		Message: "Key not authorised. OAuth client access was revoked",
 		Code:    http.StatusForbidden,
 	}
+
+	TykErrors[ErrOAuthClientRateLimited] = config.TykError{
+		Message: "OAuth client rate limit exceeded",
+		Code:    http.StatusTooManyRequests,
+	}
 }
 
 // Oauth2KeyExists will check if the key being used to access the API is in the request data,
@@ -104,27 +116,34 @@ func (k *Oauth2KeyExists) ProcessRequest(w http.ResponseWriter, r *http.Request,
 	}
 
 	// Make sure OAuth-client is still present
-	oauthClientDeletedKey := "oauth-del-" + k.Spec.APIID + session.OauthClientID
-	oauthClientDeleted := false
+	oauthClientKey := "oauth-client-" + k.Spec.APIID + session.OauthClientID
+	var oauthClient osin.Client
 	// check if that oauth client was deleted with using  memory cache first
-	if val, found := k.Gw.UtilCache.Get(oauthClientDeletedKey); found {
-		oauthClientDeleted = val.(bool)
+	if val, found := k.Gw.UtilCache.Get(oauthClientKey); found {
+		oauthClient, _ = val.(osin.Client)
 	} else {
-		// if not cached in memory then hit Redis to get oauth-client from there
-		if _, err := k.Spec.OAuthManager.OsinServer.Storage.GetClient(session.OauthClientID); err != nil {
-			// set this oauth client as deleted in memory cache for the next N sec
-			k.Gw.UtilCache.Set(oauthClientDeletedKey, true, checkOAuthClientDeletedInetrval)
-			oauthClientDeleted = true
-		} else {
-			// set this oauth client as NOT deleted in memory cache for next N sec
-			k.Gw.UtilCache.Set(oauthClientDeletedKey, false, checkOAuthClientDeletedInetrval)
-		}
+		// if not cached in memory then hit Redis to get oauth-client from there, deleted clients
+		// are cached as nil for the next N sec
+		oauthClient, _ = k.Spec.OAuthManager.OsinServer.Storage.GetClient(session.OauthClientID)
+		k.Gw.UtilCache.Set(oauthClientKey, oauthClient, checkOAuthClientDeletedInetrval)
 	}
-	if oauthClientDeleted {
+	if oauthClient == nil {
 		logger.WithField("oauthClientID", session.OauthClientID).Warning("Attempted access for deleted OAuth client.")
 		return errorAndStatusCode(ErrOAuthClientDeleted)
 	}
 
+	if c, ok := oauthClient.(*OAuthClient); ok && k.clientRateLimitExceeded(r, c) {
+		logger.WithField("oauthClientID", c.ClientID).Info("OAuth client rate limit exceeded.")
+		k.FireEvent(EventRateLimitExceeded, EventKeyFailureMeta{
+			EventMetaDefault: EventMetaDefault{Message: "OAuth Client Rate Limit Exceeded", OriginatingRequest: EncodeRequestToEvent(r)},
+			Path:             r.URL.Path,
+			Origin:           request.RealIP(r),
+			Key:              accessToken,
+		})
+		reportHealthValue(k.Spec, Throttle, "-1")
+		return errorAndStatusCode(ErrOAuthClientRateLimited)
+	}
+
 	// Set session state on context, we will need it later
 	switch k.Spec.BaseIdentityProvidedBy {
 	case apidef.OAuthKey, apidef.UnsetAuth:
@@ -134,3 +153,31 @@ func (k *Oauth2KeyExists) ProcessRequest(w http.ResponseWriter, r *http.Request,
 	// Request is valid, carry on
 	return nil, http.StatusOK
 }
+
+// clientRateLimitExceeded applies the rate limit of client, shared by all the tokens issued to it.
+func (k *Oauth2KeyExists) clientRateLimitExceeded(r *http.Request, client *OAuthClient) bool {
+	limit := client.RateLimit
+	if limit == nil || limit.Rate <= 0 || !ctxCheckLimits(r) {
+		return false
+	}
+
+	keyName := "oauthclientlimiter-" + k.Spec.OrgID + k.Spec.APIID + client.ClientID
+	clientSess := &user.SessionState{
+		Rate: limit.Rate,
+		Per:  limit.Per,
+		// a new bucket is used once the limit changes
+		LastUpdated: strconv.FormatFloat(limit.Rate, 'f', -1, 64) + "/" + strconv.FormatFloat(limit.Per, 'f', -1, 64),
+	}
+	clientSess.SetKeyHash(storage.HashKey(keyName, k.Gw.GetConfig().HashKeys))
+
+	reason := k.Gw.SessionLimiter.ForwardMessage(r, clientSess,
+		keyName,
+		k.Gw.GlobalSessionManager.Store(),
+		true,
+		false,
+		&k.Spec.GlobalConfig,
+		k.Spec,
+		false,
+	)
+	return reason == sessionFailRateLimit
+}
@@ -117,6 +117,9 @@ func (k *OpenIDMW) ProcessRequest(w http.ResponseWriter, r *http.Request, _ inte
 	}
 
 	// 3. Create or set the session to match
+	scopeClaimName := k.scopeClaimName()
+	ctxSetGrantedScopes(r, getScopeFromClaim(token.Claims.(jwt.MapClaims), scopeClaimName))
+
 	iss, found := token.Claims.(jwt.MapClaims)["iss"]
 	clients, cfound := token.Claims.(jwt.MapClaims)["aud"]
 
@@ -181,11 +184,6 @@ func (k *OpenIDMW) ProcessRequest(w http.ResponseWriter, r *http.Request, _ inte
 	if !useScope {
 		policiesToApply = append(policiesToApply, policyID)
 	} else {
-		scopeClaimName := k.Spec.JWTScopeClaimName
-		if scopeClaimName == "" {
-			scopeClaimName = "scope"
-		}
-
 		if scope := getScopeFromClaim(token.Claims.(jwt.MapClaims), scopeClaimName); scope != nil {
 			// add all policies matched from scope-policy mapping
 			policiesToApply = mapScopeToPolicies(k.Spec.JWTScopeToPolicyMapping, scope)
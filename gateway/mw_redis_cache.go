@@ -35,6 +35,8 @@ type RedisCacheMiddleware struct {
 	CacheStore   storage.Handler
 	sh           SuccessHandler
 	singleFlight singleflight.Group
+	// collapseFlight shares the upstream request of concurrent identical requests missing the cache.
+	collapseFlight singleflight.Group
 }
 
 func (m *RedisCacheMiddleware) Name() string {
@@ -221,114 +223,157 @@ func (m *RedisCacheMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 		if !errCreatingChecksum {
 			log.Debug("Cache enabled, but record not found")
 		}
-		// Pass through to proxy AND CACHE RESULT
 
-		var resVal *http.Response
-		if isVirtual {
-			log.Debug("This is a virtual function")
-			vp := VirtualEndpoint{BaseMiddleware: m.BaseMiddleware}
-			vp.Init()
-			resVal = vp.ServeHTTPForCache(w, r, nil)
-		} else {
-			// This passes through and will write the value to the writer, but spit out a copy for the cache
-			log.Debug("Not virtual, passing")
-			if newURL := ctxGetURLRewriteTarget(r); newURL != nil {
-				r.URL = newURL
-				ctxSetURLRewriteTarget(r, nil)
-			}
-			if newMethod := ctxGetTransformRequestMethod(r); newMethod != "" {
-				r.Method = newMethod
-				ctxSetTransformRequestMethod(r, "")
-			}
-			sr := m.sh.ServeHTTPWithCache(w, r)
-			resVal = sr.Response
+		if m.Spec.CacheOptions.CollapseRequests && r.Method == http.MethodGet && !errCreatingChecksum {
+			return m.collapse(w, r, key, isVirtual, cacheMeta)
 		}
 
-		cacheThisRequest := true
-		cacheTTL := m.Spec.CacheOptions.CacheTimeout
+		// Pass through to proxy AND CACHE RESULT
+		m.fetch(w, r, key, isVirtual, cacheMeta, errCreatingChecksum)
+		return nil, mwStatusRespond
+	}
 
-		if resVal == nil {
-			log.Warning("Upstream request must have failed, response is empty")
-			return nil, mwStatusRespond
-		}
+	cachedData, timestamp, err := m.decodePayload(retBlob)
+	if err != nil {
+		// Tere was an issue with this cache entry - lets remove it:
+		m.CacheStore.DeleteKey(key)
+		return nil, http.StatusOK
+	}
 
-		cacheOnlyResponseCodes := m.Spec.CacheOptions.CacheOnlyResponseCodes
-		// override api main CacheOnlyResponseCodes by endpoint specific if provided
-		if cacheMeta != nil && len(cacheMeta.CacheOnlyResponseCodes) > 0 {
-			cacheOnlyResponseCodes = cacheMeta.CacheOnlyResponseCodes
-		}
+	if m.isTimeStampExpired(timestamp) || len(cachedData) == 0 {
+		m.CacheStore.DeleteKey(key)
+		return nil, http.StatusOK
+	}
 
-		// make sure the status codes match if specified
-		if len(cacheOnlyResponseCodes) > 0 {
-			foundCode := false
-			for _, code := range cacheOnlyResponseCodes {
-				if code == resVal.StatusCode {
-					foundCode = true
-					break
-				}
-			}
-			cacheThisRequest = foundCode
+	m.respond(w, r, cachedData)
+
+	// Stop any further execution
+	return nil, mwStatusRespond
+}
+
+// fetch passes the request through to the upstream, or the virtual endpoint, and caches the
+// response unless errCreatingChecksum is set. It returns the response in wire format, empty when
+// the upstream request failed.
+func (m *RedisCacheMiddleware) fetch(w http.ResponseWriter, r *http.Request, key string, isVirtual bool, cacheMeta *EndPointCacheMeta, errCreatingChecksum bool) string {
+	var resVal *http.Response
+	if isVirtual {
+		log.Debug("This is a virtual function")
+		vp := VirtualEndpoint{BaseMiddleware: m.BaseMiddleware}
+		vp.Init()
+		resVal = vp.ServeHTTPForCache(w, r, nil)
+	} else {
+		// This passes through and will write the value to the writer, but spit out a copy for the cache
+		log.Debug("Not virtual, passing")
+		if newURL := ctxGetURLRewriteTarget(r); newURL != nil {
+			r.URL = newURL
+			ctxSetURLRewriteTarget(r, nil)
+		}
+		if newMethod := ctxGetTransformRequestMethod(r); newMethod != "" {
+			r.Method = newMethod
+			ctxSetTransformRequestMethod(r, "")
 		}
+		sr := m.sh.ServeHTTPWithCache(w, r)
+		resVal = sr.Response
+	}
 
-		// Are we using upstream cache control?
-		if m.Spec.CacheOptions.EnableUpstreamCacheControl {
-			log.Debug("Upstream control enabled")
-			// Do we cache?
-			if resVal.Header.Get(upstreamCacheHeader) == "" {
-				log.Warning("Upstream cache action not found, not caching")
-				cacheThisRequest = false
-			}
+	cacheThisRequest := true
+	cacheTTL := m.Spec.CacheOptions.CacheTimeout
 
-			cacheTTLHeader := upstreamCacheTTLHeader
-			if m.Spec.CacheOptions.CacheControlTTLHeader != "" {
-				cacheTTLHeader = m.Spec.CacheOptions.CacheControlTTLHeader
-			}
+	if resVal == nil {
+		log.Warning("Upstream request must have failed, response is empty")
+		return ""
+	}
+
+	cacheOnlyResponseCodes := m.Spec.CacheOptions.CacheOnlyResponseCodes
+	// override api main CacheOnlyResponseCodes by endpoint specific if provided
+	if cacheMeta != nil && len(cacheMeta.CacheOnlyResponseCodes) > 0 {
+		cacheOnlyResponseCodes = cacheMeta.CacheOnlyResponseCodes
+	}
 
-			ttl := resVal.Header.Get(cacheTTLHeader)
-			if ttl != "" {
-				log.Debug("TTL Set upstream")
-				cacheAsInt, err := strconv.Atoi(ttl)
-				if err != nil {
-					log.Error("Failed to decode TTL cache value: ", err)
-					cacheTTL = m.Spec.CacheOptions.CacheTimeout
-				} else {
-					cacheTTL = int64(cacheAsInt)
-				}
+	// make sure the status codes match if specified
+	if len(cacheOnlyResponseCodes) > 0 {
+		foundCode := false
+		for _, code := range cacheOnlyResponseCodes {
+			if code == resVal.StatusCode {
+				foundCode = true
+				break
 			}
 		}
+		cacheThisRequest = foundCode
+	}
 
-		if cacheThisRequest && !errCreatingChecksum {
-			log.Debug("Caching request to redis")
-			var wireFormatReq bytes.Buffer
-			resVal.Write(&wireFormatReq)
-			log.Debug("Cache TTL is:", cacheTTL)
-			ts := m.getTimeTTL(cacheTTL)
-			toStore := m.encodePayload(wireFormatReq.String(), ts)
-			go func() {
-				err := m.CacheStore.SetKey(key, toStore, cacheTTL)
-				if err != nil {
-					log.WithError(err).Error("could not save key in cache store")
-				}
-			}()
+	// Are we using upstream cache control?
+	if m.Spec.CacheOptions.EnableUpstreamCacheControl {
+		log.Debug("Upstream control enabled")
+		// Do we cache?
+		if resVal.Header.Get(upstreamCacheHeader) == "" {
+			log.Warning("Upstream cache action not found, not caching")
+			cacheThisRequest = false
 		}
 
-		return nil, mwStatusRespond
+		cacheTTLHeader := upstreamCacheTTLHeader
+		if m.Spec.CacheOptions.CacheControlTTLHeader != "" {
+			cacheTTLHeader = m.Spec.CacheOptions.CacheControlTTLHeader
+		}
+
+		ttl := resVal.Header.Get(cacheTTLHeader)
+		if ttl != "" {
+			log.Debug("TTL Set upstream")
+			cacheAsInt, err := strconv.Atoi(ttl)
+			if err != nil {
+				log.Error("Failed to decode TTL cache value: ", err)
+				cacheTTL = m.Spec.CacheOptions.CacheTimeout
+			} else {
+				cacheTTL = int64(cacheAsInt)
+			}
+		}
 	}
 
-	cachedData, timestamp, err := m.decodePayload(retBlob)
-	if err != nil {
-		// Tere was an issue with this cache entry - lets remove it:
-		m.CacheStore.DeleteKey(key)
-		return nil, http.StatusOK
+	var wireFormatReq bytes.Buffer
+	resVal.Write(&wireFormatReq)
+
+	if cacheThisRequest && !errCreatingChecksum {
+		log.Debug("Caching request to redis")
+		log.Debug("Cache TTL is:", cacheTTL)
+		ts := m.getTimeTTL(cacheTTL)
+		toStore := m.encodePayload(wireFormatReq.String(), ts)
+		go func() {
+			err := m.CacheStore.SetKey(key, toStore, cacheTTL)
+			if err != nil {
+				log.WithError(err).Error("could not save key in cache store")
+			}
+		}()
 	}
 
-	if m.isTimeStampExpired(timestamp) || len(cachedData) == 0 {
-		m.CacheStore.DeleteKey(key)
-		return nil, http.StatusOK
+	return wireFormatReq.String()
+}
+
+// collapse passes the request through like fetch, unless an identical request is already being
+// passed through, in which case the request is responded with the response of that one when it
+// completes.
+func (m *RedisCacheMiddleware) collapse(w http.ResponseWriter, r *http.Request, key string, isVirtual bool, cacheMeta *EndPointCacheMeta) (error, int) {
+	var passedThrough bool
+	v, _, _ := m.collapseFlight.Do(key, func() (interface{}, error) {
+		passedThrough = true
+		return m.fetch(w, r, key, isVirtual, cacheMeta, false), nil
+	})
+	if passedThrough {
+		return nil, mwStatusRespond
 	}
 
-	log.Debug("Cache got: ", cachedData)
-	bufData := bufio.NewReader(strings.NewReader(cachedData))
+	log.Debug("Request collapsed, responding with the shared response")
+	wireFormatRes := v.(string)
+	if wireFormatRes == "" {
+		return errors.New("There was a problem proxying the request"), http.StatusBadGateway
+	}
+	m.respond(w, r, wireFormatRes)
+	return nil, mwStatusRespond
+}
+
+// respond writes the response in wire format, read from the cache, to w.
+func (m *RedisCacheMiddleware) respond(w http.ResponseWriter, r *http.Request, wireFormatRes string) {
+	log.Debug("Cache got: ", wireFormatRes)
+	bufData := bufio.NewReader(strings.NewReader(wireFormatRes))
 	newRes, err := http.ReadResponse(bufData, r)
 	if err != nil {
 		log.Error("Could not create response object: ", err)
@@ -369,9 +414,6 @@ func (m *RedisCacheMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 	if !m.Spec.DoNotTrack {
 		m.sh.RecordHit(r, Latency{}, newRes.StatusCode, newRes)
 	}
-
-	// Stop any further execution
-	return nil, mwStatusRespond
 }
 
 func isSafeMethod(method string) bool {
@@ -5,8 +5,14 @@ import (
 	"encoding/hex"
 	"hash"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/tyk/config"
 
@@ -67,6 +73,57 @@ func TestRedisCacheMiddleware_WithCompressedResponse(t *testing.T) {
 	})
 }
 
+func TestRedisCacheMiddleware_CollapseRequests(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	var upstreamHits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamHits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow response"))
+	}))
+	defer upstream.Close()
+
+	createAPI := func(collapse bool) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.CacheOptions.CacheTimeout = 60
+			spec.CacheOptions.EnableCache = true
+			spec.CacheOptions.CacheAllSafeRequests = true
+			spec.CacheOptions.CollapseRequests = collapse
+		})
+	}
+
+	runConcurrently := func(t *testing.T, path string) {
+		t.Helper()
+		atomic.StoreInt32(&upstreamHits, 0)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = ts.Run(t, test.TestCase{Path: path, Code: http.StatusOK, BodyMatch: "slow response"})
+			}()
+		}
+		wg.Wait()
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		createAPI(false)
+		runConcurrently(t, "/disabled")
+		assert.Greater(t, atomic.LoadInt32(&upstreamHits), int32(1))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		createAPI(true)
+		runConcurrently(t, "/enabled")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&upstreamHits))
+	})
+}
+
 func Test_isSafeMethod(t *testing.T) {
 	tests := []struct {
 		name     string
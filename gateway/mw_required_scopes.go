@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+// RequiredScopesMiddleware rejects requests to endpoints requiring OAuth scopes which
+// were not granted to the token the request was authenticated with.
+type RequiredScopesMiddleware struct {
+	BaseMiddleware
+}
+
+func (m *RequiredScopesMiddleware) Name() string {
+	return "RequiredScopesMiddleware"
+}
+
+func (m *RequiredScopesMiddleware) EnabledForSpec() bool {
+	for _, version := range m.Spec.VersionData.Versions {
+		if len(version.ExtendedPaths.RequiredScopes) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *RequiredScopesMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	vInfo, _ := m.Spec.Version(r)
+	versionPaths := m.Spec.RxPaths[vInfo.Name]
+	found, meta := m.Spec.CheckSpecMatchesStatus(r, versionPaths, RequiredScopes)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	required := meta.(*apidef.RequiredScopesMeta).Scopes
+	missing := missingScopes(required, ctxGetGrantedScopes(r))
+	if len(missing) == 0 {
+		return nil, http.StatusOK
+	}
+
+	m.Logger().WithField("missing_scopes", missing).Info("Token does not have the scopes required by the endpoint")
+
+	// RFC 6750, section 3.1
+	w.Header().Set(headers.WWWAuthenticate, `Bearer error="insufficient_scope", `+
+		`error_description="The request requires higher privileges than provided by the access token", `+
+		`scope="`+strings.Join(required, " ")+`"`)
+
+	return errors.New("Insufficient scope"), http.StatusForbidden
+}
+
+// missingScopes returns the scopes of required which are not in granted.
+func missingScopes(required, granted []string) []string {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		grantedSet[scope] = true
+	}
+
+	var missing []string
+	for _, scope := range required {
+		if !grantedSet[scope] {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
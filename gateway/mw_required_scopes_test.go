@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestRequiredScopes(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	policyID := ts.CreatePolicy(func(p *user.Policy) {
+		p.AccessRights = map[string]user.AccessDefinition{
+			"scoped-api": {Limit: user.APILimit{QuotaMax: -1}},
+		}
+	})
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "scoped-api"
+		spec.UseKeylessAccess = false
+		spec.EnableJWT = true
+		spec.JWTSigningMethod = RSASign
+		spec.JWTSource = base64.StdEncoding.EncodeToString([]byte(jwtRSAPubKey))
+		spec.JWTIdentityBaseField = "user_id"
+		spec.JWTDefaultPolicies = []string{policyID}
+		spec.Proxy.ListenPath = "/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.RequiredScopes = []apidef.RequiredScopesMeta{
+				{Path: "/users", Method: http.MethodPost, Scopes: []string{"user:read", "user:write"}},
+				{Path: "/users", Method: http.MethodGet, Scopes: []string{"user:read"}},
+			}
+		})
+	})
+
+	authHeaders := func(scope interface{}) map[string]string {
+		token := CreateJWKToken(func(t *jwt.Token) {
+			t.Claims.(jwt.MapClaims)["user_id"] = "user"
+			if scope != nil {
+				t.Claims.(jwt.MapClaims)["scope"] = scope
+			}
+			t.Claims.(jwt.MapClaims)["exp"] = time.Now().Add(time.Hour).Unix()
+		})
+		return map[string]string{"Authorization": token}
+	}
+
+	insufficient := map[string]string{
+		headers.WWWAuthenticate: `Bearer error="insufficient_scope", ` +
+			`error_description="The request requires higher privileges than provided by the access token", ` +
+			`scope="user:read user:write"`,
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/other", Headers: authHeaders(nil), Code: http.StatusOK},
+		{Path: "/users", Headers: authHeaders("user:read"), Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/users", Headers: authHeaders("user:read user:write"), Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/users", Headers: authHeaders([]string{"user:write", "user:read"}), Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/users", Headers: authHeaders("user:read"), Code: http.StatusForbidden,
+			HeadersMatch: insufficient, BodyMatch: "Insufficient scope"},
+		{Method: http.MethodPost, Path: "/users", Headers: authHeaders(nil), Code: http.StatusForbidden,
+			HeadersMatch: insufficient},
+	}...)
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// sessionHeadersData is what the templates of the session headers are rendered with.
+type sessionHeadersData struct {
+	Alias    string
+	OrgID    string
+	KeyHash  string
+	MetaData map[string]string
+	Tags     []string
+	Policies []string
+}
+
+// SessionHeadersMiddleware injects fields of the session of the key into the upstream request
+// headers, declared with templates rather than with a plugin.
+type SessionHeadersMiddleware struct {
+	BaseMiddleware
+
+	templates map[string]*template.Template
+}
+
+func (m *SessionHeadersMiddleware) Name() string {
+	return "SessionHeadersMiddleware"
+}
+
+func (m *SessionHeadersMiddleware) EnabledForSpec() bool {
+	return m.Spec.SessionHeaders.Enabled && len(m.Spec.SessionHeaders.Headers) > 0
+}
+
+func (m *SessionHeadersMiddleware) Init() {
+	funcs := APIDefinitionLoader{Gw: m.Gw}.filterSprigFuncs()
+
+	m.templates = make(map[string]*template.Template, len(m.Spec.SessionHeaders.Headers))
+	for name, text := range m.Spec.SessionHeaders.Headers {
+		tmpl, err := apidef.Template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			m.Logger().WithError(err).WithField("header", name).Error("Invalid session header template")
+			continue
+		}
+		m.templates[name] = tmpl
+	}
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *SessionHeadersMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	var data *sessionHeadersData
+	if session := ctxGetSession(r); session != nil {
+		data = newSessionHeadersData(session)
+	}
+
+	ignoreCanonical := m.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey
+	for name := range m.Spec.SessionHeaders.Headers {
+		// headers sent by the client are never passed through
+		r.Header.Del(name)
+
+		tmpl, ok := m.templates[name]
+		if !ok || data == nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			m.Logger().WithError(err).WithField("header", name).Warning("Could not render session header")
+			continue
+		}
+		if value := sanitizeHeaderValue(buf.String()); value != "" {
+			setCustomHeader(r.Header, name, value, ignoreCanonical)
+		}
+	}
+
+	return nil, http.StatusOK
+}
+
+func newSessionHeadersData(session *user.SessionState) *sessionHeadersData {
+	data := &sessionHeadersData{
+		Alias:    session.Alias,
+		OrgID:    session.OrgID,
+		MetaData: make(map[string]string, len(session.MetaData)),
+		Tags:     session.Tags,
+		Policies: session.PolicyIDs(),
+	}
+	if !session.KeyHashEmpty() {
+		data.KeyHash = session.KeyHash()
+	}
+
+	// metadata which aren't strings are rendered as JSON
+	for key, value := range session.MetaData {
+		if s, ok := value.(string); ok {
+			data.MetaData[key] = s
+		} else if raw, err := json.Marshal(value); err == nil {
+			data.MetaData[key] = string(raw)
+		}
+	}
+	return data
+}
+
+// sanitizeHeaderValue replaces the line breaks of rendered templates, invalid in header values.
+func sanitizeHeaderValue(value string) string {
+	return strings.TrimSpace(strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(value))
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestSessionHeaders(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "session-headers"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+		spec.SessionHeaders = apidef.SessionHeadersConfig{
+			Enabled: true,
+			Headers: map[string]string{
+				"X-Tenant":  "{{.MetaData.tenant}}",
+				"X-Plan":    `{{.MetaData.plan | default "free"}}`,
+				"X-Limits":  "{{.MetaData.limits}}",
+				"X-Alias":   "{{.Alias}}",
+				"X-Missing": "{{.MetaData.missing}}",
+				"X-Invalid": "{{.MetaData.tenant",
+			},
+		}
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.Alias = "acme-app"
+		s.MetaData = map[string]interface{}{
+			"tenant": "acme",
+			"limits": map[string]interface{}{"max": 10},
+		}
+		s.AccessRights = map[string]user.AccessDefinition{"session-headers": {APIID: "session-headers"}}
+	})
+
+	authHeaders := map[string]string{
+		"Authorization": key,
+		"X-Tenant":      "spoofed",
+		"X-Missing":     "spoofed",
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"X-Tenant":"acme"`},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"X-Plan":"free"`},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"X-Limits":"{\\"max\\":10}"`},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"X-Alias":"acme-app"`},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyNotMatch: `"X-Missing"`},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyNotMatch: `spoofed`},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK, BodyNotMatch: `"X-Invalid"`},
+	}...)
+}
+
+func TestSanitizeHeaderValue(t *testing.T) {
+	if got := sanitizeHeaderValue(" a\r\nb\nc "); got != "a b c" {
+		t.Errorf("Expected line breaks to be replaced, got %q", got)
+	}
+}
@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	tokenExchangeGrantType      = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenExchangeJWTType        = "urn:ietf:params:oauth:token-type:jwt"
+	tokenExchangeAccessType     = "urn:ietf:params:oauth:token-type:access_token"
+	defaultTokenExchangeTimeout = 5 * time.Second
+	// tokenExchangeExpirySkew is how long before their expiry the exchanged tokens stop being used,
+	// so that they don't expire on their way to the upstream.
+	tokenExchangeExpirySkew = 10 * time.Second
+)
+
+// tokenExchangeResponse is the response of the security token service, see RFC 8693 section 2.2.
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// TokenExchangeMiddleware exchanges the JWT the request was authenticated with for a token issued
+// for the upstream by a security token service, so that the upstreams receive tokens scoped to
+// them rather than the tokens of the clients.
+type TokenExchangeMiddleware struct {
+	BaseMiddleware
+
+	exchanges singleflight.Group
+}
+
+func (m *TokenExchangeMiddleware) Name() string {
+	return "TokenExchangeMiddleware"
+}
+
+func (m *TokenExchangeMiddleware) EnabledForSpec() bool {
+	conf := m.Spec.TokenExchange
+	return conf.Enabled && conf.TokenEndpoint != "" && m.Spec.EnableJWT
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (m *TokenExchangeMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	if ctxGetRequestStatus(r) == StatusOkAndIgnore {
+		return nil, http.StatusOK
+	}
+
+	token := ctxGetJWTToken(r)
+	if token == nil {
+		return errors.New("Key not authorized"), http.StatusUnauthorized
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+
+	// tokens without subject are cached by themselves
+	subject, _ := claims[SUB].(string)
+	if subject == "" {
+		subject = storage.HashStr(token.Raw)
+	}
+	cacheKey := "token-exchange-" + m.Spec.APIID + "-" + m.Spec.TokenExchange.Audience + "-" + subject
+
+	var accessToken string
+	if cached, found := m.Gw.UtilCache.Get(cacheKey); found {
+		accessToken = cached.(string)
+	} else {
+		v, err, _ := m.exchanges.Do(cacheKey, func() (interface{}, error) {
+			res, err := m.exchange(token.Raw)
+			if err != nil {
+				return "", err
+			}
+			if ttl := m.cacheTTL(res, claims); ttl > 0 {
+				m.Gw.UtilCache.Set(cacheKey, res.AccessToken, ttl)
+			}
+			return res.AccessToken, nil
+		})
+		if err != nil {
+			m.Logger().WithError(err).Error("Token exchange failed")
+			if errors.Is(err, errTokenExchangeDenied) {
+				return errors.New("Access to the upstream denied"), http.StatusForbidden
+			}
+			return errors.New("Couldn't obtain a token for the upstream"), http.StatusBadGateway
+		}
+		accessToken = v.(string)
+	}
+
+	header := m.Spec.TokenExchange.Header
+	if header == "" || strings.EqualFold(header, headers.Authorization) {
+		r.Header.Set(headers.Authorization, "Bearer "+accessToken)
+	} else {
+		setCustomHeader(r.Header, header, accessToken, m.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
+	}
+
+	return nil, http.StatusOK
+}
+
+// errTokenExchangeDenied is returned when the security token service refuses the exchange.
+var errTokenExchangeDenied = errors.New("token exchange denied")
+
+// exchange requests a token for the upstream in exchange of subjectToken.
+func (m *TokenExchangeMiddleware) exchange(subjectToken string) (*tokenExchangeResponse, error) {
+	conf := m.Spec.TokenExchange
+
+	form := url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {tokenExchangeJWTType},
+	}
+	if conf.Audience != "" {
+		form.Set("audience", conf.Audience)
+	}
+	if conf.Resource != "" {
+		form.Set("resource", conf.Resource)
+	}
+	if len(conf.Scopes) > 0 {
+		form.Set("scope", strings.Join(conf.Scopes, " "))
+	}
+	requestedType := conf.RequestedTokenType
+	if requestedType == "" {
+		requestedType = tokenExchangeAccessType
+	}
+	form.Set("requested_token_type", requestedType)
+
+	req, err := http.NewRequest(http.MethodPost, conf.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(headers.ContentType, "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", headers.ApplicationJSON)
+	if conf.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(conf.ClientSecret))
+	}
+
+	timeout := defaultTokenExchangeTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout * float64(time.Second))
+	}
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusBadRequest, res.StatusCode == http.StatusUnauthorized, res.StatusCode == http.StatusForbidden:
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&oauthErr)
+		return nil, fmt.Errorf("%w: status %d %s %s", errTokenExchangeDenied, res.StatusCode, oauthErr.Error, oauthErr.Description)
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("security token service returned status %d", res.StatusCode)
+	}
+
+	var exchanged tokenExchangeResponse
+	if err := json.NewDecoder(res.Body).Decode(&exchanged); err != nil {
+		return nil, err
+	}
+	if exchanged.AccessToken == "" {
+		return nil, errors.New("security token service returned no token")
+	}
+	return &exchanged, nil
+}
+
+// cacheTTL returns how long the exchanged token can be used for, no longer than the token it was
+// exchanged for. Tokens without expiry aren't cached.
+func (m *TokenExchangeMiddleware) cacheTTL(res *tokenExchangeResponse, claims jwt.MapClaims) time.Duration {
+	if res.ExpiresIn <= 0 {
+		return 0
+	}
+	ttl := time.Duration(res.ExpiresIn)*time.Second - tokenExchangeExpirySkew
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if untilExp := time.Until(time.Unix(int64(exp), 0)); untilExp < ttl {
+			ttl = untilExp
+		}
+	}
+	return ttl
+}
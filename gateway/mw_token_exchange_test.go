@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestTokenExchange(t *testing.T) {
+	var exchanges int64
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&exchanges, 1)
+
+		clientID, secret, _ := r.BasicAuth()
+		assert.Equal(t, "gateway", clientID)
+		assert.Equal(t, "secret", secret)
+		assert.Equal(t, tokenExchangeGrantType, r.FormValue("grant_type"))
+		assert.Equal(t, tokenExchangeJWTType, r.FormValue("subject_token_type"))
+		assert.Equal(t, tokenExchangeAccessType, r.FormValue("requested_token_type"))
+		assert.Equal(t, "orders read", r.FormValue("scope"))
+
+		if r.FormValue("audience") != "orders" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_target"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(tokenExchangeResponse{
+			AccessToken:     "upstream-" + r.FormValue("subject_token"),
+			IssuedTokenType: tokenExchangeAccessType,
+			TokenType:       "Bearer",
+			ExpiresIn:       300,
+		})
+	}))
+	defer sts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	newMiddleware := func(conf apidef.TokenExchangeConfig) *TokenExchangeMiddleware {
+		conf.Enabled = true
+		conf.TokenEndpoint = sts.URL
+		conf.ClientID = "gateway"
+		conf.ClientSecret = "secret"
+		conf.Scopes = []string{"orders", "read"}
+		spec := &APISpec{APIDefinition: &apidef.APIDefinition{APIID: "orders-api", EnableJWT: true, TokenExchange: conf}}
+		return &TokenExchangeMiddleware{BaseMiddleware: BaseMiddleware{Spec: spec, Gw: gw}}
+	}
+	request := func(raw string, claims jwt.MapClaims) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if raw != "" {
+			ctxSetJWTToken(r, &jwt.Token{Raw: raw, Claims: claims})
+		}
+		return r
+	}
+
+	mw := newMiddleware(apidef.TokenExchangeConfig{Audience: "orders"})
+	assert.True(t, mw.EnabledForSpec())
+
+	t.Run("Exchanged", func(t *testing.T) {
+		r := request("alice-1", jwt.MapClaims{"sub": "alice"})
+		err, code := mw.ProcessRequest(nil, r, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "Bearer upstream-alice-1", r.Header.Get("Authorization"))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&exchanges))
+	})
+
+	t.Run("CachedPerSubject", func(t *testing.T) {
+		r := request("alice-2", jwt.MapClaims{"sub": "alice"})
+		err, _ := mw.ProcessRequest(nil, r, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer upstream-alice-1", r.Header.Get("Authorization"))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&exchanges))
+
+		r = request("bob-1", jwt.MapClaims{"sub": "bob"})
+		err, _ = mw.ProcessRequest(nil, r, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer upstream-bob-1", r.Header.Get("Authorization"))
+		assert.Equal(t, int64(2), atomic.LoadInt64(&exchanges))
+	})
+
+	t.Run("CustomHeader", func(t *testing.T) {
+		mw := newMiddleware(apidef.TokenExchangeConfig{Audience: "orders", Header: "X-Upstream-Token"})
+		r := request("carol-1", jwt.MapClaims{"sub": "carol"})
+		r.Header.Set("Authorization", "Bearer carol-1")
+		err, _ := mw.ProcessRequest(nil, r, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "upstream-carol-1", r.Header.Get("X-Upstream-Token"))
+		assert.Equal(t, "Bearer carol-1", r.Header.Get("Authorization"))
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		mw := newMiddleware(apidef.TokenExchangeConfig{Audience: "payments"})
+		err, code := mw.ProcessRequest(nil, request("alice-1", jwt.MapClaims{"sub": "alice"}), nil)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		mw := newMiddleware(apidef.TokenExchangeConfig{Audience: "orders"})
+		mw.Spec.TokenExchange.TokenEndpoint = "http://127.0.0.1:0/token"
+		err, code := mw.ProcessRequest(nil, request("dave-1", jwt.MapClaims{"sub": "dave"}), nil)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadGateway, code)
+	})
+
+	t.Run("NoJWT", func(t *testing.T) {
+		err, code := mw.ProcessRequest(nil, request("", nil), nil)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
+
+func TestTokenExchange_CacheTTL(t *testing.T) {
+	mw := &TokenExchangeMiddleware{}
+
+	assert.Zero(t, mw.cacheTTL(&tokenExchangeResponse{}, jwt.MapClaims{}))
+	assert.Equal(t, 290*time.Second, mw.cacheTTL(&tokenExchangeResponse{ExpiresIn: 300}, jwt.MapClaims{}))
+
+	// the exchanged token isn't used after the token it was exchanged for expired
+	exp := float64(time.Now().Add(time.Minute).Unix())
+	ttl := mw.cacheTTL(&tokenExchangeResponse{ExpiresIn: 300}, jwt.MapClaims{"exp": exp})
+	assert.True(t, ttl <= time.Minute && ttl > 58*time.Second, ttl)
+}
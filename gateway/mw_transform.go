@@ -52,6 +52,11 @@ func (t *TransformMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Requ
 }
 
 func transformBody(r *http.Request, tmeta *TransformSpec, t *TransformMiddleware) error {
+	switch tmeta.TemplateData.Mode {
+	case apidef.UseJSONToXML, apidef.UseXMLToJSON:
+		return convertRequestBody(r, tmeta.TemplateData)
+	}
+
 	body, _ := ioutil.ReadAll(r.Body)
 	defer r.Body.Close()
 
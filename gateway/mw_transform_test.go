@@ -2,7 +2,9 @@ package gateway
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"io/ioutil"
+	"net/http"
 	"strings"
 	"testing"
 	"text/template"
@@ -347,3 +349,38 @@ func TestBodyTransformCaseSensitivity(t *testing.T) {
 		assert("/Get", "/Get", `{"http_method":"GET"}`)
 	})
 }
+
+func TestBodyConversion(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.ResponseProcessors = []apidef.ResponseProcessor{{Name: "response_body_transform"}}
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.ExtendedPaths.Transform = []apidef.TemplateMeta{
+				{Path: "/xml", Method: "POST", TemplateData: apidef.TemplateData{Mode: apidef.UseXMLToJSON}},
+				{Path: "/json", Method: "POST", TemplateData: apidef.TemplateData{Mode: apidef.UseJSONToXML}},
+			}
+			v.ExtendedPaths.TransformResponse = []apidef.TemplateMeta{
+				{Path: "/get", Method: "GET", TemplateData: apidef.TemplateData{Mode: apidef.UseJSONToXML, TemplateSource: "response"}},
+			}
+		})
+	})
+
+	bodyMatch := func(want, contentType string) func([]byte) bool {
+		return func(body []byte) bool {
+			var resp TestHttpResponse
+			return json.Unmarshal(body, &resp) == nil && resp.Body == want && resp.Headers["Content-Type"] == contentType
+		}
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: "POST", Path: "/xml", Data: `<order id="1"><item>a</item><item>b</item></order>`, Code: http.StatusOK,
+			BodyMatchFunc: bodyMatch(`{"order":{"-id":"1","item":["a","b"]}}`, "application/json")},
+		{Method: "POST", Path: "/json", Data: `{"order":{"-id":"1","item":["a","b"]}}`, Code: http.StatusOK,
+			BodyMatchFunc: bodyMatch(`<order id="1"><item>a</item><item>b</item></order>`, "application/xml")},
+		{Method: "GET", Path: "/get", Code: http.StatusOK, BodyMatch: `^<response><Method>GET</Method>`,
+			HeadersMatch: map[string]string{"Content-Type": "application/xml"}},
+	}...)
+}
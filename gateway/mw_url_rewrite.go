@@ -476,13 +476,14 @@ func (m *URLRewriteMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 	//To get host and query parameters
 	ctxSetOrigRequestURL(r, r.URL)
 
-	log.Debug("Rewriter active")
+	logger := m.Logger()
+	logger.Debug("Rewriter active")
 	umeta := meta.(*apidef.URLRewriteMeta)
-	log.Debug(r.URL)
+	logger.Debug(r.URL)
 	oldPath := r.URL.String()
 	p, err := m.Gw.urlRewrite(umeta, r)
 	if err != nil {
-		log.Error(err)
+		logger.Error(err)
 		return err, http.StatusInternalServerError
 	}
 
@@ -499,7 +500,7 @@ func (m *URLRewriteMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 
 	newURL, err := url.Parse(p)
 	if err != nil {
-		log.Error("URL Rewrite failed, could not parse: ", p)
+		logger.Error("URL Rewrite failed, could not parse: ", p)
 	} else {
 		//Setting new path here breaks request middleware
 		//New path is set in DummyProxyHandler/Cache middleware
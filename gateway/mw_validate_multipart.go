@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+// ValidateMultipart validates the parts of multipart uploads while their body is streamed to the
+// upstream, aborting the uploads violating the limits of the endpoint.
+type ValidateMultipart struct {
+	BaseMiddleware
+}
+
+func (k *ValidateMultipart) Name() string {
+	return "ValidateMultipart"
+}
+
+func (k *ValidateMultipart) EnabledForSpec() bool {
+	return k.Spec.streamsMultipart()
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (k *ValidateMultipart) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	versionInfo, _ := k.Spec.Version(r)
+	versionPaths := k.Spec.RxPaths[versionInfo.Name]
+	found, meta := k.Spec.CheckSpecMatchesStatus(r, versionPaths, ValidateMultipartRequest)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	boundary, ok := multipartBoundary(r)
+	if !ok {
+		return errors.New("Request is not a multipart upload"), http.StatusUnsupportedMediaType
+	}
+
+	if r.Body != nil {
+		r.Body = newMultipartValidator(r.Body, boundary, meta.(*apidef.ValidateMultipartMeta))
+	}
+
+	return nil, http.StatusOK
+}
+
+// streamsMultipart reports whether the multipart uploads to the API are streamed to the upstream
+// rather than buffered.
+func (a *APISpec) streamsMultipart() bool {
+	for _, version := range a.VersionData.Versions {
+		for _, meta := range version.ExtendedPaths.ValidateMultipart {
+			if !meta.Disabled {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// multipartBoundary returns the boundary of the parts of r if it is a multipart upload.
+func multipartBoundary(r *http.Request) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get(headers.ContentType))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return "", false
+	}
+
+	return params["boundary"], true
+}
+
+// multipartError is a violation of the limits of a multipart upload, failing the proxying of its
+// body with its status code.
+type multipartError struct {
+	code int
+	msg  string
+}
+
+func (e *multipartError) Error() string {
+	return e.msg
+}
+
+// multipartValidator is the body of a multipart upload, which validates the parts read through it
+// without buffering them.
+type multipartValidator struct {
+	body io.ReadCloser
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+func newMultipartValidator(body io.ReadCloser, boundary string, meta *apidef.ValidateMultipartMeta) *multipartValidator {
+	pr, pw := io.Pipe()
+	v := &multipartValidator{body: body, pw: pw, done: make(chan struct{})}
+
+	go func() {
+		defer close(v.done)
+		if v.err = validateMultipart(multipart.NewReader(pr, boundary), meta); v.err != nil {
+			_ = pr.CloseWithError(v.err)
+			return
+		}
+		// the epilogue after the last part
+		_, _ = io.Copy(ioutil.Discard, pr)
+	}()
+
+	return v
+}
+
+func (v *multipartValidator) Read(p []byte) (int, error) {
+	n, err := v.body.Read(p)
+	if n > 0 {
+		if _, werr := v.pw.Write(p[:n]); werr != nil {
+			return 0, werr
+		}
+	}
+
+	if err == io.EOF {
+		_ = v.pw.Close()
+		<-v.done
+		if v.err != nil {
+			return 0, v.err
+		}
+	}
+
+	return n, err
+}
+
+func (v *multipartValidator) Close() error {
+	_ = v.pw.Close()
+	return v.body.Close()
+}
+
+func validateMultipart(mr *multipart.Reader, meta *apidef.ValidateMultipartMeta) error {
+	for count := 1; ; count++ {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &multipartError{code: http.StatusBadRequest, msg: "Malformed multipart body"}
+		}
+
+		if meta.MaxParts > 0 && count > meta.MaxParts {
+			return &multipartError{
+				code: http.StatusRequestEntityTooLarge,
+				msg:  fmt.Sprintf("Multipart body exceeds the limit of %d parts", meta.MaxParts),
+			}
+		}
+
+		// only the content type of files is checked, form fields are text
+		if part.FileName() != "" && !multipartTypeAllowed(part.Header.Get(headers.ContentType), meta.AllowedContentTypes) {
+			return &multipartError{
+				code: http.StatusUnsupportedMediaType,
+				msg:  fmt.Sprintf("Content type of part %q is not allowed", part.FormName()),
+			}
+		}
+
+		var src io.Reader = part
+		if meta.MaxPartSize > 0 {
+			src = io.LimitReader(part, meta.MaxPartSize+1)
+		}
+		n, err := io.Copy(ioutil.Discard, src)
+		if err != nil {
+			return &multipartError{code: http.StatusBadRequest, msg: "Malformed multipart body"}
+		}
+		if meta.MaxPartSize > 0 && n > meta.MaxPartSize {
+			return &multipartError{
+				code: http.StatusRequestEntityTooLarge,
+				msg:  fmt.Sprintf("Part %q exceeds the limit of %d bytes", part.FormName(), meta.MaxPartSize),
+			}
+		}
+	}
+}
+
+func multipartTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	// the default content type of files
+	mediaType := "application/octet-stream"
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return false
+		}
+	}
+
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mediaType || a == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+type testMultipartPart struct {
+	name, fileName, contentType, content string
+}
+
+func testMultipartBody(t *testing.T, parts ...testMultipartPart) (string, map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		h := textproto.MIMEHeader{}
+		disposition := `form-data; name="` + part.name + `"`
+		if part.fileName != "" {
+			disposition += `; filename="` + part.fileName + `"`
+		}
+		h.Set("Content-Disposition", disposition)
+		if part.contentType != "" {
+			h.Set("Content-Type", part.contentType)
+		}
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(part.content))
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String(), map[string]string{"Content-Type": mw.FormDataContentType()}
+}
+
+func TestValidateMultipart(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.ValidateMultipart = []apidef.ValidateMultipartMeta{{
+				Path:                "/upload",
+				Method:              http.MethodPost,
+				MaxPartSize:         10,
+				MaxParts:            2,
+				AllowedContentTypes: []string{"image/*", "application/pdf"},
+			}}
+		})
+	})
+
+	valid, validHeaders := testMultipartBody(t,
+		testMultipartPart{name: "title", content: "holidays"},
+		testMultipartPart{name: "file", fileName: "a.png", contentType: "image/png", content: "png-image"},
+	)
+	tooLarge, tooLargeHeaders := testMultipartBody(t,
+		testMultipartPart{name: "file", fileName: "a.pdf", contentType: "application/pdf", content: "a large pdf document"},
+	)
+	wrongType, wrongTypeHeaders := testMultipartBody(t,
+		testMultipartPart{name: "file", fileName: "a.exe", contentType: "application/x-msdownload", content: "exe"},
+	)
+	noType, noTypeHeaders := testMultipartBody(t,
+		testMultipartPart{name: "file", fileName: "a.bin", content: "bin"},
+	)
+	tooMany, tooManyHeaders := testMultipartBody(t,
+		testMultipartPart{name: "a", content: "a"},
+		testMultipartPart{name: "b", content: "b"},
+		testMultipartPart{name: "c", content: "c"},
+	)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/upload", Data: valid, Headers: validHeaders, Code: http.StatusOK, BodyMatch: "png-image"},
+		{Method: http.MethodPost, Path: "/upload", Data: tooLarge, Headers: tooLargeHeaders, Code: http.StatusRequestEntityTooLarge,
+			BodyMatch: `Part \\"file\\" exceeds the limit of 10 bytes`},
+		{Method: http.MethodPost, Path: "/upload", Data: wrongType, Headers: wrongTypeHeaders, Code: http.StatusUnsupportedMediaType,
+			BodyMatch: `Content type of part \\"file\\" is not allowed`},
+		{Method: http.MethodPost, Path: "/upload", Data: noType, Headers: noTypeHeaders, Code: http.StatusUnsupportedMediaType},
+		{Method: http.MethodPost, Path: "/upload", Data: tooMany, Headers: tooManyHeaders, Code: http.StatusRequestEntityTooLarge,
+			BodyMatch: "Multipart body exceeds the limit of 2 parts"},
+		{Method: http.MethodPost, Path: "/upload", Data: valid[:len(valid)-10], Headers: validHeaders, Code: http.StatusBadRequest,
+			BodyMatch: "Malformed multipart body"},
+		{Method: http.MethodPost, Path: "/upload", Data: "{}", Code: http.StatusUnsupportedMediaType,
+			BodyMatch: "Request is not a multipart upload"},
+		{Method: http.MethodPost, Path: "/other", Data: tooLarge, Headers: tooLargeHeaders, Code: http.StatusOK},
+	}...)
+
+	t.Run("body isn't buffered", func(t *testing.T) {
+		conf := ts.Gw.GetConfig()
+		h := &handleWrapper{ts.Gw.DefaultProxyMux.router(conf.ListenPort, "", conf)}
+
+		r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(valid))
+		r.Header.Set("Content-Type", validHeaders["Content-Type"])
+		assert.True(t, h.streamsRequestBody(r))
+
+		r.Header.Set("Content-Type", "application/json")
+		assert.False(t, h.streamsRequestBody(r))
+	})
+}
+
+func TestMultipartTypeAllowed(t *testing.T) {
+	assert.True(t, multipartTypeAllowed("image/png", nil))
+	assert.True(t, multipartTypeAllowed("image/png", []string{"image/*"}))
+	assert.True(t, multipartTypeAllowed("Image/PNG; charset=binary", []string{"image/png"}))
+	assert.True(t, multipartTypeAllowed("", []string{"application/octet-stream"}))
+	assert.False(t, multipartTypeAllowed("imagery/png", []string{"image/*"}))
+	assert.False(t, multipartTypeAllowed("text/plain", []string{"image/*"}))
+	assert.False(t, multipartTypeAllowed("image/", []string{"image/*"}))
+}
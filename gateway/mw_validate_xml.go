@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// ValidateXMLSpec is the compiled XML schema of an endpoint.
+type ValidateXMLSpec struct {
+	apidef.ValidateXMLMeta
+	schema *xmlSchema
+}
+
+// ValidateXML validates the XML body of requests against the XML schema of the endpoint
+type ValidateXML struct {
+	BaseMiddleware
+}
+
+func (k *ValidateXML) Name() string {
+	return "ValidateXML"
+}
+
+func (k *ValidateXML) EnabledForSpec() bool {
+	for _, v := range k.Spec.VersionData.Versions {
+		if len(v.ExtendedPaths.ValidateXML) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
+func (k *ValidateXML) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	versionInfo, _ := k.Spec.Version(r)
+	versionPaths := k.Spec.RxPaths[versionInfo.Name]
+	found, meta := k.Spec.CheckSpecMatchesStatus(r, versionPaths, ValidateXMLRequest)
+	if !found {
+		return nil, http.StatusOK
+	}
+
+	spec := meta.(*ValidateXMLSpec)
+	if spec.schema == nil {
+		return errors.New("no schemas to validate against"), http.StatusInternalServerError
+	}
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	defer r.Body.Close()
+
+	if err := spec.schema.validate(bodyBytes); err != nil {
+		if errors.Is(err, errXMLNotWellFormed) {
+			return err, http.StatusBadRequest
+		}
+
+		errorResponseCode := spec.ErrorResponseCode
+		if errorResponseCode == 0 {
+			errorResponseCode = http.StatusUnprocessableEntity
+		}
+		return err, errorResponseCode
+	}
+
+	return nil, http.StatusOK
+}
+
+// validateXMLSchemas returns the first XML schema of the versions of def which can't be compiled.
+func validateXMLSchemas(def *apidef.APIDefinition) error {
+	for _, version := range def.VersionData.Versions {
+		for _, meta := range version.ExtendedPaths.ValidateXML {
+			if _, err := compileXMLSchema([]byte(meta.Schema)); err != nil {
+				return fmt.Errorf("%s %s: %v", meta.Method, meta.Path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestValidateXMLSchema(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.ExtendedPaths.ValidateXML = []apidef.ValidateXMLMeta{
+				{Path: "/orders", Method: http.MethodPost, Schema: testXMLSchema},
+				{Path: "/orders/{id}", Method: http.MethodPut, Schema: testXMLSchema, ErrorResponseCode: http.StatusBadRequest},
+			}
+		})
+	})
+
+	validOrder := `<order xmlns="urn:orders" currency="EUR"><id>ORD-0001</id><placed>2021-06-30</placed>` +
+		`<customer><email>a@example.com</email></customer><item quantity="1">apple</item></order>`
+	invalidOrder := `<order xmlns="urn:orders" currency="USD"><id>ORD-0001</id></order>`
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/without_validation", Data: "<not_valid>", Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/orders", Data: validOrder, Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/orders", Data: invalidOrder, Code: http.StatusUnprocessableEntity,
+			BodyMatch: `/order: attribute \\"currency\\": value \\"USD\\" is not one of EUR, GBP; /order: missing element`},
+		{Method: http.MethodPost, Path: "/orders", Data: "<order>", Code: http.StatusBadRequest, BodyMatch: "XML parsing error"},
+		{Method: http.MethodPut, Path: "/orders/1", Data: invalidOrder, Code: http.StatusBadRequest},
+	}...)
+}
+
+func TestValidateXMLSchemas(t *testing.T) {
+	def := &apidef.APIDefinition{}
+	def.VersionData.Versions = map[string]apidef.VersionInfo{"v1": {}}
+	v := def.VersionData.Versions["v1"]
+	v.ExtendedPaths.ValidateXML = []apidef.ValidateXMLMeta{{Path: "/orders", Method: http.MethodPost, Schema: testXMLSchema}}
+	def.VersionData.Versions["v1"] = v
+	assert.NoError(t, validateXMLSchemas(def))
+
+	v.ExtendedPaths.ValidateXML = append(v.ExtendedPaths.ValidateXML, apidef.ValidateXMLMeta{Path: "/broken", Method: http.MethodPost, Schema: "<schema/>"})
+	def.VersionData.Versions["v1"] = v
+	assert.EqualError(t, validateXMLSchemas(def), "POST /broken: root element is not an XML schema")
+}
@@ -50,6 +50,11 @@ func (v *VersionCheck) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 			Origin: request.RealIP(r),
 			Reason: string(stat),
 		})
+
+		if stat == EndPointNotFound {
+			return errors.New(string(stat)), http.StatusNotFound
+		}
+
 		return errors.New(string(stat)), http.StatusForbidden
 	}
 
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// WasmMiddleware runs a WebAssembly plugin on the request. The plugin may change the headers and
+// the body of the request, or answer it instead of the upstream.
+type WasmMiddleware struct {
+	BaseMiddleware
+	Definition     apidef.MiddlewareDefinition
+	plugin         *wasmPlugin
+	logger         *logrus.Entry
+	successHandler *SuccessHandler // to record analytics
+}
+
+func (m *WasmMiddleware) Name() string {
+	return "WasmMiddleware: " + m.Definition.Path + ":" + m.Definition.Name
+}
+
+func (m *WasmMiddleware) EnabledForSpec() bool {
+	m.logger = log.WithFields(logrus.Fields{
+		"prefix":       "wasm",
+		"mwPath":       m.Definition.Path,
+		"mwSymbolName": m.Definition.Name,
+	})
+
+	var err error
+	if m.plugin, err = loadWasmPlugin(m.Definition); err != nil {
+		m.logger.WithError(err).Error("Could not load wasm plugin")
+		return false
+	}
+	m.Spec.wasmPlugins = append(m.Spec.wasmPlugins, m.plugin)
+
+	m.successHandler = &SuccessHandler{BaseMiddleware: m.BaseMiddleware}
+	return true
+}
+
+func (m *WasmMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	// make sure request's body can be re-read again
+	nopCloseRequestBody(r)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+
+	call := &wasmCall{
+		method:  r.Method,
+		path:    r.URL.Path,
+		header:  r.Header,
+		body:    body,
+		session: ctxGetSession(r),
+		logger:  m.logger,
+	}
+
+	t1 := time.Now()
+	err = m.plugin.run(r.Context(), call)
+	ms := DurationToMillisecond(time.Since(t1))
+	m.logger.WithField("ms", ms).Debug("Wasm plugin request processing took")
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to process request with wasm plugin")
+		return errors.New("wasm plugin failed"), http.StatusInternalServerError
+	}
+
+	if call.sessionChanged {
+		ctxScheduleSessionUpdate(r)
+	}
+
+	if call.bodyChanged {
+		r.Body = ioutil.NopCloser(bytes.NewReader(call.body))
+		r.ContentLength = int64(len(call.body))
+		nopCloseRequestBody(r)
+	}
+
+	if !call.responded {
+		return nil, http.StatusOK
+	}
+
+	if call.responseCode < 100 || call.responseCode > 599 {
+		m.logger.WithField("code", call.responseCode).Error("Wasm plugin responded with an invalid status code")
+		return errors.New("wasm plugin failed"), http.StatusInternalServerError
+	}
+
+	if call.responseCode >= http.StatusBadRequest {
+		// base middleware will report this error to analytics
+		return errors.New(string(call.responseBody)), call.responseCode
+	}
+
+	rw := &customResponseWriter{
+		ResponseWriter: w,
+		copyData:       recordDetail(r, m.Spec),
+	}
+	rw.WriteHeader(call.responseCode)
+	rw.Write(call.responseBody)
+	m.successHandler.RecordHit(r, Latency{Total: int64(ms)}, rw.statusCodeSent, rw.getHttpResponse(r))
+
+	// no need to continue passing this request down to reverse proxy
+	return nil, mwStatusRespond
+}
@@ -0,0 +1,260 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// testWasmPlugin is a hand assembled wasm module importing the host ABI, exporting:
+// add_header, copy_header, deny, rewrite, spin, grow, tag_session, session_header and bad_status.
+var testWasmPlugin = func() []byte {
+	// strings of the data segment, by offset
+	data := make([]byte, 128)
+	for offset, s := range map[int]string{0: "X-Wasm", 16: "hello", 32: "X-In", 48: "X-Out", 64: "denied", 80: "rewritten",
+		96: "plan", 104: "gold", 112: "X-Plan"} {
+		copy(data[offset:], s)
+	}
+
+	i32 := byte(0x7f)
+	const0 := func(v int32) []byte { return append([]byte{0x41}, wasmLEB(int64(v))...) }
+	call := func(f byte) []byte { return []byte{0x10, f} }
+	concat := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	bodies := [][]byte{
+		// add_header: set_header("X-Wasm", "hello")
+		concat(const0(0), const0(6), const0(16), const0(5), call(0)),
+		// copy_header: set_header("X-Out", get_header("X-In"))
+		concat(const0(48), const0(5), const0(128), const0(32), const0(4), const0(128), const0(64), call(1), call(0)),
+		// deny: respond(403, "denied")
+		concat(const0(403), const0(64), const0(6), call(2)),
+		// rewrite: set_body("rewritten")
+		concat(const0(80), const0(9), call(3)),
+		// spin: loop forever
+		{0x03, 0x40, 0x0c, 0x00, 0x0b},
+		// grow: trap when 100 pages can't be allocated
+		concat(const0(100), []byte{0x40, 0x00}, const0(-1), []byte{0x46, 0x04, 0x40, 0x00, 0x0b}),
+		// tag_session: set_session_meta("plan", "gold")
+		concat(const0(96), const0(4), const0(104), const0(4), call(5)),
+		// session_header: set_header("X-Plan", get_session_meta("plan"))
+		concat(const0(112), const0(6), const0(192), const0(96), const0(4), const0(192), const0(32), call(4), call(0)),
+		// bad_status: respond(1000, "denied")
+		concat(const0(1000), const0(64), const0(6), call(2)),
+	}
+	exports := []string{"add_header", "copy_header", "deny", "rewrite", "spin", "grow", "tag_session", "session_header", "bad_status"}
+
+	types := wasmVec(
+		concat([]byte{0x60}, wasmVec([]byte{i32}, []byte{i32}, []byte{i32}, []byte{i32}), wasmVec()),
+		concat([]byte{0x60}, wasmVec([]byte{i32}, []byte{i32}, []byte{i32}, []byte{i32}), wasmVec([]byte{i32})),
+		concat([]byte{0x60}, wasmVec([]byte{i32}, []byte{i32}, []byte{i32}), wasmVec()),
+		concat([]byte{0x60}, wasmVec([]byte{i32}, []byte{i32}), wasmVec()),
+		concat([]byte{0x60}, wasmVec(), wasmVec()),
+	)
+	var imports [][]byte
+	for _, imp := range []struct {
+		name string
+		typ  byte
+	}{{"set_header", 0}, {"get_header", 1}, {"respond", 2}, {"set_body", 3}, {"get_session_meta", 1}, {"set_session_meta", 0}} {
+		imports = append(imports, concat(wasmName(wasmHostModule), wasmName(imp.name), []byte{0x00, imp.typ}))
+	}
+	var funcs, exported, code [][]byte
+	for i, body := range bodies {
+		funcs = append(funcs, []byte{4})
+		exported = append(exported, concat(wasmName(exports[i]), []byte{0x00, byte(len(imports) + i)}))
+		body = concat([]byte{0x00}, body, []byte{0x0b})
+		code = append(code, concat(wasmLEB(int64(len(body))), body))
+	}
+	exported = append(exported, concat(wasmName("memory"), []byte{0x02, 0x00}))
+
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		wasmSection(1, types),
+		wasmSection(2, wasmVec(imports...)),
+		wasmSection(3, wasmVec(funcs...)),
+		wasmSection(5, wasmVec([]byte{0x00, 0x01})),
+		wasmSection(7, wasmVec(exported...)),
+		wasmSection(10, wasmVec(code...)),
+		wasmSection(11, wasmVec(concat([]byte{0x00}, const0(0), []byte{0x0b}, wasmName(string(data))))),
+	)
+}()
+
+func wasmLEB(v int64) (out []byte) {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func wasmVec(items ...[]byte) []byte {
+	return append(wasmLEB(int64(len(items))), bytes.Join(items, nil)...)
+}
+
+func wasmName(name string) []byte {
+	return append(wasmLEB(int64(len(name))), name...)
+}
+
+func wasmSection(id byte, content []byte) []byte {
+	return append(append([]byte{id}, wasmLEB(int64(len(content)))...), content...)
+}
+
+func TestWasmMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	pluginPath := filepath.Join(t.TempDir(), "plugin.wasm")
+	if err := ioutil.WriteFile(pluginPath, testWasmPlugin, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(section apidef.MiddlewareSection) {
+		section.Driver = apidef.WasmDriver
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.CustomMiddleware = section
+		})
+	}
+	plugin := func(name string) apidef.MiddlewareDefinition {
+		return apidef.MiddlewareDefinition{Name: name, Path: pluginPath}
+	}
+
+	t.Run("request headers", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{plugin("add_header"), plugin("copy_header")}})
+
+		_, _ = ts.Run(t, test.TestCase{
+			Headers:   map[string]string{"X-In": "copied"},
+			Code:      http.StatusOK,
+			BodyMatch: `"X-Wasm":"hello".*"X-Out":"copied"|"X-Out":"copied".*"X-Wasm":"hello"`,
+		})
+	})
+
+	t.Run("request body", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Post: []apidef.MiddlewareDefinition{plugin("rewrite")}})
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Data: "original", Code: http.StatusOK, BodyMatch: `"Body":"rewritten"`})
+	})
+
+	t.Run("respond", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{plugin("deny")}})
+
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusForbidden, BodyMatch: "denied"})
+
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{plugin("bad_status")}})
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+
+	t.Run("time limit", func(t *testing.T) {
+		spin := plugin("spin")
+		spin.Timeout = 50
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{spin}})
+
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+
+	t.Run("memory limit", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{plugin("grow")}})
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusOK})
+
+		grow := plugin("grow")
+		grow.MaxMemoryPages = 10
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{grow}})
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+
+	t.Run("response", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Response: []apidef.MiddlewareDefinition{plugin("add_header"), plugin("rewrite")}})
+
+		_, _ = ts.Run(t, test.TestCase{
+			Code:         http.StatusOK,
+			HeadersMatch: map[string]string{"X-Wasm": "hello"},
+			BodyMatch:    `^rewritten$`,
+		})
+	})
+
+	t.Run("response status", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Response: []apidef.MiddlewareDefinition{plugin("deny")}})
+
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusForbidden, BodyMatch: `^denied$`})
+
+		load(apidef.MiddlewareSection{Response: []apidef.MiddlewareDefinition{plugin("bad_status")}})
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+
+	t.Run("session", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "wasm-session"
+			spec.Proxy.ListenPath = "/"
+			spec.UseKeylessAccess = false
+			spec.CustomMiddleware = apidef.MiddlewareSection{
+				Driver:      apidef.WasmDriver,
+				PostKeyAuth: []apidef.MiddlewareDefinition{plugin("session_header"), plugin("tag_session")},
+				Response:    []apidef.MiddlewareDefinition{plugin("session_header"), plugin("tag_session")},
+			}
+		})
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"wasm-session": {APIID: "wasm-session"}}
+			s.MetaData = map[string]interface{}{"plan": "silver"}
+		})
+		authorization := map[string]string{headers.Authorization: key}
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Headers: authorization, Code: http.StatusOK, BodyMatch: `"X-Plan":"silver"`, HeadersMatch: map[string]string{"X-Plan": "gold"}},
+			{Headers: authorization, Code: http.StatusOK, BodyMatch: `"X-Plan":"gold"`},
+		}...)
+
+		session, found := ts.Gw.GlobalSessionManager.SessionDetail("default", key, false)
+		assert.True(t, found)
+		assert.Equal(t, "gold", session.MetaData["plan"])
+	})
+
+	t.Run("invalid plugins are skipped", func(t *testing.T) {
+		load(apidef.MiddlewareSection{Pre: []apidef.MiddlewareDefinition{plugin("missing"), {Name: "deny", Path: "missing.wasm"}}})
+
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusOK})
+	})
+
+	t.Run("bundle", func(t *testing.T) {
+		bundleID := ts.RegisterBundle("wasm", map[string]string{
+			"manifest.json": `{
+				"file_list": [],
+				"custom_middleware": {"driver": "wasm", "pre": [{"name": "add_header", "path": "plugin.wasm"}]}
+			}`,
+			"plugin.wasm": string(testWasmPlugin),
+		})
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.CustomMiddlewareBundle = bundleID
+		})
+
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusOK, BodyMatch: `"X-Wasm":"hello"`})
+	})
+}
+
+func TestWasmPluginRelease(t *testing.T) {
+	pluginPath := filepath.Join(t.TempDir(), "plugin.wasm")
+	if err := ioutil.WriteFile(pluginPath, testWasmPlugin, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadWasmPlugin(apidef.MiddlewareDefinition{Name: "add_header", Path: pluginPath})
+	assert.NoError(t, err)
+
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{}, wasmPlugins: []*wasmPlugin{p}}
+	spec.Release()
+
+	call := &wasmCall{header: http.Header{}}
+	assert.Error(t, p.run(context.Background(), call), "released plugins shouldn't run")
+}
@@ -0,0 +1,360 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lonelycode/osin"
+
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+const (
+	defaultConsentServiceTimeout = 5 * time.Second
+
+	// consentTokenTTL is how long the built-in consent page can be submitted after being rendered.
+	consentTokenTTL = 10 * time.Minute
+	// consentSessionTTL is how long the authorize endpoint accepts a consent session once issued.
+	consentSessionTTL = 10 * time.Minute
+
+	consentSessionField  = "consent_session"
+	consentTokenField    = "consent_token"
+	consentDecisionField = "consent"
+	consentApprove       = "approve"
+)
+
+// OAuthConsentRequest is sent to the consent service when a user has not yet granted the
+// scopes requested by a client.
+type OAuthConsentRequest struct {
+	APIID             string   `json:"api_id"`
+	ClientID          string   `json:"client_id"`
+	ClientDescription string   `json:"client_description"`
+	UserID            string   `json:"user_id"`
+	RedirectURI       string   `json:"redirect_uri"`
+	RequestedScopes   []string `json:"requested_scopes"`
+	// GrantedScopes are the scopes the user previously granted to the client.
+	GrantedScopes []string `json:"granted_scopes"`
+}
+
+// OAuthConsentResponse is the decision of the consent service.
+type OAuthConsentResponse struct {
+	Approved bool `json:"approved"`
+	// Scopes restricts the approved scopes, all requested scopes are approved when empty.
+	Scopes []string `json:"scopes"`
+}
+
+var consentPageTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorize {{.Client}}</title></head>
+<body>
+<h1>Authorize {{.Client}}</h1>
+<p>{{.Client}} is requesting access to your account{{if .Scopes}} with the following permissions:{{else}}.{{end}}</p>
+{{if .Scopes}}<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="` + consentTokenField + `" value="{{.Token}}">
+<button type="submit" name="` + consentDecisionField + `" value="` + consentApprove + `">Allow</button>
+<button type="submit" name="` + consentDecisionField + `" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+// OAuthConsentSession identifies the resource owner of the authorize requests of a client, see
+// HandleConsentSession.
+type OAuthConsentSession struct {
+	ConsentSession string `json:"consent_session"`
+	Expires        int64  `json:"expires"`
+}
+
+// HandleConsentSession issues the consent session of a user authenticated by the login system, to be
+// passed with the authorize requests of the client as the consent_session parameter.
+func (o *OAuthHandlers) HandleConsentSession(w http.ResponseWriter, r *http.Request) {
+	if !o.Manager.API.OAuthConsent.Enabled {
+		doJSONWrite(w, http.StatusNotFound, apiError("OAuth consent is not enabled for this API"))
+		return
+	}
+
+	clientID, userID := r.FormValue("client_id"), r.FormValue("user_id")
+	if clientID == "" || userID == "" {
+		doJSONWrite(w, http.StatusBadRequest, apiError("client_id and user_id are required"))
+		return
+	}
+	if _, err := o.Manager.OsinServer.Storage.GetClient(clientID); err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("OAuth client not found"))
+		return
+	}
+
+	expires := time.Now().Add(consentSessionTTL)
+	doJSONWrite(w, http.StatusOK, OAuthConsentSession{
+		ConsentSession: o.consentSession(clientID, userID, expires),
+		Expires:        expires.Unix(),
+	})
+}
+
+// consentSession signs the identity of userID for the authorize requests of clientID.
+func (o *OAuthHandlers) consentSession(clientID, userID string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	user := base64.RawURLEncoding.EncodeToString([]byte(userID))
+	return user + "." + exp + "." + o.consentMAC(consentSessionField, clientID, userID, exp)
+}
+
+// consentSessionUser returns the user of the consent session passed with an authorize request of
+// clientID, false if it isn't valid.
+func (o *OAuthHandlers) consentSessionUser(session, clientID string) (string, bool) {
+	parts := strings.Split(session, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	user, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(user) == 0 {
+		return "", false
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return "", false
+	}
+
+	expected := o.consentSession(clientID, string(user), time.Unix(exp, 0))
+	if !hmac.Equal([]byte(session), []byte(expected)) {
+		return "", false
+	}
+	return string(user), true
+}
+
+// handleConsent authorizes the request of a client once the user of the consent session consented
+// to the requested scopes, only asking for consent when they were not all granted before.
+func (o *OAuthHandlers) handleConsent(w http.ResponseWriter, r *http.Request, session string) {
+	resp := o.Manager.OsinServer.NewResponse()
+	ar := o.Manager.handleAuthorizeRequest(resp, r)
+	if ar == nil || resp.IsError {
+		log.Error("[OAuth] There was an error with the request: ", resp)
+		doJSONWrite(w, resp.ErrorStatusCode, apiError(resp.StatusText))
+		return
+	}
+
+	userID, ok := o.consentSessionUser(session, ar.Client.GetId())
+	if !ok {
+		doJSONWrite(w, http.StatusForbidden, apiError("Invalid or expired consent session"))
+		return
+	}
+
+	store := o.Manager.OsinServer.Storage
+	clientID := ar.Client.GetId()
+	requested := strings.Fields(ar.Scope)
+
+	granted, err := store.GetConsent(clientID, userID)
+	if err == nil && containsAllScopes(granted, requested) {
+		o.finishConsent(w, r, resp, ar, userID, true)
+		return
+	}
+
+	var approved []string
+	switch {
+	case r.Method == http.MethodPost && r.FormValue(consentTokenField) != "":
+		if !o.validConsentToken(r.FormValue(consentTokenField), ar, userID) {
+			doJSONWrite(w, http.StatusForbidden, apiError("Invalid or expired consent token"))
+			return
+		}
+		ok = r.FormValue(consentDecisionField) == consentApprove
+		approved = requested
+	case o.Manager.API.OAuthConsent.ServiceURL != "":
+		approved, ok, err = o.askConsentService(ar, userID, requested, granted)
+		if err != nil {
+			log.WithError(err).Error("[OAuth] Consent service failed")
+			resp.SetRedirect(ar.RedirectUri)
+			resp.SetErrorState(osin.E_SERVER_ERROR, "", ar.State)
+			o.writeConsentRedirect(w, r, resp)
+			return
+		}
+	default:
+		o.renderConsentPage(w, r, ar, userID, requested)
+		return
+	}
+
+	if ok {
+		if err := store.SetConsent(clientID, userID, mergeScopes(granted, approved)); err != nil {
+			log.WithError(err).Error("[OAuth] Couldn't record consent")
+		}
+		ar.Scope = strings.Join(approved, " ")
+	}
+	o.finishConsent(w, r, resp, ar, userID, ok)
+}
+
+func (o *OAuthHandlers) finishConsent(w http.ResponseWriter, r *http.Request, resp *osin.Response, ar *osin.AuthorizeRequest, userID string, authorized bool) {
+	ar.Authorized = authorized
+	if authorized {
+		// the session of the tokens is created from the policy of the client, for the user
+		session, err := o.Manager.Gw.generateSessionFromPolicy(ar.Client.GetPolicyID(), o.Manager.API.OrgID, false)
+		if err != nil {
+			log.WithError(err).Error("[OAuth] Couldn't create the session of the user from the policy of the client")
+			resp.SetRedirect(ar.RedirectUri)
+			resp.SetErrorState(osin.E_SERVER_ERROR, "", ar.State)
+			o.writeConsentRedirect(w, r, resp)
+			return
+		}
+		session.Alias = userID
+		userData, _ := json.Marshal(session)
+		ar.UserData = string(userData)
+	}
+	o.Manager.finishAuthorizeRequest(resp, r, ar)
+	if resp.InternalError != nil {
+		log.Error(resp.InternalError)
+	}
+	o.writeConsentRedirect(w, r, resp)
+}
+
+func (o *OAuthHandlers) writeConsentRedirect(w http.ResponseWriter, r *http.Request, resp *osin.Response) {
+	redirect, err := resp.GetRedirectUrl()
+	if err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't redirect to client"))
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (o *OAuthHandlers) askConsentService(ar *osin.AuthorizeRequest, userID string, requested, granted []string) ([]string, bool, error) {
+	conf := o.Manager.API.OAuthConsent
+
+	consentReq := OAuthConsentRequest{
+		APIID:           o.Manager.API.APIID,
+		ClientID:        ar.Client.GetId(),
+		UserID:          userID,
+		RedirectURI:     ar.RedirectUri,
+		RequestedScopes: requested,
+		GrantedScopes:   granted,
+	}
+	if client, ok := ar.Client.(ExtendedOsinClientInterface); ok {
+		consentReq.ClientDescription = client.GetDescription()
+	}
+	body, err := json.Marshal(consentReq)
+	if err != nil {
+		return nil, false, err
+	}
+
+	timeout := defaultConsentServiceTimeout
+	if conf.ServiceTimeout > 0 {
+		timeout = time.Duration(conf.ServiceTimeout * float64(time.Second))
+	}
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Post(conf.ServiceURL, headers.ApplicationJSON, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("consent service returned status %d", res.StatusCode)
+	}
+
+	var decision OAuthConsentResponse
+	if err := json.NewDecoder(res.Body).Decode(&decision); err != nil {
+		return nil, false, err
+	}
+	if !decision.Approved {
+		return nil, false, nil
+	}
+	if len(decision.Scopes) == 0 {
+		return requested, true, nil
+	}
+
+	// the service can only approve scopes which were requested
+	var approved []string
+	for _, scope := range decision.Scopes {
+		if containsAllScopes(requested, []string{scope}) {
+			approved = append(approved, scope)
+		}
+	}
+	return approved, true, nil
+}
+
+func (o *OAuthHandlers) renderConsentPage(w http.ResponseWriter, r *http.Request, ar *osin.AuthorizeRequest, userID string, requested []string) {
+	clientName := ar.Client.GetId()
+	if client, ok := ar.Client.(ExtendedOsinClientInterface); ok && client.GetDescription() != "" {
+		clientName = client.GetDescription()
+	}
+
+	// the decision is posted back with the parameters of the authorize request
+	params := url.Values{}
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", codeChallengeField, codeChallengeMethodField, consentSessionField} {
+		if v := r.FormValue(name); v != "" {
+			params.Set(name, v)
+		}
+	}
+
+	w.Header().Set(headers.ContentType, "text/html; charset=utf-8")
+	w.Header().Set(headers.CacheControl, "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	consentPageTemplate.Execute(w, map[string]interface{}{
+		"Client": clientName,
+		"Scopes": requested,
+		"Action": r.URL.Path + "?" + params.Encode(),
+		"Token":  o.consentToken(ar, userID, time.Now().Add(consentTokenTTL)),
+	})
+}
+
+// consentToken binds the decision posted from the consent page to the authorize request and the
+// user it was rendered for.
+func (o *OAuthHandlers) consentToken(ar *osin.AuthorizeRequest, userID string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	challenge, method := authorizeCodeChallenge(ar)
+	return exp + "." + o.consentMAC(consentTokenField, ar.Client.GetId(), userID, ar.Scope, ar.RedirectUri, ar.State, challenge, method, exp)
+}
+
+// consentMAC signs values for the API with the secret of the gateway.
+func (o *OAuthHandlers) consentMAC(values ...string) string {
+	mac := hmac.New(sha256.New, []byte(o.Manager.Gw.GetConfig().Secret))
+	mac.Write([]byte(o.Manager.API.APIID))
+	for _, v := range values {
+		mac.Write([]byte{0})
+		mac.Write([]byte(v))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (o *OAuthHandlers) validConsentToken(token string, ar *osin.AuthorizeRequest, userID string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	exp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) != 2 || time.Now().Unix() > exp {
+		return false
+	}
+	expected := o.consentToken(ar, userID, time.Unix(exp, 0))
+	return hmac.Equal([]byte(token), []byte(expected))
+}
+
+// containsAllScopes reports whether every scope of want is in have.
+func containsAllScopes(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeScopes(scopes, add []string) []string {
+	merged := append([]string{}, scopes...)
+	for _, scope := range add {
+		if !containsAllScopes(merged, []string{scope}) {
+			merged = append(merged, scope)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,290 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lonelycode/osin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+var consentTokenRegex = regexp.MustCompile(`name="consent_token" value="([^"]+)"`)
+
+func consentAuthorizeParams(scope, session string) url.Values {
+	param := make(url.Values)
+	param.Set("response_type", "code")
+	param.Set("redirect_uri", authRedirectUri)
+	param.Set("client_id", authClientID)
+	param.Set("scope", scope)
+	param.Set("state", "random-state-value")
+	if session != "" {
+		param.Set("consent_session", session)
+	}
+	return param
+}
+
+// consentSession gets the consent session of userID from the secret protected endpoint, as the
+// login system does.
+func (ts *Test) consentSession(t *testing.T, userID string) string {
+	t.Helper()
+	form := url.Values{"client_id": {authClientID}, "user_id": {userID}}
+	resp, _ := ts.Run(t, test.TestCase{
+		Method:    http.MethodPost,
+		Path:      "/APIID/tyk/oauth/consent-session",
+		Headers:   map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Data:      form.Encode(),
+		AdminAuth: true,
+		Code:      http.StatusOK,
+	})
+
+	var session OAuthConsentSession
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	return session.ConsentSession
+}
+
+func TestOAuthConsentPage(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := ts.Gw.LoadAPI(buildTestOAuthSpec(func(spec *APISpec) {
+		spec.OAuthConsent.Enabled = true
+	}))[0]
+	ts.createTestOAuthClient(spec, authClientID)
+
+	noRedirect := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	alice := ts.consentSession(t, "alice")
+
+	authorize := func(t *testing.T, scope string, code int) *http.Response {
+		t.Helper()
+		resp, _ := ts.Run(t, test.TestCase{
+			Path:   "/APIID/oauth/authorize/?" + consentAuthorizeParams(scope, alice).Encode(),
+			Client: noRedirect,
+			Code:   code,
+		})
+		return resp
+	}
+
+	decide := func(t *testing.T, scope, token, decision string, code int) *http.Response {
+		t.Helper()
+		form := url.Values{"consent_token": {token}, "consent": {decision}}
+		resp, _ := ts.Run(t, test.TestCase{
+			Method:  http.MethodPost,
+			Path:    "/APIID/oauth/authorize/?" + consentAuthorizeParams(scope, alice).Encode(),
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Data:    form.Encode(),
+			Client:  noRedirect,
+			Code:    code,
+		})
+		return resp
+	}
+
+	consentToken := func(t *testing.T, resp *http.Response) string {
+		t.Helper()
+		body, _ := ioutil.ReadAll(resp.Body)
+		match := consentTokenRegex.FindSubmatch(body)
+		require.NotNil(t, match, "consent page should contain a token")
+		return string(match[1])
+	}
+
+	t.Run("without logged in user", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{
+				Path:   "/APIID/oauth/authorize/?" + consentAuthorizeParams("read", "").Encode(),
+				Client: noRedirect,
+				Code:   http.StatusTemporaryRedirect,
+			},
+			// the user can't be claimed by the client
+			{
+				Path:    "/APIID/oauth/authorize/?" + consentAuthorizeParams("read", "").Encode(),
+				Headers: map[string]string{"X-User-ID": "alice"},
+				Client:  noRedirect,
+				Code:    http.StatusTemporaryRedirect,
+			},
+			{
+				Method: http.MethodPost,
+				Path:   "/APIID/tyk/oauth/consent-session",
+				Data:   url.Values{"client_id": {authClientID}, "user_id": {"alice"}}.Encode(),
+				Code:   http.StatusForbidden,
+			},
+		}...)
+	})
+
+	t.Run("forged consent session", func(t *testing.T) {
+		handlers := OAuthHandlers{*spec.OAuthManager}
+		for _, session := range []string{
+			"YWxpY2U.9999999999.00",
+			handlers.consentSession("other-client", "alice", time.Now().Add(time.Minute)),
+			handlers.consentSession(authClientID, "alice", time.Now().Add(-time.Minute)),
+		} {
+			_, _ = ts.Run(t, test.TestCase{
+				Path:   "/APIID/oauth/authorize/?" + consentAuthorizeParams("read", session).Encode(),
+				Client: noRedirect,
+				Code:   http.StatusForbidden,
+			})
+		}
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		token := consentToken(t, authorize(t, "read", http.StatusOK))
+		resp := decide(t, "read", token, "deny", http.StatusFound)
+		assert.Contains(t, resp.Header.Get("Location"), "error=access_denied")
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		resp := authorize(t, "read write", http.StatusOK)
+		assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+		token := consentToken(t, resp)
+
+		// the token is bound to the requested scopes
+		decide(t, "read write admin", token, consentApprove, http.StatusForbidden)
+
+		resp = decide(t, "read write", token, consentApprove, http.StatusFound)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "client.oauth.com", location.Host)
+		assert.NotEmpty(t, location.Query().Get("code"))
+		assert.Equal(t, "random-state-value", location.Query().Get("state"))
+
+		scopes, err := spec.OAuthManager.OsinServer.Storage.GetConsent(authClientID, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"read", "write"}, scopes)
+
+		// the code is bound to the user
+		authData, err := spec.OAuthManager.OsinServer.Storage.LoadAuthorize(location.Query().Get("code"))
+		require.NoError(t, err)
+		var session user.SessionState
+		require.NoError(t, json.Unmarshal([]byte(authData.UserData.(string)), &session))
+		assert.Equal(t, "alice", session.Alias)
+	})
+
+	t.Run("granted scopes skip consent", func(t *testing.T) {
+		resp := authorize(t, "write", http.StatusFound)
+		assert.Contains(t, resp.Header.Get("Location"), "code=")
+	})
+
+	t.Run("new scopes require consent", func(t *testing.T) {
+		authorize(t, "read admin", http.StatusOK)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		client, err := spec.OAuthManager.OsinServer.Storage.GetClient(authClientID)
+		require.NoError(t, err)
+		ar := &osin.AuthorizeRequest{Client: client, Scope: "admin", RedirectUri: authRedirectUri, State: "random-state-value"}
+		handlers := OAuthHandlers{*spec.OAuthManager}
+		token := handlers.consentToken(ar, "alice", time.Now().Add(-time.Minute))
+		decide(t, "admin", token, consentApprove, http.StatusForbidden)
+	})
+}
+
+func TestOAuthConsentService(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received OAuthConsentRequest
+		approve  = true
+	)
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(OAuthConsentResponse{Approved: approve, Scopes: []string{"read", "unrequested"}})
+	}))
+	defer service.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := ts.Gw.LoadAPI(buildTestOAuthSpec(func(spec *APISpec) {
+		spec.OAuthConsent.Enabled = true
+		spec.OAuthConsent.ServiceURL = service.URL
+	}))[0]
+	ts.createTestOAuthClient(spec, authClientID)
+
+	noRedirect := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	bob := ts.consentSession(t, "bob")
+
+	resp, _ := ts.Run(t, test.TestCase{
+		Path:   "/APIID/oauth/authorize/?" + consentAuthorizeParams("read write", bob).Encode(),
+		Client: noRedirect,
+		Code:   http.StatusFound,
+	})
+	assert.Contains(t, resp.Header.Get("Location"), "code=")
+	mu.Lock()
+	assert.Equal(t, "bob", received.UserID)
+	assert.Equal(t, authClientID, received.ClientID)
+	assert.Equal(t, []string{"read", "write"}, received.RequestedScopes)
+	approve = false
+	mu.Unlock()
+
+	scopes, err := spec.OAuthManager.OsinServer.Storage.GetConsent(authClientID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, scopes)
+
+	resp, _ = ts.Run(t, test.TestCase{
+		Path:   "/APIID/oauth/authorize/?" + consentAuthorizeParams("write", bob).Encode(),
+		Client: noRedirect,
+		Code:   http.StatusFound,
+	})
+	assert.Contains(t, resp.Header.Get("Location"), "error=access_denied")
+	mu.Lock()
+	assert.Equal(t, []string{"read"}, received.GrantedScopes)
+	mu.Unlock()
+
+	service.Close()
+	resp, _ = ts.Run(t, test.TestCase{
+		Path:   "/APIID/oauth/authorize/?" + consentAuthorizeParams("write", bob).Encode(),
+		Client: noRedirect,
+		Code:   http.StatusFound,
+	})
+	assert.Contains(t, resp.Header.Get("Location"), "error=server_error")
+}
+
+func TestMergeScopes(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, mergeScopes([]string{"a", "b"}, []string{"b", "c"}))
+	assert.True(t, containsAllScopes([]string{"a", "b"}, nil))
+	assert.False(t, containsAllScopes([]string{"a"}, []string{"a", "b"}))
+}
+
+func TestConsentSessionUser(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{Secret: "secret"}, ctx, cancel)
+
+	handlers := OAuthHandlers{OAuthManager{API: &APISpec{APIDefinition: &apidef.APIDefinition{APIID: "api"}}, Gw: gw}}
+	session := handlers.consentSession("client", "alice", time.Now().Add(time.Minute))
+
+	userID, ok := handlers.consentSessionUser(session, "client")
+	assert.True(t, ok)
+	assert.Equal(t, "alice", userID)
+
+	_, ok = handlers.consentSessionUser(session, "other-client")
+	assert.False(t, ok, "the session is bound to the client")
+
+	other := OAuthHandlers{OAuthManager{API: &APISpec{APIDefinition: &apidef.APIDefinition{APIID: "other-api"}}, Gw: gw}}
+	_, ok = other.consentSessionUser(session, "client")
+	assert.False(t, ok, "the session is bound to the API")
+
+	parts := strings.Split(session, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte("bob")) + "." + parts[1] + "." + parts[2]
+	_, ok = handlers.consentSessionUser(forged, "client")
+	assert.False(t, ok, "the user is signed")
+}
@@ -0,0 +1,299 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lonelycode/osin"
+
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+// Device authorization grant, see https://tools.ietf.org/html/rfc8628
+const (
+	deviceCodeGrant osin.AccessRequestType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	deviceCodeField = "device_code"
+	userCodeField   = "user_code"
+
+	// deviceCodeExpiration is how long the user has to authorize a device, in seconds.
+	deviceCodeExpiration = 600
+	// deviceCodeInterval is the minimum number of seconds between two polls of a device.
+	deviceCodeInterval = 5
+
+	deviceAuthorizationPending  = "pending"
+	deviceAuthorizationApproved = "approved"
+	deviceAuthorizationDenied   = "denied"
+
+	// device access token errors, see https://tools.ietf.org/html/rfc8628#section-3.5
+	errAuthorizationPending = "authorization_pending"
+	errSlowDown             = "slow_down"
+	errExpiredToken         = "expired_token"
+
+	// userCodeAlphabet has no vowels, so that user codes don't spell words, and no easily confused
+	// characters.
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// DeviceAuthorization is the authorization of a device, pending until the user approves or denies it.
+type DeviceAuthorization struct {
+	DeviceCode string `json:"device_code"`
+	// UserCode is normalized, see normalizeUserCode.
+	UserCode string `json:"user_code"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"`
+	Status   string `json:"status"`
+	// UserData is the session of the tokens issued to the device, as JSON.
+	UserData  string `json:"user_data,omitempty"`
+	ExpiresAt int64  `json:"expires_at"`
+	// Interval is the minimum number of seconds between two polls until the device slows down.
+	Interval int64 `json:"interval"`
+}
+
+// DevicePoll is the last poll of a device. It's saved apart from the authorization of the device
+// so that polls never overwrite the decision of the user.
+type DevicePoll struct {
+	LastPolled int64 `json:"last_polled"`
+	Interval   int64 `json:"interval"`
+}
+
+// normalizeUserCode strips the separators and case of a user code as typed by the user.
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// formatUserCode returns the user code as shown to the user.
+func formatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+func generateUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = userCodeAlphabet[int(b[i])%len(userCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+func generateDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HandleDeviceAuthorization handles a device requesting to be authorized, returning the user code
+// the user enters on the verification page of the resource provider.
+func (o *OAuthHandlers) HandleDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(headers.ContentType, headers.ApplicationJSON)
+	resp := o.Manager.HandleDeviceAuthorization(r)
+	msg := o.generateOAuthOutputFromOsinResponse(resp)
+
+	code := http.StatusOK
+	if resp.IsError {
+		code = resp.ErrorStatusCode
+	}
+	w.WriteHeader(code)
+	w.Write(msg)
+}
+
+// HandleAuthorizeDevice handles a resource provider approving, or denying, the device of the user
+// code entered by the user.
+func (o *OAuthHandlers) HandleAuthorizeDevice(w http.ResponseWriter, r *http.Request) {
+	userCode := normalizeUserCode(r.FormValue(userCodeField))
+	if userCode == "" {
+		doJSONWrite(w, http.StatusBadRequest, apiError(oauthUserCodeEmpty))
+		return
+	}
+
+	storage := o.Manager.OsinServer.Storage
+	auth, err := storage.GetDeviceAuthorizationByUserCode(userCode)
+	if err != nil || time.Now().Unix() >= auth.ExpiresAt {
+		doJSONWrite(w, http.StatusNotFound, apiError(oauthUserCodeNotFound))
+		return
+	}
+
+	if auth.Status != deviceAuthorizationPending {
+		doJSONWrite(w, http.StatusBadRequest, apiError(oauthUserCodeUsed))
+		return
+	}
+
+	message := "device authorized"
+	if deny, _ := strconv.ParseBool(r.FormValue("deny")); deny {
+		auth.Status = deviceAuthorizationDenied
+		message = "device denied"
+	} else {
+		auth.Status = deviceAuthorizationApproved
+		auth.UserData = r.FormValue("key_rules")
+		if auth.UserData == "" {
+			log.Warning("Device authorization is missing key_rules in params, policy will be required!")
+		}
+	}
+
+	if err := storage.SetDeviceAuthorization(auth); err != nil {
+		log.WithError(err).Error("[OAuth] Could not save device authorization")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("failed to save device authorization"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, apiOk(message))
+}
+
+// HandleDeviceAuthorization issues a device code and its user code to the client of r.
+func (o *OAuthManager) HandleDeviceAuthorization(r *http.Request) *osin.Response {
+	resp := o.OsinServer.NewResponse()
+	r.ParseForm()
+
+	if !o.OsinServer.Config.AllowedAccessTypes.Exists(deviceCodeGrant) {
+		resp.SetError(osin.E_UNAUTHORIZED_CLIENT, "")
+		return resp
+	}
+
+	client := o.deviceClient(resp, r)
+	if client == nil {
+		return resp
+	}
+
+	deviceCode, err := generateDeviceCode()
+	if err != nil {
+		resp.SetError(osin.E_SERVER_ERROR, "")
+		resp.InternalError = err
+		return resp
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		resp.SetError(osin.E_SERVER_ERROR, "")
+		resp.InternalError = err
+		return resp
+	}
+
+	auth := &DeviceAuthorization{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ClientID:   client.GetId(),
+		Scope:      r.Form.Get("scope"),
+		Status:     deviceAuthorizationPending,
+		ExpiresAt:  time.Now().Unix() + deviceCodeExpiration,
+		Interval:   deviceCodeInterval,
+	}
+	if err := o.OsinServer.Storage.SetDeviceAuthorization(auth); err != nil {
+		resp.SetError(osin.E_SERVER_ERROR, "")
+		resp.InternalError = err
+		return resp
+	}
+
+	verificationURI := o.API.Oauth2Meta.AuthorizeLoginRedirect
+	separator := "?"
+	if strings.Contains(verificationURI, "?") {
+		separator = "&"
+	}
+
+	resp.Output[deviceCodeField] = deviceCode
+	resp.Output[userCodeField] = formatUserCode(userCode)
+	resp.Output["verification_uri"] = verificationURI
+	resp.Output["verification_uri_complete"] = verificationURI + separator + userCodeField + "=" + url.QueryEscape(formatUserCode(userCode))
+	resp.Output["expires_in"] = deviceCodeExpiration
+	resp.Output["interval"] = deviceCodeInterval
+	return resp
+}
+
+// handleDeviceAccessRequest handles a device polling for its tokens, returning the access request
+// once the user approved the device.
+func (o *OAuthManager) handleDeviceAccessRequest(resp *osin.Response, r *http.Request) *osin.AccessRequest {
+	if !o.OsinServer.Config.AllowedAccessTypes.Exists(deviceCodeGrant) {
+		resp.SetError(osin.E_UNSUPPORTED_GRANT_TYPE, "")
+		return nil
+	}
+
+	client := o.deviceClient(resp, r)
+	if client == nil {
+		return nil
+	}
+
+	storage := o.OsinServer.Storage
+	auth, err := storage.GetDeviceAuthorization(r.Form.Get(deviceCodeField))
+	if err != nil || auth.ClientID != client.GetId() {
+		resp.SetError(osin.E_INVALID_GRANT, "")
+		return nil
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case now >= auth.ExpiresAt:
+		resp.SetError(errExpiredToken, "The device code has expired.")
+	case auth.Status == deviceAuthorizationDenied:
+		storage.RemoveDeviceAuthorization(auth)
+		resp.SetError(osin.E_ACCESS_DENIED, "")
+	case auth.Status == deviceAuthorizationPending:
+		poll, err := storage.GetDevicePoll(auth.DeviceCode)
+		if err != nil {
+			poll = &DevicePoll{Interval: auth.Interval}
+		}
+		if now-poll.LastPolled < poll.Interval {
+			// the device has to wait 5 more seconds between its polls from now on
+			poll.Interval += deviceCodeInterval
+			resp.SetError(errSlowDown, "The device is polling too frequently.")
+		} else {
+			resp.SetError(errAuthorizationPending, "The user hasn't authorized the device yet.")
+		}
+		poll.LastPolled = now
+		if err := storage.SetDevicePoll(auth, poll); err != nil {
+			log.WithError(err).Error("[OAuth] Could not save device poll")
+		}
+	default:
+		// the device code can only be exchanged once, by the poll which removed it
+		if err := storage.RemoveDeviceAuthorization(auth); err != nil {
+			resp.SetError(osin.E_INVALID_GRANT, "")
+			return nil
+		}
+
+		ar := &osin.AccessRequest{
+			Type:            deviceCodeGrant,
+			Code:            auth.DeviceCode,
+			Client:          client,
+			Scope:           auth.Scope,
+			Expiration:      o.OsinServer.Config.AccessExpiration,
+			GenerateRefresh: true,
+			HttpRequest:     r,
+		}
+		if auth.UserData != "" {
+			ar.UserData = auth.UserData
+		}
+		return ar
+	}
+
+	return nil
+}
+
+// deviceClient returns the client of a device request. Devices are usually public clients that
+// only identify with their client_id, a secret sent with basic auth is checked though.
+func (o *OAuthManager) deviceClient(resp *osin.Response, r *http.Request) osin.Client {
+	id, secret, basicAuth := r.BasicAuth()
+	if !basicAuth {
+		id = r.Form.Get("client_id")
+	}
+
+	if id != "" {
+		client, err := o.OsinServer.Storage.GetClient(id)
+		if err == nil && (!basicAuth || clientSecretMatches(client, secret)) {
+			return client
+		}
+	}
+
+	resp.SetError(osin.E_INVALID_CLIENT, "")
+	return nil
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestUserCode(t *testing.T) {
+	code, err := generateUserCode()
+	assert.NoError(t, err)
+	assert.Len(t, code, userCodeLength)
+	assert.Empty(t, strings.Trim(code, userCodeAlphabet))
+
+	assert.Equal(t, "BCDF-GHJK", formatUserCode("BCDFGHJK"))
+	assert.Equal(t, "BCDFGHJK", normalizeUserCode("bcdf-ghjk"))
+	assert.Equal(t, "BCDFGHJK", normalizeUserCode(" BCDF GHJK"))
+}
+
+func TestOAuthDeviceGrant(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	formHeaders := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	authorizeDevice := func(t *testing.T, clientID string, code int) map[string]interface{} {
+		t.Helper()
+		param := make(url.Values)
+		param.Set("client_id", clientID)
+
+		resp, _ := ts.Run(t, test.TestCase{
+			Path:    "/APIID/oauth/device_authorization",
+			Data:    param.Encode(),
+			Headers: formHeaders,
+			Method:  http.MethodPost,
+			Code:    code,
+		})
+
+		response := map[string]interface{}{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		return response
+	}
+
+	approve := func(userCode string, deny bool, code int) test.TestCase {
+		param := make(url.Values)
+		param.Set("user_code", userCode)
+		param.Set("key_rules", keyRules)
+		if deny {
+			param.Set("deny", "true")
+		}
+
+		return test.TestCase{
+			Path:      "/APIID/tyk/oauth/authorize-device/",
+			AdminAuth: true,
+			Data:      param.Encode(),
+			Headers:   formHeaders,
+			Method:    http.MethodPost,
+			Code:      code,
+		}
+	}
+
+	poll := func(clientID, deviceCode string, code int, bodyMatch string) test.TestCase {
+		param := make(url.Values)
+		param.Set("grant_type", string(deviceCodeGrant))
+		param.Set("client_id", clientID)
+		param.Set("device_code", deviceCode)
+
+		return test.TestCase{
+			Path:      "/APIID/oauth/token/",
+			Data:      param.Encode(),
+			Headers:   formHeaders,
+			Method:    http.MethodPost,
+			Code:      code,
+			BodyMatch: bodyMatch,
+		}
+	}
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		spec := ts.LoadTestOAuthSpec()
+		ts.createTestOAuthClient(spec, authClientID)
+
+		response := authorizeDevice(t, authClientID, http.StatusForbidden)
+		assert.Equal(t, "unauthorized_client", response["error"])
+		_, _ = ts.Run(t, poll(authClientID, "code", http.StatusForbidden, `"error":"unsupported_grant_type"`))
+	})
+
+	spec := ts.Gw.LoadAPI(buildTestOAuthSpec(func(spec *APISpec) {
+		spec.Oauth2Meta.AllowedAccessTypes = append(spec.Oauth2Meta.AllowedAccessTypes, deviceCodeGrant)
+	}))[0]
+	ts.createTestOAuthClient(spec, authClientID)
+	ts.createTestOAuthClient(spec, "other-client")
+
+	t.Run("unknown client", func(t *testing.T) {
+		response := authorizeDevice(t, "unknown", http.StatusForbidden)
+		assert.Equal(t, "invalid_client", response["error"])
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		response := authorizeDevice(t, authClientID, http.StatusOK)
+		deviceCode, _ := response["device_code"].(string)
+		userCode, _ := response["user_code"].(string)
+		assert.NotEmpty(t, deviceCode)
+		assert.Len(t, userCode, userCodeLength+1)
+		assert.Equal(t, testHttpPost, response["verification_uri"])
+		assert.Equal(t, testHttpPost+"?user_code="+userCode, response["verification_uri_complete"])
+
+		_, _ = ts.Run(t,
+			poll(authClientID, deviceCode, http.StatusForbidden, `"error":"authorization_pending"`),
+			poll(authClientID, deviceCode, http.StatusForbidden, `"error":"slow_down"`),
+			approve("unknown", false, http.StatusNotFound),
+			approve(strings.ToLower(strings.Replace(userCode, "-", "", 1)), false, http.StatusOK),
+			approve(userCode, false, http.StatusBadRequest),
+			poll("other-client", deviceCode, http.StatusForbidden, `"error":"invalid_grant"`),
+		)
+
+		resp, _ := ts.Run(t, poll(authClientID, deviceCode, http.StatusOK, `"refresh_token"`))
+		var token tokenData
+		json.NewDecoder(resp.Body).Decode(&token)
+
+		_, _ = ts.Run(t,
+			test.TestCase{Path: "/APIID/get", Headers: map[string]string{"Authorization": "Bearer " + token.AccessToken}, Code: http.StatusOK},
+			poll(authClientID, deviceCode, http.StatusForbidden, `"error":"invalid_grant"`),
+		)
+	})
+
+	t.Run("exchanged once", func(t *testing.T) {
+		response := authorizeDevice(t, authClientID, http.StatusOK)
+		deviceCode, _ := response["device_code"].(string)
+		userCode, _ := response["user_code"].(string)
+		_, _ = ts.Run(t, approve(userCode, false, http.StatusOK))
+
+		var wg sync.WaitGroup
+		var issued int32
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := ts.Do(poll(authClientID, deviceCode, 0, ""))
+				if err != nil {
+					return
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					atomic.AddInt32(&issued, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), issued)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		response := authorizeDevice(t, authClientID, http.StatusOK)
+		deviceCode, _ := response["device_code"].(string)
+		userCode, _ := response["user_code"].(string)
+
+		_, _ = ts.Run(t,
+			approve(userCode, true, http.StatusOK),
+			poll(authClientID, deviceCode, http.StatusForbidden, `"error":"access_denied"`),
+			poll(authClientID, deviceCode, http.StatusForbidden, `"error":"invalid_grant"`),
+		)
+	})
+}
@@ -17,7 +17,6 @@ import (
 
 	"github.com/lonelycode/osin"
 	uuid "github.com/satori/go.uuid"
-	"golang.org/x/crypto/bcrypt"
 
 	"strconv"
 
@@ -54,6 +53,17 @@ type OAuthClient struct {
 	MetaData          interface{} `json:"meta_data,omitempty"`
 	PolicyID          string      `json:"policyid"`
 	Description       string      `json:"description"`
+	// PreviousSecret keeps authenticating the client until PreviousSecretExpires, once its secret
+	// was rotated with a grace period.
+	PreviousSecret        string                `json:"previous_secret,omitempty"`
+	PreviousSecretExpires int64                 `json:"previous_secret_expires,omitempty"`
+	RateLimit             *OAuthClientRateLimit `json:"rate_limit,omitempty"`
+}
+
+// OAuthClientRateLimit limits the requests made with all the tokens issued to a client.
+type OAuthClientRateLimit struct {
+	Rate float64 `json:"rate"`
+	Per  float64 `json:"per"`
 }
 
 func (oc *OAuthClient) GetId() string {
@@ -80,6 +90,27 @@ func (oc *OAuthClient) GetDescription() string {
 	return oc.Description
 }
 
+func (oc *OAuthClient) GetRateLimit() *OAuthClientRateLimit {
+	return oc.RateLimit
+}
+
+// validSecret reports whether secret authenticates the client, accepting the previous secret
+// during the grace period of a rotation.
+func (oc *OAuthClient) validSecret(secret string) bool {
+	if secret == oc.ClientSecret {
+		return true
+	}
+	return oc.PreviousSecret != "" && secret == oc.PreviousSecret && time.Now().Unix() < oc.PreviousSecretExpires
+}
+
+// clientSecretMatches reports whether secret authenticates client.
+func clientSecretMatches(client osin.Client, secret string) bool {
+	if c, ok := client.(*OAuthClient); ok {
+		return c.validSecret(secret)
+	}
+	return client.GetSecret() == secret
+}
+
 // OAuthNotificationType const to reduce risk of collisions
 type OAuthNotificationType string
 
@@ -157,6 +188,12 @@ func (o *OAuthHandlers) HandleGenerateAuthCodeData(w http.ResponseWriter, r *htt
 // HandleAuthorizePassthrough handles a Client Auth request, first it checks if the client
 // is OK (otherwise it blocks the request), then it forwards on to the resource providers approval URI
 func (o *OAuthHandlers) HandleAuthorizePassthrough(w http.ResponseWriter, r *http.Request) {
+	// Users authenticated by the login system are asked for consent by the gateway instead
+	if session := r.FormValue(consentSessionField); session != "" && o.Manager.API.OAuthConsent.Enabled {
+		o.handleConsent(w, r, session)
+		return
+	}
+
 	// Extract client data and check
 	resp := o.Manager.HandleAuthorisation(r, false, "")
 	if resp.IsError {
@@ -231,8 +268,8 @@ const (
 	refreshToken = "refresh_token"
 )
 
-//in compliance with https://tools.ietf.org/html/rfc7009#section-2.1
-//ToDo: set an authentication mechanism
+// HandleRevokeToken revokes a token on behalf of the client it was issued to, in compliance with
+// https://tools.ietf.org/html/rfc7009#section-2.1
 func (o *OAuthHandlers) HandleRevokeToken(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -248,20 +285,94 @@ func (o *OAuthHandlers) HandleRevokeToken(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	RevokeToken(o.Manager.OsinServer.Storage, token, tokenTypeHint)
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	if clientID == "" {
+		doJSONWrite(w, http.StatusUnauthorized, apiError(oauthClientIdEmpty))
+		return
+	}
+
+	storage := o.Manager.OsinServer.Storage
+	client, err := storage.GetClient(clientID)
+	if err != nil || !clientSecretMatches(client, secret) {
+		doJSONWrite(w, http.StatusUnauthorized, apiError(oauthClientAuthFailed))
+		return
+	}
+
+	switch tokenClientID(storage, token, tokenTypeHint) {
+	case "":
+		// invalid tokens don't cause an error response, see https://tools.ietf.org/html/rfc7009#section-2.2
+	case clientID:
+		o.Manager.Gw.notifyTokensRevoked(RevokeToken(storage, token, tokenTypeHint))
+	default:
+		doJSONWrite(w, http.StatusForbidden, apiError(oauthTokenClientMismatch))
+		return
+	}
+
 	doJSONWrite(w, http.StatusOK, apiOk("token revoked successfully"))
 }
 
-func RevokeToken(storage ExtendedOsinStorageInterface, token, tokenTypeHint string) {
+// RevokeToken revokes an access or refresh token, revoking a refresh token revokes the access
+// token issued with it too. It returns the revoked access tokens, to flush them from the session
+// caches.
+func RevokeToken(storage ExtendedOsinStorageInterface, token, tokenTypeHint string) []string {
+	var revoked []string
 	switch tokenTypeHint {
 	case accessToken:
 		storage.RemoveAccess(token)
+		revoked = append(revoked, token)
 	case refreshToken:
-		storage.RemoveRefresh(token)
+		revoked = append(revoked, revokeRefreshToken(storage, token)...)
 	default:
 		storage.RemoveAccess(token)
-		storage.RemoveRefresh(token)
+		revoked = append(revoked, token)
+		revoked = append(revoked, revokeRefreshToken(storage, token)...)
+	}
+	return revoked
+}
+
+// revokeRefreshToken removes a refresh token and the access token issued with it, which it
+// returns.
+func revokeRefreshToken(storage ExtendedOsinStorageInterface, token string) []string {
+	var revoked []string
+	if access, err := storage.LoadRefresh(token); err == nil && access.AccessToken != "" {
+		storage.RemoveAccess(access.AccessToken)
+		revoked = append(revoked, access.AccessToken)
+	}
+	storage.RemoveRefresh(token)
+	return revoked
+}
+
+// notifyTokensRevoked flushes the sessions of the revoked access tokens from the caches of the
+// gateways.
+func (gw *Gateway) notifyTokensRevoked(tokens []string) {
+	if len(tokens) == 0 {
+		return
+	}
+	gw.MainNotifier.Notify(Notification{
+		Command: KeySpaceUpdateNotification,
+		Payload: strings.Join(tokens, ","),
+		Gw:      gw,
+	})
+}
+
+// tokenClientID returns the ID of the client token was issued to, empty if token is unknown.
+func tokenClientID(storage ExtendedOsinStorageInterface, token, tokenTypeHint string) string {
+	load := []func(string) (*osin.AccessData, error){storage.LoadAccess, storage.LoadRefresh}
+	if tokenTypeHint == refreshToken {
+		load[0], load[1] = load[1], load[0]
 	}
+
+	for _, l := range load {
+		if access, err := l(token); err == nil && access.Client != nil {
+			return access.Client.GetId()
+		}
+	}
+	return ""
 }
 
 func (o *OAuthHandlers) HandleRevokeAllTokens(w http.ResponseWriter, r *http.Request) {
@@ -308,7 +419,7 @@ func RevokeAllTokens(storage ExtendedOsinStorageInterface, clientId, clientSecre
 		return http.StatusNotFound, resp, errors.New("error getting oauth client")
 	}
 
-	if client.GetSecret() != clientSecret {
+	if !clientSecretMatches(client, clientSecret) {
 		return http.StatusUnauthorized, resp, errors.New(oauthClientSecretWrong)
 	}
 
@@ -343,13 +454,13 @@ type OAuthManager struct {
 func (o *OAuthManager) HandleAuthorisation(r *http.Request, complete bool, session string) *osin.Response {
 	resp := o.OsinServer.NewResponse()
 
-	if ar := o.OsinServer.HandleAuthorizeRequest(resp, r); ar != nil {
+	if ar := o.handleAuthorizeRequest(resp, r); ar != nil {
 		// Since this is called by the Reource provider (proxied API), we assume it has been approved
 		ar.Authorized = true
 
 		if complete {
 			ar.UserData = session
-			o.OsinServer.FinishAuthorizeRequest(resp, r, ar)
+			o.finishAuthorizeRequest(resp, r, ar)
 		}
 	}
 	if resp.IsError && resp.InternalError != nil {
@@ -394,7 +505,15 @@ func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 	}
 	var username string
 
-	if ar := o.OsinServer.HandleAccessRequest(resp, r); ar != nil {
+	o.acceptPreviousClientSecret(r)
+
+	var ar *osin.AccessRequest
+	if r.Form.Get("grant_type") == string(deviceCodeGrant) {
+		ar = o.handleDeviceAccessRequest(resp, r)
+	} else if o.checkCodeVerifier(resp, r) && o.checkRefreshTokenReuse(resp, r) {
+		ar = o.OsinServer.HandleAccessRequest(resp, r)
+	}
+	if ar != nil {
 
 		var session *user.SessionState
 		if ar.Type == osin.PASSWORD {
@@ -408,18 +527,7 @@ func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 			if err != nil {
 				log.Warning("Attempted access with non-existent user (OAuth password flow).")
 			} else {
-				var passMatch bool
-				if session.BasicAuthData.Hash == user.HashBCrypt {
-					err := bcrypt.CompareHashAndPassword([]byte(session.BasicAuthData.Password), []byte(password))
-					if err == nil {
-						passMatch = true
-					}
-				}
-
-				if session.BasicAuthData.Hash == user.HashPlainText &&
-					session.BasicAuthData.Password == password {
-					passMatch = true
-				}
+				passMatch := comparePasswordHash(session.BasicAuthData.Hash, []byte(session.BasicAuthData.Password), []byte(password)) == nil
 
 				if passMatch {
 					ar.Authorized = true
@@ -455,6 +563,7 @@ func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 
 		log.Debug("[OAuth] Finishing access request ")
 		o.OsinServer.FinishAccessRequest(resp, r, ar)
+		o.recordRefreshTokenRotation(resp, r)
 		new_token, foundNewToken := resp.Output["access_token"]
 		if username != "" && foundNewToken {
 			log.Debug("Updating token data in key")
@@ -505,6 +614,73 @@ func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 	return resp
 }
 
+// acceptPreviousClientSecret authenticates a client with the secret it had before a rotation, osin
+// only knowing about the current secret.
+func (o *OAuthManager) acceptPreviousClientSecret(r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return
+	}
+
+	client, err := o.OsinServer.Storage.GetClient(id)
+	if err != nil || client.GetSecret() == secret {
+		return
+	}
+	if clientSecretMatches(client, secret) {
+		r.SetBasicAuth(id, client.GetSecret())
+	}
+}
+
+// checkRefreshTokenReuse rejects a refresh token that was already exchanged. Its reuse means it
+// leaked, so the tokens issued in exchange of it are revoked too.
+func (o *OAuthManager) checkRefreshTokenReuse(resp *osin.Response, r *http.Request) bool {
+	token := r.Form.Get("refresh_token")
+	if r.Form.Get("grant_type") != string(osin.REFRESH_TOKEN) || token == "" {
+		return true
+	}
+
+	next, err := o.OsinServer.Storage.GetRotatedRefresh(token)
+	switch {
+	case err == storage.ErrKeyNotFound:
+		return true
+	case err != nil:
+		resp.SetError(osin.E_SERVER_ERROR, "")
+		resp.InternalError = err
+		return false
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":    "oauth",
+		"org_id":    o.API.OrgID,
+		"client_id": r.Form.Get("client_id"),
+	}).Warning("Refresh token reused, revoking the tokens issued in exchange of it.")
+
+	var revoked []string
+	for next != "" {
+		revoked = append(revoked, revokeRefreshToken(o.OsinServer.Storage, next)...)
+		next, _ = o.OsinServer.Storage.GetRotatedRefresh(next)
+	}
+	o.Gw.notifyTokensRevoked(revoked)
+
+	resp.SetError(osin.E_INVALID_GRANT, "")
+	resp.InternalError = errors.New("refresh token was already used")
+	return false
+}
+
+// recordRefreshTokenRotation records the refresh token issued in exchange of the one of a refresh
+// request, so that its reuse can be detected.
+func (o *OAuthManager) recordRefreshTokenRotation(resp *osin.Response, r *http.Request) {
+	token := r.Form.Get("refresh_token")
+	next, _ := resp.Output["refresh_token"].(string)
+	if resp.IsError || r.Form.Get("grant_type") != string(osin.REFRESH_TOKEN) || token == "" || next == "" {
+		return
+	}
+
+	if err := o.OsinServer.Storage.SetRotatedRefresh(token, next); err != nil {
+		log.WithError(err).Error("[OAuth] Could not record the refresh token rotation")
+	}
+}
+
 // These enums fix the prefix to use when storing various OAuth keys and data, since we
 // delegate everything to the osin framework
 const (
@@ -515,6 +691,12 @@ const (
 	prefixClientset       = "oauth-clientset."
 	prefixClientIndexList = "oauth-client-index."
 	prefixClientTokens    = "oauth-client-tokens."
+	prefixConsent         = "oauth-consent."
+	prefixCodeChallenge   = "oauth-code-challenge."
+	prefixRotatedRefresh  = "oauth-rotated-refresh."
+	prefixDeviceCode      = "oauth-device-code."
+	prefixUserCode        = "oauth-user-code."
+	prefixDevicePoll      = "oauth-device-poll."
 )
 
 // swagger:model
@@ -526,6 +708,7 @@ type OAuthClientToken struct {
 type ExtendedOsinClientInterface interface {
 	osin.Client
 	GetDescription() string
+	GetRateLimit() *OAuthClientRateLimit
 }
 
 type ExtendedOsinStorageInterface interface {
@@ -554,6 +737,42 @@ type ExtendedOsinStorageInterface interface {
 
 	// SetUser updates a Basic Access user token type in the key store
 	SetUser(string, *user.SessionState, int64) error
+
+	// GetConsent retrieves the scopes a user granted to a client
+	GetConsent(clientID, userID string) ([]string, error)
+
+	// SetConsent records the scopes a user granted to a client
+	SetConsent(clientID, userID string, scopes []string) error
+
+	// GetCodeChallenge retrieves the PKCE challenge of an authorization code
+	GetCodeChallenge(code string) (challenge, method string, err error)
+
+	// SetCodeChallenge records the PKCE challenge of an authorization code
+	SetCodeChallenge(code, challenge, method string, expiresIn int64) error
+
+	// GetRotatedRefresh retrieves the refresh token that replaced a used one
+	GetRotatedRefresh(token string) (string, error)
+
+	// SetRotatedRefresh records the refresh token that replaced a used one
+	SetRotatedRefresh(token, next string) error
+
+	// GetDeviceAuthorization retrieves the authorization of a device by its device code
+	GetDeviceAuthorization(deviceCode string) (*DeviceAuthorization, error)
+
+	// GetDeviceAuthorizationByUserCode retrieves the authorization of a device by its user code
+	GetDeviceAuthorizationByUserCode(userCode string) (*DeviceAuthorization, error)
+
+	// SetDeviceAuthorization creates or updates the authorization of a device
+	SetDeviceAuthorization(auth *DeviceAuthorization) error
+
+	// RemoveDeviceAuthorization removes the authorization of a device, failing if it was already removed
+	RemoveDeviceAuthorization(auth *DeviceAuthorization) error
+
+	// GetDevicePoll retrieves the last poll of a device
+	GetDevicePoll(deviceCode string) (*DevicePoll, error)
+
+	// SetDevicePoll records the last poll of a device
+	SetDevicePoll(auth *DeviceAuthorization, poll *DevicePoll) error
 }
 
 // TykOsinServer subclasses osin.Server so we can add the SetClient method without wrecking the lbrary
@@ -926,6 +1145,7 @@ func (r *RedisOsinStorageInterface) LoadAuthorize(code string) (*osin.AuthorizeD
 func (r *RedisOsinStorageInterface) RemoveAuthorize(code string) error {
 	key := prefixAuth + code
 	r.store.DeleteKey(key)
+	r.store.DeleteKey(prefixCodeChallenge + code)
 	return nil
 }
 
@@ -1013,12 +1233,8 @@ func (r *RedisOsinStorageInterface) SaveAccess(accessData *osin.AccessData) erro
 			return err
 		}
 		key := prefixRefresh + accessData.RefreshToken
-		refreshExpire := int64(1209600) // 14 days
-		if oauthRefreshExpire := r.Gw.GetConfig().OauthRefreshExpire; oauthRefreshExpire != 0 {
-			refreshExpire = oauthRefreshExpire
-		}
 		log.Debug("STORING ACCESS DATA: ", string(accessDataJSON))
-		err = r.store.SetKey(key, string(accessDataJSON), refreshExpire)
+		err = r.store.SetKey(key, string(accessDataJSON), r.refreshExpire())
 		if err != nil {
 			log.WithError(err).Error("could not save access data")
 		}
@@ -1097,6 +1313,14 @@ func (r *RedisOsinStorageInterface) LoadRefresh(token string) (*osin.AccessData,
 	return &accessData, nil
 }
 
+// refreshExpire returns the TTL of refresh tokens in seconds.
+func (r *RedisOsinStorageInterface) refreshExpire() int64 {
+	if oauthRefreshExpire := r.Gw.GetConfig().OauthRefreshExpire; oauthRefreshExpire != 0 {
+		return oauthRefreshExpire
+	}
+	return 1209600 // 14 days
+}
+
 // RemoveRefresh will remove a refresh token from redis
 func (r *RedisOsinStorageInterface) RemoveRefresh(token string) error {
 	log.Debug("is going to revoke refresh token: ", token)
@@ -1161,6 +1385,7 @@ func (r *RedisOsinStorageInterface) GetUser(username string) (*user.SessionState
 			"; Decoding: ", accessJSON)
 		return nil, err
 	}
+	session.Migrate()
 
 	return session, nil
 }
@@ -1180,3 +1405,149 @@ func (r *RedisOsinStorageInterface) SetUser(username string, session *user.Sessi
 	return nil
 
 }
+
+func consentKey(clientID, userID string) string {
+	return prefixConsent + clientID + "." + userID
+}
+
+// GetConsent returns the scopes userID granted to clientID, storage.ErrKeyNotFound if the user
+// never granted consent.
+func (r *RedisOsinStorageInterface) GetConsent(clientID, userID string) ([]string, error) {
+	consentJSON, err := r.store.GetKey(consentKey(clientID, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	var scopes []string
+	if err := json.Unmarshal([]byte(consentJSON), &scopes); err != nil {
+		log.Error("Couldn't unmarshal OAuth consent: ", err)
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// SetConsent records the scopes userID granted to clientID, replacing previous grants.
+func (r *RedisOsinStorageInterface) SetConsent(clientID, userID string, scopes []string) error {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	consentJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return err
+	}
+	return r.store.SetKey(consentKey(clientID, userID), string(consentJSON), 0)
+}
+
+// GetCodeChallenge returns the PKCE challenge of code and its method, storage.ErrKeyNotFound if
+// the code was issued without challenge.
+func (r *RedisOsinStorageInterface) GetCodeChallenge(code string) (challenge, method string, err error) {
+	value, err := r.store.GetKey(prefixCodeChallenge + code)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.New("invalid code challenge")
+	}
+	return parts[1], parts[0], nil
+}
+
+// SetCodeChallenge records the PKCE challenge of code, it expires with the code.
+func (r *RedisOsinStorageInterface) SetCodeChallenge(code, challenge, method string, expiresIn int64) error {
+	return r.store.SetKey(prefixCodeChallenge+code, method+":"+challenge, expiresIn)
+}
+
+// GetRotatedRefresh returns the refresh token issued in exchange of token, storage.ErrKeyNotFound
+// if token wasn't used.
+func (r *RedisOsinStorageInterface) GetRotatedRefresh(token string) (string, error) {
+	return r.store.GetKey(prefixRotatedRefresh + token)
+}
+
+// SetRotatedRefresh records that token was exchanged for next, as long as token would have lived.
+func (r *RedisOsinStorageInterface) SetRotatedRefresh(token, next string) error {
+	return r.store.SetKey(prefixRotatedRefresh+token, next, r.refreshExpire())
+}
+
+// GetDeviceAuthorization returns the authorization of the device of deviceCode.
+func (r *RedisOsinStorageInterface) GetDeviceAuthorization(deviceCode string) (*DeviceAuthorization, error) {
+	authJSON, err := r.store.GetKey(prefixDeviceCode + deviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &DeviceAuthorization{}
+	if err := json.Unmarshal([]byte(authJSON), auth); err != nil {
+		log.Error("Couldn't unmarshal OAuth device authorization: ", err)
+		return nil, err
+	}
+	return auth, nil
+}
+
+// GetDeviceAuthorizationByUserCode returns the authorization of the device userCode was issued to.
+func (r *RedisOsinStorageInterface) GetDeviceAuthorizationByUserCode(userCode string) (*DeviceAuthorization, error) {
+	deviceCode, err := r.store.GetKey(prefixUserCode + userCode)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetDeviceAuthorization(deviceCode)
+}
+
+// SetDeviceAuthorization saves the authorization of a device until it expires.
+func (r *RedisOsinStorageInterface) SetDeviceAuthorization(auth *DeviceAuthorization) error {
+	authJSON, err := json.Marshal(auth)
+	if err != nil {
+		return err
+	}
+
+	expiresIn := deviceAuthorizationTTL(auth)
+	if err := r.store.SetKey(prefixDeviceCode+auth.DeviceCode, string(authJSON), expiresIn); err != nil {
+		return err
+	}
+	return r.store.SetKey(prefixUserCode+auth.UserCode, auth.DeviceCode, expiresIn)
+}
+
+// RemoveDeviceAuthorization removes the authorization of a device, storage.ErrKeyNotFound if it
+// was already removed. Only one of concurrent calls succeeds, the removal being atomic.
+func (r *RedisOsinStorageInterface) RemoveDeviceAuthorization(auth *DeviceAuthorization) error {
+	removed := r.store.DeleteKey(prefixDeviceCode + auth.DeviceCode)
+	r.store.DeleteKey(prefixUserCode + auth.UserCode)
+	r.store.DeleteKey(prefixDevicePoll + auth.DeviceCode)
+	if !removed {
+		return storage.ErrKeyNotFound
+	}
+	return nil
+}
+
+// GetDevicePoll returns the last poll of the device of deviceCode.
+func (r *RedisOsinStorageInterface) GetDevicePoll(deviceCode string) (*DevicePoll, error) {
+	pollJSON, err := r.store.GetKey(prefixDevicePoll + deviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	poll := &DevicePoll{}
+	if err := json.Unmarshal([]byte(pollJSON), poll); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// SetDevicePoll saves the last poll of the device of auth until auth expires.
+func (r *RedisOsinStorageInterface) SetDevicePoll(auth *DeviceAuthorization, poll *DevicePoll) error {
+	pollJSON, err := json.Marshal(poll)
+	if err != nil {
+		return err
+	}
+	return r.store.SetKey(prefixDevicePoll+auth.DeviceCode, string(pollJSON), deviceAuthorizationTTL(auth))
+}
+
+// deviceAuthorizationTTL returns the number of seconds until auth expires.
+func deviceAuthorizationTTL(auth *DeviceAuthorization) int64 {
+	expiresIn := auth.ExpiresAt - time.Now().Unix()
+	if expiresIn < 1 {
+		// a TTL of 0 would never expire
+		expiresIn = 1
+	}
+	return expiresIn
+}
@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/lonelycode/osin"
+
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+// Proof Key for Code Exchange, see https://tools.ietf.org/html/rfc7636
+const (
+	codeChallengeField       = "code_challenge"
+	codeChallengeMethodField = "code_challenge_method"
+	codeVerifierField        = "code_verifier"
+
+	codeChallengeMethodPlain = "plain"
+	codeChallengeMethodS256  = "S256"
+)
+
+var (
+	errInvalidCodeChallenge           = errors.New("code_challenge must be 43 to 128 unreserved characters")
+	errUnsupportedCodeChallengeMethod = errors.New("code_challenge_method must be plain or S256")
+	errMissingCodeChallenge           = errors.New("code_challenge_method sent without code_challenge")
+	errInvalidCodeVerifier            = errors.New("code_verifier doesn't match the code_challenge")
+	errUnexpectedCodeVerifier         = errors.New("code_verifier sent for a code issued without code_challenge")
+)
+
+// validPKCEValue reports whether v is a valid code challenge or code verifier.
+func validPKCEValue(v string) bool {
+	if len(v) < 43 || len(v) > 128 {
+		return false
+	}
+	for _, c := range v {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// codeChallengeFromRequest returns the code challenge of an authorize request, empty when the
+// client doesn't use PKCE.
+func codeChallengeFromRequest(r *http.Request) (challenge, method string, err error) {
+	challenge = r.FormValue(codeChallengeField)
+	method = r.FormValue(codeChallengeMethodField)
+	if challenge == "" {
+		if method != "" {
+			return "", "", errMissingCodeChallenge
+		}
+		return "", "", nil
+	}
+
+	if method == "" {
+		// unlike RFC 7636, which defaults to plain, the weaker method has to be asked for
+		method = codeChallengeMethodS256
+	}
+	if method != codeChallengeMethodPlain && method != codeChallengeMethodS256 {
+		return "", "", errUnsupportedCodeChallengeMethod
+	}
+	if !validPKCEValue(challenge) {
+		return "", "", errInvalidCodeChallenge
+	}
+	return challenge, method, nil
+}
+
+// verifyCodeVerifier reports whether verifier is the secret challenge was derived from.
+func verifyCodeVerifier(verifier, challenge, method string) bool {
+	if !validPKCEValue(verifier) {
+		return false
+	}
+
+	expected := verifier
+	if method == codeChallengeMethodS256 {
+		sum := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// handleAuthorizeRequest wraps osin's HandleAuthorizeRequest, rejecting invalid code challenges.
+func (o *OAuthManager) handleAuthorizeRequest(resp *osin.Response, r *http.Request) *osin.AuthorizeRequest {
+	ar := o.OsinServer.HandleAuthorizeRequest(resp, r)
+	if ar == nil || ar.Type != osin.CODE {
+		return ar
+	}
+
+	if _, _, err := codeChallengeFromRequest(r); err != nil {
+		resp.SetErrorState(osin.E_INVALID_REQUEST, err.Error(), ar.State)
+		resp.InternalError = err
+		return nil
+	}
+	return ar
+}
+
+// finishAuthorizeRequest wraps osin's FinishAuthorizeRequest, recording the code challenge of the
+// issued authorization code.
+func (o *OAuthManager) finishAuthorizeRequest(resp *osin.Response, r *http.Request, ar *osin.AuthorizeRequest) {
+	o.OsinServer.FinishAuthorizeRequest(resp, r, ar)
+	if resp.IsError || !ar.Authorized || ar.Type != osin.CODE {
+		return
+	}
+
+	challenge, method, _ := codeChallengeFromRequest(r)
+	if challenge == "" {
+		return
+	}
+
+	code, _ := resp.Output["code"].(string)
+	if err := o.OsinServer.Storage.SetCodeChallenge(code, challenge, method, int64(ar.Expiration)); err != nil {
+		// the code can't be issued without its challenge
+		o.OsinServer.Storage.RemoveAuthorize(code)
+		resp.SetErrorState(osin.E_SERVER_ERROR, "", ar.State)
+		resp.InternalError = err
+	}
+}
+
+// checkCodeVerifier verifies the code verifier of an authorization code access request, when the
+// code was issued with a challenge. Public clients, which have no secret to send, are
+// authenticated by the verifier alone. Confidential clients still have to send their secret.
+func (o *OAuthManager) checkCodeVerifier(resp *osin.Response, r *http.Request) bool {
+	code := r.Form.Get("code")
+	if r.Form.Get("grant_type") != string(osin.AUTHORIZATION_CODE) || code == "" {
+		return true
+	}
+
+	verifier := r.Form.Get(codeVerifierField)
+	challenge, method, err := o.OsinServer.Storage.GetCodeChallenge(code)
+	switch {
+	case err == storage.ErrKeyNotFound:
+		if verifier == "" {
+			return true
+		}
+		err = errUnexpectedCodeVerifier
+	case err != nil:
+		resp.SetError(osin.E_SERVER_ERROR, "")
+		resp.InternalError = err
+		return false
+	case !verifyCodeVerifier(verifier, challenge, method):
+		err = errInvalidCodeVerifier
+	default:
+		if _, _, ok := r.BasicAuth(); !ok {
+			if client, err := o.OsinServer.Storage.GetClient(r.Form.Get("client_id")); err == nil && client.GetSecret() == "" {
+				r.SetBasicAuth(client.GetId(), "")
+			}
+		}
+		return true
+	}
+
+	resp.SetError(osin.E_INVALID_GRANT, "")
+	resp.InternalError = err
+	return false
+}
+
+// authorizeCodeChallenge returns the code challenge parameters of ar, empty when they are unknown.
+func authorizeCodeChallenge(ar *osin.AuthorizeRequest) (challenge, method string) {
+	if ar.HttpRequest == nil {
+		return "", ""
+	}
+	return ar.HttpRequest.FormValue(codeChallengeField), ar.HttpRequest.FormValue(codeChallengeMethodField)
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// OrgLimits are the global rate limit and quota of an organisation, shared by all the keys of
+// the organisation on top of their own limits. They are enforced with enforce_org_quotas.
+type OrgLimits struct {
+	OrgID string `json:"org_id"`
+	// Rate is the number of requests allowed every Per seconds, 0 disables the rate limit.
+	Rate float64 `json:"rate"`
+	Per  float64 `json:"per"`
+	// QuotaMax is the number of requests allowed every QuotaRenewalRate seconds, 0 or -1
+	// disables the quota.
+	QuotaMax         int64 `json:"quota_max"`
+	QuotaRenewalRate int64 `json:"quota_renewal_rate"`
+	QuotaRemaining   int64 `json:"quota_remaining"`
+	QuotaRenews      int64 `json:"quota_renews"`
+}
+
+func newOrgLimits(orgID string, session *user.SessionState) OrgLimits {
+	return OrgLimits{
+		OrgID:            orgID,
+		Rate:             session.Rate,
+		Per:              session.Per,
+		QuotaMax:         session.QuotaMax,
+		QuotaRenewalRate: session.QuotaRenewalRate,
+		QuotaRemaining:   session.QuotaRemaining,
+		QuotaRenews:      session.QuotaRenews,
+	}
+}
+
+// orgSessionManager returns the session store of the organisation sessions.
+func (gw *Gateway) orgSessionManager(orgID string) SessionHandler {
+	if spec := gw.getSpecForOrg(orgID); spec != nil {
+		return spec.OrgSessionManager
+	}
+	if gw.GetConfig().SupressDefaultOrgStore {
+		return nil
+	}
+	return &gw.DefaultOrgStore
+}
+
+// orgLimitsHandler reads, sets or removes the limits of an organisation, the organisation
+// session being created when the organisation has none yet.
+func (gw *Gateway) orgLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	orgID := mux.Vars(r)["orgID"]
+
+	sessionManager := gw.orgSessionManager(orgID)
+	if sessionManager == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("No such organisation found in Active API list"))
+		return
+	}
+
+	session, found := sessionManager.SessionDetail(orgID, orgID, false)
+
+	switch r.Method {
+	case http.MethodGet:
+		if !found {
+			doJSONWrite(w, http.StatusNotFound, apiError("Org not found"))
+			return
+		}
+		doJSONWrite(w, http.StatusOK, newOrgLimits(orgID, &session))
+		return
+
+	case http.MethodPut:
+		var limits OrgLimits
+		if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+			log.Error("Couldn't decode org limits: ", err)
+			doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+			return
+		}
+		if limits.Rate < 0 || limits.Per < 0 || (limits.Rate > 0 && limits.Per == 0) {
+			doJSONWrite(w, http.StatusBadRequest, apiError("rate and per must be positive"))
+			return
+		}
+		if limits.QuotaMax > 0 && limits.QuotaRenewalRate <= 0 {
+			doJSONWrite(w, http.StatusBadRequest, apiError("quota_renewal_rate must be positive"))
+			return
+		}
+
+		if !found {
+			session = user.SessionState{OrgID: orgID}
+		}
+		quotaChanged := session.QuotaMax != limits.QuotaMax || session.QuotaRenewalRate != limits.QuotaRenewalRate
+		session.Rate = limits.Rate
+		session.Per = limits.Per
+		session.QuotaMax = limits.QuotaMax
+		session.QuotaRenewalRate = limits.QuotaRenewalRate
+		if quotaChanged {
+			gw.resetOrgQuota(sessionManager, orgID, &session)
+		}
+
+	case http.MethodDelete:
+		if !found {
+			doJSONWrite(w, http.StatusNotFound, apiError("Org not found"))
+			return
+		}
+		session.Rate = 0
+		session.Per = 0
+		session.QuotaMax = -1
+		session.QuotaRenewalRate = 0
+		gw.resetOrgQuota(sessionManager, orgID, &session)
+	}
+
+	if err := sessionManager.UpdateSession(orgID, &session, 0, false); err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Error writing to key store "+err.Error()))
+		return
+	}
+	gw.orgSessionUpdated(orgID)
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+		"org":    orgID,
+		"status": "ok",
+	}).Info("Organisation limits updated.")
+
+	doJSONWrite(w, http.StatusOK, newOrgLimits(orgID, &session))
+}
+
+// resetOrgQuota restarts the quota of the organisation, for a new quota to apply right away.
+func (gw *Gateway) resetOrgQuota(sessionManager SessionHandler, orgID string, session *user.SessionState) {
+	sessionManager.ResetQuota(orgID, session, false)
+	gw.DefaultQuotaStore.RemoveSession(orgID, QuotaKeyPrefix+storage.HashKey(orgID, gw.GetConfig().HashKeys), false)
+	session.QuotaRemaining = session.QuotaMax
+	session.QuotaRenews = 0
+	if session.QuotaRenewalRate > 0 {
+		session.QuotaRenews = time.Now().Unix() + session.QuotaRenewalRate
+	}
+}
+
+// orgSessionUpdated drops the cached organisation session, and flags the APIs of the organisation
+// as having one, for the organisation monitors to pick the session up with the next requests.
+func (gw *Gateway) orgSessionUpdated(orgID string) {
+	gw.SessionCache.Delete(orgID)
+
+	gw.apisMu.RLock()
+	defer gw.apisMu.RUnlock()
+	for _, spec := range gw.apisByID {
+		if spec.OrgID != orgID {
+			continue
+		}
+		spec.Lock()
+		spec.OrgHasNoSession = false
+		spec.Unlock()
+	}
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestOrgLimits(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnforceOrgQuotas = true
+	})
+	defer ts.Close()
+
+	orgID := "test-org-" + uuid.NewV4().String()
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = true
+		spec.OrgID = orgID
+		spec.Proxy.ListenPath = "/"
+	})
+
+	limitsPath := "/tyk/orgs/" + orgID + "/limits"
+
+	_, _ = ts.Run(t, []test.TestCase{
+		// the API has no org session yet
+		{Code: http.StatusOK},
+		{Path: limitsPath, AdminAuth: true, Code: http.StatusNotFound},
+		{Path: limitsPath, AdminAuth: true, Method: http.MethodPut, Data: `{"rate": 10}`, Code: http.StatusBadRequest},
+		{Path: limitsPath, AdminAuth: true, Method: http.MethodPut, Data: `{"quota_max": 2}`, Code: http.StatusBadRequest},
+		{Path: limitsPath, AdminAuth: true, Method: http.MethodPut, Data: `{"quota_max": 2, "quota_renewal_rate": 60}`,
+			Code: http.StatusOK, BodyMatch: `"quota_remaining":2`},
+		{Path: limitsPath, AdminAuth: true, BodyMatch: `"quota_max":2`, Code: http.StatusOK},
+		{Code: http.StatusOK},
+		{Code: http.StatusOK},
+		{Code: http.StatusForbidden},
+		{Path: limitsPath, AdminAuth: true, Method: http.MethodDelete, BodyMatch: `"quota_max":-1`, Code: http.StatusOK},
+		{Code: http.StatusOK},
+	}...)
+}
@@ -22,6 +22,7 @@ type DBAccessDefinition struct {
 	APIID             string                       `json:"apiid"`
 	Versions          []string                     `json:"versions"`
 	AllowedURLs       []user.AccessSpec            `bson:"allowed_urls" json:"allowed_urls"` // mapped string MUST be a valid regex
+	DeniedURLs        []user.AccessSpec            `bson:"denied_urls" json:"denied_urls"`
 	RestrictedTypes   []graphql.Type               `json:"restricted_types"`
 	FieldAccessRights []user.FieldAccessDefinition `json:"field_access_rights"`
 	Limit             *user.APILimit               `json:"limit"`
@@ -33,6 +34,7 @@ func (d *DBAccessDefinition) ToRegularAD() user.AccessDefinition {
 		APIID:             d.APIID,
 		Versions:          d.Versions,
 		AllowedURLs:       d.AllowedURLs,
+		DeniedURLs:        d.DeniedURLs,
 		RestrictedTypes:   d.RestrictedTypes,
 		FieldAccessRights: d.FieldAccessRights,
 	}
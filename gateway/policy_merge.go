@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// policiesToApply returns the policies of ids ordered by priority, the policies of the highest
+// priority last, and the strategy merging their rate limits and quotas.
+func (t BaseMiddleware) policiesToApply(ids []string) ([]user.Policy, string, error) {
+	policies := make([]user.Policy, 0, len(ids))
+	strategy := ""
+
+	t.Gw.policiesMu.RLock()
+	defer t.Gw.policiesMu.RUnlock()
+
+	for _, polID := range ids {
+		policy, ok := t.Gw.policiesByID[polID]
+		if !ok {
+			err := fmt.Errorf("policy not found: %q", polID)
+			t.Logger().Error(err)
+			return nil, "", err
+		}
+
+		switch policy.MergeStrategy {
+		case "":
+		case user.MergeStrategyMax, user.MergeStrategyMin, user.MergeStrategySum, user.MergeStrategyPriority:
+			if strategy != "" && strategy != policy.MergeStrategy {
+				err := fmt.Errorf("cannot apply policies with different merge strategies: %q and %q", strategy, policy.MergeStrategy)
+				t.Logger().Error(err)
+				return nil, "", err
+			}
+			strategy = policy.MergeStrategy
+		default:
+			err := fmt.Errorf("unknown merge strategy %q of policy %s", policy.MergeStrategy, policy.ID)
+			t.Logger().Error(err)
+			return nil, "", err
+		}
+
+		policies = append(policies, policy)
+	}
+
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Priority < policies[j].Priority
+	})
+
+	if strategy == "" {
+		strategy = user.MergeStrategyMax
+	}
+
+	return policies, strategy, nil
+}
+
+// mergeQuota merges the quota of policy into limit with strategy, limit being unset if first.
+func mergeQuota(strategy string, limit *user.APILimit, policy user.Policy, first bool) {
+	if first || strategy == user.MergeStrategyPriority {
+		limit.QuotaMax = policy.QuotaMax
+		limit.QuotaRenewalRate = policy.QuotaRenewalRate
+		return
+	}
+
+	switch strategy {
+	case user.MergeStrategyMin:
+		if greaterThanInt64(limit.QuotaMax, policy.QuotaMax) {
+			limit.QuotaMax = policy.QuotaMax
+			limit.QuotaRenewalRate = policy.QuotaRenewalRate
+		}
+	case user.MergeStrategySum:
+		switch {
+		case limit.QuotaMax == -1:
+		case policy.QuotaMax == -1:
+			limit.QuotaMax = -1
+		default:
+			limit.QuotaMax += policy.QuotaMax
+		}
+
+		if policy.QuotaRenewalRate > limit.QuotaRenewalRate {
+			limit.QuotaRenewalRate = policy.QuotaRenewalRate
+		}
+	}
+}
+
+// mergeRateLimit merges the rate limit of policy into limit with strategy, limit being unset if
+// first. The rates of different periods are compared and added up per second.
+func mergeRateLimit(strategy string, limit *user.APILimit, policy user.Policy, first bool) {
+	if first || strategy == user.MergeStrategyPriority {
+		limit.Rate = policy.Rate
+		limit.Per = policy.Per
+		return
+	}
+
+	switch strategy {
+	case user.MergeStrategyMin:
+		if rateGreaterThan(limit.Rate, limit.Per, policy.Rate, policy.Per) {
+			limit.Rate = policy.Rate
+			limit.Per = policy.Per
+		}
+	case user.MergeStrategySum:
+		switch {
+		case limit.Rate == -1:
+		case policy.Rate == -1:
+			limit.Rate = -1
+		case policy.Per <= 0:
+		case limit.Per <= 0:
+			limit.Rate = policy.Rate
+			limit.Per = policy.Per
+		default:
+			limit.Rate += policy.Rate * limit.Per / policy.Per
+		}
+	}
+}
+
+// rateGreaterThan checks whether the first rate limit allows more requests than the second one,
+// -1 being unlimited.
+func rateGreaterThan(firstRate, firstPer, secondRate, secondPer float64) bool {
+	if firstRate == -1 || secondRate == -1 {
+		return greaterThanFloat64(firstRate, secondRate)
+	}
+
+	return ratePerSecond(firstRate, firstPer) > ratePerSecond(secondRate, secondPer)
+}
+
+func ratePerSecond(rate, per float64) float64 {
+	if per <= 0 {
+		return rate
+	}
+	return rate / per
+}
@@ -1,10 +1,14 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
@@ -46,6 +50,36 @@ func TestLoadPoliciesFromDashboardReLogin(t *testing.T) {
 	}
 }
 
+func TestSyncPoliciesFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tyk-policies-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf := config.Config{}
+	conf.Policies.PolicyPath = dir
+	gw := NewGateway(conf, ctx, cancel)
+
+	file := filepath.Join(dir, "last.json")
+	if err := ioutil.WriteFile(file, []byte(`{"id":"last"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	count, err := gw.syncPolicies()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "last", gw.getPolicy("last").ID)
+
+	// deleting the last policy unloads it
+	assert.NoError(t, os.Remove(file))
+	count, err = gw.syncPolicies()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Empty(t, gw.getPolicy("last").ID)
+}
+
 type dummySessionManager struct {
 	DefaultSessionManager
 }
@@ -794,6 +828,140 @@ func BenchmarkApplyPolicies(b *testing.B) {
 	}
 }
 
+func TestApplyPoliciesMergeStrategy(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	bmid := &BaseMiddleware{
+		Spec: &APISpec{
+			APIDefinition: &apidef.APIDefinition{},
+		},
+		Gw: ts.Gw,
+	}
+
+	policy := func(id string, priority int, quotaMax, quotaRenewalRate int64, rate, per float64) user.Policy {
+		return user.Policy{
+			ID:               id,
+			Priority:         priority,
+			QuotaMax:         quotaMax,
+			QuotaRenewalRate: quotaRenewalRate,
+			Rate:             rate,
+			Per:              per,
+			AccessRights:     map[string]user.AccessDefinition{"a": {}},
+		}
+	}
+
+	type limits struct {
+		quotaMax, quotaRenewalRate int64
+		rate, per                  float64
+	}
+
+	tests := []struct {
+		name       string
+		strategies []string
+		policies   []user.Policy
+		want       limits
+		errMatch   string
+	}{
+		{
+			name:     "max by default",
+			policies: []user.Policy{policy("small", 2, 100, 3600, 10, 1), policy("large", 1, 1000, 60, 1200, 60)},
+			want:     limits{1000, 3600, 1200, 60},
+		},
+		{
+			name:       "min",
+			strategies: []string{user.MergeStrategyMin},
+			policies:   []user.Policy{policy("small", 2, 100, 3600, 10, 1), policy("large", 1, 1000, 60, 1200, 60)},
+			want:       limits{100, 3600, 10, 1},
+		},
+		{
+			name:       "min with unlimited",
+			strategies: []string{user.MergeStrategyMin},
+			policies:   []user.Policy{policy("unlimited", 0, -1, 0, -1, 0), policy("large", 1, 1000, 60, 1200, 60)},
+			want:       limits{1000, 60, 1200, 60},
+		},
+		{
+			name:       "sum",
+			strategies: []string{user.MergeStrategySum, user.MergeStrategySum},
+			policies:   []user.Policy{policy("small", 2, 100, 3600, 10, 1), policy("large", 1, 1000, 60, 1200, 60)},
+			want:       limits{1100, 3600, 1800, 60},
+		},
+		{
+			name:       "sum with unlimited",
+			strategies: []string{user.MergeStrategySum},
+			policies:   []user.Policy{policy("small", 2, 100, 3600, 10, 1), policy("unlimited", 0, -1, 0, -1, 0)},
+			want:       limits{-1, 3600, -1, 0},
+		},
+		{
+			name:       "sum of partitions",
+			strategies: []string{user.MergeStrategySum},
+			policies: []user.Policy{
+				{ID: "quota1", QuotaMax: 2, QuotaRenewalRate: 60, Partitions: user.PolicyPartitions{Quota: true}},
+				{ID: "quota2", QuotaMax: 3, QuotaRenewalRate: 60, Partitions: user.PolicyPartitions{Quota: true}},
+			},
+			want: limits{5, 60, 0, 0},
+		},
+		{
+			name:       "priority",
+			strategies: []string{user.MergeStrategyPriority},
+			policies:   []user.Policy{policy("small", 2, 100, 3600, 10, 1), policy("large", 1, 1000, 60, 1200, 60)},
+			want:       limits{100, 3600, 10, 1},
+		},
+		{
+			name:       "priority of the last policy",
+			strategies: []string{user.MergeStrategyPriority},
+			policies:   []user.Policy{policy("small", 1, 100, 3600, 10, 1), policy("large", 2, 1000, 60, 1200, 60)},
+			want:       limits{1000, 60, 1200, 60},
+		},
+		{
+			name:       "different strategies",
+			strategies: []string{user.MergeStrategyMin, user.MergeStrategySum},
+			policies:   []user.Policy{policy("small", 2, 100, 3600, 10, 1), policy("large", 1, 1000, 60, 1200, 60)},
+			errMatch:   "cannot apply policies with different merge strategies",
+		},
+		{
+			name:       "unknown strategy",
+			strategies: []string{"avg"},
+			policies:   []user.Policy{policy("small", 2, 100, 3600, 10, 1)},
+			errMatch:   `unknown merge strategy "avg" of policy small`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts.Gw.policiesMu.Lock()
+			ts.Gw.policiesByID = map[string]user.Policy{}
+			var ids []string
+			for i, pol := range tc.policies {
+				if i < len(tc.strategies) {
+					pol.MergeStrategy = tc.strategies[i]
+				}
+				ts.Gw.policiesByID[pol.ID] = pol
+				ids = append(ids, pol.ID)
+			}
+			ts.Gw.policiesMu.Unlock()
+
+			session := &user.SessionState{}
+			session.SetPolicies(ids...)
+			err := bmid.ApplyPolicies(session)
+			if tc.errMatch != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errMatch)
+				return
+			}
+			assert.NoError(t, err)
+
+			got := limits{session.QuotaMax, session.QuotaRenewalRate, session.Rate, session.Per}
+			assert.Equal(t, tc.want, got)
+
+			if rights, ok := session.AccessRights["a"]; ok {
+				got = limits{rights.Limit.QuotaMax, rights.Limit.QuotaRenewalRate, rights.Limit.Rate, rights.Limit.Per}
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
 func TestApplyPoliciesQuotaAPILimit(t *testing.T) {
 	ts := StartTest(nil)
 	defer ts.Close()
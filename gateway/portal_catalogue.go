@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// portalTag marks the APIs published to the developer portals.
+const portalTag = "portal"
+
+// Auth modes of the catalogue entries.
+const (
+	portalAuthKeyless   = "keyless"
+	portalAuthToken     = "auth_token"
+	portalAuthBasic     = "basic"
+	portalAuthJWT       = "jwt"
+	portalAuthOAuth2    = "oauth2"
+	portalAuthOIDC      = "openid_connect"
+	portalAuthHMAC      = "hmac"
+	portalAuthMutualTLS = "mutual_tls"
+	portalAuthCustom    = "custom"
+)
+
+// PortalCatalogue lists the APIs tagged portal, for external developer portals to be generated from.
+type PortalCatalogue struct {
+	APIs []PortalCatalogueAPI `json:"apis"`
+}
+
+// PortalCatalogueAPI is an API of the catalogue. OAS is the OAS document of the OAS APIs only.
+type PortalCatalogueAPI struct {
+	APIID      string                  `json:"api_id"`
+	Name       string                  `json:"name"`
+	ListenPath string                  `json:"listen_path"`
+	AuthModes  []string                `json:"auth_modes"`
+	OAS        *openapi3.Swagger       `json:"oas,omitempty"`
+	Plans      []PortalCataloguePolicy `json:"plans"`
+}
+
+// PortalCataloguePolicy is a policy granting access to a catalogue API, with its limits for the API.
+type PortalCataloguePolicy struct {
+	PolicyID         string  `json:"policy_id"`
+	Name             string  `json:"name"`
+	Rate             float64 `json:"rate"`
+	Per              float64 `json:"per"`
+	QuotaMax         int64   `json:"quota_max"`
+	QuotaRenewalRate int64   `json:"quota_renewal_rate"`
+}
+
+// portalCatalogueHandler renders the catalogue of the APIs tagged portal, sorted by name.
+func (gw *Gateway) portalCatalogueHandler(w http.ResponseWriter, r *http.Request) {
+	catalogue := PortalCatalogue{APIs: []PortalCatalogueAPI{}}
+
+	gw.apisMu.RLock()
+	specs := make([]*APISpec, 0, len(gw.apisByID))
+	for _, spec := range gw.apisByID {
+		if spec.Active && hasPortalTag(spec.Tags) {
+			specs = append(specs, spec)
+		}
+	}
+	gw.apisMu.RUnlock()
+
+	gw.policiesMu.RLock()
+	policies := make([]user.Policy, 0, len(gw.policiesByID))
+	for _, policy := range gw.policiesByID {
+		policies = append(policies, policy)
+	}
+	gw.policiesMu.RUnlock()
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].ID < policies[j].ID
+	})
+
+	for _, spec := range specs {
+		api := PortalCatalogueAPI{
+			APIID:      spec.APIID,
+			Name:       spec.Name,
+			ListenPath: spec.Proxy.ListenPath,
+			AuthModes:  portalAuthModes(spec),
+			Plans:      portalPlans(spec.APIID, policies),
+		}
+		if spec.OAS.OpenAPI != "" {
+			oasDoc := spec.OAS
+			api.OAS = &oasDoc
+		}
+		catalogue.APIs = append(catalogue.APIs, api)
+	}
+
+	sort.Slice(catalogue.APIs, func(i, j int) bool {
+		if catalogue.APIs[i].Name != catalogue.APIs[j].Name {
+			return catalogue.APIs[i].Name < catalogue.APIs[j].Name
+		}
+		return catalogue.APIs[i].APIID < catalogue.APIs[j].APIID
+	})
+
+	doJSONWrite(w, http.StatusOK, catalogue)
+}
+
+func hasPortalTag(tags []string) bool {
+	for _, tag := range tags {
+		if tag == portalTag {
+			return true
+		}
+	}
+	return false
+}
+
+// portalAuthModes returns the auth modes the API accepts, all of them required when there are
+// several ones.
+func portalAuthModes(spec *APISpec) []string {
+	if spec.UseKeylessAccess {
+		return []string{portalAuthKeyless}
+	}
+
+	var modes []string
+	if spec.UseMutualTLSAuth {
+		modes = append(modes, portalAuthMutualTLS)
+	}
+	if spec.UseOauth2 {
+		modes = append(modes, portalAuthOAuth2)
+	}
+	if spec.UseOpenID {
+		modes = append(modes, portalAuthOIDC)
+	}
+	if spec.EnableSignatureChecking {
+		modes = append(modes, portalAuthHMAC)
+	}
+	if spec.UseBasicAuth {
+		modes = append(modes, portalAuthBasic)
+	}
+	if spec.EnableJWT {
+		modes = append(modes, portalAuthJWT)
+	}
+	if spec.EnableCoProcessAuth || spec.UseGoPluginAuth {
+		modes = append(modes, portalAuthCustom)
+	}
+	// the API loader falls back to the standard auth when no other auth is enabled
+	if spec.UseStandardAuth || len(modes) == 0 {
+		modes = append(modes, portalAuthToken)
+	}
+	return modes
+}
+
+// portalPlans returns the policies granting access to the API, except the inactive ones, with
+// their per API limits when they have some.
+func portalPlans(apiID string, policies []user.Policy) []PortalCataloguePolicy {
+	plans := []PortalCataloguePolicy{}
+	for _, policy := range policies {
+		accessRights, ok := policy.AccessRights[apiID]
+		if !ok || policy.IsInactive {
+			continue
+		}
+
+		plan := PortalCataloguePolicy{
+			PolicyID:         policy.ID,
+			Name:             policy.Name,
+			Rate:             policy.Rate,
+			Per:              policy.Per,
+			QuotaMax:         policy.QuotaMax,
+			QuotaRenewalRate: policy.QuotaRenewalRate,
+		}
+		if limit := accessRights.Limit; !limit.IsEmpty() {
+			plan.Rate = limit.Rate
+			plan.Per = limit.Per
+			plan.QuotaMax = limit.QuotaMax
+			plan.QuotaRenewalRate = limit.QuotaRenewalRate
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
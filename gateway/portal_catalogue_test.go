@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestPortalCatalogueHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	newSpec := func(apiID, name string, tags ...string) *APISpec {
+		return &APISpec{APIDefinition: &apidef.APIDefinition{APIID: apiID, Name: name, Active: true, Tags: tags}}
+	}
+
+	pets := newSpec("pets", "Pets", "portal")
+	pets.UseKeylessAccess = true
+	pets.OAS = openapi3.Swagger{OpenAPI: "3.0.3", Info: &openapi3.Info{Title: "Pets", Version: "1"}}
+
+	orders := newSpec("orders", "Orders", "internal", "portal")
+	orders.EnableJWT = true
+	orders.UseStandardAuth = true
+
+	inactive := newSpec("inactive", "Inactive", "portal")
+	inactive.Active = false
+
+	gw.apisByID = map[string]*APISpec{
+		"pets":     pets,
+		"orders":   orders,
+		"internal": newSpec("internal", "Internal", "internal"),
+		"inactive": inactive,
+	}
+	gw.policiesByID = map[string]user.Policy{
+		"gold": {ID: "gold", Name: "Gold", Rate: 100, Per: 1, QuotaMax: -1, AccessRights: map[string]user.AccessDefinition{
+			"orders": {},
+			"pets":   {Limit: user.APILimit{Rate: 10, Per: 60, QuotaMax: 1000, QuotaRenewalRate: 3600}},
+		}},
+		"disabled": {ID: "disabled", IsInactive: true, AccessRights: map[string]user.AccessDefinition{"orders": {}}},
+	}
+
+	w := httptest.NewRecorder()
+	gw.portalCatalogueHandler(w, httptest.NewRequest(http.MethodGet, "/tyk/portal/catalogue", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var catalogue PortalCatalogue
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&catalogue))
+	require.Len(t, catalogue.APIs, 2)
+
+	assert.Equal(t, "orders", catalogue.APIs[0].APIID)
+	assert.Equal(t, []string{portalAuthJWT, portalAuthToken}, catalogue.APIs[0].AuthModes)
+	assert.Nil(t, catalogue.APIs[0].OAS)
+	assert.Equal(t, []PortalCataloguePolicy{{PolicyID: "gold", Name: "Gold", Rate: 100, Per: 1, QuotaMax: -1}},
+		catalogue.APIs[0].Plans)
+
+	assert.Equal(t, "pets", catalogue.APIs[1].APIID)
+	assert.Equal(t, []string{portalAuthKeyless}, catalogue.APIs[1].AuthModes)
+	require.NotNil(t, catalogue.APIs[1].OAS)
+	assert.Equal(t, "3.0.3", catalogue.APIs[1].OAS.OpenAPI)
+	assert.Equal(t, []PortalCataloguePolicy{{PolicyID: "gold", Name: "Gold", Rate: 10, Per: 60, QuotaMax: 1000, QuotaRenewalRate: 3600}},
+		catalogue.APIs[1].Plans)
+}
@@ -42,7 +42,9 @@ func (h *h2cWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (h *handleWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// make request body to be nopCloser and re-readable before serve it through chain of middlewares
-	nopCloseRequestBody(r)
+	if !h.streamsRequestBody(r) {
+		nopCloseRequestBody(r)
+	}
 	if NewRelicApplication != nil {
 		txn := NewRelicApplication.StartTransaction(r.URL.Path, w, r)
 		defer txn.End()
@@ -52,6 +54,26 @@ func (h *handleWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.router.ServeHTTP(w, r)
 }
 
+// streamsRequestBody reports whether r is a multipart upload to an API streaming them to the
+// upstream, whose body isn't buffered.
+func (h *handleWrapper) streamsRequestBody(r *http.Request) bool {
+	if _, ok := multipartBoundary(r); !ok {
+		return false
+	}
+
+	var match mux.RouteMatch
+	if !h.router.Match(r, &match) {
+		return false
+	}
+	_, ok := match.Handler.(*streamedBodyHandler)
+	return ok
+}
+
+// streamedBodyHandler is the handler of the APIs streaming multipart uploads.
+type streamedBodyHandler struct {
+	http.Handler
+}
+
 type proxy struct {
 	listener         net.Listener
 	port             int
@@ -211,7 +233,7 @@ func (gw *Gateway) flushNetworkAnalytics(ctx context.Context) {
 			gw.apisMu.RLock()
 			for _, spec := range gw.apiSpecs {
 				switch spec.Protocol {
-				case "tcp", "tls":
+				case "tcp", "tls", "mqtt", "mqtts", "amqp", "amqps":
 					// we only flush network analytics for these services
 				default:
 					continue
@@ -387,7 +409,7 @@ func (m *proxyMux) serve(gw *Gateway) {
 			continue
 		}
 		switch p.protocol {
-		case "tcp", "tls":
+		case "tcp", "tls", "mqtt", "mqtts", "amqp", "amqps":
 			mainLog.Warning("Starting TCP server on:", p.listener.Addr().String())
 			go p.tcpProxy.Serve(p.getListener())
 		case "http", "https", "h2c":
@@ -407,17 +429,22 @@ func (m *proxyMux) serve(gw *Gateway) {
 			// by default enabling h2c by wrapping handler in h2c. This ensures all features including tracing work
 			// in h2c services.
 			h2s := &http2.Server{}
-			h = &h2cWrapper{
+			wrapper := &h2cWrapper{
 				w: h.(*handleWrapper),
 				h: h2c.NewHandler(h, h2s),
 			}
+			if gw.acme != nil && p.protocol == "http" {
+				wrapper.h = gw.acme.httpHandler(wrapper.h)
+			}
+			h = wrapper
 
 			addr := conf.ListenAddress + ":" + strconv.Itoa(p.port)
 			p.httpServer = &http.Server{
-				Addr:         addr,
-				ReadTimeout:  readTimeout,
-				WriteTimeout: writeTimeout,
-				Handler:      h,
+				Addr:           addr,
+				ReadTimeout:    readTimeout,
+				WriteTimeout:   writeTimeout,
+				MaxHeaderBytes: conf.HttpServerOptions.MaxHeaderBytes,
+				Handler:        h,
 			}
 
 			if conf.CloseConnections {
@@ -429,6 +456,38 @@ func (m *proxyMux) serve(gw *Gateway) {
 	}
 }
 
+// shutdown closes the listeners and waits for the connections of the HTTP servers to be idle,
+// until ctx is done.
+func (m *proxyMux) shutdown(ctx context.Context) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(m.proxies))
+	for _, p := range m.proxies {
+		if p.httpServer == nil {
+			if p.listener != nil {
+				p.listener.Close()
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			errs <- srv.Shutdown(ctx)
+		}(p.httpServer)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func target(listenAddress string, listenPort int) string {
 	return fmt.Sprintf("%s:%d", listenAddress, listenPort)
 }
@@ -460,7 +519,7 @@ func (m *proxyMux) generateListener(listenPort int, protocol string, gw *Gateway
 		return ls, nil
 	}
 	switch protocol {
-	case "https", "tls":
+	case "https", "tls", "mqtts", "amqps":
 		mainLog.Infof("--> Using TLS (%s)", protocol)
 		httpServerOptions := conf.HttpServerOptions
 
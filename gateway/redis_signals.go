@@ -140,7 +140,7 @@ func (gw *Gateway) handleKeySpaceEventCacheFlush(payload string) {
 		}
 
 		gw.RPCGlobalCache.Delete("apikey-" + key)
-		gw.SessionCache.Delete(key)
+		gw.deleteCachedSession(key)
 	}
 }
 
@@ -204,8 +204,8 @@ type RedisNotifier struct {
 	*Gateway
 }
 
-// Notify will send a notification to a channel
-func (r *RedisNotifier) Notify(notif interface{}) bool {
+// message returns the message notif is published as, signed if it's a Notification.
+func (r *RedisNotifier) message(notif interface{}) (string, bool) {
 	if n, ok := notif.(Notification); ok {
 		n.Sign()
 		notif = n
@@ -216,12 +216,21 @@ func (r *RedisNotifier) Notify(notif interface{}) bool {
 	if err != nil {
 
 		pubSubLog.Error("Problem marshalling notification: ", err)
+		return "", false
+	}
+	return string(toSend), true
+}
+
+// Notify will send a notification to a channel
+func (r *RedisNotifier) Notify(notif interface{}) bool {
+	toSend, ok := r.message(notif)
+	if !ok {
 		return false
 	}
 
 	// pubSubLog.Debug("Sending notification", notif)
 
-	if err := r.store.Publish(r.channel, string(toSend)); err != nil {
+	if err := r.store.Publish(r.channel, toSend); err != nil {
 		if err != storage.ErrRedisIsDown {
 			pubSubLog.Error("Could not send notification: ", err)
 		}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/trace"
+)
+
+const (
+	requestIDFormatUUID  = "uuid"
+	requestIDFormatTrace = "trace"
+
+	// maxRequestIDLength bounds the IDs kept from the clients, the longer ones are replaced.
+	maxRequestIDLength = 200
+)
+
+// validRequestID are the IDs kept from the clients, so they are safe in headers and logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]+$`)
+
+// requestIDHeader returns the header of the request IDs.
+func requestIDHeader(conf config.RequestIDConfig) string {
+	if conf.Header != "" {
+		return conf.Header
+	}
+	return headers.XRequestID
+}
+
+// newRequestID generates a request ID in the configured format. The trace IDs are the ones of the
+// tracing span of the request when tracing is enabled.
+func newRequestID(r *http.Request, format string) string {
+	if format != requestIDFormatTrace {
+		return uuid.NewV4().String()
+	}
+
+	if traceID, _ := trace.IDs(r.Context()); len(traceID) == 32 {
+		return traceID
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return hex.EncodeToString(uuid.NewV4().Bytes())
+	}
+	return hex.EncodeToString(id)
+}
+
+// requestIDHandler gives an ID to the requests, the one in the request ID header when valid or a
+// generated one, before any middleware. It sends the ID to the upstream and back to the client by
+// config.
+func (gw *Gateway) requestIDHandler(next http.Handler) http.Handler {
+	conf := gw.GetConfig().RequestID
+	if !conf.Enabled {
+		return next
+	}
+	header := requestIDHeader(conf)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if len(id) > maxRequestIDLength || !validRequestID.MatchString(id) {
+			id = newRequestID(r, conf.Format)
+		}
+		ctxSetRequestID(r, id)
+
+		if conf.InjectUpstream {
+			r.Header.Set(header, id)
+		}
+		if conf.InjectResponse {
+			w.Header().Set(header, id)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDResponseHeader drops the request ID header of the upstream response when the gateway
+// sends its own, for the clients to get a single ID.
+func requestIDResponseHeader(conf config.RequestIDConfig, res *http.Response) {
+	if conf.Enabled && conf.InjectResponse {
+		res.Header.Del(requestIDHeader(conf))
+	}
+}
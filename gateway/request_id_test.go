@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	newHandler := func(conf config.RequestIDConfig) (http.Handler, *http.Request) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		gw := NewGateway(config.Config{RequestID: conf}, ctx, cancel)
+
+		var upstream http.Request
+		return gw.requestIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstream = *r
+		})), &upstream
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		handler, upstream := newHandler(config.RequestIDConfig{})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, ctxGetRequestID(upstream))
+		assert.Empty(t, w.Header().Get(headers.XRequestID))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		handler, upstream := newHandler(config.RequestIDConfig{Enabled: true, InjectUpstream: true, InjectResponse: true})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		id := ctxGetRequestID(upstream)
+		assert.Len(t, id, 36)
+		assert.Equal(t, id, upstream.Header.Get(headers.XRequestID))
+		assert.Equal(t, id, w.Header().Get(headers.XRequestID))
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		handler, upstream := newHandler(config.RequestIDConfig{Enabled: true, Header: "X-Correlation-ID"})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Correlation-ID", "abc-123")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, "abc-123", ctxGetRequestID(upstream))
+		assert.Empty(t, w.Header().Get("X-Correlation-ID"))
+	})
+
+	t.Run("invalid replaced with trace ID", func(t *testing.T) {
+		handler, upstream := newHandler(config.RequestIDConfig{Enabled: true, Format: requestIDFormatTrace})
+		for _, id := range []string{"bad id\n", strings.Repeat("a", maxRequestIDLength+1)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(headers.XRequestID, id)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), ctxGetRequestID(upstream))
+		}
+	})
+
+	t.Run("upstream response header dropped", func(t *testing.T) {
+		res := &http.Response{Header: http.Header{}}
+		res.Header.Set(headers.XRequestID, "upstream")
+		requestIDResponseHeader(config.RequestIDConfig{Enabled: true}, res)
+		assert.Equal(t, "upstream", res.Header.Get(headers.XRequestID))
+
+		requestIDResponseHeader(config.RequestIDConfig{Enabled: true, InjectResponse: true}, res)
+		assert.Empty(t, res.Header.Get(headers.XRequestID))
+	})
+}
@@ -42,7 +42,10 @@ func (h *ResponseGoPluginMiddleware) Init(c interface{}, spec *APISpec) error {
 
 	// try to load plugin
 	var err error
-	if h.ResHandler, err = goplugin.GetResponseHandler(h.Path, h.SymbolName); err != nil {
+	goPluginLoadMu.Lock()
+	h.ResHandler, err = goplugin.GetResponseHandler(h.Path, h.SymbolName)
+	goPluginLoadMu.Unlock()
+	if err != nil {
 		h.logger.WithError(err).Error("Could not load Go-plugin")
 		return err
 	}
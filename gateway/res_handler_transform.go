@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/andybalholm/brotli"
 	"github.com/clbanning/mxj"
+	"github.com/klauspost/compress/zstd"
 	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/tyk/apidef"
@@ -37,9 +39,9 @@ func respBodyReader(req *http.Request, resp *http.Response) io.ReadCloser {
 		return resp.Body
 	}
 
-	switch resp.Header.Get(headers.ContentEncoding) {
-	case "gzip":
-		reader, err := gzip.NewReader(resp.Body)
+	switch encoding := resp.Header.Get(headers.ContentEncoding); encoding {
+	case "gzip", "deflate", "br", "zstd":
+		reader, err := decompressReader(encoding, resp.Body)
 		if err != nil {
 			log.Error("Body decompression error:", err)
 			return ioutil.NopCloser(bytes.NewReader(nil))
@@ -49,11 +51,6 @@ func respBodyReader(req *http.Request, resp *http.Response) io.ReadCloser {
 		resp.ContentLength = 0
 
 		return reader
-	case "deflate":
-		// represents unknown length
-		resp.ContentLength = 0
-
-		return flate.NewReader(resp.Body)
 	}
 
 	return resp.Body
@@ -69,6 +66,14 @@ func compressBuffer(in bytes.Buffer, encoding string) (out bytes.Buffer) {
 		zw, _ := flate.NewWriter(&out, 1)
 		zw.Write(in.Bytes())
 		zw.Close()
+	case "br":
+		zw := brotli.NewWriter(&out)
+		zw.Write(in.Bytes())
+		zw.Close()
+	case "zstd":
+		zw, _ := zstd.NewWriter(&out)
+		zw.Write(in.Bytes())
+		zw.Close()
 	default:
 		out = in
 	}
@@ -96,6 +101,12 @@ func (h *ResponseTransformMiddleware) HandleResponse(rw http.ResponseWriter, res
 	}
 	tmeta := meta.(*TransformSpec)
 
+	switch tmeta.TemplateData.Mode {
+	case apidef.UseJSONToXML, apidef.UseXMLToJSON:
+		convertResponseBody(req, res, tmeta.TemplateData)
+		return nil
+	}
+
 	respBody := respBodyReader(req, res)
 	body, _ := ioutil.ReadAll(respBody)
 	defer respBody.Close()
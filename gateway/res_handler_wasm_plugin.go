@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// ResponseWasmMiddleware runs a WebAssembly plugin on the response of the upstream. The plugin
+// may change the headers, the body or the status code of the response.
+type ResponseWasmMiddleware struct {
+	Spec       *APISpec
+	Definition apidef.MiddlewareDefinition
+	plugin     *wasmPlugin
+	logger     *logrus.Entry
+}
+
+func (ResponseWasmMiddleware) Name() string {
+	return "ResponseWasmMiddleware"
+}
+
+func (h *ResponseWasmMiddleware) Init(c interface{}, spec *APISpec) error {
+	h.Spec = spec
+	h.Definition = c.(apidef.MiddlewareDefinition)
+
+	h.logger = log.WithFields(logrus.Fields{
+		"prefix":       "wasm",
+		"mwPath":       h.Definition.Path,
+		"mwSymbolName": h.Definition.Name,
+	})
+
+	var err error
+	if h.plugin, err = loadWasmPlugin(h.Definition); err != nil {
+		h.logger.WithError(err).Error("Could not load wasm plugin")
+		return err
+	}
+	spec.wasmPlugins = append(spec.wasmPlugins, h.plugin)
+	h.logger.Infof("Loaded wasm response plugin: %s", h.Definition.Name)
+
+	return nil
+}
+
+func (h *ResponseWasmMiddleware) HandleError(rw http.ResponseWriter, req *http.Request) {
+}
+
+func (h *ResponseWasmMiddleware) HandleResponse(rw http.ResponseWriter, res *http.Response, req *http.Request, ses *user.SessionState) error {
+	if h.plugin == nil {
+		return errors.New("wasm plugin isn't loaded")
+	}
+
+	raw, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	contentLength := res.ContentLength
+	res.Body = ioutil.NopCloser(bytes.NewReader(raw))
+	body, _ := ioutil.ReadAll(respBodyReader(req, res))
+
+	call := &wasmCall{
+		method:          req.Method,
+		path:            req.URL.Path,
+		header:          res.Header,
+		body:            body,
+		session:         ses,
+		sessionReadOnly: true,
+		logger:          h.logger,
+	}
+
+	t1 := time.Now()
+	err = h.plugin.run(req.Context(), call)
+	ms := DurationToMillisecond(time.Since(t1))
+	h.logger.WithField("ms", ms).Debug("Wasm plugin response processing took")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to process response with wasm plugin")
+		return err
+	}
+
+	if call.responded {
+		if call.responseCode < 100 || call.responseCode > 599 {
+			h.logger.WithField("code", call.responseCode).Error("Wasm plugin responded with an invalid status code")
+			return errors.New("wasm plugin failed")
+		}
+		res.StatusCode = call.responseCode
+		res.Status = http.StatusText(call.responseCode)
+		call.body, call.bodyChanged = call.responseBody, true
+	}
+
+	if !call.bodyChanged {
+		res.ContentLength = contentLength
+		res.Body = ioutil.NopCloser(bytes.NewReader(raw))
+		return nil
+	}
+
+	var bodyBuffer bytes.Buffer
+	bodyBuffer.Write(call.body)
+	if req.Header.Get(headers.AcceptEncoding) != "" {
+		// Re-compress if original upstream response was decompressed by respBodyReader
+		bodyBuffer = compressBuffer(bodyBuffer, res.Header.Get(headers.ContentEncoding))
+	}
+
+	res.ContentLength = int64(bodyBuffer.Len())
+	res.Header.Set(headers.ContentLength, strconv.Itoa(bodyBuffer.Len()))
+	res.Body = ioutil.NopCloser(&bodyBuffer)
+
+	return nil
+}
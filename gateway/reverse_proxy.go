@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strconv"
 	"strings"
@@ -244,7 +246,7 @@ func (gw *Gateway) TykNewSingleHostReverseProxy(target *url.URL, spec *APISpec,
 			}
 			fallthrough // implies load balancing, with replaced host list
 		case spec.Proxy.EnableLoadBalancing:
-			host, err := gw.nextTarget(hostList, spec)
+			host, err := gw.nextHashedTarget(hostList, spec, req)
 			if err != nil {
 				log.Error("[PROXY] [LOAD BALANCING] ", err)
 				host = allHostsDownURL
@@ -259,6 +261,14 @@ func (gw *Gateway) TykNewSingleHostReverseProxy(target *url.URL, spec *APISpec,
 			}
 		}
 
+		// Canary routing is decided per request, so it must not leak into
+		// the target shared between requests.
+		target, targetQuery := target, targetQuery
+		if canaryTarget := spec.Canary.Target(req); canaryTarget != nil {
+			target = canaryTarget
+			targetQuery = canaryTarget.RawQuery
+		}
+
 		targetToUse := target
 
 		if spec.URLRewriteEnabled && req.Context().Value(ctx.RetainHost) == true {
@@ -318,6 +328,10 @@ func (gw *Gateway) TykNewSingleHostReverseProxy(target *url.URL, spec *APISpec,
 		case "wss":
 			req.URL.Scheme = "https"
 		}
+
+		if spec.GatewayFederation.Forward {
+			gw.setFederationHop(req)
+		}
 	}
 
 	if logger == nil {
@@ -403,13 +417,22 @@ func (p *ReverseProxy) defaultTransport(dialerTimeout float64) *http.Transport {
 		dialContextFunc = p.Gw.dnsCacheManager.WrapDialer(dialer)
 	}
 
-	return &http.Transport{
-		DialContext:           dialContextFunc,
+	transport := &http.Transport{
+		DialContext:           dialWithUpstreamTimeout(dialContextFunc),
 		MaxIdleConns:          p.Gw.GetConfig().MaxIdleConns,
 		MaxIdleConnsPerHost:   p.Gw.GetConfig().MaxIdleConnsPerHost, // default is 100
 		ResponseHeaderTimeout: time.Duration(dialerTimeout) * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 	}
+
+	if n := p.TykAPISpec.Proxy.Transport.MaxIdleConnsPerHost; n > 0 {
+		transport.MaxIdleConnsPerHost = n
+	}
+	if timeout := p.TykAPISpec.Proxy.Transport.IdleConnTimeout; timeout > 0 {
+		transport.IdleConnTimeout = time.Duration(timeout * float64(time.Second))
+	}
+
+	return transport
 }
 
 func singleJoiningSlash(a, b string, disableStripSlash bool) string {
@@ -494,7 +517,10 @@ var hopHeaders = []string{
 func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) ProxyResponse {
 	startTime := time.Now()
 	p.logger.WithField("ts", startTime.UnixNano()).Debug("Started")
-	resp := p.WrappedServeHTTP(rw, req, recordDetail(req, p.TykAPISpec))
+	// the responses of the sampled and captured traffic are copied for their records
+	withCache := recordDetail(req, p.TykAPISpec) || p.TykAPISpec.TrafficSampler.sample(req) ||
+		p.Gw.debugCaptures.capturing(p.TykAPISpec.APIID)
+	resp := p.WrappedServeHTTP(rw, req, withCache)
 
 	finishTime := time.Since(startTime)
 	p.logger.WithField("ns", finishTime.Nanoseconds()).Debug("Finished")
@@ -526,14 +552,144 @@ func (p *ReverseProxy) CheckHardTimeoutEnforced(spec *APISpec, req *http.Request
 	versionPaths := spec.RxPaths[vInfo.Name]
 	found, meta := spec.CheckSpecMatchesStatus(req, versionPaths, HardTimeout)
 	if found {
-		intMeta := meta.(*int)
-		p.logger.Debug("HARD TIMEOUT ENFORCED: ", *intMeta)
-		return true, float64(*intMeta)
+		timeoutMeta := meta.(*apidef.HardTimeoutMeta)
+		p.logger.Debug("HARD TIMEOUT ENFORCED: ", timeoutMeta.TimeOut)
+		return true, float64(timeoutMeta.TimeOut)
 	}
 
 	return false, spec.GlobalConfig.ProxyDefaultTimeout
 }
 
+// upstreamTimeouts holds the connect and response header timeouts applied to a single upstream request.
+type upstreamTimeouts struct {
+	connect        time.Duration
+	responseHeader time.Duration
+}
+
+// checkUpstreamTimeouts returns the connect and response header timeouts for req, taken from a
+// matching hard timeout endpoint or the global proxy_default_timeout otherwise.
+func (p *ReverseProxy) checkUpstreamTimeouts(spec *APISpec, req *http.Request) upstreamTimeouts {
+	timeout := spec.GlobalConfig.ProxyDefaultTimeout
+	connectTimeout := timeout
+
+	if spec.EnforcedTimeoutEnabled {
+		vInfo, _ := spec.Version(req)
+		versionPaths := spec.RxPaths[vInfo.Name]
+		if found, meta := spec.CheckSpecMatchesStatus(req, versionPaths, HardTimeout); found {
+			timeoutMeta := meta.(*apidef.HardTimeoutMeta)
+			timeout = float64(timeoutMeta.TimeOut)
+			connectTimeout = timeout
+			if timeoutMeta.ConnectTimeOut > 0 {
+				connectTimeout = float64(timeoutMeta.ConnectTimeOut)
+			}
+			p.logger.Debug("HARD TIMEOUT ENFORCED: ", timeoutMeta.TimeOut, ", CONNECT TIMEOUT: ", connectTimeout)
+		}
+	}
+
+	return upstreamTimeouts{
+		connect:        time.Duration(connectTimeout * float64(time.Second)),
+		responseHeader: time.Duration(timeout * float64(time.Second)),
+	}
+}
+
+func ctxGetUpstreamTimeouts(c context.Context) *upstreamTimeouts {
+	if v := c.Value(ctx.UpstreamTimeouts); v != nil {
+		t := v.(upstreamTimeouts)
+		return &t
+	}
+	return nil
+}
+
+// dialWithUpstreamTimeout bounds dial by the connect timeout stored in the request context, if any.
+func dialWithUpstreamTimeout(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(c context.Context, network, address string) (net.Conn, error) {
+		if t := ctxGetUpstreamTimeouts(c); t != nil && t.connect > 0 {
+			var cancel context.CancelFunc
+			c, cancel = context.WithTimeout(c, t.connect)
+			defer cancel()
+		}
+		return dial(c, network, address)
+	}
+}
+
+var errResponseHeaderTimeout = errors.New("net/http: timeout awaiting response headers")
+
+// cancelOnCloseBody releases the request context once the response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// roundTripWithHeaderTimeout runs rt, cancelling the request when no response headers arrive within timeout.
+func roundTripWithHeaderTimeout(rt http.RoundTripper, r *http.Request, timeout time.Duration) (*http.Response, error) {
+	reqCtx, cancel := context.WithCancel(r.Context())
+	timer := time.AfterFunc(timeout, cancel)
+
+	res, err := rt.RoundTrip(r.WithContext(reqCtx))
+	if !timer.Stop() && err != nil {
+		err = errResponseHeaderTimeout
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	res.Body = &cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// defaultRetryBodyLimit is the size of the largest request body buffered to be retried, when the
+// API doesn't limit the size of the request bodies.
+const defaultRetryBodyLimit = 1 << 20
+
+// retryBodyLimit returns the size of the largest request body of the API buffered to be retried,
+// the requests with larger bodies aren't retried.
+func retryBodyLimit(spec *APISpec) int64 {
+	if limit := requestBodyLimit(spec); limit > 0 {
+		return limit
+	}
+	return defaultRetryBodyLimit
+}
+
+// shouldRetry reports whether an upstream attempt which ended with res or err should be retried.
+func shouldRetry(conf apidef.RetryConfig, res *http.Response, err error) bool {
+	if err != nil {
+		return conf.OnConnectionError && !errors.Is(err, context.Canceled)
+	}
+
+	for _, code := range conf.StatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryBackoff returns the delay before the given retry, doubling the initial backoff on every retry.
+func retryBackoff(conf apidef.RetryConfig, retry int) time.Duration {
+	backoff := conf.InitialBackoff * math.Pow(2, float64(retry-1))
+	if conf.MaxBackoff > 0 && backoff > conf.MaxBackoff {
+		backoff = conf.MaxBackoff
+	}
+
+	return time.Duration(backoff * float64(time.Second))
+}
+
 func (p *ReverseProxy) CheckHeaderInRemoveList(hdr string, spec *APISpec, req *http.Request) bool {
 	vInfo, _ := spec.Version(req)
 	versionPaths := spec.RxPaths[vInfo.Name]
@@ -679,20 +835,25 @@ func (p *ReverseProxy) httpTransport(timeOut float64, rw http.ResponseWriter, re
 		transport.TLSClientConfig.Renegotiation = tls.RenegotiateFreelyAsClient
 	}
 
+	if size := p.TykAPISpec.Proxy.Transport.TLSSessionCacheSize; size > 0 {
+		transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(size)
+	}
+
 	transport.DisableKeepAlives = p.TykAPISpec.GlobalConfig.ProxyCloseConnections
 
-	if p.Gw.GetConfig().ProxyEnableHttp2 {
+	if p.Gw.GetConfig().ProxyEnableHttp2 || p.TykAPISpec.Proxy.Transport.EnableHTTP2 {
 		http2.ConfigureTransport(transport)
 	}
 
 	p.logger.Debug("Out request url: ", outReq.URL.String())
 
-	if outReq.URL.Scheme == "h2c" {
+	if outReq.URL.Scheme == "h2c" || p.TykAPISpec.Proxy.Transport.H2C {
 		p.logger.Info("Enabling h2c mode")
+		dialContext := transport.DialContext
 		h2t := &http2.Transport{
 			// kind of a hack, but for plaintext/H2C requests, pretend to dial TLS
 			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
-				return net.Dial(network, addr)
+				return dialContext(context.Background(), network, addr)
 			},
 			AllowHTTP: true,
 		}
@@ -778,10 +939,17 @@ func (rt *TykRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
 		return handleInMemoryLoop(handler, r)
 	}
 
-	if rt.h2ctransport != nil {
-		return rt.h2ctransport.RoundTrip(r)
+	var transport http.RoundTripper = rt.transport
+	// h2c:// targets are rewritten to http://, https:// ones keep using HTTP/1.1 or HTTP/2 over TLS
+	if rt.h2ctransport != nil && r.URL.Scheme == "http" {
+		transport = rt.h2ctransport
+	}
+
+	if t := ctxGetUpstreamTimeouts(r.Context()); t != nil && t.responseHeader > 0 {
+		return roundTripWithHeaderTimeout(transport, r, t.responseHeader)
 	}
-	return rt.transport.RoundTrip(r)
+
+	return transport.RoundTrip(r)
 }
 
 const (
@@ -826,9 +994,23 @@ func (p *ReverseProxy) handleOutboundRequest(roundTripper *TykRoundTripper, outr
 
 	if p.TykAPISpec.GraphQL.Enabled {
 		res, hijacked, err = p.handleGraphQL(roundTripper, outreq, w)
+		if err == nil && !hijacked {
+			countGraphQLErrors(ctxGetGraphQLStatsRef(outreq), res)
+		}
 		return
 	}
 
+	if timings := ctxGetRequestTimings(outreq); timings != nil {
+		outreq = outreq.WithContext(httptrace.WithClientTrace(outreq.Context(), &httptrace.ClientTrace{
+			GotFirstResponseByte: func() {
+				timings.upstreamTTFB = time.Since(begin)
+				if p.Gw.benchStats != nil {
+					p.Gw.benchStats.add(benchUpstream, timings.upstreamTTFB)
+				}
+			},
+		}))
+	}
+
 	res, err = p.sendRequestToUpstream(roundTripper, outreq)
 	return
 }
@@ -1002,6 +1184,7 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		span, ctx := trace.Span(req.Context(), req.URL.Path)
 		defer span.Finish()
 		ext.SpanKindRPCClient.Set(span)
+		span.SetTag("api_id", p.TykAPISpec.APIID)
 		req = req.WithContext(ctx)
 	}
 	var roundTripper *TykRoundTripper
@@ -1045,6 +1228,9 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		outreq.Body = nil // Issue 16036: nil Body for http.Transport retries
 	}
 	outreq = outreq.WithContext(reqCtx)
+	if p.TykAPISpec.EnforcedTimeoutEnabled {
+		setCtxValue(outreq, ctx.UpstreamTimeouts, p.checkUpstreamTimeouts(p.TykAPISpec, req))
+	}
 
 	outreq.Header = cloneHeader(req.Header)
 	if trace.IsEnabled() {
@@ -1114,7 +1300,11 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 	}
 
 	if createTransport {
-		_, timeout := p.CheckHardTimeoutEnforced(p.TykAPISpec, req)
+		// the transport is shared by all endpoints, per-endpoint timeouts are applied per request
+		timeout := p.TykAPISpec.GlobalConfig.ProxyDefaultTimeout
+		if p.TykAPISpec.EnforcedTimeoutEnabled {
+			timeout = 0
+		}
 		p.TykAPISpec.HTTPTransport = p.httpTransport(timeout, rw, req, outreq)
 		p.TykAPISpec.HTTPTransportCreated = time.Now()
 	}
@@ -1154,22 +1344,87 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		err             error
 	)
 
-	if breakerEnforced {
-		if !breakerConf.CB.Ready() {
-			p.logger.Debug("ON REQUEST: Circuit Breaker is in OPEN state")
-			p.ErrorHandler.HandleError(rw, logreq, "Service temporarily unavailable.", 503, true)
+	retryConf := p.TykAPISpec.Proxy.Retry
+	retryEnabled := retryConf.Enabled && retryConf.MaxAttempts > 1 && !outReqUpgrade &&
+		(retryConf.NonIdempotent || isIdempotentMethod(outreq.Method))
+
+	// the body has to be buffered so it can be replayed on every attempt, the requests with bodies
+	// larger than the buffer are sent once
+	var reqBody []byte
+	if retryEnabled && outreq.Body != nil && outreq.Body != http.NoBody {
+		limit := retryBodyLimit(p.TykAPISpec)
+		if outreq.ContentLength > limit {
+			retryEnabled = false
+		} else if reqBody, err = ioutil.ReadAll(io.LimitReader(outreq.Body, limit+1)); err != nil {
+			if requestBodySizeExceeded(logreq, err) {
+				p.ErrorHandler.HandleError(rw, logreq, msgBodySizeExceeded, http.StatusRequestEntityTooLarge, true)
+				return ProxyResponse{}
+			}
+			p.ErrorHandler.HandleError(rw, logreq, "There was a problem proxying the request", http.StatusInternalServerError, true)
 			return ProxyResponse{}
+		} else if int64(len(reqBody)) > limit {
+			retryEnabled = false
+			outreq.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(reqBody), outreq.Body))
+			reqBody = nil
+		} else {
+			req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+		}
+	}
+
+	var retries int
+	for attempt := 1; ; attempt++ {
+		if reqBody != nil {
+			outreq.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
 		}
-		p.logger.Debug("ON REQUEST: Circuit Breaker is in CLOSED or HALF-OPEN state")
 
-		res, isHijacked, upstreamLatency, err = p.handleOutboundRequest(roundTripper, outreq, rw)
-		if err != nil || res.StatusCode/100 == 5 {
-			breakerConf.CB.Fail()
+		var latency time.Duration
+		if breakerEnforced {
+			if !breakerConf.CB.Ready() {
+				p.logger.Debug("ON REQUEST: Circuit Breaker is in OPEN state")
+				ctxSetUpstreamRetries(logreq, retries)
+				p.ErrorHandler.HandleError(rw, logreq, "Service temporarily unavailable.", 503, true)
+				return ProxyResponse{UpstreamLatency: upstreamLatency, Retries: retries}
+			}
+			p.logger.Debug("ON REQUEST: Circuit Breaker is in CLOSED or HALF-OPEN state")
+
+			res, isHijacked, latency, err = p.handleOutboundRequest(roundTripper, outreq, rw)
+			if err != nil || res.StatusCode/100 == 5 {
+				breakerConf.CB.Fail()
+			} else {
+				breakerConf.CB.Success()
+			}
 		} else {
-			breakerConf.CB.Success()
+			res, isHijacked, latency, err = p.handleOutboundRequest(roundTripper, outreq, rw)
 		}
-	} else {
-		res, isHijacked, upstreamLatency, err = p.handleOutboundRequest(roundTripper, outreq, rw)
+		upstreamLatency += latency
+		p.TykAPISpec.UpstreamStats.Record(outreq.URL.Host, latency, err != nil || (res != nil && res.StatusCode/100 == 5))
+
+		if !retryEnabled || isHijacked || attempt >= retryConf.MaxAttempts || !shouldRetry(retryConf, res, err) {
+			break
+		}
+
+		if res != nil {
+			res.Body.Close()
+		}
+
+		retries++
+		p.logger.WithError(err).Debug("Retrying upstream request, attempt ", attempt+1)
+
+		select {
+		case <-time.After(retryBackoff(retryConf, retries)):
+			continue
+		case <-reqCtx.Done():
+			err = reqCtx.Err()
+		}
+		break
+	}
+
+	if retries > 0 {
+		ctxSetUpstreamRetries(logreq, retries)
+	}
+
+	if rec := ctxGetAccessLogRecord(req); rec != nil {
+		rec.SetUpstream(outreq.URL.Host, upstreamLatency)
 	}
 
 	if err != nil {
@@ -1211,6 +1466,17 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 			p.ErrorHandler.HandleError(rw, logreq, "Upstream host lookup failed", http.StatusInternalServerError, true)
 			return ProxyResponse{UpstreamLatency: upstreamLatency}
 		}
+
+		var mpErr *multipartError
+		if errors.As(err, &mpErr) {
+			p.ErrorHandler.HandleError(rw, logreq, mpErr.Error(), mpErr.code, true)
+			return ProxyResponse{UpstreamLatency: upstreamLatency}
+		}
+
+		if requestBodySizeExceeded(logreq, err) {
+			p.ErrorHandler.HandleError(rw, logreq, msgBodySizeExceeded, http.StatusRequestEntityTooLarge, true)
+			return ProxyResponse{UpstreamLatency: upstreamLatency}
+		}
 		p.ErrorHandler.HandleError(rw, logreq, "There was a problem proxying the request", http.StatusInternalServerError, true)
 		return ProxyResponse{UpstreamLatency: upstreamLatency}
 
@@ -1220,6 +1486,10 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		return ProxyResponse{UpstreamLatency: upstreamLatency}
 	}
 
+	if !p.enforceResponseSizeLimit(rw, req, outreq, logreq, res) {
+		return ProxyResponse{UpstreamLatency: upstreamLatency}
+	}
+
 	upgrade, _ := p.IsUpgrade(req)
 	// Deal with 101 Switching Protocols responses: (WebSocket, h2c, etc)
 	if upgrade {
@@ -1247,6 +1517,10 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		p.logger.Error("Response chain failed! ", err)
 	}
 
+	if !p.maskResponseBody(rw, req, logreq, res) {
+		return ProxyResponse{UpstreamLatency: upstreamLatency}
+	}
+
 	inres := new(http.Response)
 	if withCache {
 		*inres = *res // includes shallow copies of maps, but okay
@@ -1270,6 +1544,13 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 	// We should at least copy the status code in
 	inres.StatusCode = res.StatusCode
 	inres.ContentLength = res.ContentLength
+
+	// compressed for this client only, after the response was cached
+	if p.TykAPISpec.Compression.Enabled {
+		compressResponse(p.TykAPISpec.Compression, req, res)
+	}
+
+	requestIDResponseHeader(p.Gw.GetConfig().RequestID, res)
 	p.HandleResponse(rw, res, ses)
 	return ProxyResponse{UpstreamLatency: upstreamLatency, Response: inres}
 }
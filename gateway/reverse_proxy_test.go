@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,6 +14,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"text/template"
 	"time"
@@ -134,7 +136,7 @@ type configTestReverseProxyDnsCache struct {
 func (s *Test) SetupTestReverseProxyDnsCache(cfg *configTestReverseProxyDnsCache) func() {
 	pullDomains := s.MockHandle.PushDomains(cfg.etcHostsMap, nil)
 	s.Gw.dnsCacheManager.InitDNSCaching(
-		time.Duration(cfg.dnsConfig.TTL)*time.Second, time.Duration(cfg.dnsConfig.CheckInterval)*time.Second)
+		time.Duration(cfg.dnsConfig.TTL)*time.Second, time.Duration(cfg.dnsConfig.CheckInterval)*time.Second, dnscache.Options{})
 
 	globalConf := s.Gw.GetConfig()
 	enableWebSockets := globalConf.HttpServerOptions.EnableWebSockets
@@ -405,6 +407,142 @@ func TestCircuitBreaker5xxs(t *testing.T) {
 	})
 }
 
+func TestUpstreamRetry(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	var attempts int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		// fail every odd attempt
+		if atomic.AddInt32(&attempts, 1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer upstream.Close()
+
+	retry := apidef.RetryConfig{
+		Enabled:        true,
+		MaxAttempts:    2,
+		InitialBackoff: 0.001,
+		StatusCodes:    []int{http.StatusServiceUnavailable},
+	}
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Proxy.Retry = retry
+	})
+
+	t.Run("retry on status code", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPut, Path: "/", Data: "payload", Code: http.StatusOK, BodyMatch: "payload"})
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("non idempotent method", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Code: http.StatusServiceUnavailable})
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("non idempotent method allowed", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.Proxy.Retry = retry
+			spec.Proxy.Retry.NonIdempotent = true
+		})
+
+		atomic.StoreInt32(&attempts, 0)
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Data: "payload", Code: http.StatusOK, BodyMatch: "payload"})
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("body larger than the retry buffer", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+		large := strings.Repeat("a", defaultRetryBodyLimit+1)
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPut, Path: "/", Data: large, Code: http.StatusServiceUnavailable})
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+		})
+
+		atomic.StoreInt32(&attempts, 0)
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusServiceUnavailable})
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+}
+
+func TestShouldRetry(t *testing.T) {
+	conf := apidef.RetryConfig{OnConnectionError: true, StatusCodes: []int{http.StatusBadGateway}}
+
+	assert.True(t, shouldRetry(conf, nil, errors.New("connection refused")))
+	assert.False(t, shouldRetry(conf, nil, fmt.Errorf("dial: %w", context.Canceled)))
+	assert.True(t, shouldRetry(conf, &http.Response{StatusCode: http.StatusBadGateway}, nil))
+	assert.False(t, shouldRetry(conf, &http.Response{StatusCode: http.StatusInternalServerError}, nil))
+
+	conf.OnConnectionError = false
+	assert.False(t, shouldRetry(conf, nil, errors.New("connection refused")))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	conf := apidef.RetryConfig{InitialBackoff: 0.1, MaxBackoff: 0.3}
+
+	assert.Equal(t, 100*time.Millisecond, retryBackoff(conf, 1))
+	assert.Equal(t, 200*time.Millisecond, retryBackoff(conf, 2))
+	assert.Equal(t, 300*time.Millisecond, retryBackoff(conf, 3))
+}
+
+func TestUpstreamEndpointTimeouts(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+	}))
+	defer upstream.Close()
+
+	api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.HardTimeouts = []apidef.HardTimeoutMeta{
+				{Path: "/short", Method: http.MethodGet, TimeOut: 1},
+				{Path: "/long", Method: http.MethodGet, TimeOut: 3, ConnectTimeOut: 2},
+			}
+		})
+	})[0]
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/short", Code: http.StatusGatewayTimeout},
+		{Path: "/long", Code: http.StatusOK},
+		// the transport is shared, the first endpoint must not leak its timeout
+		{Path: "/short", Code: http.StatusGatewayTimeout},
+	}...)
+
+	t.Run("timeouts per endpoint", func(t *testing.T) {
+		target, _ := url.Parse(upstream.URL)
+		proxy := ts.Gw.TykNewSingleHostReverseProxy(target, api, nil)
+
+		req := TestReq(t, http.MethodGet, "/long", nil)
+		assert.Equal(t, upstreamTimeouts{connect: 2 * time.Second, responseHeader: 3 * time.Second}, proxy.checkUpstreamTimeouts(api, req))
+
+		req = TestReq(t, http.MethodGet, "/short", nil)
+		assert.Equal(t, upstreamTimeouts{connect: time.Second, responseHeader: time.Second}, proxy.checkUpstreamTimeouts(api, req))
+
+		req = TestReq(t, http.MethodGet, "/other", nil)
+		assert.Equal(t, upstreamTimeouts{}, proxy.checkUpstreamTimeouts(api, req))
+	})
+}
+
 func TestSingleJoiningSlash(t *testing.T) {
 	testsFalse := []struct {
 		a, b, want string
@@ -882,12 +882,13 @@ func (r *RPCStorageHandler) ProcessKeySpaceChanges(keys []string, orgId string)
 			case OAuthRevokeRefreshToken:
 				tokenTypeHint = "refresh_token"
 			}
-			RevokeToken(storage, token, tokenTypeHint)
+			// the access token of a revoked refresh token is flushed with the other keys
+			keys = append(keys, RevokeToken(storage, token, tokenTypeHint)...)
 		} else {
 			token = strings.Split(token, "#")[0]
 			r.Gw.handleDeleteHashedKey(token, orgId, apiId, false)
 		}
-		r.Gw.SessionCache.Delete(token)
+		r.Gw.deleteCachedSession(token)
 		r.Gw.RPCGlobalCache.Delete(r.KeyPrefix + token)
 	}
 
@@ -925,7 +926,7 @@ func (r *RPCStorageHandler) ProcessKeySpaceChanges(keys []string, orgId string)
 				r.Gw.handleDeleteKey(key, orgId, "-1", resetQuota)
 				r.Gw.getSessionAndCreate(splitKeys[0], r, false, orgId)
 			}
-			r.Gw.SessionCache.Delete(key)
+			r.Gw.deleteCachedSession(key)
 			r.Gw.RPCGlobalCache.Delete(r.KeyPrefix + key)
 		}
 	}
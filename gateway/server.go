@@ -40,8 +40,10 @@ import (
 	uuid "github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
 	logrus_syslog "github.com/sirupsen/logrus/hooks/syslog"
+	"golang.org/x/sync/singleflight"
 	"rsc.io/letsencrypt"
 
+	"github.com/TykTechnologies/tyk/accesslog"
 	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/certs"
 	"github.com/TykTechnologies/tyk/checkup"
@@ -49,12 +51,15 @@ import (
 	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/dnscache"
 	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/kafka"
 	logger "github.com/TykTechnologies/tyk/log"
 	"github.com/TykTechnologies/tyk/regexp"
 	"github.com/TykTechnologies/tyk/rpc"
 	"github.com/TykTechnologies/tyk/storage"
 	"github.com/TykTechnologies/tyk/storage/kv"
+	"github.com/TykTechnologies/tyk/storage/ratelimit"
 	"github.com/TykTechnologies/tyk/trace"
+	"github.com/TykTechnologies/tyk/trace/otlp"
 	"github.com/TykTechnologies/tyk/user"
 )
 
@@ -95,8 +100,33 @@ type Gateway struct {
 	DRLManager *drl.DRL
 	reloadMu   sync.Mutex
 
+	// draining is set once the gateway started draining, drained is closed when it is done.
+	draining int32
+	drained  chan struct{}
+
+	// bundleMu serialises the loading of bundles, as the APIs sharing one may be loaded concurrently.
+	bundleMu sync.Mutex
+
+	appGitMu sync.Mutex // guards appGitRevision and appGitDir
+	// appGitRevision is the revision of the API definitions repository checked out.
+	appGitRevision string
+	// appGitDir is the temporary directory the repository is cloned into when no checkout
+	// directory is configured.
+	appGitDir string
+
+	maintenanceMu sync.RWMutex // guards maintenanceOverrides
+	// maintenanceOverrides are the maintenance configurations of APIs set with the gateway API.
+	maintenanceOverrides map[string]apidef.MaintenanceConfig
+
+	confReloadMu sync.Mutex // guards loadedConf
+	// loadedConf is the configuration file as last loaded, before the defaults are applied.
+	loadedConf config.Config
+
 	analytics            RedisAnalyticsHandler
+	geoIPOnce            sync.Once
+	geoIPDB              geoIPReader
 	GlobalEventsJSVM     JSVM
+	globalEventsJSVMMu   sync.Mutex // guards the loading of scripts into GlobalEventsJSVM
 	MainNotifier         RedisNotifier
 	DefaultOrgStore      DefaultSessionManager
 	DefaultQuotaStore    DefaultSessionManager
@@ -117,6 +147,13 @@ type Gateway struct {
 	RPCGlobalCache *cache.Cache
 	// key session memory cache
 	SessionCache *cache.Cache
+	// unknownKeysCache remembers the keys not found in the key store, see unknown_key_timeout
+	unknownKeysCache *cache.Cache
+	// sessionLoads shares the loading of a session between the concurrent requests of the key
+	sessionLoads      singleflight.Group
+	sessionCacheStats sessionCacheStats
+	// basicAuthHashStats counts the migration of the basic auth passwords to the configured hashing
+	basicAuthHashStats basicAuthHashStats
 	// org session memory cache
 	ExpiryCache *cache.Cache
 	// memory cache to store arbitrary items
@@ -138,10 +175,17 @@ type Gateway struct {
 
 	consulKVStore kv.Store
 	vaultKVStore  kv.Store
+	awsKVStore    kv.Store
+
+	// secretsCache caches the secret references of API definitions
+	secretsCache *cache.Cache
 
 	LE_MANAGER  letsencrypt.Manager
 	LE_FIRSTRUN bool
 
+	// acme provisions the certificates of the custom domains, nil if ACME is disabled
+	acme *acmeManager
+
 	NotificationVerifier goverify.Verifier
 
 	RedisPurgeOnce sync.Once
@@ -178,6 +222,21 @@ type Gateway struct {
 
 	// RedisController keeps track of redis connection and singleton
 	RedisController *storage.RedisController
+
+	webhookSubscriptions *WebhookSubscriptionManager
+	asyncOperations      *AsyncOperationManager
+	keyIndex             *keyIndex
+
+	trafficSamples trafficSampleBuffer
+	debugCaptures  debugCaptures
+
+	// newKafkaClient creates the clients of the APIs with a Kafka upstream, replaced in tests.
+	newKafkaClient func(kafka.Config) (kafka.Client, error)
+
+	// benchStats records the timings of the middlewares when running the bench command, nil otherwise.
+	benchStats *benchStats
+
+	accessLog *accesslog.Logger
 }
 
 func NewGateway(config config.Config, ctx context.Context, cancelFn context.CancelFunc) *Gateway {
@@ -198,13 +257,16 @@ func NewGateway(config config.Config, ctx context.Context, cancelFn context.Canc
 	gw.SessionMonitor = Monitor{Gw: &gw}
 	gw.RPCGlobalCache = cache.New(30*time.Second, 15*time.Second)
 	gw.HostCheckTicker = make(chan struct{})
+	gw.drained = make(chan struct{})
 	gw.HostCheckerClient = &http.Client{
 		Timeout: 500 * time.Millisecond,
 	}
 
 	gw.SessionCache = cache.New(10*time.Second, 5*time.Second)
+	gw.unknownKeysCache = cache.New(time.Minute, time.Minute)
 	gw.ExpiryCache = cache.New(600*time.Second, 10*time.Minute)
 	gw.UtilCache = cache.New(time.Hour, 10*time.Minute)
+	gw.secretsCache = cache.New(defaultSecretsCacheTTL*time.Second, 10*time.Minute)
 
 	gw.apisByID = map[string]*APISpec{}
 	gw.apisHandlesByID = new(sync.Map)
@@ -218,6 +280,11 @@ func NewGateway(config config.Config, ctx context.Context, cancelFn context.Canc
 	gw.TestBundles = map[string]map[string]string{}
 
 	gw.RedisController = storage.NewRedisController()
+	gw.webhookSubscriptions = NewWebhookSubscriptionManager(&gw)
+	gw.asyncOperations = NewAsyncOperationManager(&gw)
+	gw.keyIndex = newKeyIndex(&gw)
+	sessionManager.index = gw.keyIndex
+	gw.newKafkaClient = kafka.NewClient
 
 	return &gw
 }
@@ -289,7 +356,12 @@ func (gw *Gateway) setupGlobals() {
 	if gwConfig.DnsCache.Enabled {
 		gw.dnsCacheManager.InitDNSCaching(
 			time.Duration(gwConfig.DnsCache.TTL)*time.Second,
-			time.Duration(gwConfig.DnsCache.CheckInterval)*time.Second)
+			time.Duration(gwConfig.DnsCache.CheckInterval)*time.Second,
+			dnscache.Options{
+				UseRecordTTL: gwConfig.DnsCache.UseRecordTTL,
+				NegativeTTL:  time.Duration(gwConfig.DnsCache.NegativeTTL) * time.Second,
+				Failover:     gwConfig.DnsCache.Failover,
+			})
 	}
 
 	if gwConfig.EnableAnalytics && gwConfig.Storage.Type != "redis" {
@@ -307,13 +379,20 @@ func (gw *Gateway) setupGlobals() {
 	}
 
 	gw.initHealthCheck(gw.ctx)
+	gw.setupAccessLog(gwConfig.AccessLogs)
 
 	redisStore := storage.RedisCluster{KeyPrefix: "apikey-", HashKeys: gwConfig.HashKeys, RedisController: gw.RedisController}
 	gw.GlobalSessionManager.Init(&redisStore)
 
+	rateLimitStore, err := ratelimit.NewStore(gwConfig.RateLimitStorage)
+	if err != nil {
+		mainLog.WithError(err).Error("Invalid rate limit storage, falling back to Redis")
+	}
+	gw.SessionLimiter.rateLimitStore = rateLimitStore
+
 	versionStore := storage.RedisCluster{KeyPrefix: "version-check-", RedisController: gw.RedisController}
 	versionStore.Connect()
-	err := versionStore.SetKey("gateway", VERSION, 0)
+	err = versionStore.SetKey("gateway", VERSION, 0)
 	if err != nil {
 		mainLog.WithError(err).Error("Could not set version in versionStore")
 	}
@@ -388,6 +467,7 @@ func (gw *Gateway) setupGlobals() {
 		}
 		gw.CertificateManager = certs.NewSlaveCertManager(storeCert, rpcStore, certificateSecret, log, !gw.GetConfig().Cloud)
 	}
+	gw.acme = gw.newACMEManager(gw.GetConfig().HttpServerOptions.ACME)
 
 	if gw.GetConfig().NewRelic.AppName != "" {
 		NewRelicApplication = gw.SetupNewRelic()
@@ -413,6 +493,22 @@ func buildConnStr(resource string, conf config.Config) string {
 }
 
 func (gw *Gateway) syncAPISpecs() (int, error) {
+	filter, err := gw.fetchAPISpecs()
+	if err != nil {
+		return 0, err
+	}
+
+	gw.apisMu.Lock()
+	gw.apiSpecs = filter
+	apiLen := len(gw.apiSpecs)
+	tlsConfigCache.Flush()
+	gw.apisMu.Unlock()
+
+	return apiLen, nil
+}
+
+// fetchAPISpecs returns the valid API definitions of the configured source.
+func (gw *Gateway) fetchAPISpecs() ([]*APISpec, error) {
 	loader := APIDefinitionLoader{gw}
 
 	var s []*APISpec
@@ -421,7 +517,7 @@ func (gw *Gateway) syncAPISpecs() (int, error) {
 		tmpSpecs, err := loader.FromDashboardService(connStr)
 		if err != nil {
 			log.Error("failed to load API specs: ", err)
-			return 0, err
+			return nil, err
 		}
 
 		s = tmpSpecs
@@ -433,8 +529,21 @@ func (gw *Gateway) syncAPISpecs() (int, error) {
 		var err error
 		s, err = loader.FromRPC(gw.GetConfig().SlaveOptions.RPCKey, gw)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
+	} else if gw.GetConfig().Kubernetes.Enabled {
+		var err error
+		s, err = loader.FromKubernetes(gw.GetConfig().Kubernetes)
+		if err != nil {
+			log.Error("failed to load API specs from Kubernetes: ", err)
+			return nil, err
+		}
+	} else if gw.GetConfig().AppGit.Enabled {
+		dir, err := gw.appGitDefinitionsDir()
+		if err != nil {
+			return nil, err
+		}
+		s = loader.FromDir(dir)
 	} else {
 		s = loader.FromDir(gw.GetConfig().AppPath)
 	}
@@ -461,17 +570,17 @@ func (gw *Gateway) syncAPISpecs() (int, error) {
 		filter = append(filter, v)
 	}
 
-	gw.apisMu.Lock()
-	gw.apiSpecs = filter
-	apiLen := len(gw.apiSpecs)
-	tlsConfigCache.Flush()
-	gw.apisMu.Unlock()
+	if gw.GetConfig().EnableIncrementalReload {
+		gw.checksumAPISpecs(filter)
+	}
 
-	return apiLen, nil
+	return filter, nil
 }
 
 func (gw *Gateway) syncPolicies() (count int, err error) {
 	var pols map[string]user.Policy
+	// the policies loaded remotely are kept when none are fetched, fetching them may have failed
+	local := false
 
 	mainLog.Info("Loading policies")
 
@@ -489,7 +598,11 @@ func (gw *Gateway) syncPolicies() (count int, err error) {
 	case "rpc":
 		mainLog.Debug("Using Policies from RPC")
 		pols, err = gw.LoadPoliciesFromRPC(gw.GetConfig().SlaveOptions.RPCKey)
+	case "kubernetes":
+		mainLog.Debug("Using Policies from Kubernetes")
+		pols, err = LoadPoliciesFromKubernetes(gw.GetConfig().Kubernetes)
 	default:
+		local = true
 		//if policy path defined we want to allow use of the REST API
 		if gw.GetConfig().Policies.PolicyPath != "" {
 			pols = LoadPoliciesFromDir(gw.GetConfig().Policies.PolicyPath)
@@ -510,7 +623,8 @@ func (gw *Gateway) syncPolicies() (count int, err error) {
 
 	gw.policiesMu.Lock()
 	defer gw.policiesMu.Unlock()
-	if len(pols) > 0 {
+	// deleting the last local policy unloads it, unless its file couldn't be read
+	if len(pols) > 0 || (local && pols != nil) {
 		gw.policiesByID = pols
 	}
 
@@ -563,7 +677,14 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 		}
 	}
 
-	muxer.HandleFunc("/"+gw.GetConfig().HealthCheckEndpointName, gw.liveCheckHandler)
+	healthCheckPath := "/" + gw.GetConfig().HealthCheckEndpointName
+	muxer.HandleFunc(healthCheckPath, gw.liveCheckHandler)
+	muxer.HandleFunc(healthCheckPath+livenessCheckPath, gw.livenessCheckHandler)
+	muxer.HandleFunc(healthCheckPath+readinessCheckPath, gw.readinessCheckHandler)
+	if gw.GetConfig().AppGit.Enabled && gw.GetConfig().AppGit.WebhookSecret != "" {
+		// authenticated by the webhook secret instead of the API secret
+		muxer.HandleFunc("/tyk/git/webhook", gw.appGitWebhookHandler).Methods("POST")
+	}
 
 	r := mux.NewRouter()
 	muxer.PathPrefix("/tyk/").Handler(http.StripPrefix("/tyk",
@@ -587,10 +708,14 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 	// set up main API handlers
 	r.HandleFunc("/reload/group", gw.groupResetHandler).Methods("GET")
 	r.HandleFunc("/reload", gw.resetHandler(nil)).Methods("GET")
+	r.HandleFunc("/reload/api/{apiID}", gw.reloadAPIHandler).Methods("POST")
+	r.HandleFunc("/reload-config", gw.reloadConfigHandler).Methods("POST")
+	r.HandleFunc("/drain", gw.drainHandler).Methods("POST")
 
 	if !gw.isRPCMode() {
 		r.HandleFunc("/org/keys", gw.orgHandler).Methods("GET")
 		r.HandleFunc("/org/keys/{keyName:[^/]*}", gw.orgHandler).Methods("POST", "PUT", "GET", "DELETE")
+		r.HandleFunc("/orgs/{orgID}/limits", gw.orgLimitsHandler).Methods("GET", "PUT", "DELETE")
 		r.HandleFunc("/keys/policy/{keyName}", gw.policyUpdateHandler).Methods("POST")
 		r.HandleFunc("/keys/create", gw.createKeyHandler).Methods("POST")
 		r.HandleFunc("/apis", gw.apiHandler).Methods("GET", "POST", "PUT", "DELETE")
@@ -612,8 +737,23 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 
 	r.HandleFunc("/debug", gw.traceHandler).Methods("POST")
 	r.HandleFunc("/cache/{apiID}", gw.invalidateCacheHandler).Methods("DELETE")
+	r.HandleFunc("/apis/{apiID}/canary", gw.canaryHandler).Methods("GET", "PUT")
+	r.HandleFunc("/apis/{apiID}/ip-access", gw.ipAccessHandler).Methods("GET", "POST", "DELETE")
+	r.HandleFunc("/apis/{apiID}/maintenance", gw.maintenanceHandler).Methods("GET", "PUT", "DELETE")
+	r.HandleFunc("/apis/{apiID}/upstream-status", gw.upstreamStatusHandler).Methods("GET")
+	r.HandleFunc("/apis/{apiID}/postman", gw.postmanExportHandler).Methods("GET")
+	r.HandleFunc("/apis/{apiID}/capture", gw.debugCaptureHandler).Methods("GET", "PUT", "DELETE")
+	r.HandleFunc("/apis/{apiID}/capture/records", gw.debugCaptureRecordsHandler).Methods("GET")
+	r.HandleFunc("/apis/{apiID}/subscriptions", gw.webhookSubscriptionsHandler).Methods("GET")
+	r.HandleFunc("/apis/{apiID}/subscriptions/{subID}", gw.webhookSubscriptionDeleteHandler).Methods("DELETE")
+	r.HandleFunc("/apis/{apiID}/events", gw.webhookPublishHandler).Methods("POST")
+	r.HandleFunc("/portal/catalogue", gw.portalCatalogueHandler).Methods("GET")
+	r.HandleFunc("/billing/deliveries", gw.billingDeliveriesHandler).Methods("GET")
 	r.HandleFunc("/keys", gw.keyHandler).Methods("POST", "PUT", "GET", "DELETE")
 	r.HandleFunc("/keys/preview", gw.previewKeyHandler).Methods("POST")
+	r.HandleFunc("/keys/bulk", gw.bulkKeyHandler).Methods("POST")
+	r.HandleFunc("/keys/{keyName:[^/]*}/rotate", gw.keyRotationHandler).Methods("GET", "POST")
+	r.HandleFunc("/keys/{keyName:[^/]*}/usage", gw.keyUsageHandler).Methods("GET")
 	r.HandleFunc("/keys/{keyName:[^/]*}", gw.keyHandler).Methods("POST", "PUT", "GET", "DELETE")
 	r.HandleFunc("/certs", gw.certHandler).Methods("POST", "GET")
 	r.HandleFunc("/certs/{certID:[^/]*}", gw.certHandler).Methods("POST", "GET", "DELETE")
@@ -651,8 +791,11 @@ func generateOAuthPrefix(apiID string) string {
 func (gw *Gateway) addOAuthHandlers(spec *APISpec, muxer *mux.Router) *OAuthManager {
 
 	apiAuthorizePath := "/tyk/oauth/authorize-client{_:/?}"
+	apiAuthorizeDevicePath := "/tyk/oauth/authorize-device{_:/?}"
+	apiConsentSessionPath := "/tyk/oauth/consent-session{_:/?}"
 	clientAuthPath := "/oauth/authorize{_:/?}"
 	clientAccessPath := "/oauth/token{_:/?}"
+	clientDevicePath := "/oauth/device_authorization{_:/?}"
 	revokeToken := "/oauth/revoke"
 	revokeAllTokens := "/oauth/revoke_all"
 
@@ -688,6 +831,9 @@ func (gw *Gateway) addOAuthHandlers(spec *APISpec, muxer *mux.Router) *OAuthMana
 	muxer.Handle(apiAuthorizePath, gw.checkIsAPIOwner(allowMethods(oauthHandlers.HandleGenerateAuthCodeData, "POST")))
 	muxer.HandleFunc(clientAuthPath, allowMethods(oauthHandlers.HandleAuthorizePassthrough, "GET", "POST"))
 	muxer.HandleFunc(clientAccessPath, addSecureAndCacheHeaders(allowMethods(oauthHandlers.HandleAccessRequest, "GET", "POST")))
+	muxer.Handle(apiAuthorizeDevicePath, gw.checkIsAPIOwner(allowMethods(oauthHandlers.HandleAuthorizeDevice, "POST")))
+	muxer.Handle(apiConsentSessionPath, gw.checkIsAPIOwner(allowMethods(oauthHandlers.HandleConsentSession, "POST")))
+	muxer.HandleFunc(clientDevicePath, addSecureAndCacheHeaders(allowMethods(oauthHandlers.HandleDeviceAuthorization, "POST")))
 	muxer.HandleFunc(revokeToken, oauthHandlers.HandleRevokeToken)
 	muxer.HandleFunc(revokeAllTokens, oauthHandlers.HandleRevokeAllTokens)
 	return &oauthManager
@@ -813,6 +959,8 @@ func (gw *Gateway) createResponseMiddlewareChain(spec *APISpec, responseFuncs []
 		//is it goplugin or other middleware
 		if strings.HasSuffix(mw.Path, ".so") {
 			processor = gw.responseProcessorByName("goplugin_res_hook")
+		} else if spec.CustomMiddleware.Driver == apidef.WasmDriver {
+			processor = gw.responseProcessorByName("wasm_res_hook")
 		} else {
 			processor = gw.responseProcessorByName("custom_mw_res_hook")
 		}
@@ -1091,6 +1239,7 @@ func (gw *Gateway) initialiseSystem() error {
 		if err := config.Load(confPaths, &gwConfig); err != nil {
 			return err
 		}
+		gw.loadedConf = gwConfig
 		if gwConfig.PIDFileLocation == "" {
 			gwConfig.PIDFileLocation = "/var/run/tyk/tyk-gateway.pid"
 		}
@@ -1101,6 +1250,9 @@ func (gw *Gateway) initialiseSystem() error {
 	overrideTykErrors(gw)
 
 	gwConfig := gw.GetConfig()
+	if err := validateBasicAuthHashConfig(gwConfig.BasicAuthHash); err != nil {
+		return err
+	}
 	if os.Getenv("TYK_LOGLEVEL") == "" && !*cli.DebugMode {
 		level := strings.ToLower(gwConfig.LogLevel)
 		switch level {
@@ -1321,6 +1473,18 @@ func (gw *Gateway) kvStore(value string) (string, error) {
 		return gw.vaultKVStore.Get(key)
 	}
 
+	if strings.HasPrefix(value, "aws://") {
+		key := strings.TrimPrefix(value, "aws://")
+		log.Debugf("Retrieving %s from AWS Secrets Manager", key)
+		if err := gw.setUpAWSSecretsManager(); err != nil {
+			log.Error("Failed to setup AWS Secrets Manager: ", err)
+			// Return value as is If AWS Secrets Manager cannot be set up
+			return value, nil
+		}
+
+		return gw.awsKVStore.Get(key)
+	}
+
 	return value, nil
 }
 
@@ -1339,6 +1503,21 @@ func (gw *Gateway) setUpVault() error {
 	return err
 }
 
+func (gw *Gateway) setUpAWSSecretsManager() error {
+	if gw.awsKVStore != nil {
+		return nil
+	}
+
+	var err error
+
+	gw.awsKVStore, err = kv.NewAWSSecretsManager(gw.GetConfig().KV.AWS)
+	if err != nil {
+		log.Debugf("an error occurred while setting up AWS Secrets Manager... %v", err)
+	}
+
+	return err
+}
+
 func (gw *Gateway) setUpConsul() error {
 	if gw.consulKVStore != nil {
 		return nil
@@ -1385,6 +1564,9 @@ func Start() {
 	defer cancel()
 	cli.Init(VERSION, confPaths)
 	cli.Parse()
+	if cli.BenchMode {
+		os.Exit(runBench(ctx, cancel, cli.Bench))
+	}
 	// Stop gateway process if not running in "start" mode:
 	if !cli.DefaultMode {
 		os.Exit(0)
@@ -1435,6 +1617,17 @@ func Start() {
 		trace.SetupTracing(tr.Name, tr.Options)
 		trace.SetLogger(mainLog)
 		defer trace.Close()
+
+		log.AddHook(trace.SpanLogHook{})
+		if tr.Name == otlp.Name {
+			exporter, err := otlp.NewLogExporter("tyk-gateway", tr.Options, mainLog)
+			if err != nil {
+				mainLog.WithError(err).Error("Could not export the logs with OTLP")
+			} else if exporter != nil {
+				log.AddHook(exporter)
+				defer exporter.Close()
+			}
+		}
 	}
 	gw.start()
 	configs := gw.GetConfig()
@@ -1480,11 +1673,23 @@ func Start() {
 			mainLog.Fatal(err)
 		}
 	}
-	_, err = again.Wait(&gw.DefaultProxyMux.again)
-	if err != nil {
-		mainLog.WithError(err).Error("waiting")
+	gw.DefaultProxyMux.again.Hooks.OnSIGHUP = func(*again.Again) error {
+		_, err := gw.reloadConfig()
+		return err
+	}
+	stopped := make(chan struct{})
+	go func() {
+		if _, err := again.Wait(&gw.DefaultProxyMux.again); err != nil {
+			mainLog.WithError(err).Error("waiting")
+		}
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		mainLog.Info("Stop signal received.")
+	case <-gw.drained:
+		mainLog.Info("Gateway drained.")
 	}
-	mainLog.Info("Stop signal received.")
 	if err = gw.DefaultProxyMux.again.Close(); err != nil {
 		mainLog.Error("Closing listeners: ", err)
 	}
@@ -1493,6 +1698,11 @@ func Start() {
 		gw.analytics.Stop()
 	}
 
+	// flush access logs
+	if gw.accessLog != nil {
+		gw.accessLog.Close()
+	}
+
 	// write pprof profiles
 	writeProfiles()
 
@@ -1566,6 +1776,15 @@ func (gw *Gateway) start() {
 	// interval counts from the start of one reload to the next.
 	go gw.reloadLoop(time.Tick(time.Second))
 	go gw.reloadQueueLoop()
+
+	go gw.syntheticMonitoringLoop(gw.ctx)
+	go gw.billingExportLoop(gw.ctx)
+	go gw.trafficSamplesLoop(gw.ctx)
+	go gw.asyncOperationsLoop(gw.ctx)
+	go gw.certificateExpiryLoop(gw.ctx)
+	go gw.secretsRenewalLoop(gw.ctx)
+	go gw.appGitLoop(gw.ctx)
+	go gw.kubernetesWatchLoop(gw.ctx)
 }
 
 func dashboardServiceInit(gw *Gateway) {
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// sessionCacheStats counts the lookups of the local session cache by the auth middlewares.
+type sessionCacheStats struct {
+	hits           uint64
+	unknownKeyHits uint64
+	misses         uint64
+	sharedLoads    uint64
+}
+
+// SessionCacheMetrics are the metrics of the local session cache, reported with the health checks.
+type SessionCacheMetrics struct {
+	Size int    `json:"size"`
+	Hits uint64 `json:"hits"`
+	// UnknownKeyHits are the lookups of keys remembered as not found in the key store.
+	UnknownKeyHits uint64 `json:"unknown_key_hits"`
+	Misses         uint64 `json:"misses"`
+	// SharedLoads are the misses served with the session loaded for a concurrent request of the
+	// same key, rather than by querying the key store.
+	SharedLoads uint64 `json:"shared_loads"`
+}
+
+func (gw *Gateway) sessionCacheMetrics() SessionCacheMetrics {
+	return SessionCacheMetrics{
+		Size:           gw.SessionCache.ItemCount(),
+		Hits:           atomic.LoadUint64(&gw.sessionCacheStats.hits),
+		UnknownKeyHits: atomic.LoadUint64(&gw.sessionCacheStats.unknownKeyHits),
+		Misses:         atomic.LoadUint64(&gw.sessionCacheStats.misses),
+		SharedLoads:    atomic.LoadUint64(&gw.sessionCacheStats.sharedLoads),
+	}
+}
+
+// countSessionCacheLookup counts a lookup of the session cache, event being hit, unknown_key_hit,
+// miss or shared_load.
+func (gw *Gateway) countSessionCacheLookup(event string) {
+	switch event {
+	case "hit":
+		atomic.AddUint64(&gw.sessionCacheStats.hits, 1)
+	case "unknown_key_hit":
+		atomic.AddUint64(&gw.sessionCacheStats.unknownKeyHits, 1)
+	case "miss":
+		atomic.AddUint64(&gw.sessionCacheStats.misses, 1)
+	case "shared_load":
+		atomic.AddUint64(&gw.sessionCacheStats.sharedLoads, 1)
+	}
+	if instrumentationEnabled {
+		instrument.NewJob("SessionCache").Event(event)
+	}
+}
+
+// loadSession gets the session of key from the key store, the concurrent loads of a key sharing
+// a single query, so that an expired cache entry of a busy key doesn't stampede Redis.
+func (gw *Gateway) loadSession(sessionManager SessionHandler, orgID, key string) (user.SessionState, bool) {
+	type loaded struct {
+		session user.SessionState
+		found   bool
+	}
+	// the caller running the load is told the load was shared too
+	var loader bool
+	v, _, _ := gw.sessionLoads.Do(orgID+"-"+key, func() (interface{}, error) {
+		loader = true
+		session, found := sessionManager.SessionDetail(orgID, key, false)
+		return loaded{session: session, found: found}, nil
+	})
+	if !loader {
+		gw.countSessionCacheLookup("shared_load")
+	}
+	res := v.(loaded)
+	return res.session.Clone(), res.found
+}
+
+// isUnknownKey tells whether key is remembered as not found in the key store.
+func (gw *Gateway) isUnknownKey(cacheKey string) bool {
+	if gw.GetConfig().LocalSessionCache.UnknownKeyTimeout <= 0 {
+		return false
+	}
+	_, found := gw.unknownKeysCache.Get(cacheKey)
+	return found
+}
+
+// rememberUnknownKey remembers that key wasn't found in the key store. Keys aren't remembered
+// while Redis is down, as they couldn't be looked up.
+func (gw *Gateway) rememberUnknownKey(cacheKey string) {
+	timeout := gw.GetConfig().LocalSessionCache.UnknownKeyTimeout
+	if timeout <= 0 || !gw.RedisController.Connected() {
+		return
+	}
+	gw.unknownKeysCache.Set(cacheKey, true, time.Duration(timeout)*time.Second)
+}
+
+// deleteCachedSession drops the cached session of key, and forgets it if it was unknown.
+func (gw *Gateway) deleteCachedSession(cacheKey string) {
+	gw.SessionCache.Delete(cacheKey)
+	gw.unknownKeysCache.Delete(cacheKey)
+}
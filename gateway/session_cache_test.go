@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// slowSessionHandler returns the sessions after release is closed, counting the lookups.
+type slowSessionHandler struct {
+	SessionHandler
+	release chan struct{}
+	lookups int64
+}
+
+func (s *slowSessionHandler) SessionDetail(orgID, keyName string, hashed bool) (user.SessionState, bool) {
+	atomic.AddInt64(&s.lookups, 1)
+	<-s.release
+	return user.SessionState{KeyID: keyName, OrgID: orgID}, true
+}
+
+func TestLoadSession(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	handler := &slowSessionHandler{release: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session, found := gw.loadSession(handler, "org", "key")
+			assert.True(t, found)
+			assert.Equal(t, "key", session.KeyID)
+		}()
+	}
+	// let the loads queue up behind the first one
+	time.Sleep(50 * time.Millisecond)
+	close(handler.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&handler.lookups))
+	assert.Equal(t, uint64(9), gw.sessionCacheMetrics().SharedLoads)
+}
+
+func TestUnknownKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	gw.unknownKeysCache.Set("key", true, time.Minute)
+	assert.False(t, gw.isUnknownKey("key"), "unknown keys must not be remembered when disabled")
+
+	conf := gw.GetConfig()
+	conf.LocalSessionCache.UnknownKeyTimeout = 60
+	gw.SetConfig(conf)
+	assert.True(t, gw.isUnknownKey("key"))
+
+	gw.deleteCachedSession("key")
+	assert.False(t, gw.isUnknownKey("key"))
+
+	gw.rememberUnknownKey("other")
+	assert.False(t, gw.isUnknownKey("other"), "unknown keys must not be remembered while Redis is down")
+}
+
+func TestSessionCacheMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := NewGateway(config.Config{}, ctx, cancel)
+
+	gw.SessionCache.Set("key", user.SessionState{}, time.Minute)
+	gw.countSessionCacheLookup("hit")
+	gw.countSessionCacheLookup("hit")
+	gw.countSessionCacheLookup("miss")
+	gw.countSessionCacheLookup("unknown_key_hit")
+
+	assert.Equal(t, SessionCacheMetrics{Size: 1, Hits: 2, Misses: 1, UnknownKeyHits: 1}, gw.sessionCacheMetrics())
+}
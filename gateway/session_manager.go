@@ -35,12 +35,14 @@ const (
 // check if a message should pass through or not
 type SessionLimiter struct {
 	bucketStore leakybucket.Storage
-	Gw          *Gateway `json:"-"`
+	// rateLimitStore replaces the Redis storage of the rolling windows when set.
+	rateLimitStore storage.RateLimitStore
+	Gw             *Gateway `json:"-"`
 }
 
 func (l *SessionLimiter) doRollingWindowWrite(key, rateLimiterKey, rateLimiterSentinelKey string,
 	currentSession *user.SessionState,
-	store storage.Handler,
+	store storage.RateLimitStore,
 	globalConf *config.Config,
 	apiLimit *user.APILimit, dryRun bool) bool {
 
@@ -89,6 +91,14 @@ func (l *SessionLimiter) doRollingWindowWrite(key, rateLimiterKey, rateLimiterSe
 	return false
 }
 
+// store returns the storage of the rolling windows, store unless replaced by a rate limit storage driver.
+func (l *SessionLimiter) store(store storage.Handler) storage.RateLimitStore {
+	if l.rateLimitStore != nil {
+		return l.rateLimitStore
+	}
+	return store
+}
+
 type sessionFailReason uint
 
 const (
@@ -98,7 +108,7 @@ const (
 	sessionFailInternalServerError
 )
 
-func (l *SessionLimiter) limitSentinel(currentSession *user.SessionState, key string, rateScope string, store storage.Handler,
+func (l *SessionLimiter) limitSentinel(currentSession *user.SessionState, key string, rateScope string, store storage.RateLimitStore,
 	globalConf *config.Config, apiLimit *user.APILimit, dryRun bool) bool {
 
 	rateLimiterKey := RateLimitKeyPrefix + rateScope + currentSession.KeyHash()
@@ -115,7 +125,7 @@ func (l *SessionLimiter) limitSentinel(currentSession *user.SessionState, key st
 	return false
 }
 
-func (l *SessionLimiter) limitRedis(currentSession *user.SessionState, key string, rateScope string, store storage.Handler,
+func (l *SessionLimiter) limitRedis(currentSession *user.SessionState, key string, rateScope string, store storage.RateLimitStore,
 	globalConf *config.Config, apiLimit *user.APILimit, dryRun bool) bool {
 
 	rateLimiterKey := RateLimitKeyPrefix + rateScope + currentSession.KeyHash()
@@ -197,12 +207,13 @@ func (l *SessionLimiter) ForwardMessage(r *http.Request, currentSession *user.Se
 		if allowanceScope != "" {
 			rateScope = allowanceScope + "-"
 		}
+		rateLimitStore := l.store(store)
 		if globalConf.EnableSentinelRateLimiter {
-			if l.limitSentinel(currentSession, key, rateScope, store, globalConf, &accessDef.Limit, dryRun) {
+			if l.limitSentinel(currentSession, key, rateScope, rateLimitStore, globalConf, &accessDef.Limit, dryRun) {
 				return sessionFailRateLimit
 			}
 		} else if globalConf.EnableRedisRollingLimiter {
-			if l.limitRedis(currentSession, key, rateScope, store, globalConf, &accessDef.Limit, dryRun) {
+			if l.limitRedis(currentSession, key, rateScope, rateLimitStore, globalConf, &accessDef.Limit, dryRun) {
 				return sessionFailRateLimit
 			}
 		} else {
@@ -224,7 +235,7 @@ func (l *SessionLimiter) ForwardMessage(r *http.Request, currentSession *user.Se
 					return sessionFailRateLimit
 				}
 			} else {
-				if l.limitRedis(currentSession, key, rateScope, store, globalConf, &accessDef.Limit, dryRun) {
+				if l.limitRedis(currentSession, key, rateScope, rateLimitStore, globalConf, &accessDef.Limit, dryRun) {
 					return sessionFailRateLimit
 				}
 			}
@@ -1,11 +1,15 @@
 package gateway
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage/ratelimit"
+	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/user"
 )
 
@@ -134,3 +138,31 @@ func TestGetAccessDefinitionByAPIIDOrSession(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestRateLimitStorage(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnableRedisRollingLimiter = true
+		globalConf.RateLimitStorage.Driver = ratelimit.DriverMemory
+	})
+	defer ts.Close()
+
+	assert.NotNil(t, ts.Gw.SessionLimiter.rateLimitStore)
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "limited"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+	})
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.Rate = 2
+		s.Per = 60
+		s.AccessRights = map[string]user.AccessDefinition{"limited": {APIID: "limited"}}
+	})
+	authHeaders := map[string]string{"Authorization": key}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/", Headers: authHeaders, Code: http.StatusTooManyRequests},
+	}...)
+}
@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	SyntheticAnalytics_KEYNAME = "tyk-synthetic-analytics"
+
+	syntheticCheckHeader = "X-Tyk-Synthetic-Check"
+
+	defaultSyntheticInterval = 60
+	defaultSyntheticTimeout  = 10
+)
+
+// SyntheticCheckRecord is the analytics record of a synthetic check, stored apart from the
+// analytics of the requests.
+type SyntheticCheckRecord struct {
+	APIID          string
+	OrgID          string
+	CheckName      string
+	Method         string
+	Path           string
+	ResponseCode   int
+	ExpectedStatus int
+	Success        bool
+	Error          string
+	RequestTime    int64
+	Day            int
+	Month          time.Month
+	Year           int
+	Hour           int
+	Minute         int
+	TimeStamp      time.Time
+	ExpireAt       time.Time `bson:"expireAt" json:"expireAt"`
+}
+
+func (s *SyntheticCheckRecord) SetExpiry(expiresInSeconds int64) {
+	expiry := time.Duration(expiresInSeconds) * time.Second
+
+	if expiresInSeconds == 0 {
+		// Expiry is set to 100 years
+		expiry = (24 * time.Hour) * (365 * 100)
+	}
+
+	s.ExpireAt = time.Now().Add(expiry)
+}
+
+// syntheticMonitor runs the synthetic checks of the loaded APIs through the public interface of
+// the gateway.
+type syntheticMonitor struct {
+	Gw      *Gateway
+	store   storage.Handler
+	client  *http.Client
+	lastRun map[string]time.Time
+	running sync.WaitGroup
+}
+
+func (gw *Gateway) newSyntheticMonitor() *syntheticMonitor {
+	return &syntheticMonitor{
+		Gw:    gw,
+		store: &storage.RedisCluster{KeyPrefix: "analytics-", IsAnalytics: true, RedisController: gw.RedisController},
+		client: &http.Client{
+			Transport: &http.Transport{
+				// checks are sent to ourselves, the certificate doesn't need to match the local address
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+			// redirects are answers of the API, not something to follow
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		lastRun: make(map[string]time.Time),
+	}
+}
+
+// syntheticMonitoringLoop runs the due synthetic checks every second until ctx is done.
+func (gw *Gateway) syntheticMonitoringLoop(ctx context.Context) {
+	monitor := gw.newSyntheticMonitor()
+	tick := time.NewTicker(time.Second)
+	defer func() {
+		tick.Stop()
+		monitor.running.Wait()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-tick.C:
+			monitor.runDueChecks(ctx, t)
+		}
+	}
+}
+
+// runDueChecks starts the checks of the APIs which weren't run in the last interval of the API.
+func (m *syntheticMonitor) runDueChecks(ctx context.Context, now time.Time) {
+	m.Gw.apisMu.RLock()
+	specs := make([]*APISpec, 0)
+	for _, spec := range m.Gw.apiSpecs {
+		if spec.SyntheticMonitoring.Enabled && len(spec.SyntheticMonitoring.Checks) > 0 {
+			specs = append(specs, spec)
+		}
+	}
+	m.Gw.apisMu.RUnlock()
+
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		seen[spec.APIID] = true
+
+		interval := spec.SyntheticMonitoring.Interval
+		if interval <= 0 {
+			interval = defaultSyntheticInterval
+		}
+		if last, ok := m.lastRun[spec.APIID]; ok && now.Sub(last) < time.Duration(interval)*time.Second {
+			continue
+		}
+		m.lastRun[spec.APIID] = now
+
+		for _, check := range spec.SyntheticMonitoring.Checks {
+			m.running.Add(1)
+			go func(spec *APISpec, check apidef.SyntheticCheck) {
+				defer m.running.Done()
+				m.runCheck(ctx, spec, check)
+			}(spec, check)
+		}
+	}
+
+	// forget the APIs which were unloaded or had their checks disabled
+	for apiID := range m.lastRun {
+		if !seen[apiID] {
+			delete(m.lastRun, apiID)
+		}
+	}
+}
+
+// checkURL returns the URL of check on the public interface of the gateway.
+func (m *syntheticMonitor) checkURL(spec *APISpec, check apidef.SyntheticCheck) string {
+	gwConfig := m.Gw.GetConfig()
+
+	scheme := "http"
+	if gwConfig.HttpServerOptions.UseSSL {
+		scheme = "https"
+	}
+	host := gwConfig.ListenAddress
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	port := gwConfig.ListenPort
+	if spec.ListenPort != 0 {
+		port = spec.ListenPort
+	}
+
+	path := strings.TrimSuffix(spec.Proxy.ListenPath, "/") + "/" + strings.TrimPrefix(check.Path, "/")
+	return scheme + "://" + host + ":" + strconv.Itoa(port) + path
+}
+
+// runCheck sends check to the gateway, records its result and fires EventSyntheticCheckFailed
+// on failure.
+func (m *syntheticMonitor) runCheck(ctx context.Context, spec *APISpec, check apidef.SyntheticCheck) SyntheticCheckRecord {
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	expected := check.ExpectedStatus
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultSyntheticTimeout
+	}
+
+	record := SyntheticCheckRecord{
+		APIID:          spec.APIID,
+		OrgID:          spec.OrgID,
+		CheckName:      check.Name,
+		Method:         method,
+		Path:           check.Path,
+		ExpectedStatus: expected,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	code, err := m.send(ctx, spec, check, method)
+	record.RequestTime = int64(time.Since(start) / time.Millisecond)
+	record.ResponseCode = code
+	if err != nil {
+		record.Error = err.Error()
+	}
+	record.Success = err == nil && code == expected
+
+	m.record(spec, &record, start)
+
+	if !record.Success {
+		log.WithFields(logrus.Fields{
+			"prefix": "synthetic-monitoring",
+			"api_id": spec.APIID,
+			"check":  check.Name,
+			"code":   code,
+		}).WithError(err).Warning("Synthetic check failed")
+
+		spec.FireEvent(EventSyntheticCheckFailed, EventSyntheticCheckFailedMeta{
+			EventMetaDefault: EventMetaDefault{Message: "Synthetic check failed"},
+			APIID:            spec.APIID,
+			Check:            check.Name,
+			Path:             check.Path,
+			ResponseCode:     code,
+			ExpectedStatus:   expected,
+			Error:            record.Error,
+		})
+	}
+
+	return record
+}
+
+// send sends check with the dedicated key of spec and returns the response code.
+func (m *syntheticMonitor) send(ctx context.Context, spec *APISpec, check apidef.SyntheticCheck, method string) (int, error) {
+	var body io.Reader
+	if check.Body != "" {
+		body = strings.NewReader(check.Body)
+	}
+	req, err := http.NewRequest(method, m.checkURL(spec, check), body)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+
+	for name, value := range check.Headers {
+		req.Header.Set(name, value)
+	}
+	if key := spec.SyntheticMonitoring.Key; key != "" {
+		authHeaderName := spec.Auth.AuthHeaderName
+		if authHeaderName == "" {
+			authHeaderName = headers.Authorization
+		}
+		req.Header.Set(authHeaderName, key)
+	}
+	req.Header.Set(syntheticCheckHeader, check.Name)
+	if spec.Domain != "" && m.Gw.GetConfig().EnableCustomDomains {
+		req.Host = spec.Domain
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
+
+func (m *syntheticMonitor) record(spec *APISpec, record *SyntheticCheckRecord, t time.Time) {
+	record.Day = t.Day()
+	record.Month = t.Month()
+	record.Year = t.Year()
+	record.Hour = t.Hour()
+	record.Minute = t.Minute()
+	record.TimeStamp = t
+	record.SetExpiry(spec.ExpireAnalyticsAfter)
+
+	encoded, err := msgpack.Marshal(record)
+	if err != nil {
+		log.WithField("prefix", "synthetic-monitoring").WithError(err).Error("Error encoding synthetic check data")
+		return
+	}
+	m.store.AppendToSet(SyntheticAnalytics_KEYNAME, string(encoded))
+}
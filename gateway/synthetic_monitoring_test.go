@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestSyntheticMonitoring(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "synthetic"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/synthetic/"
+		spec.SyntheticMonitoring = apidef.SyntheticMonitoringConfig{
+			Enabled: true,
+			Checks: []apidef.SyntheticCheck{
+				{Name: "ok", Path: "/status"},
+				{Name: "wrong status", Method: http.MethodPost, Path: "/status", Body: "{}", ExpectedStatus: http.StatusCreated},
+			},
+		}
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"synthetic": {APIID: "synthetic"}}
+	})
+	spec := ts.Gw.getApiSpec("synthetic")
+	spec.SyntheticMonitoring.Key = key
+	failures := make(chan config.EventMessage, 2)
+	spec.EventPaths = map[apidef.TykEvent][]config.TykEventHandler{
+		EventSyntheticCheckFailed: {&testEventHandler{func(em config.EventMessage) {
+			failures <- em
+		}}},
+	}
+
+	monitor := ts.Gw.newSyntheticMonitor()
+	analytics := monitor.store.(interface {
+		GetAndDeleteSet(string) []interface{}
+	})
+	analytics.GetAndDeleteSet(SyntheticAnalytics_KEYNAME)
+
+	t.Run("through the middleware chain with the dedicated key", func(t *testing.T) {
+		record := monitor.runCheck(context.Background(), spec, spec.SyntheticMonitoring.Checks[0])
+		assert.True(t, record.Success)
+		assert.Equal(t, http.StatusOK, record.ResponseCode)
+		assert.Empty(t, record.Error)
+
+		spec.SyntheticMonitoring.Key = "invalid"
+		defer func() { spec.SyntheticMonitoring.Key = key }()
+		record = monitor.runCheck(context.Background(), spec, spec.SyntheticMonitoring.Checks[0])
+		assert.False(t, record.Success)
+		assert.Equal(t, http.StatusForbidden, record.ResponseCode)
+
+		select {
+		case em := <-failures:
+			meta := em.Meta.(EventSyntheticCheckFailedMeta)
+			assert.Equal(t, "synthetic", meta.APIID)
+			assert.Equal(t, "ok", meta.Check)
+			assert.Equal(t, http.StatusForbidden, meta.ResponseCode)
+		case <-time.After(time.Second):
+			t.Fatal("failed synthetic check should fire an event")
+		}
+	})
+
+	t.Run("records separate analytics", func(t *testing.T) {
+		values := analytics.GetAndDeleteSet(SyntheticAnalytics_KEYNAME)
+		require.Len(t, values, 2)
+
+		var record SyntheticCheckRecord
+		require.NoError(t, msgpack.Unmarshal([]byte(values[0].(string)), &record))
+		assert.Equal(t, "synthetic", record.APIID)
+		assert.Equal(t, "ok", record.CheckName)
+		assert.Equal(t, http.MethodGet, record.Method)
+		assert.True(t, record.Success)
+	})
+
+	t.Run("runs due checks once per interval", func(t *testing.T) {
+		now := time.Now()
+		monitor.runDueChecks(context.Background(), now)
+
+		select {
+		case em := <-failures:
+			meta := em.Meta.(EventSyntheticCheckFailedMeta)
+			assert.Equal(t, "wrong status", meta.Check)
+			assert.Equal(t, http.StatusOK, meta.ResponseCode)
+			assert.Equal(t, http.StatusCreated, meta.ExpectedStatus)
+		case <-time.After(time.Second):
+			t.Fatal("failed synthetic check should fire an event")
+		}
+
+		assert.Equal(t, now, monitor.lastRun["synthetic"])
+		monitor.runDueChecks(context.Background(), now.Add(time.Second))
+		assert.Equal(t, now, monitor.lastRun["synthetic"], "checks shouldn't run before the interval")
+		monitor.runDueChecks(context.Background(), now.Add(defaultSyntheticInterval*time.Second))
+		assert.Equal(t, now.Add(defaultSyntheticInterval*time.Second), monitor.lastRun["synthetic"])
+		monitor.running.Wait()
+	})
+}
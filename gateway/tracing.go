@@ -133,14 +133,14 @@ func (gw *Gateway) traceHandler(w http.ResponseWriter, r *http.Request) {
 	chainObj.ThisHandler.ServeHTTP(wr, tr)
 
 	var response string
-	if dump, err := httputil.DumpResponse(wr.Result(), true); err == nil {
+	if dump, err := httputil.DumpResponse(spec.LogRedactor.response(wr.Result()), true); err == nil {
 		response = string(dump)
 	} else {
 		response = err.Error()
 	}
 
 	var request string
-	if dump, err := httputil.DumpRequest(tr, true); err == nil {
+	if dump, err := httputil.DumpRequest(spec.LogRedactor.request(tr), true); err == nil {
 		request = string(dump)
 	} else {
 		request = err.Error()
@@ -0,0 +1,406 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+const (
+	defaultTrafficSampleMaxBody       = 64 << 10
+	defaultTrafficSamplesBatchSize    = 100
+	defaultTrafficSamplesFlushSeconds = 60
+	defaultTrafficSamplesTimeout      = 30
+)
+
+var trafficSamplesLog = log.WithField("prefix", "traffic-samples")
+
+// TrafficSampler captures and masks a sample of the requests of an API.
+type TrafficSampler struct {
+	rate        uint64
+	counter     uint64
+	maxBodySize int64
+	headers     map[string]bool
+	queryParams map[string]bool
+	masks       []bodyMask
+}
+
+// NewTrafficSampler creates a sampler from the traffic samples section of an API definition. It
+// returns nil when traffic samples are disabled.
+func NewTrafficSampler(def *apidef.APIDefinition) (*TrafficSampler, error) {
+	conf := def.TrafficSamples
+	if !conf.Enabled {
+		return nil, nil
+	}
+	if conf.SampleRate < 0 {
+		return nil, errors.New("sample_rate must not be negative")
+	}
+
+	s := &TrafficSampler{
+		rate:        uint64(conf.SampleRate),
+		maxBodySize: conf.MaxBodySize,
+		headers:     make(map[string]bool),
+		queryParams: make(map[string]bool),
+	}
+	if s.maxBodySize <= 0 {
+		s.maxBodySize = defaultTrafficSampleMaxBody
+	}
+
+	for _, name := range []string{headers.Authorization, "Proxy-Authorization", "Cookie", "Set-Cookie"} {
+		s.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	authConfigs := []apidef.AuthConfig{def.Auth}
+	for _, authConfig := range def.AuthConfigs {
+		authConfigs = append(authConfigs, authConfig)
+	}
+	for _, authConfig := range authConfigs {
+		if authConfig.AuthHeaderName != "" {
+			s.headers[http.CanonicalHeaderKey(authConfig.AuthHeaderName)] = true
+		}
+		if authConfig.UseParam {
+			name := authConfig.ParamName
+			if name == "" {
+				name = authConfig.AuthHeaderName
+			}
+			if name == "" {
+				name = headers.Authorization
+			}
+			s.queryParams[name] = true
+		}
+	}
+	for _, name := range conf.MaskHeaders {
+		s.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range conf.MaskQueryParams {
+		s.queryParams[name] = true
+	}
+
+	for _, rule := range conf.MaskRules {
+		// tokenizing would store the values of the samples in Redis
+		if rule.Action != apidef.BodyMaskRedact && rule.Action != apidef.BodyMaskHash {
+			return nil, fmt.Errorf("traffic samples don't support the body masking action %q", rule.Action)
+		}
+		mask, err := newBodyMask(rule)
+		if err != nil {
+			return nil, err
+		}
+		s.masks = append(s.masks, mask)
+	}
+
+	return s, nil
+}
+
+// sample reports whether r is part of the sample, the decision being taken once per request.
+func (s *TrafficSampler) sample(r *http.Request) bool {
+	if s == nil {
+		return false
+	}
+	if sampled, ok := r.Context().Value(ctx.TrafficSampled).(bool); ok {
+		return sampled
+	}
+
+	sampled := s.shouldSample()
+	setCtxValue(r, ctx.TrafficSampled, sampled)
+	return sampled
+}
+
+func (s *TrafficSampler) shouldSample() bool {
+	if s.rate > 1 {
+		return atomic.AddUint64(&s.counter, 1)%s.rate == 1
+	}
+	return true
+}
+
+// TrafficSample is a masked request and response pair of an API.
+type TrafficSample struct {
+	APIID     string                `json:"api_id"`
+	Timestamp time.Time             `json:"timestamp"`
+	Latency   int64                 `json:"latency"`
+	Request   TrafficSampleRequest  `json:"request"`
+	Response  TrafficSampleResponse `json:"response"`
+}
+
+type TrafficSampleRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	TrafficSampleMessage
+}
+
+type TrafficSampleResponse struct {
+	Code int `json:"code"`
+	TrafficSampleMessage
+}
+
+// TrafficSampleMessage holds the headers and body of a sampled request or response. Bodies which
+// aren't UTF-8 are base64 encoded, bodies which are too large or can't be masked are omitted.
+type TrafficSampleMessage struct {
+	Headers     http.Header `json:"headers,omitempty"`
+	Body        string      `json:"body,omitempty"`
+	BodyBase64  bool        `json:"body_base64,omitempty"`
+	BodyOmitted bool        `json:"body_omitted,omitempty"`
+}
+
+func (s *TrafficSampler) message(h http.Header, body []byte) TrafficSampleMessage {
+	msg := TrafficSampleMessage{Headers: make(http.Header, len(h))}
+	for name, values := range h {
+		if s.headers[http.CanonicalHeaderKey(name)] {
+			values = []string{bodyMaskDefaultRedaction}
+		}
+		msg.Headers[name] = values
+	}
+
+	if int64(len(body)) > s.maxBodySize {
+		msg.BodyOmitted = true
+		return msg
+	}
+	if len(body) > 0 && len(s.masks) > 0 {
+		masked, err := maskBody(body, s.masks, nil, nil)
+		if err != nil {
+			msg.BodyOmitted = true
+			return msg
+		}
+		body = masked
+	}
+
+	if utf8.Valid(body) {
+		msg.Body = string(body)
+	} else {
+		msg.Body = base64.StdEncoding.EncodeToString(body)
+		msg.BodyBase64 = true
+	}
+	return msg
+}
+
+func (s *TrafficSampler) query(rawQuery string) string {
+	if rawQuery == "" || len(s.queryParams) == 0 {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ""
+	}
+	for name, v := range values {
+		if s.queryParams[name] {
+			for i := range v {
+				v[i] = bodyMaskDefaultRedaction
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// recordTrafficSample captures the request r of spec and its response if it's part of the sample
+// of the API.
+func (gw *Gateway) recordTrafficSample(r *http.Request, spec *APISpec, code int, timing Latency, res *http.Response) {
+	s := spec.TrafficSampler
+	if !s.sample(r) {
+		return
+	}
+
+	gw.trafficSamples.add(gw, s.capture(r, spec, code, timing, res))
+}
+
+// capture returns the masked sample of the request r of spec and its response. The bodies of r
+// and res are restored once read.
+func (s *TrafficSampler) capture(r *http.Request, spec *APISpec, code int, timing Latency, res *http.Response) TrafficSample {
+	// the URL as sent by the client, before the listen path is stripped or the URL rewritten
+	u := r.URL
+	if requestURL, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		u = requestURL
+	}
+
+	sample := TrafficSample{
+		APIID:     spec.APIID,
+		Timestamp: time.Now().UTC(),
+		Latency:   timing.Total,
+		Request: TrafficSampleRequest{
+			Method: r.Method,
+			Path:   u.Path,
+			Query:  s.query(u.RawQuery),
+		},
+		Response: TrafficSampleResponse{Code: code},
+	}
+
+	// streamed request bodies can't be read again
+	var reqBody []byte
+	reqBodyRead := r.Body == nil || r.Body == http.NoBody
+	if nc, ok := r.Body.(nopCloser); ok {
+		nc.Seek(0, io.SeekStart)
+		reqBody, _ = ioutil.ReadAll(nc)
+		nc.Seek(0, io.SeekStart)
+		reqBodyRead = true
+	}
+	sample.Request.TrafficSampleMessage = s.message(r.Header, reqBody)
+	if !reqBodyRead {
+		sample.Request.BodyOmitted = true
+	}
+
+	// responses served from the cache have no copy
+	if res != nil {
+		var resBody []byte
+		if res.Body != nil {
+			contents, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				trafficSamplesLog.WithError(err).Debug("Couldn't read response body")
+			}
+			res.Body = ioutil.NopCloser(bytes.NewReader(contents))
+
+			decoded := *res
+			decoded.Body = ioutil.NopCloser(bytes.NewReader(contents))
+			resBody, _ = ioutil.ReadAll(respBodyReader(r, &decoded))
+		}
+		sample.Response.TrafficSampleMessage = s.message(res.Header, resBody)
+	}
+
+	return sample
+}
+
+// trafficSampleBuffer holds the samples of the APIs until they are uploaded. It's kept by the
+// gateway rather than the API specs, so the samples survive reloads.
+type trafficSampleBuffer struct {
+	mu      sync.Mutex
+	samples map[string][]TrafficSample
+}
+
+func (b *trafficSampleBuffer) add(gw *Gateway, sample TrafficSample) {
+	batchSize := gw.GetConfig().TrafficSamples.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultTrafficSamplesBatchSize
+	}
+
+	b.mu.Lock()
+	if b.samples == nil {
+		b.samples = make(map[string][]TrafficSample)
+	}
+	batch := append(b.samples[sample.APIID], sample)
+	if len(batch) < batchSize {
+		b.samples[sample.APIID] = batch
+		batch = nil
+	} else {
+		delete(b.samples, sample.APIID)
+	}
+	b.mu.Unlock()
+
+	if batch != nil {
+		go gw.uploadTrafficSamples(gw.ctx, sample.APIID, batch)
+	}
+}
+
+// take removes and returns the buffered samples of every API.
+func (b *trafficSampleBuffer) take() map[string][]TrafficSample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	samples := b.samples
+	b.samples = nil
+	return samples
+}
+
+// trafficSamplesLoop uploads the buffered samples every flush interval until ctx is done.
+func (gw *Gateway) trafficSamplesLoop(ctx context.Context) {
+	interval := gw.GetConfig().TrafficSamples.FlushInterval
+	if interval <= 0 {
+		interval = defaultTrafficSamplesFlushSeconds
+	}
+
+	tick := time.NewTicker(time.Duration(interval) * time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			gw.flushTrafficSamples(ctx)
+		}
+	}
+}
+
+func (gw *Gateway) flushTrafficSamples(ctx context.Context) {
+	for apiID, batch := range gw.trafficSamples.take() {
+		gw.uploadTrafficSamples(ctx, apiID, batch)
+	}
+}
+
+// uploadTrafficSamples PUTs batch as a JSON lines object. Samples are dropped when the upload
+// fails, as the corpus is a sample anyway.
+func (gw *Gateway) uploadTrafficSamples(ctx context.Context, apiID string, batch []TrafficSample) {
+	conf := gw.GetConfig().TrafficSamples
+	logger := trafficSamplesLog.WithField("api_id", apiID)
+	if conf.URL == "" {
+		logger.Warning("Traffic samples URL isn't configured, dropping samples")
+		return
+	}
+
+	if err := sendTrafficSamples(ctx, conf, apiID, batch); err != nil {
+		logger.WithError(err).Error("Failed to upload traffic samples")
+		return
+	}
+	logger.WithField("samples", len(batch)).Debug("Uploaded traffic samples")
+}
+
+func sendTrafficSamples(ctx context.Context, conf config.TrafficSamplesConfig, apiID string, batch []TrafficSample) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, sample := range batch {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s%s/%s-%s.jsonl", conf.ObjectPrefix, apiID,
+		time.Now().UTC().Format("20060102T150405.000000000Z"), hex.EncodeToString(suffix))
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultTrafficSamplesTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(conf.URL, "/")+"/"+name, &body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, value := range conf.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set(headers.ContentType, "application/x-ndjson")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("traffic samples target responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+type trafficSamplesTarget struct {
+	sync.Mutex
+	server  *httptest.Server
+	paths   []string
+	types   []string
+	batches [][]TrafficSample
+}
+
+func newTrafficSamplesTarget() *trafficSamplesTarget {
+	target := &trafficSamplesTarget{}
+	target.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target.Lock()
+		defer target.Unlock()
+
+		var batch []TrafficSample
+		body, _ := ioutil.ReadAll(r.Body)
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(nil, len(body)+1)
+		for scanner.Scan() {
+			var sample TrafficSample
+			json.Unmarshal(scanner.Bytes(), &sample)
+			batch = append(batch, sample)
+		}
+		target.paths = append(target.paths, r.URL.Path)
+		target.types = append(target.types, r.Header.Get("Content-Type"))
+		target.batches = append(target.batches, batch)
+	}))
+	return target
+}
+
+func (target *trafficSamplesTarget) uploads() int {
+	target.Lock()
+	defer target.Unlock()
+	return len(target.batches)
+}
+
+func TestTrafficSamples(t *testing.T) {
+	target := newTrafficSamplesTarget()
+	defer target.server.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.TrafficSamples = config.TrafficSamplesConfig{
+			URL:          target.server.URL,
+			ObjectPrefix: "samples/",
+			BatchSize:    2,
+		}
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "sampled"
+		spec.Proxy.ListenPath = "/"
+		spec.TrafficSamples = apidef.TrafficSamplesConfig{
+			Enabled:         true,
+			MaskHeaders:     []string{"X-Secret"},
+			MaskQueryParams: []string{"token"},
+			MaskRules: []apidef.BodyMaskRule{
+				{JSONPath: "$.card", Action: apidef.BodyMaskRedact},
+				{JSONPath: "$.URI", Action: apidef.BodyMaskRedact},
+				{JSONPath: "$.Headers.X-Secret", Action: apidef.BodyMaskHash},
+			},
+		}
+	})
+
+	request := test.TestCase{
+		Method:  http.MethodPost,
+		Path:    "/pay?token=abc&page=1",
+		Data:    `{"card":"4111","amount":5}`,
+		Headers: map[string]string{"X-Secret": "s3cret", "Authorization": "key"},
+		Code:    http.StatusOK,
+	}
+	_, _ = ts.Run(t, request, request)
+
+	assert.Eventually(t, func() bool { return target.uploads() == 1 }, time.Second, 10*time.Millisecond)
+
+	target.Lock()
+	assert.True(t, strings.HasPrefix(target.paths[0], "/samples/sampled/"), target.paths[0])
+	assert.True(t, strings.HasSuffix(target.paths[0], ".jsonl"), target.paths[0])
+	assert.Equal(t, "application/x-ndjson", target.types[0])
+	batch := target.batches[0]
+	target.Unlock()
+
+	require.Len(t, batch, 2)
+	sample := batch[0]
+	assert.Equal(t, "sampled", sample.APIID)
+	assert.Equal(t, http.MethodPost, sample.Request.Method)
+	assert.Equal(t, "/pay", sample.Request.Path)
+	assert.Equal(t, "page=1&token=%2A%2A%2A%2A", sample.Request.Query)
+	assert.Equal(t, "****", sample.Request.Headers.Get("X-Secret"))
+	assert.Equal(t, "****", sample.Request.Headers.Get("Authorization"))
+	assert.Equal(t, `{"amount":5,"card":"****"}`, sample.Request.Body)
+	assert.Equal(t, http.StatusOK, sample.Response.Code)
+	assert.Contains(t, sample.Response.Body, `"URI":"****"`)
+	assert.NotContains(t, sample.Response.Body, "s3cret")
+
+	t.Run("partial batches are flushed", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Path: "/text", Code: http.StatusOK})
+		assert.Equal(t, 1, target.uploads())
+
+		ts.Gw.flushTrafficSamples(context.Background())
+		require.Equal(t, 2, target.uploads())
+
+		target.Lock()
+		defer target.Unlock()
+		require.Len(t, target.batches[1], 1)
+		assert.Equal(t, "/text", target.batches[1][0].Request.Path)
+	})
+}
+
+func TestTrafficSampler(t *testing.T) {
+	def := &apidef.APIDefinition{}
+
+	s, err := NewTrafficSampler(def)
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	def.TrafficSamples = apidef.TrafficSamplesConfig{Enabled: true, SampleRate: 3}
+	s, err = NewTrafficSampler(def)
+	require.NoError(t, err)
+	sampled := 0
+	for i := 0; i < 9; i++ {
+		if s.shouldSample() {
+			sampled++
+		}
+	}
+	assert.Equal(t, 3, sampled)
+
+	t.Run("tokenizing isn't supported", func(t *testing.T) {
+		def.TrafficSamples.MaskRules = []apidef.BodyMaskRule{{JSONPath: "$.card", Action: apidef.BodyMaskTokenize}}
+		_, err := NewTrafficSampler(def)
+		assert.Error(t, err)
+	})
+
+	t.Run("non JSON and large bodies are omitted", func(t *testing.T) {
+		def.TrafficSamples.MaskRules = []apidef.BodyMaskRule{{JSONPath: "$.card", Action: apidef.BodyMaskRedact}}
+		def.TrafficSamples.MaxBodySize = 10
+		s, err := NewTrafficSampler(def)
+		require.NoError(t, err)
+
+		assert.True(t, s.message(nil, []byte("plain")).BodyOmitted)
+		assert.True(t, s.message(nil, []byte(`{"card":"4111111111"}`)).BodyOmitted)
+		assert.Equal(t, `{"a":1}`, s.message(nil, []byte(`{"a":1}`)).Body)
+	})
+}
@@ -0,0 +1,473 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+)
+
+// The conversions follow the conventions of mxj, which the XML input of body transforms is
+// parsed with: attributes are keys prefixed by "-", the text of elements with attributes or
+// children is the "#text" key and repeated elements are arrays.
+const (
+	xmlAttrPrefix = "-"
+	xmlTextKey    = "#text"
+	xmlArrayItem  = "item"
+)
+
+type bodyConverter func(dst io.Writer, src io.Reader) error
+
+// bodyConverterFor returns the converter of a conversion template mode and the content type of
+// its output.
+func bodyConverterFor(data apidef.TemplateData) (bodyConverter, string) {
+	if data.Mode == apidef.UseJSONToXML {
+		return func(dst io.Writer, src io.Reader) error {
+			return jsonToXML(dst, src, data.TemplateSource)
+		}, headers.ApplicationXML
+	}
+	return xmlToJSON, headers.ApplicationJSON
+}
+
+// convertRequestBody converts the body of r according to the conversion mode of data. Streamed
+// request bodies are converted on the fly, others were already read in memory by the gateway.
+func convertRequestBody(r *http.Request, data apidef.TemplateData) error {
+	convert, contentType := bodyConverterFor(data)
+
+	if r.ContentLength == -1 {
+		r.Body = convertStream(r.Body, convert)
+		r.Header.Del(headers.ContentLength)
+		r.Header.Set(headers.ContentType, contentType)
+		return nil
+	}
+
+	var converted bytes.Buffer
+	if err := convert(&converted, r.Body); err != nil {
+		// rewind the original body for the upstream
+		nopCloseRequestBody(r)
+		return err
+	}
+
+	r.Body = ioutil.NopCloser(&converted)
+	r.ContentLength = int64(converted.Len())
+	r.Header.Set(headers.ContentType, contentType)
+	nopCloseRequestBody(r)
+
+	return nil
+}
+
+// convertResponseBody converts the body of res on the fly according to the conversion mode of data.
+func convertResponseBody(req *http.Request, res *http.Response, data apidef.TemplateData) {
+	convert, contentType := bodyConverterFor(data)
+
+	upstreamBody := res.Body
+	body := respBodyReader(req, res)
+	switch res.Header.Get(headers.ContentEncoding) {
+	case "gzip", "deflate", "br", "zstd":
+		if req.Header.Get(headers.AcceptEncoding) != "" {
+			// decompressed by respBodyReader
+			res.Header.Del(headers.ContentEncoding)
+		}
+	}
+
+	res.Body = convertStream(struct {
+		io.Reader
+		io.Closer
+	}{body, upstreamBody}, convert)
+	res.ContentLength = -1
+	res.Header.Del(headers.ContentLength)
+	res.Header.Set(headers.ContentType, contentType)
+}
+
+// convertStream returns the output of convert, run on src as the output is read.
+func convertStream(src io.ReadCloser, convert bodyConverter) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		err := convert(pw, src)
+		if err != nil {
+			log.WithError(err).Error("Body conversion failure")
+		}
+		src.Close()
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// jsonToXML converts the JSON document of src to XML token by token. Array items repeat the
+// element of their key. The document is the content of the root element when root is set,
+// otherwise it must be an object with a single key, the root element.
+func jsonToXML(dst io.Writer, src io.Reader, root string) error {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	w := bufio.NewWriter(dst)
+	c := jsonXMLConverter{dec: dec, w: w}
+
+	if root != "" {
+		if !isXMLName(root) {
+			return fmt.Errorf("%q is not a valid XML name", root)
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('[') {
+			if err := c.valueOf(root, tok); err != nil {
+				return err
+			}
+		} else {
+			// a single root element holds the items
+			w.WriteString("<" + root + ">")
+			for dec.More() {
+				if err := c.value(xmlArrayItem); err != nil {
+					return err
+				}
+			}
+			if err := c.delim(']'); err != nil {
+				return err
+			}
+			w.WriteString("</" + root + ">")
+		}
+	} else {
+		if err := c.delim('{'); err != nil {
+			return errors.New("JSON document must be an object to be converted without root element")
+		}
+		if !dec.More() {
+			return errors.New("JSON document has no root element")
+		}
+		key, err := c.key()
+		if err != nil {
+			return err
+		}
+		if err := c.value(key); err != nil {
+			return err
+		}
+		if dec.More() {
+			return errors.New("JSON document has more than one root element")
+		}
+		if err := c.delim('}'); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the JSON document")
+	}
+	return w.Flush()
+}
+
+type jsonXMLConverter struct {
+	dec *json.Decoder
+	w   *bufio.Writer
+}
+
+func (c *jsonXMLConverter) delim(want json.Delim) error {
+	tok, err := c.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %v in JSON document", want)
+	}
+	return nil
+}
+
+func (c *jsonXMLConverter) key() (string, error) {
+	tok, err := c.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	return tok.(string), nil
+}
+
+// value writes the next JSON value as element name, arrays as repeated elements.
+func (c *jsonXMLConverter) value(name string) error {
+	if !isXMLName(name) {
+		return fmt.Errorf("%q is not a valid XML name", name)
+	}
+
+	tok, err := c.dec.Token()
+	if err != nil {
+		return err
+	}
+	return c.valueOf(name, tok)
+}
+
+func (c *jsonXMLConverter) valueOf(name string, tok json.Token) error {
+	switch tok {
+	case json.Delim('{'):
+		return c.object(name)
+	case json.Delim('['):
+		for c.dec.More() {
+			if err := c.value(name); err != nil {
+				return err
+			}
+		}
+		return c.delim(']')
+	}
+
+	c.w.WriteString("<" + name + ">")
+	xml.EscapeText(c.w, []byte(jsonScalar(tok)))
+	c.w.WriteString("</" + name + ">")
+	return nil
+}
+
+func (c *jsonXMLConverter) object(name string) error {
+	c.w.WriteString("<" + name)
+	startTagOpen := true
+	for c.dec.More() {
+		key, err := c.key()
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(key, xmlAttrPrefix) && key != xmlAttrPrefix {
+			if !startTagOpen {
+				return fmt.Errorf("attribute %q of <%s> must precede its content", key, name)
+			}
+			value, err := c.scalar()
+			if err != nil {
+				return err
+			}
+			attr := strings.TrimPrefix(key, xmlAttrPrefix)
+			if !isXMLName(attr) {
+				return fmt.Errorf("%q is not a valid XML name", attr)
+			}
+			c.w.WriteString(" " + attr + `="`)
+			xml.EscapeText(c.w, []byte(value))
+			c.w.WriteString(`"`)
+			continue
+		}
+
+		if startTagOpen {
+			c.w.WriteString(">")
+			startTagOpen = false
+		}
+
+		if key == xmlTextKey {
+			value, err := c.scalar()
+			if err != nil {
+				return err
+			}
+			xml.EscapeText(c.w, []byte(value))
+			continue
+		}
+
+		if err := c.value(key); err != nil {
+			return err
+		}
+	}
+
+	if startTagOpen {
+		c.w.WriteString("/>")
+	} else {
+		c.w.WriteString("</" + name + ">")
+	}
+	return c.delim('}')
+}
+
+func (c *jsonXMLConverter) scalar() (string, error) {
+	tok, err := c.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := tok.(json.Delim); ok {
+		return "", errors.New("attributes and text must be scalar JSON values")
+	}
+	return jsonScalar(tok), nil
+}
+
+func jsonScalar(tok json.Token) string {
+	switch v := tok.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	// null
+	return ""
+}
+
+// isXMLName reports whether s is a valid XML name, without namespace prefix rules.
+func isXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || r == '_' || r == ':' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// xmlToJSON converts the XML document of src to JSON token by token. Values are strings and
+// elements keep their local name. Consecutive elements of the same name become an array, the
+// first one being held in memory until the next sibling is known.
+func xmlToJSON(dst io.Writer, src io.Reader) error {
+	dec := xml.NewDecoder(src)
+	dec.CharsetReader = WrappedCharsetReader
+	w := bufio.NewWriter(dst)
+
+	var root *xml.StartElement
+	for root == nil {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return errors.New("XML document has no root element")
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root = &start
+		}
+	}
+
+	w.WriteString("{")
+	writeJSONString(w, root.Name.Local)
+	w.WriteString(":")
+	if err := xmlElementToJSON(w, dec, *root); err != nil {
+		return err
+	}
+	w.WriteString("}")
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			return errors.New("XML document has more than one root element")
+		}
+	}
+
+	return w.Flush()
+}
+
+// xmlElementToJSON writes the value of element start: a string if it only has text, an object
+// otherwise.
+func xmlElementToJSON(w io.Writer, dec *xml.Decoder, start xml.StartElement) error {
+	var (
+		isObject bool
+		first    = true
+		text     strings.Builder
+
+		// the current run of elements of the same name
+		runName  string
+		runLen   int
+		runFirst bytes.Buffer
+	)
+
+	openObject := func() {
+		if !isObject {
+			io.WriteString(w, "{")
+			isObject = true
+		}
+	}
+	writeKey := func(key string) {
+		if !first {
+			io.WriteString(w, ",")
+		}
+		first = false
+		writeJSONString(w, key)
+		io.WriteString(w, ":")
+	}
+	endRun := func() {
+		switch {
+		case runLen == 1:
+			writeKey(runName)
+			w.Write(runFirst.Bytes())
+		case runLen > 1:
+			io.WriteString(w, "]")
+		}
+		runLen = 0
+		runFirst.Reset()
+	}
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		openObject()
+		writeKey(xmlAttrPrefix + attr.Name.Local)
+		writeJSONString(w, attr.Value)
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			openObject()
+			if runLen > 0 && t.Name.Local == runName {
+				if runLen == 1 {
+					writeKey(runName)
+					io.WriteString(w, "[")
+					w.Write(runFirst.Bytes())
+				}
+				io.WriteString(w, ",")
+				if err := xmlElementToJSON(w, dec, t); err != nil {
+					return err
+				}
+				runLen++
+				continue
+			}
+
+			endRun()
+			runName, runLen = t.Name.Local, 1
+			if err := xmlElementToJSON(&runFirst, dec, t); err != nil {
+				return err
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			endRun()
+			value := strings.TrimSpace(text.String())
+			if !isObject {
+				writeJSONString(w, value)
+				return nil
+			}
+			if value != "" {
+				writeKey(xmlTextKey)
+				writeJSONString(w, value)
+			}
+			io.WriteString(w, "}")
+			return nil
+		}
+	}
+}
+
+func writeJSONString(w io.Writer, s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
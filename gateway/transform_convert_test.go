@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestJSONToXML(t *testing.T) {
+	for _, tc := range []struct {
+		name, root, in, out, err string
+	}{
+		{name: "single root", in: `{"order":{"-id":"1","item":[{"-qty":2,"#text":"apple"},{"#text":"pear"}],"paid":true,"note":null}}`,
+			out: `<order id="1"><item qty="2">apple</item><item>pear</item><paid>true</paid><note></note></order>`},
+		{name: "escaping", in: `{"a":"<b> & \"c\""}`, out: `<a>&lt;b&gt; &amp; &#34;c&#34;</a>`},
+		{name: "empty object", in: `{"a":{}}`, out: `<a/>`},
+		{name: "numbers", in: `{"a":[1.50,1e3]}`, out: `<a>1.50</a><a>1e3</a>`},
+		{name: "root", root: "doc", in: `{"a":1,"b":2}`, out: `<doc><a>1</a><b>2</b></doc>`},
+		{name: "root with array", root: "doc", in: `[1,{"a":2}]`, out: `<doc><item>1</item><item><a>2</a></item></doc>`},
+		{name: "several roots", in: `{"a":1,"b":2}`, err: "more than one root element"},
+		{name: "no root", in: `{}`, err: "no root element"},
+		{name: "array without root", in: `[1]`, err: "must be an object"},
+		{name: "invalid name", in: `{"a":{"1b":1}}`, err: `"1b" is not a valid XML name`},
+		{name: "attribute after content", in: `{"a":{"b":1,"-c":2}}`, err: `attribute "-c" of <a> must precede its content`},
+		{name: "invalid JSON", in: `{"a":`, err: "EOF"},
+		{name: "trailing data", in: `{"a":1}{}`, err: "unexpected data"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := jsonToXML(&out, strings.NewReader(tc.in), tc.root)
+			if tc.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.out, out.String())
+		})
+	}
+}
+
+func TestXMLToJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, out, err string
+	}{
+		{name: "elements and attributes", in: `<?xml version="1.0"?><order xmlns="urn:orders" xmlns:x="urn:x" id="1"><customer>Ann</customer><note/></order>`,
+			out: `{"order":{"-id":"1","customer":"Ann","note":""}}`},
+		{name: "consecutive repeats", in: `<list><a>1</a><a>2</a><a><b>3</b></a><c>4</c><a>5</a></list>`,
+			out: `{"list":{"a":["1","2",{"b":"3"}],"c":"4","a":"5"}}`},
+		{name: "text with children", in: `<a> hello <b>x</b></a>`, out: `{"a":{"b":"x","#text":"hello"}}`},
+		{name: "text with attributes", in: `<a b="&lt;c&gt;">d &amp; e</a>`, out: `{"a":{"-b":"<c>","#text":"d & e"}}`},
+		{name: "namespaces", in: `<x:a xmlns:x="urn:x"><x:b>1</x:b></x:a>`, out: `{"a":{"b":"1"}}`},
+		{name: "not well formed", in: `<a><b></a>`, err: "XML syntax error"},
+		{name: "truncated", in: `<a><b>`, err: "EOF"},
+		{name: "no root", in: `<?xml version="1.0"?>`, err: "no root element"},
+		{name: "several roots", in: `<a/><b/>`, err: "more than one root element"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := xmlToJSON(&out, strings.NewReader(tc.in))
+			if tc.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.out, out.String())
+		})
+	}
+}
+
+func TestConvertRequestBody(t *testing.T) {
+	jsonToXMLMode := apidef.TemplateData{Mode: apidef.UseJSONToXML}
+
+	t.Run("buffered body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`))
+		nopCloseRequestBody(r)
+
+		require.NoError(t, convertRequestBody(r, jsonToXMLMode))
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, `<a>1</a>`, string(body))
+		assert.Equal(t, int64(len(body)), r.ContentLength)
+		assert.Equal(t, "application/xml", r.Header.Get("Content-Type"))
+	})
+
+	t.Run("invalid body is kept", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1,"b":2}`))
+		nopCloseRequestBody(r)
+
+		assert.Error(t, convertRequestBody(r, jsonToXMLMode))
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, `{"a":1,"b":2}`, string(body))
+	})
+
+	t.Run("streamed body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", ioutil.NopCloser(strings.NewReader(`<a><b>1</b><b>2</b></a>`)))
+		r.ContentLength = -1
+
+		require.NoError(t, convertRequestBody(r, apidef.TemplateData{Mode: apidef.UseXMLToJSON}))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":{"b":["1","2"]}}`, string(body))
+		assert.Equal(t, int64(-1), r.ContentLength)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	})
+
+	t.Run("streamed invalid body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", ioutil.NopCloser(strings.NewReader(`<a>`)))
+		r.ContentLength = -1
+
+		require.NoError(t, convertRequestBody(r, apidef.TemplateData{Mode: apidef.UseXMLToJSON}))
+		_, err := ioutil.ReadAll(r.Body)
+		assert.Error(t, err, "the reader of the body should fail")
+	})
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+var errResponseTooLarge = errors.New("upstream response body exceeds the size limit")
+
+// responseSizeLimit returns the maximum upstream response body size for req, a matched
+// endpoint limit taking precedence over the global one. Zero means no limit.
+func (p *ReverseProxy) responseSizeLimit(req *http.Request) int64 {
+	vInfo, _ := p.TykAPISpec.Version(req)
+	if vInfo == nil {
+		return 0
+	}
+
+	if len(vInfo.ExtendedPaths.ResponseSizeLimit) > 0 {
+		versionPaths := p.TykAPISpec.RxPaths[vInfo.Name]
+		if found, meta := p.TykAPISpec.CheckSpecMatchesStatus(req, versionPaths, ResponseSizeLimit); found {
+			return meta.(*apidef.ResponseSizeMeta).SizeLimit
+		}
+	}
+
+	return vInfo.GlobalResponseSizeLimit
+}
+
+// limitResponseBody checks the body of res against limit. Responses declaring a larger
+// Content-Length are rejected without being read, responses of unknown length are
+// buffered up to the limit so that they can still be rejected before anything is
+// written to the client.
+func limitResponseBody(res *http.Response, limit int64) error {
+	if res.ContentLength > limit {
+		return errResponseTooLarge
+	}
+	if res.ContentLength >= 0 {
+		// the transport does not read past the declared length
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		return errResponseTooLarge
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// enforceResponseSizeLimit applies the response size limit of req to res. It writes a
+// 502 error and fires EventResponseSizeExceeded when the response is rejected, in which
+// case it returns false.
+func (p *ReverseProxy) enforceResponseSizeLimit(rw http.ResponseWriter, req, outreq, logreq *http.Request, res *http.Response) bool {
+	limit := p.responseSizeLimit(req)
+	if limit <= 0 || res.StatusCode == http.StatusSwitchingProtocols {
+		return true
+	}
+
+	err := limitResponseBody(res, limit)
+	if err == nil {
+		return true
+	}
+	res.Body.Close()
+
+	if err != errResponseTooLarge {
+		p.logger.WithError(err).Error("Could not read upstream response")
+		p.ErrorHandler.HandleError(rw, logreq, "There was a problem proxying the request", http.StatusBadGateway, true)
+		return false
+	}
+
+	p.logger.WithField("size_limit", limit).Warning("Upstream response exceeded the size limit")
+	p.TykAPISpec.FireEvent(EventResponseSizeExceeded, EventResponseSizeExceededMeta{
+		EventMetaDefault: EventMetaDefault{Message: "Upstream response exceeded the size limit", OriginatingRequest: EncodeRequestToEvent(logreq)},
+		Path:             req.URL.Path,
+		APIID:            p.TykAPISpec.APIID,
+		Upstream:         outreq.URL.Host,
+		SizeLimit:        limit,
+	})
+	p.ErrorHandler.HandleError(rw, logreq, "Upstream response is too large", http.StatusBadGateway, true)
+	return false
+}
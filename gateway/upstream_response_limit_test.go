@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestUpstreamResponseSizeLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("a", 10)
+		if strings.HasSuffix(r.URL.Path, "/large") {
+			body = strings.Repeat("a", 100)
+		}
+		if strings.HasPrefix(r.URL.Path, "/chunked") {
+			// flushing before writing the whole body makes the response chunked
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	specs := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.GlobalResponseSizeLimit = 50
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.ResponseSizeLimit = []apidef.ResponseSizeMeta{
+				{Path: "/allowed/large", Method: http.MethodGet, SizeLimit: 200},
+			}
+		})
+	})
+
+	events := make(chan config.EventMessage, 10)
+	specs[0].EventPaths = map[apidef.TykEvent][]config.TykEventHandler{
+		EventResponseSizeExceeded: {&testEventHandler{func(em config.EventMessage) { events <- em }}},
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/small", Code: http.StatusOK, BodyMatch: "^a{10}$"},
+		{Path: "/chunked/small", Code: http.StatusOK, BodyMatch: "^a{10}$"},
+		{Path: "/allowed/large", Code: http.StatusOK, BodyMatch: "^a{100}$"},
+		{Path: "/large", Code: http.StatusBadGateway, BodyMatch: "Upstream response is too large"},
+		{Path: "/chunked/large", Code: http.StatusBadGateway, BodyMatch: "Upstream response is too large"},
+	}...)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case em := <-events:
+			meta := em.Meta.(EventResponseSizeExceededMeta)
+			assert.Equal(t, int64(50), meta.SizeLimit)
+			assert.Equal(t, specs[0].APIID, meta.APIID)
+		case <-time.After(time.Second):
+			t.Fatal("response size exceeded event was not fired")
+		}
+	}
+}
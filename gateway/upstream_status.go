@@ -0,0 +1,246 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// upstreamLatencySamples is the number of most recent latencies kept per upstream host
+// to compute percentiles.
+const upstreamLatencySamples = 1000
+
+type upstreamHostStats struct {
+	requests  uint64
+	errors    uint64
+	latencies []time.Duration
+	next      int
+}
+
+// UpstreamStats collects request counts, errors and latencies per upstream host of an API.
+type UpstreamStats struct {
+	mu    sync.Mutex
+	hosts map[string]*upstreamHostStats
+}
+
+func NewUpstreamStats() *UpstreamStats {
+	return &UpstreamStats{hosts: make(map[string]*upstreamHostStats)}
+}
+
+// Record adds an upstream request to host which took latency and failed when failed is true.
+func (s *UpstreamStats) Record(host string, latency time.Duration, failed bool) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.hosts[host]
+	if !ok {
+		h = &upstreamHostStats{}
+		s.hosts[host] = h
+	}
+
+	h.requests++
+	if failed {
+		h.errors++
+	}
+
+	if len(h.latencies) < upstreamLatencySamples {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+		h.next = (h.next + 1) % upstreamLatencySamples
+	}
+}
+
+// UpstreamLatencyPercentiles are the latencies of the most recent requests to an upstream host, in milliseconds.
+type UpstreamLatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+// UpstreamTargetStatus is the status of an upstream target of an API.
+type UpstreamTargetStatus struct {
+	Host string `json:"host"`
+	// Weight is the share of load balanced requests routed to the target.
+	Weight    float64                    `json:"weight"`
+	Up        bool                       `json:"up"`
+	Requests  uint64                     `json:"requests"`
+	Errors    uint64                     `json:"errors"`
+	ErrorRate float64                    `json:"error_rate"`
+	Latency   UpstreamLatencyPercentiles `json:"latency"`
+}
+
+// CircuitBreakerStatus is the state of a circuit breaker configured on an endpoint of an API.
+type CircuitBreakerStatus struct {
+	Path      string  `json:"path"`
+	Method    string  `json:"method"`
+	State     string  `json:"state"`
+	Failures  int64   `json:"failures"`
+	Successes int64   `json:"successes"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// UpstreamStatus is the response of the upstream status endpoint of the control API.
+type UpstreamStatus struct {
+	APIID           string                 `json:"api_id"`
+	LoadBalancing   bool                   `json:"load_balancing"`
+	Targets         []UpstreamTargetStatus `json:"targets"`
+	CircuitBreakers []CircuitBreakerStatus `json:"circuit_breakers"`
+}
+
+func (s *UpstreamStats) status(host string) UpstreamTargetStatus {
+	st := UpstreamTargetStatus{Host: host, Up: true}
+	if s == nil {
+		return st
+	}
+
+	s.mu.Lock()
+	h, ok := s.hosts[host]
+	if !ok {
+		s.mu.Unlock()
+		return st
+	}
+	st.Requests, st.Errors = h.requests, h.errors
+	latencies := make([]time.Duration, len(h.latencies))
+	copy(latencies, h.latencies)
+	s.mu.Unlock()
+
+	if st.Requests > 0 {
+		st.ErrorRate = float64(st.Errors) / float64(st.Requests)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	st.Latency = UpstreamLatencyPercentiles{
+		P50: latencyPercentile(latencies, 50),
+		P90: latencyPercentile(latencies, 90),
+		P99: latencyPercentile(latencies, 99),
+	}
+
+	return st
+}
+
+func (s *UpstreamStats) hostNames() []string {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hosts := make([]string, 0, len(s.hosts))
+	for host := range s.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// latencyPercentile returns the p-th percentile of sorted in milliseconds, using the nearest rank.
+func latencyPercentile(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
+
+// upstreamStatus returns the status of the upstream targets and circuit breakers of spec.
+func (gw *Gateway) upstreamStatus(spec *APISpec) UpstreamStatus {
+	status := UpstreamStatus{
+		APIID:           spec.APIID,
+		LoadBalancing:   spec.Proxy.EnableLoadBalancing,
+		Targets:         []UpstreamTargetStatus{},
+		CircuitBreakers: []CircuitBreakerStatus{},
+	}
+
+	// hosts are repeated in the target list to give them a bigger share of the traffic
+	targets := []string{spec.Proxy.TargetURL}
+	if spec.Proxy.EnableLoadBalancing {
+		targets = spec.Proxy.Targets
+		if spec.Proxy.StructuredTargetList != nil {
+			targets = spec.Proxy.StructuredTargetList.All()
+		}
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, target := range targets {
+		if counts[target] == 0 {
+			order = append(order, target)
+		}
+		counts[target]++
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range order {
+		host := EnsureTransport(target, spec.Protocol)
+		u, err := url.Parse(host)
+		if err != nil {
+			continue
+		}
+
+		st := spec.UpstreamStats.status(u.Host)
+		st.Weight = float64(counts[target]) / float64(len(targets))
+		if spec.Proxy.CheckHostAgainstUptimeTests && gw.GlobalHostChecker.store != nil {
+			st.Up = !gw.GlobalHostChecker.HostDown(host)
+		}
+
+		status.Targets = append(status.Targets, st)
+		seen[u.Host] = true
+	}
+
+	// hosts reached through service discovery updates or URL rewrites
+	for _, host := range spec.UpstreamStats.hostNames() {
+		if !seen[host] {
+			status.Targets = append(status.Targets, spec.UpstreamStats.status(host))
+		}
+	}
+
+	for _, paths := range spec.RxPaths {
+		for _, path := range paths {
+			if path.Status != CircuitBreaker || path.CircuitBreaker.CB == nil {
+				continue
+			}
+
+			cb := path.CircuitBreaker.CB
+			state := "closed"
+			if cb.Tripped() {
+				state = "open"
+			}
+			status.CircuitBreakers = append(status.CircuitBreakers, CircuitBreakerStatus{
+				Path:      path.CircuitBreaker.Path,
+				Method:    path.CircuitBreaker.Method,
+				State:     state,
+				Failures:  cb.Failures(),
+				Successes: cb.Successes(),
+				ErrorRate: cb.ErrorRate(),
+			})
+		}
+	}
+
+	return status
+}
+
+func (gw *Gateway) upstreamStatusHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, gw.upstreamStatus(spec))
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestUpstreamStatus(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "balanced"
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.EnableLoadBalancing = true
+		spec.Proxy.Targets = []string{healthy.URL, healthy.URL, failing.URL}
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.CircuitBreaker = []apidef.CircuitBreakerMeta{
+				{Path: "/breaker", Method: http.MethodGet, ThresholdPercent: 0.5, Samples: 100, ReturnToServiceAfter: 60},
+			}
+		})
+	})
+
+	for i := 0; i < 6; i++ {
+		_, _ = ts.Run(t, test.TestCase{Path: "/"})
+	}
+
+	resp, _ := ts.Run(t, []test.TestCase{
+		{Path: "/tyk/apis/unknown/upstream-status", AdminAuth: true, Code: http.StatusNotFound},
+		{Path: "/tyk/apis/balanced/upstream-status", AdminAuth: true, Code: http.StatusOK},
+	}...)
+
+	var status UpstreamStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+
+	assert.Equal(t, "balanced", status.APIID)
+	assert.True(t, status.LoadBalancing)
+	require.Len(t, status.Targets, 2)
+
+	healthyURL, _ := url.Parse(healthy.URL)
+	assert.Equal(t, healthyURL.Host, status.Targets[0].Host)
+	assert.InDelta(t, 2.0/3, status.Targets[0].Weight, 0.001)
+	assert.Equal(t, uint64(4), status.Targets[0].Requests)
+	assert.Zero(t, status.Targets[0].ErrorRate)
+	assert.True(t, status.Targets[0].Up)
+
+	failingURL, _ := url.Parse(failing.URL)
+	assert.Equal(t, failingURL.Host, status.Targets[1].Host)
+	assert.InDelta(t, 1.0/3, status.Targets[1].Weight, 0.001)
+	assert.Equal(t, uint64(2), status.Targets[1].Errors)
+	assert.Equal(t, float64(1), status.Targets[1].ErrorRate)
+
+	require.Len(t, status.CircuitBreakers, 1)
+	assert.Equal(t, "/breaker", status.CircuitBreakers[0].Path)
+	assert.Equal(t, "closed", status.CircuitBreakers[0].State)
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	assert.Zero(t, latencyPercentile(nil, 50))
+
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, float64(50), latencyPercentile(latencies, 50))
+	assert.Equal(t, float64(99), latencyPercentile(latencies, 99))
+	assert.Equal(t, float64(5), latencyPercentile([]time.Duration{5 * time.Millisecond}, 90))
+}
@@ -0,0 +1,269 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	// wasmHostModule is the name of the module the host ABI is imported from.
+	wasmHostModule = "tyk"
+
+	defaultWasmMemoryPages = 256 // 16MiB
+	defaultWasmTimeout     = 1000
+)
+
+var errWasmOutOfBounds = errors.New("memory access out of bounds")
+
+// wasmCall holds the message a wasm plugin works on during one call: the request in request
+// middleware, the response in response middleware.
+type wasmCall struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+
+	bodyChanged bool
+
+	responded    bool
+	responseCode int
+	responseBody []byte
+
+	// session is the session of the request, nil before authentication and for keyless APIs.
+	session         *user.SessionState
+	sessionReadOnly bool
+	sessionChanged  bool
+
+	logger *logrus.Entry
+}
+
+type wasmCallKey struct{}
+
+// wasmPlugin is a compiled wasm module running in its own runtime, so the memory limit applies
+// to this plugin only.
+type wasmPlugin struct {
+	name    string
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+	timeout time.Duration
+}
+
+// loadWasmPlugin compiles the module at def.Path, def.Name being the exported function to call.
+func loadWasmPlugin(def apidef.MiddlewareDefinition) (*wasmPlugin, error) {
+	code, err := ioutil.ReadFile(def.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := def.MaxMemoryPages
+	if pages == 0 {
+		pages = defaultWasmMemoryPages
+	}
+	timeout := def.Timeout
+	if timeout <= 0 {
+		timeout = defaultWasmTimeout
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+
+	plugin := &wasmPlugin{
+		name:    def.Name,
+		runtime: runtime,
+		timeout: time.Duration(timeout) * time.Millisecond,
+	}
+	if err := plugin.compile(ctx, code); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+
+	return plugin, nil
+}
+
+func (p *wasmPlugin) compile(ctx context.Context, code []byte) error {
+	// plugins compiled for WASI, e.g. by TinyGo or Rust, import it even when unused
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, p.runtime); err != nil {
+		return err
+	}
+	if _, err := p.hostModule().Instantiate(ctx); err != nil {
+		return err
+	}
+
+	var err error
+	if p.module, err = p.runtime.CompileModule(ctx, code); err != nil {
+		return err
+	}
+	if _, ok := p.module.ExportedFunctions()[p.name]; !ok {
+		return fmt.Errorf("function %q is not exported by the module", p.name)
+	}
+
+	return nil
+}
+
+// run calls the plugin function in a fresh instance of the module, so calls neither share
+// memory nor state.
+func (p *wasmPlugin) run(ctx context.Context, call *wasmCall) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, wasmCallKey{}, call)
+
+	config := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize")
+	mod, err := p.runtime.InstantiateModule(ctx, p.module, config)
+	if err != nil {
+		return err
+	}
+	defer mod.Close(ctx)
+
+	if _, err := mod.ExportedFunction(p.name).Call(ctx); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("plugin exceeded its time limit of %s", p.timeout)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *wasmPlugin) close() {
+	p.runtime.Close(context.Background())
+}
+
+// hostModule defines the ABI of plugins. Strings and bytes are passed as pointer and length in
+// the memory of the plugin. Getters copy the value to the given buffer if it's large enough and
+// return its length, or -1 if there's no such value, so plugins can retry with a larger buffer.
+//
+//	get_header(name_ptr, name_len, buf_ptr, buf_len i32) i32
+//	set_header(name_ptr, name_len, value_ptr, value_len i32)
+//	remove_header(name_ptr, name_len i32)
+//	get_body(buf_ptr, buf_len i32) i32
+//	set_body(ptr, len i32)
+//	get_method(buf_ptr, buf_len i32) i32
+//	get_path(buf_ptr, buf_len i32) i32
+//	respond(code, body_ptr, body_len i32)
+//	get_session_meta(key_ptr, key_len, buf_ptr, buf_len i32) i32
+//	set_session_meta(key_ptr, key_len, value_ptr, value_len i32)
+//	log(level, msg_ptr, msg_len i32) // 0 debug, 1 info, 2 warning, 3 error
+//
+// Headers and body are the ones of the request in request middleware and the ones of the
+// response in response middleware. respond answers the request instead of the upstream, or
+// replaces the status and body of the response.
+//
+// Session metadata values other than strings are read as JSON. The metadata set by request
+// middleware is stored with the session, response middleware can only read it.
+func (p *wasmPlugin) hostModule() wazero.HostModuleBuilder {
+	return p.runtime.NewHostModuleBuilder(wasmHostModule).
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, namePtr, nameLen, bufPtr, bufLen uint32) int32 {
+		call := wasmCallFrom(ctx)
+		values, ok := call.header[http.CanonicalHeaderKey(wasmRead(m, namePtr, nameLen))]
+		if !ok || len(values) == 0 {
+			return -1
+		}
+		return wasmWrite(m, bufPtr, bufLen, []byte(values[0]))
+	}).Export("get_header").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, namePtr, nameLen, valuePtr, valueLen uint32) {
+		wasmCallFrom(ctx).header.Set(wasmRead(m, namePtr, nameLen), wasmRead(m, valuePtr, valueLen))
+	}).Export("set_header").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, namePtr, nameLen uint32) {
+		wasmCallFrom(ctx).header.Del(wasmRead(m, namePtr, nameLen))
+	}).Export("remove_header").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, bufPtr, bufLen uint32) int32 {
+		return wasmWrite(m, bufPtr, bufLen, wasmCallFrom(ctx).body)
+	}).Export("get_body").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, ptr, length uint32) {
+		call := wasmCallFrom(ctx)
+		call.body = []byte(wasmRead(m, ptr, length))
+		call.bodyChanged = true
+	}).Export("set_body").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, bufPtr, bufLen uint32) int32 {
+		return wasmWrite(m, bufPtr, bufLen, []byte(wasmCallFrom(ctx).method))
+	}).Export("get_method").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, bufPtr, bufLen uint32) int32 {
+		return wasmWrite(m, bufPtr, bufLen, []byte(wasmCallFrom(ctx).path))
+	}).Export("get_path").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, code, bodyPtr, bodyLen uint32) {
+		call := wasmCallFrom(ctx)
+		call.responded = true
+		call.responseCode = int(code)
+		call.responseBody = []byte(wasmRead(m, bodyPtr, bodyLen))
+	}).Export("respond").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, keyPtr, keyLen, bufPtr, bufLen uint32) int32 {
+		call := wasmCallFrom(ctx)
+		if call.session == nil {
+			return -1
+		}
+		value, ok := call.session.MetaData[wasmRead(m, keyPtr, keyLen)]
+		if !ok {
+			return -1
+		}
+		if s, ok := value.(string); ok {
+			return wasmWrite(m, bufPtr, bufLen, []byte(s))
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return -1
+		}
+		return wasmWrite(m, bufPtr, bufLen, encoded)
+	}).Export("get_session_meta").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, keyPtr, keyLen, valuePtr, valueLen uint32) {
+		call := wasmCallFrom(ctx)
+		key, value := wasmRead(m, keyPtr, keyLen), wasmRead(m, valuePtr, valueLen)
+		if call.session == nil || call.sessionReadOnly {
+			call.logger.Warningf("Wasm plugin can't set session metadata %q here", key)
+			return
+		}
+		if call.session.MetaData == nil {
+			call.session.MetaData = make(map[string]interface{})
+		}
+		call.session.MetaData[key] = value
+		call.sessionChanged = true
+	}).Export("set_session_meta").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, level, msgPtr, msgLen uint32) {
+		logger, msg := wasmCallFrom(ctx).logger, wasmRead(m, msgPtr, msgLen)
+		switch level {
+		case 0:
+			logger.Debug(msg)
+		case 1:
+			logger.Info(msg)
+		case 2:
+			logger.Warning(msg)
+		default:
+			logger.Error(msg)
+		}
+	}).Export("log")
+}
+
+func wasmCallFrom(ctx context.Context) *wasmCall {
+	return ctx.Value(wasmCallKey{}).(*wasmCall)
+}
+
+// wasmRead reads a string from the memory of m, the call fails if it's out of bounds.
+func wasmRead(m api.Module, ptr, length uint32) string {
+	b, ok := m.Memory().Read(ptr, length)
+	if !ok {
+		panic(errWasmOutOfBounds)
+	}
+	return string(b)
+}
+
+// wasmWrite copies value to the buffer of the plugin if it fits, and returns its length.
+func wasmWrite(m api.Module, bufPtr, bufLen uint32, value []byte) int32 {
+	if uint32(len(value)) <= bufLen && !m.Memory().Write(bufPtr, value) {
+		panic(errWasmOutOfBounds)
+	}
+	return int32(len(value))
+}
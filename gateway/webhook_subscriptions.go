@@ -0,0 +1,626 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gocraft/health"
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	defaultWebhookSubscriptionsPath = "/subscriptions"
+	defaultWebhookMaxAttempts       = 3
+	defaultWebhookRetryBackoff      = 1.0
+	defaultWebhookTimeout           = 10.0
+	webhookResolveTimeout           = 5 * time.Second
+
+	// webhookDeliveryConcurrency limits the number of deliveries in flight per gateway.
+	webhookDeliveryConcurrency = 20
+)
+
+var (
+	errWebhookCallbackURL     = errors.New("callback_url must be an absolute http or https URL")
+	errWebhookCallbackHost    = errors.New("callback_url host is not allowed")
+	errWebhookCallbackResolve = errors.New("callback_url host can't be resolved")
+	errWebhookNoEvents        = errors.New("at least one event must be subscribed to")
+	errWebhookEventDenied     = errors.New("subscribing to this event is not allowed")
+	errWebhookLimit           = errors.New("subscription limit reached for this key")
+)
+
+// WebhookSubscription is a callback URL registered by an API consumer.
+type WebhookSubscription struct {
+	ID          string               `json:"id"`
+	APIID       string               `json:"api_id"`
+	Owner       string               `json:"-"`
+	CallbackURL string               `json:"callback_url"`
+	Events      []string             `json:"events"`
+	Secret      string               `json:"secret,omitempty"`
+	Created     time.Time            `json:"created"`
+	Stats       WebhookDeliveryStats `json:"stats"`
+}
+
+// webhookSubscriptionRecord is the stored form of a subscription, which unlike the API form keeps the owner.
+type webhookSubscriptionRecord struct {
+	WebhookSubscription
+	Owner string `json:"owner"`
+}
+
+// WebhookDeliveryStats holds delivery analytics of a subscription.
+type WebhookDeliveryStats struct {
+	Delivered   int64     `json:"delivered"`
+	Failed      int64     `json:"failed"`
+	LastStatus  int       `json:"last_status"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastAttempt time.Time `json:"last_attempt"`
+}
+
+// WebhookEvent is an event published by an upstream and delivered to subscribers.
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	APIID     string          `json:"api_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// WebhookSubscriptionManager stores subscriptions and fans out published events to them.
+type WebhookSubscriptionManager struct {
+	Gw *Gateway
+
+	store storage.Handler
+	// mu serialises read-modify-write updates of stored subscriptions
+	mu  sync.Mutex
+	sem chan struct{}
+	// publicTransport delivers the events of the APIs without allowed hosts.
+	publicTransport *http.Transport
+}
+
+func NewWebhookSubscriptionManager(gw *Gateway) *WebhookSubscriptionManager {
+	store := &storage.RedisCluster{KeyPrefix: "webhook.subscriptions.", RedisController: gw.RedisController}
+	store.Connect()
+
+	return &WebhookSubscriptionManager{
+		Gw:    gw,
+		store: store,
+		sem:   make(chan struct{}, webhookDeliveryConcurrency),
+
+		publicTransport: newWebhookPublicTransport(),
+	}
+}
+
+func webhookSubscriptionKey(apiID, id string) string {
+	return apiID + "." + id
+}
+
+// webhookSubscriptionIndexKey is the key of the set of the IDs of the subscriptions of an API, all
+// of them, those of an owner or those subscribed to an event.
+func webhookSubscriptionIndexKey(apiID, index string) string {
+	return apiID + ".index." + index
+}
+
+func webhookSubscriptionIndexes(sub WebhookSubscription) []string {
+	indexes := []string{"all", "owner." + sub.Owner}
+	for _, event := range sub.Events {
+		indexes = append(indexes, "event."+event)
+	}
+	return indexes
+}
+
+func (m *WebhookSubscriptionManager) save(sub WebhookSubscription) error {
+	data, err := json.Marshal(webhookSubscriptionRecord{WebhookSubscription: sub, Owner: sub.Owner})
+	if err != nil {
+		return err
+	}
+	return m.store.SetKey(webhookSubscriptionKey(sub.APIID, sub.ID), string(data), 0)
+}
+
+func decodeWebhookSubscription(data string) (WebhookSubscription, error) {
+	var record webhookSubscriptionRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return WebhookSubscription{}, err
+	}
+	record.WebhookSubscription.Owner = record.Owner
+	return record.WebhookSubscription, nil
+}
+
+// Get returns a single subscription of an API.
+func (m *WebhookSubscriptionManager) Get(apiID, id string) (WebhookSubscription, bool) {
+	data, err := m.store.GetKey(webhookSubscriptionKey(apiID, id))
+	if err != nil {
+		return WebhookSubscription{}, false
+	}
+
+	sub, err := decodeWebhookSubscription(data)
+	return sub, err == nil
+}
+
+// List returns the subscriptions of an API, limited to the given owner when it is not empty.
+func (m *WebhookSubscriptionManager) List(apiID, owner string) []WebhookSubscription {
+	if owner == "" {
+		return m.listIndex(apiID, "all")
+	}
+	return m.listIndex(apiID, "owner."+owner)
+}
+
+// listIndex returns the subscriptions of an index of an API.
+func (m *WebhookSubscriptionManager) listIndex(apiID, index string) []WebhookSubscription {
+	subs := []WebhookSubscription{}
+
+	ids, err := m.store.GetSet(webhookSubscriptionIndexKey(apiID, index))
+	if err != nil || len(ids) == 0 {
+		return subs
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, webhookSubscriptionKey(apiID, id))
+	}
+
+	values, err := m.store.GetMultiKey(keys)
+	if err != nil {
+		return subs
+	}
+	for _, data := range values {
+		// the subscription may have been deleted since the index was read
+		if data == "" {
+			continue
+		}
+		sub, err := decodeWebhookSubscription(data)
+		if err != nil || sub.APIID != apiID {
+			continue
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
+
+// Add validates sub against the API subscription policy and stores it.
+func (m *WebhookSubscriptionManager) Add(conf apidef.WebhookSubscriptions, sub *WebhookSubscription) error {
+	if err := validateWebhookSubscription(conf, sub); err != nil {
+		return err
+	}
+
+	if conf.MaxPerKey > 0 && len(m.List(sub.APIID, sub.Owner)) >= conf.MaxPerKey {
+		return errWebhookLimit
+	}
+
+	sub.ID = uuid.NewV4().String()
+	sub.Secret = strings.Replace(uuid.NewV4().String(), "-", "", -1)
+	sub.Created = time.Now()
+	sub.Stats = WebhookDeliveryStats{}
+
+	if err := m.save(*sub); err != nil {
+		return err
+	}
+	for _, index := range webhookSubscriptionIndexes(*sub) {
+		m.store.AddToSet(webhookSubscriptionIndexKey(sub.APIID, index), sub.ID)
+	}
+	return nil
+}
+
+// Delete removes a subscription, only if owned by owner when owner is not empty.
+func (m *WebhookSubscriptionManager) Delete(apiID, id, owner string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub, found := m.Get(apiID, id)
+	if !found || (owner != "" && sub.Owner != owner) {
+		return false
+	}
+
+	for _, index := range webhookSubscriptionIndexes(sub) {
+		m.store.RemoveFromSet(webhookSubscriptionIndexKey(apiID, index), id)
+	}
+	return m.store.DeleteKey(webhookSubscriptionKey(apiID, id))
+}
+
+func validateWebhookSubscription(conf apidef.WebhookSubscriptions, sub *WebhookSubscription) error {
+	u, err := url.Parse(sub.CallbackURL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errWebhookCallbackURL
+	}
+
+	if len(conf.AllowedHosts) > 0 {
+		if !contains(conf.AllowedHosts, u.Hostname()) {
+			return errWebhookCallbackHost
+		}
+	} else if err := checkWebhookCallbackHost(u.Hostname()); err != nil {
+		return err
+	}
+
+	if len(sub.Events) == 0 {
+		return errWebhookNoEvents
+	}
+
+	if len(conf.AllowedEvents) > 0 {
+		for _, event := range sub.Events {
+			if !contains(conf.AllowedEvents, event) {
+				return errWebhookEventDenied
+			}
+		}
+	}
+
+	return nil
+}
+
+// webhookInternalNets are the private ranges callbacks can't target unless their host is allowed.
+var webhookInternalNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		nets = append(nets, ipNet)
+	}
+	return nets
+}()
+
+// webhookPublicIP reports whether ip isn't a loopback, private, link-local, e.g. the cloud metadata
+// endpoints, multicast or unspecified address.
+func webhookPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, ipNet := range webhookInternalNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookCallbackHost refuses the callback hosts resolving to internal addresses, when the
+// API doesn't restrict the callbacks to allowed hosts.
+func checkWebhookCallbackHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return errWebhookCallbackResolve
+	}
+	for _, addr := range addrs {
+		if !webhookPublicIP(addr.IP) {
+			return errWebhookCallbackHost
+		}
+	}
+	return nil
+}
+
+// webhookDialControl refuses the connections to internal addresses, checked once the host is
+// resolved so that the callbacks can't be rebound to internal addresses after their registration.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !webhookPublicIP(ip) {
+		return errWebhookCallbackHost
+	}
+	return nil
+}
+
+// newWebhookPublicTransport returns the transport of the deliveries of the APIs without allowed
+// hosts, which only connects to public addresses. It doesn't go through the proxy of the
+// environment, whose address is usually internal.
+func newWebhookPublicTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: webhookDialControl}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// Publish queues delivery of an event to all subscribers of the event type and returns the number of subscribers.
+func (m *WebhookSubscriptionManager) Publish(spec *APISpec, event WebhookEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	delivered := map[string]bool{}
+	subs := append(m.listIndex(spec.APIID, "event."+event.Type), m.listIndex(spec.APIID, "event.*")...)
+	for _, sub := range subs {
+		// subscriptions to both the event and all events get it once
+		if delivered[sub.ID] {
+			continue
+		}
+		delivered[sub.ID] = true
+
+		count++
+		go m.deliver(spec.WebhookSubscriptions, sub, event, body)
+	}
+
+	return count, nil
+}
+
+// webhookSignature signs the delivery timestamp and body with the subscription secret.
+func webhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *WebhookSubscriptionManager) deliver(conf apidef.WebhookSubscriptions, sub WebhookSubscription, event WebhookEvent, body []byte) {
+	m.sem <- struct{}{}
+	defer func() { <-m.sem }()
+
+	attempts := conf.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookMaxAttempts
+	}
+
+	backoffSeconds := conf.RetryBackoff
+	if backoffSeconds <= 0 {
+		backoffSeconds = defaultWebhookRetryBackoff
+	}
+	backoff := time.Duration(backoffSeconds * float64(time.Second))
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	client := &http.Client{Timeout: time.Duration(timeout * float64(time.Second))}
+	if len(conf.AllowedHosts) == 0 {
+		client.Transport = m.publicTransport
+	}
+
+	logger := log.WithFields(logrus.Fields{
+		"prefix":          "webhook-subscriptions",
+		"api_id":          sub.APIID,
+		"subscription_id": sub.ID,
+		"event_id":        event.ID,
+	})
+
+	var status int
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		status, err = m.send(client, sub, event, body)
+		if err == nil {
+			m.recordDelivery(sub, status, nil)
+			logger.Debug("Event delivered")
+			return
+		}
+
+		logger.WithError(err).Debug("Event delivery attempt ", attempt, " failed")
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-m.Gw.ctx.Done():
+			return
+		}
+		backoff *= 2
+	}
+
+	logger.WithError(err).Warning("Event delivery failed")
+	m.recordDelivery(sub, status, err)
+}
+
+func (m *WebhookSubscriptionManager) send(client *http.Client, sub WebhookSubscription, event WebhookEvent, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set(headers.ContentType, headers.ApplicationJSON)
+	req.Header.Set(headers.UserAgent, defaultUserAgent)
+	req.Header.Set(headers.XTykEvent, event.Type)
+	req.Header.Set(headers.XTykDelivery, event.ID)
+	req.Header.Set(headers.XTykSignature, webhookSignature(sub.Secret, time.Now().Unix(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// recordDelivery updates the delivery analytics of a subscription.
+func (m *WebhookSubscriptionManager) recordDelivery(sub WebhookSubscription, status int, deliveryErr error) {
+	job := instrument.NewJob("WebhookSubscription")
+	kvs := health.Kvs{"api_id": sub.APIID, "subscription_id": sub.ID}
+	if deliveryErr != nil {
+		job.EventKv("delivery_failed", kvs)
+	} else {
+		job.EventKv("delivered", kvs)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the subscription may have been updated or removed while delivering
+	current, found := m.Get(sub.APIID, sub.ID)
+	if !found {
+		return
+	}
+
+	current.Stats.LastStatus = status
+	current.Stats.LastAttempt = time.Now()
+	current.Stats.LastError = ""
+	if deliveryErr != nil {
+		current.Stats.Failed++
+		current.Stats.LastError = deliveryErr.Error()
+	} else {
+		current.Stats.Delivered++
+	}
+
+	if err := m.save(current); err != nil {
+		log.WithError(err).Error("Could not save webhook subscription delivery stats")
+	}
+}
+
+// WebhookSubscriptionMiddleware serves the consumer facing subscription management endpoints of an API.
+type WebhookSubscriptionMiddleware struct {
+	BaseMiddleware
+}
+
+func (m *WebhookSubscriptionMiddleware) Name() string {
+	return "WebhookSubscriptionMiddleware"
+}
+
+func (m *WebhookSubscriptionMiddleware) EnabledForSpec() bool {
+	return m.Spec.WebhookSubscriptions.Enabled && !m.Spec.UseKeylessAccess
+}
+
+func (m *WebhookSubscriptionMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	basePath := m.Spec.WebhookSubscriptions.Path
+	if basePath == "" {
+		basePath = defaultWebhookSubscriptionsPath
+	}
+	basePath = "/" + strings.Trim(basePath, "/")
+
+	path := "/" + strings.TrimPrefix(m.Spec.StripListenPath(r, r.URL.Path), "/")
+	if path != basePath && !strings.HasPrefix(path, basePath+"/") {
+		return nil, http.StatusOK
+	}
+
+	token := ctxGetAuthToken(r)
+	if token == "" {
+		return errors.New("Access to this resource has been disallowed"), http.StatusForbidden
+	}
+
+	owner := storage.HashStr(token)
+	manager := m.Gw.webhookSubscriptions
+	id := strings.Trim(strings.TrimPrefix(path, basePath), "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		doJSONWrite(w, http.StatusOK, manager.List(m.Spec.APIID, owner))
+	case id == "" && r.Method == http.MethodPost:
+		var sub WebhookSubscription
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			return errors.New("Request malformed"), http.StatusBadRequest
+		}
+
+		sub.APIID = m.Spec.APIID
+		sub.Owner = owner
+		if err := manager.Add(m.Spec.WebhookSubscriptions, &sub); err != nil {
+			if err == errWebhookLimit {
+				return err, http.StatusForbidden
+			}
+			return err, http.StatusBadRequest
+		}
+
+		m.Logger().WithField("subscription_id", sub.ID).Info("Webhook subscription created")
+		doJSONWrite(w, http.StatusCreated, sub)
+	case id != "" && r.Method == http.MethodGet:
+		sub, found := manager.Get(m.Spec.APIID, id)
+		if !found || sub.Owner != owner {
+			return errors.New("Subscription not found"), http.StatusNotFound
+		}
+		doJSONWrite(w, http.StatusOK, sub)
+	case id != "" && r.Method == http.MethodDelete:
+		if !manager.Delete(m.Spec.APIID, id, owner) {
+			return errors.New("Subscription not found"), http.StatusNotFound
+		}
+
+		m.Logger().WithField("subscription_id", id).Info("Webhook subscription deleted")
+		doJSONWrite(w, http.StatusOK, apiOk("deleted"))
+	default:
+		return errors.New("Method not allowed"), http.StatusMethodNotAllowed
+	}
+
+	return nil, mwStatusRespond
+}
+
+// webhookSubscriptionsHandler lists the subscriptions of an API with their delivery analytics.
+func (gw *Gateway) webhookSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	subs := gw.webhookSubscriptions.List(apiID, "")
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+
+	doJSONWrite(w, http.StatusOK, subs)
+}
+
+// webhookSubscriptionDeleteHandler removes any subscription of an API.
+func (gw *Gateway) webhookSubscriptionDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if !gw.webhookSubscriptions.Delete(vars["apiID"], vars["subID"], "") {
+		doJSONWrite(w, http.StatusNotFound, apiError("Subscription not found"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, apiOk("deleted"))
+}
+
+// webhookPublishHandler accepts an event from an upstream and fans it out to the API subscribers.
+func (gw *Gateway) webhookPublishHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil || !spec.WebhookSubscriptions.Enabled {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	var event WebhookEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.Type == "" {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	event.ID = uuid.NewV4().String()
+	event.APIID = apiID
+	event.Timestamp = time.Now().Unix()
+
+	count, err := gw.webhookSubscriptions.Publish(spec, event)
+	if err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError(err.Error()))
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":      "webhook-subscriptions",
+		"api_id":      apiID,
+		"event_id":    event.ID,
+		"subscribers": count,
+	}).Debug("Event published")
+
+	doJSONWrite(w, http.StatusAccepted, map[string]interface{}{
+		"event_id":    event.ID,
+		"subscribers": count,
+	})
+}
@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/headers"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+
+	var attempts int32
+	deliveries := make(chan delivery, 10)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail the first attempt to exercise retries
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		deliveries <- delivery{header: r.Header, body: body}
+	}))
+	defer subscriber.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "test"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+		spec.WebhookSubscriptions = apidef.WebhookSubscriptions{
+			Enabled:       true,
+			MaxPerKey:     1,
+			AllowedHosts:  []string{"127.0.0.1"},
+			AllowedEvents: []string{"order.created"},
+			RetryBackoff:  0.01,
+		}
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"test": {
+			APIID: "test", Versions: []string{"v1"},
+		}}
+	})
+	authHeader := map[string]string{headers.Authorization: key}
+
+	subscription := map[string]interface{}{"callback_url": subscriber.URL, "events": []string{"order.created"}}
+
+	t.Run("policy", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/subscriptions", Code: http.StatusUnauthorized, Data: subscription},
+			{Method: http.MethodPost, Path: "/subscriptions", Headers: authHeader, Code: http.StatusBadRequest,
+				Data: map[string]interface{}{"callback_url": "http://example.com/hook", "events": []string{"order.created"}}},
+			{Method: http.MethodPost, Path: "/subscriptions", Headers: authHeader, Code: http.StatusBadRequest,
+				Data: map[string]interface{}{"callback_url": subscriber.URL, "events": []string{"order.deleted"}}},
+			{Method: http.MethodPost, Path: "/subscriptions", Headers: authHeader, Code: http.StatusBadRequest,
+				Data: map[string]interface{}{"callback_url": "/relative", "events": []string{"order.created"}}},
+		}...)
+	})
+
+	resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/subscriptions", Headers: authHeader, Data: subscription, Code: http.StatusCreated})
+	var sub WebhookSubscription
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&sub))
+	assert.NotEmpty(t, sub.ID)
+	assert.NotEmpty(t, sub.Secret)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/subscriptions", Headers: authHeader, Data: subscription, Code: http.StatusForbidden},
+		{Method: http.MethodGet, Path: "/subscriptions", Headers: authHeader, Code: http.StatusOK, BodyMatch: sub.ID},
+		{Method: http.MethodGet, Path: "/subscriptions/" + sub.ID, Headers: authHeader, Code: http.StatusOK, BodyMatch: `"callback_url"`},
+		{Method: http.MethodGet, Path: "/tyk/apis/test/subscriptions", AdminAuth: true, Code: http.StatusOK, BodyNotMatch: sub.Secret},
+	}...)
+
+	t.Run("publish", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/tyk/apis/test/events", AdminAuth: true, Data: `{"type":"order.updated"}`, Code: http.StatusAccepted, BodyMatch: `"subscribers":0`},
+			{Method: http.MethodPost, Path: "/tyk/apis/test/events", AdminAuth: true, Data: `{"type":"order.created","payload":{"id":1}}`, Code: http.StatusAccepted, BodyMatch: `"subscribers":1`},
+			{Method: http.MethodPost, Path: "/tyk/apis/unknown/events", AdminAuth: true, Data: `{"type":"order.created"}`, Code: http.StatusNotFound},
+		}...)
+
+		select {
+		case d := <-deliveries:
+			var event WebhookEvent
+			assert.NoError(t, json.Unmarshal(d.body, &event))
+			assert.Equal(t, "order.created", event.Type)
+			assert.JSONEq(t, `{"id":1}`, string(event.Payload))
+			assert.Equal(t, "order.created", d.header.Get(headers.XTykEvent))
+			assert.Equal(t, event.ID, d.header.Get(headers.XTykDelivery))
+
+			signature := d.header.Get(headers.XTykSignature)
+			var timestamp int64
+			_, err := fmt.Sscanf(signature, "t=%d,", &timestamp)
+			assert.NoError(t, err)
+			assert.Equal(t, webhookSignature(sub.Secret, timestamp, d.body), signature)
+		case <-time.After(5 * time.Second):
+			t.Fatal("event was not delivered")
+		}
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+		assert.Eventually(t, func() bool {
+			stored, _ := ts.Gw.webhookSubscriptions.Get("test", sub.ID)
+			return stored.Stats.Delivered == 1 && stored.Stats.LastStatus == http.StatusOK
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodDelete, Path: "/subscriptions/" + sub.ID, Headers: authHeader, Code: http.StatusOK},
+		{Method: http.MethodDelete, Path: "/subscriptions/" + sub.ID, Headers: authHeader, Code: http.StatusNotFound},
+		{Method: http.MethodGet, Path: "/subscriptions", Headers: authHeader, Code: http.StatusOK, BodyMatch: `^\[\]`},
+	}...)
+}
+
+func TestWebhookCallbackHost(t *testing.T) {
+	for _, tc := range []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.20.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+	} {
+		assert.Equal(t, tc.public, webhookPublicIP(net.ParseIP(tc.ip)), tc.ip)
+	}
+
+	conf := apidef.WebhookSubscriptions{}
+	for _, callbackURL := range []string{"http://127.0.0.1/hook", "http://localhost:8080/hook", "http://169.254.169.254/latest/meta-data"} {
+		sub := &WebhookSubscription{CallbackURL: callbackURL, Events: []string{"order.created"}}
+		assert.Equal(t, errWebhookCallbackHost, validateWebhookSubscription(conf, sub), callbackURL)
+	}
+
+	conf.AllowedHosts = []string{"127.0.0.1"}
+	sub := &WebhookSubscription{CallbackURL: "http://127.0.0.1/hook", Events: []string{"order.created"}}
+	assert.NoError(t, validateWebhookSubscription(conf, sub), "allowed hosts can be internal")
+
+	assert.Equal(t, errWebhookCallbackHost, webhookDialControl("tcp", "10.0.0.1:80", nil))
+	assert.NoError(t, webhookDialControl("tcp", "93.184.216.34:443", nil))
+}
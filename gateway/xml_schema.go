@@ -0,0 +1,1172 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+const (
+	xsdNamespace            = "http://www.w3.org/2001/XMLSchema"
+	xsiNamespace            = "http://www.w3.org/2001/XMLSchema-instance"
+	xmlNamespace            = "http://www.w3.org/XML/1998/namespace"
+	soap11EnvelopeNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNamespace = "http://www.w3.org/2003/05/soap-envelope"
+
+	xsdUnbounded = -1
+)
+
+var errXMLNotWellFormed = errors.New("XML parsing error")
+
+// xmlNode is a parsed XML element.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     string
+	// ns maps the prefixes in scope to their namespace, the default namespace has no prefix.
+	ns map[string]string
+}
+
+// attr returns the value of the unqualified attribute name.
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// child returns the first child of n with the given local name.
+func (n *xmlNode) child(local string) *xmlNode {
+	for _, c := range n.children {
+		if c.name.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseXML parses data into its root element.
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	var root *xmlNode
+	var stack []*xmlNode
+	var text [][]byte
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attrs: t.Attr, ns: map[string]string{}}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+				node.ns = parent.ns
+			} else if root != nil {
+				return nil, errors.New("multiple root elements")
+			} else {
+				root = node
+			}
+
+			for _, a := range t.Attr {
+				prefix, declared := "", false
+				switch {
+				case a.Name.Space == "xmlns":
+					prefix, declared = a.Name.Local, true
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					declared = true
+				}
+				if !declared {
+					continue
+				}
+				ns := make(map[string]string, len(node.ns)+1)
+				for k, v := range node.ns {
+					ns[k] = v
+				}
+				ns[prefix] = a.Value
+				node.ns = ns
+			}
+
+			stack = append(stack, node)
+			text = append(text, nil)
+		case xml.EndElement:
+			stack[len(stack)-1].text = string(text[len(text)-1])
+			stack, text = stack[:len(stack)-1], text[:len(text)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				text[len(text)-1] = append(text[len(text)-1], t...)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("no root element")
+	}
+	return root, nil
+}
+
+// xmlSchema is a compiled XML schema, supporting the subset of XSD used to describe request
+// payloads: global elements, named and anonymous complex and simple types, sequences, choices,
+// all groups, model and attribute groups, extensions, restrictions with their facets, lists,
+// unions and the built-in data types. Elements and attributes are matched by namespace and
+// local name, constructs outside of the subset are rejected when the schema is compiled.
+type xmlSchema struct {
+	targetNamespace string
+	// elementQualified and attributeQualified are the elementFormDefault and attributeFormDefault
+	// of the schema.
+	elementQualified   bool
+	attributeQualified bool
+	elements           map[string]*xsdElement
+
+	complexTypes    map[string]*xmlNode
+	simpleTypes     map[string]*xmlNode
+	groups          map[string]*xmlNode
+	attributeGroups map[string]*xmlNode
+
+	compiledComplex map[string]*xsdType
+	compiledSimple  map[string]*xsdSimpleType
+}
+
+type xsdElement struct {
+	space string
+	name  string
+	typ   *xsdType
+}
+
+type xsdParticleKind int
+
+const (
+	xsdElementParticle xsdParticleKind = iota
+	xsdSequence
+	xsdChoice
+	xsdAll
+	xsdAny
+)
+
+type xsdParticle struct {
+	kind      xsdParticleKind
+	element   *xsdElement
+	particles []*xsdParticle
+	minOccurs int
+	maxOccurs int
+}
+
+type xsdAttribute struct {
+	space    string
+	typ      *xsdSimpleType
+	required bool
+}
+
+type xsdType struct {
+	// any accepts any content, as xs:anyType does.
+	any bool
+	// simple is the type of the text of simple types and complex types with simple content.
+	simple       *xsdSimpleType
+	content      *xsdParticle
+	mixed        bool
+	attributes   map[string]*xsdAttribute
+	anyAttribute bool
+}
+
+type xsdSimpleType struct {
+	builtin string
+	// itemType is the type of the items of lists, members the types of unions.
+	itemType *xsdSimpleType
+	members  []*xsdSimpleType
+
+	enumeration []string
+	patterns    []*regexp.Regexp
+	length      int
+	minLength   int
+	maxLength   int
+	bounds      []xsdBound
+}
+
+// xsdBound is a minInclusive, maxInclusive, minExclusive or maxExclusive facet.
+type xsdBound struct {
+	facet string
+	value string
+}
+
+// compileXMLSchema compiles the XSD document data.
+func compileXMLSchema(data []byte) (*xmlSchema, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.name.Space != xsdNamespace || root.name.Local != "schema" {
+		return nil, errors.New("root element is not an XML schema")
+	}
+
+	s := &xmlSchema{
+		elements:        map[string]*xsdElement{},
+		complexTypes:    map[string]*xmlNode{},
+		simpleTypes:     map[string]*xmlNode{},
+		groups:          map[string]*xmlNode{},
+		attributeGroups: map[string]*xmlNode{},
+		compiledComplex: map[string]*xsdType{},
+		compiledSimple:  map[string]*xsdSimpleType{},
+	}
+	s.targetNamespace, _ = root.attr("targetNamespace")
+	if form, _ := root.attr("elementFormDefault"); form == "qualified" {
+		s.elementQualified = true
+	}
+	if form, _ := root.attr("attributeFormDefault"); form == "qualified" {
+		s.attributeQualified = true
+	}
+
+	// declarations can be referenced before they are defined, index them all first
+	var elements []*xmlNode
+	for _, c := range root.children {
+		name, _ := c.attr("name")
+		switch c.name.Local {
+		case "element":
+			s.elements[name] = &xsdElement{space: s.targetNamespace, name: name}
+			elements = append(elements, c)
+		case "complexType":
+			s.complexTypes[name] = c
+		case "simpleType":
+			s.simpleTypes[name] = c
+		case "group":
+			s.groups[name] = c
+		case "attributeGroup":
+			s.attributeGroups[name] = c
+		case "annotation", "attribute", "notation":
+		case "import", "include", "redefine", "override":
+			return nil, fmt.Errorf("xs:%s is not supported", c.name.Local)
+		default:
+			return nil, fmt.Errorf("unexpected xs:%s", c.name.Local)
+		}
+	}
+
+	for _, node := range elements {
+		name, _ := node.attr("name")
+		if s.elements[name].typ, err = s.elementType(node); err != nil {
+			return nil, fmt.Errorf("element %q: %v", name, err)
+		}
+	}
+	for name := range s.complexTypes {
+		if _, err := s.complexType(name); err != nil {
+			return nil, fmt.Errorf("complex type %q: %v", name, err)
+		}
+	}
+	for name := range s.simpleTypes {
+		if _, err := s.simpleType(name); err != nil {
+			return nil, fmt.Errorf("simple type %q: %v", name, err)
+		}
+	}
+
+	return s, nil
+}
+
+// qname resolves the prefixed name value in the scope of node.
+func qname(node *xmlNode, value string) xml.Name {
+	prefix, local := "", value
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		prefix, local = value[:i], value[i+1:]
+	}
+	return xml.Name{Space: node.ns[prefix], Local: local}
+}
+
+// namespace returns the namespace of the local element or attribute declaration node, given the
+// form default of the schema.
+func (s *xmlSchema) namespace(node *xmlNode, qualifiedDefault bool) string {
+	qualified := qualifiedDefault
+	if form, ok := node.attr("form"); ok {
+		qualified = form == "qualified"
+	}
+	if qualified {
+		return s.targetNamespace
+	}
+	return ""
+}
+
+func (s *xmlSchema) complexType(name string) (*xsdType, error) {
+	if t, ok := s.compiledComplex[name]; ok {
+		return t, nil
+	}
+	node, ok := s.complexTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", name)
+	}
+
+	// registered before compiling so that recursive types refer to themselves
+	t := &xsdType{}
+	s.compiledComplex[name] = t
+	return t, s.compileComplexType(node, t)
+}
+
+func (s *xmlSchema) simpleType(name string) (*xsdSimpleType, error) {
+	if st, ok := s.compiledSimple[name]; ok {
+		return st, nil
+	}
+	node, ok := s.simpleTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown simple type %q", name)
+	}
+
+	st, err := s.compileSimpleType(node)
+	if err != nil {
+		return nil, err
+	}
+	s.compiledSimple[name] = st
+	return st, nil
+}
+
+// typeRef resolves the type referenced by the value of the type attribute of node.
+func (s *xmlSchema) typeRef(node *xmlNode, value string) (*xsdType, error) {
+	name := qname(node, value)
+	if name.Space == xsdNamespace {
+		if name.Local == "anyType" {
+			return &xsdType{any: true}, nil
+		}
+		st, err := builtinSimpleType(name.Local)
+		if err != nil {
+			return nil, err
+		}
+		return &xsdType{simple: st}, nil
+	}
+	if _, ok := s.simpleTypes[name.Local]; ok {
+		st, err := s.simpleType(name.Local)
+		if err != nil {
+			return nil, err
+		}
+		return &xsdType{simple: st}, nil
+	}
+	return s.complexType(name.Local)
+}
+
+// simpleTypeRef resolves the simple type referenced by value in the scope of node.
+func (s *xmlSchema) simpleTypeRef(node *xmlNode, value string) (*xsdSimpleType, error) {
+	name := qname(node, value)
+	if name.Space == xsdNamespace {
+		return builtinSimpleType(name.Local)
+	}
+	return s.simpleType(name.Local)
+}
+
+func builtinSimpleType(name string) (*xsdSimpleType, error) {
+	if _, ok := xsdBuiltinTypes[name]; !ok {
+		return nil, fmt.Errorf("unsupported type xs:%s", name)
+	}
+	return &xsdSimpleType{builtin: name, length: -1, minLength: -1, maxLength: -1}, nil
+}
+
+// elementType compiles the type of the element declaration node.
+func (s *xmlSchema) elementType(node *xmlNode) (*xsdType, error) {
+	for _, attr := range []string{"substitutionGroup", "abstract", "fixed"} {
+		if _, ok := node.attr(attr); ok {
+			return nil, fmt.Errorf("%s is not supported", attr)
+		}
+	}
+	for _, c := range node.children {
+		switch c.name.Local {
+		case "complexType", "simpleType", "annotation":
+		case "key", "keyref", "unique":
+			return nil, fmt.Errorf("xs:%s is not supported", c.name.Local)
+		default:
+			return nil, fmt.Errorf("unexpected xs:%s", c.name.Local)
+		}
+	}
+
+	if typ, ok := node.attr("type"); ok {
+		return s.typeRef(node, typ)
+	}
+	if c := node.child("complexType"); c != nil {
+		t := &xsdType{}
+		return t, s.compileComplexType(c, t)
+	}
+	if c := node.child("simpleType"); c != nil {
+		st, err := s.compileSimpleType(c)
+		if err != nil {
+			return nil, err
+		}
+		return &xsdType{simple: st}, nil
+	}
+	return &xsdType{any: true}, nil
+}
+
+func (s *xmlSchema) compileComplexType(node *xmlNode, t *xsdType) error {
+	t.attributes = map[string]*xsdAttribute{}
+	if mixed, _ := node.attr("mixed"); mixed == "true" {
+		t.mixed = true
+	}
+
+	for _, c := range node.children {
+		switch c.name.Local {
+		case "simpleContent":
+			if err := s.compileSimpleContent(c, t); err != nil {
+				return err
+			}
+		case "complexContent":
+			if err := s.compileComplexContent(c, t); err != nil {
+				return err
+			}
+		default:
+			if err := s.compileContentChild(c, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compileContentChild compiles a model group or attribute declaration of a complex type.
+func (s *xmlSchema) compileContentChild(node *xmlNode, t *xsdType) error {
+	switch node.name.Local {
+	case "sequence", "choice", "all", "group":
+		p, err := s.compileParticle(node)
+		if err != nil {
+			return err
+		}
+		t.content = p
+	case "attribute":
+		return s.compileAttribute(node, t)
+	case "attributeGroup":
+		ref, _ := node.attr("ref")
+		group, ok := s.attributeGroups[qname(node, ref).Local]
+		if !ok {
+			return fmt.Errorf("unknown attribute group %q", ref)
+		}
+		for _, c := range group.children {
+			if err := s.compileContentChild(c, t); err != nil {
+				return err
+			}
+		}
+	case "anyAttribute":
+		t.anyAttribute = true
+	case "annotation":
+	default:
+		return fmt.Errorf("unexpected xs:%s", node.name.Local)
+	}
+	return nil
+}
+
+func (s *xmlSchema) compileAttribute(node *xmlNode, t *xsdType) error {
+	if _, ok := node.attr("fixed"); ok {
+		return errors.New("fixed is not supported")
+	}
+
+	name, _ := node.attr("name")
+	space := s.namespace(node, s.attributeQualified)
+	if ref, ok := node.attr("ref"); ok {
+		// global attributes are in the target namespace
+		name, space = qname(node, ref).Local, s.targetNamespace
+	}
+	if name == "" {
+		return errors.New("attribute without a name")
+	}
+
+	attr := &xsdAttribute{space: space}
+	if use, _ := node.attr("use"); use == "required" {
+		attr.required = true
+	} else if use == "prohibited" {
+		delete(t.attributes, name)
+		return nil
+	}
+
+	var err error
+	switch typ, ok := node.attr("type"); {
+	case ok:
+		attr.typ, err = s.simpleTypeRef(node, typ)
+	case node.child("simpleType") != nil:
+		attr.typ, err = s.compileSimpleType(node.child("simpleType"))
+	default:
+		attr.typ, err = builtinSimpleType("anySimpleType")
+	}
+	if err != nil {
+		return fmt.Errorf("attribute %q: %v", name, err)
+	}
+
+	t.attributes[name] = attr
+	return nil
+}
+
+// derivation returns the extension or restriction of a simple or complex content and its base.
+func (s *xmlSchema) derivation(node *xmlNode) (*xmlNode, *xsdType, error) {
+	for _, c := range node.children {
+		if c.name.Local != "extension" && c.name.Local != "restriction" {
+			continue
+		}
+		base, ok := c.attr("base")
+		if !ok {
+			return nil, nil, fmt.Errorf("xs:%s without a base", c.name.Local)
+		}
+		t, err := s.typeRef(c, base)
+		return c, t, err
+	}
+	return nil, nil, errors.New("extension or restriction expected")
+}
+
+func (s *xmlSchema) compileSimpleContent(node *xmlNode, t *xsdType) error {
+	derivation, base, err := s.derivation(node)
+	if err != nil {
+		return err
+	}
+	if base.simple == nil {
+		return errors.New("simple content must derive from a simple type")
+	}
+
+	t.simple = base.simple
+	for name, attr := range base.attributes {
+		t.attributes[name] = attr
+	}
+	if derivation.name.Local == "restriction" {
+		if t.simple, err = s.restrict(derivation, base.simple); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range derivation.children {
+		switch c.name.Local {
+		case "attribute", "attributeGroup", "anyAttribute", "annotation":
+			if err := s.compileContentChild(c, t); err != nil {
+				return err
+			}
+		default:
+			// the facets of restrictions are compiled by restrict
+			if derivation.name.Local == "extension" {
+				return fmt.Errorf("unexpected xs:%s", c.name.Local)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *xmlSchema) compileComplexContent(node *xmlNode, t *xsdType) error {
+	if mixed, _ := node.attr("mixed"); mixed == "true" {
+		t.mixed = true
+	}
+
+	derivation, base, err := s.derivation(node)
+	if err != nil {
+		return err
+	}
+	for name, attr := range base.attributes {
+		t.attributes[name] = attr
+	}
+	t.anyAttribute = base.anyAttribute
+
+	for _, c := range derivation.children {
+		if err := s.compileContentChild(c, t); err != nil {
+			return err
+		}
+	}
+
+	// an extension appends its content to the content of its base
+	if derivation.name.Local == "extension" && base.content != nil {
+		if t.content == nil {
+			t.content = base.content
+		} else {
+			t.content = &xsdParticle{kind: xsdSequence, particles: []*xsdParticle{base.content, t.content}, minOccurs: 1, maxOccurs: 1}
+		}
+	}
+	return nil
+}
+
+func (s *xmlSchema) compileParticle(node *xmlNode) (*xsdParticle, error) {
+	p := &xsdParticle{minOccurs: 1, maxOccurs: 1}
+	if v, ok := node.attr("minOccurs"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid minOccurs %q", v)
+		}
+		p.minOccurs = n
+	}
+	if v, ok := node.attr("maxOccurs"); ok {
+		if v == "unbounded" {
+			p.maxOccurs = xsdUnbounded
+		} else {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid maxOccurs %q", v)
+			}
+			p.maxOccurs = n
+		}
+	}
+
+	switch node.name.Local {
+	case "element":
+		p.kind = xsdElementParticle
+		if ref, ok := node.attr("ref"); ok {
+			el, ok := s.elements[qname(node, ref).Local]
+			if !ok {
+				return nil, fmt.Errorf("unknown element %q", ref)
+			}
+			p.element = el
+			return p, nil
+		}
+
+		name, _ := node.attr("name")
+		typ, err := s.elementType(node)
+		if err != nil {
+			return nil, fmt.Errorf("element %q: %v", name, err)
+		}
+		p.element = &xsdElement{space: s.namespace(node, s.elementQualified), name: name, typ: typ}
+	case "any":
+		p.kind = xsdAny
+	case "group":
+		ref, _ := node.attr("ref")
+		group, ok := s.groups[qname(node, ref).Local]
+		if !ok {
+			return nil, fmt.Errorf("unknown group %q", ref)
+		}
+		for _, c := range group.children {
+			if c.name.Local == "annotation" {
+				continue
+			}
+			inner, err := s.compileParticle(c)
+			if err != nil {
+				return nil, err
+			}
+			p.kind, p.particles = inner.kind, inner.particles
+		}
+	case "sequence", "choice", "all":
+		p.kind = map[string]xsdParticleKind{"sequence": xsdSequence, "choice": xsdChoice, "all": xsdAll}[node.name.Local]
+		for _, c := range node.children {
+			if c.name.Local == "annotation" {
+				continue
+			}
+			inner, err := s.compileParticle(c)
+			if err != nil {
+				return nil, err
+			}
+			p.particles = append(p.particles, inner)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected xs:%s", node.name.Local)
+	}
+
+	return p, nil
+}
+
+func (s *xmlSchema) compileSimpleType(node *xmlNode) (*xsdSimpleType, error) {
+	for _, c := range node.children {
+		switch c.name.Local {
+		case "restriction":
+			var base *xsdSimpleType
+			var err error
+			if typ, ok := c.attr("base"); ok {
+				base, err = s.simpleTypeRef(c, typ)
+			} else if inline := c.child("simpleType"); inline != nil {
+				base, err = s.compileSimpleType(inline)
+			} else {
+				err = errors.New("restriction without a base")
+			}
+			if err != nil {
+				return nil, err
+			}
+			return s.restrict(c, base)
+		case "list":
+			st, _ := builtinSimpleType("anySimpleType")
+			var err error
+			if typ, ok := c.attr("itemType"); ok {
+				st.itemType, err = s.simpleTypeRef(c, typ)
+			} else if inline := c.child("simpleType"); inline != nil {
+				st.itemType, err = s.compileSimpleType(inline)
+			} else {
+				err = errors.New("list without an item type")
+			}
+			return st, err
+		case "union":
+			st, _ := builtinSimpleType("anySimpleType")
+			members, _ := c.attr("memberTypes")
+			for _, typ := range strings.Fields(members) {
+				member, err := s.simpleTypeRef(c, typ)
+				if err != nil {
+					return nil, err
+				}
+				st.members = append(st.members, member)
+			}
+			for _, inline := range c.children {
+				if inline.name.Local != "simpleType" {
+					continue
+				}
+				member, err := s.compileSimpleType(inline)
+				if err != nil {
+					return nil, err
+				}
+				st.members = append(st.members, member)
+			}
+			return st, nil
+		}
+	}
+	return nil, errors.New("restriction, list or union expected")
+}
+
+// restrict returns base restricted by the facets of the restriction node.
+func (s *xmlSchema) restrict(node *xmlNode, base *xsdSimpleType) (*xsdSimpleType, error) {
+	st := *base
+	st.patterns = append([]*regexp.Regexp{}, base.patterns...)
+	st.bounds = append([]xsdBound{}, base.bounds...)
+
+	var enumeration []string
+	for _, c := range node.children {
+		value, _ := c.attr("value")
+		switch c.name.Local {
+		case "enumeration":
+			enumeration = append(enumeration, value)
+		case "pattern":
+			// XSD patterns match whole values
+			re, err := regexp.Compile("^(?:" + value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", value, err)
+			}
+			st.patterns = append(st.patterns, re)
+		case "length", "minLength", "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", c.name.Local, value)
+			}
+			switch c.name.Local {
+			case "length":
+				st.length = n
+			case "minLength":
+				st.minLength = n
+			case "maxLength":
+				st.maxLength = n
+			}
+		case "minInclusive", "maxInclusive", "minExclusive", "maxExclusive":
+			st.bounds = append(st.bounds, xsdBound{facet: c.name.Local, value: value})
+		case "whiteSpace", "annotation", "simpleType":
+		default:
+			return nil, fmt.Errorf("unsupported facet xs:%s", c.name.Local)
+		}
+	}
+	if enumeration != nil {
+		st.enumeration = enumeration
+	}
+
+	return &st, nil
+}
+
+// validate validates the XML document data, or the payload of data when it is a SOAP envelope.
+func (s *xmlSchema) validate(data []byte) error {
+	root, err := parseXML(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errXMLNotWellFormed, err)
+	}
+
+	node := root
+	if root.name.Local == "Envelope" && (root.name.Space == soap11EnvelopeNamespace || root.name.Space == soap12EnvelopeNamespace) {
+		body := root.child("Body")
+		if body == nil || len(body.children) == 0 {
+			return errors.New("SOAP body is empty")
+		}
+		node = body.children[0]
+	}
+
+	el, ok := s.elements[node.name.Local]
+	if !ok {
+		return fmt.Errorf("element <%s> is not declared in the schema", node.name.Local)
+	}
+	if node.name.Space != el.space {
+		return fmt.Errorf("element <%s> is not in namespace %q", node.name.Local, el.space)
+	}
+
+	var errs []string
+	validateXMLNode(el.typ, node, "/"+node.name.Local, &errs)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateXMLNode(t *xsdType, node *xmlNode, path string, errs *[]string) {
+	if t.any {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	seen := map[string]bool{}
+	for _, a := range node.attrs {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" || a.Name.Space == xsiNamespace || a.Name.Space == xmlNamespace {
+			continue
+		}
+		attr, ok := t.attributes[a.Name.Local]
+		if !ok || attr.space != a.Name.Space {
+			if !t.anyAttribute {
+				fail("attribute %q is not allowed", a.Name.Local)
+			}
+			continue
+		}
+		seen[a.Name.Local] = true
+		if err := attr.typ.validate(a.Value); err != nil {
+			fail("attribute %q: %v", a.Name.Local, err)
+		}
+	}
+	for name, attr := range t.attributes {
+		if attr.required && !seen[name] {
+			fail("attribute %q is required", name)
+		}
+	}
+
+	if t.simple != nil {
+		if len(node.children) > 0 {
+			fail("element <%s> is not allowed", node.children[0].name.Local)
+			return
+		}
+		if err := t.simple.validate(node.text); err != nil {
+			fail("%v", err)
+		}
+		return
+	}
+
+	if !t.mixed && strings.TrimSpace(node.text) != "" {
+		fail("text content is not allowed")
+	}
+
+	m := &xsdMatcher{children: node.children, assigned: make([]*xsdElement, len(node.children)), failPos: -1}
+	pos := 0
+	if t.content != nil {
+		var ok bool
+		if pos, ok = m.match(t.content, 0); !ok {
+			if m.failPos < len(node.children) {
+				fail("unexpected element <%s>, expecting %s", node.children[m.failPos].name.Local, m.expectation())
+			} else {
+				fail("missing element %s", m.expectation())
+			}
+			return
+		}
+	}
+	if pos < len(node.children) {
+		fail("unexpected element <%s>", node.children[pos].name.Local)
+		return
+	}
+
+	for i, child := range node.children {
+		if el := m.assigned[i]; el != nil {
+			validateXMLNode(el.typ, child, path+"/"+child.name.Local, errs)
+		}
+	}
+}
+
+// xsdMatcher matches the children of an element against a content model. Schemas must have
+// deterministic content models, so particles are matched greedily.
+type xsdMatcher struct {
+	children []*xmlNode
+	// assigned holds the declarations of the matched children, nil for wildcards.
+	assigned []*xsdElement
+	// failPos and expected describe the furthest position where no element could be matched.
+	failPos  int
+	expected []string
+}
+
+// fail records that the element name was expected at pos.
+func (m *xsdMatcher) fail(pos int, name string) {
+	if pos > m.failPos {
+		m.failPos, m.expected = pos, nil
+	}
+	if pos == m.failPos {
+		for _, e := range m.expected {
+			if e == name {
+				return
+			}
+		}
+		m.expected = append(m.expected, name)
+	}
+}
+
+// expectation describes the expected elements.
+func (m *xsdMatcher) expectation() string {
+	return "<" + strings.Join(m.expected, "> or <") + ">"
+}
+
+// match matches p from pos and returns the position of the first child after the match.
+func (m *xsdMatcher) match(p *xsdParticle, pos int) (int, bool) {
+	count := 0
+	for p.maxOccurs == xsdUnbounded || count < p.maxOccurs {
+		next, ok := m.matchOnce(p, pos)
+		if !ok {
+			break
+		}
+		if next == pos {
+			// empty match of a particle with optional content
+			count = p.minOccurs
+			break
+		}
+		pos = next
+		count++
+	}
+
+	if count < p.minOccurs {
+		if p.kind == xsdElementParticle {
+			m.fail(pos, p.element.name)
+		}
+		return pos, false
+	}
+	return pos, true
+}
+
+func (m *xsdMatcher) matchOnce(p *xsdParticle, pos int) (int, bool) {
+	switch p.kind {
+	case xsdElementParticle:
+		if pos < len(m.children) && m.children[pos].name == (xml.Name{Space: p.element.space, Local: p.element.name}) {
+			m.assigned[pos] = p.element
+			return pos + 1, true
+		}
+		return pos, false
+	case xsdAny:
+		if pos < len(m.children) {
+			m.assigned[pos] = nil
+			return pos + 1, true
+		}
+		return pos, false
+	case xsdSequence:
+		next := pos
+		for _, inner := range p.particles {
+			var ok bool
+			if next, ok = m.match(inner, next); !ok {
+				return pos, false
+			}
+		}
+		return next, true
+	case xsdChoice:
+		empty := false
+		for _, inner := range p.particles {
+			next, ok := m.match(inner, pos)
+			if ok && next > pos {
+				return next, true
+			}
+			empty = empty || ok
+		}
+		return pos, empty
+	case xsdAll:
+		matched := make([]bool, len(p.particles))
+		for progress := true; progress; {
+			progress = false
+			for i, inner := range p.particles {
+				if matched[i] {
+					continue
+				}
+				if next, ok := m.matchOnce(inner, pos); ok && next > pos {
+					matched[i], pos, progress = true, next, true
+				}
+			}
+		}
+		for i, inner := range p.particles {
+			if !matched[i] && inner.minOccurs > 0 {
+				if inner.kind == xsdElementParticle {
+					m.fail(pos, inner.element.name)
+				}
+				return pos, false
+			}
+		}
+		return pos, true
+	}
+	return pos, false
+}
+
+// validate validates the text value against the simple type.
+func (st *xsdSimpleType) validate(value string) error {
+	if st.builtin != "string" && st.builtin != "anySimpleType" {
+		// whitespace of all the other types is collapsed
+		value = strings.Join(strings.Fields(value), " ")
+	}
+
+	length := utf8.RuneCountInString(value)
+	switch {
+	case st.itemType != nil:
+		items := strings.Fields(value)
+		for _, item := range items {
+			if err := st.itemType.validate(item); err != nil {
+				return err
+			}
+		}
+		length = len(items)
+	case st.members != nil:
+		valid := false
+		for _, member := range st.members {
+			if member.validate(value) == nil {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("value %q doesn't match any member of the union", value)
+		}
+	default:
+		if !xsdBuiltinTypes[st.builtin](value) {
+			return fmt.Errorf("value %q is not a valid %s", value, st.builtin)
+		}
+	}
+
+	if st.enumeration != nil {
+		found := false
+		for _, v := range st.enumeration {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value %q is not one of %s", value, strings.Join(st.enumeration, ", "))
+		}
+	}
+	for _, re := range st.patterns {
+		if !re.MatchString(value) {
+			return fmt.Errorf("value %q doesn't match pattern %s", value, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+		}
+	}
+	if st.length >= 0 && length != st.length {
+		return fmt.Errorf("value %q must have a length of %d", value, st.length)
+	}
+	if st.minLength >= 0 && length < st.minLength {
+		return fmt.Errorf("value %q must have a length of at least %d", value, st.minLength)
+	}
+	if st.maxLength >= 0 && length > st.maxLength {
+		return fmt.Errorf("value %q must have a length of at most %d", value, st.maxLength)
+	}
+	for _, b := range st.bounds {
+		cmp := compareXSDValues(value, b.value)
+		if (b.facet == "minInclusive" && cmp < 0) || (b.facet == "maxInclusive" && cmp > 0) ||
+			(b.facet == "minExclusive" && cmp <= 0) || (b.facet == "maxExclusive" && cmp >= 0) {
+			return fmt.Errorf("value %q is out of bounds, %s is %s", value, b.facet, b.value)
+		}
+	}
+
+	return nil
+}
+
+// compareXSDValues compares a and b as numbers, or as strings when they aren't, which orders
+// dates and times of the same format.
+func compareXSDValues(a, b string) int {
+	x, okA := new(big.Float).SetString(a)
+	y, okB := new(big.Float).SetString(b)
+	if okA && okB {
+		return x.Cmp(y)
+	}
+	return strings.Compare(a, b)
+}
+
+var (
+	xsdDecimal    = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	xsdInteger    = regexp.MustCompile(`^[+-]?\d+$`)
+	xsdFloatValue = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	xsdDate       = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`)
+	xsdTime       = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	xsdDateTime   = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	xsdDuration   = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+	xsdGYear      = regexp.MustCompile(`^-?\d{4,}(Z|[+-]\d{2}:\d{2})?$`)
+	xsdName       = regexp.MustCompile(`^[\pL_:][\pL\pN._:-]*$`)
+	xsdNCName     = regexp.MustCompile(`^[\pL_][\pL\pN._-]*$`)
+	xsdNMToken    = regexp.MustCompile(`^[\pL\pN._:-]+$`)
+	xsdLanguage   = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+)
+
+func anyXSDValue(string) bool { return true }
+
+// xsdIntegerRange returns a validator of integers between min and max, unbounded when empty.
+func xsdIntegerRange(min, max string) func(string) bool {
+	var lower, upper *big.Int
+	if min != "" {
+		lower, _ = new(big.Int).SetString(min, 10)
+	}
+	if max != "" {
+		upper, _ = new(big.Int).SetString(max, 10)
+	}
+	return func(v string) bool {
+		if !xsdInteger.MatchString(v) {
+			return false
+		}
+		n, _ := new(big.Int).SetString(strings.TrimPrefix(v, "+"), 10)
+		return (lower == nil || n.Cmp(lower) >= 0) && (upper == nil || n.Cmp(upper) <= 0)
+	}
+}
+
+func xsdDateValue(layout string, re *regexp.Regexp) func(string) bool {
+	return func(v string) bool {
+		if !re.MatchString(v) {
+			return false
+		}
+		// checks the ranges of the date, such as the number of days of the month
+		_, err := time.Parse(layout, strings.TrimPrefix(v, "-")[:len(layout)])
+		return err == nil
+	}
+}
+
+// xsdBuiltinTypes are the validators of the supported built-in simple types.
+var xsdBuiltinTypes = map[string]func(string) bool{
+	"anySimpleType":    anyXSDValue,
+	"string":           anyXSDValue,
+	"normalizedString": anyXSDValue,
+	"token":            anyXSDValue,
+	"anyURI":           anyXSDValue,
+	"QName":            anyXSDValue,
+	"NOTATION":         anyXSDValue,
+	"language":         xsdLanguage.MatchString,
+	"Name":             xsdName.MatchString,
+	"NCName":           xsdNCName.MatchString,
+	"ID":               xsdNCName.MatchString,
+	"IDREF":            xsdNCName.MatchString,
+	"ENTITY":           xsdNCName.MatchString,
+	"NMTOKEN":          xsdNMToken.MatchString,
+	"boolean": func(v string) bool {
+		return v == "true" || v == "false" || v == "1" || v == "0"
+	},
+	"decimal": xsdDecimal.MatchString,
+	"float":   xsdFloat,
+	"double":  xsdFloat,
+
+	"integer":            xsdIntegerRange("", ""),
+	"nonNegativeInteger": xsdIntegerRange("0", ""),
+	"positiveInteger":    xsdIntegerRange("1", ""),
+	"nonPositiveInteger": xsdIntegerRange("", "0"),
+	"negativeInteger":    xsdIntegerRange("", "-1"),
+	"long":               xsdIntegerRange("-9223372036854775808", "9223372036854775807"),
+	"int":                xsdIntegerRange("-2147483648", "2147483647"),
+	"short":              xsdIntegerRange("-32768", "32767"),
+	"byte":               xsdIntegerRange("-128", "127"),
+	"unsignedLong":       xsdIntegerRange("0", "18446744073709551615"),
+	"unsignedInt":        xsdIntegerRange("0", "4294967295"),
+	"unsignedShort":      xsdIntegerRange("0", "65535"),
+	"unsignedByte":       xsdIntegerRange("0", "255"),
+
+	"date":     xsdDateValue("2006-01-02", xsdDate),
+	"dateTime": xsdDateValue("2006-01-02T15:04:05", xsdDateTime),
+	"time":     xsdDateValue("15:04:05", xsdTime),
+	"gYear":    xsdGYear.MatchString,
+	"duration": func(v string) bool {
+		return xsdDuration.MatchString(v) && !strings.HasSuffix(v, "P") && !strings.HasSuffix(v, "T")
+	},
+
+	"base64Binary": func(v string) bool {
+		_, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(v), ""))
+		return err == nil
+	},
+	"hexBinary": func(v string) bool {
+		_, err := hex.DecodeString(v)
+		return err == nil
+	},
+}
+
+func xsdFloat(v string) bool {
+	switch v {
+	case "INF", "+INF", "-INF", "NaN":
+		return true
+	}
+	return xsdFloatValue.MatchString(v)
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testXMLSchema = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:orders" targetNamespace="urn:orders" elementFormDefault="qualified">
+	<xs:element name="order" type="tns:Order"/>
+
+	<xs:complexType name="Order">
+		<xs:sequence>
+			<xs:element name="id" type="tns:OrderID"/>
+			<xs:element name="placed" type="xs:date"/>
+			<xs:element name="customer">
+				<xs:complexType>
+					<xs:choice>
+						<xs:element name="email" type="xs:string"/>
+						<xs:element name="phone" type="xs:string"/>
+					</xs:choice>
+				</xs:complexType>
+			</xs:element>
+			<xs:element name="item" type="tns:Item" maxOccurs="unbounded"/>
+			<xs:element name="note" type="xs:string" minOccurs="0"/>
+		</xs:sequence>
+		<xs:attribute name="currency" type="tns:Currency" use="required"/>
+	</xs:complexType>
+
+	<xs:complexType name="Item">
+		<xs:simpleContent>
+			<xs:extension base="xs:string">
+				<xs:attribute name="quantity" type="xs:positiveInteger" use="required"/>
+			</xs:extension>
+		</xs:simpleContent>
+	</xs:complexType>
+
+	<xs:simpleType name="OrderID">
+		<xs:restriction base="xs:string">
+			<xs:pattern value="ORD-\d{4}"/>
+		</xs:restriction>
+	</xs:simpleType>
+
+	<xs:simpleType name="Currency">
+		<xs:restriction base="xs:token">
+			<xs:enumeration value="EUR"/>
+			<xs:enumeration value="GBP"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`
+
+func TestXMLSchema(t *testing.T) {
+	schema, err := compileXMLSchema([]byte(testXMLSchema))
+	require.NoError(t, err)
+
+	order := func(currency, id, placed, customer, items string) string {
+		return `<order xmlns="urn:orders" currency="` + currency + `"><id>` + id + `</id><placed>` + placed + `</placed>` +
+			`<customer>` + customer + `</customer>` + items + `</order>`
+	}
+	validItems := `<item quantity="2">apple</item><item quantity="1">pear</item>`
+
+	for _, tc := range []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{"valid", order("EUR", "ORD-0001", "2021-06-30", "<email>a@example.com</email>", validItems), ""},
+		{"valid SOAP payload", `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+			order("GBP", "ORD-0002", "2021-06-30", "<phone>1234</phone>", validItems) + `</soap:Body></soap:Envelope>`, ""},
+		{"not well formed", `<order>`, "XML parsing error"},
+		{"undeclared root", `<invoice xmlns="urn:orders"/>`, "element <invoice> is not declared in the schema"},
+		{"wrong namespace", `<order/>`, `element <order> is not in namespace "urn:orders"`},
+		{"unqualified child", `<order xmlns="urn:orders" currency="EUR"><id xmlns="">ORD-0001</id></order>`,
+			`/order: unexpected element <id>, expecting <id>`},
+		{"qualified attribute", order("EUR", "ORD-0001", "2021-06-30", "<email>a@example.com</email>",
+			`<item xmlns:o="urn:orders" o:quantity="2">apple</item>`), `/order/item: attribute "quantity" is not allowed`},
+		{"attribute enumeration", order("", "ORD-0001", "2021-06-30", "<email>a</email>", validItems),
+			`/order: attribute "currency": value "" is not one of EUR, GBP`},
+		{"pattern", order("EUR", "1", "2021-06-30", "<email>a</email>", validItems),
+			`/order/id: value "1" doesn't match pattern ORD-\d{4}`},
+		{"date", order("EUR", "ORD-0001", "2021-02-30", "<email>a</email>", validItems),
+			`/order/placed: value "2021-02-30" is not a valid date`},
+		{"choice", order("EUR", "ORD-0001", "2021-06-30", "", validItems),
+			`/order/customer: missing element <email> or <phone>`},
+		{"missing element", order("EUR", "ORD-0001", "2021-06-30", "<email>a</email>", ""),
+			`/order: missing element <item>`},
+		{"unexpected element", order("EUR", "ORD-0001", "2021-06-30", "<email>a</email>", validItems+"<gift/>"),
+			`/order: unexpected element <gift>`},
+		{"simple content attribute", order("EUR", "ORD-0001", "2021-06-30", "<email>a</email>", `<item quantity="0">apple</item>`),
+			`/order/item: attribute "quantity": value "0" is not a valid positiveInteger`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.validate([]byte(tc.doc))
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	t.Run("invalid schemas", func(t *testing.T) {
+		for _, doc := range []string{
+			`<schema/>`,
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="xs:unknown"/></xs:schema>`,
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="Missing"/></xs:schema>`,
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:import namespace="urn:other"/></xs:schema>`,
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" substitutionGroup="b"/></xs:schema>`,
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:key name="k"/></xs:element></xs:schema>`,
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:simpleType name="a"><xs:restriction base="xs:decimal"><xs:totalDigits value="2"/></xs:restriction></xs:simpleType></xs:schema>`,
+		} {
+			_, err := compileXMLSchema([]byte(doc))
+			assert.Error(t, err, doc)
+		}
+	})
+
+	t.Run("unqualified local elements", func(t *testing.T) {
+		schema, err := compileXMLSchema([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:orders">
+			<xs:element name="order"><xs:complexType><xs:sequence><xs:element name="id" type="xs:string"/></xs:sequence></xs:complexType></xs:element>
+		</xs:schema>`))
+		require.NoError(t, err)
+
+		assert.NoError(t, schema.validate([]byte(`<o:order xmlns:o="urn:orders"><id>1</id></o:order>`)))
+		assert.Error(t, schema.validate([]byte(`<order xmlns="urn:orders"><id>1</id></order>`)))
+	})
+}
+
+func TestXSDSimpleTypes(t *testing.T) {
+	for _, tc := range []struct {
+		builtin string
+		valid   []string
+		invalid []string
+	}{
+		{"boolean", []string{"true", "0", " false "}, []string{"yes", ""}},
+		{"int", []string{"-2147483648", "+42"}, []string{"2147483648", "4.2"}},
+		{"unsignedByte", []string{"255"}, []string{"-1", "256"}},
+		{"decimal", []string{"1.50", "-.5", "3"}, []string{"1e3", "abc"}},
+		{"double", []string{"1e3", "-INF", "NaN"}, []string{"0x10", "Infinity"}},
+		{"dateTime", []string{"2021-06-30T10:00:00Z", "2021-06-30T10:00:00.5+02:00"}, []string{"2021-06-30", "2021-06-30T25:00:00"}},
+		{"duration", []string{"P1Y2M", "PT1.5S"}, []string{"P", "P1YT", "1Y"}},
+		{"base64Binary", []string{"dHlr"}, []string{"dHl"}},
+		{"NCName", []string{"order_1"}, []string{"1order", "a:b"}},
+	} {
+		st, err := builtinSimpleType(tc.builtin)
+		require.NoError(t, err)
+		for _, v := range tc.valid {
+			assert.NoError(t, st.validate(v), "%s %q", tc.builtin, v)
+		}
+		for _, v := range tc.invalid {
+			assert.Error(t, st.validate(v), "%s %q", tc.builtin, v)
+		}
+	}
+}
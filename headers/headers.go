@@ -14,6 +14,12 @@ const (
 	Expires                 = "Expires"
 	Connection              = "Connection"
 	WWWAuthenticate         = "WWW-Authenticate"
+	Vary                    = "Vary"
+	Link                    = "Link"
+	Warning                 = "Warning"
+	Deprecation             = "Deprecation"
+	Sunset                  = "Sunset"
+	Location                = "Location"
 )
 
 const (
@@ -39,6 +45,7 @@ const (
 	XTykHostname        = "x-tyk-hostname"
 	XGenerator          = "X-Generator"
 	XTykAuthorization   = "X-Tyk-Authorization"
+	XTykFederation      = "X-Tyk-Federation"
 )
 
 // upgrade and websocket
@@ -55,3 +62,13 @@ const (
 	XRateLimitRemaining = "X-RateLimit-Remaining"
 	XRateLimitReset     = "X-RateLimit-Reset"
 )
+
+// request correlation header
+const XRequestID = "X-Request-ID"
+
+// webhook subscription delivery headers
+const (
+	XTykEvent     = "X-Tyk-Event"
+	XTykDelivery  = "X-Tyk-Delivery"
+	XTykSignature = "X-Tyk-Signature"
+)
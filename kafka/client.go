@@ -0,0 +1,164 @@
+// Package kafka publishes messages to and consumes messages from Kafka clusters, on top of
+// github.com/segmentio/kafka-go.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const (
+	defaultClientID = "tyk"
+	defaultTimeout  = 10 * time.Second
+
+	// produceMaxAttempts bounds the attempts to publish messages, so that unavailable brokers
+	// fail requests quickly.
+	produceMaxAttempts = 3
+	// produceBatchTimeout is the time messages published concurrently wait to be batched.
+	produceBatchTimeout = 5 * time.Millisecond
+)
+
+// SASL mechanisms supported by Config.
+const (
+	SASLPlain       = "plain"
+	SASLSCRAMSHA256 = "scram-sha-256"
+	SASLSCRAMSHA512 = "scram-sha-512"
+)
+
+var errNoBrokers = errors.New("kafka: no brokers available")
+
+// Config configures a Client.
+type Config struct {
+	// Brokers are the addresses of the brokers used to discover the cluster.
+	Brokers  []string
+	ClientID string
+	// Timeout limits dialing and every request to a broker.
+	Timeout time.Duration
+	// TLS connects to the brokers over TLS when not nil.
+	TLS *tls.Config
+	// SASLMechanism authenticates to the brokers with SASLUsername and SASLPassword, one of
+	// SASLPlain, SASLSCRAMSHA256 and SASLSCRAMSHA512. No authentication when empty.
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+}
+
+// Client publishes messages to and consumes messages from a Kafka cluster.
+type Client interface {
+	// Produce publishes msgs to topic, with acks from the partition leaders. Keyed messages
+	// are partitioned as the Java client does.
+	Produce(topic string, msgs ...Message) error
+	// Subscribe calls handler with every message published to topic from now on, until the
+	// subscription is closed.
+	Subscribe(topic string, handler func(Message), logger Logger) (Subscription, error)
+	// Close closes the connections to the brokers.
+	Close() error
+}
+
+// Subscription delivers the messages published to a topic after it was created.
+type Subscription interface {
+	Close() error
+}
+
+// Logger is used to report errors of subscriptions.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
+
+type client struct {
+	conf      Config
+	addr      net.Addr
+	dialer    *kafkago.Dialer
+	transport *kafkago.Transport
+	writer    *kafkago.Writer
+}
+
+// NewClient creates a client for the cluster of conf. Brokers are connected to lazily.
+func NewClient(conf Config) (Client, error) {
+	if len(conf.Brokers) == 0 {
+		return nil, errNoBrokers
+	}
+	if conf.ClientID == "" {
+		conf.ClientID = defaultClientID
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultTimeout
+	}
+
+	mechanism, err := saslMechanism(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &kafkago.Transport{
+		DialTimeout: conf.Timeout,
+		ClientID:    conf.ClientID,
+		TLS:         conf.TLS,
+		SASL:        mechanism,
+	}
+	addr := kafkago.TCP(conf.Brokers...)
+
+	return &client{
+		conf: conf,
+		addr: addr,
+		dialer: &kafkago.Dialer{
+			ClientID:      conf.ClientID,
+			Timeout:       conf.Timeout,
+			DualStack:     true,
+			TLS:           conf.TLS,
+			SASLMechanism: mechanism,
+		},
+		transport: transport,
+		writer: &kafkago.Writer{
+			Addr:         addr,
+			Balancer:     &kafkago.Murmur2Balancer{},
+			MaxAttempts:  produceMaxAttempts,
+			BatchTimeout: produceBatchTimeout,
+			ReadTimeout:  conf.Timeout,
+			WriteTimeout: conf.Timeout,
+			RequiredAcks: kafkago.RequireOne,
+			Transport:    transport,
+		},
+	}, nil
+}
+
+func saslMechanism(conf Config) (sasl.Mechanism, error) {
+	switch conf.SASLMechanism {
+	case "":
+		return nil, nil
+	case SASLPlain:
+		return plain.Mechanism{Username: conf.SASLUsername, Password: conf.SASLPassword}, nil
+	case SASLSCRAMSHA256:
+		return scram.Mechanism(scram.SHA256, conf.SASLUsername, conf.SASLPassword)
+	case SASLSCRAMSHA512:
+		return scram.Mechanism(scram.SHA512, conf.SASLUsername, conf.SASLPassword)
+	default:
+		return nil, fmt.Errorf("kafka: unsupported SASL mechanism %q", conf.SASLMechanism)
+	}
+}
+
+func (c *client) Produce(topic string, msgs ...Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.conf.Timeout)
+	defer cancel()
+
+	records := make([]kafkago.Message, len(msgs))
+	for i, m := range msgs {
+		records[i] = toKafkaGo(topic, m)
+	}
+	return c.writer.WriteMessages(ctx, records...)
+}
+
+func (c *client) Close() error {
+	err := c.writer.Close()
+	c.transport.CloseIdleConnections()
+	return err
+}
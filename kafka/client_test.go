@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Errorf(format string, args ...interface{}) { l.t.Logf(format, args...) }
+
+func TestMessageConversion(t *testing.T) {
+	ts := time.Unix(1600000000, 0)
+	msg := Message{Key: []byte("a"), Value: []byte("first"), Headers: []Header{{Key: "h", Value: []byte("v")}}, Timestamp: ts}
+
+	record := toKafkaGo("topic", msg)
+	if record.Topic != "topic" || len(record.Headers) != 1 {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	record.Partition, record.Offset = 2, 5
+
+	decoded := fromKafkaGo(record)
+	if decoded.Topic != "topic" || decoded.Partition != 2 || decoded.Offset != 5 {
+		t.Errorf("unexpected position %s/%d/%d", decoded.Topic, decoded.Partition, decoded.Offset)
+	}
+	if !bytes.Equal(decoded.Key, msg.Key) || !bytes.Equal(decoded.Value, msg.Value) || !decoded.Timestamp.Equal(ts) {
+		t.Errorf("want %+v, got %+v", msg, decoded)
+	}
+	if string(decoded.Header("h")) != "v" || decoded.Header("missing") != nil {
+		t.Error("headers not converted")
+	}
+}
+
+func TestSASLMechanism(t *testing.T) {
+	for _, mechanism := range []string{"", SASLPlain, SASLSCRAMSHA256, SASLSCRAMSHA512} {
+		if _, err := NewClient(Config{Brokers: []string{"localhost:9092"}, SASLMechanism: mechanism, SASLUsername: "user", SASLPassword: "secret"}); err != nil {
+			t.Errorf("%q: unexpected error %v", mechanism, err)
+		}
+	}
+	if _, err := NewClient(Config{Brokers: []string{"localhost:9092"}, SASLMechanism: "gssapi"}); err == nil {
+		t.Error("want unsupported mechanism rejected")
+	}
+}
+func TestProduceSubscribe(t *testing.T) {
+	broker := NewMockBroker(3)
+	defer broker.Close()
+
+	client := broker.Client()
+	defer client.Close()
+
+	// messages published before subscribing are not delivered
+	if err := client.Produce("replies", Message{Value: []byte("old")}); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan Message, 10)
+	sub, err := client.Subscribe("replies", func(m Message) { received <- m }, testLogger{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Produce("replies", Message{Key: []byte{byte(i)}, Value: []byte("new")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case m := <-received:
+			if string(m.Value) != "new" {
+				t.Errorf("unexpected message %q", m.Value)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("message not delivered")
+		}
+	}
+
+	if n := len(broker.Messages("replies")); n != 4 {
+		t.Errorf("want 4 messages stored, got %d", n)
+	}
+
+	// keyed messages always go to the same partition
+	for i := 0; i < 2; i++ {
+		if err := client.Produce("keyed", Message{Key: []byte("key"), Value: []byte("v")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msgs := broker.Messages("keyed")
+	if len(msgs) != 2 || msgs[0].Partition != msgs[1].Partition {
+		t.Errorf("keyed messages should share a partition: %+v", msgs)
+	}
+}
+
+func TestNewClientNoBrokers(t *testing.T) {
+	if _, err := NewClient(Config{}); err != errNoBrokers {
+		t.Errorf("want %v, got %v", errNoBrokers, err)
+	}
+}
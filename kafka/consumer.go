@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	subscriptionMaxWait    = 250 * time.Millisecond
+	subscriptionRetryDelay = time.Second
+	// subscriptionRefreshInterval is the interval partitions added to the topic are looked
+	// for at.
+	subscriptionRefreshInterval = 30 * time.Second
+
+	// fetchMaxBytes is the maximum size of a fetch response per partition.
+	fetchMaxBytes = 1 << 20
+)
+
+// subscription reads every partition of a topic with its own reader, without consumer group,
+// so that every gateway sees every message.
+type subscription struct {
+	client  *client
+	topic   string
+	handler func(Message)
+	logger  Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// readers is only used by the goroutine refreshing the partitions once subscribed.
+	readers map[int]*kafkago.Reader
+}
+
+// Subscribe reads the partitions of topic from their latest offsets. Partitions added later
+// are read from their first offset.
+func (c *client) Subscribe(topic string, handler func(Message), logger Logger) (Subscription, error) {
+	offsets, err := c.latestOffsets(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &subscription{
+		client:  c,
+		topic:   topic,
+		handler: handler,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+		readers: make(map[int]*kafkago.Reader),
+	}
+	for partition, offset := range offsets {
+		s.read(partition, offset)
+	}
+
+	s.wg.Add(1)
+	go s.refresh()
+	return s, nil
+}
+
+func (c *client) partitions(ctx context.Context, topic string) ([]int, error) {
+	kc := &kafkago.Client{Addr: c.addr, Timeout: c.conf.Timeout, Transport: c.transport}
+	res, err := kc.Metadata(ctx, &kafkago.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range res.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		ids := make([]int, len(t.Partitions))
+		for i, p := range t.Partitions {
+			ids[i] = p.ID
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("kafka: topic %s not found", topic)
+}
+
+func (c *client) latestOffsets(topic string) (map[int]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.conf.Timeout)
+	defer cancel()
+
+	partitions, err := c.partitions(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &kafkago.ListOffsetsRequest{Topics: map[string][]kafkago.OffsetRequest{topic: nil}}
+	for _, p := range partitions {
+		req.Topics[topic] = append(req.Topics[topic], kafkago.LastOffsetOf(p))
+	}
+	kc := &kafkago.Client{Addr: c.addr, Timeout: c.conf.Timeout, Transport: c.transport}
+	res, err := kc.ListOffsets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		offsets[p.Partition] = p.LastOffset
+	}
+	return offsets, nil
+}
+
+func (s *subscription) read(partition int, offset int64) {
+	r := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:        s.client.conf.Brokers,
+		Topic:          s.topic,
+		Partition:      partition,
+		Dialer:         s.client.dialer,
+		MinBytes:       1,
+		MaxBytes:       fetchMaxBytes,
+		MaxWait:        subscriptionMaxWait,
+		ReadBackoffMin: subscriptionMaxWait,
+		ReadBackoffMax: subscriptionRetryDelay,
+		ErrorLogger:    kafkago.LoggerFunc(s.logger.Errorf),
+	})
+	r.SetOffset(offset)
+	s.readers[partition] = r
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer r.Close()
+
+		for {
+			m, err := r.ReadMessage(s.ctx)
+			if err != nil {
+				if s.ctx.Err() != nil {
+					return
+				}
+				s.logger.Errorf("kafka: fetching from topic %s partition %d failed: %v", s.topic, partition, err)
+
+				select {
+				case <-s.ctx.Done():
+					return
+				case <-time.After(subscriptionRetryDelay):
+				}
+				continue
+			}
+			s.handler(fromKafkaGo(m))
+		}
+	}()
+}
+
+// refresh reads the partitions added to the topic since it was subscribed to.
+func (s *subscription) refresh() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(subscriptionRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, s.client.conf.Timeout)
+		partitions, err := s.client.partitions(ctx, s.topic)
+		cancel()
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.logger.Errorf("kafka: refreshing the partitions of topic %s failed: %v", s.topic, err)
+			}
+			continue
+		}
+		for _, p := range partitions {
+			if _, ok := s.readers[p]; !ok {
+				s.read(p, kafkago.FirstOffset)
+			}
+		}
+	}
+}
+
+// Close stops reading the topic, waiting for the handler to return.
+func (s *subscription) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
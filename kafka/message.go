@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Header is a record header.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a record of a topic partition.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+}
+
+// Header returns the value of the first header named key, nil if there is none.
+func (m *Message) Header(key string) []byte {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return h.Value
+		}
+	}
+	return nil
+}
+
+func toKafkaGo(topic string, m Message) kafkago.Message {
+	msg := kafkago.Message{
+		Topic: topic,
+		Key:   m.Key,
+		Value: m.Value,
+		Time:  m.Timestamp,
+	}
+	for _, h := range m.Headers {
+		msg.Headers = append(msg.Headers, kafkago.Header{Key: h.Key, Value: h.Value})
+	}
+	return msg
+}
+
+func fromKafkaGo(m kafkago.Message) Message {
+	msg := Message{
+		Topic:     m.Topic,
+		Partition: int32(m.Partition),
+		Offset:    m.Offset,
+		Key:       m.Key,
+		Value:     m.Value,
+		Timestamp: m.Time,
+	}
+	for _, h := range m.Headers {
+		msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+	return msg
+}
@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+var errMockBrokerClosed = errors.New("kafka: mock broker closed")
+
+// MockBroker is an in-memory single node cluster for tests. Topics are created with the
+// configured number of partitions when first requested.
+type MockBroker struct {
+	partitions int
+	roundRobin uint32
+
+	mu      sync.Mutex
+	topics  map[string][][]Message
+	updated chan struct{}
+	closed  bool
+}
+
+// NewMockBroker creates a broker.
+func NewMockBroker(partitions int) *MockBroker {
+	return &MockBroker{
+		partitions: partitions,
+		topics:     make(map[string][][]Message),
+		updated:    make(chan struct{}),
+	}
+}
+
+// Close stops the broker, ending its subscriptions.
+func (b *MockBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.updated)
+	}
+}
+
+// Messages returns the messages published to topic, ordered by partition and offset.
+func (b *MockBroker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var msgs []Message
+	for _, partition := range b.topics[topic] {
+		msgs = append(msgs, partition...)
+	}
+	return msgs
+}
+
+// Publish appends msg to partition of topic as if it was produced by another client.
+func (b *MockBroker) Publish(topic string, partition int32, msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.append(topic, partition, msg)
+}
+
+// Client returns a client connected to the broker.
+func (b *MockBroker) Client() Client {
+	return &mockClient{broker: b}
+}
+
+func (b *MockBroker) topic(name string) [][]Message {
+	t, ok := b.topics[name]
+	if !ok {
+		t = make([][]Message, b.partitions)
+		b.topics[name] = t
+	}
+	return t
+}
+
+// append must be called with the lock held.
+func (b *MockBroker) append(topic string, partition int32, m Message) {
+	t := b.topic(topic)
+	m.Topic = topic
+	m.Partition = partition
+	m.Offset = int64(len(t[partition]))
+	t[partition] = append(t[partition], m)
+
+	close(b.updated)
+	b.updated = make(chan struct{})
+}
+
+// partition returns the partition of m as the Java client does.
+func (b *MockBroker) partition(m Message) int32 {
+	partitions := make([]int, b.partitions)
+	for i := range partitions {
+		partitions[i] = i
+	}
+	if m.Key == nil {
+		return int32(atomic.AddUint32(&b.roundRobin, 1) % uint32(b.partitions))
+	}
+	return int32(kafkago.Murmur2Balancer{}.Balance(kafkago.Message{Key: m.Key}, partitions...))
+}
+
+type mockClient struct {
+	broker *MockBroker
+}
+
+func (c *mockClient) Produce(topic string, msgs ...Message) error {
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errMockBrokerClosed
+	}
+	for _, m := range msgs {
+		b.append(topic, b.partition(m), m)
+	}
+	return nil
+}
+
+func (c *mockClient) Subscribe(topic string, handler func(Message), logger Logger) (Subscription, error) {
+	b := c.broker
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, errMockBrokerClosed
+	}
+	t := b.topic(topic)
+	offsets := make([]int, len(t))
+	for i, partition := range t {
+		offsets[i] = len(partition)
+	}
+	b.mu.Unlock()
+
+	s := &mockSubscription{done: make(chan struct{}), stopped: make(chan struct{})}
+	go func() {
+		defer close(s.stopped)
+		for {
+			b.mu.Lock()
+			var msgs []Message
+			for i, partition := range b.topics[topic] {
+				msgs = append(msgs, partition[offsets[i]:]...)
+				offsets[i] = len(partition)
+			}
+			updated, closed := b.updated, b.closed
+			b.mu.Unlock()
+
+			for _, m := range msgs {
+				handler(m)
+			}
+			if closed {
+				return
+			}
+
+			select {
+			case <-s.done:
+				return
+			case <-updated:
+			}
+		}
+	}()
+	return s, nil
+}
+
+func (c *mockClient) Close() error {
+	return nil
+}
+
+type mockSubscription struct {
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func (s *mockSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	<-s.stopped
+	return nil
+}
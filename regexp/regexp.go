@@ -45,6 +45,7 @@ func ResetCache(ttl time.Duration, isEnabled bool) {
 	findStringSubmatchCache.reset(ttl, isEnabled)
 	findAllStringCache.reset(ttl, isEnabled)
 	findAllStringSubmatchCache.reset(ttl, isEnabled)
+	sharedCache.reset(isEnabled)
 }
 
 // Compile does the same as regexp.Compile but returns cached *Regexp instead.
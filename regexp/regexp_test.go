@@ -611,3 +611,72 @@ func BenchmarkRegexpFindStringSubmatch(b *testing.B) {
 
 	b.Log(res)
 }
+
+func TestCompileShared(t *testing.T) {
+	ResetCache(defaultCacheItemTTL, true)
+	// 1st miss
+	rx, err := CompileShared("^abc.*$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rx.FromCache {
+		t.Error("Regexp should not be from cache")
+	}
+	if !rx.MatchString("abcxyz") {
+		t.Error("String didn't match to compiled regexp: ", rx.String())
+	}
+	// 2nd hit, sharing the compiled regexp
+	rx2, err := CompileShared("^abc.*$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rx2.FromCache {
+		t.Error("Regexp should be from cache")
+	}
+	if rx2.Regexp != rx.Regexp {
+		t.Error("Regexp should be shared")
+	}
+
+	if _, err := CompileShared("("); err == nil {
+		t.Error("Invalid regexp should not compile")
+	}
+
+	stats := SharedStats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Size != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if rate := stats.HitRate(); rate < 0.33 || rate > 0.34 {
+		t.Error("Unexpected hit rate: ", rate)
+	}
+
+	SweepShared(func(expr string) bool { return expr != "^abc.*$" })
+	if size := SharedStats().Size; size != 0 {
+		t.Error("Unused regexp should be swept, cache size: ", size)
+	}
+
+	ResetCache(defaultCacheItemTTL, false)
+	rx3, _ := CompileShared("^abc.*$")
+	rx4, _ := CompileShared("^abc.*$")
+	if rx3.Regexp == rx4.Regexp {
+		t.Error("Regexp should not be shared when the cache is disabled")
+	}
+	ResetCache(defaultCacheItemTTL, true)
+}
+
+func BenchmarkRegExpCompileShared(b *testing.B) {
+	ResetCache(defaultCacheItemTTL, true)
+
+	b.ReportAllocs()
+
+	var rx *Regexp
+	var err error
+
+	for i := 0; i < b.N; i++ {
+		rx, err = CompileShared("^abc.*$")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Log(rx)
+}
@@ -0,0 +1,116 @@
+package regexp
+
+import (
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// sharedCache holds the regexps compiled with CompileShared.
+var sharedCache = newSharedRegexpCache()
+
+// SharedCacheStats are the statistics of the regexps compiled with CompileShared.
+type SharedCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Size is the number of cached regexps.
+	Size int
+}
+
+// HitRate returns the ratio of the compilations served from the cache.
+func (s SharedCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type sharedRegexpCache struct {
+	mu           sync.RWMutex
+	regexps      map[string]*regexp.Regexp
+	isEnabled    bool
+	hits, misses uint64
+}
+
+func newSharedRegexpCache() *sharedRegexpCache {
+	return &sharedRegexpCache{regexps: make(map[string]*regexp.Regexp), isEnabled: true}
+}
+
+func (c *sharedRegexpCache) do(expr string) (*Regexp, error) {
+	c.mu.RLock()
+	rx, found := c.regexps[expr]
+	enabled := c.isEnabled
+	c.mu.RUnlock()
+
+	if !enabled {
+		rx, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Regexp{rx, false}, nil
+	}
+	if found {
+		atomic.AddUint64(&c.hits, 1)
+		return &Regexp{rx, true}, nil
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+	rx, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	// keep the regexp compiled first if another caller raced us
+	if cached, ok := c.regexps[expr]; ok {
+		rx = cached
+	} else {
+		c.regexps[expr] = rx
+	}
+	c.mu.Unlock()
+	return &Regexp{rx, false}, nil
+}
+
+func (c *sharedRegexpCache) sweep(used func(expr string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for expr := range c.regexps {
+		if !used(expr) {
+			delete(c.regexps, expr)
+		}
+	}
+}
+
+func (c *sharedRegexpCache) stats() SharedCacheStats {
+	c.mu.RLock()
+	size := len(c.regexps)
+	c.mu.RUnlock()
+	return SharedCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+		Size:   size,
+	}
+}
+
+func (c *sharedRegexpCache) reset(isEnabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isEnabled = isEnabled
+	c.regexps = make(map[string]*regexp.Regexp)
+}
+
+// CompileShared does the same as Compile, except the callers compiling the same expression share
+// its compiled regexp instead of a copy, as regexps are safe for concurrent use. The regexps are
+// kept until SweepShared removes them, rather than for the cache TTL.
+func CompileShared(expr string) (*Regexp, error) {
+	return sharedCache.do(expr)
+}
+
+// SweepShared removes the regexps compiled with CompileShared whose expression isn't used anymore.
+func SweepShared(used func(expr string) bool) {
+	sharedCache.sweep(used)
+}
+
+// SharedStats returns the statistics of the regexps compiled with CompileShared.
+func SharedStats() SharedCacheStats {
+	return sharedCache.stats()
+}
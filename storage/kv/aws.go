@@ -0,0 +1,124 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage/internal/sigv4"
+)
+
+const awsSecretsManagerService = "secretsmanager"
+
+// AWSSecretsManager is an implementation of a KV store which uses AWS Secrets Manager as its backend
+type AWSSecretsManager struct {
+	client      *http.Client
+	endpoint    string
+	region      string
+	credentials sigv4.Credentials
+}
+
+// NewAWSSecretsManager returns a configured AWS Secrets Manager KV store adapter
+func NewAWSSecretsManager(conf config.AWSSecretsManagerConfig) (Store, error) {
+	region := conf.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	creds := sigv4.CredentialsOrEnv(sigv4.Credentials{
+		AccessKeyID:     conf.AccessKeyID,
+		SecretAccessKey: conf.SecretAccessKey,
+		SessionToken:    conf.SessionToken,
+	})
+
+	if region == "" {
+		return nil, errors.New("you must provide a region in order to use AWS Secrets Manager")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("you must provide credentials in order to use AWS Secrets Manager")
+	}
+
+	endpoint := conf.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", awsSecretsManagerService, region)
+	}
+
+	return &AWSSecretsManager{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		endpoint:    endpoint,
+		region:      region,
+		credentials: creds,
+	}, nil
+}
+
+// Get returns the value of a secret, the key being its name or ARN. Fields of secrets holding a
+// JSON object are read with `name#field`.
+func (a *AWSSecretsManager) Get(key string) (string, error) {
+	id, field := key, ""
+	if i := strings.LastIndex(key, "#"); i != -1 {
+		id, field = key[:i], key[i+1:]
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, body, a.region, awsSecretsManagerService, a.credentials, time.Now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &awsErr)
+		if strings.HasSuffix(awsErr.Type, "ResourceNotFoundException") {
+			return "", ErrKeyNotFound
+		}
+		return "", fmt.Errorf("AWS Secrets Manager responded %s: %s %s", resp.Status, awsErr.Type, awsErr.Message)
+	}
+
+	var secret struct {
+		SecretString string
+	}
+	if err := json.Unmarshal(respBody, &secret); err != nil {
+		return "", err
+	}
+	if field == "" {
+		return secret.SecretString, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret.SecretString), &fields); err != nil {
+		return "", errors.New("secret should be a JSON object to read its fields")
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
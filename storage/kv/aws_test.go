@@ -0,0 +1,71 @@
+package kv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+var _ Store = (*AWSSecretsManager)(nil)
+
+func TestAWSSecretsManager_Get(t *testing.T) {
+	secrets := map[string]string{
+		"plain": "value",
+		"json":  `{"password":"secret","port":5432}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request") ||
+			r.Header.Get("X-Amz-Security-Token") != "token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var input struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&input)
+		secret, ok := secrets[input.SecretId]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"Name": input.SecretId, "SecretString": secret})
+	}))
+	defer server.Close()
+
+	store, err := NewAWSSecretsManager(config.AWSSecretsManagerConfig{
+		Region:          "eu-west-1",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Endpoint:        server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]string{"plain": "value", "json#password": "secret", "json#port": "5432"} {
+		val, err := store.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val != expected {
+			t.Fatalf("Got an unexpected value for %s.. Expected %s, got %s", key, expected, val)
+		}
+	}
+
+	for _, key := range []string{"missing", "json#missing"} {
+		if _, err := store.Get(key); err != ErrKeyNotFound {
+			t.Fatalf("Expect %s not to exists, got %v", key, err)
+		}
+	}
+
+	if _, err := store.Get("plain#field"); err == nil {
+		t.Fatal("Expect fields of a plain secret not to be readable")
+	}
+}
@@ -19,6 +19,8 @@ func NewVault(conf config.VaultConfig) (Store, error) {
 	return newVault(conf)
 }
 
+// Get returns the value of a field of a secret, the key being in the form of `path.field` or
+// `path#field`, the latter allowing dots in the path.
 func (v *Vault) Get(key string) (string, error) {
 
 	logicalStore := v.client.Logical()
@@ -32,9 +34,14 @@ func (v *Vault) Get(key string) (string, error) {
 		}
 	}
 
-	splitted := strings.Split(key, ".")
+	var splitted []string
+	if i := strings.LastIndex(key, "#"); i != -1 {
+		splitted = []string{key[:i], key[i+1:]}
+	} else {
+		splitted = strings.Split(key, ".")
+	}
 	if len(splitted) != 2 {
-		return "", errors.New("key should be in form of config.value")
+		return "", errors.New("key should be in form of config.value or config#value")
 	}
 
 	secret, err := logicalStore.Read(splitted[0])
@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage/internal/sigv4"
+)
+
+const (
+	dynamoDBService        = "dynamodb"
+	defaultDynamoDBTimeout = time.Second
+)
+
+// dynamoDBValue is an attribute value of DynamoDB.
+type dynamoDBValue struct {
+	S string `json:"S,omitempty"`
+	N string `json:"N,omitempty"`
+}
+
+type dynamoDBItem map[string]dynamoDBValue
+
+// dynamoDBCounters keeps the counters in a DynamoDB table, items holding their `count` and their
+// `expires` Unix time. Items are read after they expire until the TTL of the table deletes them,
+// so their expiry is checked when read.
+type dynamoDBCounters struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	table    string
+	creds    sigv4.Credentials
+	now      func() time.Time
+}
+
+func newDynamoDBCounters(conf config.DynamoDBConfig) (*dynamoDBCounters, error) {
+	if conf.Table == "" {
+		return nil, errors.New("dynamodb rate limit storage requires a table")
+	}
+	region := conf.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("dynamodb rate limit storage requires a region")
+	}
+	creds := sigv4.CredentialsOrEnv(sigv4.Credentials{
+		AccessKeyID:     conf.AccessKeyID,
+		SecretAccessKey: conf.SecretAccessKey,
+		SessionToken:    conf.SessionToken,
+	})
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("dynamodb rate limit storage requires credentials")
+	}
+
+	endpoint := conf.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", dynamoDBService, region)
+	}
+	timeout := defaultDynamoDBTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout * float64(time.Second))
+	}
+
+	return &dynamoDBCounters{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: endpoint,
+		region:   region,
+		table:    conf.Table,
+		creds:    creds,
+		now:      time.Now,
+	}, nil
+}
+
+// call calls the action of the DynamoDB API with input, decoding its response into output.
+func (d *dynamoDBCounters) call(action string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, d.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+action)
+	sigv4.Sign(req, body, d.region, dynamoDBService, d.creds, d.now())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &awsErr)
+		return fmt.Errorf("DynamoDB responded %s: %s %s", resp.Status, awsErr.Type, awsErr.Message)
+	}
+	return json.Unmarshal(respBody, output)
+}
+
+func (d *dynamoDBCounters) key(key string) dynamoDBItem {
+	return dynamoDBItem{"key": {S: key}}
+}
+
+func (d *dynamoDBCounters) expires(ttl int64) string {
+	return strconv.FormatInt(d.now().Unix()+ttl, 10)
+}
+
+func (d *dynamoDBCounters) incr(key string, ttl int64) (int64, error) {
+	input := map[string]interface{}{
+		"TableName":                d.table,
+		"Key":                      d.key(key),
+		"UpdateExpression":         "ADD #count :one",
+		"ExpressionAttributeNames": map[string]string{"#count": "count"},
+		"ExpressionAttributeValues": dynamoDBItem{
+			":one": {N: "1"},
+		},
+		"ReturnValues": "UPDATED_NEW",
+	}
+	if ttl > 0 {
+		input["UpdateExpression"] = "ADD #count :one SET #expires = if_not_exists(#expires, :expires)"
+		input["ExpressionAttributeNames"] = map[string]string{"#count": "count", "#expires": "expires"}
+		input["ExpressionAttributeValues"] = dynamoDBItem{
+			":one":     {N: "1"},
+			":expires": {N: d.expires(ttl)},
+		}
+	}
+
+	var output struct {
+		Attributes dynamoDBItem
+	}
+	if err := d.call("UpdateItem", input, &output); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(output.Attributes["count"].N, 10, 64)
+}
+
+func (d *dynamoDBCounters) get(key string) (int64, error) {
+	input := map[string]interface{}{
+		"TableName":      d.table,
+		"Key":            d.key(key),
+		"ConsistentRead": true,
+	}
+
+	var output struct {
+		Item dynamoDBItem
+	}
+	if err := d.call("GetItem", input, &output); err != nil {
+		return 0, err
+	}
+	if output.Item == nil {
+		return 0, nil
+	}
+	if expires := output.Item["expires"].N; expires != "" {
+		if at, err := strconv.ParseInt(expires, 10, 64); err == nil && at <= d.now().Unix() {
+			return 0, nil
+		}
+	}
+	return strconv.ParseInt(output.Item["count"].N, 10, 64)
+}
+
+func (d *dynamoDBCounters) set(key string, value, ttl int64) error {
+	item := d.key(key)
+	item["count"] = dynamoDBValue{N: strconv.FormatInt(value, 10)}
+	if ttl > 0 {
+		item["expires"] = dynamoDBValue{N: d.expires(ttl)}
+	}
+
+	var output struct{}
+	return d.call("PutItem", map[string]interface{}{"TableName": d.table, "Item": item}, &output)
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// newFakeDynamoDB serves the UpdateItem, GetItem and PutItem actions used by the counters.
+func newFakeDynamoDB(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	items := map[string]dynamoDBItem{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/dynamodb/aws4_request") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.dynamodb#MissingAuthenticationToken","message":"Missing signature"}`))
+			return
+		}
+
+		var input struct {
+			TableName                 string
+			Key                       dynamoDBItem
+			Item                      dynamoDBItem
+			UpdateExpression          string
+			ExpressionAttributeValues dynamoDBItem
+		}
+		json.NewDecoder(r.Body).Decode(&input)
+		if input.TableName != "rate-limits" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.dynamodb#ResourceNotFoundException","message":"Requested resource not found"}`))
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Header.Get("X-Amz-Target") {
+		case "DynamoDB_20120810.UpdateItem":
+			item, ok := items[input.Key["key"].S]
+			if !ok {
+				item = dynamoDBItem{"key": input.Key["key"]}
+			}
+			count, _ := strconv.Atoi(item["count"].N)
+			item["count"] = dynamoDBValue{N: strconv.Itoa(count + 1)}
+			if _, ok := item["expires"]; !ok && strings.Contains(input.UpdateExpression, "if_not_exists") {
+				item["expires"] = input.ExpressionAttributeValues[":expires"]
+			}
+			items[input.Key["key"].S] = item
+			json.NewEncoder(w).Encode(map[string]interface{}{"Attributes": dynamoDBItem{"count": item["count"]}})
+		case "DynamoDB_20120810.GetItem":
+			output := map[string]interface{}{}
+			if item, ok := items[input.Key["key"].S]; ok {
+				output["Item"] = item
+			}
+			json.NewEncoder(w).Encode(output)
+		case "DynamoDB_20120810.PutItem":
+			items[input.Item["key"].S] = input.Item
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestDynamoDBCounters(t *testing.T) {
+	server := newFakeDynamoDB(t)
+	defer server.Close()
+
+	conf := config.DynamoDBConfig{
+		Table:           "rate-limits",
+		Region:          "eu-west-1",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+		Endpoint:        server.URL,
+	}
+	counters, err := newDynamoDBCounters(conf)
+	require.NoError(t, err)
+	clock := &testClock{t: time.Unix(1000, 0)}
+	counters.now = clock.now
+
+	value, err := counters.get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), value)
+
+	for i := int64(1); i <= 3; i++ {
+		value, err = counters.incr("counter", 20)
+		assert.NoError(t, err)
+		assert.Equal(t, i, value)
+	}
+	value, err = counters.get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+
+	assert.NoError(t, counters.set("sentinel", 1, 10))
+	value, err = counters.get("sentinel")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	// items are read until the TTL of the table deletes them
+	clock.t = clock.t.Add(10 * time.Second)
+	value, err = counters.get("sentinel")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), value)
+	value, err = counters.get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+
+	conf.Table = "unknown"
+	counters, err = newDynamoDBCounters(conf)
+	require.NoError(t, err)
+	_, err = counters.incr("counter", 20)
+	assert.Contains(t, err.Error(), "ResourceNotFoundException")
+}
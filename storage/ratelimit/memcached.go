@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+const (
+	defaultMemcachedTimeout = time.Second
+	// memcachedMaxIdleConns is the number of idle connections kept per server.
+	memcachedMaxIdleConns = 16
+)
+
+// memcachedConn is a connection to a memcached server speaking the text protocol.
+type memcachedConn struct {
+	net.Conn
+	rw *bufio.ReadWriter
+}
+
+type memcachedServer struct {
+	addr string
+	idle chan *memcachedConn
+}
+
+// memcachedCounters keeps the counters in memcached servers, keys being distributed among them
+// by hash.
+type memcachedCounters struct {
+	servers []*memcachedServer
+	timeout time.Duration
+}
+
+func newMemcachedCounters(conf config.MemcachedConfig) (*memcachedCounters, error) {
+	if len(conf.Addresses) == 0 {
+		return nil, errors.New("memcached rate limit storage requires at least one address")
+	}
+
+	m := &memcachedCounters{timeout: defaultMemcachedTimeout}
+	if conf.Timeout > 0 {
+		m.timeout = time.Duration(conf.Timeout * float64(time.Second))
+	}
+	for _, addr := range conf.Addresses {
+		m.servers = append(m.servers, &memcachedServer{addr: addr, idle: make(chan *memcachedConn, memcachedMaxIdleConns)})
+	}
+	return m, nil
+}
+
+// do runs fn with a connection to the server of key. Connections are reused unless fn fails.
+func (m *memcachedCounters) do(key string, fn func(rw *bufio.ReadWriter) error) error {
+	if strings.ContainsAny(key, " \r\n") || len(key) > 250 {
+		return fmt.Errorf("invalid memcached key %q", key)
+	}
+	server := m.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(m.servers))]
+
+	var conn *memcachedConn
+	select {
+	case conn = <-server.idle:
+	default:
+		c, err := net.DialTimeout("tcp", server.addr, m.timeout)
+		if err != nil {
+			return err
+		}
+		conn = &memcachedConn{Conn: c, rw: bufio.NewReadWriter(bufio.NewReader(c), bufio.NewWriter(c))}
+	}
+
+	conn.SetDeadline(time.Now().Add(m.timeout))
+	err := fn(conn.rw)
+	if err == nil {
+		err = conn.rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	select {
+	case server.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return nil
+}
+
+func memcachedCommand(rw *bufio.ReadWriter, format string, args ...interface{}) (string, error) {
+	if _, err := fmt.Fprintf(rw, format, args...); err != nil {
+		return "", err
+	}
+	if err := rw.Flush(); err != nil {
+		return "", err
+	}
+	return memcachedLine(rw)
+}
+
+func memcachedLine(rw *bufio.ReadWriter) (string, error) {
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+		return "", fmt.Errorf("memcached: %s", line)
+	}
+	return line, nil
+}
+
+func (m *memcachedCounters) incr(key string, ttl int64) (int64, error) {
+	var value int64
+	err := m.do(key, func(rw *bufio.ReadWriter) error {
+		// a concurrent add wins the race to create the counter, it's incremented again
+		for attempt := 0; attempt < 2; attempt++ {
+			line, err := memcachedCommand(rw, "incr %s 1\r\n", key)
+			if err != nil {
+				return err
+			}
+			if line != "NOT_FOUND" {
+				value, err = strconv.ParseInt(line, 10, 64)
+				return err
+			}
+
+			line, err = memcachedCommand(rw, "add %s 0 %d 1\r\n1\r\n", key, ttl)
+			if err != nil {
+				return err
+			}
+			if line == "STORED" {
+				value = 1
+				return nil
+			}
+		}
+		return fmt.Errorf("memcached: could not create counter %s", key)
+	})
+	return value, err
+}
+
+func (m *memcachedCounters) get(key string) (int64, error) {
+	var value int64
+	err := m.do(key, func(rw *bufio.ReadWriter) error {
+		line, err := memcachedCommand(rw, "get %s\r\n", key)
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return nil
+		}
+
+		// VALUE <key> <flags> <bytes>
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("memcached: unexpected response %q", line)
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rw, data); err != nil {
+			return err
+		}
+		if value, err = strconv.ParseInt(strings.TrimSpace(string(data[:size])), 10, 64); err != nil {
+			return err
+		}
+
+		if line, err = memcachedLine(rw); err != nil {
+			return err
+		}
+		if line != "END" {
+			return fmt.Errorf("memcached: unexpected response %q", line)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (m *memcachedCounters) set(key string, value, ttl int64) error {
+	return m.do(key, func(rw *bufio.ReadWriter) error {
+		data := strconv.FormatInt(value, 10)
+		line, err := memcachedCommand(rw, "set %s 0 %d %d\r\n%s\r\n", key, ttl, len(data), data)
+		if err != nil {
+			return err
+		}
+		if line != "STORED" {
+			return fmt.Errorf("memcached: unexpected response %q", line)
+		}
+		return nil
+	})
+}
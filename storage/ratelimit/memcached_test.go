@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// fakeMemcached serves the incr, add, get and set commands of the text protocol, ignoring expiry.
+type fakeMemcached struct {
+	sync.Mutex
+	listener net.Listener
+	values   map[string]string
+	ttls     map[string]string
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	m := &fakeMemcached{listener: l, values: map[string]string{}, ttls: map[string]string{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return m
+}
+
+func (m *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			fmt.Fprint(conn, "ERROR\r\n")
+			continue
+		}
+
+		m.Lock()
+		switch fields[0] {
+		case "incr":
+			value, ok := m.values[fields[1]]
+			if !ok {
+				fmt.Fprint(conn, "NOT_FOUND\r\n")
+				break
+			}
+			n, _ := strconv.Atoi(value)
+			m.values[fields[1]] = strconv.Itoa(n + 1)
+			fmt.Fprintf(conn, "%d\r\n", n+1)
+		case "add", "set":
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			io.ReadFull(r, data)
+			if _, ok := m.values[fields[1]]; ok && fields[0] == "add" {
+				fmt.Fprint(conn, "NOT_STORED\r\n")
+				break
+			}
+			m.values[fields[1]] = string(data[:size])
+			m.ttls[fields[1]] = fields[3]
+			fmt.Fprint(conn, "STORED\r\n")
+		case "get":
+			if value, ok := m.values[fields[1]]; ok {
+				fmt.Fprintf(conn, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(value), value)
+			}
+			fmt.Fprint(conn, "END\r\n")
+		default:
+			fmt.Fprint(conn, "ERROR\r\n")
+		}
+		m.Unlock()
+	}
+}
+
+func TestMemcachedCounters(t *testing.T) {
+	server := newFakeMemcached(t)
+	defer server.listener.Close()
+
+	counters, err := newMemcachedCounters(config.MemcachedConfig{Addresses: []string{server.listener.Addr().String()}})
+	require.NoError(t, err)
+
+	value, err := counters.get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), value)
+
+	for i := int64(1); i <= 3; i++ {
+		value, err = counters.incr("counter", 20)
+		assert.NoError(t, err)
+		assert.Equal(t, i, value)
+	}
+	value, err = counters.get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+
+	assert.NoError(t, counters.set("sentinel", 1, 10))
+	value, err = counters.get("sentinel")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	server.Lock()
+	assert.Equal(t, "20", server.ttls["counter"])
+	assert.Equal(t, "10", server.ttls["sentinel"])
+	server.Unlock()
+
+	_, err = counters.get("invalid key")
+	assert.Error(t, err)
+
+	server.listener.Close()
+	_, err = newMemcachedCounters(config.MemcachedConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is the minimum time between two removals of the expired counters.
+const memorySweepInterval = time.Minute
+
+type memoryCounter struct {
+	value   int64
+	expires time.Time
+}
+
+// memoryCounters keeps the counters in the gateway, limits being enforced per gateway.
+type memoryCounters struct {
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newMemoryCounters() *memoryCounters {
+	return &memoryCounters{counters: make(map[string]memoryCounter), now: time.Now}
+}
+
+// current returns the counter of key if it hasn't expired, the lock being held.
+func (m *memoryCounters) current(key string, now time.Time) (memoryCounter, bool) {
+	if now.Sub(m.lastSweep) > memorySweepInterval {
+		for k, c := range m.counters {
+			if !c.expires.IsZero() && !now.Before(c.expires) {
+				delete(m.counters, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	c, ok := m.counters[key]
+	if ok && !c.expires.IsZero() && !now.Before(c.expires) {
+		delete(m.counters, key)
+		return memoryCounter{}, false
+	}
+	return c, ok
+}
+
+func (m *memoryCounters) incr(key string, ttl int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	c, ok := m.current(key, now)
+	if !ok && ttl > 0 {
+		c.expires = now.Add(time.Duration(ttl) * time.Second)
+	}
+	c.value++
+	m.counters[key] = c
+	return c.value, nil
+}
+
+func (m *memoryCounters) get(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, _ := m.current(key, m.now())
+	return c.value, nil
+}
+
+func (m *memoryCounters) set(key string, value, ttl int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := memoryCounter{value: value}
+	if ttl > 0 {
+		c.expires = m.now().Add(time.Duration(ttl) * time.Second)
+	}
+	m.counters[key] = c
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package ratelimit provides the storage drivers of the rate limiters other than Redis.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/log"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	DriverRedis     = "redis"
+	DriverMemory    = "memory"
+	DriverMemcached = "memcached"
+	DriverDynamoDB  = "dynamodb"
+)
+
+var logger = log.Get().WithField("prefix", "rate-limit-storage")
+
+// counters is the storage of the integer counters of a driver.
+type counters interface {
+	// incr increments the counter of key, created with a TTL of ttl seconds when missing, and
+	// returns its new value.
+	incr(key string, ttl int64) (int64, error)
+	// get returns the value of the counter of key, 0 when missing.
+	get(key string) (int64, error)
+	// set sets the counter of key to value with a TTL of ttl seconds.
+	set(key string, value, ttl int64) error
+}
+
+// NewStore creates the store of the driver of conf. It returns nil for the redis driver, whose
+// store is the Redis storage handler of the gateway.
+func NewStore(conf config.RateLimitStorageConfig) (storage.RateLimitStore, error) {
+	var c counters
+	var err error
+
+	switch conf.Driver {
+	case "", DriverRedis:
+		return nil, nil
+	case DriverMemory:
+		c = newMemoryCounters()
+	case DriverMemcached:
+		c, err = newMemcachedCounters(conf.Memcached)
+	case DriverDynamoDB:
+		c, err = newDynamoDBCounters(conf.DynamoDB)
+	default:
+		return nil, fmt.Errorf("unknown rate limit storage driver %q", conf.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &windowStore{counters: c, now: time.Now}, nil
+}
+
+// windowStore approximates the rolling windows of the rate limiters with the counters of the
+// current and previous fixed windows, the previous one being weighted by its overlap with the
+// rolling window. Sentinels are counters too.
+type windowStore struct {
+	counters counters
+	now      func() time.Time
+}
+
+func (s *windowStore) SetRollingWindow(key string, per int64, _ string, _ bool) (int, []interface{}) {
+	return s.rollingWindow(key, per, true), nil
+}
+
+func (s *windowStore) GetRollingWindow(key string, per int64, _ bool) (int, []interface{}) {
+	return s.rollingWindow(key, per, false), nil
+}
+
+// rollingWindow returns the number of requests in the rolling window of per seconds of key before
+// the current one, which is counted if add is set. Requests aren't limited when the storage fails,
+// as with Redis.
+func (s *windowStore) rollingWindow(key string, per int64, add bool) int {
+	if per <= 0 {
+		per = 1
+	}
+
+	now := s.now()
+	window := now.Unix() / per
+
+	var count int64
+	var err error
+	current := key + "." + strconv.FormatInt(window, 10)
+	if add {
+		count, err = s.counters.incr(current, 2*per)
+		count--
+	} else {
+		count, err = s.counters.get(current)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Could not count request")
+		return 0
+	}
+
+	previous, err := s.counters.get(key + "." + strconv.FormatInt(window-1, 10))
+	if err != nil {
+		logger.WithError(err).Error("Could not read previous window")
+		return int(count)
+	}
+
+	windowStart := time.Unix(window*per, 0)
+	overlap := 1 - float64(now.Sub(windowStart))/float64(time.Duration(per)*time.Second)
+	return int(count + int64(float64(previous)*overlap))
+}
+
+func (s *windowStore) GetRawKey(key string) (string, error) {
+	value, err := s.counters.get(key)
+	if err != nil {
+		return "", err
+	}
+	if value == 0 {
+		return "", storage.ErrKeyNotFound
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+func (s *windowStore) SetRawKey(key, value string, ttl int64) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("rate limit storage only holds integers: %w", err)
+	}
+	return s.counters.set(key, n, ttl)
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+var _ storage.RateLimitStore = (*windowStore)(nil)
+
+// testClock is a clock moved by the tests.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(config.RateLimitStorageConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, store)
+
+	store, err = NewStore(config.RateLimitStorageConfig{Driver: DriverMemory})
+	assert.NoError(t, err)
+	assert.NotNil(t, store)
+
+	_, err = NewStore(config.RateLimitStorageConfig{Driver: "cassandra"})
+	assert.Error(t, err)
+
+	_, err = NewStore(config.RateLimitStorageConfig{Driver: DriverMemcached})
+	assert.Error(t, err)
+
+	_, err = NewStore(config.RateLimitStorageConfig{Driver: DriverDynamoDB, DynamoDB: config.DynamoDBConfig{Region: "eu-west-1"}})
+	assert.Error(t, err)
+}
+
+func TestWindowStore(t *testing.T) {
+	clock := &testClock{t: time.Unix(6000, 0)}
+	counters := newMemoryCounters()
+	counters.now = clock.now
+	store := &windowStore{counters: counters, now: clock.now}
+
+	for i := 0; i < 10; i++ {
+		count, _ := store.SetRollingWindow("key", 10, "-1", false)
+		assert.Equal(t, i, count)
+	}
+	count, _ := store.GetRollingWindow("key", 10, false)
+	assert.Equal(t, 10, count)
+
+	// a quarter into the next window, three quarters of the previous one overlap the rolling window
+	clock.t = clock.t.Add(12500 * time.Millisecond)
+	count, _ = store.SetRollingWindow("key", 10, "-1", false)
+	assert.Equal(t, 7, count)
+	count, _ = store.GetRollingWindow("key", 10, false)
+	assert.Equal(t, 8, count)
+
+	// the counters of past windows expire
+	clock.t = clock.t.Add(time.Minute)
+	count, _ = store.GetRollingWindow("key", 10, false)
+	assert.Equal(t, 0, count)
+
+	t.Run("sentinels", func(t *testing.T) {
+		_, err := store.GetRawKey("key.BLOCKED")
+		assert.Equal(t, storage.ErrKeyNotFound, err)
+
+		require.NoError(t, store.SetRawKey("key.BLOCKED", "1", 10))
+		value, err := store.GetRawKey("key.BLOCKED")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+
+		clock.t = clock.t.Add(10 * time.Second)
+		_, err = store.GetRawKey("key.BLOCKED")
+		assert.Equal(t, storage.ErrKeyNotFound, err)
+
+		assert.Error(t, store.SetRawKey("key.BLOCKED", "yes", 10))
+	})
+}
@@ -303,6 +303,41 @@ func (r *RedisCluster) SetKey(keyName, session string, timeout int64) error {
 	return nil
 }
 
+// SetKeyAndPublish sets the key like SetKey and publishes message to channel in the same round
+// trip. Failing to publish the message is only logged.
+func (r *RedisCluster) SetKeyAndPublish(keyName, session string, timeout int64, channel, message string) error {
+	return r.setAndPublish(r.fixKey(keyName), session, timeout, channel, message)
+}
+
+// SetRawKeyAndPublish sets the key like SetRawKey and publishes message to channel in the same
+// round trip. Failing to publish the message is only logged.
+func (r *RedisCluster) SetRawKeyAndPublish(keyName, session string, timeout int64, channel, message string) error {
+	return r.setAndPublish(keyName, session, timeout, channel, message)
+}
+
+func (r *RedisCluster) setAndPublish(key, value string, timeout int64, channel, message string) error {
+	if err := r.up(); err != nil {
+		return err
+	}
+
+	var set *redis.StatusCmd
+	var publish *redis.IntCmd
+	_, _ = r.singleton().Pipelined(r.RedisController.ctx, func(pipe redis.Pipeliner) error {
+		set = pipe.Set(r.RedisController.ctx, key, value, time.Duration(timeout)*time.Second)
+		publish = pipe.Publish(r.RedisController.ctx, channel, message)
+		return nil
+	})
+
+	if err := publish.Err(); err != nil {
+		log.Error("Error trying to publish message: ", err)
+	}
+	if err := set.Err(); err != nil {
+		log.Error("Error trying to set value: ", err)
+		return err
+	}
+	return nil
+}
+
 func (r *RedisCluster) SetRawKey(keyName, session string, timeout int64) error {
 	if err := r.up(); err != nil {
 		return err
@@ -329,6 +364,15 @@ func (r *RedisCluster) Decrement(keyName string) {
 	}
 }
 
+// incrWithExpireScript increments the key, setting its expiry in seconds when it creates it.
+var incrWithExpireScript = redis.NewScript(`
+local val = redis.call("INCR", KEYS[1])
+if val == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("EXPIRE", KEYS[1], ARGV[1])
+end
+return val
+`)
+
 // IncrementWithExpire will increment a key in redis
 func (r *RedisCluster) IncrememntWithExpire(keyName string, expire int64) int64 {
 	// log.Debug("Incrementing raw key: ", keyName)
@@ -338,7 +382,9 @@ func (r *RedisCluster) IncrememntWithExpire(keyName string, expire int64) int64
 	}
 	// This function uses a raw key, so we shouldn't call fixKey
 	fixedKey := keyName
-	val, err := r.singleton().Incr(r.RedisController.ctx, fixedKey).Result()
+
+	// the expiry is set by the increment creating the key, in the same round trip
+	val, err := incrWithExpireScript.Run(r.RedisController.ctx, r.singleton(), []string{fixedKey}, expire).Int64()
 
 	if err != nil {
 		log.Error("Error trying to increment value:", err)
@@ -346,11 +392,6 @@ func (r *RedisCluster) IncrememntWithExpire(keyName string, expire int64) int64
 		log.Debug("Incremented key: ", fixedKey, ", val is: ", val)
 	}
 
-	if val == 1 && expire > 0 {
-		log.Debug("--> Setting Expire")
-		r.singleton().Expire(r.RedisController.ctx, fixedKey, time.Duration(expire)*time.Second)
-	}
-
 	return val
 }
 
@@ -138,3 +138,53 @@ func TestRedisExpirationTime(t *testing.T) {
 	assert.Equal(t, nil, errGetExp)
 
 }
+
+func TestRedisClusterIncrememntWithExpire(t *testing.T) {
+	r := RedisCluster{KeyPrefix: "test-cluster", RedisController: &rc}
+	key := "test-cluster-incr"
+	r.DeleteRawKey(key)
+	defer r.DeleteRawKey(key)
+
+	before := rc.Metrics()[defaultPoolName]
+
+	assert.Equal(t, int64(1), r.IncrememntWithExpire(key, 10))
+	assert.Equal(t, int64(2), r.IncrememntWithExpire(key, 10))
+
+	ttl, err := rc.singleton(false, false).TTL(rc.ctx, key).Result()
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= 10*time.Second, ttl)
+
+	// an increment is a single script, sent once more the first time it's run
+	after := rc.Metrics()[defaultPoolName]
+	assert.Equal(t, before.Pipelines, after.Pipelines)
+	assert.True(t, after.Commands-before.Commands <= 3, after.Commands-before.Commands)
+	assert.Equal(t, before.Errors, after.Errors)
+	assert.True(t, after.AvgLatency > 0)
+	assert.True(t, after.TotalConns > 0)
+}
+
+func TestRedisClusterSetKeyAndPublish(t *testing.T) {
+	r := RedisCluster{KeyPrefix: "test-cluster", RedisController: &rc}
+	defer r.DeleteKey("session")
+
+	sub := rc.singleton(false, false).Subscribe(rc.ctx, "test-cluster-channel")
+	defer sub.Close()
+	_, err := sub.Receive(rc.ctx)
+	assert.NoError(t, err)
+
+	before := rc.Metrics()[defaultPoolName]
+	assert.NoError(t, r.SetKeyAndPublish("session", "value", 10, "test-cluster-channel", "updated"))
+
+	// the key is set and the message published in a single round trip
+	after := rc.Metrics()[defaultPoolName]
+	assert.Equal(t, before.Pipelines+1, after.Pipelines)
+	assert.Equal(t, before.Commands, after.Commands)
+
+	val, err := r.GetKey("session")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", val)
+
+	msg, err := sub.ReceiveMessage(rc.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", msg.Payload)
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 
 	"github.com/TykTechnologies/tyk/config"
@@ -16,6 +17,8 @@ type RedisController struct {
 	singleAnalyticsPool atomic.Value
 	redisUp             atomic.Value
 	disableRedis        atomic.Value
+	// metrics are the metrics of the pools by pool name.
+	metrics sync.Map
 
 	ctx context.Context
 }
@@ -98,14 +101,18 @@ func (rc *RedisController) connectSingleton(cache, analytics bool, conf config.C
 	d := rc.singleton(cache, analytics) == nil
 	if d {
 		log.Debug("Connecting to redis cluster")
+		pool := NewRedisClusterPool(cache, analytics, conf)
+		metrics := &redisMetrics{}
+		pool.AddHook(metrics)
+		rc.metrics.Store(poolName(cache, analytics), metrics)
 		if cache {
-			rc.singleCachePool.Store(NewRedisClusterPool(cache, analytics, conf))
+			rc.singleCachePool.Store(pool)
 			return true
 		} else if analytics {
-			rc.singleAnalyticsPool.Store(NewRedisClusterPool(cache, analytics, conf))
+			rc.singleAnalyticsPool.Store(pool)
 			return true
 		}
-		rc.singlePool.Store(NewRedisClusterPool(cache, analytics, conf))
+		rc.singlePool.Store(pool)
 		return true
 	}
 	return true
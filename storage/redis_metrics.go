@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+// Names of the Redis connection pools.
+const (
+	defaultPoolName   = "default"
+	cachePoolName     = "cache"
+	analyticsPoolName = "analytics"
+)
+
+func poolName(cache, analytics bool) string {
+	switch {
+	case cache:
+		return cachePoolName
+	case analytics:
+		return analyticsPoolName
+	}
+	return defaultPoolName
+}
+
+// RedisPoolMetrics are the connection and latency metrics of a Redis connection pool, since the
+// gateway started. A pipeline is counted as a single round trip.
+type RedisPoolMetrics struct {
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+	StaleConns uint32 `json:"stale_conns"`
+
+	Commands   uint64  `json:"commands"`
+	Pipelines  uint64  `json:"pipelines"`
+	Errors     uint64  `json:"errors"`
+	AvgLatency float64 `json:"avg_latency_ms"`
+}
+
+type redisMetricsStartKey struct{}
+
+// redisMetrics is a go-redis hook measuring the round trips of a connection pool.
+type redisMetrics struct {
+	commands  uint64
+	pipelines uint64
+	errors    uint64
+	// latency is the total latency of the round trips, in nanoseconds.
+	latency uint64
+}
+
+func (m *redisMetrics) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisMetricsStartKey{}, time.Now()), nil
+}
+
+func (m *redisMetrics) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	atomic.AddUint64(&m.commands, 1)
+	m.record(ctx, cmd)
+	return nil
+}
+
+func (m *redisMetrics) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisMetricsStartKey{}, time.Now()), nil
+}
+
+func (m *redisMetrics) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	atomic.AddUint64(&m.pipelines, 1)
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			atomic.AddUint64(&m.errors, 1)
+			break
+		}
+	}
+	m.record(ctx, nil)
+	return nil
+}
+
+func (m *redisMetrics) record(ctx context.Context, cmd redis.Cmder) {
+	if cmd != nil {
+		// the scripts missing from the script cache of Redis are sent again, it isn't a failure
+		if err := cmd.Err(); err != nil && err != redis.Nil && !strings.HasPrefix(err.Error(), "NOSCRIPT ") {
+			atomic.AddUint64(&m.errors, 1)
+		}
+	}
+	if start, ok := ctx.Value(redisMetricsStartKey{}).(time.Time); ok {
+		atomic.AddUint64(&m.latency, uint64(time.Since(start)))
+	}
+}
+
+func (m *redisMetrics) metrics(client redis.UniversalClient) RedisPoolMetrics {
+	var metrics RedisPoolMetrics
+	if stats := client.PoolStats(); stats != nil {
+		metrics.Hits = stats.Hits
+		metrics.Misses = stats.Misses
+		metrics.Timeouts = stats.Timeouts
+		metrics.TotalConns = stats.TotalConns
+		metrics.IdleConns = stats.IdleConns
+		metrics.StaleConns = stats.StaleConns
+	}
+
+	metrics.Commands = atomic.LoadUint64(&m.commands)
+	metrics.Pipelines = atomic.LoadUint64(&m.pipelines)
+	metrics.Errors = atomic.LoadUint64(&m.errors)
+	if trips := metrics.Commands + metrics.Pipelines; trips > 0 {
+		metrics.AvgLatency = float64(atomic.LoadUint64(&m.latency)) / float64(trips) / float64(time.Millisecond)
+	}
+	return metrics
+}
+
+// Metrics returns the metrics of the connected Redis pools by pool name: default, cache and
+// analytics.
+func (rc *RedisController) Metrics() map[string]RedisPoolMetrics {
+	pools := map[string]RedisPoolMetrics{}
+	for _, pool := range []struct{ cache, analytics bool }{{false, false}, {true, false}, {false, true}} {
+		name := poolName(pool.cache, pool.analytics)
+		client := rc.singleton(pool.cache, pool.analytics)
+		m, ok := rc.metrics.Load(name)
+		if client == nil || !ok {
+			continue
+		}
+		pools[name] = m.(*redisMetrics).metrics(client)
+	}
+	return pools
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamMessage is a message of a Redis stream.
+type StreamMessage struct {
+	Stream string
+	ID     string
+	Values map[string]interface{}
+}
+
+func streamMessages(stream string, messages []redis.XMessage) []StreamMessage {
+	out := make([]StreamMessage, len(messages))
+	for i, msg := range messages {
+		out[i] = StreamMessage{Stream: stream, ID: msg.ID, Values: msg.Values}
+	}
+	return out
+}
+
+// StreamAdd appends a message with values to the stream, returning its ID.
+func (r *RedisCluster) StreamAdd(stream string, values map[string]interface{}) (string, error) {
+	if err := r.up(); err != nil {
+		return "", err
+	}
+	fixedKey := r.fixKey(stream)
+	id, err := r.singleton().XAdd(r.RedisController.ctx, &redis.XAddArgs{Stream: fixedKey, Values: values}).Result()
+	if err != nil {
+		log.WithField("fixedKey", fixedKey).WithError(err).Error("XADD command failed")
+	}
+	return id, err
+}
+
+// StreamCreateGroup creates the consumer group of the stream, and the stream if it doesn't exist.
+// Creating a group which exists isn't an error.
+func (r *RedisCluster) StreamCreateGroup(stream, group string) error {
+	if err := r.up(); err != nil {
+		return err
+	}
+	err := r.singleton().XGroupCreateMkStream(r.RedisController.ctx, r.fixKey(stream), group, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// StreamReadGroup reads up to count new messages of the streams for the consumer of group,
+// waiting for them up to block.
+func (r *RedisCluster) StreamReadGroup(streams []string, group, consumer string, count int64, block time.Duration) ([]StreamMessage, error) {
+	if err := r.up(); err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, 2*len(streams))
+	for _, stream := range streams {
+		args = append(args, r.fixKey(stream))
+	}
+	for range streams {
+		args = append(args, ">")
+	}
+
+	result, err := r.singleton().XReadGroup(r.RedisController.ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  args,
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []StreamMessage
+	for _, stream := range result {
+		messages = append(messages, streamMessages(r.cleanKey(stream.Stream), stream.Messages)...)
+	}
+	return messages, nil
+}
+
+// StreamClaimIdle moves to consumer up to count messages of group pending for another consumer
+// for longer than minIdle, e.g. read by a consumer which stopped before acknowledging them.
+func (r *RedisCluster) StreamClaimIdle(stream, group, consumer string, minIdle time.Duration, count int64) ([]StreamMessage, error) {
+	if err := r.up(); err != nil {
+		return nil, err
+	}
+	fixedKey := r.fixKey(stream)
+	client := r.singleton()
+
+	// the pending messages of consumer are skipped, it's still delivering them
+	var ids []string
+	for start := "-"; len(ids) < int(count); {
+		pending, err := client.XPendingExt(r.RedisController.ctx, &redis.XPendingExtArgs{
+			Stream: fixedKey,
+			Group:  group,
+			Start:  start,
+			End:    "+",
+			Count:  count,
+		}).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range pending {
+			if p.Consumer != consumer && p.Idle >= minIdle && len(ids) < int(count) {
+				ids = append(ids, p.ID)
+			}
+		}
+		if len(pending) < int(count) {
+			break
+		}
+		start = nextStreamID(pending[len(pending)-1].ID)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	messages, err := client.XClaim(r.RedisController.ctx, &redis.XClaimArgs{
+		Stream:   fixedKey,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return streamMessages(stream, messages), nil
+}
+
+// nextStreamID returns the smallest stream ID greater than id.
+func nextStreamID(id string) string {
+	i := strings.LastIndexByte(id, '-')
+	seq, err := strconv.ParseUint(id[i+1:], 10, 64)
+	if i < 0 || err != nil {
+		return id
+	}
+	return id[:i+1] + strconv.FormatUint(seq+1, 10)
+}
+
+// StreamAck acknowledges the messages of group and deletes them from the stream.
+func (r *RedisCluster) StreamAck(stream, group string, ids ...string) error {
+	if err := r.up(); err != nil {
+		return err
+	}
+	fixedKey := r.fixKey(stream)
+	client := r.singleton()
+	if err := client.XAck(r.RedisController.ctx, fixedKey, group, ids...).Err(); err != nil {
+		log.WithFields(logrus.Fields{"fixedKey": fixedKey, "ids": ids}).WithError(err).Error("XACK command failed")
+		return err
+	}
+	return client.XDel(r.RedisController.ctx, fixedKey, ids...).Err()
+}
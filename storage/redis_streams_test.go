@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisClusterStreams(t *testing.T) {
+	r := RedisCluster{KeyPrefix: "test-streams.", RedisController: &rc}
+	r.DeleteKey("first")
+	r.DeleteKey("second")
+
+	require.NoError(t, r.StreamCreateGroup("first", "group"))
+	require.NoError(t, r.StreamCreateGroup("first", "group"))
+	require.NoError(t, r.StreamCreateGroup("second", "group"))
+
+	id, err := r.StreamAdd("second", map[string]interface{}{"value": "1"})
+	require.NoError(t, err)
+
+	messages, err := r.StreamReadGroup([]string{"first", "second"}, "group", "consumer", 10, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, StreamMessage{Stream: "second", ID: id, Values: map[string]interface{}{"value": "1"}}, messages[0])
+
+	// read messages are pending until acknowledged
+	messages, err = r.StreamReadGroup([]string{"first", "second"}, "group", "consumer", 10, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	messages, err = r.StreamClaimIdle("second", "group", "other", time.Hour, 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	messages, err = r.StreamClaimIdle("second", "group", "other", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, id, messages[0].ID)
+
+	// consumers don't claim back their own messages
+	messages, err = r.StreamClaimIdle("second", "group", "other", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	require.NoError(t, r.StreamAck("second", "group", id))
+	messages, err = r.StreamClaimIdle("second", "group", "other", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestNextStreamID(t *testing.T) {
+	assert.Equal(t, "1526919030474-56", nextStreamID("1526919030474-55"))
+	assert.Equal(t, "0-1", nextStreamID("0-0"))
+}
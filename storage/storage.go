@@ -58,6 +58,15 @@ type Handler interface {
 	Exists(string) (bool, error)
 }
 
+// RateLimitStore is the storage of the rolling windows and sentinels of the Redis and sentinel
+// rate limiters. Handler implementations satisfy it, the other drivers are in the ratelimit package.
+type RateLimitStore interface {
+	GetRawKey(string) (string, error)
+	SetRawKey(string, string, int64) error
+	SetRollingWindow(key string, per int64, val string, pipeline bool) (int, []interface{})
+	GetRollingWindow(key string, per int64, pipeline bool) (int, []interface{})
+}
+
 type AnalyticsHandler interface {
 	Connect() bool
 	AppendToSetPipelined(string, [][]byte)
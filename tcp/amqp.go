@@ -0,0 +1,230 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+)
+
+const (
+	amqpFrameMethod = 1
+	amqpFrameEnd    = 0xCE
+
+	amqpClassConnection    = 10
+	amqpMethodStartOk      = 11
+	amqpMethodClose        = 50
+	amqpReplyAccessRefused = 403
+
+	amqpMechanismPlain = "PLAIN"
+
+	// amqpMaxHandshakeFrame limits the size of frames read before the connection is tuned.
+	amqpMaxHandshakeFrame = 1 << 20
+)
+
+var (
+	amqpProtocolPrefix = []byte("AMQP")
+	errMalformedAMQP   = errors.New("malformed AMQP frame")
+)
+
+// amqpStartOk is the Connection.Start-Ok method a client answers the broker's Connection.Start with.
+type amqpStartOk struct {
+	clientProperties []byte
+	mechanism        string
+	response         string
+	locale           string
+}
+
+// AMQPHandshake authenticates the Connection.Start-Ok method opening an AMQP 0-9-1 connection and
+// forwards it upstream with the credentials returned by auth, using the PLAIN mechanism when they
+// differ from the client's. Rejected clients are sent a Connection.Close with ACCESS_REFUSED.
+func AMQPHandshake(auth Authenticator) func(client, upstream net.Conn) (net.Conn, net.Conn, error) {
+	return func(client, upstream net.Conn) (net.Conn, net.Conn, error) {
+		bc, bu := newBufferedConn(client), newBufferedConn(upstream)
+
+		var startOk *amqpStartOk
+		var raw []byte
+		err := withHandshakeDeadline(client, func() (err error) {
+			startOk, raw, err = relayAMQPStart(bc, bu)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		creds := startOk.credentials()
+		upstreamCreds, err := auth(client, creds)
+		if err != nil {
+			client.Write(encodeAMQPMethod(amqpClassConnection, amqpMethodClose, amqpAccessRefused(err)))
+			return nil, nil, err
+		}
+
+		if upstreamCreds != creds {
+			startOk.mechanism = amqpMechanismPlain
+			startOk.response = "\x00" + upstreamCreds.Username + "\x00" + upstreamCreds.Password
+			raw = encodeAMQPMethod(amqpClassConnection, amqpMethodStartOk, startOk.encode())
+		}
+		if _, err := upstream.Write(raw); err != nil {
+			return nil, nil, err
+		}
+		return bc, bu, nil
+	}
+}
+
+// relayAMQPStart relays the protocol header of the client and the Connection.Start of the broker,
+// returning the Connection.Start-Ok of the client along with its raw frame.
+func relayAMQPStart(client, upstream *bufferedConn) (*amqpStartOk, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(client.r, header); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.HasPrefix(header, amqpProtocolPrefix) {
+		return nil, nil, errMalformedAMQP
+	}
+	if _, err := upstream.Write(header); err != nil {
+		return nil, nil, err
+	}
+
+	// a broker not supporting the protocol version answers with the one it supports
+	if prefix, err := upstream.r.Peek(len(amqpProtocolPrefix)); err == nil && bytes.Equal(prefix, amqpProtocolPrefix) {
+		io.CopyN(client, upstream.r, int64(len(header)))
+		return nil, nil, errors.New("AMQP protocol version not supported by upstream")
+	}
+	start, err := readAMQPFrame(upstream.r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := client.Write(start); err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := readAMQPFrame(client.r)
+	if err != nil {
+		return nil, nil, err
+	}
+	startOk, err := decodeAMQPStartOk(raw)
+	return startOk, raw, err
+}
+
+// readAMQPFrame reads a whole frame, header and end octet included.
+func readAMQPFrame(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[3:])
+	if size > amqpMaxHandshakeFrame {
+		return nil, errMalformedAMQP
+	}
+	frame := make([]byte, len(header)+int(size)+1)
+	copy(frame, header)
+	if _, err := io.ReadFull(r, frame[len(header):]); err != nil {
+		return nil, err
+	}
+	if frame[len(frame)-1] != amqpFrameEnd {
+		return nil, errMalformedAMQP
+	}
+	return frame, nil
+}
+
+func decodeAMQPStartOk(frame []byte) (*amqpStartOk, error) {
+	payload := frame[7 : len(frame)-1]
+	if frame[0] != amqpFrameMethod || len(payload) < 4 ||
+		binary.BigEndian.Uint16(payload) != amqpClassConnection ||
+		binary.BigEndian.Uint16(payload[2:]) != amqpMethodStartOk {
+		return nil, errUnexpectedPacket
+	}
+
+	d := &amqpDecoder{buf: payload[4:]}
+	m := &amqpStartOk{
+		clientProperties: d.next(int(d.uint32())),
+		mechanism:        d.shortString(),
+		response:         string(d.next(int(d.uint32()))),
+		locale:           d.shortString(),
+	}
+	return m, d.err
+}
+
+// credentials returns the username and password of a PLAIN response, empty for other mechanisms.
+func (m *amqpStartOk) credentials() Credentials {
+	if m.mechanism != amqpMechanismPlain {
+		return Credentials{}
+	}
+	// authorization identity, authentication identity and password
+	parts := strings.SplitN(m.response, "\x00", 3)
+	if len(parts) != 3 {
+		return Credentials{}
+	}
+	return Credentials{Username: parts[1], Password: parts[2]}
+}
+
+func (m *amqpStartOk) encode() []byte {
+	var b []byte
+	b = appendAMQPUint32(b, uint32(len(m.clientProperties)))
+	b = append(b, m.clientProperties...)
+	b = append(b, byte(len(m.mechanism)))
+	b = append(b, m.mechanism...)
+	b = appendAMQPUint32(b, uint32(len(m.response)))
+	b = append(b, m.response...)
+	b = append(b, byte(len(m.locale)))
+	return append(b, m.locale...)
+}
+
+// amqpAccessRefused returns the arguments of a Connection.Close refusing the Start-Ok of a client.
+func amqpAccessRefused(reason error) []byte {
+	text := "ACCESS_REFUSED - " + reason.Error()
+	if len(text) > 255 {
+		text = text[:255]
+	}
+	b := []byte{amqpReplyAccessRefused >> 8, amqpReplyAccessRefused & 0xff, byte(len(text))}
+	b = append(b, text...)
+	return append(b, 0, amqpClassConnection, 0, amqpMethodStartOk)
+}
+
+func encodeAMQPMethod(class, method uint16, args []byte) []byte {
+	b := []byte{amqpFrameMethod, 0, 0}
+	b = appendAMQPUint32(b, uint32(4+len(args)))
+	b = append(b, byte(class>>8), byte(class), byte(method>>8), byte(method))
+	b = append(b, args...)
+	return append(b, amqpFrameEnd)
+}
+
+func appendAMQPUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+type amqpDecoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *amqpDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.pos+n > len(d.buf) {
+		d.err = errMalformedAMQP
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *amqpDecoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *amqpDecoder) shortString() string {
+	if l := d.next(1); l != nil {
+		return string(d.next(int(l[0])))
+	}
+	return ""
+}
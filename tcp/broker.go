@@ -0,0 +1,46 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"time"
+)
+
+// handshakeTimeout bounds how long a client may take to open a broker session.
+const handshakeTimeout = 30 * time.Second
+
+var errUnexpectedPacket = errors.New("unexpected packet during handshake")
+
+// Credentials are the username and password a client opens a broker session with.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Authenticator validates the credentials a client opened a broker session with and returns
+// the credentials to open the upstream session with. Returning an error rejects the client.
+type Authenticator func(conn net.Conn, creds Credentials) (Credentials, error)
+
+// bufferedConn is a connection whose reads go through a buffered reader, so that data read
+// ahead while parsing a handshake is not lost.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func newBufferedConn(conn net.Conn) *bufferedConn {
+	return &bufferedConn{Conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// withHandshakeDeadline runs a handshake on client, making sure it completes in time.
+func withHandshakeDeadline(client net.Conn, handshake func() error) error {
+	client.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	err := handshake()
+	client.SetReadDeadline(time.Time{})
+	return err
+}
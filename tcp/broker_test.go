@@ -0,0 +1,260 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+var errInvalidKey = errors.New("invalid key")
+
+// testBrokerAuth accepts clients using the "good" password, opening the upstream session as "broker".
+func testBrokerAuth(conn net.Conn, creds Credentials) (Credentials, error) {
+	if creds.Password != "good" {
+		return creds, errInvalidKey
+	}
+	return Credentials{Username: "broker", Password: "secret"}, nil
+}
+
+func testMQTTConnect(level byte, will bool, creds Credentials) []byte {
+	name := "MQTT"
+	if level == 3 {
+		name = "MQIsdp"
+	}
+	p := &mqttConnectPacket{
+		level: level,
+		flags: 0x02, // clean session
+		head:  append(appendMQTTString(nil, name), level),
+		rest:  []byte{0, 60},
+	}
+	if level == mqttLevel5 {
+		p.rest = append(p.rest, 0x03, 0x21, 0x00, 0x0a) // receive maximum
+	}
+	p.rest = appendMQTTString(p.rest, "client-1")
+	if will {
+		p.flags |= mqttFlagWill
+		if level == mqttLevel5 {
+			p.rest = append(p.rest, 0)
+		}
+		p.rest = appendMQTTString(p.rest, "will/topic")
+		p.rest = appendMQTTString(p.rest, "gone")
+	}
+	return p.encode(creds)
+}
+
+func TestMQTTConnectPacket(t *testing.T) {
+	client := Credentials{Username: "user", Password: "key"}
+	upstream := Credentials{Username: "broker", Password: "secret"}
+
+	for _, tc := range []struct {
+		name  string
+		level byte
+		will  bool
+	}{
+		{"3.1", 3, false},
+		{"3.1.1", 4, false},
+		{"3.1.1 with will", 4, true},
+		{"5", mqttLevel5, false},
+		{"5 with will", mqttLevel5, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := readMQTTConnect(bufio.NewReader(bytes.NewReader(testMQTTConnect(tc.level, tc.will, client))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.username != client.Username || p.password != client.Password {
+				t.Fatalf("expected client credentials, got %q/%q", p.username, p.password)
+			}
+
+			rewritten, err := readMQTTConnect(bufio.NewReader(bytes.NewReader(p.encode(upstream))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rewritten.username != upstream.Username || rewritten.password != upstream.Password {
+				t.Fatalf("expected upstream credentials, got %q/%q", rewritten.username, rewritten.password)
+			}
+			if !bytes.Equal(rewritten.rest, p.rest) || rewritten.flags&mqttFlagWill != p.flags&mqttFlagWill {
+				t.Error("rewriting credentials should keep the rest of the packet")
+			}
+		})
+	}
+
+	t.Run("not a CONNECT", func(t *testing.T) {
+		_, err := readMQTTConnect(bufio.NewReader(bytes.NewReader([]byte{0x30, 0x00})))
+		if err != errUnexpectedPacket {
+			t.Fatalf("expected %v, got %v", errUnexpectedPacket, err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		packet := testMQTTConnect(4, false, client)
+		packet[1] -= 3
+		_, err := readMQTTConnect(bufio.NewReader(bytes.NewReader(packet[:len(packet)-3])))
+		if err != errMalformedMQTT {
+			t.Fatalf("expected %v, got %v", errMalformedMQTT, err)
+		}
+	})
+}
+
+// startBrokerProxy proxies to upstream through the handshake, returning the address to connect to.
+func startBrokerProxy(t *testing.T, upstream string, handshake func(client, upstream net.Conn) (net.Conn, net.Conn, error)) string {
+	proxyLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { proxyLn.Close() })
+
+	proxy := &Proxy{}
+	proxy.AddDomainHandler("", upstream, &Modifier{Handshake: handshake})
+	go proxy.Serve(proxyLn)
+	return proxyLn.Addr().String()
+}
+
+// startBroker runs serve for each connection accepted by a fake broker.
+func startBroker(t *testing.T, serve func(conn net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestMQTTHandshake(t *testing.T) {
+	// the broker echoes the CONNECT it received
+	upstream := startBroker(t, func(conn net.Conn) {
+		io.Copy(conn, conn)
+	})
+	addr := startBrokerProxy(t, "mqtt://"+upstream, MQTTHandshake(testBrokerAuth))
+
+	connect := func(t *testing.T, level byte, password string) *bufio.Reader {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.Write(testMQTTConnect(level, false, Credentials{Username: "user", Password: password}))
+		return bufio.NewReader(conn)
+	}
+
+	t.Run("authorized", func(t *testing.T) {
+		p, err := readMQTTConnect(connect(t, 4, "good"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.username != "broker" || p.password != "secret" {
+			t.Fatalf("expected upstream credentials, got %q/%q", p.username, p.password)
+		}
+	})
+
+	for _, tc := range []struct {
+		level   byte
+		connack []byte
+	}{
+		{4, []byte{mqttConnack, 0x02, 0x00, mqttNotAuthorized}},
+		{mqttLevel5, []byte{mqttConnack, 0x03, 0x00, mqtt5NotAuthorized, 0x00}},
+	} {
+		r := connect(t, tc.level, "bad")
+		resp, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resp, tc.connack) {
+			t.Errorf("level %d: expected CONNACK %v, got %v", tc.level, tc.connack, resp)
+		}
+	}
+}
+
+func testAMQPStartOk(mechanism, response string) []byte {
+	m := &amqpStartOk{
+		clientProperties: []byte{0x07, 'p', 'r', 'o', 'd', 'u', 'c', 't', 'S', 0, 0, 0, 0x04, 't', 'e', 's', 't'},
+		mechanism:        mechanism,
+		response:         response,
+		locale:           "en_US",
+	}
+	return encodeAMQPMethod(amqpClassConnection, amqpMethodStartOk, m.encode())
+}
+
+func TestAMQPHandshake(t *testing.T) {
+	start := encodeAMQPMethod(amqpClassConnection, 10, []byte{0, 9, 0, 0, 0, 0, 0, 0, 0, 0x05, 'P', 'L', 'A', 'I', 'N', 0, 0, 0, 0x05, 'e', 'n', '_', 'U', 'S'})
+
+	// the broker starts the connection and echoes the Start-Ok it received
+	upstream := startBroker(t, func(conn net.Conn) {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(conn, header); err != nil || string(header) != "AMQP\x00\x00\x09\x01" {
+			return
+		}
+		conn.Write(start)
+		io.Copy(conn, conn)
+	})
+	addr := startBrokerProxy(t, "amqp://"+upstream, AMQPHandshake(testBrokerAuth))
+
+	open := func(t *testing.T, startOk []byte) []byte {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		conn.Write([]byte("AMQP\x00\x00\x09\x01"))
+		frame, err := readAMQPFrame(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(frame, start) {
+			t.Fatal("expected the Connection.Start of the broker")
+		}
+		conn.Write(startOk)
+		if frame, err = readAMQPFrame(r); err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+
+	t.Run("authorized", func(t *testing.T) {
+		m, err := decodeAMQPStartOk(open(t, testAMQPStartOk(amqpMechanismPlain, "\x00user\x00good")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds := m.credentials(); creds.Username != "broker" || creds.Password != "secret" {
+			t.Fatalf("expected upstream credentials, got %+v", creds)
+		}
+		if m.locale != "en_US" || len(m.clientProperties) != 17 {
+			t.Error("rewriting credentials should keep the rest of the method")
+		}
+	})
+
+	for name, startOk := range map[string][]byte{
+		"wrong key":         testAMQPStartOk(amqpMechanismPlain, "\x00user\x00bad"),
+		"unknown mechanism": testAMQPStartOk("AMQPLAIN", "\x05LOGIN"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			frame := open(t, startOk)
+			payload := frame[7 : len(frame)-1]
+			if payload[1] != amqpClassConnection || payload[3] != amqpMethodClose {
+				t.Fatalf("expected Connection.Close, got %v", payload[:4])
+			}
+			if code := int(payload[4])<<8 | int(payload[5]); code != amqpReplyAccessRefused {
+				t.Errorf("expected reply code %d, got %d", amqpReplyAccessRefused, code)
+			}
+		})
+	}
+}
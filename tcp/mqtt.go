@@ -0,0 +1,240 @@
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+)
+
+const (
+	mqttConnect = 0x10
+	mqttConnack = 0x20
+
+	mqttFlagUsername = 0x80
+	mqttFlagPassword = 0x40
+	mqttFlagWill     = 0x04
+
+	mqttLevel5 = 5
+
+	// CONNACK return codes of 3.1.1 and reason codes of 5 refusing a client.
+	mqttNotAuthorized  = 0x05
+	mqtt5NotAuthorized = 0x87
+
+	// mqttMaxConnectSize limits the size of the CONNECT packet read before authentication.
+	mqttMaxConnectSize = 1 << 16
+)
+
+var errMalformedMQTT = errors.New("malformed MQTT packet")
+
+// mqttConnectPacket is the CONNECT packet opening an MQTT session, kept as raw fields around the
+// credentials so it can be forwarded with different ones.
+type mqttConnectPacket struct {
+	level byte
+	flags byte
+	// head is the variable header up to the connect flags.
+	head []byte
+	// rest is everything between the connect flags and the username.
+	rest []byte
+
+	username string
+	password string
+}
+
+// MQTTHandshake authenticates the CONNECT packet opening an MQTT 3.1, 3.1.1 or 5 session and
+// forwards it upstream with the credentials returned by auth. Rejected clients are sent a
+// CONNACK refusing the connection.
+func MQTTHandshake(auth Authenticator) func(client, upstream net.Conn) (net.Conn, net.Conn, error) {
+	return func(client, upstream net.Conn) (net.Conn, net.Conn, error) {
+		bc := newBufferedConn(client)
+
+		var packet *mqttConnectPacket
+		err := withHandshakeDeadline(client, func() (err error) {
+			packet, err = readMQTTConnect(bc.r)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		creds, err := auth(client, Credentials{Username: packet.username, Password: packet.password})
+		if err != nil {
+			client.Write(packet.refusal())
+			return nil, nil, err
+		}
+
+		if _, err := upstream.Write(packet.encode(creds)); err != nil {
+			return nil, nil, err
+		}
+		return bc, upstream, nil
+	}
+}
+
+func readMQTTConnect(r *bufio.Reader) (*mqttConnectPacket, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if typ != mqttConnect {
+		return nil, errUnexpectedPacket
+	}
+	length, err := readMQTTVarInt(r.ReadByte)
+	if err != nil {
+		return nil, err
+	}
+	if length > mqttMaxConnectSize {
+		return nil, errMalformedMQTT
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	d := &mqttDecoder{buf: body}
+	switch d.string() {
+	case "MQTT", "MQIsdp":
+	default:
+		return nil, errMalformedMQTT
+	}
+	p := &mqttConnectPacket{level: d.byte()}
+	flagsAt := d.pos
+	p.flags = d.byte()
+	d.next(2) // keep alive
+	if p.level == mqttLevel5 {
+		d.properties()
+	}
+	d.string() // client identifier
+	if p.flags&mqttFlagWill != 0 {
+		if p.level == mqttLevel5 {
+			d.properties()
+		}
+		d.string() // will topic
+		d.string() // will payload
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	p.head = body[:flagsAt]
+	p.rest = body[flagsAt+1 : d.pos]
+
+	if p.flags&mqttFlagUsername != 0 {
+		p.username = d.string()
+	}
+	if p.flags&mqttFlagPassword != 0 {
+		p.password = d.string()
+	}
+	return p, d.err
+}
+
+// encode returns the packet carrying creds instead of the credentials of the client.
+func (p *mqttConnectPacket) encode(creds Credentials) []byte {
+	flags := p.flags &^ (mqttFlagUsername | mqttFlagPassword)
+	var credentials []byte
+	if creds.Username != "" {
+		flags |= mqttFlagUsername
+		credentials = appendMQTTString(credentials, creds.Username)
+	}
+	if creds.Password != "" {
+		flags |= mqttFlagPassword
+		credentials = appendMQTTString(credentials, creds.Password)
+	}
+
+	length := len(p.head) + 1 + len(p.rest) + len(credentials)
+	out := appendMQTTVarInt([]byte{mqttConnect}, length)
+	out = append(out, p.head...)
+	out = append(out, flags)
+	out = append(out, p.rest...)
+	return append(out, credentials...)
+}
+
+// refusal returns the CONNACK refusing the client as not authorized.
+func (p *mqttConnectPacket) refusal() []byte {
+	if p.level == mqttLevel5 {
+		return []byte{mqttConnack, 0x03, 0x00, mqtt5NotAuthorized, 0x00}
+	}
+	return []byte{mqttConnack, 0x02, 0x00, mqttNotAuthorized}
+}
+
+type mqttDecoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *mqttDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.pos+n > len(d.buf) {
+		d.err = errMalformedMQTT
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *mqttDecoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *mqttDecoder) string() string {
+	l := d.next(2)
+	if l == nil {
+		return ""
+	}
+	return string(d.next(int(binary.BigEndian.Uint16(l))))
+}
+
+func (d *mqttDecoder) properties() {
+	n, err := readMQTTVarInt(func() (byte, error) {
+		if b := d.next(1); b != nil {
+			return b[0], nil
+		}
+		return 0, d.err
+	})
+	if err != nil {
+		d.err = err
+		return
+	}
+	d.next(n)
+}
+
+func readMQTTVarInt(readByte func() (byte, error)) (int, error) {
+	var value, shift int
+	for i := 0; i < 4; i++ {
+		b, err := readByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+		shift += 7
+	}
+	return 0, errMalformedMQTT
+}
+
+func appendMQTTVarInt(b []byte, value int) []byte {
+	for {
+		digit := byte(value & 0x7f)
+		value >>= 7
+		if value > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if value == 0 {
+			return b
+		}
+	}
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
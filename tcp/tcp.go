@@ -31,6 +31,9 @@ const (
 type Modifier struct {
 	ModifyRequest  func(src, dst net.Conn, data []byte) ([]byte, error)
 	ModifyResponse func(src, dst net.Conn, data []byte) ([]byte, error)
+	// Handshake runs once the upstream connection is open, before any data is piped. It returns
+	// the connections to pipe, which may buffer data read ahead during the handshake.
+	Handshake func(client, upstream net.Conn) (net.Conn, net.Conn, error)
 }
 
 type targetConfig struct {
@@ -218,13 +221,13 @@ func (p *Proxy) handleConn(conn net.Conn) error {
 	// connects to target server
 	var rconn net.Conn
 	switch u.Scheme {
-	case "tcp":
+	case "tcp", "mqtt", "amqp":
 		if p.Dial != nil {
 			rconn, err = p.Dial("tcp", u.Host)
 		} else {
 			rconn, err = net.Dial("tcp", u.Host)
 		}
-	case "tls":
+	case "tls", "mqtts", "amqps":
 		if p.DialTLS != nil {
 			rconn, err = p.DialTLS("tcp", u.Host)
 		} else {
@@ -241,6 +244,13 @@ func (p *Proxy) handleConn(conn net.Conn) error {
 		conn.Close()
 		rconn.Close()
 	}()
+	if h := config.modifier.Handshake; h != nil {
+		client, upstream, err := h(conn, rconn)
+		if err != nil {
+			return err
+		}
+		conn, rconn = client, upstream
+	}
 	var wg sync.WaitGroup
 	wg.Add(2)
 
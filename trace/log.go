@@ -6,6 +6,8 @@ import (
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
+	"github.com/sirupsen/logrus"
+	jaeger "github.com/uber/jaeger-client-go"
 )
 
 // Logrus implements a subset of logrus api to reduce friction when we want
@@ -44,3 +46,50 @@ func Info(ctx context.Context, logrus Logrus, args ...interface{}) {
 	logrus.Info(args...)
 	Log(ctx, log.String("INFO", fmt.Sprint(args...)))
 }
+
+// correlatedContext is implemented by the span contexts exposing their IDs.
+type correlatedContext interface {
+	TraceIDString() string
+	SpanIDString() string
+}
+
+// IDs returns the trace and span IDs of the span in ctx, to correlate logs with traces. They're
+// empty if there is no span in ctx.
+func IDs(ctx context.Context) (traceID, spanID string) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return "", ""
+	}
+	switch c := span.Context().(type) {
+	case correlatedContext:
+		return c.TraceIDString(), c.SpanIDString()
+	case jaeger.SpanContext:
+		return c.TraceID().String(), c.SpanID().String()
+	}
+	return "", ""
+}
+
+// SpanLogHook is a logrus hook adding the entries logged with a context to the span of the context.
+type SpanLogHook struct{}
+
+func (SpanLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (SpanLogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	span := opentracing.SpanFromContext(entry.Context)
+	if span == nil {
+		return nil
+	}
+
+	fields := make([]log.Field, 0, len(entry.Data)+2)
+	fields = append(fields, log.String("level", entry.Level.String()), log.String("message", entry.Message))
+	for k, v := range entry.Data {
+		fields = append(fields, log.String(k, fmt.Sprint(v)))
+	}
+	span.LogFields(fields...)
+	return nil
+}
@@ -94,6 +94,16 @@ type spanContext struct {
 
 func (spanContext) ForeachBaggageItem(handler func(k, v string) bool) {}
 
+// TraceIDString returns the trace ID, to correlate logs with the trace.
+func (c spanContext) TraceIDString() string {
+	return c.TraceID.String()
+}
+
+// SpanIDString returns the span ID, to correlate logs with the span.
+func (c spanContext) SpanIDString() string {
+	return c.ID.String()
+}
+
 type extractor interface {
 	extract(carrier interface{}) (spanContext, error)
 }
@@ -0,0 +1,14 @@
+package otlp
+
+import (
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// Load returns an OpenTelemetry configuration from the opts.
+func Load(opts map[string]interface{}) (*config.OTLPConfig, error) {
+	var c config.OTLPConfig
+	if err := config.DecodeJSON(&c, opts); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,331 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultMaxBacklog    = 2048
+	defaultFlushInterval = 5 * time.Second
+	defaultTimeout       = 10 * time.Second
+
+	scopeName = "github.com/TykTechnologies/tyk"
+)
+
+// exporter batches items, finished spans or log records, and sends them to the collector in the background.
+type exporter struct {
+	endpoint  string
+	headers   map[string]string
+	client    *http.Client
+	logger    Logger
+	batchSize int
+	interval  time.Duration
+	// kind names the items in the errors
+	kind string
+	// encode returns the export request of a batch
+	encode func(batch []interface{}) interface{}
+
+	items chan interface{}
+	done  chan struct{}
+
+	// mu guards items against being written to after close
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newExporter(endpoint, kind string, c *config.OTLPConfig, client *http.Client, logger Logger, encode func([]interface{}) interface{}) *exporter {
+	e := &exporter{
+		endpoint:  endpoint,
+		headers:   c.Headers,
+		client:    client,
+		logger:    logger,
+		batchSize: c.BatchSize,
+		interval:  time.Duration(c.FlushInterval) * time.Millisecond,
+		kind:      kind,
+		encode:    encode,
+		done:      make(chan struct{}),
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = defaultBatchSize
+	}
+	if e.interval <= 0 {
+		e.interval = defaultFlushInterval
+	}
+	if e.client.Timeout <= 0 {
+		e.client.Timeout = defaultTimeout
+	}
+	backlog := c.MaxBacklog
+	if backlog <= 0 {
+		backlog = defaultMaxBacklog
+	}
+	e.items = make(chan interface{}, backlog)
+
+	go e.run()
+
+	return e
+}
+
+// export queues an item, dropping it if the backlog is full.
+func (e *exporter) export(item interface{}) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return
+	}
+
+	select {
+	case e.items <- item:
+	default:
+	}
+}
+
+// close sends the queued items and stops the exporter.
+func (e *exporter) close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	close(e.items)
+	e.mu.Unlock()
+
+	<-e.done
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	batch := make([]interface{}, 0, e.batchSize)
+	for {
+		select {
+		case item, ok := <-e.items:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= e.batchSize {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (e *exporter) send(batch []interface{}) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		e.logger.Errorf("otlp: could not encode %s: %v", e.kind, err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		e.logger.Errorf("otlp: could not create export request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.logger.Errorf("otlp: could not export %d %s: %v", len(batch), e.kind, err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		e.logger.Errorf("otlp: collector rejected %d %s with status %d", len(batch), e.kind, resp.StatusCode)
+	}
+}
+
+// The types below are the OTLP/HTTP JSON encoding of an export request.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	TraceState        string      `json:"traceState,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []keyValue  `json:"attributes,omitempty"`
+	Events            []otlpEvent `json:"events,omitempty"`
+	Status            status      `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+// OTLP status codes.
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+type status struct {
+	Code int `json:"code"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// encodeSpans returns the export request of a batch of spans of service.
+func encodeSpans(service string, batch []interface{}) interface{} {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, item := range batch {
+		s := item.(*Span)
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.ctx.traceID[:]),
+			SpanID:            hex.EncodeToString(s.ctx.spanID[:]),
+			TraceState:        s.ctx.traceState,
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attributes),
+			Status:            status{Code: statusUnset},
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.isError {
+			span.Status.Code = statusError
+		}
+		for _, ev := range s.events {
+			span.Events = append(span.Events, otlpEvent{
+				TimeUnixNano: strconv.FormatInt(ev.time.UnixNano(), 10),
+				Name:         ev.name,
+				Attributes:   attributes(ev.attributes),
+			})
+		}
+		s.mu.Unlock()
+
+		spans = append(spans, span)
+	}
+
+	return exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{Attributes: []keyValue{
+				{Key: "service.name", Value: value(service)},
+			}},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func attributes(m map[string]interface{}) []keyValue {
+	if len(m) == 0 {
+		return nil
+	}
+
+	kvs := make([]keyValue, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, keyValue{Key: k, Value: value(v)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+
+	return kvs
+}
+
+func value(v interface{}) anyValue {
+	var i int64
+	switch v := v.(type) {
+	case bool:
+		return anyValue{BoolValue: &v}
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint8:
+		i = int64(v)
+	case uint16:
+		i = int64(v)
+	case uint32:
+		i = int64(v)
+	default:
+		s := fmt.Sprint(v)
+		return anyValue{StringValue: &s}
+	}
+
+	s := strconv.FormatInt(i, 10)
+	return anyValue{IntValue: &s}
+}
@@ -0,0 +1,156 @@
+package otlp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Log fields holding the IDs of the trace and span an entry was logged for.
+const (
+	traceIDField = "trace_id"
+	spanIDField  = "span_id"
+)
+
+// OTLP severity numbers.
+var severities = map[logrus.Level]int{
+	logrus.TraceLevel: 1,
+	logrus.DebugLevel: 5,
+	logrus.InfoLevel:  9,
+	logrus.WarnLevel:  13,
+	logrus.ErrorLevel: 17,
+	logrus.FatalLevel: 21,
+	logrus.PanicLevel: 21,
+}
+
+type logRecord struct {
+	time       time.Time
+	level      logrus.Level
+	message    string
+	traceID    string
+	spanID     string
+	attributes map[string]interface{}
+}
+
+// LogExporter is a logrus hook exporting the log entries to an OpenTelemetry collector using
+// OTLP/HTTP, with the IDs of the trace and span they were logged for.
+type LogExporter struct {
+	exporter *exporter
+}
+
+// NewLogExporter returns a log exporter for service, nil if opts have no logs endpoint.
+func NewLogExporter(service string, opts map[string]interface{}, logger Logger) (*LogExporter, error) {
+	c, err := Load(opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.LogsEndpoint == "" {
+		return nil, nil
+	}
+
+	exp := newExporter(c.LogsEndpoint, "log records", c, &http.Client{Timeout: time.Duration(c.Timeout) * time.Second}, logger,
+		func(batch []interface{}) interface{} { return encodeLogs(service, batch) })
+	return &LogExporter{exporter: exp}, nil
+}
+
+func (*LogExporter) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire queues entry for export. The trace and span IDs are taken from the span of the entry
+// context, or else from its trace_id and span_id fields.
+func (l *LogExporter) Fire(entry *logrus.Entry) error {
+	r := &logRecord{
+		time:       entry.Time,
+		level:      entry.Level,
+		message:    entry.Message,
+		attributes: make(map[string]interface{}, len(entry.Data)),
+	}
+	for k, v := range entry.Data {
+		switch k {
+		case traceIDField:
+			r.traceID, _ = v.(string)
+		case spanIDField:
+			r.spanID, _ = v.(string)
+		default:
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			r.attributes[k] = v
+		}
+	}
+	if entry.Context != nil {
+		if span := opentracing.SpanFromContext(entry.Context); span != nil {
+			if c, ok := span.Context().(spanContext); ok {
+				r.traceID, r.spanID = c.TraceIDString(), c.SpanIDString()
+			}
+		}
+	}
+
+	l.exporter.export(r)
+	return nil
+}
+
+// Close sends the queued log records and stops the exporter.
+func (l *LogExporter) Close() error {
+	l.exporter.close()
+	return nil
+}
+
+// The types below are the OTLP/HTTP JSON encoding of a logs export request.
+
+type logsExportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type scopeLogs struct {
+	Scope      scope           `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string     `json:"timeUnixNano"`
+	SeverityNumber int        `json:"severityNumber"`
+	SeverityText   string     `json:"severityText"`
+	Body           anyValue   `json:"body"`
+	Attributes     []keyValue `json:"attributes,omitempty"`
+	TraceID        string     `json:"traceId,omitempty"`
+	SpanID         string     `json:"spanId,omitempty"`
+}
+
+// encodeLogs returns the export request of a batch of log records of service.
+func encodeLogs(service string, batch []interface{}) interface{} {
+	records := make([]otlpLogRecord, 0, len(batch))
+	for _, item := range batch {
+		r := item.(*logRecord)
+		records = append(records, otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(r.time.UnixNano(), 10),
+			SeverityNumber: severities[r.level],
+			SeverityText:   r.level.String(),
+			Body:           value(r.message),
+			Attributes:     attributes(r.attributes),
+			TraceID:        r.traceID,
+			SpanID:         r.spanID,
+		})
+	}
+
+	return logsExportRequest{
+		ResourceLogs: []resourceLogs{{
+			Resource: resource{Attributes: []keyValue{
+				{Key: "service.name", Value: value(service)},
+			}},
+			ScopeLogs: []scopeLogs{{
+				Scope:      scope{Name: scopeName},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,401 @@
+// Package otlp implements an opentracing.Tracer which propagates the W3C trace
+// context and exports spans to an OpenTelemetry collector using OTLP/HTTP.
+package otlp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+var _ opentracing.Tracer = (*otlpTracer)(nil)
+var _ opentracing.SpanContext = spanContext{}
+var _ opentracing.Span = (*Span)(nil)
+
+// Name is the name of this tracer.
+const Name = "otlp"
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+
+	defaultEndpoint = "http://localhost:4318/v1/traces"
+)
+
+// OTLP span kinds.
+const (
+	kindInternal = 1
+	kindServer   = 2
+	kindClient   = 3
+)
+
+type spanContext struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	sampled    bool
+	traceState string
+	// remote is set for contexts extracted from an incoming request
+	remote bool
+}
+
+func (spanContext) ForeachBaggageItem(handler func(k, v string) bool) {}
+
+// TraceIDString returns the hex encoded trace ID, to correlate logs with the trace.
+func (c spanContext) TraceIDString() string {
+	return hex.EncodeToString(c.traceID[:])
+}
+
+// SpanIDString returns the hex encoded span ID, to correlate logs with the span.
+func (c spanContext) SpanIDString() string {
+	return hex.EncodeToString(c.spanID[:])
+}
+
+// traceParent formats the context as a W3C traceparent header value.
+func (c spanContext) traceParent() string {
+	flags := 0
+	if c.sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(c.traceID[:]), hex.EncodeToString(c.spanID[:]), flags)
+}
+
+// parseTraceParent parses a W3C traceparent header value.
+func parseTraceParent(value string) (spanContext, error) {
+	var c spanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+
+	if _, err := hex.Decode(c.traceID[:], []byte(parts[1])); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if _, err := hex.Decode(c.spanID[:], []byte(parts[2])); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if c.traceID == [16]byte{} || c.spanID == [8]byte{} {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+
+	c.sampled = flags[0]&1 == 1
+	c.remote = true
+	return c, nil
+}
+
+type event struct {
+	name       string
+	time       time.Time
+	attributes map[string]interface{}
+}
+
+// Span is a span recorded by the OpenTelemetry tracer.
+type Span struct {
+	tr *otlpTracer
+
+	mu         sync.Mutex
+	ctx        spanContext
+	parentID   [8]byte
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	events     []event
+	isError    bool
+	finished   bool
+}
+
+func (s *Span) Context() opentracing.SpanContext {
+	return s.ctx
+}
+
+func (s *Span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+func (s *Span) FinishWithOptions(opts opentracing.FinishOptions) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	s.end = opts.FinishTime
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+	for _, record := range opts.LogRecords {
+		s.addEvent(record.Timestamp, record.Fields)
+	}
+	s.mu.Unlock()
+
+	if s.ctx.sampled {
+		s.tr.exporter.export(s)
+	}
+}
+
+func (s *Span) SetOperationName(operationName string) opentracing.Span {
+	s.mu.Lock()
+	s.name = operationName
+	s.mu.Unlock()
+	return s
+}
+
+func (s *Span) SetTag(key string, value interface{}) opentracing.Span {
+	s.mu.Lock()
+	s.setTag(key, value)
+	s.mu.Unlock()
+	return s
+}
+
+func (s *Span) setTag(key string, value interface{}) {
+	switch key {
+	case string(ext.SpanKind):
+		switch fmt.Sprint(value) {
+		case string(ext.SpanKindRPCClientEnum):
+			s.kind = kindClient
+		case string(ext.SpanKindRPCServerEnum):
+			s.kind = kindServer
+		}
+	case string(ext.Error):
+		if isError, ok := value.(bool); ok {
+			s.isError = isError
+		}
+	default:
+		s.attributes[key] = value
+	}
+}
+
+func (s *Span) LogFields(fields ...log.Field) {
+	s.mu.Lock()
+	s.addEvent(time.Now(), fields)
+	s.mu.Unlock()
+}
+
+func (s *Span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		return
+	}
+	s.LogFields(fields...)
+}
+
+func (s *Span) addEvent(ts time.Time, fields []log.Field) {
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	e := event{name: "log", time: ts, attributes: make(map[string]interface{}, len(fields))}
+	for _, field := range fields {
+		if field.Key() == "event" {
+			e.name = fmt.Sprint(field.Value())
+			continue
+		}
+		e.attributes[field.Key()] = field.Value()
+	}
+	s.events = append(s.events, e)
+}
+
+func (s *Span) SetBaggageItem(restrictedKey, value string) opentracing.Span { return s }
+func (*Span) BaggageItem(restrictedKey string) string                       { return "" }
+func (s *Span) Tracer() opentracing.Tracer                                  { return s.tr }
+func (s *Span) LogEvent(event string)                                       { s.LogKV("event", event) }
+
+func (s *Span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogKV("event", event, "payload", payload)
+}
+
+func (s *Span) Log(data opentracing.LogData) {
+	s.LogKV("event", data.Event, "payload", data.Payload)
+}
+
+type otlpTracer struct {
+	exporter *exporter
+	// sampleBound is the upper bound of trace IDs sampled for new traces
+	sampleBound uint64
+}
+
+func newTracer(exp *exporter, sampleRatio float64) *otlpTracer {
+	tr := &otlpTracer{exporter: exp, sampleBound: math.MaxUint64}
+	if sampleRatio > 0 && sampleRatio < 1 {
+		tr.sampleBound = uint64(sampleRatio * math.MaxUint64)
+	}
+	return tr
+}
+
+func (t *otlpTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var o opentracing.StartSpanOptions
+	for _, opt := range opts {
+		opt.Apply(&o)
+	}
+
+	s := &Span{
+		tr:         t,
+		name:       operationName,
+		kind:       kindInternal,
+		start:      o.StartTime,
+		attributes: make(map[string]interface{}, len(o.Tags)),
+	}
+	if s.start.IsZero() {
+		s.start = time.Now()
+	}
+
+	var parent *spanContext
+	for _, ref := range o.References {
+		if c, ok := ref.ReferencedContext.(spanContext); ok {
+			parent = &c
+			break
+		}
+	}
+
+	if parent != nil {
+		s.ctx.traceID = parent.traceID
+		s.ctx.sampled = parent.sampled
+		s.ctx.traceState = parent.traceState
+		s.parentID = parent.spanID
+		if parent.remote {
+			s.kind = kindServer
+		}
+	} else {
+		randomID(s.ctx.traceID[:])
+		// the sampling decision is derived from the trace id so that it is consistent
+		s.ctx.sampled = binary.BigEndian.Uint64(s.ctx.traceID[8:]) <= t.sampleBound
+		s.kind = kindServer
+	}
+	randomID(s.ctx.spanID[:])
+
+	for k, v := range o.Tags {
+		s.setTag(k, v)
+	}
+
+	return s
+}
+
+func (t *otlpTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	var reader opentracing.TextMapReader
+	switch format {
+	case opentracing.HTTPHeaders, opentracing.TextMap:
+		var ok bool
+		if reader, ok = carrier.(opentracing.TextMapReader); !ok {
+			return nil, opentracing.ErrInvalidCarrier
+		}
+	default:
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+
+	var traceParent, traceState string
+	err := reader.ForeachKey(func(key, val string) error {
+		switch strings.ToLower(key) {
+		case traceParentHeader:
+			traceParent = val
+		case traceStateHeader:
+			traceState = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if traceParent == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	c, err := parseTraceParent(traceParent)
+	if err != nil {
+		return nil, err
+	}
+	c.traceState = traceState
+	return c, nil
+}
+
+func (t *otlpTracer) Inject(ctx opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	c, ok := ctx.(spanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+
+	var writer opentracing.TextMapWriter
+	switch format {
+	case opentracing.HTTPHeaders, opentracing.TextMap:
+		if writer, ok = carrier.(opentracing.TextMapWriter); !ok {
+			return opentracing.ErrInvalidCarrier
+		}
+	default:
+		return opentracing.ErrUnsupportedFormat
+	}
+
+	writer.Set(traceParentHeader, c.traceParent())
+	if c.traceState != "" {
+		writer.Set(traceStateHeader, c.traceState)
+	}
+	return nil
+}
+
+func randomID(b []byte) {
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		for _, v := range b {
+			if v != 0 {
+				return
+			}
+		}
+	}
+}
+
+// Logger is used to report export errors.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Tracer is an opentracing.Tracer exporting spans to an OpenTelemetry collector.
+type Tracer struct {
+	opentracing.Tracer
+	exporter *exporter
+}
+
+func (Tracer) Name() string {
+	return Name
+}
+
+// Close flushes the finished spans and stops the exporter.
+func (t *Tracer) Close() error {
+	t.exporter.close()
+	return nil
+}
+
+// Init returns an implementation of tyk.Tracer exporting spans of service with OTLP/HTTP.
+func Init(service string, opts map[string]interface{}, logger Logger) (*Tracer, error) {
+	c, err := Load(opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, fmt.Errorf("otlp: sample_ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = defaultEndpoint
+	}
+
+	exp := newExporter(c.Endpoint, "spans", c, &http.Client{Timeout: time.Duration(c.Timeout) * time.Second}, logger,
+		func(batch []interface{}) interface{} { return encodeSpans(service, batch) })
+	return &Tracer{Tracer: newTracer(exp, c.SampleRatio), exporter: exp}, nil
+}
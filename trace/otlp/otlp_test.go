@@ -0,0 +1,198 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type testLogger struct{}
+
+func (testLogger) Errorf(format string, args ...interface{}) {}
+
+func TestParseTraceParent(t *testing.T) {
+	c, err := parseTraceParent(testTraceParent)
+	assert.NoError(t, err)
+	assert.True(t, c.sampled)
+	assert.True(t, c.remote)
+	assert.Equal(t, testTraceParent, c.traceParent())
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, err := parseTraceParent(value)
+		assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, value)
+	}
+
+	// future versions may append fields
+	_, err = parseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+}
+
+func TestTracer(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []exportRequest
+		auth     string
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req exportRequest
+		assert.NoError(t, json.Unmarshal(body, &req))
+
+		mu.Lock()
+		requests = append(requests, req)
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	tr, err := Init("test-service", map[string]interface{}{
+		"endpoint": collector.URL,
+		"headers":  map[string]string{"Authorization": "secret"},
+	}, testLogger{})
+	assert.NoError(t, err)
+
+	incoming := http.Header{}
+	incoming.Set("Traceparent", testTraceParent)
+	incoming.Set("Tracestate", "vendor=value")
+	parent, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(incoming))
+	assert.NoError(t, err)
+
+	root := tr.StartSpan("root", opentracing.ChildOf(parent), opentracing.Tags{"api_id": "api"})
+	child := tr.StartSpan("proxy", opentracing.ChildOf(root.Context()))
+	ext.SpanKindRPCClient.Set(child)
+	ext.Error.Set(child, true)
+	child.SetTag("status", 502)
+
+	outgoing := http.Header{}
+	assert.NoError(t, tr.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(outgoing)))
+	assert.Regexp(t, `^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$`, outgoing.Get("traceparent"))
+	assert.Equal(t, "vendor=value", outgoing.Get("tracestate"))
+
+	child.Finish()
+	root.Finish()
+
+	// unsampled traces are not exported
+	unsampled := http.Header{}
+	unsampled.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	parent, err = tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(unsampled))
+	assert.NoError(t, err)
+	tr.StartSpan("unsampled", opentracing.ChildOf(parent)).Finish()
+
+	_, err = tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{}))
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	assert.NoError(t, tr.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, "secret", auth)
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+
+	rs := requests[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, "test-service", *rs.Resource.Attributes[0].Value.StringValue)
+
+	spans := rs.ScopeSpans[0].Spans
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	proxy, rootSpan := spans[0], spans[1]
+	assert.Equal(t, "root", rootSpan.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rootSpan.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", rootSpan.ParentSpanID)
+	assert.Equal(t, kindServer, rootSpan.Kind)
+	assert.Equal(t, "api_id", rootSpan.Attributes[0].Key)
+
+	assert.Equal(t, "proxy", proxy.Name)
+	assert.Equal(t, rootSpan.TraceID, proxy.TraceID)
+	assert.Equal(t, rootSpan.SpanID, proxy.ParentSpanID)
+	assert.Equal(t, kindClient, proxy.Kind)
+	assert.Equal(t, statusError, proxy.Status.Code)
+	assert.Equal(t, "502", *proxy.Attributes[0].Value.IntValue)
+}
+
+func TestSampling(t *testing.T) {
+	_, err := Init("test", map[string]interface{}{"sample_ratio": 2}, testLogger{})
+	assert.Error(t, err)
+
+	tr := newTracer(nil, 0)
+	assert.True(t, tr.StartSpan("all").Context().(spanContext).sampled)
+
+	tr = newTracer(nil, 0.5)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if tr.StartSpan("half").Context().(spanContext).sampled {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 100)
+}
+
+func TestLogExporter(t *testing.T) {
+	requests := make(chan logsExportRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req logsExportRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests <- req
+	}))
+	defer collector.Close()
+
+	exp, err := NewLogExporter("test-service", map[string]interface{}{}, testLogger{})
+	assert.NoError(t, err)
+	assert.Nil(t, exp)
+
+	exp, err = NewLogExporter("test-service", map[string]interface{}{"logs_endpoint": collector.URL}, testLogger{})
+	assert.NoError(t, err)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(exp)
+
+	tr := newTracer(nil, 0)
+	span := tr.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	c := span.Context().(spanContext)
+
+	logger.WithContext(ctx).WithField("api_id", "api").Warning("from span")
+	logger.WithFields(logrus.Fields{traceIDField: "trace", spanIDField: "span"}).Info("from fields")
+	assert.NoError(t, exp.Close())
+
+	req := <-requests
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if !assert.Len(t, records, 2) {
+		return
+	}
+
+	assert.Equal(t, "from span", *records[0].Body.StringValue)
+	assert.Equal(t, 13, records[0].SeverityNumber)
+	assert.Equal(t, c.TraceIDString(), records[0].TraceID)
+	assert.Equal(t, c.SpanIDString(), records[0].SpanID)
+	assert.Equal(t, "api_id", records[0].Attributes[0].Key)
+
+	assert.Equal(t, "trace", records[1].TraceID)
+	assert.Equal(t, "span", records[1].SpanID)
+	assert.Empty(t, records[1].Attributes)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/TykTechnologies/tyk/trace/jaeger"
 	"github.com/TykTechnologies/tyk/trace/openzipkin"
+	"github.com/TykTechnologies/tyk/trace/otlp"
 	opentracing "github.com/opentracing/opentracing-go"
 )
 
@@ -38,6 +39,8 @@ func Init(name string, service string, opts map[string]interface{}, logger Logge
 		return jaeger.Init(service, opts, logger)
 	case openzipkin.Name:
 		return openzipkin.Init(service, opts)
+	case otlp.Name:
+		return otlp.Init(service, opts, logger)
 	default:
 		return NoopTracer{}, nil
 	}
@@ -32,8 +32,25 @@ type Policy struct {
 	LastUpdated                   string                           `bson:"last_updated" json:"last_updated"`
 	MetaData                      map[string]interface{}           `bson:"meta_data" json:"meta_data"`
 	GraphQL                       map[string]GraphAccessDefinition `bson:"graphql_access_rights" json:"graphql_access_rights"`
+	// Priority orders the policies applied to a key, the policies of the highest priority are applied last.
+	Priority int `bson:"priority" json:"priority"`
+	// MergeStrategy merges the rate limits and quotas of the policies applied to a key, one of max, min,
+	// sum or priority. The policies applied to a key can't declare different strategies. Max if empty.
+	MergeStrategy string `bson:"merge_strategy" json:"merge_strategy,omitempty"`
 }
 
+// Strategies merging the rate limits and quotas of the policies applied to a key.
+const (
+	// MergeStrategyMax keeps the highest rate limit and quota.
+	MergeStrategyMax = "max"
+	// MergeStrategyMin keeps the lowest rate limit and quota.
+	MergeStrategyMin = "min"
+	// MergeStrategySum adds the rate limits and quotas up.
+	MergeStrategySum = "sum"
+	// MergeStrategyPriority keeps the rate limit and quota of the policy of the highest priority.
+	MergeStrategyPriority = "priority"
+)
+
 type PolicyPartitions struct {
 	Quota      bool `bson:"quota" json:"quota"`
 	RateLimit  bool `bson:"rate_limit" json:"rate_limit"`
@@ -0,0 +1,152 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule restricts the times at which a key authenticates to recurring windows, e.g. the
+// business hours of a partner.
+type Schedule struct {
+	// Timezone is the IANA name of the location of the windows, e.g. `Europe/London`, UTC when empty.
+	Timezone string `json:"timezone,omitempty" msg:"timezone"`
+	// Windows are the periods of the week during which the key authenticates, at any time when empty.
+	Windows []ScheduleWindow `json:"windows,omitempty" msg:"windows"`
+}
+
+// ScheduleWindow is a recurring period of the days of the week.
+type ScheduleWindow struct {
+	// Days are the days of the window, e.g. `mon` or `monday`, every day when empty.
+	Days []string `json:"days,omitempty" msg:"days"`
+	// Start and End are times of the day formatted as `15:04`. A window ending before it starts
+	// spans midnight and ends the next day.
+	Start string `json:"start" msg:"start"`
+	End   string `json:"end" msg:"end"`
+}
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+func parseScheduleTime(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (s Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// Validate checks the timezone, days and times of the windows of s.
+func (s Schedule) Validate() error {
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf("invalid timezone %q", s.Timezone)
+	}
+
+	for _, w := range s.Windows {
+		for _, day := range w.Days {
+			if _, ok := scheduleDays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("invalid day %q", day)
+			}
+		}
+		start, err := parseScheduleTime(w.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseScheduleTime(w.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("window %s-%s is empty", w.Start, w.End)
+		}
+	}
+
+	return nil
+}
+
+// ActiveAt reports whether t falls in a window of s. Invalid windows never match.
+func (s Schedule) ActiveAt(t time.Time) bool {
+	if len(s.Windows) == 0 {
+		return true
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	yesterday := (t.Weekday() + 6) % 7
+
+	for _, w := range s.Windows {
+		start, err := parseScheduleTime(w.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseScheduleTime(w.End)
+		if err != nil {
+			continue
+		}
+
+		if start < end {
+			if w.onDay(t.Weekday()) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		// the window spans midnight, it's part of the day it starts
+		if w.onDay(t.Weekday()) && minute >= start || w.onDay(yesterday) && minute < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w ScheduleWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if wd, ok := scheduleDays[strings.ToLower(d)]; ok && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateActivity checks the activity period and the schedule of the key.
+func (s *SessionState) ValidateActivity() error {
+	if s.ActiveFrom < 0 || s.ActiveUntil < 0 {
+		return errors.New("active_from and active_until must not be negative")
+	}
+	if s.ActiveFrom > 0 && s.ActiveUntil > 0 && s.ActiveUntil <= s.ActiveFrom {
+		return errors.New("active_until must be after active_from")
+	}
+	return s.Schedule.Validate()
+}
+
+// NotActiveYet reports whether the key is used at t before the start of its activity period.
+func (s *SessionState) NotActiveYet(t time.Time) bool {
+	return s.ActiveFrom > 0 && t.Before(time.Unix(s.ActiveFrom, 0))
+}
+
+// NoLongerActive reports whether the key is used at t after the end of its activity period.
+func (s *SessionState) NoLongerActive(t time.Time) bool {
+	return s.ActiveUntil > 0 && !t.Before(time.Unix(s.ActiveUntil, 0))
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"crypto/md5"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/jensneuse/graphql-go-tools/pkg/graphql"
@@ -17,12 +18,16 @@ type HashType string
 const (
 	HashPlainText HashType = ""
 	HashBCrypt    HashType = "bcrypt"
+	HashArgon2id  HashType = "argon2id"
 )
 
-// AccessSpecs define what URLS a user has access to an what methods are enabled
+// AccessSpecs define what URLS a user has access to an what methods are enabled.
+// Methods may be "*" for any method, and rules apply to all the versions of the API
+// unless Versions is set.
 type AccessSpec struct {
-	URL     string   `json:"url" msg:"url"`
-	Methods []string `json:"methods" msg:"methods"`
+	URL      string   `json:"url" msg:"url"`
+	Methods  []string `json:"methods" msg:"methods"`
+	Versions []string `json:"versions,omitempty" msg:"versions"`
 }
 
 // APILimit stores quota and rate limit on ACL level (per API)
@@ -47,7 +52,8 @@ type AccessDefinition struct {
 	APIName           string                  `json:"api_name" msg:"api_name"`
 	APIID             string                  `json:"api_id" msg:"api_id"`
 	Versions          []string                `json:"versions" msg:"versions"`
-	AllowedURLs       []AccessSpec            `bson:"allowed_urls" json:"allowed_urls" msg:"allowed_urls"` // mapped string MUST be a valid regex
+	AllowedURLs       []AccessSpec            `bson:"allowed_urls" json:"allowed_urls" msg:"allowed_urls"`        // mapped string MUST be a valid regex
+	DeniedURLs        []AccessSpec            `bson:"denied_urls" json:"denied_urls,omitempty" msg:"denied_urls"` // take precedence over AllowedURLs
 	RestrictedTypes   []graphql.Type          `json:"restricted_types" msg:"restricted_types"`
 	Limit             APILimit                `json:"limit" msg:"limit"`
 	FieldAccessRights []FieldAccessDefinition `json:"field_access_rights" msg:"field_access_rights"`
@@ -55,6 +61,21 @@ type AccessDefinition struct {
 	AllowanceScope string `json:"allowance_scope" msg:"allowance_scope"`
 }
 
+// ValidateAccessSpecs checks that the URLs of the allowed and denied rules of rights are valid
+// regular expressions.
+func ValidateAccessSpecs(rights map[string]AccessDefinition) error {
+	for apiID, access := range rights {
+		for _, specs := range [][]AccessSpec{access.AllowedURLs, access.DeniedURLs} {
+			for _, spec := range specs {
+				if _, err := regexp.Compile(spec.URL); err != nil {
+					return fmt.Errorf("invalid URL %q in the access rights of API %s: %v", spec.URL, apiID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (limit APILimit) IsEmpty() bool {
 	if limit.Rate != 0 || limit.Per != 0 || limit.ThrottleInterval != 0 || limit.ThrottleRetryLimit != 0 || limit.MaxQueryDepth != 0 || limit.QuotaMax != 0 || limit.QuotaRenews != 0 || limit.QuotaRemaining != 0 || limit.QuotaRenewalRate != 0 || limit.SetBy != "" {
 		return false
@@ -85,6 +106,18 @@ type Monitor struct {
 	TriggerLimits []float64 `json:"trigger_limits" msg:"trigger_limits"`
 }
 
+// KeyRotation links the sessions of a rotated key and of the key replacing it during the grace
+// period in which both authenticate.
+type KeyRotation struct {
+	// PreviousKeyHash is set on the session of the new key to the hash of the key it replaced.
+	PreviousKeyHash string `json:"previous_key_hash,omitempty" msg:"previous_key_hash"`
+	// NextKeyHash is set on the session of the rotated key to the hash of the key replacing it,
+	// whose session is used when the rotated key authenticates.
+	NextKeyHash string `json:"next_key_hash,omitempty" msg:"next_key_hash"`
+	// GraceExpires is the Unix time after which the rotated key no longer authenticates.
+	GraceExpires int64 `json:"grace_expires,omitempty" msg:"grace_expires"`
+}
+
 // SessionState objects represent a current API session, mainly used for rate limiting.
 // There's a data structure that's based on this and it's used for Protocol Buffer support, make sure to update "coprocess/proto/coprocess_session_state.proto" and generate the bindings using: cd coprocess/proto && ./update_bindings.sh
 //
@@ -129,6 +162,15 @@ type SessionState struct {
 	LastUpdated             string                 `json:"last_updated" msg:"last_updated"`
 	IdExtractorDeadline     int64                  `json:"id_extractor_deadline" msg:"id_extractor_deadline"`
 	SessionLifetime         int64                  `bson:"session_lifetime" json:"session_lifetime"`
+	KeyRotation             KeyRotation            `json:"key_rotation" msg:"key_rotation"`
+	// ActiveFrom is the Unix time before which the key doesn't authenticate, ignored when 0, so
+	// keys can be provisioned ahead of time.
+	ActiveFrom int64 `json:"active_from" msg:"active_from"`
+	// ActiveUntil is the Unix time from which the key no longer authenticates, ignored when 0.
+	ActiveUntil int64    `json:"active_until" msg:"active_until"`
+	Schedule    Schedule `json:"schedule" msg:"schedule"`
+	// Version is the format version of the session, see SessionVersion.
+	Version int `json:"version" msg:"version"`
 
 	// Used to store token hash
 	keyHash string
@@ -148,6 +190,7 @@ func (s SessionState) Clone() SessionState {
 	newSession.ApplyPolicies = cloneSlice(s.ApplyPolicies)
 	newSession.MetaData = cloneMetadata(s.MetaData)
 	newSession.Tags = cloneSlice(s.Tags)
+	newSession.Schedule.Windows = cloneScheduleWindows(s.Schedule.Windows)
 
 	return newSession
 }
@@ -164,6 +207,18 @@ func cloneSlice(s []string) []string {
 	return x
 }
 
+func cloneScheduleWindows(w []ScheduleWindow) []ScheduleWindow {
+	if w == nil {
+		return nil
+	}
+	x := make([]ScheduleWindow, len(w))
+	for i := range w {
+		x[i] = w[i]
+		x[i].Days = cloneSlice(w[i].Days)
+	}
+	return x
+}
+
 func cloneMetadata(m map[string]interface{}) map[string]interface{} {
 	if m == nil {
 		return nil
@@ -0,0 +1,65 @@
+package user
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SessionVersion is the version of the SessionState format written by the gateway. Bump it, and
+// register a migration from the previous version, when the meaning of stored fields changes.
+const SessionVersion = 2
+
+// SessionMigration upgrades a session from the version it's registered for to the next one.
+type SessionMigration func(s *SessionState)
+
+var sessionMigrations = struct {
+	sync.RWMutex
+	m map[int]SessionMigration
+}{m: map[int]SessionMigration{}}
+
+// RegisterSessionMigration registers the migration upgrading sessions of version from to version
+// from+1. It panics when a migration is already registered for from.
+func RegisterSessionMigration(from int, migration SessionMigration) {
+	sessionMigrations.Lock()
+	defer sessionMigrations.Unlock()
+
+	if _, ok := sessionMigrations.m[from]; ok {
+		panic(fmt.Sprintf("session migration from version %d is already registered", from))
+	}
+	sessionMigrations.m[from] = migration
+}
+
+func init() {
+	// version 1 applies policies with ApplyPolicies only
+	RegisterSessionMigration(0, func(s *SessionState) {
+		if len(s.ApplyPolicies) == 0 && s.ApplyPolicyID != "" {
+			s.SetPolicies(s.ApplyPolicyID)
+		}
+		s.ApplyPolicyID = ""
+	})
+
+	// version 2 enables detailed recording with EnableDetailedRecording only
+	RegisterSessionMigration(1, func(s *SessionState) {
+		s.EnableDetailedRecording = s.EnableDetailedRecording || s.EnableDetailRecording
+		s.EnableDetailRecording = false
+	})
+}
+
+// Migrate upgrades the session to SessionVersion, sessions stored before versioning being of
+// version 0. It returns whether the session was upgraded, sessions of a newer version, written
+// by a newer gateway, are left untouched.
+func (s *SessionState) Migrate() bool {
+	if s.Version >= SessionVersion {
+		return false
+	}
+
+	sessionMigrations.RLock()
+	defer sessionMigrations.RUnlock()
+
+	for ; s.Version < SessionVersion; s.Version++ {
+		if migration, ok := sessionMigrations.m[s.Version]; ok {
+			migration(s)
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionState_Migrate(t *testing.T) {
+	t.Run("version 0", func(t *testing.T) {
+		var s SessionState
+		err := json.Unmarshal([]byte(`{"apply_policy_id":"pol","enable_detail_recording":true}`), &s)
+		assert.NoError(t, err)
+
+		assert.True(t, s.Migrate())
+		assert.Equal(t, SessionVersion, s.Version)
+		assert.Empty(t, s.ApplyPolicyID)
+		assert.Equal(t, []string{"pol"}, s.ApplyPolicies)
+		assert.False(t, s.EnableDetailRecording)
+		assert.True(t, s.EnableDetailedRecording)
+	})
+
+	t.Run("version 0 with policies", func(t *testing.T) {
+		s := SessionState{ApplyPolicyID: "old", ApplyPolicies: []string{"pol1", "pol2"}}
+
+		assert.True(t, s.Migrate())
+		assert.Empty(t, s.ApplyPolicyID)
+		assert.Equal(t, []string{"pol1", "pol2"}, s.ApplyPolicies)
+	})
+
+	t.Run("version 1", func(t *testing.T) {
+		var s SessionState
+		err := json.Unmarshal([]byte(`{"version":1,"apply_policies":["pol"],"enable_detail_recording":true}`), &s)
+		assert.NoError(t, err)
+
+		assert.True(t, s.Migrate())
+		assert.Equal(t, SessionVersion, s.Version)
+		assert.Equal(t, []string{"pol"}, s.ApplyPolicies)
+		assert.False(t, s.EnableDetailRecording)
+		assert.True(t, s.EnableDetailedRecording)
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		s := SessionState{Version: SessionVersion, EnableDetailRecording: true}
+
+		assert.False(t, s.Migrate())
+		assert.True(t, s.EnableDetailRecording)
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		s := SessionState{Version: SessionVersion + 1, ApplyPolicyID: "pol"}
+
+		assert.False(t, s.Migrate())
+		assert.Equal(t, SessionVersion+1, s.Version)
+		assert.Equal(t, "pol", s.ApplyPolicyID)
+	})
+}
+
+func TestRegisterSessionMigration(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterSessionMigration(0, func(s *SessionState) {})
+	})
+}
@@ -0,0 +1,20 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAccessSpecs(t *testing.T) {
+	rights := map[string]AccessDefinition{
+		"api": {
+			AllowedURLs: []AccessSpec{{URL: "^/users/[0-9]+$", Methods: []string{"GET"}}},
+			DeniedURLs:  []AccessSpec{{URL: "/admin", Methods: []string{"*"}}},
+		},
+	}
+	assert.NoError(t, ValidateAccessSpecs(rights))
+
+	rights["api"] = AccessDefinition{DeniedURLs: []AccessSpec{{URL: "/admin(", Methods: []string{"*"}}}}
+	assert.Error(t, ValidateAccessSpecs(rights))
+}